@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/detector"
+	"mezon-checkin-bot/internal/encryption"
+	"mezon-checkin-bot/internal/webrtc"
+	"mezon-checkin-bot/models"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// CLI - Subcommands for checking a deployment without running the bot
+// ============================================================
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{"serve", "", "Run the bot (default)", func([]string) error { serve(); return nil }},
+		{"validate-config", "", "Check the environment, offices, models and audio files", validateConfig},
+		{"test-auth", "", "Log in to Mezon and check the backend API", testAuth},
+		{"decode-frame", "[-out image.jpg] <file>", "Decode a VP8 keyframe (raw or IVF) and detect faces", decodeFrame},
+		{"simulate-call", "[-user id] [-dry-run] <image>...", "Run the capture pipeline on images as a call would", simulateCall},
+		{"bench-jpeg", "[-n runs] [-quality q] [-min-speedup x] <image>", "Compare the opencv and go JPEG encoders", benchJPEG},
+		{"decrypt", "[-out file] <file>", "Decrypt an archived crop, debug frame or embeddings file", decryptFile},
+	}
+}
+
+// runCommand runs the subcommand named by args[0], serving without one
+func runCommand(args []string) error {
+	if len(args) == 0 {
+		serve()
+		return nil
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage()
+		return nil
+	}
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd.run(args[1:])
+		}
+	}
+	printUsage()
+	return fmt.Errorf("unknown command %q", name)
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [arguments]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands() {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", cmd.name, cmd.summary)
+		if cmd.args != "" {
+			fmt.Fprintf(os.Stderr, "  %-16s   %s %s\n", "", cmd.name, cmd.args)
+		}
+	}
+}
+
+// checkList prints one line per check and counts the failures
+type checkList struct {
+	failed int
+}
+
+func (c *checkList) check(name string, err error) {
+	if err != nil {
+		c.failed++
+		fmt.Printf("✗ %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("✓ %s\n", name)
+}
+
+func (c *checkList) err() error {
+	if c.failed > 0 {
+		return fmt.Errorf("%d check(s) failed", c.failed)
+	}
+	return nil
+}
+
+// fileExists fails for an empty path or a missing file
+func fileExists(path string) error {
+	if path == "" {
+		return errors.New("path not set")
+	}
+	_, err := os.Stat(path)
+	return err
+}
+
+// ============================================================
+// VALIDATE-CONFIG
+// ============================================================
+
+func validateConfig(args []string) error {
+	var checks checkList
+
+	_, err := mezonConfigFromEnv()
+	checks.check("Mezon credentials", err)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	_, err = apiClientFromEnv(stop)
+	checks.check("Backend API TLS and request signing", err)
+	_, err = dataKeysFromEnv(stop)
+	checks.check("Data encryption keys", err)
+	_, err = auditKeyFromEnv()
+	checks.check("Audit log key", err)
+
+	if kind := os.Getenv("EVENT_STORE"); kind != "" && kind != "file" {
+		checks.check("Event store ("+kind+")", webrtc.SQLEventStoreSupported(kind))
+	}
+
+	// EVENT_STORE=sqlite|postgres imports this file into the database
+	locationConfig := locationConfigFromEnv(0, 0)
+	checks.check("Offices ("+locationConfig.OfficesFilePath+")", locationConfig.LoadOffices())
+	checks.check("Home locations ("+locationConfig.HomeLocationsFilePath+")", locationConfig.LoadHomeLocations())
+
+	faceConfig := faceConfigFromEnv()
+	if strings.EqualFold(faceConfig.DetectionBackend, "dnn") {
+		checks.check("DNN model", fileExists(faceConfig.DNNModelPath))
+	}
+	if faceConfig.LivenessEnabled {
+		checks.check("Eye cascade", fileExists(faceConfig.EyeCascadePath))
+	}
+	if faceConfig.LocalRecognitionEnabled {
+		checks.check("Recognition model", fileExists(faceConfig.RecognitionModelPath))
+	}
+
+	audioConfig := audioConfigFromEnv()
+	for name, path := range map[string]string{
+		"welcome":          audioConfig.WelcomeAudioPath,
+		"checkin_success":  audioConfig.CheckinSuccessPath,
+		"checkin_fail":     audioConfig.CheckinFailPath,
+		"checkout_success": audioConfig.CheckoutSuccessPath,
+	} {
+		checks.check("Audio "+name, fileExists(path))
+	}
+
+	_, err = exec.LookPath("ffmpeg")
+	checks.check("ffmpeg in PATH", err)
+
+	return checks.err()
+}
+
+// ============================================================
+// TEST-AUTH
+// ============================================================
+
+func testAuth(args []string) error {
+	var checks checkList
+
+	config, err := mezonConfigFromEnv()
+	checks.check("Mezon credentials", err)
+	if err == nil {
+		mezonClient := client.NewMezonClient(config)
+		err = mezonClient.Login()
+		mezonClient.Close()
+		checks.check("Mezon login as bot "+fmt.Sprint(config.BotID), err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	apiClient, err := apiClientFromEnv(stop)
+	checks.check("Backend API TLS and request signing", err)
+	if err == nil {
+		health := api.NewHealthChecker(models.BaseURL, api.DefaultHealthInterval, api.DefaultHealthFailureLimit)
+		health.SetTransport(apiClient.Transport())
+		if !health.Check() {
+			err = fmt.Errorf("health check of %s failed", models.BaseURL)
+		}
+		checks.check("Backend API reachable", err)
+	}
+
+	return checks.err()
+}
+
+// ============================================================
+// DECODE-FRAME
+// ============================================================
+
+func decodeFrame(args []string) error {
+	flags := flag.NewFlagSet("decode-frame", flag.ContinueOnError)
+	out := flags.String("out", "", "where to write the decoded frame with the faces boxed (default <file>.jpg)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: decode-frame [-out image.jpg] <file>")
+	}
+	path := flags.Arg(0)
+	if *out == "" {
+		*out = path + ".jpg"
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	frame, err := webrtc.DecodeVP8Frame(data)
+	if err != nil {
+		return fmt.Errorf("decode failed: %w", err)
+	}
+	defer frame.Close()
+	fmt.Printf("Decoded %dx%d frame\n", frame.Cols(), frame.Rows())
+
+	faceDetector, err := detector.NewFaceDetector(faceConfigFromEnv(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to load face detector: %w", err)
+	}
+	defer faceDetector.Close()
+
+	faces := faceDetector.Detect(frame)
+	fmt.Printf("%d face(s) found with the %s detector\n", len(faces), faceDetector.BackendName())
+	for i, face := range faces {
+		quality := faceDetector.ScoreQuality(frame, face)
+		fmt.Printf("  #%d %v %s passed=%v %s\n", i+1, face, quality, quality.Passed, quality.RejectReason)
+		gocv.Rectangle(&frame, face, color.RGBA{G: 255}, 2)
+	}
+
+	if !gocv.IMWrite(*out, frame) {
+		return fmt.Errorf("failed to write %s", *out)
+	}
+	fmt.Println("Written to", *out)
+	return nil
+}
+
+// ============================================================
+// SIMULATE-CALL
+// ============================================================
+
+// simulateCall runs images through detection, the quality gate and
+// recognition like frames of a call, stopping at the first recognition
+func simulateCall(args []string) error {
+	flags := flag.NewFlagSet("simulate-call", flag.ContinueOnError)
+	userID := flags.Int64("user", 0, "user ID the call is from, sent with the recognition request")
+	dryRun := flags.Bool("dry-run", false, "stop before the recognition request")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("usage: simulate-call [-user id] [-dry-run] <image>...")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	apiClient, err := apiClientFromEnv(stop)
+	if err != nil {
+		return err
+	}
+	faceDetector, err := detector.NewFaceDetector(faceConfigFromEnv(), apiClient)
+	if err != nil {
+		return fmt.Errorf("failed to load face detector: %w", err)
+	}
+	defer faceDetector.Close()
+
+	for attempt, path := range flags.Args() {
+		fmt.Printf("Frame %d: %s\n", attempt+1, path)
+		recognized, err := simulateFrame(faceDetector, path, *userID, attempt+1, *dryRun)
+		if err != nil {
+			fmt.Println("  ✗", err)
+			continue
+		}
+		if recognized {
+			return nil
+		}
+	}
+	if *dryRun {
+		return nil
+	}
+	return errors.New("no frame was recognized")
+}
+
+func simulateFrame(faceDetector *detector.FaceDetector, path string, userID int64, attempt int, dryRun bool) (bool, error) {
+	frame := gocv.IMRead(path, gocv.IMReadColor)
+	if frame.Empty() {
+		return false, fmt.Errorf("cannot read image")
+	}
+	defer frame.Close()
+
+	faces := faceDetector.Detect(frame)
+	switch {
+	case len(faces) == 0:
+		return false, errors.New("no face detected")
+	case len(faces) > 1 && faceDetector.Config.RejectMultipleFaces:
+		return false, fmt.Errorf("%d faces detected, calls reject multiple faces", len(faces))
+	}
+	face := faces[0]
+	fmt.Printf("  ✓ face %v\n", face)
+
+	quality := faceDetector.ScoreQuality(frame, face)
+	if !quality.Passed {
+		return false, fmt.Errorf("quality gate: %s (%s)", quality.RejectReason, quality)
+	}
+	fmt.Printf("  ✓ quality %s\n", quality)
+
+	crop := frame.Region(face.Intersect(image.Rect(0, 0, frame.Cols(), frame.Rows())))
+	defer crop.Close()
+	encoded, err := gocv.IMEncode(gocv.JPEGFileExt, crop)
+	if err != nil {
+		return false, fmt.Errorf("encode failed: %w", err)
+	}
+	defer encoded.Close()
+	fmt.Printf("  ✓ crop encoded, %d bytes\n", encoded.Len())
+
+	if dryRun {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	response, err := faceDetector.Recognize(ctx, crop, encoded.GetBytes(), userID, attempt)
+	if err != nil {
+		return false, fmt.Errorf("recognition failed: %w", err)
+	}
+	if !response.IsSuccessful() {
+		return false, fmt.Errorf("not recognized: %s", response)
+	}
+	fmt.Printf("  ✓ recognized %s\n", response)
+	return true, nil
+}
+
+// ============================================================
+// BENCH-JPEG
+// ============================================================
+
+// benchJPEG times both JPEG encoders on an image. With -min-speedup it
+// fails unless OpenCV is that many times faster, for CI on new images.
+func benchJPEG(args []string) error {
+	flags := flag.NewFlagSet("bench-jpeg", flag.ContinueOnError)
+	runs := flags.Int("n", 200, "encodes per encoder")
+	quality := flags.Int("quality", faceConfigFromEnv().JPEGQuality, "JPEG quality")
+	minSpeedup := flags.Float64("min-speedup", 0, "fail unless opencv is at least this many times faster than go")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *runs <= 0 {
+		return errors.New("usage: bench-jpeg [-n runs] [-quality q] [-min-speedup x] <image>")
+	}
+
+	img := gocv.IMRead(flags.Arg(0), gocv.IMReadColor)
+	if img.Empty() {
+		return fmt.Errorf("cannot read image %s", flags.Arg(0))
+	}
+	defer img.Close()
+	fmt.Printf("%dx%d image, quality %d, %d runs\n", img.Cols(), img.Rows(), *quality, *runs)
+
+	perEncode := make(map[string]time.Duration)
+	for _, encoder := range []string{webrtc.JPEGEncoderGo, webrtc.JPEGEncoderOpenCV} {
+		var buf bytes.Buffer
+		start := time.Now()
+		for i := 0; i < *runs; i++ {
+			buf.Reset()
+			if err := webrtc.EncodeJPEG(img, *quality, encoder, &buf); err != nil {
+				return fmt.Errorf("%s: %w", encoder, err)
+			}
+		}
+		perEncode[encoder] = time.Since(start) / time.Duration(*runs)
+		fmt.Printf("  %-7s %10s/encode  %6.1f KB\n", encoder, perEncode[encoder], float64(buf.Len())/1024)
+	}
+
+	speedup := float64(perEncode[webrtc.JPEGEncoderGo]) / float64(perEncode[webrtc.JPEGEncoderOpenCV])
+	fmt.Printf("opencv is %.2fx the speed of go\n", speedup)
+	if *minSpeedup > 0 && speedup < *minSpeedup {
+		return fmt.Errorf("speedup %.2fx below %.2fx", speedup, *minSpeedup)
+	}
+	return nil
+}
+
+// ============================================================
+// DECRYPT
+// ============================================================
+
+// decryptFile opens a file sealed with the data keys. Its name is
+// authenticated, so it must not have been renamed.
+func decryptFile(args []string) error {
+	flags := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	out := flags.String("out", "", "where to write the plaintext (default <file> without .enc)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: decrypt [-out file] <file>")
+	}
+	path := flags.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(path, ".enc")
+		if *out == path {
+			return errors.New("set -out for a file not ending in .enc")
+		}
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	keys, err := dataKeysFromEnv(stop)
+	if err != nil {
+		return err
+	}
+	if keys == nil {
+		return errors.New("no data keys configured, set DATA_KEYS_FILE or DATA_KEYS")
+	}
+
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	plaintext, err := keys.Open(sealed, []byte(filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, plaintext, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("Decrypted with key %q to %s\n", encryption.KeyID(sealed), *out)
+	return nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/audio"
+	"mezon-checkin-bot/internal/audit"
+	"mezon-checkin-bot/internal/encryption"
+	"mezon-checkin-bot/internal/leader"
+	"mezon-checkin-bot/internal/webrtc"
+	"mezon-checkin-bot/models"
+	"mezon-checkin-bot/proto/checkin"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// CONFIGURATION - Built from the environment, shared by the commands
+// ============================================================
+
+// mezonConfigFromEnv reads the bot credentials and gateway address
+func mezonConfigFromEnv() (models.Config, error) {
+	host := os.Getenv("MEZON_HOST")
+	if host == "" {
+		host = "gw.mezon.ai"
+	}
+
+	port := os.Getenv("MEZON_PORT")
+	if port == "" {
+		port = "443"
+	}
+
+	botID, err := strconv.ParseInt(os.Getenv("BOT_ID"), 10, 64)
+	if err != nil {
+		return models.Config{}, fmt.Errorf("invalid BOT_ID: %w", err)
+	}
+	if os.Getenv("BOT_TOKEN") == "" {
+		return models.Config{}, fmt.Errorf("BOT_TOKEN is not set")
+	}
+
+	return models.Config{
+		BotID:    botID,
+		BotToken: os.Getenv("BOT_TOKEN"),
+		Host:     host,
+		Port:     port,
+		UseSSL:   os.Getenv("MEZON_USE_SSL") != "false",
+	}, nil
+}
+
+// apiClientFromEnv creates the backend client with its TLS and request
+// signing settings. A signing key file is watched until stop is closed.
+func apiClientFromEnv(stop chan struct{}) (*api.APIClient, error) {
+	apiClient := api.NewAPIClient(30 * time.Second)
+	if err := apiClient.ConfigureTLS(api.TLSOptions{
+		CertFile:   os.Getenv("API_TLS_CERT_FILE"),
+		KeyFile:    os.Getenv("API_TLS_KEY_FILE"),
+		CAFile:     os.Getenv("API_TLS_CA_FILE"),
+		MinVersion: os.Getenv("API_TLS_MIN_VERSION"),
+	}); err != nil {
+		return nil, fmt.Errorf("API TLS: %w", err)
+	}
+
+	switch keyFile, keys := os.Getenv("API_SIGNING_KEYS_FILE"), os.Getenv("API_SIGNING_KEYS"); {
+	case keyFile != "":
+		// Rotated by editing the file, no restart needed
+		file, err := api.LoadSigningKeyFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API_SIGNING_KEYS_FILE: %w", err)
+		}
+		signer, err := api.NewRequestSigner(file.Keys, file.ActiveKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("request signer: %w", err)
+		}
+		signer.WatchKeyFile(keyFile, time.Minute, stop)
+		apiClient.SetSigner(signer)
+		logger.Info("API requests are signed", "key_id", file.ActiveKeyID, "key_file", keyFile)
+	case keys != "":
+		parsed, err := api.ParseSigningKeys(keys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API_SIGNING_KEYS: %w", err)
+		}
+		signer, err := api.NewRequestSigner(parsed, os.Getenv("API_SIGNING_KEY_ID"))
+		if err != nil {
+			return nil, fmt.Errorf("request signer: %w", err)
+		}
+		apiClient.SetSigner(signer)
+		logger.Info("API requests are signed", "key_id", os.Getenv("API_SIGNING_KEY_ID"))
+	case os.Getenv("SECRET_KEY") != "":
+		logger.Warn("SECRET_KEY is deprecated: the static X-Secret-Key header can be replayed from a leaked log line, set API_SIGNING_KEYS_FILE or API_SIGNING_KEYS")
+	default:
+		logger.Warn("API requests are not authenticated: set API_SIGNING_KEYS_FILE or API_SIGNING_KEYS")
+	}
+
+	// Recognition results and status updates are only trusted when the
+	// backend signed them with the same keys
+	if os.Getenv("API_SIGNED_RESPONSES") == "true" {
+		if err := apiClient.RequireSignedResponses(models.APICheckIn, models.APIQRCheckIn, models.APIUpdateStatus,
+			checkin.CheckinService_RecognizeStream_FullMethodName); err != nil {
+			return nil, fmt.Errorf("API_SIGNED_RESPONSES: %w", err)
+		}
+		logger.Info("Backend responses must be signed")
+	}
+	return apiClient, nil
+}
+
+// dataKeysFromEnv loads the keys sealing face crops, debug frames and
+// embeddings at rest (nil = none configured). A key file is watched until
+// stop is closed.
+func dataKeysFromEnv(stop chan struct{}) (*encryption.Keyring, error) {
+	var keyring *encryption.Keyring
+	switch keyFile, keys := os.Getenv("DATA_KEYS_FILE"), os.Getenv("DATA_KEYS"); {
+	case keyFile != "":
+		// Mounted by the secrets manager, rotated by editing it
+		parsed, activeID, err := encryption.LoadKeyFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DATA_KEYS_FILE: %w", err)
+		}
+		if keyring, err = encryption.NewKeyring(parsed, activeID); err != nil {
+			return nil, fmt.Errorf("data keys: %w", err)
+		}
+		keyring.WatchKeyFile(keyFile, time.Minute, stop)
+	case keys != "":
+		parsed, err := encryption.ParseKeys(keys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DATA_KEYS: %w", err)
+		}
+		if keyring, err = encryption.NewKeyring(parsed, os.Getenv("DATA_KEY_ID")); err != nil {
+			return nil, fmt.Errorf("data keys: %w", err)
+		}
+	case os.Getenv("IMAGE_ARCHIVE_KEY") != "":
+		// The archive's own key before the keyring, loaded as key "archive".
+		// To rotate, put it in DATA_KEYS_FILE under that ID with the new key.
+		key, err := base64.StdEncoding.DecodeString(os.Getenv("IMAGE_ARCHIVE_KEY"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid IMAGE_ARCHIVE_KEY: %w", err)
+		}
+		if keyring, err = encryption.NewKeyring(map[string][]byte{"archive": key}, "archive"); err != nil {
+			return nil, fmt.Errorf("invalid IMAGE_ARCHIVE_KEY: %w", err)
+		}
+		logger.Warn("IMAGE_ARCHIVE_KEY is deprecated, set DATA_KEYS_FILE with it as key \"archive\"")
+	default:
+		return nil, nil
+	}
+	logger.Info("Data at rest is encrypted", "key_id", keyring.ActiveKeyID())
+	return keyring, nil
+}
+
+// auditKeyFromEnv reads the audit log's HMAC key, base64 in AUDIT_KEY_FILE
+// or AUDIT_KEY. The file must be outside the data directory: a key stored
+// next to the log would let whoever edits the log rebuild its chain.
+func auditKeyFromEnv() ([]byte, error) {
+	encoded := os.Getenv("AUDIT_KEY")
+	if keyFile := os.Getenv("AUDIT_KEY_FILE"); keyFile != "" {
+		absKey, err := filepath.Abs(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUDIT_KEY_FILE: %w", err)
+		}
+		absData, err := filepath.Abs("data")
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUDIT_KEY_FILE: %w", err)
+		}
+		if strings.HasPrefix(absKey, absData+string(filepath.Separator)) {
+			return nil, fmt.Errorf("AUDIT_KEY_FILE must be outside the data directory")
+		}
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read AUDIT_KEY_FILE: %w", err)
+		}
+		encoded = strings.TrimSpace(string(data))
+	}
+	if encoded == "" {
+		return nil, fmt.Errorf("the audit log needs a key, set AUDIT_KEY_FILE or AUDIT_KEY (base64 of %d+ bytes)", audit.MinKeyLen)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("audit key is not base64: %w", err)
+	}
+	if len(key) < audit.MinKeyLen {
+		return nil, fmt.Errorf("audit key must be at least %d bytes, got %d", audit.MinKeyLen, len(key))
+	}
+	return key, nil
+}
+
+// alertChannels are where location alerts and operational alerts go
+type alertChannels struct {
+	locationClanID, locationChannelID int64
+	adminClanID, adminChannelID       int64 // The location alert channel if unset
+}
+
+// alertChannelsFromEnv reads the alert channels
+func alertChannelsFromEnv() alertChannels {
+	var channels alertChannels
+	channels.locationClanID, _ = strconv.ParseInt(os.Getenv("LOCATION_ALERT_CLAN_ID"), 10, 64)
+	channels.locationChannelID, _ = strconv.ParseInt(os.Getenv("LOCATION_ALERT_CHANNEL_ID"), 10, 64)
+	channels.adminClanID, _ = strconv.ParseInt(os.Getenv("ADMIN_ALERT_CLAN_ID"), 10, 64)
+	channels.adminChannelID, _ = strconv.ParseInt(os.Getenv("ADMIN_ALERT_CHANNEL_ID"), 10, 64)
+	if channels.adminChannelID == 0 {
+		channels.adminClanID, channels.adminChannelID = channels.locationClanID, channels.locationChannelID
+	}
+	return channels
+}
+
+// locationConfigFromEnv describes the offices and location checks
+func locationConfigFromEnv(alertClanID, alertChannelID int64) *webrtc.LocationConfig {
+	return &webrtc.LocationConfig{
+		Enabled:         true,
+		OfficesFilePath: "config/offices.json", // Đường dẫn tương đối từ thư mục chạy
+
+		OfficeButtonsEnabled: os.Getenv("OFFICE_BUTTONS_ENABLED") == "true",
+
+		AssignmentsFilePath: "config/office_assignments.json",
+		AssignmentsFromAPI:  os.Getenv("OFFICE_ASSIGNMENTS_FROM_API") == "true",
+
+		HomeLocationsFilePath: "config/home_locations.json",
+
+		HistoryFilePath: "data/location_history.jsonl",
+		AlertClanID:     alertClanID,
+		AlertChannelID:  alertChannelID,
+
+		ReverseGeocodeEnabled: os.Getenv("REVERSE_GEOCODE_ENABLED") == "true",
+		ReverseGeocodeURL:     os.Getenv("REVERSE_GEOCODE_URL"),
+	}
+}
+
+// faceConfigFromEnv describes face detection and recognition
+func faceConfigFromEnv() *models.FaceRecognitionConfig {
+	dnnConfidence, _ := strconv.ParseFloat(os.Getenv("DNN_CONFIDENCE_THRESHOLD"), 32) // 0 = default 0.6
+	dnnInputSize, _ := strconv.Atoi(os.Getenv("DNN_INPUT_SIZE"))                      // 0 = model default
+	return &models.FaceRecognitionConfig{
+		Enabled:     true,
+		MinFaceSize: 80,
+		JPEGQuality: 90,                        // High quality JPEG (range: 1-100)
+		JPEGEncoder: os.Getenv("JPEG_ENCODER"), // "opencv" (default) or "go"
+
+		DetectionBackend: os.Getenv("FACE_DETECTION_BACKEND"), // "haar" (default), "dnn" or "external"
+		DNNModelType:     os.Getenv("DNN_MODEL_TYPE"),         // "yunet" or "ssd"
+		DNNModelPath:     os.Getenv("DNN_MODEL_PATH"),
+		DNNConfigPath:    os.Getenv("DNN_CONFIG_PATH"),
+
+		DNNConfidenceThreshold: float32(dnnConfidence),
+		DNNInputSize:           dnnInputSize,
+
+		ExternalDetectorCommand: os.Getenv("EXTERNAL_DETECTOR_COMMAND"),
+
+		LivenessEnabled: os.Getenv("LIVENESS_ENABLED") == "true",
+		EyeCascadePath:  "haarcascade_eye.xml",
+
+		QualityGateEnabled:  true,
+		RecognitionCacheTTL: 5 * time.Minute,
+
+		LocalRecognitionEnabled: os.Getenv("LOCAL_RECOGNITION_ENABLED") == "true",
+		RecognitionModelPath:    os.Getenv("RECOGNITION_MODEL_PATH"),
+		EmbeddingsFilePath:      "config/embeddings.json",
+
+		RejectMultipleFaces: true,
+
+		Acceleration:   os.Getenv("FACE_ACCELERATION"), // "none", "cuda" or "opencl" (DNN detector only)
+		GPUCascadePath: "haarcascade_frontalface_default_cuda.xml",
+
+		AlignFaces: true,
+
+		BatchSize:   1, // Set > 1 to submit the best K crops per API call
+		BatchWindow: 3 * time.Second,
+
+		UploadMode: os.Getenv("RECOGNITION_UPLOAD_MODE"), // "json" (default) or "multipart"
+
+		Backend:     os.Getenv("CHECKIN_BACKEND"), // "rest" (default) or "grpc"
+		GRPCAddress: os.Getenv("CHECKIN_GRPC_ADDRESS"),
+	}
+}
+
+// audioConfigFromEnv describes the call audio
+func audioConfigFromEnv() audio.AudioConfig {
+	return audio.AudioConfig{
+		WelcomeAudioPath:    "./audio/welcome.ogg",
+		CheckinSuccessPath:  "./audio/checkin-success.ogg",
+		CheckinFailPath:     "./audio/checkin-failed.ogg",
+		CheckoutSuccessPath: "./audio/checkout-success.ogg",
+		Enabled:             true,
+
+		TTSEnabled:       os.Getenv("TTS_ENABLED") == "true",
+		TTSCommand:       os.Getenv("TTS_COMMAND"),
+		GreetingTemplate: "Xin chào %s",
+
+		LanguagePacks: map[string]string{
+			audio.LocaleVI: "./audio/vi",
+			audio.LocaleEN: "./audio/en",
+		},
+		UserLocalesPath: "config/user_locales.json",
+		DefaultLocale:   audio.LocaleVI,
+
+		DuckingEnabled: true,
+		DuckingLevel:   audio.DefaultDuckingLevel,
+
+		BackgroundMusicGain: 0.5,
+
+		RemoteRefreshInterval: 1 * time.Hour,
+
+		STTEnabled:         os.Getenv("STT_ENABLED") == "true",
+		STTCommand:         os.Getenv("STT_COMMAND"),
+		VoiceConfirmWindow: 15 * time.Second,
+	}
+}
+
+// leaderConfigFromEnv picks the lease for LEADER_ELECTION=file|backend. ok is
+// false when election is off and the instance always answers calls.
+func leaderConfigFromEnv(apiClient *api.APIClient) (cfg webrtc.LeaderConfig, ok bool, err error) {
+	switch kind := os.Getenv("LEADER_ELECTION"); kind {
+	case "":
+		return cfg, false, nil
+	case "file":
+		path := os.Getenv("LEADER_LOCK_PATH")
+		if path == "" {
+			path = "data/leader.lock"
+		}
+		cfg.Lock = leader.FileLock{Path: path}
+	case "backend":
+		cfg.Lock = leader.BackendLock{Client: apiClient}
+	default:
+		return cfg, false, fmt.Errorf("unknown LEADER_ELECTION %q, want file or backend", kind)
+	}
+
+	cfg.Holder = os.Getenv("INSTANCE_ID")
+	if cfg.Holder == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return cfg, false, fmt.Errorf("INSTANCE_ID not set and no hostname: %w", err)
+		}
+		cfg.Holder = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	leaseSeconds, _ := strconv.Atoi(os.Getenv("LEADER_LEASE_SECONDS"))
+	cfg.TTL = time.Duration(leaseSeconds) * time.Second
+	return cfg, true, nil
+}
@@ -0,0 +1,253 @@
+package adminserver
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/logging"
+	"mezon-checkin-bot/internal/webrtc"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// ADMIN API - Live operations over HTTP, behind a bearer token
+// ============================================================
+
+var logger = logging.For("adminserver")
+
+// Path is where the API is mounted on the admin server
+const Path = "/admin/"
+
+// defaultEventLimit is how many events GET /admin/events returns by default
+const defaultEventLimit = 50
+
+// Server serves the admin API:
+//
+//	GET  /admin/status                 bot connection, open calls, maintenance
+//	GET  /admin/connections            open calls
+//	POST /admin/connections/{id}/end   hang up a user's call
+//	POST /admin/offices/reload         reload the offices
+//	GET  /admin/maintenance            maintenance mode
+//	POST /admin/maintenance            {"enabled": true|false, "message": "..."}
+//	GET  /admin/loglevel               global and per-component log levels
+//	POST /admin/loglevel               {"component": "webrtc", "level": "debug"|"reset", "verbose": true}
+//	POST /admin/handoff                leave new calls to the standby instance
+//	GET  /admin/events?limit=N         latest check-in events
+//	GET  /admin/dashboard              live call view, updated over a websocket
+//	POST /admin/dashboard/session      exchange the token for the dashboard's session cookie
+type Server struct {
+	manager    *webrtc.WebRTCManager
+	client     *client.MezonClient
+	token      string
+	started    time.Time
+	sessions   map[string]time.Time // Dashboard session ID -> expiry
+	sessionsMu sync.Mutex
+}
+
+// New creates the API. Requests must carry "Authorization: Bearer <token>".
+// The dashboard page asks for the token once and exchanges it for an
+// HttpOnly session cookie, so the token never ends up in a URL.
+func New(manager *webrtc.WebRTCManager, mezonClient *client.MezonClient, token string) *Server {
+	return &Server{manager: manager, client: mezonClient, token: token, started: time.Now(), sessions: make(map[string]time.Time)}
+}
+
+// Handler returns the API's handler, to be mounted at Path
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/status", s.handleStatus)
+	mux.HandleFunc("GET /admin/connections", s.handleConnections)
+	mux.HandleFunc("POST /admin/connections/{id}/end", s.handleEndCall)
+	mux.HandleFunc("POST /admin/offices/reload", s.handleReloadOffices)
+	mux.HandleFunc("GET /admin/maintenance", s.handleGetMaintenance)
+	mux.HandleFunc("POST /admin/maintenance", s.handleSetMaintenance)
+	mux.HandleFunc("GET /admin/loglevel", s.handleGetLogLevel)
+	mux.HandleFunc("POST /admin/loglevel", s.handleSetLogLevel)
+	mux.HandleFunc("POST /admin/handoff", s.handleHandoff)
+	mux.HandleFunc("GET /admin/events", s.handleEvents)
+	mux.HandleFunc("GET /admin/dashboard", s.handleDashboard)
+	mux.HandleFunc("GET /admin/dashboard/ws", s.handleDashboardSocket)
+	mux.HandleFunc("GET /admin/dashboard/session", s.handleDashboardSessionCheck)
+	mux.HandleFunc("POST /admin/dashboard/session", s.handleDashboardLogin)
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			logger.Warn("Unauthorized admin API request", "remote", r.RemoteAddr, "path", r.URL.Path)
+			http.Error(rw, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(rw, r)
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return bearer != "" && hmac.Equal([]byte(bearer), []byte(s.token))
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	switch r.URL.Path {
+	case "/admin/dashboard":
+		// The page alone holds no data, it asks for the token
+		return true
+	case "/admin/dashboard/ws", "/admin/dashboard/session":
+		return s.validSession(r)
+	}
+	return false
+}
+
+// ============================================================
+// HANDLERS
+// ============================================================
+
+func (s *Server) handleStatus(rw http.ResponseWriter, r *http.Request) {
+	droppedDelta, evicted := s.manager.SampleDrops()
+	writeJSON(rw, http.StatusOK, map[string]any{
+		"connected":    s.client.IsConnected(),
+		"active_calls": s.manager.ActiveCalls(),
+		"maintenance":  s.manager.Maintenance(),
+		"draining":     s.manager.Draining(),
+		"standby":      s.manager.Standby(),
+		"instance":     s.manager.LeaderHolder(),
+		"samples_dropped": map[string]uint64{
+			"delta_frames": droppedDelta,
+			"evicted":      evicted,
+		},
+		"uptime": time.Since(s.started).Round(time.Second).String(),
+	})
+}
+
+func (s *Server) handleConnections(rw http.ResponseWriter, r *http.Request) {
+	writeJSON(rw, http.StatusOK, s.manager.Connections())
+}
+
+func (s *Server) handleEndCall(rw http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	if err := s.manager.EndCall(userID); err != nil {
+		writeError(rw, http.StatusNotFound, err.Error())
+		return
+	}
+	s.audit(r)
+	writeJSON(rw, http.StatusOK, map[string]any{"ended": userID})
+}
+
+func (s *Server) handleReloadOffices(rw http.ResponseWriter, r *http.Request) {
+	count, err := s.manager.ReloadOffices()
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.audit(r)
+	writeJSON(rw, http.StatusOK, map[string]any{"offices": count})
+}
+
+func (s *Server) handleGetMaintenance(rw http.ResponseWriter, r *http.Request) {
+	writeJSON(rw, http.StatusOK, map[string]any{
+		"enabled": s.manager.Maintenance(),
+		"message": s.manager.MaintenanceMessage(),
+	})
+}
+
+func (s *Server) handleSetMaintenance(rw http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Enabled *bool  `json:"enabled"`
+		Message string `json:"message"` // Replaces the maintenance DM while enabled
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 1<<12)).Decode(&body); err != nil || body.Enabled == nil {
+		writeError(rw, http.StatusBadRequest, `body must be {"enabled": true|false}`)
+		return
+	}
+	if *body.Enabled {
+		s.manager.SetMaintenanceMessage(body.Message)
+	}
+	s.manager.SetMaintenance(*body.Enabled)
+	s.audit(r)
+	s.handleGetMaintenance(rw, r)
+}
+
+func (s *Server) handleGetLogLevel(rw http.ResponseWriter, r *http.Request) {
+	writeJSON(rw, http.StatusOK, s.manager.LogLevels())
+}
+
+// handleSetLogLevel changes the global level, or a component's when one is
+// named, and/or the Mezon protocol tracing
+func (s *Server) handleSetLogLevel(rw http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Component string `json:"component"`
+		Level     string `json:"level"`
+		Verbose   *bool  `json:"verbose"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 1<<10)).Decode(&body); err != nil ||
+		(body.Level == "" && body.Verbose == nil) {
+		writeError(rw, http.StatusBadRequest, `body must set "level" and/or "verbose"`)
+		return
+	}
+	if body.Level != "" {
+		if err := s.manager.SetLogLevel(body.Component, body.Level); err != nil {
+			writeError(rw, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if body.Verbose != nil {
+		s.client.SetVerbose(*body.Verbose)
+	}
+	s.audit(r)
+	s.handleGetLogLevel(rw, r)
+}
+
+// handleHandoff steps down, the way to upgrade the leader without missing
+// calls: hand off, wait for its open calls to end, then stop it
+func (s *Server) handleHandoff(rw http.ResponseWriter, r *http.Request) {
+	if err := s.manager.Handoff(); err != nil {
+		writeError(rw, http.StatusConflict, err.Error())
+		return
+	}
+	s.audit(r)
+	writeJSON(rw, http.StatusOK, map[string]any{"standby": true, "active_calls": s.manager.ActiveCalls()})
+}
+
+func (s *Server) handleEvents(rw http.ResponseWriter, r *http.Request) {
+	limit := defaultEventLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(rw, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	events, err := s.manager.RecentEvents(limit)
+	if err != nil {
+		writeError(rw, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(rw, http.StatusOK, events)
+}
+
+// audit records a state changing request
+func (s *Server) audit(r *http.Request) {
+	request := r.Method + " " + r.URL.Path
+	logger.Info("Admin API request", "request", request, "remote", r.RemoteAddr)
+	s.manager.AuditAdminAPI(request, r.RemoteAddr)
+}
+
+func writeJSON(rw http.ResponseWriter, status int, value any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(value); err != nil {
+		logger.Warn("Failed to write admin API response", "err", err)
+	}
+}
+
+func writeError(rw http.ResponseWriter, status int, message string) {
+	writeJSON(rw, status, map[string]string{"error": message})
+}
@@ -0,0 +1,160 @@
+package adminserver
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"mezon-checkin-bot/internal/webrtc"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ============================================================
+// DASHBOARD - Live view of the calls for a reception screen
+// ============================================================
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+const (
+	dashboardInterval      = time.Second      // How often the page is updated
+	dashboardEventsRefresh = 10 * time.Second // Recent events are read less often
+	dashboardEventLimit    = 15
+	dashboardWriteTimeout  = 5 * time.Second
+	dashboardSessionTTL    = 12 * time.Hour // A reception screen logs in once a day
+	dashboardSessionCookie = "dashboard_session"
+)
+
+// dashboardSnapshot is one update pushed to the page
+type dashboardSnapshot struct {
+	At          time.Time               `json:"at"`
+	Connected   bool                    `json:"connected"`
+	Maintenance bool                    `json:"maintenance"`
+	Calls       []webrtc.ConnectionInfo `json:"calls"`
+	Recent      []webrtc.CheckinEvent   `json:"recent"`
+}
+
+// The default origin check only accepts pages served by this server
+var dashboardUpgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+func (s *Server) handleDashboard(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.Write(dashboardHTML)
+}
+
+// handleDashboardLogin exchanges the bearer token for a session cookie. The
+// cookie holds a random session ID, not the token, is HttpOnly and is only
+// sent to the dashboard's paths.
+func (s *Server) handleDashboardLogin(rw http.ResponseWriter, r *http.Request) {
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		logger.Error("Failed to create dashboard session", "err", err)
+		http.Error(rw, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+	sessionID := hex.EncodeToString(id)
+	expires := time.Now().Add(dashboardSessionTTL)
+
+	s.sessionsMu.Lock()
+	for existing, expiry := range s.sessions {
+		if time.Now().After(expiry) {
+			delete(s.sessions, existing)
+		}
+	}
+	s.sessions[sessionID] = expires
+	s.sessionsMu.Unlock()
+
+	http.SetCookie(rw, &http.Cookie{
+		Name:     dashboardSessionCookie,
+		Value:    sessionID,
+		Path:     "/admin/dashboard",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	logger.Info("Dashboard session created", "remote", r.RemoteAddr)
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// handleDashboardSessionCheck tells the page whether its cookie is still
+// valid, it only gets here if so
+func (s *Server) handleDashboardSessionCheck(rw http.ResponseWriter, r *http.Request) {
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) validSession(r *http.Request) bool {
+	cookie, err := r.Cookie(dashboardSessionCookie)
+	if err != nil {
+		return false
+	}
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	expires, exists := s.sessions[cookie.Value]
+	if exists && time.Now().After(expires) {
+		delete(s.sessions, cookie.Value)
+		return false
+	}
+	return exists
+}
+
+// handleDashboardSocket pushes a snapshot every dashboardInterval until the
+// page goes away
+func (s *Server) handleDashboardSocket(rw http.ResponseWriter, r *http.Request) {
+	conn, err := dashboardUpgrader.Upgrade(rw, r, nil)
+	if err != nil {
+		logger.Warn("Dashboard websocket upgrade failed", "remote", r.RemoteAddr, "err", err)
+		return
+	}
+	defer conn.Close()
+	logger.Info("Dashboard connected", "remote", r.RemoteAddr)
+
+	// The page never sends anything; reading notices when it closes
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+
+	var recent []webrtc.CheckinEvent
+	var recentAt time.Time
+	for {
+		if time.Since(recentAt) >= dashboardEventsRefresh {
+			events, err := s.manager.RecentEvents(dashboardEventLimit)
+			if err != nil {
+				logger.Debug("Dashboard cannot read recent events", "err", err)
+			}
+			recent, recentAt = events, time.Now()
+		}
+
+		snapshot := dashboardSnapshot{
+			At:          time.Now(),
+			Connected:   s.client.IsConnected(),
+			Maintenance: s.manager.Maintenance(),
+			Calls:       s.manager.Connections(),
+			Recent:      recent,
+		}
+		conn.SetWriteDeadline(time.Now().Add(dashboardWriteTimeout))
+		if err := conn.WriteJSON(snapshot); err != nil {
+			logger.Debug("Dashboard disconnected", "remote", r.RemoteAddr, "err", err)
+			return
+		}
+
+		select {
+		case <-closed:
+			logger.Info("Dashboard disconnected", "remote", r.RemoteAddr)
+			return
+		case <-ticker.C:
+		}
+	}
+}
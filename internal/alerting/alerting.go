@@ -0,0 +1,162 @@
+package alerting
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/crash"
+	"mezon-checkin-bot/internal/logging"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================
+// ALERTING - Critical conditions posted to the admin channel
+// ============================================================
+
+var logger = logging.For("alerting")
+
+const (
+	DefaultCooldown = 15 * time.Minute
+	maxPending      = 20
+)
+
+// Alert keys, one per condition. Repeats of a key within the cooldown are
+// only logged.
+const (
+	KeyReconnectFailed = "reconnect_failed"
+	KeyBackendDown     = "backend_down"
+	KeyFFmpegMissing   = "ffmpeg_missing"
+	KeyClassifierLoad  = "classifier_load"
+	KeyResourceLeak    = "resource_leak"
+	KeyPanic           = "panic"
+	KeySupervisor      = "supervisor"
+	KeyLeaderTakeover  = "leader_takeover"
+	KeyWarmupFailed    = "warmup_failed"
+	KeyDataKeys        = "data_keys"
+)
+
+// Sender delivers one alert, typically as a channel message
+type Sender func(title, description string) error
+
+type pendingAlert struct {
+	title       string
+	description string
+}
+
+// Alerter posts alerts through a Sender, at most once per key per cooldown.
+// Alerts raised before a sender is set, or that fail to send (e.g. while the
+// websocket is down), are kept and retried by Flush.
+type Alerter struct {
+	send     Sender
+	cooldown time.Duration
+	last     map[string]time.Time
+	pending  []pendingAlert
+	mu       sync.Mutex
+}
+
+// New creates an alerter without a sender
+func New(cooldown time.Duration) *Alerter {
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+	return &Alerter{
+		cooldown: cooldown,
+		last:     make(map[string]time.Time),
+	}
+}
+
+// SetSender sets the delivery function and flushes queued alerts
+func (a *Alerter) SetSender(send Sender) {
+	a.mu.Lock()
+	a.send = send
+	a.mu.Unlock()
+
+	a.Flush()
+}
+
+// Alert logs the condition and posts it unless the same key alerted within
+// the cooldown. It blocks while sending.
+func (a *Alerter) Alert(key, title, description string) {
+	logger.Error("Operational alert", "key", key, "title", title, "description", description)
+
+	a.mu.Lock()
+	if last, ok := a.last[key]; ok && time.Since(last) < a.cooldown {
+		a.mu.Unlock()
+		logger.Debug("Alert suppressed by cooldown", "key", key)
+		return
+	}
+	a.last[key] = time.Now()
+	a.mu.Unlock()
+
+	a.deliver(pendingAlert{title: title, description: description})
+}
+
+// Flush retries alerts that could not be sent
+func (a *Alerter) Flush() {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+
+	for _, p := range pending {
+		a.deliver(p)
+	}
+}
+
+func (a *Alerter) deliver(p pendingAlert) {
+	a.mu.Lock()
+	send := a.send
+	a.mu.Unlock()
+
+	if send != nil {
+		err := send(p.title, p.description)
+		if err == nil {
+			return
+		}
+		logger.Warn("Failed to send alert, will retry", "title", p.title, "err", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.pending) >= maxPending {
+		a.pending = a.pending[1:]
+	}
+	a.pending = append(a.pending, p)
+}
+
+// ============================================================
+// DEFAULT ALERTER
+// ============================================================
+
+var defaultAlerter atomic.Pointer[Alerter]
+
+// SetDefault sets the alerter used by the package-level functions
+func SetDefault(a *Alerter) {
+	defaultAlerter.Store(a)
+}
+
+// Alert raises an alert on the default alerter. Without one it only logs.
+func Alert(key, title, description string) {
+	if a := defaultAlerter.Load(); a != nil {
+		a.Alert(key, title, description)
+		return
+	}
+	logger.Error("Operational alert", "key", key, "title", title, "description", description)
+}
+
+// Recover stops a panic in the calling goroutine, reports it with its stack
+// and raises an alert. args are slog-style call metadata (user_id, call_id...).
+// Use it as `defer alerting.Recover("component", args...)`.
+func Recover(component string, args ...any) {
+	if r := recover(); r != nil {
+		Panic(component, r, args...)
+	}
+}
+
+// Panic reports a value already recovered by the caller and raises an alert.
+// Call it from the deferred function that recovered.
+func Panic(component string, value any, args ...any) {
+	crash.Capture(component, value, args...)
+	Alert(KeyPanic+":"+component, "🔥 Bot gặp lỗi nghiêm trọng (panic)",
+		fmt.Sprintf("Thành phần: %s\nLỗi: %v\nBot đã tự phục hồi, vui lòng kiểm tra log.", component, value))
+}
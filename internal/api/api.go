@@ -2,22 +2,32 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"mezon-checkin-bot/internal/logging"
 	"net/http"
 	"os"
 	"time"
 )
 
+var logger = logging.For("api")
+
 // ============================================================
 // API CLIENT - Reusable HTTP client for face recognition API
 // ============================================================
 
 type APIClient struct {
-	Timeout time.Duration
-	client  *http.Client
+	Timeout   time.Duration
+	client    *http.Client
+	signer    *RequestSigner
+	tlsConfig *tls.Config // Set by ConfigureTLS, shared with the gRPC client
+	metrics   *Metrics
+
+	signedResponses map[string]bool // Endpoint paths whose responses must be signed
 }
 
 // isSuccessStatusCode checks if the HTTP status code indicates success
@@ -30,63 +40,126 @@ func NewAPIClient(timeout time.Duration) *APIClient {
 	return &APIClient{
 		Timeout: timeout,
 		client:  &http.Client{Timeout: timeout},
+		metrics: DefaultMetrics,
 	}
 }
 
 // SendRequest sends a POST request to the API with proper headers
 func (c *APIClient) SendRequest(payload interface{}, endpoint string) ([]byte, int, error) {
+	return c.SendRequestContext(context.Background(), payload, endpoint)
+}
+
+// SendRequestContext is SendRequest tagged with the call ID of ctx, if any
+func (c *APIClient) SendRequestContext(ctx context.Context, payload interface{}, endpoint string) ([]byte, int, error) {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Log request payload
-	log.Printf("Request Payload: %s", string(jsonData))
+	logger.Debug("Request payload", "endpoint", endpoint, "call_id", CallIDFromContext(ctx), "payload", string(jsonData))
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	c.setHeaders(req)
+	if err := c.setHeaders(req, jsonData); err != nil {
+		return nil, 0, err
+	}
 
 	// Log request headers
-	log.Println("Request Headers:")
-	for key, values := range req.Header {
-		for _, value := range values {
-			log.Printf("  %s: %s", key, value)
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		for key, values := range req.Header {
+			for _, value := range values {
+				if logging.IsSecretKey(key) {
+					value = logging.Redacted
+				}
+				logger.Debug("Request header", "key", key, "value", value)
+			}
 		}
 	}
 
+	body, statusCode, err := c.do(req)
+	if err != nil {
+		return body, statusCode, err
+	}
+
+	logger.Debug("Response body", "endpoint", endpoint, "body", string(body))
+
+	return body, statusCode, nil
+}
+
+// GetRequest sends a GET request to the API with proper headers
+func (c *APIClient) GetRequest(endpoint string) ([]byte, int, error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if err := c.setHeaders(req, nil); err != nil {
+		return nil, 0, err
+	}
+
+	return c.do(req)
+}
+
+// do sends the request, reads the response body and records latency and
+// status class for the endpoint
+func (c *APIClient) do(req *http.Request) ([]byte, int, error) {
+	start := time.Now()
+	body, statusCode, err := c.roundTrip(req)
+	c.metrics.Observe(req.URL.Path, statusCode, err, time.Since(start))
+	return body, statusCode, err
+}
+
+func (c *APIClient) roundTrip(req *http.Request) ([]byte, int, error) {
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, 0, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Log response status
-	log.Printf("Response Status: %d %s", resp.StatusCode, resp.Status)
+	logger.Debug("Response status", "endpoint", req.URL.Path, "status", resp.StatusCode)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
 	}
-
-	// Log response body
-	log.Printf("Response Body: %s", string(body))
+	if err := c.verifyResponse(req, resp, body); err != nil {
+		return nil, resp.StatusCode, err
+	}
 
 	return body, resp.StatusCode, nil
 }
 
+// SetSigner enables HMAC request signing, replacing the static X-Secret-Key header
+func (c *APIClient) SetSigner(signer *RequestSigner) {
+	c.signer = signer
+}
+
 // setHeaders sets required headers for the API request
-func (c *APIClient) setHeaders(req *http.Request) {
+func (c *APIClient) setHeaders(req *http.Request, body []byte) error {
 	req.Header.Set("Accept", "application/json, text/plain, */*")
 	req.Header.Set("Accept-Language", "vi,en-US;q=0.9,en;q=0.8")
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Secret-Key", os.Getenv("SECRET_KEY"))
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	if callID := CallIDFromContext(req.Context()); callID != "" {
+		req.Header.Set(CallIDHeader, callID)
+	}
+
+	if c.signer != nil {
+		if err := c.signer.Sign(req, body); err != nil {
+			return fmt.Errorf("failed to sign request: %w", err)
+		}
+		return nil
+	}
+
+	// Deprecated static secret, only sent without a signer (see main)
+	req.Header.Set("X-Secret-Key", os.Getenv("SECRET_KEY"))
+	return nil
 }
 
 // ParseResponse unmarshals JSON response into provided struct
@@ -100,12 +173,12 @@ func (c *APIClient) ParseResponse(body []byte, result interface{}) error {
 // LogResponse logs the raw response if it's small enough
 func (c *APIClient) LogResponse(body []byte, statusCode int) {
 	if statusCode >= 200 && statusCode < 300 {
-		log.Printf("✅ API response: %d - Success!", statusCode)
+		logger.Info("API response", "status", statusCode)
 	} else {
-		log.Printf("⚠️  API response: %d - Failed", statusCode)
+		logger.Warn("API response failed", "status", statusCode)
 	}
 
 	if len(body) > 0 && len(body) < 1000 {
-		log.Printf("📥 Raw response: %s", string(body))
+		logger.Debug("Raw response", "body", string(body))
 	}
 }
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// ============================================================
+// CALL ID - Correlates one call across logs, DMs and backend requests
+// ============================================================
+
+// CallIDHeader carries the call ID on REST requests (and as gRPC metadata)
+const CallIDHeader = "X-Call-ID"
+
+type callIDKey struct{}
+
+// NewCallID returns a random 12 character ID, short enough to read out to support
+func NewCallID() string {
+	var b [6]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithCallID returns a copy of ctx carrying the call ID
+func WithCallID(ctx context.Context, callID string) context.Context {
+	return context.WithValue(ctx, callIDKey{}, callID)
+}
+
+// CallIDFromContext returns the call ID of ctx, or ""
+func CallIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(callIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// API ERRORS - Typed errors parsed from non-2xx responses
+// ============================================================
+
+var (
+	ErrUnrecognized     = errors.New("face not recognized")
+	ErrAlreadyCheckedIn = errors.New("already checked in")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrNotClockedIn     = errors.New("not clocked in")
+	ErrBreakInProgress  = errors.New("break already in progress")
+	ErrNoActiveBreak    = errors.New("no active break")
+	ErrDuplicateRequest = errors.New("request already registered")
+)
+
+// DefaultRetryAfter is used for rate limiting without a retry hint
+const DefaultRetryAfter = 5 * time.Second
+
+// errorCodes maps backend error codes to sentinel errors
+var errorCodes = map[string]error{
+	"UNRECOGNIZED":        ErrUnrecognized,
+	"FACE_NOT_RECOGNIZED": ErrUnrecognized,
+	"ALREADY_CHECKED_IN":  ErrAlreadyCheckedIn,
+	"RATE_LIMITED":        ErrRateLimited,
+	"TOO_MANY_REQUESTS":   ErrRateLimited,
+	"NOT_CHECKED_IN":      ErrNotClockedIn,
+	"NOT_CLOCKED_IN":      ErrNotClockedIn,
+	"BREAK_IN_PROGRESS":   ErrBreakInProgress,
+	"ALREADY_ON_BREAK":    ErrBreakInProgress,
+	"NO_ACTIVE_BREAK":     ErrNoActiveBreak,
+	"NOT_ON_BREAK":        ErrNoActiveBreak,
+	"DUPLICATE_REQUEST":   ErrDuplicateRequest,
+	"REQUEST_EXISTS":      ErrDuplicateRequest,
+}
+
+// APIError is a non-2xx response from the backend. Match it with
+// errors.Is against the sentinel errors above.
+type APIError struct {
+	StatusCode int
+	Code       string        // Machine-readable code, e.g. "ALREADY_CHECKED_IN"
+	Message    string        // Human-readable message from the backend
+	RetryAfter time.Duration // Set for rate limiting
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.Code != "" && e.Message != "":
+		return fmt.Sprintf("API error %d %s: %s", e.StatusCode, e.Code, e.Message)
+	case e.Code != "":
+		return fmt.Sprintf("API error %d %s", e.StatusCode, e.Code)
+	case e.Message != "":
+		return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+	default:
+		return fmt.Sprintf("API returned status %d", e.StatusCode)
+	}
+}
+
+// Is maps the error code (or status code) to the sentinel errors
+func (e *APIError) Is(target error) bool {
+	if sentinel, ok := errorCodes[strings.ToUpper(e.Code)]; ok {
+		return sentinel == target
+	}
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrAlreadyCheckedIn:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
+
+// errorBody covers the field names the backend uses for errors
+type errorBody struct {
+	Code       string          `json:"code"`
+	ErrorCode  string          `json:"errorCode"`
+	Message    string          `json:"message"`
+	Error      string          `json:"error"`
+	RetryAfter json.RawMessage `json:"retryAfter"` // Seconds, as number or string
+}
+
+// ParseError builds an APIError from a non-2xx response. Returns nil for
+// successful status codes.
+func ParseError(body []byte, statusCode int) error {
+	if statusCode >= 200 && statusCode < 300 {
+		return nil
+	}
+
+	apiErr := &APIError{StatusCode: statusCode}
+
+	var parsed errorBody
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		apiErr.Code = firstNonEmpty(parsed.Code, parsed.ErrorCode)
+		apiErr.Message = firstNonEmpty(parsed.Message, parsed.Error)
+		apiErr.RetryAfter = parseRetryAfter(parsed.RetryAfter)
+	} else if len(body) > 0 && len(body) < 500 {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+
+	if apiErr.RetryAfter == 0 && errors.Is(apiErr, ErrRateLimited) {
+		apiErr.RetryAfter = DefaultRetryAfter
+	}
+	return apiErr
+}
+
+// RetryAfter returns how long to wait before retrying err (0 = no hint)
+func RetryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	var grpcErr *GRPCError
+	if errors.As(err, &grpcErr) && errors.Is(grpcErr, ErrRateLimited) {
+		return DefaultRetryAfter // gRPC statuses carry no retry hint
+	}
+	return 0
+}
+
+func parseRetryAfter(raw json.RawMessage) time.Duration {
+	value := strings.Trim(string(raw), `" `)
+	if value == "" || value == "null" {
+		return 0
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
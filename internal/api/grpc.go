@@ -0,0 +1,399 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// ============================================================
+// GRPC CLIENT - grpc-go connection to the check-in backend with
+// the request signing, signed responses and metrics of the REST
+// client
+// ============================================================
+
+const grpcMaxMessageSize = 16 * 1024 * 1024 // 16MB
+
+// GRPCError is a non-OK gRPC status from the backend. Match it with
+// errors.Is against the sentinel errors in errors.go.
+type GRPCError struct {
+	Code    codes.Code
+	Message string
+}
+
+func (e *GRPCError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("gRPC status %s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("gRPC status %s", e.Code)
+}
+
+// Is maps gRPC status codes to the sentinel errors
+func (e *GRPCError) Is(target error) bool {
+	switch target {
+	case ErrAlreadyCheckedIn:
+		return e.Code == codes.AlreadyExists
+	case ErrRateLimited:
+		return e.Code == codes.ResourceExhausted
+	}
+	return false
+}
+
+// GRPCClient is the grpc.ClientConnInterface handed to the clients generated
+// in proto/. It signs each request like the REST client does, verifies the
+// responses of the methods required to be signed and records metrics per
+// method. Only unary and server-streaming methods are supported: the request
+// is signed before the call starts.
+type GRPCClient struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+	signer  *RequestSigner
+	metrics *Metrics
+
+	signedResponses map[string]bool // Methods whose responses must be signed, from base
+}
+
+// NewGRPCClient creates a client for target ("host:port", "http://host:port"
+// or "https://host:port"; no scheme means plaintext). TLS config and signer
+// are taken from base when given.
+func NewGRPCClient(target string, timeout time.Duration, base *APIClient) (*GRPCClient, error) {
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gRPC target %q: %w", target, err)
+	}
+
+	c := &GRPCClient{
+		timeout: timeout,
+		metrics: DefaultMetrics,
+	}
+	tlsConfig := &tls.Config{}
+	if base != nil {
+		if base.tlsConfig != nil {
+			tlsConfig = base.tlsConfig
+		}
+		c.signer = base.signer
+		c.metrics = base.metrics
+		c.signedResponses = base.signedResponses
+	}
+
+	var creds credentials.TransportCredentials
+	switch u.Scheme {
+	case "http":
+		creds = insecure.NewCredentials()
+	case "https":
+		creds = credentials.NewTLS(tlsConfig)
+	default:
+		return nil, fmt.Errorf("unsupported gRPC target scheme %q", u.Scheme)
+	}
+
+	c.conn, err = grpc.NewClient(u.Host,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(grpcMaxMessageSize)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection to %q: %w", target, err)
+	}
+	return c, nil
+}
+
+// Invoke calls a unary method ("/package.Service/Method")
+func (c *GRPCClient) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	call, err := c.newCall(ctx, method, args)
+	if err != nil {
+		return err
+	}
+	var header, trailer metadata.MD
+	err = c.conn.Invoke(call.ctx, method, args, reply,
+		append(opts, grpc.ForceCodec(grpcCallCodec{call}), grpc.Header(&header), grpc.Trailer(&trailer))...)
+	return call.finish(err, header, trailer)
+}
+
+// NewStream opens a server-streaming call. The call starts with SendMsg,
+// once the request is known and can be signed.
+func (c *GRPCClient) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if desc.ClientStreams {
+		return nil, fmt.Errorf("client-streaming method %s is not supported", method)
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	return &grpcStream{client: c, ctx: ctx, cancel: cancel, desc: desc, method: method, opts: opts}, nil
+}
+
+func (c *GRPCClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout > 0 {
+		return context.WithTimeout(ctx, c.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// ============================================================
+// CALLS
+// ============================================================
+
+// grpcCall is one call: the encoded request it signed, and the digest of the
+// response messages checked against the response signature
+type grpcCall struct {
+	client  *GRPCClient
+	method  string
+	ctx     context.Context // Carries the signature and call ID metadata
+	request []byte
+	nonce   string
+	digest  hash.Hash
+	start   time.Time
+}
+
+// newCall encodes the request and signs it. The signed body is the gRPC frame
+// of the message (flag byte, length, message), the URI the method path.
+func (c *GRPCClient) newCall(ctx context.Context, method string, args any) (*grpcCall, error) {
+	msg, ok := args.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("gRPC request %T is not a protobuf message", args)
+	}
+	request, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode gRPC request: %w", err)
+	}
+
+	call := &grpcCall{
+		client:  c,
+		method:  method,
+		request: request,
+		digest:  sha256.New(),
+		start:   time.Now(),
+	}
+
+	var pairs []string
+	if callID := CallIDFromContext(ctx); callID != "" {
+		pairs = append(pairs, strings.ToLower(CallIDHeader), callID)
+	}
+	if c.signer != nil {
+		frame := make([]byte, 5+len(request))
+		binary.BigEndian.PutUint32(frame[1:5], uint32(len(request)))
+		copy(frame[5:], request)
+
+		headers, err := c.signer.signature(http.MethodPost, method, frame)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+		for name := range headers {
+			pairs = append(pairs, strings.ToLower(name), headers.Get(name))
+		}
+		call.nonce = headers.Get(HeaderNonce)
+	}
+	call.ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+	return call, nil
+}
+
+// finish checks the response signature, converts the status and records the
+// call. gRPC statuses are reported with the HTTP class of their REST
+// equivalent (see grpcHTTPStatus).
+func (call *grpcCall) finish(err error, header, trailer metadata.MD) error {
+	st := status.Convert(err)
+	switch {
+	case err != nil && !fromBackend(st, header, trailer):
+		err = fmt.Errorf("gRPC call %s failed: %w", call.method, err)
+	default:
+		if verifyErr := call.verifyResponse(st.Code(), header, trailer); verifyErr != nil {
+			err = verifyErr
+		} else if err != nil {
+			logger.Warn("gRPC call failed", "method", call.method, "code", st.Code(), "message", st.Message())
+			err = &GRPCError{Code: st.Code(), Message: st.Message()}
+		}
+	}
+	call.client.metrics.Observe(call.method, grpcHTTPStatus(err), transportError(err), time.Since(call.start))
+	return err
+}
+
+// verifyResponse checks the signature of a method required to be signed,
+// whatever its status: a forged ALREADY_EXISTS is acted on too. The signed
+// STATUS is the grpc-status code, the signature headers come in the trailers
+// (or the headers).
+func (call *grpcCall) verifyResponse(code codes.Code, header, trailer metadata.MD) error {
+	c := call.client
+	if !c.signedResponses[call.method] {
+		return nil
+	}
+	signed := http.Header{}
+	for _, md := range []metadata.MD{header, trailer} {
+		for key, values := range md {
+			signed[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+	if err := c.signer.verifyResponse(call.method, call.nonce, int(code), signed, call.digest.Sum(nil)); err != nil {
+		logger.Error("Rejected backend response", "method", call.method, "grpc_status", int(code), "err", err)
+		return err
+	}
+	return nil
+}
+
+// fromBackend reports whether a non-OK status was sent by the backend. grpc-go
+// reports a failed connection or a passed deadline with these codes and no
+// metadata.
+func fromBackend(st *status.Status, header, trailer metadata.MD) bool {
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return len(header) > 0 || len(trailer) > 0
+	}
+	return true
+}
+
+// grpcCallCodec sends the request encoded and signed by newCall, and adds the
+// response messages to the call's digest as they are decoded
+type grpcCallCodec struct {
+	call *grpcCall
+}
+
+func (c grpcCallCodec) Marshal(v any) ([]byte, error) {
+	return c.call.request, nil
+}
+
+func (c grpcCallCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("gRPC response %T is not a protobuf message", v)
+	}
+	c.call.digest.Write(data)
+	return proto.Unmarshal(data, msg)
+}
+
+func (c grpcCallCodec) Name() string {
+	return "proto"
+}
+
+// grpcStream opens the underlying stream on SendMsg and finishes the call
+// when RecvMsg reaches the end of the stream. A signed response covers all
+// its messages and the signature comes in the trailers: callers act on the
+// messages once RecvMsg returned io.EOF.
+type grpcStream struct {
+	client *GRPCClient
+	ctx    context.Context
+	cancel context.CancelFunc
+	desc   *grpc.StreamDesc
+	method string
+	opts   []grpc.CallOption
+
+	call     *grpcCall
+	stream   grpc.ClientStream
+	finished error // io.EOF or the error the call ended with
+}
+
+func (s *grpcStream) SendMsg(m any) error {
+	if s.stream != nil {
+		return fmt.Errorf("server-streaming method %s takes one request", s.method)
+	}
+	call, err := s.client.newCall(s.ctx, s.method, m)
+	if err != nil {
+		s.cancel()
+		return err
+	}
+	s.call = call
+	stream, err := s.client.conn.NewStream(call.ctx, s.desc, s.method, append(s.opts, grpc.ForceCodec(grpcCallCodec{call}))...)
+	if err != nil {
+		s.cancel()
+		return call.finish(err, nil, nil)
+	}
+	s.stream = stream
+	return stream.SendMsg(m)
+}
+
+func (s *grpcStream) RecvMsg(m any) error {
+	if s.finished != nil {
+		return s.finished
+	}
+	if s.stream == nil {
+		return fmt.Errorf("gRPC stream %s has no request", s.method)
+	}
+	err := s.stream.RecvMsg(m)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	header, _ := s.stream.Header()
+	err = s.call.finish(err, header, s.stream.Trailer())
+	s.cancel()
+	if err == nil {
+		err = io.EOF
+	}
+	s.finished = err
+	return err
+}
+
+func (s *grpcStream) Header() (metadata.MD, error) {
+	if s.stream == nil {
+		return nil, fmt.Errorf("gRPC stream %s has no request", s.method)
+	}
+	return s.stream.Header()
+}
+
+func (s *grpcStream) Trailer() metadata.MD {
+	if s.stream == nil {
+		return nil
+	}
+	return s.stream.Trailer()
+}
+
+func (s *grpcStream) CloseSend() error {
+	if s.stream == nil {
+		return nil
+	}
+	return s.stream.CloseSend()
+}
+
+func (s *grpcStream) Context() context.Context {
+	if s.stream == nil {
+		return s.ctx
+	}
+	return s.stream.Context()
+}
+
+// grpcHTTPStatus maps the call outcome to an HTTP-like status for metrics
+func grpcHTTPStatus(err error) int {
+	var grpcErr *GRPCError
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.As(err, &grpcErr):
+		switch grpcErr.Code {
+		case codes.AlreadyExists:
+			return http.StatusConflict
+		case codes.ResourceExhausted:
+			return http.StatusTooManyRequests
+		default:
+			return http.StatusInternalServerError
+		}
+	default:
+		return 0
+	}
+}
+
+// transportError returns err unless it is a gRPC status from the backend
+func transportError(err error) error {
+	var grpcErr *GRPCError
+	if errors.As(err, &grpcErr) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// HEALTH CHECKER - Periodic ping of the check-in backend
+// ============================================================
+
+const (
+	DefaultHealthInterval     = 30 * time.Second
+	DefaultHealthFailureLimit = 2 // Consecutive failures before marking unhealthy
+	healthPingTimeout         = 5 * time.Second
+)
+
+// HealthChecker pings the backend periodically. Any HTTP response below 500
+// counts as healthy: the base URL itself may well answer 404.
+type HealthChecker struct {
+	url          string
+	interval     time.Duration
+	failureLimit int
+	client       *http.Client
+
+	healthy     bool
+	failures    int
+	onRecover   []func()
+	onUnhealthy []func(failures int)
+	mu          sync.RWMutex
+}
+
+// NewHealthChecker creates a checker for the given URL. The backend is assumed
+// healthy until the first failed pings.
+func NewHealthChecker(url string, interval time.Duration, failureLimit int) *HealthChecker {
+	if interval <= 0 {
+		interval = DefaultHealthInterval
+	}
+	if failureLimit <= 0 {
+		failureLimit = DefaultHealthFailureLimit
+	}
+	return &HealthChecker{
+		url:          url,
+		interval:     interval,
+		failureLimit: failureLimit,
+		client:       &http.Client{Timeout: healthPingTimeout},
+		healthy:      true,
+	}
+}
+
+// SetTransport shares the API client's transport (TLS settings) with the pings
+func (h *HealthChecker) SetTransport(transport http.RoundTripper) {
+	h.client.Transport = transport
+}
+
+// OnRecover registers a callback run when the backend becomes healthy again
+func (h *HealthChecker) OnRecover(fn func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onRecover = append(h.onRecover, fn)
+}
+
+// OnUnhealthy registers a callback run when the backend is marked unhealthy,
+// with the number of consecutive failed pings
+func (h *HealthChecker) OnUnhealthy(fn func(failures int)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onUnhealthy = append(h.onUnhealthy, fn)
+}
+
+// Start pings until stop is closed
+func (h *HealthChecker) Start(stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		h.Check()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				h.Check()
+			}
+		}
+	}()
+}
+
+// Check pings the backend once and updates the state
+func (h *HealthChecker) Check() bool {
+	ok := h.ping()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ok {
+		if !h.healthy {
+			logger.Info("Backend is healthy again", "url", h.url)
+			for _, fn := range h.onRecover {
+				go fn()
+			}
+		}
+		h.failures = 0
+		h.healthy = true
+		return true
+	}
+
+	h.failures++
+	if h.healthy && h.failures >= h.failureLimit {
+		logger.Warn("Backend unhealthy", "url", h.url, "failed_pings", h.failures)
+		h.healthy = false
+		for _, fn := range h.onUnhealthy {
+			go fn(h.failures)
+		}
+	}
+	return h.healthy
+}
+
+// Healthy reports the last known backend state
+func (h *HealthChecker) Healthy() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.healthy
+}
+
+func (h *HealthChecker) ping() bool {
+	resp, err := h.client.Get(h.url)
+	if err != nil {
+		logger.Warn("Health ping failed", "err", err)
+		return false
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		logger.Warn("Health ping failed", "status", resp.StatusCode)
+		return false
+	}
+	return true
+}
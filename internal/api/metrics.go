@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// METRICS - Backend latency and error rate per endpoint
+// ============================================================
+
+// latencyBuckets are the histogram upper bounds in seconds
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// DefaultMetrics collects the metrics of every API and gRPC client
+var DefaultMetrics = NewMetrics()
+
+type metricKey struct {
+	endpoint string
+	class    string // "2xx", "4xx", "5xx" or "error" for transport failures
+}
+
+type latencySeries struct {
+	buckets []uint64 // Cumulative counts per latencyBuckets bound
+	count   uint64
+	sum     float64
+}
+
+// Metrics counts requests and records latency histograms per endpoint and
+// status class, exported in the Prometheus text format
+type Metrics struct {
+	series map[metricKey]*latencySeries
+	mu     sync.Mutex
+}
+
+// NewMetrics creates an empty registry
+func NewMetrics() *Metrics {
+	return &Metrics{series: make(map[metricKey]*latencySeries)}
+}
+
+// Observe records one request. statusCode is ignored when err is set.
+func (m *Metrics) Observe(endpoint string, statusCode int, err error, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	key := metricKey{endpoint: endpoint, class: statusClass(statusCode, err)}
+	seconds := duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.series[key]
+	if !ok {
+		s = &latencySeries{buckets: make([]uint64, len(latencyBuckets))}
+		m.series[key] = s
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			s.buckets[i]++
+		}
+	}
+	s.count++
+	s.sum += seconds
+}
+
+// WritePrometheus writes all series in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]metricKey, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].endpoint != keys[j].endpoint {
+			return keys[i].endpoint < keys[j].endpoint
+		}
+		return keys[i].class < keys[j].class
+	})
+
+	fmt.Fprintln(w, "# HELP checkin_api_requests_total Requests to the check-in backend.")
+	fmt.Fprintln(w, "# TYPE checkin_api_requests_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "checkin_api_requests_total{%s} %d\n", key.labels(), m.series[key].count)
+	}
+
+	fmt.Fprintln(w, "# HELP checkin_api_request_duration_seconds Latency of requests to the check-in backend.")
+	fmt.Fprintln(w, "# TYPE checkin_api_request_duration_seconds histogram")
+	for _, key := range keys {
+		s := m.series[key]
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(w, "checkin_api_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				key.labels(), strconv.FormatFloat(bound, 'g', -1, 64), s.buckets[i])
+		}
+		fmt.Fprintf(w, "checkin_api_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", key.labels(), s.count)
+		fmt.Fprintf(w, "checkin_api_request_duration_seconds_sum{%s} %g\n", key.labels(), s.sum)
+		fmt.Fprintf(w, "checkin_api_request_duration_seconds_count{%s} %d\n", key.labels(), s.count)
+	}
+}
+
+// Handler serves the metrics for a /metrics endpoint
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.WritePrometheus(w)
+	})
+}
+
+func (k metricKey) labels() string {
+	return fmt.Sprintf("endpoint=%q,status=%q", k.endpoint, k.class)
+}
+
+func statusClass(statusCode int, err error) string {
+	if err != nil || statusCode == 0 {
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", statusCode/100)
+}
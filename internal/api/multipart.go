@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+)
+
+// ============================================================
+// MULTIPART UPLOAD - Raw binary parts instead of base64 in JSON
+// ============================================================
+
+// MultipartFile is one binary part of a multipart/form-data request
+type MultipartFile struct {
+	Field       string // Form field name, e.g. "imgs"
+	FileName    string
+	ContentType string // Defaults to application/octet-stream
+	Data        []byte
+}
+
+// SendMultipart sends a multipart/form-data POST. Without a signer the body is
+// streamed straight from the file buffers; with one it is built in memory first
+// since the signature covers the body digest.
+func (c *APIClient) SendMultipart(endpoint string, fields map[string]string, files []MultipartFile) ([]byte, int, error) {
+	return c.SendMultipartContext(context.Background(), endpoint, fields, files)
+}
+
+// SendMultipartContext is SendMultipart tagged with the call ID of ctx, if any
+func (c *APIClient) SendMultipartContext(ctx context.Context, endpoint string, fields map[string]string, files []MultipartFile) ([]byte, int, error) {
+	var (
+		req *http.Request
+		err error
+	)
+
+	if c.signer != nil {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		if err := writeMultipart(writer, fields, files); err != nil {
+			return nil, 0, err
+		}
+
+		req, err = http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to create request: %w", err)
+		}
+		if err := c.setHeaders(req, body.Bytes()); err != nil {
+			return nil, 0, err
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+	} else {
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		go func() {
+			pw.CloseWithError(writeMultipart(writer, fields, files))
+		}()
+
+		req, err = http.NewRequestWithContext(ctx, "POST", endpoint, pr)
+		if err != nil {
+			pr.Close()
+			return nil, 0, fmt.Errorf("failed to create request: %w", err)
+		}
+		if err := c.setHeaders(req, nil); err != nil {
+			pr.Close()
+			return nil, 0, err
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+	}
+
+	logger.Debug("Request multipart", "endpoint", endpoint, "call_id", CallIDFromContext(ctx),
+		"fields", len(fields), "files", len(files), "size_kb", float64(multipartSize(files))/1024.0)
+
+	body, statusCode, err := c.do(req)
+	if err != nil {
+		return body, statusCode, err
+	}
+
+	logger.Debug("Response body", "endpoint", endpoint, "body", string(body))
+
+	return body, statusCode, nil
+}
+
+// writeMultipart writes the fields and files and closes the writer
+func writeMultipart(writer *multipart.Writer, fields map[string]string, files []MultipartFile) error {
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return fmt.Errorf("failed to write field %s: %w", name, err)
+		}
+	}
+
+	for _, file := range files {
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition",
+			fmt.Sprintf(`form-data; name=%q; filename=%q`, file.Field, file.FileName))
+		header.Set("Content-Type", contentType)
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return fmt.Errorf("failed to create part %s: %w", file.FileName, err)
+		}
+		if _, err := part.Write(file.Data); err != nil {
+			return fmt.Errorf("failed to write part %s: %w", file.FileName, err)
+		}
+	}
+
+	return writer.Close()
+}
+
+func multipartSize(files []MultipartFile) int {
+	total := 0
+	for _, file := range files {
+		total += len(file.Data)
+	}
+	return total
+}
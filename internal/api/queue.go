@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// SUBMISSION QUEUE - Disk-backed retry of submissions to the backend
+// ============================================================
+
+const DefaultQueueRetryInterval = 30 * time.Second
+
+// ErrQueued is returned by SubmitOrQueue when the backend was unreachable and
+// the submission was stored for replay
+var ErrQueued = errors.New("submission queued for retry")
+
+// QueuedSubmission is one pending request, stored as its own JSON file
+type QueuedSubmission struct {
+	Key       string          `json:"key"` // Dedup key: one pending submission per key
+	Endpoint  string          `json:"endpoint"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	Attempts  int             `json:"attempts"`
+	CallID    string          `json:"call_id,omitempty"` // Call that produced the submission
+}
+
+// SubmissionQueue stores submissions that failed because the backend was
+// down and replays them in order once it answers again. Only idempotent
+// submissions belong here: a replay may follow an attempt that actually
+// reached the backend. Recognition requests to APICheckIn are not queued:
+// the call needs the recognition answer while the user is on the line.
+type SubmissionQueue struct {
+	dir      string
+	client   *APIClient
+	mu       sync.Mutex // Guards the files, never held while sending
+	draining sync.Mutex // One Drain at a time, so replays stay in order
+	active   func() bool
+}
+
+// NewSubmissionQueue opens (or creates) the queue directory
+func NewSubmissionQueue(dir string, client *APIClient) (*SubmissionQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	q := &SubmissionQueue{dir: dir, client: client}
+	if pending := q.Len(); pending > 0 {
+		logger.Info("Queued submissions waiting for replay", "pending", pending)
+	}
+	return q, nil
+}
+
+// SubmitOrQueue sends the request; on a transport error, 5xx or rate limit
+// response the submission is queued and ErrQueued is returned. Other 4xx
+// responses are returned as-is since retrying would not help.
+func (q *SubmissionQueue) SubmitOrQueue(ctx context.Context, key, endpoint string, payload interface{}) ([]byte, int, error) {
+	body, statusCode, err := q.client.SendRequestContext(ctx, payload, endpoint)
+	if !retryable(statusCode, err) {
+		return body, statusCode, nil
+	}
+
+	if err != nil {
+		logger.Warn("Submission failed", "key", key, "err", err)
+	} else {
+		logger.Warn("Submission failed", "key", key, "status", statusCode)
+	}
+
+	if qerr := q.Enqueue(ctx, key, endpoint, payload); qerr != nil {
+		return body, statusCode, fmt.Errorf("failed to queue submission: %w", qerr)
+	}
+	return body, statusCode, ErrQueued
+}
+
+// Enqueue stores a submission. A pending submission with the same key is
+// replaced by the newer payload.
+func (q *SubmissionQueue) Enqueue(ctx context.Context, key, endpoint string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry := QueuedSubmission{
+		Key:       key,
+		Endpoint:  endpoint,
+		Payload:   data,
+		CreatedAt: time.Now(),
+		CallID:    CallIDFromContext(ctx),
+	}
+	if existing, err := q.read(q.path(key)); err == nil {
+		entry.CreatedAt = existing.CreatedAt // Keep the original position
+		entry.Attempts = existing.Attempts
+	}
+
+	if err := q.write(entry); err != nil {
+		return err
+	}
+	logger.Info("Queued submission", "key", key, "endpoint", endpoint)
+	return nil
+}
+
+// Len returns the number of pending submissions
+func (q *SubmissionQueue) Len() int {
+	files, _ := filepath.Glob(filepath.Join(q.dir, "*.json"))
+	return len(files)
+}
+
+// Start replays the queue every interval until stop is closed
+func (q *SubmissionQueue) Start(interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		interval = DefaultQueueRetryInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				q.Drain()
+			}
+		}
+	}()
+}
+
+// SetActive makes Drain replay only while active returns true. Two
+// instances sharing the directory both enqueue, only the leader replays.
+func (q *SubmissionQueue) SetActive(active func() bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.active = active
+}
+
+// Drain replays pending submissions oldest first and stops at the first one
+// the backend still can't take. The queue stays open to Enqueue while the
+// requests are in flight.
+func (q *SubmissionQueue) Drain() {
+	if !q.draining.TryLock() {
+		return
+	}
+	defer q.draining.Unlock()
+
+	q.mu.Lock()
+	if q.active != nil && !q.active() {
+		q.mu.Unlock()
+		return
+	}
+	entries := q.list()
+	q.mu.Unlock()
+	if len(entries) == 0 {
+		return
+	}
+
+	logger.Info("Replaying queued submissions", "pending", len(entries))
+
+	for i, entry := range entries {
+		ctx := WithCallID(context.Background(), entry.CallID)
+		body, statusCode, err := q.client.SendRequestContext(ctx, entry.Payload, entry.Endpoint)
+		if retryable(statusCode, err) {
+			q.mu.Lock()
+			if current, rerr := q.read(q.path(entry.Key)); rerr == nil {
+				current.Attempts++
+				if werr := q.write(current); werr != nil {
+					logger.Warn("Failed to update queued submission", "key", entry.Key, "err", werr)
+				}
+			}
+			q.mu.Unlock()
+			logger.Warn("Backend still unavailable", "pending", len(entries)-i)
+			return
+		}
+
+		q.client.LogResponse(body, statusCode)
+		if !q.client.IsSuccessStatusCode(statusCode) {
+			logger.Error("Dropping queued submission", "key", entry.Key, "status", statusCode)
+		} else {
+			logger.Info("Replayed queued submission", "key", entry.Key)
+		}
+
+		q.mu.Lock()
+		q.removeIfUnchanged(entry)
+		q.mu.Unlock()
+	}
+}
+
+// removeIfUnchanged deletes the replayed entry unless Enqueue replaced it
+// with a newer payload meanwhile. Caller holds q.mu.
+func (q *SubmissionQueue) removeIfUnchanged(sent QueuedSubmission) {
+	path := q.path(sent.Key)
+	current, err := q.read(path)
+	if err != nil {
+		return
+	}
+	if !bytes.Equal(current.Payload, sent.Payload) {
+		logger.Info("Queued submission replaced during replay, kept", "key", sent.Key)
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		logger.Warn("Failed to remove queued submission", "key", sent.Key, "err", err)
+	}
+}
+
+// retryable reports whether a failed submission may succeed later
+func retryable(statusCode int, err error) bool {
+	return err != nil || statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
+
+// list returns the pending submissions oldest first. Caller holds q.mu.
+func (q *SubmissionQueue) list() []QueuedSubmission {
+	files, _ := filepath.Glob(filepath.Join(q.dir, "*.json"))
+
+	entries := make([]QueuedSubmission, 0, len(files))
+	for _, file := range files {
+		entry, err := q.read(file)
+		if err != nil {
+			logger.Warn("Skipping unreadable queued submission", "file", file, "err", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries
+}
+
+func (q *SubmissionQueue) read(path string) (QueuedSubmission, error) {
+	var entry QueuedSubmission
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal(data, &entry)
+	return entry, err
+}
+
+// write stores the entry atomically. Caller holds q.mu.
+func (q *SubmissionQueue) write(entry QueuedSubmission) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queued submission: %w", err)
+	}
+
+	path := q.path(entry.Key)
+	if err := utils.WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write queued submission: %w", err)
+	}
+	return nil
+}
+
+// path maps a dedup key to a file name safe on any filesystem
+func (q *SubmissionQueue) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(q.dir, hex.EncodeToString(sum[:8])+".json")
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// REQUEST SIGNING - Per-request HMAC instead of a static secret header
+// ============================================================
+
+const (
+	HeaderKeyID     = "X-Key-Id"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+// RequestSigner signs requests with HMAC-SHA256 over
+//
+//	METHOD \n REQUEST URI \n TIMESTAMP \n NONCE \n hex(SHA256(body))
+//
+// where REQUEST URI is the path with its query string, e.g.
+// /employees/bot/check-in?attempt=2 (req.URL.RequestURI()).
+// The backend rejects stale timestamps and reused nonces, so a logged request
+// cannot be replayed. Several keys can be loaded for rotation; only the
+// active one signs. Keys are rotated by editing the key file (WatchKeyFile).
+type RequestSigner struct {
+	keys     map[string][]byte
+	activeID string
+	mu       sync.RWMutex
+}
+
+// NewRequestSigner creates a signer with the given key ID -> secret map
+func NewRequestSigner(keys map[string]string, activeID string) (*RequestSigner, error) {
+	s := &RequestSigner{}
+	if err := s.SetKeys(keys, activeID); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SigningKeyFile is the JSON key file read by LoadSigningKeyFile
+type SigningKeyFile struct {
+	ActiveKeyID string            `json:"active_key_id"`
+	Keys        map[string]string `json:"keys"` // Key ID -> secret
+}
+
+// LoadSigningKeyFile reads a key file. Rotating means adding the next key,
+// pointing active_key_id at it once the backend knows it, and later removing
+// the old one.
+func LoadSigningKeyFile(path string) (SigningKeyFile, error) {
+	var file SigningKeyFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, fmt.Errorf("failed to read signing key file: %w", err)
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("failed to parse signing key file: %w", err)
+	}
+	return file, nil
+}
+
+// ParseSigningKeys parses "id1:secret1,id2:secret2"
+func ParseSigningKeys(value string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(part, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("invalid signing key entry %q (expected id:secret)", part)
+		}
+		keys[id] = secret
+	}
+	return keys, nil
+}
+
+// SetKeys replaces the loaded keys and the active one. On error the current
+// keys stay in use.
+func (s *RequestSigner) SetKeys(keys map[string]string, activeID string) error {
+	if _, exists := keys[activeID]; !exists {
+		return fmt.Errorf("signing key %q not loaded", activeID)
+	}
+
+	loaded := make(map[string][]byte, len(keys))
+	for id, secret := range keys {
+		if secret == "" {
+			return fmt.Errorf("signing key %q has no secret", id)
+		}
+		loaded[id] = []byte(secret)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = loaded
+	s.activeID = activeID
+	return nil
+}
+
+// ActiveKeyID returns the ID of the key that signs
+func (s *RequestSigner) ActiveKeyID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.activeID
+}
+
+// WatchKeyFile reloads the keys whenever the file changes, checking every
+// interval until stop is closed. A broken file is logged and ignored.
+func (s *RequestSigner) WatchKeyFile(path string, interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var lastMod time.Time
+		if info, err := os.Stat(path); err == nil {
+			lastMod = info.ModTime()
+		}
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				logger.Warn("Failed to check signing key file", "err", err)
+				continue
+			}
+			if info.ModTime().Equal(lastMod) {
+				continue
+			}
+			lastMod = info.ModTime()
+
+			previous := s.ActiveKeyID()
+			file, err := LoadSigningKeyFile(path)
+			if err == nil {
+				err = s.SetKeys(file.Keys, file.ActiveKeyID)
+			}
+			if err != nil {
+				logger.Error("Signing key file rejected, keeping current keys", "err", err)
+				continue
+			}
+			logger.Info("Signing keys reloaded", "key_id", file.ActiveKeyID, "previous_key_id", previous, "keys", len(file.Keys))
+		}
+	}()
+}
+
+// Sign adds the signature headers to the request
+func (s *RequestSigner) Sign(req *http.Request, body []byte) error {
+	headers, err := s.signature(req.Method, req.URL.RequestURI(), body)
+	if err != nil {
+		return err
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	return nil
+}
+
+// signature returns the signature headers of a request
+func (s *RequestSigner) signature(method, requestURI string, body []byte) (http.Header, error) {
+	s.mu.RLock()
+	keyID := s.activeID
+	key := s.keys[keyID]
+	s.mu.RUnlock()
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonceHex := hex.EncodeToString(nonce)
+	bodyDigest := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s",
+		method, requestURI, timestamp, nonceHex, hex.EncodeToString(bodyDigest[:]))
+
+	headers := http.Header{}
+	headers.Set(HeaderKeyID, keyID)
+	headers.Set(HeaderTimestamp, timestamp)
+	headers.Set(HeaderNonce, nonceHex)
+	headers.Set(HeaderSignature, hex.EncodeToString(mac.Sum(nil)))
+	return headers, nil
+}
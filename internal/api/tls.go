@@ -0,0 +1,96 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ============================================================
+// TLS CONFIG - Client certificates and custom CA for the backend
+// ============================================================
+
+// TLSOptions configures TLS to the backend. Empty fields keep Go's defaults.
+type TLSOptions struct {
+	CertFile   string // Client certificate (PEM) for mutual TLS
+	KeyFile    string // Client private key (PEM)
+	CAFile     string // CA bundle (PEM) added to the system roots
+	MinVersion string // "1.2" or "1.3"
+}
+
+// Enabled reports whether any TLS option is set
+func (o TLSOptions) Enabled() bool {
+	return o.CertFile != "" || o.KeyFile != "" || o.CAFile != "" || o.MinVersion != ""
+}
+
+// BuildTLSConfig loads the certificates into a tls.Config
+func BuildTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	switch opts.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		cfg.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported TLS min version %q", opts.MinVersion)
+	}
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be set together")
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
+
+// ConfigureTLS applies the TLS options to the client's transport
+func (c *APIClient) ConfigureTLS(opts TLSOptions) error {
+	if !opts.Enabled() {
+		return nil
+	}
+
+	tlsConfig, err := BuildTLSConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	c.client.Transport = transport
+	c.tlsConfig = tlsConfig
+
+	logger.Info("API TLS configured", "client_cert", opts.CertFile != "", "custom_ca", opts.CAFile != "")
+	return nil
+}
+
+// Transport returns the client's transport, for other HTTP clients talking
+// to the same backend
+func (c *APIClient) Transport() http.RoundTripper {
+	if c.client.Transport == nil {
+		return http.DefaultTransport
+	}
+	return c.client.Transport
+}
@@ -0,0 +1,43 @@
+package audio
+
+import "time"
+
+// ============================================================
+// PLAYBACK CLOCK - Pacing theo timestamp mục tiêu, không cộng dồn drift
+// ============================================================
+
+// maxPlaybackLag: trễ quá mức này (GC, CPU bận, ...) thì dời mốc thay vì
+// gửi dồn một loạt packet để đuổi kịp
+const maxPlaybackLag = 200 * time.Millisecond
+
+// playbackClock tính thời điểm phát của mỗi packet từ một mốc cố định:
+// target = start + tổng duration đã phát. Sai số của mỗi lần sleep
+// không bị cộng dồn như khi sleep theo từng packet
+type playbackClock struct {
+	start   time.Time
+	elapsed time.Duration
+}
+
+func newPlaybackClock() *playbackClock {
+	return &playbackClock{start: time.Now()}
+}
+
+// wait chờ đến thời điểm phát của packet tiếp theo rồi cộng duration của nó
+func (c *playbackClock) wait(d time.Duration) {
+	target := c.start.Add(c.elapsed)
+	now := time.Now()
+
+	if lag := now.Sub(target); lag > maxPlaybackLag {
+		// Jitter compensation: dời mốc để phát tiếp từ hiện tại
+		c.start = c.start.Add(lag)
+	} else if lag < 0 {
+		time.Sleep(-lag)
+	}
+
+	c.elapsed += d
+}
+
+// reset đặt lại mốc, dùng sau khi pause
+func (c *playbackClock) reset() {
+	c.start = time.Now().Add(-c.elapsed)
+}
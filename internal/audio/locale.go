@@ -0,0 +1,174 @@
+package audio
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ============================================================
+// LANGUAGE PACKS - Chọn audio theo ngôn ngữ của user
+// ============================================================
+
+const (
+	LocaleVI      = "vi"
+	LocaleEN      = "en"
+	DefaultLocale = LocaleVI
+)
+
+// packFiles ánh xạ tên audio -> tên file (không đuôi) trong thư mục language pack
+var packFiles = map[string]string{
+	"welcome":          "welcome",
+	"checkin_success":  "checkin-success",
+	"checkin_fail":     "checkin-failed",
+	"checkout_success": "checkout-success",
+
+	"stage_" + StageScanning:     StageScanning,
+	"stage_" + StageLookStraight: StageLookStraight,
+	"stage_" + StageSendLocation: StageSendLocation,
+}
+
+// Đuôi file được chấp nhận trong language pack, theo thứ tự ưu tiên
+var packExtensions = []string{".ogg", ".opus", ".wav", ".mp3"}
+
+// RegisterLocale đăng ký audio cho một ngôn ngữ cụ thể
+func (al *AudioLibrary) RegisterLocale(locale, name, filePath string) error {
+	return al.Register(localeKey(locale, name), filePath)
+}
+
+// GetLocale lấy audio theo ngôn ngữ, không có thì dùng bản mặc định
+func (al *AudioLibrary) GetLocale(locale, name string) (string, bool) {
+	if locale != "" {
+		if path, ok := al.Get(localeKey(locale, name)); ok {
+			return path, true
+		}
+	}
+	return al.Get(name)
+}
+
+// RegisterPack đăng ký toàn bộ prompt trong thư mục language pack.
+// Trả về số file đã đăng ký
+func (al *AudioLibrary) RegisterPack(locale, dir string) int {
+	count := 0
+	for name, base := range packFiles {
+		for _, ext := range packExtensions {
+			path := filepath.Join(dir, base+ext)
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			if err := al.RegisterLocale(locale, name, path); err != nil {
+				logger.Warn("Failed to register localized audio", "locale", locale, "name", name, "err", err)
+			} else {
+				count++
+			}
+			break
+		}
+	}
+	return count
+}
+
+func localeKey(locale, name string) string {
+	return locale + "/" + name
+}
+
+// ============================================================
+// LOCALE RESOLVER - Ngôn ngữ theo user
+// ============================================================
+
+// LocaleResolver xác định ngôn ngữ của user: ưu tiên giá trị lấy từ profile
+// Mezon (SetProfileLocale), sau đó file mapping, cuối cùng là mặc định
+type LocaleResolver struct {
+	defaultLocale string
+	mapping       map[int64]string
+	profile       map[int64]string
+	mu            sync.RWMutex
+}
+
+// userLocalesFile là format file mapping, VD:
+// {"default": "vi", "users": {"1840651530236071936": "en"}}
+type userLocalesFile struct {
+	Default string            `json:"default"`
+	Users   map[string]string `json:"users"`
+}
+
+// NewLocaleResolver đọc file mapping (nếu có)
+func NewLocaleResolver(mappingPath, defaultLocale string) (*LocaleResolver, error) {
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+
+	r := &LocaleResolver{
+		defaultLocale: defaultLocale,
+		mapping:       make(map[int64]string),
+		profile:       make(map[int64]string),
+	}
+
+	if mappingPath == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(mappingPath)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user locales file: %w", err)
+	}
+
+	var file userLocalesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse user locales JSON: %w", err)
+	}
+
+	if file.Default != "" {
+		r.defaultLocale = normalizeLocale(file.Default)
+	}
+	for id, locale := range file.Users {
+		userID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			logger.Warn("Invalid user ID in locales file", "user_id", id)
+			continue
+		}
+		r.mapping[userID] = normalizeLocale(locale)
+	}
+
+	logger.Info("Loaded user locales", "count", len(r.mapping), "default", r.defaultLocale)
+	return r, nil
+}
+
+// SetProfileLocale ghi nhận ngôn ngữ từ profile Mezon (lang_tag) của user
+func (r *LocaleResolver) SetProfileLocale(userID int64, langTag string) {
+	if langTag == "" {
+		return
+	}
+	r.mu.Lock()
+	r.profile[userID] = normalizeLocale(langTag)
+	r.mu.Unlock()
+}
+
+// Resolve trả về ngôn ngữ của user
+func (r *LocaleResolver) Resolve(userID int64) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if locale, ok := r.profile[userID]; ok {
+		return locale
+	}
+	if locale, ok := r.mapping[userID]; ok {
+		return locale
+	}
+	return r.defaultLocale
+}
+
+// normalizeLocale chuyển lang tag dạng "en-US" / "vi_VN" về "en" / "vi"
+func normalizeLocale(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i > 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
@@ -0,0 +1,387 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media"
+	"github.com/pion/webrtc/v4/pkg/media/oggreader"
+)
+
+// ============================================================
+// DUCKING MIXER - Trộn nhạc nền với prompt, giảm âm lượng nhạc khi có prompt
+// ============================================================
+
+const (
+	mixSampleRate    = 48000
+	mixChannels      = 2
+	mixFrameDuration = 20 * time.Millisecond
+	mixFrameSamples  = mixSampleRate / 50 // 20ms
+	mixFrameBytes    = mixFrameSamples * mixChannels * 2
+
+	DefaultDuckingLevel = 0.2 // Âm lượng nhạc nền khi đang có prompt
+	duckingFadeFrames   = 10  // 200ms fade in/out
+)
+
+// pcmStream giải mã một file audio sang PCM s16le 48kHz stereo bằng ffmpeg
+type pcmStream struct {
+	cmd *exec.Cmd
+	out io.ReadCloser
+}
+
+func openPCMStream(path string) (*pcmStream, error) {
+	cmd := exec.Command("ffmpeg",
+		"-loglevel", "error", "-nostdin",
+		"-i", path,
+		"-f", "s16le",
+		"-ar", fmt.Sprint(mixSampleRate),
+		"-ac", fmt.Sprint(mixChannels),
+		"pipe:1",
+	)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start decoder: %w", err)
+	}
+	return &pcmStream{cmd: cmd, out: out}, nil
+}
+
+// readFrame đọc một frame 20ms vào buf. Phần thiếu ở cuối file được
+// điền 0; trả về false khi đã hết dữ liệu
+func (s *pcmStream) readFrame(buf []byte) bool {
+	n, err := io.ReadFull(s.out, buf)
+	if err != nil {
+		for i := n; i < len(buf); i++ {
+			buf[i] = 0
+		}
+		return n > 0
+	}
+	return true
+}
+
+func (s *pcmStream) close() {
+	s.out.Close()
+	if s.cmd.Process != nil {
+		s.cmd.Process.Kill()
+	}
+	s.cmd.Wait()
+}
+
+// duckingMixer sở hữu track khi nhạc nền đang phát: mỗi 20ms trộn một frame
+// nhạc (nhân với gain) và một frame prompt, encode lại Opus bằng ffmpeg.
+// mu chỉ bảo vệ các field, không giữ khi đọc ffmpeg hay mở/đóng process
+type duckingMixer struct {
+	track     *webrtc.TrackLocalStaticSample
+	stopChan  chan struct{}
+	duckLevel float64
+
+	mu         sync.Mutex
+	musicPath  string
+	music      *pcmStream
+	prompt     *pcmStream
+	promptDone chan struct{}
+	promptErr  error
+	paused     bool
+	gain       float64
+
+	encoder *exec.Cmd
+	encIn   io.WriteCloser
+}
+
+func newDuckingMixer(track *webrtc.TrackLocalStaticSample, stopChan chan struct{}, duckLevel float64) (*duckingMixer, error) {
+	if duckLevel < 0 || duckLevel > 1 {
+		duckLevel = DefaultDuckingLevel
+	}
+
+	encoder := exec.Command("ffmpeg",
+		"-loglevel", "error", "-nostdin",
+		"-f", "s16le",
+		"-ar", fmt.Sprint(mixSampleRate),
+		"-ac", fmt.Sprint(mixChannels),
+		"-i", "pipe:0",
+		"-c:a", "libopus",
+		"-b:a", "48k",
+		"-frame_duration", "20",
+		"-page_duration", "20000", // 1 packet / page
+		"-flush_packets", "1",
+		"-f", "ogg",
+		"pipe:1",
+	)
+	encIn, err := encoder.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	encOut, err := encoder.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := encoder.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start encoder: %w", err)
+	}
+
+	m := &duckingMixer{
+		track:     track,
+		stopChan:  stopChan,
+		duckLevel: duckLevel,
+		gain:      1,
+		encoder:   encoder,
+		encIn:     encIn,
+	}
+
+	go m.mixLoop()
+	go m.writeLoop(encOut)
+
+	logger.Info("Ducking mixer started", "level", duckLevel)
+	return m, nil
+}
+
+// setMusic đổi nhạc nền (lặp vô hạn)
+func (m *duckingMixer) setMusic(path string) error {
+	stream, err := openPCMStream(path)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	old := m.music
+	m.musicPath = path
+	m.music = stream
+	m.mu.Unlock()
+
+	if old != nil {
+		old.close()
+	}
+	return nil
+}
+
+// playPrompt phát prompt trên nhạc nền và chờ đến khi phát xong
+func (m *duckingMixer) playPrompt(path string) error {
+	stream, err := openPCMStream(path)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+
+	m.mu.Lock()
+	old := m.prompt
+	if old != nil {
+		close(m.promptDone)
+	}
+	m.prompt = stream
+	m.promptDone = done
+	m.promptErr = io.EOF
+	m.mu.Unlock()
+
+	if old != nil {
+		old.close()
+	}
+
+	select {
+	case <-done:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.promptErr
+	case <-m.stopChan:
+		return fmt.Errorf("stopped")
+	}
+}
+
+// setPromptPaused dừng/tiếp tục đọc prompt; nhạc nền vẫn chạy
+func (m *duckingMixer) setPromptPaused(paused bool) {
+	m.mu.Lock()
+	m.paused = paused
+	m.mu.Unlock()
+}
+
+// skipPrompt kết thúc prompt đang phát
+func (m *duckingMixer) skipPrompt() {
+	m.mu.Lock()
+	prompt := m.prompt
+	if prompt == nil {
+		m.mu.Unlock()
+		return
+	}
+	m.prompt = nil
+	m.promptErr = errSkipped
+	close(m.promptDone)
+	m.promptDone = nil
+	m.mu.Unlock()
+
+	prompt.close()
+}
+
+func (m *duckingMixer) mixLoop() {
+	ticker := time.NewTicker(mixFrameDuration)
+	defer ticker.Stop()
+	defer m.shutdown()
+
+	musicBuf := make([]byte, mixFrameBytes)
+	promptBuf := make([]byte, mixFrameBytes)
+	out := make([]byte, mixFrameBytes)
+
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		m.mu.Lock()
+		music, prompt := m.music, m.prompt
+		if m.paused {
+			prompt = nil
+		}
+		m.mu.Unlock()
+
+		hasMusic := m.readMusic(music, musicBuf)
+		hasPrompt := m.readPrompt(prompt, promptBuf)
+
+		m.mu.Lock()
+		// Fade gain về mức duck khi có prompt, về 1 khi hết
+		target := 1.0
+		if hasPrompt {
+			target = m.duckLevel
+		}
+		step := (1 - m.duckLevel) / duckingFadeFrames
+		switch {
+		case m.gain > target:
+			m.gain = max(target, m.gain-step)
+		case m.gain < target:
+			m.gain = min(target, m.gain+step)
+		}
+		gain := m.gain
+		m.mu.Unlock()
+
+		for i := 0; i < mixFrameBytes; i += 2 {
+			var sample float64
+			if hasMusic {
+				sample += float64(int16(binary.LittleEndian.Uint16(musicBuf[i:]))) * gain
+			}
+			if hasPrompt {
+				sample += float64(int16(binary.LittleEndian.Uint16(promptBuf[i:])))
+			}
+			sample = max(-32768, min(32767, sample))
+			binary.LittleEndian.PutUint16(out[i:], uint16(int16(sample)))
+		}
+
+		if _, err := m.encIn.Write(out); err != nil {
+			logger.Error("Mixer encoder write failed", "err", err)
+			return
+		}
+	}
+}
+
+// readMusic đọc frame nhạc, mở lại file khi hết để lặp (không giữ mu)
+func (m *duckingMixer) readMusic(music *pcmStream, buf []byte) bool {
+	if music == nil {
+		return false
+	}
+	if music.readFrame(buf) {
+		return true
+	}
+
+	m.mu.Lock()
+	path := m.musicPath
+	m.mu.Unlock()
+	stream, err := openPCMStream(path)
+	if err != nil {
+		logger.Error("Cannot loop background music", "err", err)
+	}
+
+	// setMusic/shutdown có thể đã thay stream trong lúc đọc
+	m.mu.Lock()
+	owned := m.music == music
+	if owned {
+		m.music = stream
+	}
+	m.mu.Unlock()
+
+	if !owned {
+		if stream != nil {
+			stream.close()
+		}
+		return false
+	}
+	music.close()
+	return stream != nil && stream.readFrame(buf)
+}
+
+// readPrompt đọc frame prompt, báo hoàn tất khi hết (không giữ mu)
+func (m *duckingMixer) readPrompt(prompt *pcmStream, buf []byte) bool {
+	if prompt == nil {
+		return false
+	}
+	if prompt.readFrame(buf) {
+		return true
+	}
+
+	m.mu.Lock()
+	owned := m.prompt == prompt
+	if owned {
+		m.prompt = nil
+		close(m.promptDone)
+		m.promptDone = nil
+	}
+	m.mu.Unlock()
+
+	if owned {
+		prompt.close()
+	}
+	return false
+}
+
+// writeLoop đọc Opus từ encoder và ghi vào track
+func (m *duckingMixer) writeLoop(encOut io.Reader) {
+	ogg, _, err := oggreader.NewWith(encOut)
+	if err != nil {
+		logger.Error("Mixer cannot read encoder output", "err", err)
+		return
+	}
+
+	for {
+		pageData, _, err := ogg.ParseNextPage()
+		if err != nil {
+			return
+		}
+		if bytes.HasPrefix(pageData, []byte("OpusTags")) {
+			continue
+		}
+		if err := m.track.WriteSample(media.Sample{
+			Data:     pageData,
+			Duration: mixFrameDuration,
+		}); err != nil {
+			return
+		}
+	}
+}
+
+func (m *duckingMixer) shutdown() {
+	m.mu.Lock()
+	music, prompt := m.music, m.prompt
+	m.music = nil
+	if prompt != nil {
+		m.prompt = nil
+		close(m.promptDone)
+		m.promptDone = nil
+	}
+	m.mu.Unlock()
+
+	if music != nil {
+		music.close()
+	}
+	if prompt != nil {
+		prompt.close()
+	}
+
+	m.encIn.Close()
+	m.encoder.Wait()
+	logger.Info("Ducking mixer stopped")
+}
@@ -4,9 +4,10 @@
 package audio
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"mezon-checkin-bot/internal/logging"
 	"os"
 	"sync"
 	"time"
@@ -16,12 +17,15 @@ import (
 	"github.com/pion/webrtc/v4/pkg/media/oggreader"
 )
 
+var logger = logging.For("audio")
+
 // AudioItem đại diện cho một file audio cần phát
 type AudioItem struct {
-	FilePath string // Đường dẫn file OGG
-	Name     string // Tên để log (VD: "greeting", "checkin_success")
-	Loop     bool   // true = lặp lại, false = phát 1 lần
-	OnFinish func() // Callback khi phát xong (optional)
+	FilePath string  // Đường dẫn file OGG
+	Name     string  // Tên để log (VD: "greeting", "checkin_success")
+	Loop     bool    // true = lặp lại, false = phát 1 lần
+	OnFinish func()  // Callback khi phát xong (optional)
+	Gain     float64 // Hệ số âm lượng (1 = giữ nguyên, 0.5 = nhỏ một nửa, 0 = mặc định 1)
 }
 
 // AudioPlayer quản lý việc phát audio cho một WebRTC track
@@ -32,16 +36,65 @@ type AudioPlayer struct {
 	isPlaying   bool
 	currentFile string
 	mu          sync.Mutex
+
+	// Ducking: nhạc nền chạy qua mixer, prompt được trộn đè lên
+	duckingEnabled bool
+	duckLevel      float64
+	mixer          *duckingMixer
+
+	// Điều khiển phát: pause/resume/skip
+	paused     bool
+	resumeChan chan struct{} // Đóng khi Resume
+	skipChan   chan struct{}
+	granule    uint64 // Granule position của page cuối đã phát
+
+	cacheDir string // Cache file đã chỉnh gain
 }
 
+// errSkipped báo item hiện tại bị Skip()
+var errSkipped = errors.New("skipped")
+
 type AudioConfig struct {
 	Enabled                bool
 	WelcomeAudioPath       string
 	CheckinSuccessPath     string
 	CheckinFailPath        string
+	CheckoutSuccessPath    string
 	BackgroundMusicPath    string
 	BackgroundMusicEnabled bool
 	GoodbyeAudioPath       string
+	TranscodeCacheDir      string // Cache OGG cho asset WAV/MP3 (rỗng = mặc định)
+
+	// TTS chào theo tên sau khi nhận diện thành công
+	TTSEnabled       bool
+	TTSCommand       string // VD: "espeak-ng -v vi --stdout" hoặc "piper --model vi.onnx --output_file /dev/stdout"
+	TTSCacheDir      string // Thư mục cache file OGG đã render
+	GreetingTemplate string // VD: "Xin chào %s"
+
+	// Prompt theo từng bước check-in: stage -> file/URL (xem Stage* constants).
+	// Stage không có file sẽ dùng TTS nếu bật
+	StagePrompts map[string]string
+
+	// Xác nhận bằng giọng nói (STT trên audio của người gọi), vẫn cần gửi vị trí
+	STTEnabled          bool
+	STTCommand          string        // VD: "whisper-cli -m ggml-base.bin -l vi -nt -f {file}"
+	VoiceConfirmPhrases []string      // Cụm từ xác nhận (rỗng = mặc định "đồng ý", "xác nhận")
+	VoiceConfirmWindow  time.Duration // Thời gian giữ cuộc gọi để chờ xác nhận
+
+	// Language packs: locale -> thư mục chứa welcome/checkin-success/checkin-failed
+	LanguagePacks   map[string]string
+	UserLocalesPath string // File mapping user -> locale
+	DefaultLocale   string
+
+	// Giảm âm lượng nhạc nền khi phát prompt
+	DuckingEnabled bool
+	DuckingLevel   float64 // 0..1, âm lượng nhạc khi có prompt (0 = mặc định)
+
+	// Chu kỳ tải lại asset dạng URL https (0 = không refresh)
+	RemoteRefreshInterval time.Duration
+
+	// Âm lượng nhạc nền so với prompt (1 = giữ nguyên, 0 = mặc định 1)
+	BackgroundMusicGain float64
 }
 
 // NewAudioPlayer tạo player mới
@@ -50,6 +103,8 @@ func NewAudioPlayer(track *webrtc.TrackLocalStaticSample, stopChan chan struct{}
 		track:     track,
 		stopChan:  stopChan,
 		queue:     make(chan AudioItem, 10), // Buffer 10 items
+		skipChan:  make(chan struct{}, 1),
+		cacheDir:  DefaultTranscodeCacheDir,
 		isPlaying: false,
 	}
 
@@ -63,12 +118,87 @@ func NewAudioPlayer(track *webrtc.TrackLocalStaticSample, stopChan chan struct{}
 func (ap *AudioPlayer) Play(item AudioItem) {
 	select {
 	case ap.queue <- item:
-		log.Printf("🎵 Queued: %s", item.Name)
+		logger.Info("Audio queued", "name", item.Name)
 	case <-ap.stopChan:
 		return
 	default:
-		log.Printf("⚠️  Queue full, skipping: %s", item.Name)
+		logger.Warn("Audio queue full, skipping", "name", item.Name)
+	}
+}
+
+// SetCacheDir đổi thư mục cache cho file đã chỉnh gain
+func (ap *AudioPlayer) SetCacheDir(dir string) {
+	if dir == "" {
+		return
+	}
+	ap.mu.Lock()
+	ap.cacheDir = dir
+	ap.mu.Unlock()
+}
+
+// resolveGain trả về file đã áp dụng Gain của item. Không chạy ffmpeg trong
+// cuộc gọi: nếu bản chỉnh gain chưa có (AudioLibrary.PrepareGain) thì phát
+// file gốc và render ở background cho lần sau
+func (ap *AudioPlayer) resolveGain(item AudioItem) string {
+	if item.Gain <= 0 || item.Gain == 1 {
+		return item.FilePath
+	}
+
+	ap.mu.Lock()
+	cacheDir := ap.cacheDir
+	ap.mu.Unlock()
+
+	if path, ok := cachedGain(item.FilePath, item.Gain, cacheDir); ok {
+		return path
 	}
+	prepareGainAsync(item.FilePath, item.Gain, cacheDir)
+	logger.Warn("Gain not rendered yet, playing original", "name", item.Name, "gain", item.Gain)
+	return item.FilePath
+}
+
+// EnableDucking bật trộn nhạc nền với prompt. level là âm lượng nhạc
+// nền trong lúc prompt phát (0..1)
+func (ap *AudioPlayer) EnableDucking(level float64) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	if level <= 0 {
+		level = DefaultDuckingLevel
+	}
+	ap.duckingEnabled = true
+	ap.duckLevel = level
+}
+
+// PlayBackground phát nhạc nền lặp vô hạn. Khi ducking bật, nhạc chạy
+// trong mixer nên các prompt sau vẫn phát được (nhạc nhỏ lại);
+// nếu không thì xếp vào queue như audio thường
+func (ap *AudioPlayer) PlayBackground(item AudioItem) {
+	ap.mu.Lock()
+	if !ap.duckingEnabled {
+		ap.mu.Unlock()
+		item.Loop = true
+		ap.Play(item)
+		return
+	}
+
+	if ap.mixer == nil {
+		mixer, err := newDuckingMixer(ap.track, ap.stopChan, ap.duckLevel)
+		if err != nil {
+			ap.mu.Unlock()
+			logger.Warn("Ducking unavailable, playing music without mixing", "err", err)
+			item.Loop = true
+			ap.Play(item)
+			return
+		}
+		ap.mixer = mixer
+	}
+	mixer := ap.mixer
+	ap.mu.Unlock()
+
+	if err := mixer.setMusic(ap.resolveGain(item)); err != nil {
+		logger.Error("Audio playback failed", "name", item.Name, "err", err)
+		return
+	}
+	logger.Info("Playing background", "name", item.Name)
 }
 
 // PlayNow ngắt audio hiện tại và phát ngay
@@ -84,6 +214,93 @@ func (ap *AudioPlayer) PlayNow(item AudioItem) {
 	ap.Play(item)
 }
 
+// Pause tạm dừng audio đang phát, giữ nguyên vị trí để Resume phát tiếp
+func (ap *AudioPlayer) Pause() {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	if ap.paused {
+		return
+	}
+	ap.paused = true
+	ap.resumeChan = make(chan struct{})
+	if ap.mixer != nil {
+		ap.mixer.setPromptPaused(true)
+	}
+	logger.Info("Audio paused", "name", ap.currentFile)
+}
+
+// Resume phát tiếp từ vị trí đã Pause
+func (ap *AudioPlayer) Resume() {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	if !ap.paused {
+		return
+	}
+	ap.paused = false
+	close(ap.resumeChan)
+	if ap.mixer != nil {
+		ap.mixer.setPromptPaused(false)
+	}
+	logger.Info("Audio resumed", "name", ap.currentFile)
+}
+
+// Skip bỏ qua item đang phát (kể cả item loop) và chuyển sang item tiếp theo
+// trong queue. OnFinish của item bị skip vẫn được gọi
+func (ap *AudioPlayer) Skip() {
+	ap.mu.Lock()
+	if !ap.isPlaying {
+		ap.mu.Unlock()
+		return
+	}
+	mixer := ap.mixer
+	name := ap.currentFile
+	ap.mu.Unlock()
+
+	if mixer != nil {
+		mixer.skipPrompt()
+	}
+
+	select {
+	case ap.skipChan <- struct{}{}:
+	default:
+	}
+	// Đang pause thì Resume để vòng phát nhận được tín hiệu skip
+	ap.Resume()
+	logger.Info("Audio skipped", "name", name)
+}
+
+// Position trả về vị trí phát của item hiện tại (theo granule position)
+func (ap *AudioPlayer) Position() time.Duration {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	return time.Duration(ap.granule) * time.Second / 48000
+}
+
+// waitIfPaused chặn khi đang pause. Trả về true nếu vừa resume, errSkipped /
+// lỗi stop nếu bị skip hoặc dừng trong lúc chờ
+func (ap *AudioPlayer) waitIfPaused() (bool, error) {
+	ap.mu.Lock()
+	paused, resume := ap.paused, ap.resumeChan
+	ap.mu.Unlock()
+
+	if !paused {
+		return false, nil
+	}
+
+	select {
+	case <-resume:
+	case <-ap.stopChan:
+		return false, fmt.Errorf("stopped")
+	}
+
+	select {
+	case <-ap.skipChan:
+		return false, errSkipped
+	default:
+		return true, nil
+	}
+}
+
 // Stop dừng player
 func (ap *AudioPlayer) Stop() {
 	close(ap.stopChan)
@@ -101,7 +318,7 @@ func (ap *AudioPlayer) processQueue() {
 	for {
 		select {
 		case <-ap.stopChan:
-			log.Println("🛑 Audio player stopped")
+			logger.Info("Audio player stopped")
 			return
 
 		case item := <-ap.queue:
@@ -115,8 +332,16 @@ func (ap *AudioPlayer) playAudio(item AudioItem) {
 	ap.mu.Lock()
 	ap.isPlaying = true
 	ap.currentFile = item.Name
+	ap.granule = 0
+	mixer := ap.mixer
 	ap.mu.Unlock()
 
+	// Bỏ tín hiệu skip cũ gửi khi chưa có item nào phát
+	select {
+	case <-ap.skipChan:
+	default:
+	}
+
 	defer func() {
 		ap.mu.Lock()
 		ap.isPlaying = false
@@ -129,16 +354,37 @@ func (ap *AudioPlayer) playAudio(item AudioItem) {
 		}
 	}()
 
-	log.Printf("▶️  Playing: %s", item.Name)
+	logger.Info("Playing audio", "name", item.Name)
+	filePath := ap.resolveGain(item)
+
+	// Nhạc nền đang chạy trong mixer: trộn prompt lên nhạc
+	if mixer != nil {
+		err := mixer.playPrompt(filePath)
+		if err == errSkipped {
+			return
+		}
+		if err != io.EOF {
+			logger.Error("Audio playback failed", "name", item.Name, "err", err)
+			return
+		}
+		logger.Info("Audio finished", "name", item.Name)
+		return
+	}
+
+	// Một clock cho cả item để các vòng loop nối tiếp không bị lệch
+	clock := newPlaybackClock()
 
 	// Loop nếu cần
 	for {
-		err := ap.streamOGG(item.FilePath)
+		err := ap.streamOGG(filePath, clock)
 
+		if err == errSkipped {
+			return
+		}
 		if err == io.EOF {
-			log.Printf("✅ Finished: %s", item.Name)
+			logger.Info("Audio finished", "name", item.Name)
 		} else if err != nil {
-			log.Printf("❌ Error playing %s: %v", item.Name, err)
+			logger.Error("Audio playback failed", "name", item.Name, "err", err)
 			return
 		}
 
@@ -152,13 +398,13 @@ func (ap *AudioPlayer) playAudio(item AudioItem) {
 		case <-ap.stopChan:
 			return
 		default:
-			log.Printf("🔄 Looping: %s", item.Name)
+			logger.Debug("Looping audio", "name", item.Name)
 		}
 	}
 }
 
 // streamOGG đọc và stream file OGG Opus
-func (ap *AudioPlayer) streamOGG(filePath string) error {
+func (ap *AudioPlayer) streamOGG(filePath string, clock *playbackClock) error {
 	// Mở file
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -180,9 +426,20 @@ func (ap *AudioPlayer) streamOGG(filePath string) error {
 		select {
 		case <-ap.stopChan:
 			return fmt.Errorf("stopped")
+		case <-ap.skipChan:
+			return errSkipped
 		default:
 		}
 
+		// Pause giữ nguyên lastGranule nên page kế tiếp vẫn tính đúng duration
+		resumed, err := ap.waitIfPaused()
+		if err != nil {
+			return err
+		}
+		if resumed {
+			clock.reset()
+		}
+
 		// Đọc page từ OGG
 		pageData, pageHeader, err := ogg.ParseNextPage()
 		if err == io.EOF {
@@ -197,7 +454,7 @@ func (ap *AudioPlayer) streamOGG(filePath string) error {
 		if pageHeader.GranulePosition > lastGranule && lastGranule != 0 {
 			sampleCount := pageHeader.GranulePosition - lastGranule
 			// Opus = 48kHz
-			sampleDuration = time.Duration((float64(sampleCount)/48000)*1000) * time.Millisecond
+			sampleDuration = time.Duration(sampleCount) * time.Second / 48000
 		}
 		lastGranule = pageHeader.GranulePosition
 
@@ -206,6 +463,9 @@ func (ap *AudioPlayer) streamOGG(filePath string) error {
 			sampleDuration = 20 * time.Millisecond
 		}
 
+		// Chờ đến thời điểm phát theo playback clock
+		clock.wait(sampleDuration)
+
 		// Ghi Opus frame vào WebRTC track
 		if err := ap.track.WriteSample(media.Sample{
 			Data:     pageData,
@@ -216,8 +476,9 @@ func (ap *AudioPlayer) streamOGG(filePath string) error {
 
 		packetCount++
 
-		// Sleep để giữ real-time playback
-		time.Sleep(sampleDuration)
+		ap.mu.Lock()
+		ap.granule = lastGranule
+		ap.mu.Unlock()
 	}
 }
 
@@ -226,28 +487,59 @@ func (ap *AudioPlayer) streamOGG(filePath string) error {
 // ============================================================
 
 type AudioLibrary struct {
-	sounds map[string]string // name -> file path
-	mu     sync.RWMutex
+	sounds   map[string]string // name -> file path
+	remotes  map[string]string // name -> URL (asset tải từ xa)
+	cacheDir string            // Thư mục chứa file đã transcode
+	mu       sync.RWMutex
 }
 
 func NewAudioLibrary() *AudioLibrary {
 	return &AudioLibrary{
-		sounds: make(map[string]string),
+		sounds:   make(map[string]string),
+		remotes:  make(map[string]string),
+		cacheDir: DefaultTranscodeCacheDir,
 	}
 }
 
-// Register đăng ký một file audio
+// SetCacheDir đổi thư mục cache cho file transcode
+func (al *AudioLibrary) SetCacheDir(dir string) {
+	if dir == "" {
+		return
+	}
+	al.mu.Lock()
+	al.cacheDir = dir
+	al.mu.Unlock()
+}
+
+// Register đăng ký một file audio. File không phải OGG (WAV, MP3, ...)
+// được transcode sang OGG/Opus và cache lại. filePath có thể là URL https
 func (al *AudioLibrary) Register(name, filePath string) error {
+	if isRemoteURL(filePath) {
+		return al.registerRemote(name, filePath)
+	}
+
 	// Kiểm tra file tồn tại
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return fmt.Errorf("file not found: %s", filePath)
 	}
 
+	if !isOggFile(filePath) {
+		al.mu.RLock()
+		cacheDir := al.cacheDir
+		al.mu.RUnlock()
+
+		oggPath, err := transcodeAsset(filePath, cacheDir)
+		if err != nil {
+			return fmt.Errorf("failed to transcode %s: %w", filePath, err)
+		}
+		filePath = oggPath
+	}
+
 	al.mu.Lock()
 	al.sounds[name] = filePath
 	al.mu.Unlock()
 
-	log.Printf("📚 Registered audio: %s -> %s", name, filePath)
+	logger.Info("Registered audio", "name", name, "path", filePath)
 	return nil
 }
 
@@ -259,6 +551,37 @@ func (al *AudioLibrary) Get(name string) (string, bool) {
 	return path, exists
 }
 
+// PrepareGain render trước bản chỉnh gain của audio name ở background, để
+// lúc phát trong cuộc gọi chỉ cần đọc cache
+func (al *AudioLibrary) PrepareGain(name string, gain float64) {
+	al.mu.RLock()
+	path, exists := al.sounds[name]
+	cacheDir := al.cacheDir
+	al.mu.RUnlock()
+
+	if exists {
+		prepareGainAsync(path, gain, cacheDir)
+	}
+}
+
+// Check mở file của audio name và đọc header OGG, để lỗi file hiện ra lúc
+// khởi động thay vì trong cuộc gọi đầu tiên
+func (al *AudioLibrary) Check(name string) error {
+	path, exists := al.Get(name)
+	if !exists {
+		return fmt.Errorf("audio %s not registered", name)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open file: %w", err)
+	}
+	defer file.Close()
+	if _, _, err := oggreader.NewWith(file); err != nil {
+		return fmt.Errorf("cannot read OGG %s: %w", path, err)
+	}
+	return nil
+}
+
 // List liệt kê tất cả audio đã đăng ký
 func (al *AudioLibrary) List() []string {
 	al.mu.RLock()
@@ -0,0 +1,174 @@
+package audio
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mezon-checkin-bot/internal/utils"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// REMOTE ASSETS - Tải audio qua HTTPS, cache và refresh định kỳ
+// ============================================================
+
+const (
+	remoteDownloadTimeout = 30 * time.Second
+	remoteMaxSize         = 20 << 20 // 20MB
+)
+
+var remoteHTTPClient = &http.Client{Timeout: remoteDownloadTimeout}
+
+// isRemoteURL kiểm tra filePath có phải URL https không
+func isRemoteURL(filePath string) bool {
+	return strings.HasPrefix(strings.ToLower(filePath), "https://")
+}
+
+// registerRemote tải asset về cache rồi đăng ký bản local. Nếu tải lỗi
+// nhưng đã có bản cache cũ thì dùng tạm bản đó
+func (al *AudioLibrary) registerRemote(name, rawURL string) error {
+	al.mu.RLock()
+	cacheDir := al.cacheDir
+	al.mu.RUnlock()
+
+	localPath, err := downloadAsset(rawURL, cacheDir)
+	if err != nil {
+		cached := remoteCachePath(rawURL, cacheDir)
+		if _, statErr := os.Stat(cached); statErr != nil {
+			return err
+		}
+		logger.Warn("Download failed, using cached copy", "name", name, "err", err)
+		localPath = cached
+	}
+
+	if !isOggFile(localPath) {
+		if localPath, err = transcodeAsset(localPath, cacheDir); err != nil {
+			return fmt.Errorf("failed to transcode %s: %w", rawURL, err)
+		}
+	}
+
+	al.mu.Lock()
+	al.sounds[name] = localPath
+	al.remotes[name] = rawURL
+	al.mu.Unlock()
+
+	logger.Info("Registered remote audio", "name", name, "url", rawURL)
+	return nil
+}
+
+// StartRemoteRefresh tải lại các asset remote mỗi interval cho đến khi stop đóng
+func (al *AudioLibrary) StartRemoteRefresh(interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+
+	al.mu.RLock()
+	count := len(al.remotes)
+	al.mu.RUnlock()
+	if count == 0 {
+		return
+	}
+
+	logger.Info("Remote audio refresh scheduled", "interval", interval, "assets", count)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				al.refreshRemotes()
+			}
+		}
+	}()
+}
+
+func (al *AudioLibrary) refreshRemotes() {
+	al.mu.RLock()
+	remotes := make(map[string]string, len(al.remotes))
+	for name, rawURL := range al.remotes {
+		remotes[name] = rawURL
+	}
+	al.mu.RUnlock()
+
+	for name, rawURL := range remotes {
+		if err := al.registerRemote(name, rawURL); err != nil {
+			logger.Warn("Failed to refresh remote audio", "name", name, "err", err)
+		}
+	}
+}
+
+// remoteCachePath trả về đường dẫn cache cố định cho URL
+func remoteCachePath(rawURL, cacheDir string) string {
+	sum := sha1.Sum([]byte(rawURL))
+	base := "asset"
+	if u, err := url.Parse(rawURL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		base = path.Base(u.Path)
+	}
+	return filepath.Join(cacheDir, "remote", hex.EncodeToString(sum[:8])+"-"+base)
+}
+
+// downloadAsset tải URL về cache sau khi kiểm tra kích thước và định dạng.
+// File chỉ bị thay khi nội dung thay đổi để giữ nguyên cache transcode
+func downloadAsset(rawURL, cacheDir string) (string, error) {
+	resp, err := remoteHTTPClient.Get(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, remoteMaxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	if len(data) > remoteMaxSize {
+		return "", fmt.Errorf("asset too large (> %d bytes)", remoteMaxSize)
+	}
+	if err := validateAudioData(data); err != nil {
+		return "", err
+	}
+
+	outPath := remoteCachePath(rawURL, cacheDir)
+	if existing, err := os.ReadFile(outPath); err == nil && bytes.Equal(existing, data) {
+		return outPath, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create remote cache dir: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(outPath, data, 0644); err != nil {
+		return "", err
+	}
+
+	logger.Info("Downloaded remote audio", "url", rawURL, "bytes", len(data))
+	return outPath, nil
+}
+
+// validateAudioData kiểm tra magic bytes của OGG, WAV hoặc MP3
+func validateAudioData(data []byte) error {
+	switch {
+	case bytes.HasPrefix(data, []byte("OggS")):
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WAVE")):
+	case bytes.HasPrefix(data, []byte("ID3")):
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0: // MPEG frame sync
+	default:
+		return fmt.Errorf("unsupported audio format")
+	}
+	return nil
+}
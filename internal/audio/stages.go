@@ -0,0 +1,25 @@
+package audio
+
+// ============================================================
+// STAGE PROMPTS - Hướng dẫn bằng giọng nói theo từng bước check-in
+// ============================================================
+
+const (
+	StageScanning     = "scanning"      // Bắt đầu thấy khuôn mặt
+	StageLookStraight = "look_straight" // Khuôn mặt bị nghiêng
+	StageSendLocation = "send_location" // Nhận diện xong, chờ gửi vị trí
+	StageMaintenance  = "maintenance"   // Bot đang bảo trì, phát xong thì cúp máy
+)
+
+// DefaultStageTexts là câu nói dùng để render TTS khi stage chưa có file audio
+var DefaultStageTexts = map[string]string{
+	StageScanning:     "Đang quét khuôn mặt",
+	StageLookStraight: "Vui lòng nhìn thẳng vào camera",
+	StageSendLocation: "Vui lòng gửi vị trí của bạn",
+	StageMaintenance:  "Hệ thống đang bảo trì, vui lòng thử lại sau",
+}
+
+// StageAudioName trả về tên đăng ký trong AudioLibrary của stage
+func StageAudioName(stage string) string {
+	return "stage_" + stage
+}
@@ -0,0 +1,189 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4/pkg/media/oggwriter"
+)
+
+// ============================================================
+// SPEECH-TO-TEXT - Giải mã audio của người gọi và nhận dạng giọng nói
+// ============================================================
+
+const (
+	STTSampleRate    = 16000 // PCM mono 16kHz, format phổ biến của engine STT
+	sttRenderTimeout = 15 * time.Second
+)
+
+// SpeechRecognizer là hook nhận dạng giọng nói: nhận PCM s16le mono
+// STTSampleRate và trả về text
+type SpeechRecognizer interface {
+	Transcribe(pcm []byte) (string, error)
+}
+
+// STTEngine gọi engine STT local (whisper.cpp, vosk, ...). File WAV được
+// thay vào "{file}", hoặc thêm vào cuối nếu không có placeholder.
+// Text được đọc từ stdout
+type STTEngine struct {
+	command string
+	args    []string
+}
+
+// NewSTTEngine tạo engine từ command line
+func NewSTTEngine(commandLine string) (*STTEngine, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("STT command not configured")
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return nil, fmt.Errorf("STT engine not found: %w", err)
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+
+	return &STTEngine{
+		command: fields[0],
+		args:    fields[1:],
+	}, nil
+}
+
+// Transcribe ghi PCM ra file WAV tạm và chạy engine
+func (e *STTEngine) Transcribe(pcm []byte) (string, error) {
+	tmp, err := os.CreateTemp("", "stt-*.wav")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeWAV(tmp, pcm, STTSampleRate); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	tmp.Close()
+
+	args := make([]string, 0, len(e.args)+1)
+	replaced := false
+	for _, arg := range e.args {
+		if strings.Contains(arg, "{file}") {
+			arg = strings.ReplaceAll(arg, "{file}", tmp.Name())
+			replaced = true
+		}
+		args = append(args, arg)
+	}
+	if !replaced {
+		args = append(args, tmp.Name())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sttRenderTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.command, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("STT failed: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// writeWAV ghi header WAV PCM 16-bit mono rồi dữ liệu
+func writeWAV(w io.Writer, pcm []byte, sampleRate int) error {
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+len(pcm)))
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:], 1) // mono
+	binary.LittleEndian.PutUint32(header[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(sampleRate*2))
+	binary.LittleEndian.PutUint16(header[32:], 2)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(len(pcm)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(pcm)
+	return err
+}
+
+// ============================================================
+// OPUS DECODER - RTP Opus -> PCM mono 16kHz qua ffmpeg
+// ============================================================
+
+// OpusDecoder nhận RTP packet Opus của người gọi, đóng gói OGG và đưa vào
+// ffmpeg; PCM đọc ra từ PCM()
+type OpusDecoder struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	ogg    *oggwriter.OggWriter
+}
+
+// NewOpusDecoder khởi động ffmpeg decoder
+func NewOpusDecoder() (*OpusDecoder, error) {
+	cmd := exec.Command("ffmpeg",
+		"-loglevel", "error", "-nostdin",
+		"-f", "ogg",
+		"-i", "pipe:0",
+		"-f", "s16le",
+		"-ar", fmt.Sprint(STTSampleRate),
+		"-ac", "1",
+		"pipe:1",
+	)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start opus decoder: %w", err)
+	}
+
+	ogg, err := oggwriter.NewWith(stdin, 48000, 2)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	return &OpusDecoder{cmd: cmd, stdin: stdin, stdout: stdout, ogg: ogg}, nil
+}
+
+// WriteRTP đưa một RTP packet Opus vào decoder
+func (d *OpusDecoder) WriteRTP(packet *rtp.Packet) error {
+	return d.ogg.WriteRTP(packet)
+}
+
+// PCM trả về stream PCM s16le mono STTSampleRate
+func (d *OpusDecoder) PCM() io.Reader {
+	return d.stdout
+}
+
+// Close dừng decoder
+func (d *OpusDecoder) Close() {
+	d.ogg.Close()
+	d.stdin.Close()
+	if d.cmd.Process != nil {
+		d.cmd.Process.Kill()
+	}
+	d.cmd.Wait()
+}
@@ -0,0 +1,182 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// TRANSCODE - Chuyển audio bất kỳ sang OGG/Opus bằng ffmpeg
+// ============================================================
+
+// transcodeToOpus đọc audio từ input (format gợi ý cho ffmpeg, rỗng = tự nhận dạng)
+// và ghi file OGG/Opus 48kHz stereo ra outPath
+func transcodeToOpus(ctx context.Context, input io.Reader, inputFormat string, outPath string) error {
+	return transcodeWithFilter(ctx, input, inputFormat, "", outPath)
+}
+
+// transcodeWithFilter giống transcodeToOpus, thêm audio filter ffmpeg (VD: "volume=0.5")
+func transcodeWithFilter(ctx context.Context, input io.Reader, inputFormat, filter, outPath string) error {
+	args := []string{"-loglevel", "error", "-nostdin", "-y"}
+	if inputFormat != "" {
+		args = append(args, "-f", inputFormat)
+	}
+	args = append(args, "-i", "pipe:0")
+	if filter != "" {
+		args = append(args, "-af", filter)
+	}
+	args = append(args,
+		"-c:a", "libopus",
+		"-b:a", "48k",
+		"-ar", "48000",
+		"-ac", "2",
+		"-f", "ogg",
+		outPath,
+	)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdin = input
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg transcode failed: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// ============================================================
+// ASSET CACHE - Transcode file WAV/MP3 khi đăng ký vào AudioLibrary
+// ============================================================
+
+const (
+	DefaultTranscodeCacheDir = "./audio/transcode-cache"
+	assetTranscodeTimeout    = 60 * time.Second
+)
+
+// isOggFile kiểm tra phần mở rộng của file đã là OGG/Opus chưa
+func isOggFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ogg", ".opus":
+		return true
+	}
+	return false
+}
+
+// transcodeAsset chuyển file audio sang OGG/Opus và cache trong cacheDir.
+// Key cache gồm đường dẫn, kích thước và thời gian sửa file nên thay file
+// nguồn sẽ tự transcode lại.
+func transcodeAsset(srcPath, cacheDir string) (string, error) {
+	return transcodeCached(srcPath, cacheDir, "")
+}
+
+// applyGain trả về bản OGG đã chỉnh âm lượng của srcPath (cache trong cacheDir).
+// gain = 1 trả về nguyên file
+func applyGain(srcPath string, gain float64, cacheDir string) (string, error) {
+	if gain <= 0 || gain == 1 {
+		return srcPath, nil
+	}
+	return transcodeCached(srcPath, cacheDir, gainFilter(gain))
+}
+
+func gainFilter(gain float64) string {
+	return fmt.Sprintf("volume=%.3f", gain)
+}
+
+// cachedGain trả về bản đã chỉnh gain nếu đã render xong, không chạy ffmpeg
+func cachedGain(srcPath string, gain float64, cacheDir string) (string, bool) {
+	outPath, err := transcodeCachePath(srcPath, cacheDir, gainFilter(gain))
+	if err != nil {
+		return "", false
+	}
+	if _, err := os.Stat(outPath); err != nil {
+		return "", false
+	}
+	return outPath, true
+}
+
+// gainRenders chứa các lần render gain đang chạy (cache path -> struct{})
+var gainRenders sync.Map
+
+// prepareGainAsync render bản chỉnh gain ở background, mỗi file một lần
+func prepareGainAsync(srcPath string, gain float64, cacheDir string) {
+	if gain <= 0 || gain == 1 {
+		return
+	}
+	outPath, err := transcodeCachePath(srcPath, cacheDir, gainFilter(gain))
+	if err != nil {
+		return
+	}
+	if _, running := gainRenders.LoadOrStore(outPath, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer gainRenders.Delete(outPath)
+		if _, err := applyGain(srcPath, gain, cacheDir); err != nil {
+			logger.Warn("Cannot apply gain", "src", srcPath, "gain", gain, "err", err)
+		}
+	}()
+}
+
+// transcodeCachePath trả về đường dẫn cache của srcPath với filter. Key gồm
+// kích thước và thời gian sửa file nên file nguồn đổi sẽ có path mới
+func transcodeCachePath(srcPath, cacheDir, filter string) (string, error) {
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("%s|%d|%d|%s", srcPath, info.Size(), info.ModTime().UnixNano(), filter)
+	sum := sha1.Sum([]byte(key))
+	base := strings.TrimSuffix(filepath.Base(srcPath), filepath.Ext(srcPath))
+	return filepath.Join(cacheDir, base+"-"+hex.EncodeToString(sum[:8])+".ogg"), nil
+}
+
+func transcodeCached(srcPath, cacheDir, filter string) (string, error) {
+	outPath, err := transcodeCachePath(srcPath, cacheDir, filter)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(outPath); err == nil {
+		return outPath, nil
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create transcode cache dir: %w", err)
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), assetTranscodeTimeout)
+	defer cancel()
+
+	// Ghi ra file tạm rồi rename để không để lại file hỏng trong cache
+	tmpPath := outPath + ".tmp"
+	if err := transcodeWithFilter(ctx, src, "", filter, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+
+	logger.Info("Transcoded audio", "src", srcPath, "out", outPath)
+	return outPath, nil
+}
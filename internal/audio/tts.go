@@ -0,0 +1,147 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ============================================================
+// TEXT-TO-SPEECH - Render câu chào thành OGG/Opus
+// ============================================================
+
+const (
+	DefaultTTSCommand  = "espeak-ng -v vi --stdout"
+	DefaultTTSCacheDir = "./audio/tts-cache"
+	ttsRenderTimeout   = 10 * time.Second
+)
+
+// TTSEngine gọi engine TTS local (espeak-ng, piper, ...) xuất WAV ra stdout,
+// sau đó dùng ffmpeg chuyển sang OGG/Opus để AudioPlayer phát được
+type TTSEngine struct {
+	command  string
+	args     []string
+	cacheDir string
+}
+
+// NewTTSEngine tạo engine từ command line. Text được thay vào "{text}",
+// hoặc thêm vào cuối nếu không có placeholder
+func NewTTSEngine(commandLine, cacheDir string) (*TTSEngine, error) {
+	if commandLine == "" {
+		commandLine = DefaultTTSCommand
+	}
+	if cacheDir == "" {
+		cacheDir = DefaultTTSCacheDir
+	}
+
+	fields := strings.Fields(commandLine)
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return nil, fmt.Errorf("TTS engine not found: %w", err)
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create TTS cache dir: %w", err)
+	}
+
+	return &TTSEngine{
+		command:  fields[0],
+		args:     fields[1:],
+		cacheDir: cacheDir,
+	}, nil
+}
+
+func (e *TTSEngine) cachePath(text string) string {
+	sum := sha1.Sum([]byte(e.command + strings.Join(e.args, " ") + "|" + text))
+	return filepath.Join(e.cacheDir, hex.EncodeToString(sum[:])+".ogg")
+}
+
+// Cached trả về file OGG của text nếu đã render, không chạy TTS
+func (e *TTSEngine) Cached(text string) (string, bool) {
+	outPath := e.cachePath(text)
+	if _, err := os.Stat(outPath); err != nil {
+		return "", false
+	}
+	return outPath, true
+}
+
+// Prerender render trước các câu cố định ở background để trong cuộc gọi
+// chỉ cần đọc cache (Cached)
+func (e *TTSEngine) Prerender(texts []string) {
+	go func() {
+		for _, text := range texts {
+			if _, err := e.Render(text); err != nil {
+				logger.Warn("TTS prerender failed", "text", text, "err", err)
+			}
+		}
+	}()
+}
+
+// Render trả về đường dẫn file OGG cho text (dùng lại cache nếu đã render)
+func (e *TTSEngine) Render(text string) (string, error) {
+	outPath := e.cachePath(text)
+
+	if _, err := os.Stat(outPath); err == nil {
+		return outPath, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ttsRenderTimeout)
+	defer cancel()
+
+	// 1. Render WAV
+	wav, err := e.synthesize(ctx, text)
+	if err != nil {
+		return "", err
+	}
+
+	// 2. Transcode WAV -> OGG/Opus 48kHz
+	tmpPath := outPath + ".tmp"
+	if err := transcodeToOpus(ctx, bytes.NewReader(wav), "wav", tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		return "", fmt.Errorf("failed to store TTS output: %w", err)
+	}
+
+	logger.Info("TTS rendered", "chars", utf8.RuneCountInString(text), "out", outPath)
+	return outPath, nil
+}
+
+func (e *TTSEngine) synthesize(ctx context.Context, text string) ([]byte, error) {
+	args := make([]string, 0, len(e.args)+1)
+	replaced := false
+	for _, arg := range e.args {
+		if strings.Contains(arg, "{text}") {
+			arg = strings.ReplaceAll(arg, "{text}", text)
+			replaced = true
+		}
+		args = append(args, arg)
+	}
+	if !replaced {
+		args = append(args, text)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.command, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("TTS failed: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("TTS produced no audio")
+	}
+	return stdout.Bytes(), nil
+}
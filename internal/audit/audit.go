@@ -0,0 +1,289 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/logging"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// AUDIT LOG - HMAC-chained record of security-relevant actions.
+// The key is kept outside the data directory, so whoever can
+// edit the log can't recompute the chain after a change.
+// ============================================================
+
+var logger = logging.For("audit")
+
+// Actions recorded in the log
+const (
+	ActionStatusUpdate   = "status_update"   // Check-in status sent to the backend
+	ActionQRCheckin      = "qr_checkin"      // QR check-in sent to the backend
+	ActionClockOut       = "clock_out"       // Check-out sent to the backend
+	ActionAdminCommand   = "admin_command"   // Admin command executed
+	ActionAdminDenied    = "admin_denied"    // Admin command refused to a non-admin
+	ActionManualApproval = "manual_approval" // Escalated check-in approved by a manager
+	ActionManualReject   = "manual_reject"   // Escalated check-in rejected by a manager
+	ActionDataExport     = "data_export"     // Attendance records downloaded
+	ActionAdminAPI       = "admin_api"       // Admin API request that changed state
+)
+
+// genesisHash is the Prev of the first entry
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// MinKeyLen is the shortest accepted HMAC key
+const MinKeyLen = 32
+
+// ErrWrongKey is returned by Open for a log chained with another key
+var ErrWrongKey = errors.New("audit log was chained with another key")
+
+// Entry is one audited action. Hash is the HMAC of every other field, Prev
+// links to the entry before so editing or deleting a line breaks the chain.
+type Entry struct {
+	Seq    int64     `json:"seq"`
+	At     time.Time `json:"at"`
+	Actor  int64     `json:"actor"` // User ID, 0 = the bot itself
+	Action string    `json:"action"`
+	Target string    `json:"target,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+	Prev   string    `json:"prev"`
+	Hash   string    `json:"hash"`
+}
+
+// Log appends entries to a JSON lines file. Two instances sharing the data
+// directory may append to the same file: the file is locked while appending
+// and the chain read again when the other instance wrote.
+type Log struct {
+	path     string
+	key      []byte
+	lastSeq  int64
+	lastHash string
+	size     int64 // File size after this instance's last read or write
+	mu       sync.Mutex
+}
+
+// Open opens the log at path, continuing the chain of existing entries. A
+// log written before entries were keyed is moved aside to <path>.unkeyed and
+// a new chain is started.
+func Open(path string, key []byte) (*Log, error) {
+	if len(key) < MinKeyLen {
+		return nil, fmt.Errorf("audit key must be at least %d bytes, got %d", MinKeyLen, len(key))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	l := &Log{path: path, key: key, lastHash: genesisHash}
+	if err := l.checkKey(); err != nil {
+		return nil, err
+	}
+	if err := l.readTail(); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Audit log opened", "path", path, "entries", l.lastSeq)
+	return l, nil
+}
+
+// Append records one action
+func (l *Log) Append(actor int64, action, target, detail string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+	unlock, err := lockFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	defer unlock()
+
+	// The other instance appended since, continue its chain
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	if info.Size() != l.size {
+		if err := l.readTail(); err != nil {
+			return err
+		}
+	}
+
+	entry := Entry{
+		Seq:    l.lastSeq + 1,
+		At:     time.Now().UTC(),
+		Actor:  actor,
+		Action: action,
+		Target: target,
+		Detail: detail,
+		Prev:   l.lastHash,
+	}
+	entry.Hash = entry.computeHash(l.key)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+
+	l.lastSeq = entry.Seq
+	l.lastHash = entry.Hash
+	l.size = info.Size() + int64(len(data)) + 1
+	return nil
+}
+
+// readTail reads the last entry and the file size. Caller holds l.mu, or
+// the log is being opened.
+func (l *Log) readTail() error {
+	l.lastSeq, l.lastHash = 0, genesisHash
+	if err := l.scan(func(entry Entry) bool {
+		l.lastSeq = entry.Seq
+		l.lastHash = entry.Hash
+		return true
+	}); err != nil {
+		return err
+	}
+	info, err := os.Stat(l.path)
+	switch {
+	case os.IsNotExist(err):
+		l.size = 0
+	case err != nil:
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	default:
+		l.size = info.Size()
+	}
+	return nil
+}
+
+// Verify walks the chain and returns the number of valid entries, with an
+// error naming the first entry that was altered, removed or reordered
+func (l *Log) Verify() (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var count int64
+	prev := genesisHash
+	var broken error
+	err := l.scan(func(entry Entry) bool {
+		switch {
+		case entry.Seq != count+1:
+			broken = fmt.Errorf("entry %d follows %d", entry.Seq, count)
+		case entry.Prev != prev:
+			broken = fmt.Errorf("entry %d does not link to the previous entry", entry.Seq)
+		case !hmac.Equal([]byte(entry.Hash), []byte(entry.computeHash(l.key))):
+			broken = fmt.Errorf("entry %d was modified", entry.Seq)
+		default:
+			count++
+			prev = entry.Hash
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, broken
+}
+
+// Recent returns up to limit of the latest entries that match, oldest first
+func (l *Log) Recent(limit int, match func(Entry) bool) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []Entry
+	err := l.scan(func(entry Entry) bool {
+		if match == nil || match(entry) {
+			entries = append(entries, entry)
+			if len(entries) > limit {
+				entries = entries[1:]
+			}
+		}
+		return true
+	})
+	return entries, err
+}
+
+// scan calls fn for each entry until it returns false. Lines that don't
+// parse are kept as zero entries so Verify reports them.
+func (l *Log) scan(fn func(Entry) bool) error {
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		_ = json.Unmarshal(scanner.Bytes(), &entry)
+		if !fn(entry) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return nil
+}
+
+// checkKey reads the first entry: chained with the key it is fine, with plain
+// SHA-256 the log predates keyed entries and is moved aside
+func (l *Log) checkKey() error {
+	var first *Entry
+	if err := l.scan(func(entry Entry) bool {
+		first = &entry
+		return false
+	}); err != nil || first == nil || first.Seq == 0 {
+		// A first line that doesn't parse is reported by Verify
+		return err
+	}
+	switch first.Hash {
+	case first.computeHash(l.key):
+		return nil
+	case first.unkeyedHash():
+		unkeyed := l.path + ".unkeyed"
+		if err := os.Rename(l.path, unkeyed); err != nil {
+			return fmt.Errorf("failed to move unkeyed audit log aside: %w", err)
+		}
+		logger.Warn("Audit log predates keyed entries, moved aside and a new chain started", "path", unkeyed)
+		return nil
+	}
+	return ErrWrongKey
+}
+
+func (e Entry) computeHash(key []byte) string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// unkeyedHash is the hash of entries written before the chain was keyed
+func (e Entry) unkeyedHash() string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,65 @@
+package client
+
+// ============================================================
+// ACCESS - Where the bot joins when added and who it ignores
+// ============================================================
+
+// AccessConfig restricts the bot. Empty lists allow everything.
+type AccessConfig struct {
+	AllowedClans    []int64 // Clans whose channels are joined when the bot is added (DMs are always joined)
+	AllowedChannels []int64 // Clan channels joined when the bot is added, within AllowedClans
+	BlockedUsers    []int64 // Users whose calls and messages are ignored
+}
+
+type accessList struct {
+	clans    map[int64]bool
+	channels map[int64]bool
+	blocked  map[int64]bool
+}
+
+func idSet(ids []int64) map[int64]bool {
+	if len(ids) == 0 {
+		return nil
+	}
+	set := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// SetAccess replaces the access lists
+func (c *MezonClient) SetAccess(config AccessConfig) {
+	access := accessList{
+		clans:    idSet(config.AllowedClans),
+		channels: idSet(config.AllowedChannels),
+		blocked:  idSet(config.BlockedUsers),
+	}
+	c.mu.Lock()
+	c.access = access
+	c.mu.Unlock()
+	logger.Info("Access lists set",
+		"allowed_clans", len(config.AllowedClans),
+		"allowed_channels", len(config.AllowedChannels),
+		"blocked_users", len(config.BlockedUsers))
+}
+
+// UserBlocked reports whether the user's calls and messages are ignored
+func (c *MezonClient) UserBlocked(userID int64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.access.blocked[userID]
+}
+
+// channelAllowed reports whether the bot may join the channel it was added to
+func (c *MezonClient) channelAllowed(clanID, channelID int64) bool {
+	if clanID == DMClanID {
+		return true
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.access.clans != nil && !c.access.clans[clanID] {
+		return false
+	}
+	return c.access.channels == nil || c.access.channels[channelID]
+}
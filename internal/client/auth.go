@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"mezon-checkin-bot/mezon-protobuf/go/api"
 	"mezon-checkin-bot/models"
 	"net/http"
@@ -20,7 +19,7 @@ import (
 // ============================================================
 
 func (c *MezonClient) Authenticate() error {
-	log.Println("🔐 Authenticating bot...")
+	logger.Info("Authenticating bot")
 
 	authEndpoint := c.buildAuthEndpoint()
 	authBody := c.buildAuthBody()
@@ -39,7 +38,7 @@ func (c *MezonClient) Authenticate() error {
 		return err
 	}
 
-	log.Println("✅ Bot authenticated successfully!")
+	logger.Info("Bot authenticated")
 	return nil
 }
 
@@ -109,8 +108,7 @@ func (c *MezonClient) processAuthResponse(resp *http.Response) error {
 	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("❌ Response status: %d", resp.StatusCode)
-		log.Printf("❌ Response body: %s", string(body))
+		logger.Error("Authentication failed", "status", resp.StatusCode, "body", string(body))
 		return fmt.Errorf("authentication failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
@@ -136,7 +134,7 @@ func (c *MezonClient) handleAPIURLSwitch(apiURL string) {
 
 	newHost, newPort, newSSL, err := parseAPIURL(apiURL)
 	if err == nil {
-		log.Printf("   🔄 Switching to API server: %s:%s (SSL: %v)", newHost, newPort, newSSL)
+		logger.Info("Switching to API server", "host", newHost, "port", newPort, "ssl", newSSL)
 		c.config.SocketHost = newHost
 		c.config.SocketPort = newPort
 		c.config.SocketUseSSL = newSSL
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	rtapi "mezon-checkin-bot/mezon-protobuf/go/rtapi"
+	"mezon-checkin-bot/models"
+	"time"
+)
+
+// ============================================================
+// SEND CLAN CHANNEL MESSAGES
+// ============================================================
+
+// SendChannelMessage posts a message to a clan text channel (e.g. an admin
+// alert channel). The clan is joined on every call; joining twice is a no-op
+// on the server.
+func (dm *DMManager) SendChannelMessage(clanID int64, channelID int64, content models.ChannelMessageContent) error {
+	if !dm.client.IsConnected() {
+		return fmt.Errorf("websocket not connected")
+	}
+
+	if err := dm.joinClanInternal(clanID); err != nil {
+		return fmt.Errorf("failed to join clan: %w", err)
+	}
+
+	contentJSON, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal content: %w", err)
+	}
+
+	envelope := &rtapi.Envelope{
+		Message: &rtapi.Envelope_ChannelMessageSend{
+			ChannelMessageSend: &rtapi.ChannelMessageSend{
+				ClanId:    clanID,
+				ChannelId: channelID,
+				Mode:      ClanChannelType,
+				IsPublic:  false,
+				Content:   string(contentJSON),
+			},
+		},
+	}
+
+	logger.Info("Sending channel message", "channel_id", channelID, "clan_id", clanID)
+
+	response, err := dm.client.sendWithResponse(envelope, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("send message failed: %w", err)
+	}
+	if response.GetError() != nil {
+		return fmt.Errorf("server error: code=%d, message=%s",
+			response.GetError().Code, response.GetError().Message)
+	}
+
+	logger.Info("Channel message sent", "channel_id", channelID)
+	return nil
+}
@@ -3,16 +3,21 @@ package client
 import (
 	"context"
 	"fmt"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/logging"
 	mzapi "mezon-checkin-bot/mezon-protobuf/go/api"
 	rtapi "mezon-checkin-bot/mezon-protobuf/go/rtapi"
 	"mezon-checkin-bot/models"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+var logger = logging.For("client")
+
 // ============================================================
 // CONSTANTS
 // ============================================================
@@ -20,10 +25,13 @@ import (
 const (
 	DMClanID          = 0
 	DMChannelType     = 4
+	ClanChannelType   = 2
+	MessageRefReply   = 0  // api.MessageRef.RefType of a reply
 	PingInterval      = 10 // seconds
 	InitialRetryDelay = 5  // seconds
 	MaxRetryDelay     = 60 // seconds
 	MaxRetries        = 10 // maximum reconnection attempts
+	ReconnectAlertAt  = 3  // failed attempts before "reconnect_failed" is emitted
 	DefaultTimeout    = 30 // seconds
 	MaxLogLength      = 200
 	WriteTimeout      = 10 // seconds for WebSocket writes
@@ -55,7 +63,8 @@ type MezonClient struct {
 	nextCID     int
 
 	// State management
-	verbose          bool
+	verbose          atomic.Bool  // Raw protocol tracing, changeable at runtime
+	lastReceived     atomic.Int64 // Unix nanoseconds of the last websocket message, pongs included
 	isRetrying       bool
 	isHardDisconnect bool
 	reconnectMu      sync.Mutex
@@ -66,6 +75,20 @@ type MezonClient struct {
 	shutdownOnce    sync.Once
 	wg              sync.WaitGroup
 	autoJoinEnabled bool
+
+	// Session cache ("" = authenticate on every start)
+	sessionCachePath    string
+	sessionRestoreTried bool
+
+	// Clan channel where commands are accepted besides DMs (0 = DMs only)
+	adminClanID    int64
+	adminChannelID int64
+
+	// Allowed clans and channels, blocked users, guarded by mu
+	access accessList
+
+	// Users whose clan channel text may be a location reply (nil = none)
+	locationAwaited func(userID int64) bool
 }
 
 type MessageHandler func(data interface{})
@@ -83,13 +106,13 @@ func NewMezonClient(config models.Config) *MezonClient {
 		handlers:         make(map[string][]MessageHandler),
 		cidHandlers:      make(map[string]chan *rtapi.Envelope),
 		nextCID:          1,
-		verbose:          verbose,
 		isHardDisconnect: false,
 		ctx:              ctx,
 		cancel:           cancel,
 		autoJoinEnabled:  true,
 	}
 
+	client.verbose.Store(verbose)
 	client.SetupEventHandlers()
 	return client
 }
@@ -99,9 +122,19 @@ func NewMezonClient(config models.Config) *MezonClient {
 // ============================================================
 
 func (c *MezonClient) Login() error {
+	if c.restoreSession() {
+		err := c.ConnectWebSocket()
+		if err == nil {
+			return nil
+		}
+		logger.Warn("Cached session rejected, authenticating", "err", err)
+		c.clearSessionCache()
+	}
+
 	if err := c.Authenticate(); err != nil {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
+	c.saveSession()
 	if err := c.ConnectWebSocket(); err != nil {
 		return fmt.Errorf("websocket connection failed: %w", err)
 	}
@@ -143,11 +176,11 @@ func (c *MezonClient) Close() error {
 
 		select {
 		case <-done:
-			if c.verbose {
+			if c.verbose.Load() {
 				fmt.Println("✅ All goroutines finished")
 			}
 		case <-time.After(ShutdownTimeout * time.Second):
-			if c.verbose {
+			if c.verbose.Load() {
 				fmt.Println("⚠️  Shutdown timeout, forcing close")
 			}
 		}
@@ -177,11 +210,7 @@ func (c *MezonClient) emit(event string, data interface{}) {
 		c.wg.Add(1)
 		go func() {
 			defer c.wg.Done()
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Printf("⚠️  Handler panic for event '%s': %v\n", event, r)
-				}
-			}()
+			defer alerting.Recover("event:"+event, "event", event)
 			h(data)
 		}()
 	}
@@ -205,7 +234,7 @@ func (c *MezonClient) resolveCID(cid string, envelope *rtapi.Envelope) {
 	c.cidMu.RUnlock()
 
 	if !exists {
-		if c.verbose {
+		if c.verbose.Load() {
 			fmt.Printf("⚠️  No handler found for CID=%s\n", cid)
 		}
 		return
@@ -213,7 +242,7 @@ func (c *MezonClient) resolveCID(cid string, envelope *rtapi.Envelope) {
 
 	select {
 	case ch <- envelope:
-		if c.verbose {
+		if c.verbose.Load() {
 			fmt.Printf("✅ Response delivered to CID=%s\n", cid)
 		}
 	case <-time.After(100 * time.Millisecond):
@@ -274,15 +303,48 @@ func (c *MezonClient) IsAutoJoinEnabled() bool {
 // ============================================================
 
 func (c *MezonClient) GetVerbose() bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return c.verbose
+	return c.verbose.Load()
 }
 
+// SetVerbose turns raw protocol tracing on or off without reconnecting
 func (c *MezonClient) SetVerbose(verbose bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.verbose = verbose
+	c.verbose.Store(verbose)
+}
+
+// LastReceived is when the last websocket message arrived. Pongs arrive
+// every PingInterval, so an old time means the reader is stuck or gone.
+func (c *MezonClient) LastReceived() time.Time {
+	nanos := c.lastReceived.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// Reconnect drops the websocket and logs in again, unless a reconnection is
+// already running. Used by the supervisor after automatic reconnection gave
+// up or the connection stalled.
+func (c *MezonClient) Reconnect() error {
+	c.reconnectMu.Lock()
+	if c.isRetrying {
+		c.reconnectMu.Unlock()
+		return fmt.Errorf("reconnection already in progress")
+	}
+	c.isRetrying = true
+	c.reconnectMu.Unlock()
+
+	defer func() {
+		c.reconnectMu.Lock()
+		c.isRetrying = false
+		c.reconnectMu.Unlock()
+	}()
+
+	if err := c.attemptReconnect(); err != nil {
+		return err
+	}
+	logger.Info("Reconnected")
+	c.emit("reconnected", nil)
+	return nil
 }
 
 func (c *MezonClient) GetSession() *mzapi.Session {
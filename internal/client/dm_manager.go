@@ -2,7 +2,6 @@ package client
 
 import (
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
@@ -35,16 +34,16 @@ func NewDMManager(client *MezonClient) *DMManager {
 	}
 	err := dm.ensureDMReady()
 	if err != nil {
-		log.Printf(" DM Manager Error %s", err)
+		logger.Error("DM manager init failed", "err", err)
 	}
 	client.On("reconnected", func(data interface{}) {
 		dm.isDMReady = false
 		err := dm.ensureDMReady()
 		if err != nil {
-			log.Printf(" DM Manager Error %s", err)
+			logger.Error("DM manager init failed", "err", err)
 		}
 	})
-	log.Printf("✅ DM Manager created (lazy init mode)")
+	logger.Info("DM manager created (lazy init mode)")
 	return dm
 }
 
@@ -74,13 +73,13 @@ func (dm *DMManager) ensureDMReady() error {
 		return fmt.Errorf("WebSocket connection not ready, call Login() first")
 	}
 
-	log.Printf("🔗 Initializing DM clan (lazy init)")
+	logger.Info("Initializing DM clan")
 	if err := dm.joinDMClan(); err != nil {
 		return fmt.Errorf("failed to join DM clan: %w", err)
 	}
 
 	dm.isDMReady = true
-	log.Printf("✅ DM clan initialized successfully")
+	logger.Info("DM clan initialized")
 	return nil
 }
 
@@ -97,7 +96,7 @@ func (dm *DMManager) joinClanInternal(clanID int64) error {
 		return fmt.Errorf("WebSocket connection is nil")
 	}
 
-	log.Printf("🔗 Joining clan: %d", clanID)
+	logger.Info("Joining clan", "clan_id", clanID)
 
 	// ⚡ SỬ DỤNG PROTOBUF thay vì JSON
 	envelope := &rtapi.Envelope{
@@ -121,6 +120,6 @@ func (dm *DMManager) joinClanInternal(clanID int64) error {
 			response.GetError().Code, response.GetError().Message)
 	}
 
-	log.Printf("✅ Joined clan: %d", clanID)
+	logger.Info("Joined clan", "clan_id", clanID)
 	return nil
 }
@@ -2,6 +2,7 @@ package client
 
 import (
 	"fmt"
+	"mezon-checkin-bot/internal/i18n"
 	"mezon-checkin-bot/models"
 	"time"
 )
@@ -14,6 +15,7 @@ const (
 	ColorPurple = "#71368A"
 	ColorGreen  = "#00FF00"
 	ColorRed    = "#FF0000"
+	ColorOrange = "#FFA500"
 
 	ButtonStyleSuccess = 3
 	ButtonStyleDanger  = 4
@@ -27,49 +29,180 @@ const (
 // CHECK-IN MESSAGES
 // ============================================================
 
-func BuildCheckinConfirmationMessage(userName string) models.ChannelMessageContent {
+// The check-in builders render their text with t, in the user's locale, and
+// take optional shift and clock status fields
+// BuildCheckinScanningMessage is the first state of the call status message
+func BuildCheckinScanningMessage(t i18n.Translator) models.ChannelMessageContent {
 	return models.ChannelMessageContent{
 		Embed: []models.InteractiveMessageEmbed{
-			buildEmbed(
-				ColorPurple,
-				"Xác định danh tính thành công - Cần xác minh vị trí",
-				fmt.Sprintf("Xin chào %s. Vui lòng gửi vị trí của bạn về cho hệ thống trong vòng 1 phút để hoàn thành check-in!", userName),
-			),
+			buildEmbed(ColorPurple, t("checkin.scanning.title", nil), t("checkin.scanning.body", nil)),
 		},
 	}
 }
 
-func BuildCheckinSuccessMessage(userName string) models.ChannelMessageContent {
+// BuildCheckinConfirmationMessage asks "is this you?" next to the recognized
+// face when faceURL is set
+func BuildCheckinConfirmationMessage(t i18n.Translator, userName, faceURL string, fields []models.EmbedField, buttons []MessageButton) models.ChannelMessageContent {
+	description := t("checkin.confirmation.body", i18n.Vars{"name": userName})
+	if faceURL != "" {
+		description += "\n" + t("checkin.confirmation.is_this_you", nil)
+	}
+
+	embed := buildEmbed(ColorPurple, t("checkin.confirmation.title", nil), description)
+	if faceURL != "" {
+		embed.Thumbnail = &models.EmbedImage{URL: faceURL}
+	}
+	embed.Fields = fields
+	content := models.ChannelMessageContent{
+		Embed: []models.InteractiveMessageEmbed{embed},
+	}
+	for _, btn := range buttons {
+		content.Components = append(content.Components, buildButton(btn.ID, btn.Label, btn.Style))
+	}
+	return content
+}
+
+func BuildCheckinSuccessMessage(t i18n.Translator, userName string, fields []models.EmbedField) models.ChannelMessageContent {
+	embed := buildEmbed(
+		ColorGreen,
+		t("checkin.success.title", nil),
+		t("checkin.success.body", i18n.Vars{"name": userName}),
+	)
+	embed.Fields = fields
+	return models.ChannelMessageContent{
+		Embed: []models.InteractiveMessageEmbed{embed},
+	}
+}
+
+func BuildCheckinSuccessAtMessage(t i18n.Translator, userName, place string, fields []models.EmbedField) models.ChannelMessageContent {
+	embed := buildEmbed(
+		ColorGreen,
+		t("checkin.success.title", nil),
+		t("checkin.success.body_at", i18n.Vars{"name": userName, "place": place}),
+	)
+	embed.Fields = fields
+	return models.ChannelMessageContent{
+		Embed: []models.InteractiveMessageEmbed{embed},
+	}
+}
+
+func BuildCheckoutSuccessMessage(t i18n.Translator, userName string, at time.Time) models.ChannelMessageContent {
+	description := t("checkout.success.body", i18n.Vars{"time": at.Format("15:04")})
+	if userName != "" {
+		description = t("checkout.success.body_name", i18n.Vars{"name": userName, "time": at.Format("15:04")})
+	}
+
 	return models.ChannelMessageContent{
+		Embed: []models.InteractiveMessageEmbed{
+			buildEmbed(ColorGreen, t("checkout.success.title", nil), description),
+		},
+	}
+}
+
+// BuildCheckinFailedMessage adds a "try again" button when retryButtonID is set
+func BuildCheckinFailedMessage(t i18n.Translator, reason string, callID string, retryButtonID string) models.ChannelMessageContent {
+	description := t("checkin.failed.reason", i18n.Vars{"reason": reason})
+	if callID != "" {
+		description += "\n" + t("checkin.failed.call_id", i18n.Vars{"call_id": callID})
+	}
+
+	content := models.ChannelMessageContent{
 		Embed: []models.InteractiveMessageEmbed{
 			buildEmbed(
-				ColorGreen,
-				"✅ Check-in thành công!",
-				fmt.Sprintf("Chào mừng %s! Bạn đã check-in thành công.", userName),
+				ColorRed,
+				t("checkin.failed.title", nil),
+				description,
 			),
 		},
 	}
+	if retryButtonID != "" {
+		content.Components = []models.MessageComponent{
+			buildButton(retryButtonID, t("checkin.retry_button", nil), ButtonStyleSuccess),
+		}
+	}
+	return content
 }
 
-func BuildCheckinFailedMessage(reason string) models.ChannelMessageContent {
+func BuildCheckinNoticeMessage(t i18n.Translator, notice string) models.ChannelMessageContent {
 	return models.ChannelMessageContent{
 		Embed: []models.InteractiveMessageEmbed{
 			buildEmbed(
-				ColorRed,
-				"❌ Check-in thất bại",
-				fmt.Sprintf("Lý do: %s", reason),
+				ColorOrange,
+				t("checkin.notice.title", nil),
+				notice,
 			),
 		},
 	}
 }
 
+// ============================================================
+// MANAGER APPROVAL MESSAGES
+// ============================================================
+
+// BuildApprovalRequestMessage asks managers to approve a check-in that failed
+// recognition. imageURL may be empty or a data URI of the best capture.
+func BuildApprovalRequestMessage(user, callID string, attempts int, imageURL, approveID, rejectID string) models.ChannelMessageContent {
+	description := fmt.Sprintf("Không nhận diện được khuôn mặt của người dùng %s sau %d lần thử.\nVui lòng kiểm tra ảnh và duyệt check-in.", user, attempts)
+	if callID != "" {
+		description += fmt.Sprintf("\nMã cuộc gọi: %s", callID)
+	}
+
+	embed := buildEmbed(ColorOrange, "🙋 Yêu cầu duyệt check-in", description)
+	if imageURL != "" {
+		embed.Image = &models.EmbedImage{URL: imageURL}
+	}
+
+	return models.ChannelMessageContent{
+		Embed: []models.InteractiveMessageEmbed{embed},
+		Components: []models.MessageComponent{
+			buildButton(approveID, "✅ Duyệt", ButtonStyleSuccess),
+			buildButton(rejectID, "❌ Từ chối", ButtonStyleDanger),
+		},
+	}
+}
+
+// BuildAnnouncementMessage is a one-line check-in announcement for a team channel
+func BuildAnnouncementMessage(text string) models.ChannelMessageContent {
+	return models.ChannelMessageContent{
+		Embed: []models.InteractiveMessageEmbed{
+			buildEmbed(ColorGreen, "", text),
+		},
+	}
+}
+
+// BuildQRCodeMessage posts an office's current check-in QR code. The code
+// is only in the image, so it has to be scanned.
+func BuildQRCodeMessage(officeName, imageURL string, expires time.Time) models.ChannelMessageContent {
+	description := fmt.Sprintf("Quét mã và gửi nội dung cho bot qua tin nhắn riêng, sau đó gửi vị trí để check-in.\nHết hạn lúc %s", expires.Format("15:04"))
+
+	embed := buildEmbed(ColorPurple, "📷 Mã QR check-in - "+officeName, description)
+	embed.Image = &models.EmbedImage{URL: imageURL}
+
+	return models.ChannelMessageContent{
+		Embed: []models.InteractiveMessageEmbed{embed},
+	}
+}
+
+// BuildImageGalleryMessage shows one captioned embed per image
+func BuildImageGalleryMessage(title string, captions, imageURLs []string) models.ChannelMessageContent {
+	embeds := make([]models.InteractiveMessageEmbed, 0, len(imageURLs))
+	for i, url := range imageURLs {
+		embed := buildEmbed(ColorPurple, title, captions[i])
+		if url != "" {
+			embed.Image = &models.EmbedImage{URL: url}
+		}
+		embeds = append(embeds, embed)
+	}
+	return models.ChannelMessageContent{Embed: embeds}
+}
+
 // ============================================================
 // EMBED BUILDER
 // ============================================================
 
 func buildEmbed(color, title, description string) models.InteractiveMessageEmbed {
 	return models.InteractiveMessageEmbed{
-		Color:       color,
+		Color:       themeColor(color),
 		Title:       title,
 		Description: description,
 		Timestamp:   time.Now().Format(time.RFC3339),
@@ -78,10 +211,20 @@ func buildEmbed(color, title, description string) models.InteractiveMessageEmbed
 }
 
 func buildFooter() *models.EmbedFooter {
-	return &models.EmbedFooter{
+	footer := &models.EmbedFooter{
 		Text:    FooterText,
 		IconURL: MezonIconURL,
 	}
+
+	themeMu.RLock()
+	defer themeMu.RUnlock()
+	if theme.FooterText != "" {
+		footer.Text = theme.FooterText
+	}
+	if theme.FooterIconURL != "" {
+		footer.IconURL = theme.FooterIconURL
+	}
+	return footer
 }
 
 // ============================================================
@@ -127,6 +270,14 @@ func BuildErrorMessage(title, description string) models.ChannelMessageContent {
 	}
 }
 
+func BuildFieldsMessage(title, description string, fields []models.EmbedField) models.ChannelMessageContent {
+	embed := buildEmbed(ColorPurple, title, description)
+	embed.Fields = fields
+	return models.ChannelMessageContent{
+		Embed: []models.InteractiveMessageEmbed{embed},
+	}
+}
+
 func BuildMessageWithButtons(title, description string, buttons []MessageButton) models.ChannelMessageContent {
 	components := make([]models.MessageComponent, len(buttons))
 	for i, btn := range buttons {
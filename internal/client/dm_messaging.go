@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"mezon-checkin-bot/mezon-protobuf/go/api"
 	rtapi "mezon-checkin-bot/mezon-protobuf/go/rtapi"
 	"mezon-checkin-bot/models"
 	"time"
@@ -19,49 +19,213 @@ func (dm *DMManager) SendDM(channelID int64, userID int64, content models.Channe
 }
 
 func (dm *DMManager) SendDMWithContext(ctx context.Context, channelID int64, userID int64, content models.ChannelMessageContent) error {
+	_, err := dm.SendDMWithID(ctx, channelID, userID, content)
+	return err
+}
+
+// SendDMWithID sends a DM and returns its message ID, for later updates.
+// The ID is 0 if the server didn't acknowledge with one.
+func (dm *DMManager) SendDMWithID(ctx context.Context, channelID int64, userID int64, content models.ChannelMessageContent) (int64, error) {
+	return dm.sendDM(ctx, channelID, userID, content, nil)
+}
+
+// SendDMReply sends a DM as a reply to one of the user's messages, so the
+// answer is shown threaded under it
+func (dm *DMManager) SendDMReply(ctx context.Context, channelID int64, userID int64, content models.ChannelMessageContent, replyTo *api.ChannelMessage) (int64, error) {
+	var references []*api.MessageRef
+	if replyTo != nil && replyTo.MessageId != 0 {
+		references = append(references, messageReference(replyTo))
+	}
+	return dm.sendDM(ctx, channelID, userID, content, references)
+}
+
+func (dm *DMManager) sendDM(ctx context.Context, channelID int64, userID int64, content models.ChannelMessageContent, references []*api.MessageRef) (int64, error) {
 	// Ensure DM clan is ready (lazy init)
 	if err := dm.ensureDMReady(); err != nil {
-		return fmt.Errorf("failed to ensure DM ready: %w", err)
+		return 0, fmt.Errorf("failed to ensure DM ready: %w", err)
 	}
 
-	// Check connection health
-	if !dm.client.IsConnected() {
-		log.Println("   ⚠️  WebSocket disconnected, waiting for reconnection...")
-
-		// Wait for reconnection (max 5s)
-		for i := 0; i < 10; i++ {
-			time.Sleep(500 * time.Millisecond)
-			if dm.client.IsConnected() {
-				log.Println("   ✅ Connection restored, sending message...")
-				break
-			}
-		}
-
-		if !dm.client.IsConnected() {
-			return fmt.Errorf("websocket not connected after waiting")
-		}
+	if err := dm.waitConnected(); err != nil {
+		return 0, err
 	}
 
 	// Build protobuf envelope
-	envelope, err := dm.buildDMEnvelope(channelID, content)
+	envelope, err := dm.buildDMEnvelope(channelID, content, references)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Send with response (to ensure message is delivered)
-	if err := dm.sendDMMessage(ctx, envelope, channelID, userID); err != nil {
+	messageID, err := dm.sendDMMessage(ctx, envelope, channelID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	logger.Info("DM sent", "channel_id", channelID, "user_id", userID, "message_id", messageID, "reply", len(references) > 0)
+	return messageID, nil
+}
+
+// UpdateDM replaces the content of a DM sent by the bot
+func (dm *DMManager) UpdateDM(ctx context.Context, channelID int64, messageID int64, content models.ChannelMessageContent) error {
+	if err := dm.ensureDMReady(); err != nil {
+		return fmt.Errorf("failed to ensure DM ready: %w", err)
+	}
+	if err := dm.waitConnected(); err != nil {
 		return err
 	}
 
-	log.Printf("✅ DM sent successfully!")
+	contentJSON, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal content: %w", err)
+	}
+
+	envelope := &rtapi.Envelope{
+		Message: &rtapi.Envelope_ChannelMessageUpdate{
+			ChannelMessageUpdate: &rtapi.ChannelMessageUpdate{
+				ClanId:      DMClanID,
+				ChannelId:   channelID,
+				MessageId:   messageID,
+				Mode:        DMChannelType,
+				IsPublic:    false,
+				Content:     string(contentJSON),
+				HideEditted: true, // A progressing status, not a correction
+			},
+		},
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	response, err := dm.client.sendWithResponse(envelope, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("update message failed: %w", err)
+	}
+	if response.GetError() != nil {
+		return fmt.Errorf("server error: code=%d, message=%s",
+			response.GetError().Code, response.GetError().Message)
+	}
+
+	logger.Info("DM updated", "channel_id", channelID, "message_id", messageID)
 	return nil
 }
 
+// DeleteDM removes a DM sent by the bot
+func (dm *DMManager) DeleteDM(ctx context.Context, channelID int64, messageID int64) error {
+	if err := dm.ensureDMReady(); err != nil {
+		return fmt.Errorf("failed to ensure DM ready: %w", err)
+	}
+	if err := dm.waitConnected(); err != nil {
+		return err
+	}
+
+	envelope := &rtapi.Envelope{
+		Message: &rtapi.Envelope_ChannelMessageRemove{
+			ChannelMessageRemove: &rtapi.ChannelMessageRemove{
+				ClanId:    DMClanID,
+				ChannelId: channelID,
+				MessageId: messageID,
+				Mode:      DMChannelType,
+				IsPublic:  false,
+			},
+		},
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	response, err := dm.client.sendWithResponse(envelope, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("remove message failed: %w", err)
+	}
+	if response.GetError() != nil {
+		return fmt.Errorf("server error: code=%d, message=%s",
+			response.GetError().Code, response.GetError().Message)
+	}
+
+	logger.Info("DM deleted", "channel_id", channelID, "message_id", messageID)
+	return nil
+}
+
+// ReactToDM adds the bot's emoji reaction to a message of a DM channel
+func (dm *DMManager) ReactToDM(channelID int64, msg *api.ChannelMessage, emoji string) error {
+	if err := dm.ensureDMReady(); err != nil {
+		return fmt.Errorf("failed to ensure DM ready: %w", err)
+	}
+	if !dm.client.IsConnected() {
+		return fmt.Errorf("websocket not connected")
+	}
+
+	envelope := &rtapi.Envelope{
+		Message: &rtapi.Envelope_MessageReactionEvent{
+			MessageReactionEvent: &api.MessageReaction{
+				Emoji:           emoji,
+				SenderId:        dm.client.ClientID,
+				Action:          false, // true removes the reaction
+				Count:           1,
+				ChannelId:       channelID,
+				MessageId:       msg.MessageId,
+				ClanId:          DMClanID,
+				Mode:            DMChannelType,
+				MessageSenderId: msg.SenderId,
+				IsPublic:        false,
+			},
+		},
+	}
+	return dm.client.sendMessage(envelope)
+}
+
+// SendTyping shows the bot as typing in a DM channel. Clients drop the
+// indicator after a few seconds, so it is resent while work goes on.
+func (dm *DMManager) SendTyping(channelID int64) error {
+	if err := dm.ensureDMReady(); err != nil {
+		return fmt.Errorf("failed to ensure DM ready: %w", err)
+	}
+	if !dm.client.IsConnected() {
+		return fmt.Errorf("websocket not connected")
+	}
+
+	envelope := &rtapi.Envelope{
+		Message: &rtapi.Envelope_MessageTypingEvent{
+			MessageTypingEvent: &rtapi.MessageTypingEvent{
+				ClanId:    DMClanID,
+				ChannelId: channelID,
+				SenderId:  dm.client.ClientID,
+				Mode:      DMChannelType,
+				IsPublic:  false,
+			},
+		},
+	}
+	return dm.client.sendMessage(envelope)
+}
+
+// waitConnected waits up to 5s for a dropped connection to come back
+func (dm *DMManager) waitConnected() error {
+	if dm.client.IsConnected() {
+		return nil
+	}
+	logger.Warn("WebSocket disconnected, waiting for reconnection")
+
+	for i := 0; i < 10; i++ {
+		time.Sleep(500 * time.Millisecond)
+		if dm.client.IsConnected() {
+			logger.Info("Connection restored, sending message")
+			return nil
+		}
+	}
+	return fmt.Errorf("websocket not connected after waiting")
+}
+
 // ============================================================
 // MESSAGE BUILDING (PROTOBUF)
 // ============================================================
 
-func (dm *DMManager) buildDMEnvelope(channelID int64, content models.ChannelMessageContent) (*rtapi.Envelope, error) {
+func (dm *DMManager) buildDMEnvelope(channelID int64, content models.ChannelMessageContent, references []*api.MessageRef) (*rtapi.Envelope, error) {
 	// Convert content to JSON string (models.ChannelMessageContent is not a protobuf message)
 	contentJSON, err := json.Marshal(content)
 	if err != nil {
@@ -72,11 +236,12 @@ func (dm *DMManager) buildDMEnvelope(channelID int64, content models.ChannelMess
 	envelope := &rtapi.Envelope{
 		Message: &rtapi.Envelope_ChannelMessageSend{
 			ChannelMessageSend: &rtapi.ChannelMessageSend{
-				ClanId:    DMClanID,
-				ChannelId: channelID,
-				Mode:      DMChannelType, // DM mode
-				IsPublic:  false,
-				Content:   string(contentJSON),
+				ClanId:     DMClanID,
+				ChannelId:  channelID,
+				Mode:       DMChannelType, // DM mode
+				IsPublic:   false,
+				Content:    string(contentJSON),
+				References: references,
 			},
 		},
 	}
@@ -84,13 +249,29 @@ func (dm *DMManager) buildDMEnvelope(channelID int64, content models.ChannelMess
 	return envelope, nil
 }
 
-func (dm *DMManager) sendDMMessage(ctx context.Context, envelope *rtapi.Envelope, channelID int64, userID int64) error {
+// messageReference builds the reply reference to msg, carrying the quoted
+// content and sender the client shows above the reply
+func messageReference(msg *api.ChannelMessage) *api.MessageRef {
+	return &api.MessageRef{
+		MessageRefId:             msg.MessageId,
+		Content:                  msg.Content,
+		HasAttachment:            len(msg.Attachments) > 0,
+		RefType:                  MessageRefReply,
+		MessageSenderId:          msg.SenderId,
+		MessageSenderUsername:    msg.Username,
+		MesagesSenderAvatar:      msg.Avatar,
+		MessageSenderClanNick:    msg.ClanNick,
+		MessageSenderDisplayName: msg.DisplayName,
+	}
+}
+
+func (dm *DMManager) sendDMMessage(ctx context.Context, envelope *rtapi.Envelope, channelID int64, userID int64) (int64, error) {
 	// Check context
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return 0, ctx.Err()
 	case <-dm.client.ctx.Done():
-		return fmt.Errorf("client closed")
+		return 0, fmt.Errorf("client closed")
 	default:
 	}
 
@@ -100,27 +281,24 @@ func (dm *DMManager) sendDMMessage(ctx context.Context, envelope *rtapi.Envelope
 	timeout := 5 * time.Second
 	response, err := dm.client.sendWithResponse(envelope, timeout)
 	if err != nil {
-		return fmt.Errorf("send message failed: %w", err)
+		return 0, fmt.Errorf("send message failed: %w", err)
 	}
 
 	// Check for server error
 	if response.GetError() != nil {
-		return fmt.Errorf("server error: code=%d, message=%s",
+		return 0, fmt.Errorf("server error: code=%d, message=%s",
 			response.GetError().Code, response.GetError().Message)
 	}
 
 	// Get message ACK
 	if ack := response.GetChannelMessageAck(); ack != nil {
-		log.Printf("   Message ID: %d", ack.MessageId)
-		log.Printf("   Create Time: %d", ack.CreateTimeSeconds)
-		return nil
+		logger.Debug("DM acknowledged", "message_id", ack.MessageId, "create_time", ack.CreateTimeSeconds)
+		return ack.MessageId, nil
 	}
 
-	return nil
+	return 0, nil
 }
 
 func (dm *DMManager) logSendDM(channelID int64, userID int64) {
-	log.Printf("📤 Sending DM...")
-	log.Printf("   Channel ID: %d", channelID)
-	log.Printf("   User ID: %d", userID)
+	logger.Info("Sending DM", "channel_id", channelID, "user_id", userID)
 }
@@ -3,7 +3,6 @@ package client
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"mezon-checkin-bot/mezon-protobuf/go/api"
 	"mezon-checkin-bot/mezon-protobuf/go/rtapi"
 	"mezon-checkin-bot/models"
@@ -26,6 +25,9 @@ const (
 
 	// Google Maps URL patterns
 	GoogleMapsPattern = "google.com/maps"
+
+	// Prefix of bot chat commands (e.g. "!office list")
+	CommandPrefix = "!"
 )
 
 // ============================================================
@@ -33,14 +35,42 @@ const (
 // ============================================================
 
 type MessageContent struct {
-	T   string `json:"t"`   // Text content containing URLs
-	Fwd bool   `json:"fwd"` // Forwarded message
+	T        string           `json:"t"`                  // Text content containing URLs
+	Fwd      bool             `json:"fwd"`                // Forwarded message
+	Accuracy float64          `json:"accuracy,omitempty"` // Reported GPS accuracy in meters
+	Location *LocationPayload `json:"location,omitempty"` // Structured location share
+}
+
+// Attachment is a file attached to a message, as listed in its attachments JSON
+type Attachment struct {
+	Filename string `json:"filename"`
+	URL      string `json:"url"`
+	Filetype string `json:"filetype"`
+	Size     int64  `json:"size"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+}
+
+// IsImage reports whether the attachment is a picture
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(strings.ToLower(a.Filetype), "image/")
+}
+
+// LocationPayload is the structured location sent by the Mezon apps'
+// location share, preferred over parsing a map link out of the text
+type LocationPayload struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy,omitempty"` // Meters
 }
 
 type LocationInfo struct {
 	Latitude  float64
 	Longitude float64
+	Accuracy  float64 // Meters, 0 if not reported
 	IsValid   bool
+	Typed     bool   // Parsed from text rather than a location share
+	ShortLink string // Map short link to resolve, Latitude and Longitude are unset
 }
 
 // ============================================================
@@ -81,29 +111,179 @@ func (c *MezonClient) parseChannelMessage(eventData interface{}) (*api.ChannelMe
 func (c *MezonClient) handleChannelMessage(eventData interface{}) {
 	message, err := c.parseChannelMessage(eventData)
 	if err != nil {
-		log.Printf("❌ Failed to parse channel_message: %v", err)
+		logger.Error("Failed to parse channel_message", "err", err)
+		return
+	}
+
+	if message.SenderId == c.ClientID {
 		return
 	}
 
 	c.logChannelMessage(message)
 
-	// Check and handle location messages
-	locationInfo, err := c.extractLocationFromMessage(message)
-	if err == nil && locationInfo.IsValid && message.Code == int32(models.CodeLocationSend) {
-		c.handleLocationMessage(message, locationInfo)
+	if c.UserBlocked(message.SenderId) {
+		logger.Debug("Ignoring message from a blocked user", "user_id", message.SenderId)
+		return
+	}
+
+	// Commands come first: an image captioned with a command is a command
+	text := extractMessageText(message)
+	if strings.HasPrefix(text, CommandPrefix) {
+		c.handleCommandMessage(message, text)
+		return
+	}
+
+	// Location shares carry CodeLocationSend; iPhone users paste links or raw
+	// coordinates as text, which is flagged as typed for manual review. Clan
+	// channel chatter is only read by users asked for their location.
+	if message.ClanId == DMClanID || c.awaitsLocation(message.SenderId) {
+		locationInfo, err := c.extractLocationFromMessage(message)
+		if err == nil && locationInfo.IsValid {
+			c.handleLocationMessage(message, locationInfo)
+			return
+		}
+	}
+
+	// Pictures sent to the bot by DM (selfie check-in)
+	if images := extractImageAttachments(message); len(images) > 0 {
+		if message.ClanId != DMClanID {
+			return
+		}
+		c.emit("image_message_received", map[string]interface{}{
+			"message":     message,
+			"attachments": images,
+			"user_id":     message.SenderId,
+			"channel_id":  message.ChannelId,
+		})
+		return
+	}
+
+	// Other text may answer a bot question
+	if text != "" {
+		c.emit("text_message_received", map[string]interface{}{
+			"message":    message,
+			"text":       text,
+			"user_id":    message.SenderId,
+			"channel_id": message.ChannelId,
+		})
 	}
 }
 
-func (c *MezonClient) logChannelMessage(msg *api.ChannelMessage) {
-	log.Printf("📨 Channel message received")
-	log.Printf("   From: %s (%s)", msg.DisplayName, msg.Username)
-	log.Printf("   Channel ID: %d", msg.ChannelId)
-	log.Printf("   Message ID: %d", msg.MessageId)
-	log.Printf("   Code      : %s", strconv.Itoa(int(msg.Code)))
-	// Quick check for location link
-	if strings.Contains(msg.Content, GoogleMapsPattern) {
-		log.Printf("   📍 Contains location link")
+// extractMessageText returns the plain text of a message
+func extractMessageText(msg *api.ChannelMessage) string {
+	var content MessageContent
+	if err := json.Unmarshal([]byte(msg.Content), &content); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(content.T)
+}
+
+// extractImageAttachments returns the message's image attachments
+func extractImageAttachments(msg *api.ChannelMessage) []Attachment {
+	if len(msg.Attachments) == 0 {
+		return nil
+	}
+
+	var attachments []Attachment
+	if err := json.Unmarshal(msg.Attachments, &attachments); err != nil {
+		logger.Debug("Unparsable message attachments", "message_id", msg.MessageId, "err", err)
+		return nil
+	}
+
+	images := attachments[:0]
+	for _, attachment := range attachments {
+		if attachment.IsImage() && attachment.URL != "" {
+			images = append(images, attachment)
+		}
 	}
+	return images
+}
+
+// ============================================================
+// COMMAND PARSING
+// ============================================================
+
+func (c *MezonClient) handleCommandMessage(msg *api.ChannelMessage, text string) {
+	fields := strings.Fields(strings.TrimPrefix(text, CommandPrefix))
+	if len(fields) == 0 {
+		return
+	}
+
+	// Replies go out by DM, and commands typed in a clan channel would show
+	// their arguments (user IDs, coordinates) to everyone there. The admin
+	// channel is the exception: its members are the operators.
+	if msg.ClanId != DMClanID {
+		if !c.isAdminChannel(msg.ClanId, msg.ChannelId) {
+			logger.Warn("Ignoring command outside a DM", "user_id", msg.SenderId, "channel_id", msg.ChannelId, "clan_id", msg.ClanId)
+			return
+		}
+		logger.Info("Admin channel command received", "user_id", msg.SenderId, "channel_id", msg.ChannelId, "text", text)
+		c.emit("admin_channel_command", map[string]interface{}{
+			"message":    msg,
+			"command":    strings.ToLower(fields[0]),
+			"args":       fields[1:],
+			"user_id":    msg.SenderId,
+			"clan_id":    msg.ClanId,
+			"channel_id": msg.ChannelId,
+		})
+		return
+	}
+
+	logger.Info("Command received", "user_id", msg.SenderId, "channel_id", msg.ChannelId, "display_name", msg.DisplayName, "text", text)
+
+	c.emit("command_received", map[string]interface{}{
+		"message":      msg,
+		"command":      strings.ToLower(fields[0]),
+		"args":         fields[1:],
+		"user_id":      msg.SenderId,
+		"channel_id":   msg.ChannelId,
+		"username":     msg.Username,
+		"display_name": msg.DisplayName,
+	})
+}
+
+// SetAdminChannel accepts commands typed in a clan channel, besides DMs.
+// They are emitted as "admin_channel_command"; the bot must be a member.
+func (c *MezonClient) SetAdminChannel(clanID, channelID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.adminClanID, c.adminChannelID = clanID, channelID
+}
+
+// SetLocationAwaited makes clan channel messages parsed as locations only
+// for the users awaited reports, e.g. those with a pending check-in. DMs are
+// always parsed.
+func (c *MezonClient) SetLocationAwaited(awaited func(userID int64) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.locationAwaited = awaited
+}
+
+func (c *MezonClient) awaitsLocation(userID int64) bool {
+	c.mu.RLock()
+	awaited := c.locationAwaited
+	c.mu.RUnlock()
+	return awaited != nil && awaited(userID)
+}
+
+func (c *MezonClient) isAdminChannel(clanID, channelID int64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.adminChannelID != 0 && clanID == c.adminClanID && channelID == c.adminChannelID
+}
+
+func (c *MezonClient) logChannelMessage(msg *api.ChannelMessage) {
+	logger.Info("Channel message received",
+		"display_name", msg.DisplayName,
+		"username", msg.Username,
+		"user_id", msg.SenderId,
+		"channel_id", msg.ChannelId,
+		"message_id", msg.MessageId,
+		"code", msg.Code,
+		// Quick check for location link or structured location
+		"location_link", strings.Contains(msg.Content, GoogleMapsPattern),
+		"structured_location", strings.Contains(msg.Content, `"location":`),
+	)
 }
 
 // ============================================================
@@ -114,7 +294,9 @@ func (c *MezonClient) logChannelMessage(msg *api.ChannelMessage) {
 // Supported formats:
 //   - https://www.google.com/maps?q=18.701103,105.679654
 //   - https://maps.google.com/maps?q=18.701103,105.679654
+//   - https://www.google.com/maps/search/?api=1&query=18.701103,105.679654
 //   - https://www.google.com/maps/@18.701103,105.679654,14z
+//   - https://www.google.com/maps?q=7PH7XQC4%2B2V (Plus Code)
 func parseGoogleMapsURL(mapURL string) (float64, float64, error) {
 	if mapURL == "" {
 		return 0, 0, fmt.Errorf("empty URL")
@@ -126,8 +308,16 @@ func parseGoogleMapsURL(mapURL string) (float64, float64, error) {
 		return 0, 0, fmt.Errorf("invalid URL format: %w", err)
 	}
 
-	// Try to extract from query parameter 'q'
-	if q := u.Query().Get("q"); q != "" {
+	// Try to extract from query parameters 'q', 'query' (api=1) and 'll'
+	for _, key := range []string{"q", "query", "ll"} {
+		q := u.Query().Get(key)
+		if q == "" {
+			continue
+		}
+		// An unescaped '+' in a Plus Code is decoded as a space
+		if code := plusCodePattern.FindString(strings.Replace(q, " ", "+", 1)); code != "" {
+			return decodePlusCode(code)
+		}
 		return parseCoordinatesString(q)
 	}
 
@@ -192,13 +382,34 @@ func (c *MezonClient) extractLocationFromMessage(msg *api.ChannelMessage) (Locat
 		return result, fmt.Errorf("failed to parse content: %w", err)
 	}
 
-	// Check if content contains Google Maps URL
-	if !strings.Contains(content.T, GoogleMapsPattern) {
-		return result, fmt.Errorf("not a Google Maps URL")
-	}
-
-	// Extract coordinates
-	lat, lon, err := parseGoogleMapsURL(content.T)
+	// Structured payload first; the text parsers remain as fallback for
+	// clients that only send a link
+	if loc := content.Location; loc != nil {
+		if err := validateCoordinates(loc.Latitude, loc.Longitude); err != nil {
+			return result, fmt.Errorf("invalid structured location: %w", err)
+		}
+		result.Latitude = loc.Latitude
+		result.Longitude = loc.Longitude
+		result.Accuracy = loc.Accuracy
+		result.IsValid = true
+		return result, nil
+	}
+
+	// Extract coordinates from a map link, raw "lat, lon" or Plus Code. Only
+	// location shares carry CodeLocationSend; anything else was typed or
+	// pasted and may not be where the user is.
+	result.Typed = msg.Code != int32(models.CodeLocationSend)
+	result.Accuracy = content.Accuracy
+	if result.Accuracy == 0 {
+		result.Accuracy = parseAccuracy(content.T)
+	}
+	if link := shortMapLink(content.T); link != "" {
+		// Resolving takes HTTP requests, left to the confirmation flow
+		result.ShortLink = link
+		result.IsValid = true
+		return result, nil
+	}
+	lat, lon, err := parseLocationText(content.T)
 	if err != nil {
 		return result, fmt.Errorf("failed to parse coordinates: %w", err)
 	}
@@ -211,21 +422,31 @@ func (c *MezonClient) extractLocationFromMessage(msg *api.ChannelMessage) (Locat
 }
 
 func (c *MezonClient) handleLocationMessage(msg *api.ChannelMessage, location LocationInfo) {
-	log.Printf("📍 Processing location message from %s", msg.DisplayName)
-	log.Printf("   📍 Coordinates: (%.6f, %.6f)", location.Latitude, location.Longitude)
+	logger.Info("Processing location message",
+		"display_name", msg.DisplayName,
+		"user_id", msg.SenderId,
+		"channel_id", msg.ChannelId,
+		"lat", location.Latitude,
+		"lon", location.Longitude,
+		"accuracy_m", location.Accuracy,
+		"short_link", location.ShortLink,
+	)
 
 	// Emit event with parsed coordinates
 	c.emit("location_message_received", map[string]interface{}{
 		"message":      msg,
 		"latitude":     location.Latitude,
 		"longitude":    location.Longitude,
+		"accuracy":     location.Accuracy,
+		"typed":        location.Typed,
+		"short_link":   location.ShortLink,
 		"user_id":      msg.SenderId,
 		"channel_id":   msg.ChannelId,
 		"username":     msg.Username,
 		"display_name": msg.DisplayName,
 	})
 
-	log.Printf("✅ Location message event emitted")
+	logger.Debug("Location message event emitted")
 }
 
 // ============================================================
@@ -233,10 +454,10 @@ func (c *MezonClient) handleLocationMessage(msg *api.ChannelMessage, location Lo
 // ============================================================
 
 func (c *MezonClient) SetupEventHandlers() {
-	log.Printf("🎧 Setting up event handlers for MezonClient...")
+	logger.Info("Setting up event handlers")
 	c.setupUserChannelAddedHandler()
 	c.setupChannelMessageHandler()
-	log.Printf("✅ Event handlers setup complete")
+	logger.Info("Event handlers ready")
 }
 
 // ============================================================
@@ -245,7 +466,6 @@ func (c *MezonClient) SetupEventHandlers() {
 
 func (c *MezonClient) setupUserChannelAddedHandler() {
 	c.On("user_channel_added_event", func(data interface{}) {
-		log.Printf("user_channel_added_event")
 		c.handleUserChannelAdded(data)
 	})
 }
@@ -253,14 +473,13 @@ func (c *MezonClient) setupUserChannelAddedHandler() {
 func (c *MezonClient) handleUserChannelAdded(eventData interface{}) {
 	event, err := c.parseUserChannelAdded(eventData)
 	if err != nil {
-		log.Printf("❌ Failed to parse user_channel_added_event: %v", err)
+		logger.Error("Failed to parse user_channel_added_event", "err", err)
 		return
 	}
 
 	c.logUserChannelAdded(event)
 
 	if !c.shouldAutoJoin(event) {
-		log.Printf("ℹ️  Client not in added users, skipping auto-join")
 		return
 	}
 
@@ -282,22 +501,20 @@ func (c *MezonClient) parseUserChannelAdded(eventData interface{}) (*rtapi.UserC
 }
 
 func (c *MezonClient) logUserChannelAdded(event *rtapi.UserChannelAdded) {
-	log.Printf("📨 Received user_channel_added_event")
-	log.Printf("   Clan ID: %d", event.ClanId)
-	log.Printf("   Channel ID: %d", event.ChannelDesc.ChannelId)
-	log.Printf("   Channel Label: %s", event.ChannelDesc.ChannelLabel)
-
-	channelType := c.getChannelType(event)
-	log.Printf("   Channel Type: %d", channelType)
-	log.Printf("   Users count: %d", len(event.Users))
-
+	args := []any{
+		"clan_id", event.ClanId,
+		"channel_id", event.ChannelDesc.ChannelId,
+		"channel_label", event.ChannelDesc.ChannelLabel,
+		"channel_type", c.getChannelType(event),
+		"users", len(event.Users),
+	}
 	if event.Caller != nil {
-		log.Printf("   Caller: %s (%d)", event.Caller.Username, event.Caller.UserId)
+		args = append(args, "caller", event.Caller.Username, "caller_id", event.Caller.UserId)
 	}
-
 	if event.Status != "" {
-		log.Printf("   Status: %s", event.Status)
+		args = append(args, "status", event.Status)
 	}
+	logger.Info("Received user_channel_added_event", args...)
 }
 
 func (c *MezonClient) getChannelType(event *rtapi.UserChannelAdded) int {
@@ -318,17 +535,33 @@ func (c *MezonClient) shouldAutoJoin(event *rtapi.UserChannelAdded) bool {
 		return false
 	}
 
+	added := false
 	for _, user := range event.Users {
 		if user.UserId == c.ClientID {
-			return true
+			added = true
+			break
 		}
 	}
-	return false
+	if !added {
+		logger.Info("Client not in added users, skipping auto-join")
+		return false
+	}
 
+	if event.Caller != nil && c.UserBlocked(event.Caller.UserId) {
+		logger.Warn("Added by a blocked user, skipping auto-join",
+			"caller_id", event.Caller.UserId, "channel_id", event.ChannelDesc.ChannelId)
+		return false
+	}
+	if !c.channelAllowed(event.ClanId, event.ChannelDesc.ChannelId) {
+		logger.Warn("Channel not allowed, skipping auto-join",
+			"clan_id", event.ClanId, "channel_id", event.ChannelDesc.ChannelId)
+		return false
+	}
+	return true
 }
 
 func (c *MezonClient) autoJoinChannel(event *rtapi.UserChannelAdded) {
-	log.Printf("✅ Client was added to channel, auto-joining...")
+	logger.Info("Client was added to channel, auto-joining", "channel_id", event.ChannelDesc.ChannelId)
 
 	channelType := c.getChannelType(event)
 	err := c.JoinChat(
@@ -343,12 +576,12 @@ func (c *MezonClient) autoJoinChannel(event *rtapi.UserChannelAdded) {
 		return
 	}
 
-	log.Printf("✅ Successfully auto-joined channel: %d", event.ChannelDesc.ChannelId)
+	logger.Info("Auto-joined channel", "channel_id", event.ChannelDesc.ChannelId)
 	c.emit("user_channel_joined", event)
 }
 
 func (c *MezonClient) handleAutoJoinError(event *rtapi.UserChannelAdded, err error) {
-	log.Printf("❌ Failed to auto-join channel: %v", err)
+	logger.Error("Failed to auto-join channel", "channel_id", event.ChannelDesc.ChannelId, "err", err)
 	c.emit("user_channel_added_error", map[string]interface{}{
 		"event": event,
 		"error": err.Error(),
@@ -382,7 +615,7 @@ func (c *MezonClient) JoinChat(clanID int64, channelID int64, channelType int, i
 		return fmt.Errorf("send join chat message failed: %w", err)
 	}
 
-	log.Printf("✅ Join chat request sent successfully")
+	logger.Info("Join chat request sent", "channel_id", channelID)
 	return nil
 }
 
@@ -412,14 +645,15 @@ func (c *MezonClient) JoinChatWithResponse(clanID int64, channelID int64, channe
 		return nil, fmt.Errorf("send join chat message failed: %w", err)
 	}
 
-	log.Printf("✅ Successfully joined channel: %d", channelID)
+	logger.Info("Joined channel", "channel_id", channelID)
 	return response, nil
 }
 
 func (c *MezonClient) logJoinChat(clanID int64, channelID int64, channelType int, isPublic bool) {
-	log.Printf("🔗 Joining chat...")
-	log.Printf("   Clan ID: %d", clanID)
-	log.Printf("   Channel ID: %d", channelID)
-	log.Printf("   Channel Type: %d", channelType)
-	log.Printf("   Is Public: %v", isPublic)
+	logger.Info("Joining chat",
+		"clan_id", clanID,
+		"channel_id", channelID,
+		"channel_type", channelType,
+		"public", isPublic,
+	)
 }
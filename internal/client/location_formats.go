@@ -0,0 +1,246 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// LOCATION FORMATS - Map links, raw coordinates and Plus Codes
+// ============================================================
+
+const (
+	shortLinkTimeout  = 5 * time.Second
+	maxShortLinkHops  = 5
+	plusCodeAlphabet  = "23456789CFGHJMPQRVWX"
+	plusCodeSeparator = 8 // Position of '+' in a full code
+)
+
+var (
+	urlPattern       = regexp.MustCompile(`https?://\S+`)
+	rawCoordsPattern = regexp.MustCompile(`^\s*(-?\d{1,3}(?:\.\d+)?)\s*[,;]\s*(-?\d{1,3}(?:\.\d+)?)\s*$`)
+	plusCodePattern  = regexp.MustCompile(`(?i)\b[23456789CFGHJMPQRVWX]{8}\+[23456789CFGHJMPQRVWX]{2,}`)
+	accuracyPattern  = regexp.MustCompile(`(?i)(?:±|\+/-|accuracy:?|độ chính xác:?)\s*(\d+(?:\.\d+)?)\s*m\b`)
+
+	// Short links are resolved by following redirects manually so the
+	// intermediate Location headers can be inspected
+	shortLinkClient = &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+)
+
+// shortMapLink returns the map short link in a message text, "" if none.
+// Short links are resolved with ResolveLocationLink, off the message dispatch.
+func shortMapLink(text string) string {
+	link := urlPattern.FindString(text)
+	if link == "" {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil || !isShortMapHost(u) {
+		return ""
+	}
+	return link
+}
+
+func isShortMapHost(u *url.URL) bool {
+	host := strings.ToLower(u.Host)
+	return host == "maps.app.goo.gl" || (host == "goo.gl" && strings.HasPrefix(u.Path, "/maps"))
+}
+
+// ResolveLocationLink follows a map short link and parses the coordinates
+// of the map URL it leads to. The HTTP requests take up to shortLinkTimeout.
+func ResolveLocationLink(ctx context.Context, shortURL string) (float64, float64, error) {
+	resolved, err := resolveShortLink(ctx, shortURL)
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseMapURL(resolved)
+}
+
+// parseLocationText extracts coordinates from a message text: a map link,
+// plain "lat, lon" or a full Plus Code
+func parseLocationText(text string) (float64, float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, 0, fmt.Errorf("empty text")
+	}
+
+	if link := urlPattern.FindString(text); link != "" {
+		return parseMapURL(link)
+	}
+
+	if m := rawCoordsPattern.FindStringSubmatch(text); m != nil {
+		return parseCoordinatesString(m[1] + "," + m[2])
+	}
+
+	if code := plusCodePattern.FindString(text); code != "" {
+		return decodePlusCode(code)
+	}
+
+	return 0, 0, fmt.Errorf("no location found")
+}
+
+// parseAccuracy extracts the reported GPS accuracy in meters ("±25m",
+// "accuracy: 25m"). Returns 0 if the text does not carry one.
+func parseAccuracy(text string) float64 {
+	m := accuracyPattern.FindStringSubmatch(text)
+	if m == nil {
+		return 0
+	}
+	accuracy, err := strconv.ParseFloat(m[1], 64)
+	if err != nil || accuracy < 0 {
+		return 0
+	}
+	return accuracy
+}
+
+// parseMapURL dispatches on the map provider
+func parseMapURL(mapURL string) (float64, float64, error) {
+	u, err := url.Parse(mapURL)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid URL format: %w", err)
+	}
+
+	host := strings.ToLower(u.Host)
+	switch {
+	case isShortMapHost(u):
+		return 0, 0, fmt.Errorf("short link %s was not resolved", mapURL)
+
+	case strings.Contains(host, "google."):
+		return parseGoogleMapsURL(mapURL)
+
+	case strings.Contains(host, "maps.apple.com"):
+		return parseAppleMapsURL(u)
+
+	case strings.Contains(host, "openstreetmap.org") || host == "osm.org":
+		return parseOSMURL(u)
+	}
+
+	return 0, 0, fmt.Errorf("unsupported map provider: %s", host)
+}
+
+// resolveShortLink follows redirects of a short link until a full map URL.
+// All hops share one shortLinkTimeout.
+func resolveShortLink(ctx context.Context, shortURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, shortLinkTimeout)
+	defer cancel()
+
+	current := shortURL
+	for hop := 0; hop < maxShortLinkHops; hop++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, current, nil)
+		if err != nil {
+			return "", fmt.Errorf("invalid short link: %w", err)
+		}
+		resp, err := shortLinkClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve short link: %w", err)
+		}
+		resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			return current, nil
+		}
+
+		next, err := resp.Request.URL.Parse(location)
+		if err != nil {
+			return "", fmt.Errorf("invalid redirect: %w", err)
+		}
+		current = next.String()
+
+		host := strings.ToLower(next.Host)
+		if host != "maps.app.goo.gl" && host != "goo.gl" {
+			return current, nil
+		}
+	}
+	return "", fmt.Errorf("too many redirects resolving %s", shortURL)
+}
+
+// parseAppleMapsURL handles ?ll=, ?q=lat,lon, ?coordinate= and ?sll=
+func parseAppleMapsURL(u *url.URL) (float64, float64, error) {
+	query := u.Query()
+	for _, key := range []string{"ll", "coordinate", "q", "sll", "daddr"} {
+		if v := query.Get(key); v != "" {
+			if lat, lon, err := parseCoordinatesString(v); err == nil {
+				return lat, lon, nil
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("no coordinates found in Apple Maps URL")
+}
+
+// parseOSMURL handles ?mlat=&mlon= and #map=zoom/lat/lon
+func parseOSMURL(u *url.URL) (float64, float64, error) {
+	query := u.Query()
+	if mlat, mlon := query.Get("mlat"), query.Get("mlon"); mlat != "" && mlon != "" {
+		return parseCoordinatesString(mlat + "," + mlon)
+	}
+
+	if fragment := strings.TrimPrefix(u.Fragment, "map="); fragment != u.Fragment {
+		parts := strings.Split(fragment, "/")
+		if len(parts) >= 3 {
+			return parseCoordinatesString(parts[1] + "," + parts[2])
+		}
+	}
+
+	return 0, 0, fmt.Errorf("no coordinates found in OpenStreetMap URL")
+}
+
+// decodePlusCode decodes a full Open Location Code (e.g. "7PH7XQC4+2V") to
+// the center of its area. Short codes need a reference locality and are
+// not supported.
+func decodePlusCode(code string) (float64, float64, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if strings.Index(code, "+") != plusCodeSeparator {
+		return 0, 0, fmt.Errorf("not a full plus code: %s", code)
+	}
+	digits := strings.Replace(code, "+", "", 1)
+
+	pairResolutions := []float64{20, 1, 0.05, 0.0025, 0.000125}
+
+	lat, lon := -90.0, -180.0
+	latSize, lonSize := 0.0, 0.0
+
+	for i, c := range digits {
+		value := strings.IndexRune(plusCodeAlphabet, c)
+		if value < 0 {
+			return 0, 0, fmt.Errorf("invalid plus code character %q", c)
+		}
+
+		if i < 2*len(pairResolutions) {
+			res := pairResolutions[i/2]
+			if i%2 == 0 {
+				lat += float64(value) * res
+				latSize = res
+			} else {
+				lon += float64(value) * res
+				lonSize = res
+			}
+			continue
+		}
+
+		// Grid refinement: 5 rows x 4 columns per digit
+		latSize /= 5
+		lonSize /= 4
+		lat += float64(value/4) * latSize
+		lon += float64(value%4) * lonSize
+	}
+
+	lat, lon = lat+latSize/2, lon+lonSize/2
+	if err := validateCoordinates(lat, lon); err != nil {
+		return 0, 0, err
+	}
+
+	lat, _ = strconv.ParseFloat(strconv.FormatFloat(lat, 'f', 7, 64), 64)
+	lon, _ = strconv.ParseFloat(strconv.FormatFloat(lon, 'f', 7, 64), 64)
+	return lat, lon, nil
+}
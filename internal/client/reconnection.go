@@ -2,7 +2,6 @@ package client
 
 import (
 	"fmt"
-	"log"
 	"time"
 )
 
@@ -19,10 +18,10 @@ func (c *MezonClient) handleDisconnect() {
 	c.isRetrying = true
 	c.reconnectMu.Unlock()
 
-	log.Println("🔄 Starting reconnection process...")
+	logger.Info("Starting reconnection")
 
 	if err := c.reconnectWithBackoff(); err != nil {
-		log.Printf("❌ Reconnection failed: %v", err)
+		logger.Error("Reconnection failed", "err", err)
 	}
 
 	c.reconnectMu.Lock()
@@ -41,7 +40,7 @@ func (c *MezonClient) reconnectWithBackoff() error {
 		}
 
 		attempts++
-		log.Printf("🔄 Reconnection attempt %d/%d", attempts, MaxRetries)
+		logger.Info("Reconnection attempt", "attempt", attempts, "max", MaxRetries)
 
 		// Wait before retry
 		select {
@@ -51,17 +50,30 @@ func (c *MezonClient) reconnectWithBackoff() error {
 		}
 
 		if err := c.attemptReconnect(); err != nil {
-			log.Printf("❌ Reconnection attempt %d failed: %v", attempts, err)
+			logger.Warn("Reconnection attempt failed", "attempt", attempts, "err", err)
+			if attempts == ReconnectAlertAt {
+				c.emit("reconnect_failed", map[string]interface{}{
+					"attempts": attempts,
+					"final":    false,
+					"error":    err.Error(),
+				})
+			}
 			retryInterval = c.calculateNextRetryInterval(retryInterval, maxRetryInterval)
 			continue
 		}
 
-		log.Println("✅ Reconnected successfully!")
+		logger.Info("Reconnected")
 		c.emit("reconnected", nil)
 		return nil
 	}
 
-	return fmt.Errorf("max reconnection attempts (%d) reached", MaxRetries)
+	err := fmt.Errorf("max reconnection attempts (%d) reached", MaxRetries)
+	c.emit("reconnect_failed", map[string]interface{}{
+		"attempts": attempts,
+		"final":    true,
+		"error":    err.Error(),
+	})
+	return err
 }
 
 func (c *MezonClient) attemptReconnect() error {
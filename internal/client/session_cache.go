@@ -0,0 +1,219 @@
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/utils"
+	"mezon-checkin-bot/mezon-protobuf/go/api"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// SESSION CACHE - Reuse the session across quick restarts
+// ============================================================
+
+// sessionExpiryMargin is how long a cached token must still be valid to be
+// reused, so it doesn't expire mid-connection
+const sessionExpiryMargin = 5 * time.Minute
+
+// cachedSession is the session saved to disk, encrypted
+type cachedSession struct {
+	BotID        int64     `json:"bot_id"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	SocketHost   string    `json:"socket_host"`
+	SocketPort   string    `json:"socket_port"`
+	SocketUseSSL bool      `json:"socket_use_ssl"`
+	Expires      time.Time `json:"expires"`
+}
+
+// SetSessionCache saves sessions to path so the first Login after a restart
+// can skip authentication. The file is encrypted with a key derived from the
+// bot token: it is only readable by whoever could authenticate anyway.
+func (c *MezonClient) SetSessionCache(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	c.mu.Lock()
+	c.sessionCachePath = path
+	c.mu.Unlock()
+	return nil
+}
+
+// restoreSession loads a cached session that is still valid. Only the first
+// Login uses it; reconnections authenticate normally.
+func (c *MezonClient) restoreSession() bool {
+	c.mu.Lock()
+	path := c.sessionCachePath
+	tried := c.sessionRestoreTried
+	c.sessionRestoreTried = true
+	c.mu.Unlock()
+	if path == "" || tried {
+		return false
+	}
+
+	cached, err := c.readSessionCache(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Ignoring session cache", "err", err)
+		}
+		return false
+	}
+	if cached.BotID != c.config.BotID {
+		logger.Info("Cached session belongs to another bot, authenticating")
+		return false
+	}
+	if time.Until(cached.Expires) < sessionExpiryMargin {
+		logger.Info("Cached session expired, authenticating", "expired", cached.Expires)
+		return false
+	}
+
+	c.config.SocketHost = cached.SocketHost
+	c.config.SocketPort = cached.SocketPort
+	c.config.SocketUseSSL = cached.SocketUseSSL
+	c.session = &api.Session{
+		Token:        cached.Token,
+		RefreshToken: cached.RefreshToken,
+	}
+	c.ClientID = c.config.BotID
+
+	logger.Info("Reusing cached session", "expires", cached.Expires)
+	return true
+}
+
+// saveSession writes the current session to the cache. Failures are only
+// logged: the next start authenticates instead.
+func (c *MezonClient) saveSession() {
+	c.mu.RLock()
+	path := c.sessionCachePath
+	c.mu.RUnlock()
+	if path == "" || c.session == nil {
+		return
+	}
+
+	expires, err := tokenExpiry(c.session.Token)
+	if err != nil {
+		logger.Warn("Session token has no expiry, not caching it", "err", err)
+		return
+	}
+
+	data, err := json.Marshal(cachedSession{
+		BotID:        c.config.BotID,
+		Token:        c.session.Token,
+		RefreshToken: c.session.RefreshToken,
+		SocketHost:   c.config.SocketHost,
+		SocketPort:   c.config.SocketPort,
+		SocketUseSSL: c.config.SocketUseSSL,
+		Expires:      expires,
+	})
+	if err != nil {
+		logger.Warn("Failed to marshal session cache", "err", err)
+		return
+	}
+
+	sealed, err := c.sealSession(data)
+	if err != nil {
+		logger.Warn("Failed to encrypt session cache", "err", err)
+		return
+	}
+
+	if err := utils.WriteFileAtomic(path, sealed, 0600); err != nil {
+		logger.Warn("Failed to write session cache", "err", err)
+	}
+}
+
+// clearSessionCache removes a cached session the server rejected
+func (c *MezonClient) clearSessionCache() {
+	c.mu.RLock()
+	path := c.sessionCachePath
+	c.mu.RUnlock()
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Failed to remove session cache", "err", err)
+	}
+}
+
+func (c *MezonClient) readSessionCache(path string) (cachedSession, error) {
+	var cached cachedSession
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return cached, err
+	}
+	data, err := c.openSession(sealed)
+	if err != nil {
+		return cached, err
+	}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return cached, fmt.Errorf("failed to parse session cache: %w", err)
+	}
+	return cached, nil
+}
+
+// sessionAEAD is AES-256-GCM keyed by the bot token
+func (c *MezonClient) sessionAEAD() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("mezon-checkin session cache:" + c.config.BotToken))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (c *MezonClient) sealSession(data []byte) ([]byte, error) {
+	aead, err := c.sessionAEAD()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func (c *MezonClient) openSession(sealed []byte) ([]byte, error) {
+	aead, err := c.sessionAEAD()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("session cache is truncated")
+	}
+	data, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt session cache (bot token changed?): %w", err)
+	}
+	return data, nil
+}
+
+// tokenExpiry reads the exp claim of a JWT session token
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode token payload: %w", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse token claims: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("token has no exp claim")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// ============================================================
+// EMBED THEME - Colors and footer overridable from a file
+// ============================================================
+
+// Theme overrides the embed colors and footer. Empty values keep the defaults.
+type Theme struct {
+	// Keyed by role: primary, success, error, warning. Values are "#RRGGBB".
+	Colors        map[string]string `json:"colors,omitempty"`
+	FooterText    string            `json:"footer_text,omitempty"`
+	FooterIconURL string            `json:"footer_icon_url,omitempty"`
+}
+
+// colorRoles maps the default colors to their theme role
+var colorRoles = map[string]string{
+	ColorPurple: "primary",
+	ColorGreen:  "success",
+	ColorRed:    "error",
+	ColorOrange: "warning",
+}
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+var (
+	theme   Theme
+	themeMu sync.RWMutex
+)
+
+// LoadTheme reads a theme file and applies it. A missing file resets the
+// defaults; on error the current theme is kept.
+func LoadTheme(path string) error {
+	var loaded Theme
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return fmt.Errorf("failed to read theme: %w", err)
+	default:
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return fmt.Errorf("failed to parse theme: %w", err)
+		}
+	}
+
+	for role, color := range loaded.Colors {
+		if !hexColorPattern.MatchString(color) {
+			return fmt.Errorf("invalid %s color %q (use #RRGGBB)", role, color)
+		}
+	}
+
+	SetTheme(loaded)
+	return nil
+}
+
+// SetTheme replaces the embed theme
+func SetTheme(t Theme) {
+	themeMu.Lock()
+	theme = t
+	themeMu.Unlock()
+}
+
+// themeColor returns the theme's override of a default color
+func themeColor(color string) string {
+	themeMu.RLock()
+	defer themeMu.RUnlock()
+	if override := theme.Colors[colorRoles[color]]; override != "" {
+		return override
+	}
+	return color
+}
@@ -3,7 +3,6 @@ package client
 import (
 	"fmt"
 	"io"
-	"log"
 	"mezon-checkin-bot/internal/utils"
 	rtapi "mezon-checkin-bot/mezon-protobuf/go/rtapi"
 	"net/http"
@@ -23,7 +22,7 @@ func (c *MezonClient) ConnectWebSocket() error {
 	}
 
 	wsURL := c.buildWebSocketURL()
-	log.Printf("🔌 Connecting to Mezon WebSocket...")
+	logger.Info("Connecting to Mezon WebSocket")
 
 	conn, wsResp, err := c.dialWebSocket(wsURL)
 	if err != nil {
@@ -120,21 +119,17 @@ func (c *MezonClient) dialWebSocket(wsURL string) (*websocket.Conn, *http.Respon
 
 func (c *MezonClient) logWebSocketError(wsResp *http.Response, err error) {
 	if wsResp != nil {
-		log.Printf("❌ HTTP Status: %d", wsResp.StatusCode)
 		body, _ := io.ReadAll(wsResp.Body)
-		if len(body) > 0 {
-			log.Printf("❌ Response: %s", string(body))
-		}
+		logger.Error("WebSocket handshake failed", "status", wsResp.StatusCode, "body", string(body))
 	}
 
 	if err != nil {
-		log.Printf("❌ WebSocket error: %v", err)
+		logger.Error("WebSocket error", "err", err)
 	}
 }
 
 func (c *MezonClient) logConnectionSuccess() {
-	log.Println("✅ Connected to Mezon WebSocket")
-	log.Printf("   Client ID: %d", c.ClientID)
+	logger.Info("Connected to Mezon WebSocket", "client_id", c.ClientID)
 }
 
 // ============================================================
@@ -143,7 +138,7 @@ func (c *MezonClient) logConnectionSuccess() {
 
 func (c *MezonClient) handleMessages() {
 	defer c.wg.Done()
-	defer log.Println("🔌 Message handler stopped")
+	defer logger.Info("Message handler stopped")
 
 	for {
 		select {
@@ -169,7 +164,7 @@ func (c *MezonClient) handleMessages() {
 				return
 			}
 
-			log.Printf("❌ WebSocket read error: %v", err)
+			logger.Error("WebSocket read failed", "err", err)
 			if !c.isHardDisconnect {
 				go c.handleDisconnect()
 			}
@@ -181,15 +176,17 @@ func (c *MezonClient) handleMessages() {
 		case websocket.BinaryMessage:
 			c.processProtobufMessage(message)
 		case websocket.TextMessage:
-			log.Printf("📄 Text message (unexpected): %s", string(message))
+			logger.Warn("Unexpected text message", "message", string(message))
 		}
 	}
 }
 
 func (c *MezonClient) processProtobufMessage(message []byte) {
+	c.lastReceived.Store(time.Now().UnixNano())
+
 	var envelope rtapi.Envelope
 	if err := proto.Unmarshal(message, &envelope); err != nil {
-		log.Printf("⚠️ Protobuf decode error: %v", err)
+		logger.Warn("Protobuf decode failed", "err", err)
 		return
 	}
 
@@ -204,43 +201,55 @@ func (c *MezonClient) processProtobufMessage(message []byte) {
 }
 
 func (c *MezonClient) handleEnvelopeMessage(envelope *rtapi.Envelope) {
-	if c.verbose {
+	if c.verbose.Load() {
 		// Use proto package to format the message
-		log.Printf("📥 Received message: %v", envelope.Message)
+		logger.Debug("Received message", "message", envelope.Message)
 	}
 	switch envelope.Message.(type) {
 	case *rtapi.Envelope_Pong:
-		if c.verbose {
-			log.Printf("💓 Pong received")
+		if c.verbose.Load() {
+			logger.Debug("Pong received")
 		}
 	case *rtapi.Envelope_UserChannelAddedEvent:
 		userChannelAdded := envelope.GetUserChannelAddedEvent()
-		log.Printf("👥 UserChannelAdded event received")
+		logger.Info("UserChannelAdded event received")
 		c.emit("user_channel_added_event", userChannelAdded)
 	case *rtapi.Envelope_Error:
-		log.Printf("❌ Server Error: code=%d, message=%s",
-			envelope.GetError().Code, envelope.GetError().Message)
+		logger.Error("Server error", "code", envelope.GetError().Code, "message", envelope.GetError().Message)
 
 	case *rtapi.Envelope_ClanJoin:
-		log.Printf("✅ ClanJoin confirmation received")
+		logger.Debug("ClanJoin confirmation received")
 
 	case *rtapi.Envelope_ChannelJoin:
-		log.Printf("✅ ChannelJoin confirmation received")
+		logger.Debug("ChannelJoin confirmation received")
 
 	case *rtapi.Envelope_Channel:
-		log.Printf("✅ Channel info received: %d", envelope.GetChannel().Id)
+		logger.Debug("Channel info received", "channel_id", envelope.GetChannel().Id)
 
 	case *rtapi.Envelope_ChannelMessageAck:
-		log.Printf("✅ MessageAck received: %d", envelope.GetChannelMessageAck().MessageId)
+		logger.Debug("MessageAck received", "message_id", envelope.GetChannelMessageAck().MessageId)
 
 	case *rtapi.Envelope_ChannelMessage:
 		channelMsg := envelope.GetChannelMessage()
-		log.Printf("📬 ChannelMessage received from %s", channelMsg.Username)
+		logger.Info("ChannelMessage received", "username", channelMsg.Username)
 		c.emit("channel_message", channelMsg)
 
+	case *rtapi.Envelope_MessageButtonClicked:
+		clicked := envelope.GetMessageButtonClicked()
+		logger.Info("Message button clicked", "button_id", clicked.ButtonId, "user_id", clicked.UserId)
+		c.emit("message_button_clicked", clicked)
+
+	case *rtapi.Envelope_MessageReactionEvent:
+		reaction := envelope.GetMessageReactionEvent()
+		if reaction.SenderId == c.ClientID {
+			return // Echo of the bot's own reaction
+		}
+		logger.Debug("Message reaction received", "emoji", reaction.Emoji, "user_id", reaction.SenderId, "message_id", reaction.MessageId)
+		c.emit("message_reaction", reaction)
+
 	case *rtapi.Envelope_WebrtcSignalingFwd:
 		webrtcMsg := envelope.GetWebrtcSignalingFwd()
-		log.Printf("📞 WebRTC signal received")
+		logger.Info("WebRTC signal received")
 		c.emit("webrtc_signaling_fwd", webrtcMsg)
 	}
 }
@@ -282,8 +291,8 @@ func (c *MezonClient) sendMessageWithTimeout(envelope *rtapi.Envelope, timeout t
 		return fmt.Errorf("write message failed: %w", err)
 	}
 
-	if c.verbose {
-		log.Printf("📤 Sent message (%d bytes protobuf)", len(data))
+	if c.verbose.Load() {
+		logger.Debug("Sent message", "bytes", len(data))
 	}
 
 	return nil
@@ -323,8 +332,8 @@ func (c *MezonClient) sendWithResponse(envelope *rtapi.Envelope, timeout time.Du
 		return nil, fmt.Errorf("marshal protobuf: %w", err)
 	}
 
-	if c.verbose {
-		log.Printf("📤 Sending CID=%s (%d bytes protobuf)", cid, len(data))
+	if c.verbose.Load() {
+		logger.Debug("Sending request", "cid", cid, "bytes", len(data))
 	}
 
 	// Set write deadline
@@ -356,7 +365,7 @@ func (c *MezonClient) sendWithResponse(envelope *rtapi.Envelope, timeout time.Du
 
 func (c *MezonClient) pingPong() {
 	defer c.wg.Done()
-	defer log.Println("🏓 Ping/pong stopped")
+	defer logger.Info("Ping/pong stopped")
 
 	// Wait before starting ping
 	time.Sleep(3 * time.Second)
@@ -374,7 +383,7 @@ func (c *MezonClient) pingPong() {
 			}
 
 			if err := c.sendPing(); err != nil {
-				log.Printf("❌ Ping failed: %v", err)
+				logger.Error("Ping failed", "err", err)
 				if !c.isHardDisconnect {
 					go c.handleDisconnect()
 				}
@@ -0,0 +1,145 @@
+package crash
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mezon-checkin-bot/internal/logging"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================
+// CRASH REPORTING - Recovered panics with stack and call context
+// ============================================================
+
+var logger = logging.For("crash")
+
+// modulePrefix marks frames of this module as in-app
+const modulePrefix = "mezon-checkin-bot/"
+
+// Frame is one stack frame, innermost first
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// InApp reports whether the frame belongs to this module
+func (f Frame) InApp() bool {
+	return strings.HasPrefix(f.Function, modulePrefix) || strings.HasPrefix(f.Function, "main.")
+}
+
+// Event is one recovered panic
+type Event struct {
+	Component string
+	Message   string
+	Frames    []Frame
+	Tags      map[string]string // Call metadata: user_id, call_id, trace_id...
+	At        time.Time
+}
+
+// Reporter ships crash events somewhere outside the process
+type Reporter interface {
+	Report(event Event)
+	Flush(ctx context.Context) error
+}
+
+var reporter atomic.Pointer[Reporter]
+
+// Setup reports to Sentry when dsn is set. Without it panics are only logged.
+func Setup(dsn, environment, release string) error {
+	if dsn == "" {
+		return nil
+	}
+	r, err := NewSentryReporter(dsn, environment, release)
+	if err != nil {
+		return err
+	}
+	SetReporter(r)
+	logger.Info("Crash reports sent to Sentry", "environment", environment)
+	return nil
+}
+
+// SetReporter replaces the reporter (nil disables reporting)
+func SetReporter(r Reporter) {
+	if r == nil {
+		reporter.Store(nil)
+		return
+	}
+	reporter.Store(&r)
+}
+
+// Flush waits for reports in flight
+func Flush(ctx context.Context) error {
+	if r := reporter.Load(); r != nil {
+		return (*r).Flush(ctx)
+	}
+	return nil
+}
+
+// Capture logs a recovered panic value with its stack and hands it to the
+// reporter. args are slog-style key/value pairs describing the call. It must
+// be called from the deferred function that recovered, so the stack still
+// shows where the panic happened.
+func Capture(component string, value any, args ...any) Event {
+	event := Event{
+		Component: component,
+		Message:   logging.RedactString(fmt.Sprint(value)),
+		Frames:    callers(3),
+		Tags:      toTags(args),
+		At:        time.Now(),
+	}
+
+	logArgs := append([]any{"source", component, "panic", event.Message, "stack", formatFrames(event.Frames)}, args...)
+	logger.Error("Panic recovered", logArgs...)
+
+	if r := reporter.Load(); r != nil {
+		(*r).Report(event)
+	}
+	return event
+}
+
+// callers returns the stack above skip. During a panic it starts at the
+// panicking frame, dropping the recovery handlers and runtime frames.
+func callers(skip int) []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []Frame
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			stack = stack[:0]
+		} else if !strings.HasPrefix(frame.Function, "runtime.") {
+			stack = append(stack, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+func formatFrames(frames []Frame) string {
+	var b strings.Builder
+	for _, f := range frames {
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+	}
+	return b.String()
+}
+
+// toTags converts key/value pairs with the same rules as slog
+func toTags(args []any) map[string]string {
+	tags := make(map[string]string)
+	var r slog.Record
+	r.Add(args...)
+	r.Attrs(func(a slog.Attr) bool {
+		tags[a.Key] = a.Value.Resolve().String()
+		return true
+	})
+	return tags
+}
@@ -0,0 +1,202 @@
+package crash
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// SENTRY REPORTER - Events posted to the Sentry store API
+// ============================================================
+
+const (
+	sentryTimeout = 10 * time.Second
+	sentryClient  = "mezon-checkin-bot/1.0"
+)
+
+// SentryReporter posts crash events to a Sentry project
+type SentryReporter struct {
+	storeURL    string
+	publicKey   string
+	environment string
+	release     string
+	serverName  string
+	client      *http.Client
+	wg          sync.WaitGroup
+}
+
+// NewSentryReporter parses a DSN of the form
+// https://<public key>@<host>/<project id>
+func NewSentryReporter(dsn, environment, release string) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: missing public key")
+	}
+
+	path := strings.Trim(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	projectID := path[slash+1:]
+	if projectID == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: missing project ID")
+	}
+	prefix := ""
+	if slash >= 0 {
+		prefix = "/" + path[:slash]
+	}
+
+	serverName, _ := os.Hostname()
+	return &SentryReporter{
+		storeURL:    fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, projectID),
+		publicKey:   u.User.Username(),
+		environment: environment,
+		release:     release,
+		serverName:  serverName,
+		client:      &http.Client{Timeout: sentryTimeout},
+	}, nil
+}
+
+// Report sends the event in the background
+func (s *SentryReporter) Report(event Event) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.send(event); err != nil {
+			logger.Warn("Failed to send crash report", "err", err)
+		}
+	}()
+}
+
+// Flush waits for reports in flight
+func (s *SentryReporter) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *SentryReporter) send(event Event) error {
+	body, err := json.Marshal(s.encode(event))
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s",
+		sentryClient, s.publicKey))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("sentry returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ============================================================
+// SENTRY JSON ENCODING
+// ============================================================
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Exception   sentryExceptions  `json:"exception"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string           `json:"type"`
+	Value      string           `json:"value"`
+	Module     string           `json:"module,omitempty"`
+	Stacktrace sentryStacktrace `json:"stacktrace"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+func (s *SentryReporter) encode(event Event) sentryEvent {
+	var id [16]byte
+	rand.Read(id[:])
+
+	// Sentry lists the outermost frame first
+	frames := make([]sentryFrame, len(event.Frames))
+	for i, f := range event.Frames {
+		frames[len(frames)-1-i] = sentryFrame{
+			Function: f.Function,
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    f.InApp(),
+		}
+	}
+
+	tags := map[string]string{"component": event.Component}
+	for k, v := range event.Tags {
+		tags[k] = v
+	}
+
+	return sentryEvent{
+		EventID:     hex.EncodeToString(id[:]),
+		Timestamp:   event.At.UTC().Format(time.RFC3339),
+		Level:       "fatal",
+		Platform:    "go",
+		Logger:      event.Component,
+		ServerName:  s.serverName,
+		Release:     s.release,
+		Environment: s.environment,
+		Tags:        tags,
+		Exception: sentryExceptions{Values: []sentryException{{
+			Type:       "panic",
+			Value:      event.Message,
+			Module:     event.Component,
+			Stacktrace: sentryStacktrace{Frames: frames},
+		}}},
+	}
+}
@@ -0,0 +1,96 @@
+package detector
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// FACE ALIGNMENT - Rotate crops so the eyes are level
+// ============================================================
+
+const (
+	minAlignAngle = 2.0  // Degrees, below this the crop is left untouched
+	maxAlignAngle = 30.0 // Degrees, above this the eye pair is likely a false positive
+)
+
+// AlignFace rotates a face crop so the line between the eyes is horizontal.
+// Returns a new Mat owned by the caller and true when a rotation was applied;
+// otherwise returns an empty Mat and false and the original crop should be used.
+func (fd *FaceDetector) AlignFace(crop gocv.Mat) (gocv.Mat, bool) {
+	if !fd.eyeReady || crop.Empty() {
+		return gocv.Mat{}, false
+	}
+
+	// Eyes are in the upper half of the crop
+	upper := image.Rect(0, 0, crop.Cols(), crop.Rows()/2)
+	eyes := detectEyes(fd.eyeClassifiers, crop, upper)
+	if len(eyes) < 2 {
+		return gocv.Mat{}, false
+	}
+
+	// Keep the two largest detections, ordered left to right
+	sort.Slice(eyes, func(i, j int) bool {
+		return eyes[i].Dx()*eyes[i].Dy() > eyes[j].Dx()*eyes[j].Dy()
+	})
+	left, right := centerOf(eyes[0]), centerOf(eyes[1])
+	if left.X > right.X {
+		left, right = right, left
+	}
+
+	angle := math.Atan2(float64(right.Y-left.Y), float64(right.X-left.X)) * 180 / math.Pi
+	if math.Abs(angle) < minAlignAngle || math.Abs(angle) > maxAlignAngle {
+		return gocv.Mat{}, false
+	}
+
+	mid := image.Pt((left.X+right.X)/2, (left.Y+right.Y)/2)
+	rotation := gocv.GetRotationMatrix2D(mid, angle, 1.0)
+	defer rotation.Close()
+
+	aligned := gocv.NewMat()
+	if err := gocv.WarpAffineWithParams(
+		crop,
+		&aligned,
+		rotation,
+		image.Pt(crop.Cols(), crop.Rows()),
+		gocv.InterpolationLinear,
+		gocv.BorderReplicate,
+		color.RGBA{},
+	); err != nil {
+		aligned.Close()
+		logger.Warn("Alignment failed", "err", err)
+		return gocv.Mat{}, false
+	}
+
+	logger.Debug("Face aligned", "angle_deg", angle)
+	return aligned, true
+}
+
+// detectEyes runs the eye cascade on region of img and returns eye boxes in img coordinates
+func detectEyes(classifiers *classifierPool, img gocv.Mat, region image.Rectangle) []image.Rectangle {
+	region = region.Intersect(image.Rect(0, 0, img.Cols(), img.Rows()))
+	if region.Empty() {
+		return nil
+	}
+
+	roi := img.Region(region)
+	defer roi.Close()
+
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(roi, &gray, gocv.ColorBGRToGray)
+
+	eyes := classifiers.detect(gray)
+	for i := range eyes {
+		eyes[i] = eyes[i].Add(region.Min)
+	}
+	return eyes
+}
+
+func centerOf(r image.Rectangle) image.Point {
+	return image.Pt(r.Min.X+r.Dx()/2, r.Min.Y+r.Dy()/2)
+}
@@ -0,0 +1,154 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"mezon-checkin-bot/internal/matpool"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// DETECTOR INTERFACE - Pluggable face detection backends
+// ============================================================
+
+// Detector finds faces in a BGR image. Implementations must return
+// rectangles in the coordinates of the given image.
+type Detector interface {
+	Detect(img gocv.Mat) []image.Rectangle
+	Close()
+}
+
+// ============================================================
+// HAAR DETECTOR
+// ============================================================
+
+type HaarDetector struct {
+	classifiers *classifierPool
+	gpu         *gpuBackend
+}
+
+// NewHaarDetector loads a Haar cascade; gpu may be nil
+func NewHaarDetector(cascadePath string, gpu *gpuBackend) (*HaarDetector, error) {
+	classifiers, err := newClassifierPool(cascadePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load face cascade classifier: %w", err)
+	}
+	return &HaarDetector{classifiers: classifiers, gpu: gpu}, nil
+}
+
+func (h *HaarDetector) Detect(img gocv.Mat) []image.Rectangle {
+	if h.gpu != nil {
+		rects, err := h.gpu.Detect(img)
+		if err == nil {
+			return rects
+		}
+		logger.Warn("GPU detection failed, using CPU", "err", err)
+	}
+
+	gray := matpool.Get()
+	defer matpool.Put(gray)
+	gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+
+	return h.classifiers.detect(gray)
+}
+
+func (h *HaarDetector) Close() {
+	h.classifiers.close()
+}
+
+// ============================================================
+// FALLBACK DETECTOR - Primary backend with a fallback on miss
+// ============================================================
+
+type FallbackDetector struct {
+	Primary  Detector
+	Fallback Detector
+}
+
+func (f *FallbackDetector) Detect(img gocv.Mat) []image.Rectangle {
+	if rects := f.Primary.Detect(img); len(rects) > 0 {
+		return rects
+	}
+	return f.Fallback.Detect(img)
+}
+
+func (f *FallbackDetector) Close() {
+	f.Primary.Close()
+	f.Fallback.Close()
+}
+
+// ============================================================
+// EXTERNAL PROCESS DETECTOR
+// ============================================================
+
+const externalDetectorTimeout = 2 * time.Second
+
+type externalRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// ExternalProcessDetector runs a command per frame: the JPEG-encoded frame is
+// written to stdin and a JSON array of {x,y,w,h} boxes is read from stdout.
+type ExternalProcessDetector struct {
+	command string
+	args    []string
+}
+
+// NewExternalProcessDetector parses a command line such as "python3 detect.py --fast"
+func NewExternalProcessDetector(commandLine string) (*ExternalProcessDetector, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("external detector command not configured")
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return nil, fmt.Errorf("external detector not found: %w", err)
+	}
+	return &ExternalProcessDetector{command: fields[0], args: fields[1:]}, nil
+}
+
+func (e *ExternalProcessDetector) Detect(img gocv.Mat) []image.Rectangle {
+	buf, err := gocv.IMEncode(gocv.JPEGFileExt, img)
+	if err != nil {
+		logger.Warn("External detector encode failed", "err", err)
+		return nil
+	}
+	defer buf.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), externalDetectorTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.command, e.args...)
+	cmd.Stdin = bytes.NewReader(buf.GetBytes())
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		logger.Warn("External detector failed", "err", err, "stderr", strings.TrimSpace(stderr.String()))
+		return nil
+	}
+
+	var boxes []externalRect
+	if err := json.Unmarshal(stdout.Bytes(), &boxes); err != nil {
+		logger.Warn("External detector output invalid", "err", err)
+		return nil
+	}
+
+	rects := make([]image.Rectangle, 0, len(boxes))
+	for _, b := range boxes {
+		rects = append(rects, clampRect(image.Rect(b.X, b.Y, b.X+b.W, b.Y+b.H), img.Cols(), img.Rows()))
+	}
+	return rects
+}
+
+func (e *ExternalProcessDetector) Close() {}
@@ -0,0 +1,73 @@
+package detector
+
+import (
+	"sort"
+	"time"
+)
+
+// ============================================================
+// CROP BATCH - Collect the K best crops for one API call
+// ============================================================
+
+type scoredCrop struct {
+	jpegImg []byte
+	score   float64
+}
+
+type CropBatch struct {
+	size    int
+	window  time.Duration
+	started time.Time
+	crops   []scoredCrop
+}
+
+// NewCropBatch creates a batch of up to size crops, flushed after window
+func NewCropBatch(size int, window time.Duration) *CropBatch {
+	return &CropBatch{
+		size:   size,
+		window: window,
+		crops:  make([]scoredCrop, 0, size),
+	}
+}
+
+// Add stores a copy of the JPEG crop with its quality score (higher is
+// better), so the caller may return its buffer to the pool
+func (b *CropBatch) Add(jpegImg []byte, score float64) {
+	if len(b.crops) == 0 {
+		b.started = time.Now()
+	}
+	b.crops = append(b.crops, scoredCrop{jpegImg: append([]byte(nil), jpegImg...), score: score})
+}
+
+// Ready reports whether the batch is full or its window has elapsed
+func (b *CropBatch) Ready() bool {
+	if len(b.crops) == 0 {
+		return false
+	}
+	return len(b.crops) >= b.size || (b.window > 0 && time.Since(b.started) >= b.window)
+}
+
+// Len returns the number of pending crops
+func (b *CropBatch) Len() int {
+	return len(b.crops)
+}
+
+// Take returns up to size crops ordered best first and resets the batch
+func (b *CropBatch) Take() [][]byte {
+	sort.SliceStable(b.crops, func(i, j int) bool {
+		return b.crops[i].score > b.crops[j].score
+	})
+
+	n := len(b.crops)
+	if n > b.size {
+		n = b.size
+	}
+
+	imgs := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		imgs[i] = b.crops[i].jpegImg
+	}
+
+	b.crops = b.crops[:0]
+	return imgs
+}
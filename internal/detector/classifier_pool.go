@@ -0,0 +1,91 @@
+package detector
+
+import (
+	"fmt"
+	"image"
+	"runtime"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// CLASSIFIER POOL - DetectMultiScale is not safe on one cascade
+// from several goroutines, so each detection borrows its own
+// ============================================================
+
+// classifierPool loads copies of a cascade on demand and keeps the idle
+// ones, so concurrent calls neither race nor wait on each other
+type classifierPool struct {
+	path   string
+	size   int // Idle copies kept, more are closed when returned
+	mu     sync.Mutex
+	idle   []gocv.CascadeClassifier
+	closed bool
+}
+
+// newClassifierPool loads a first copy of the cascade, so a missing file is
+// reported at startup
+func newClassifierPool(path string) (*classifierPool, error) {
+	p := &classifierPool{path: path, size: runtime.NumCPU()}
+	classifier, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	p.idle = append(p.idle, classifier)
+	return p, nil
+}
+
+func (p *classifierPool) load() (gocv.CascadeClassifier, error) {
+	classifier := gocv.NewCascadeClassifier()
+	if !classifier.Load(p.path) {
+		classifier.Close()
+		return gocv.CascadeClassifier{}, fmt.Errorf("failed to load cascade %s", p.path)
+	}
+	return classifier, nil
+}
+
+// get returns an idle copy or loads a new one, to be returned with put
+func (p *classifierPool) get() (gocv.CascadeClassifier, error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		classifier := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return classifier, nil
+	}
+	p.mu.Unlock()
+	return p.load()
+}
+
+func (p *classifierPool) put(classifier gocv.CascadeClassifier) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || len(p.idle) >= p.size {
+		classifier.Close()
+		return
+	}
+	p.idle = append(p.idle, classifier)
+}
+
+// detect runs the cascade on a grayscale image
+func (p *classifierPool) detect(gray gocv.Mat) []image.Rectangle {
+	classifier, err := p.get()
+	if err != nil {
+		logger.Warn("Cascade unavailable", "err", err)
+		return nil
+	}
+	defer p.put(classifier)
+	return classifier.DetectMultiScale(gray)
+}
+
+// close frees the idle copies; copies in use are freed when returned
+func (p *classifierPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, classifier := range p.idle {
+		classifier.Close()
+	}
+	p.idle = nil
+	p.closed = true
+}
@@ -1,46 +1,91 @@
 package detector
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"image"
+	"mezon-checkin-bot/internal/alerting"
 	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/logging"
 	"mezon-checkin-bot/models"
 
 	"gocv.io/x/gocv"
 )
 
+var logger = logging.For("detector")
+
 // ============================================================
-// FACE DETECTOR - Main detector with cascade classifier
+// FACE DETECTOR - Main detector with pluggable detection backend
 // ============================================================
 
 type FaceDetector struct {
-	Classifier         gocv.CascadeClassifier
 	Config             *models.FaceRecognitionConfig
-	recognitionService *FaceRecognitionService
+	recognitionService CheckinBackend
+	backend            Detector
+	backendName        string
+	haar               Detector // Fallback of a DNN backend, used alone when the DNN is flagged off
+	eyeClassifiers     *classifierPool
+	eyeReady           bool
+	cache              *RecognitionCache
+	local              *LocalRecognizer
+	gpu                *gpuBackend
+	acceleration       string
+	landmarker         *dnnDetector // YuNet, when configured, also provides landmarks
 }
 
+const defaultFaceCascadePath = "haarcascade_frontalface_default.xml"
+
 // NewFaceDetector creates a new face detector instance
 func NewFaceDetector(config *models.FaceRecognitionConfig, apiClient *api.APIClient) (*FaceDetector, error) {
 	detector := &FaceDetector{
 		Config: config,
+		cache:  NewRecognitionCache(config.RecognitionCacheTTL),
 	}
 
 	// Initialize face recognition service if enabled
 	if config.Enabled {
-		detector.recognitionService = NewFaceRecognitionService(
-			apiClient,
-		)
+		service, err := newCheckinBackend(config, apiClient)
+		if err != nil {
+			return nil, err
+		}
+		detector.recognitionService = service
+
+		// Resolve acceleration before loading models
+		detector.acceleration = detector.resolveAcceleration()
+
+		backend, name, err := detector.buildBackend()
+		if err != nil {
+			return nil, err
+		}
+		detector.backend = backend
+		detector.backendName = name
+
+		// Load on-device recognizer
+		if config.LocalRecognitionEnabled {
+			local, err := NewLocalRecognizer(config)
+			if err != nil {
+				logger.Warn("Local recognition unavailable, using API only", "err", err)
+			} else {
+				detector.local = local
+			}
+		}
 
-		// Load cascade classifier
-		classifier := gocv.NewCascadeClassifier()
-		if !classifier.Load("haarcascade_frontalface_default.xml") {
-			return nil, fmt.Errorf("failed to load face cascade classifier")
+		// Load eye cascade for liveness blink detection and alignment
+		if config.LivenessEnabled || config.AlignFaces {
+			if err := detector.loadEyeCascade(); err != nil {
+				logger.Warn("Eye cascade not loaded, blink detection and alignment disabled", "err", err)
+				alerting.Alert(alerting.KeyClassifierLoad, "⚠️ Không tải được bộ phân loại mắt",
+					fmt.Sprintf("Không tải được %s. Kiểm tra liveness và căn chỉnh khuôn mặt đã bị tắt.", detector.eyeCascadePath()))
+			}
 		}
-		detector.Classifier = classifier
 
-		log.Println("✅ Face detector initialized")
-		log.Printf("   Min face size: %dx%d", config.MinFaceSize, config.MinFaceSize)
-		log.Printf("   JPEG quality: %d", config.JPEGQuality)
+		logger.Info("Face detector initialized",
+			"backend", detector.BackendName(),
+			"liveness", config.LivenessEnabled,
+			"acceleration", detector.acceleration,
+			"min_face_size", config.MinFaceSize,
+			"jpeg_quality", config.JPEGQuality,
+		)
 	}
 
 	return detector, nil
@@ -48,14 +93,171 @@ func NewFaceDetector(config *models.FaceRecognitionConfig, apiClient *api.APICli
 
 // Close releases resources used by the detector
 func (fd *FaceDetector) Close() {
-	if fd.Config.Enabled && fd.Classifier != (gocv.CascadeClassifier{}) {
-		fd.Classifier.Close()
+	if fd.backend != nil {
+		fd.backend.Close()
+	}
+	if fd.eyeReady {
+		fd.eyeClassifiers.close()
+	}
+	if fd.local != nil {
+		fd.local.Close()
+	}
+	if fd.gpu != nil {
+		fd.gpu.Close()
+	}
+}
+
+func (fd *FaceDetector) eyeCascadePath() string {
+	if fd.Config.EyeCascadePath == "" {
+		return defaultEyeCascadePath
+	}
+	return fd.Config.EyeCascadePath
+}
+
+func (fd *FaceDetector) loadEyeCascade() error {
+	eyeClassifiers, err := newClassifierPool(fd.eyeCascadePath())
+	if err != nil {
+		return err
+	}
+	fd.eyeClassifiers = eyeClassifiers
+	fd.eyeReady = true
+	return nil
+}
+
+// CheckModels reports a detection model that should be loaded but is not
+func (fd *FaceDetector) CheckModels() error {
+	if !fd.Config.Enabled {
+		return nil
+	}
+	if fd.backend == nil {
+		return fmt.Errorf("no face detection backend")
+	}
+	if (fd.Config.LivenessEnabled || fd.Config.AlignFaces) && !fd.eyeReady {
+		return fmt.Errorf("eye cascade not loaded")
+	}
+	return nil
+}
+
+// ReloadModels loads the models CheckModels reports missing. Loaded models
+// are left alone, calls may be using them.
+func (fd *FaceDetector) ReloadModels() error {
+	if !fd.Config.Enabled {
+		return nil
+	}
+	if fd.backend == nil {
+		backend, name, err := fd.buildBackend()
+		if err != nil {
+			return err
+		}
+		fd.backend, fd.backendName = backend, name
+		logger.Info("Face detection backend reloaded", "backend", name)
+	}
+	if (fd.Config.LivenessEnabled || fd.Config.AlignFaces) && !fd.eyeReady {
+		if err := fd.loadEyeCascade(); err != nil {
+			return err
+		}
+		logger.Info("Eye cascade reloaded")
+	}
+	return nil
+}
+
+// BackendName returns the active detection backend
+func (fd *FaceDetector) BackendName() string {
+	return fd.backendName
+}
+
+// Detect runs face detection on a BGR image using the active backend
+func (fd *FaceDetector) Detect(img gocv.Mat) []image.Rectangle {
+	if fd.backend == nil {
+		return nil
+	}
+	return fd.backend.Detect(img)
+}
+
+// BaselineDetector returns Haar alone when the backend is DNN, for calls
+// where the DNN detector is flagged off. Nil when there is no DNN backend.
+func (fd *FaceDetector) BaselineDetector() Detector {
+	return fd.haar
+}
+
+// SetBackend replaces the detection backend; the previous one is closed
+func (fd *FaceDetector) SetBackend(backend Detector, name string) {
+	if fd.backend != nil {
+		fd.backend.Close()
+	}
+	fd.landmarker = nil // Closed with the previous backend
+	fd.haar = nil
+	fd.backend = backend
+	fd.backendName = name
+}
+
+// buildBackend creates the configured backend. Haar is always loaded and
+// used as fallback for DNN and external backends.
+func (fd *FaceDetector) buildBackend() (Detector, string, error) {
+	haar, err := NewHaarDetector(defaultFaceCascadePath, fd.gpu)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch fd.Config.DetectionBackend {
+	case models.DetectionBackendDNN:
+		dnn, err := newDNNDetector(fd.Config, fd.acceleration)
+		if err != nil {
+			logger.Warn("DNN detector unavailable, falling back to Haar", "err", err)
+			break
+		}
+		fd.landmarker = dnn
+		fd.haar = haar
+		return &FallbackDetector{Primary: dnn, Fallback: haar}, models.DetectionBackendDNN + "/" + dnn.modelType, nil
+
+	case models.DetectionBackendExternal:
+		external, err := NewExternalProcessDetector(fd.Config.ExternalDetectorCommand)
+		if err != nil {
+			logger.Warn("External detector unavailable, falling back to Haar", "err", err)
+			break
+		}
+		return &FallbackDetector{Primary: external, Fallback: haar}, models.DetectionBackendExternal, nil
+	}
+
+	return haar, models.DetectionBackendHaar, nil
+}
+
+// Resize scales src into dst, on the GPU when available
+func (fd *FaceDetector) Resize(src gocv.Mat, dst *gocv.Mat, size image.Point) {
+	if fd.gpu != nil {
+		if err := fd.gpu.Resize(src, dst, size); err == nil {
+			return
+		}
+	}
+	gocv.Resize(src, dst, size, 0, 0, gocv.InterpolationLinear)
+}
+
+// resolveAcceleration initializes the requested device and returns the
+// acceleration mode actually in effect.
+func (fd *FaceDetector) resolveAcceleration() string {
+	switch fd.Config.Acceleration {
+	case models.AccelerationCUDA:
+		gpu, err := newGPUBackend(fd.Config)
+		if err != nil {
+			logger.Warn("CUDA unavailable, falling back to CPU", "err", err)
+			return models.AccelerationNone
+		}
+		fd.gpu = gpu
+		return models.AccelerationCUDA
+	case models.AccelerationOpenCL:
+		if fd.Config.DetectionBackend != models.DetectionBackendDNN {
+			logger.Warn("OpenCL only accelerates the DNN detector, running on CPU", "backend", fd.Config.DetectionBackend)
+			return models.AccelerationNone
+		}
+		return models.AccelerationOpenCL
+	default:
+		return models.AccelerationNone
 	}
 }
 
 // SubmitSingleImageToAPI submits a single image to the face recognition API
 // This method maintains backward compatibility with existing code
-func (fd *FaceDetector) SubmitSingleImageToAPI(base64Img string, userId int64, attemptNum int) (*models.FaceRecognitionResponse, error) {
+func (fd *FaceDetector) SubmitSingleImageToAPI(ctx context.Context, jpegImg []byte, userId int64, attemptNum int) (*models.FaceRecognitionResponse, error) {
 	if !fd.Config.Enabled {
 		return nil, nil
 	}
@@ -64,11 +266,89 @@ func (fd *FaceDetector) SubmitSingleImageToAPI(base64Img string, userId int64, a
 		return nil, fmt.Errorf("face recognition service not initialized")
 	}
 
-	return fd.recognitionService.SubmitImage(base64Img, userId, attemptNum)
+	if cached, ok := fd.cachedResponse(userId); ok {
+		return cached, nil
+	}
+
+	response, err := fd.recognitionService.SubmitImages(ctx, [][]byte{jpegImg}, userId, attemptNum)
+	if err == nil {
+		fd.cache.Put(userId, response)
+	}
+	return response, err
+}
+
+// SubmitImagesToAPI submits a batch of crops in a single API call
+func (fd *FaceDetector) SubmitImagesToAPI(ctx context.Context, jpegImgs [][]byte, userId int64, attemptNum int) (*models.FaceRecognitionResponse, error) {
+	if !fd.Config.Enabled || len(jpegImgs) == 0 {
+		return nil, nil
+	}
+
+	if fd.recognitionService == nil {
+		return nil, fmt.Errorf("face recognition service not initialized")
+	}
+
+	if cached, ok := fd.cachedResponse(userId); ok {
+		return cached, nil
+	}
+
+	response, err := fd.recognitionService.SubmitImages(ctx, jpegImgs, userId, attemptNum)
+	if err == nil {
+		fd.cache.Put(userId, response)
+	}
+	return response, err
+}
+
+// BatchEnabled reports whether crops are accumulated and sent together.
+// Local recognition matches one crop at a time, so batching is off there.
+func (fd *FaceDetector) BatchEnabled() bool {
+	return fd.Config.BatchSize > 1 && fd.local == nil
+}
+
+// Recognize identifies the face crop. In local mode the crop is matched against
+// the enrolled embedding; users without one are sent to the API once and
+// enrolled from its successful response.
+func (fd *FaceDetector) Recognize(ctx context.Context, face gocv.Mat, jpegImg []byte, userId int64, attemptNum int) (*models.FaceRecognitionResponse, error) {
+	if fd.local == nil {
+		return fd.SubmitSingleImageToAPI(ctx, jpegImg, userId, attemptNum)
+	}
+
+	response, enrolled, err := fd.local.Verify(face, userId)
+	if err != nil {
+		logger.Warn("Local recognition failed", "user_id", userId, "err", err)
+		return nil, err
+	}
+	if enrolled {
+		return response, nil
+	}
+
+	logger.Info("User not enrolled locally, using API for enrollment", "user_id", userId)
+	response, err = fd.SubmitSingleImageToAPI(ctx, jpegImg, userId, attemptNum)
+	if err != nil || !response.IsSuccessful() {
+		return response, err
+	}
+
+	if err := fd.local.Enroll(face, userId, response); err != nil {
+		logger.Warn("Failed to enroll user", "user_id", userId, "err", err)
+	} else {
+		logger.Info("Enrolled user", "user_id", userId, "employee", response.GetFullName())
+	}
+
+	return response, nil
+}
+
+// cachedResponse stands in for the API call when the user was recognized
+// moments ago (e.g. a dropped call). Only reached with a crop from the
+// current call, after its liveness and face checks passed.
+func (fd *FaceDetector) cachedResponse(userId int64) (*models.FaceRecognitionResponse, bool) {
+	cached, ok := fd.cache.Get(userId)
+	if ok {
+		logger.Info("Using cached recognition", "user_id", userId, "result", cached.String())
+	}
+	return cached, ok
 }
 
 // GetRecognitionService returns the underlying face recognition service
 // This allows direct access to the service if needed
-func (fd *FaceDetector) GetRecognitionService() *FaceRecognitionService {
+func (fd *FaceDetector) GetRecognitionService() CheckinBackend {
 	return fd.recognitionService
 }
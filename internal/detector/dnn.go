@@ -0,0 +1,232 @@
+package detector
+
+import (
+	"fmt"
+	"image"
+	"mezon-checkin-bot/models"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// DNN FACE DETECTOR - YuNet / SSD backends
+// ============================================================
+
+const (
+	defaultDNNConfidence = 0.6
+	defaultYuNetInput    = 320
+	defaultSSDInput      = 300
+	yunetNMSThreshold    = 0.3
+	yunetTopK            = 5000
+)
+
+type dnnDetector struct {
+	modelType  string
+	threshold  float32
+	inputSize  int
+	yunet      gocv.FaceDetectorYN
+	ssd        gocv.Net
+	yunetReady bool
+	ssdReady   bool
+	mu         sync.Mutex // YuNet keeps its input size between calls
+}
+
+// newDNNDetector loads the configured DNN model
+func newDNNDetector(config *models.FaceRecognitionConfig, acceleration string) (*dnnDetector, error) {
+	if config.DNNModelPath == "" {
+		return nil, fmt.Errorf("DNN model path not configured")
+	}
+
+	d := &dnnDetector{
+		modelType: config.DNNModelType,
+		threshold: config.DNNConfidenceThreshold,
+		inputSize: config.DNNInputSize,
+	}
+	if d.modelType == "" {
+		d.modelType = models.DNNModelYuNet
+	}
+	if d.threshold <= 0 || d.threshold > 1 {
+		d.threshold = defaultDNNConfidence
+	}
+
+	backend, target := dnnTargetFor(acceleration)
+
+	switch d.modelType {
+	case models.DNNModelYuNet:
+		if d.inputSize <= 0 {
+			d.inputSize = defaultYuNetInput
+		}
+		d.yunet = gocv.NewFaceDetectorYNWithParams(
+			config.DNNModelPath,
+			config.DNNConfigPath,
+			image.Pt(d.inputSize, d.inputSize),
+			d.threshold,
+			yunetNMSThreshold,
+			yunetTopK,
+			int(backend), int(target),
+		)
+		d.yunetReady = true
+
+	case models.DNNModelSSD:
+		if d.inputSize <= 0 {
+			d.inputSize = defaultSSDInput
+		}
+		net := gocv.ReadNet(config.DNNModelPath, config.DNNConfigPath)
+		if net.Empty() {
+			return nil, fmt.Errorf("failed to load SSD model: %s", config.DNNModelPath)
+		}
+		if err := net.SetPreferableBackend(backend); err != nil {
+			logger.Warn("DNN backend not set", "err", err)
+		}
+		if err := net.SetPreferableTarget(target); err != nil {
+			logger.Warn("DNN target not set", "err", err)
+		}
+		d.ssd = net
+		d.ssdReady = true
+
+	default:
+		return nil, fmt.Errorf("unknown DNN model type: %s", d.modelType)
+	}
+
+	logger.Info("DNN face detector loaded", "model", d.modelType, "input_px", d.inputSize, "threshold", d.threshold)
+
+	return d, nil
+}
+
+// Detect returns face rectangles in the coordinates of the given BGR image
+func (d *dnnDetector) Detect(img gocv.Mat) []image.Rectangle {
+	if d.yunetReady {
+		return d.detectYuNet(img)
+	}
+	if d.ssdReady {
+		return d.detectSSD(img)
+	}
+	return nil
+}
+
+func (d *dnnDetector) detectYuNet(img gocv.Mat) []image.Rectangle {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.yunet.SetInputSize(image.Pt(img.Cols(), img.Rows()))
+
+	faces := gocv.NewMat()
+	defer faces.Close()
+	d.yunet.Detect(img, &faces)
+
+	// Each row: x, y, w, h, 5 landmarks (x,y), score
+	rects := make([]image.Rectangle, 0, faces.Rows())
+	for i := 0; i < faces.Rows(); i++ {
+		if faces.GetFloatAt(i, 14) < d.threshold {
+			continue
+		}
+		x := int(faces.GetFloatAt(i, 0))
+		y := int(faces.GetFloatAt(i, 1))
+		w := int(faces.GetFloatAt(i, 2))
+		h := int(faces.GetFloatAt(i, 3))
+		rects = append(rects, clampRect(image.Rect(x, y, x+w, y+h), img.Cols(), img.Rows()))
+	}
+
+	return rects
+}
+
+// Landmarks runs YuNet around face and returns the five landmarks of the
+// largest detection there. Only YuNet reports landmarks.
+func (d *dnnDetector) Landmarks(img gocv.Mat, face image.Rectangle) (FaceLandmarks, bool) {
+	if !d.yunetReady {
+		return FaceLandmarks{}, false
+	}
+
+	// A margin keeps a turned head inside the region
+	region := clampRect(face.Inset(-face.Dx()/4), img.Cols(), img.Rows())
+	if region.Empty() {
+		return FaceLandmarks{}, false
+	}
+	roi := img.Region(region)
+	crop := roi.Clone()
+	roi.Close()
+	defer crop.Close()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.yunet.SetInputSize(image.Pt(crop.Cols(), crop.Rows()))
+
+	faces := gocv.NewMat()
+	defer faces.Close()
+	d.yunet.Detect(crop, &faces)
+
+	best, bestArea := -1, float32(0)
+	for i := 0; i < faces.Rows(); i++ {
+		if faces.GetFloatAt(i, 14) < d.threshold {
+			continue
+		}
+		if area := faces.GetFloatAt(i, 2) * faces.GetFloatAt(i, 3); area > bestArea {
+			best, bestArea = i, area
+		}
+	}
+	if best < 0 {
+		return FaceLandmarks{}, false
+	}
+
+	// Columns 4-13: right eye, left eye, nose tip, right and left mouth corner
+	point := func(col int) image.Point {
+		return image.Pt(int(faces.GetFloatAt(best, col)), int(faces.GetFloatAt(best, col+1))).Add(region.Min)
+	}
+	return FaceLandmarks{
+		RightEye:   point(4),
+		LeftEye:    point(6),
+		Nose:       point(8),
+		MouthRight: point(10),
+		MouthLeft:  point(12),
+		HasNose:    true,
+	}, true
+}
+
+func (d *dnnDetector) detectSSD(img gocv.Mat) []image.Rectangle {
+	blob := gocv.BlobFromImage(
+		img,
+		1.0,
+		image.Pt(d.inputSize, d.inputSize),
+		gocv.NewScalar(104, 177, 123, 0),
+		false,
+		false,
+	)
+	defer blob.Close()
+
+	d.ssd.SetInput(blob, "")
+	out := d.ssd.Forward("")
+	defer out.Close()
+
+	// Output shape [1, 1, N, 7]: image_id, label, confidence, x1, y1, x2, y2 (normalized)
+	w := float32(img.Cols())
+	h := float32(img.Rows())
+	rects := make([]image.Rectangle, 0)
+	for i := 0; i+6 < out.Total(); i += 7 {
+		if out.GetFloatAt(0, i+2) < d.threshold {
+			continue
+		}
+		x1 := int(out.GetFloatAt(0, i+3) * w)
+		y1 := int(out.GetFloatAt(0, i+4) * h)
+		x2 := int(out.GetFloatAt(0, i+5) * w)
+		y2 := int(out.GetFloatAt(0, i+6) * h)
+		rects = append(rects, clampRect(image.Rect(x1, y1, x2, y2), img.Cols(), img.Rows()))
+	}
+
+	return rects
+}
+
+// Close releases the loaded model
+func (d *dnnDetector) Close() {
+	if d.yunetReady {
+		d.yunet.Close()
+		d.yunetReady = false
+	}
+	if d.ssdReady {
+		d.ssd.Close()
+		d.ssdReady = false
+	}
+}
+
+func clampRect(r image.Rectangle, width, height int) image.Rectangle {
+	return r.Intersect(image.Rect(0, 0, width, height))
+}
@@ -0,0 +1,143 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/encryption"
+	"mezon-checkin-bot/internal/utils"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// EMBEDDING STORE - Enrolled employee face embeddings on disk
+// ============================================================
+
+type EmbeddingRecord struct {
+	UserID     int64     `json:"user_id"`
+	EmployeeID string    `json:"employee_id"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	Embedding  []float32 `json:"embedding"`
+	EnrolledAt time.Time `json:"enrolled_at"`
+}
+
+type embeddingFile struct {
+	Records []EmbeddingRecord `json:"records"`
+}
+
+type EmbeddingStore struct {
+	filePath string
+	keys     *encryption.Keyring // Seals the file when set
+	records  map[int64]EmbeddingRecord
+	mu       sync.RWMutex
+}
+
+// NewEmbeddingStore loads enrolled embeddings from filePath (missing file =
+// empty store). With keys, a plaintext file or one sealed with an older key
+// is resealed with the active key.
+func NewEmbeddingStore(filePath string, keys *encryption.Keyring) (*EmbeddingStore, error) {
+	store := &EmbeddingStore{
+		filePath: filePath,
+		keys:     keys,
+		records:  make(map[int64]EmbeddingRecord),
+	}
+
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		logger.Info("Embeddings file not found, starting empty", "path", filePath)
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embeddings file: %w", err)
+	}
+	sealed := encryption.Sealed(data)
+	reseal := keys != nil && (!sealed || keys.Stale(data))
+	if sealed {
+		if keys == nil {
+			return nil, fmt.Errorf("embeddings file is encrypted but no data keys are configured")
+		}
+		if data, err = keys.Open(data, store.aad()); err != nil {
+			return nil, fmt.Errorf("failed to decrypt embeddings file: %w", err)
+		}
+	}
+
+	var file embeddingFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings JSON: %w", err)
+	}
+
+	for _, record := range file.Records {
+		store.records[record.UserID] = record
+	}
+
+	logger.Info("Loaded enrolled embeddings", "count", len(store.records), "encrypted", sealed)
+
+	if reseal {
+		if err := store.save(); err != nil {
+			return nil, fmt.Errorf("failed to reseal embeddings file: %w", err)
+		}
+		logger.Info("Embeddings file resealed", "key_id", keys.ActiveKeyID())
+	}
+	return store, nil
+}
+
+// Get returns the enrolled record for a user
+func (s *EmbeddingStore) Get(userID int64) (EmbeddingRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, exists := s.records[userID]
+	return record, exists
+}
+
+// Enroll stores (or replaces) a user's embedding and persists the store
+func (s *EmbeddingStore) Enroll(record EmbeddingRecord) error {
+	s.mu.Lock()
+	s.records[record.UserID] = record
+	s.mu.Unlock()
+	return s.save()
+}
+
+// save writes every record, sealed when the store has keys
+func (s *EmbeddingStore) save() error {
+	s.mu.RLock()
+	file := embeddingFile{Records: make([]EmbeddingRecord, 0, len(s.records))}
+	for _, r := range s.records {
+		file.Records = append(file.Records, r)
+	}
+	s.mu.RUnlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal embeddings: %w", err)
+	}
+	if s.keys != nil {
+		if data, err = s.keys.Seal(data, s.aad()); err != nil {
+			return fmt.Errorf("failed to encrypt embeddings: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(s.filePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+// aad authenticates the file name, like the other sealed files
+func (s *EmbeddingStore) aad() []byte {
+	return []byte(filepath.Base(s.filePath))
+}
+
+// Count returns the number of enrolled users
+func (s *EmbeddingStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
@@ -1,10 +1,12 @@
 package detector
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
-	"log"
 	"mezon-checkin-bot/internal/api"
 	"mezon-checkin-bot/models"
+	"strconv"
 	"time"
 )
 
@@ -12,32 +14,64 @@ import (
 // FACE RECOGNITION SERVICE
 // ============================================================
 
+// CheckinBackend submits crops to the check-in backend for recognition
+type CheckinBackend interface {
+	SubmitImages(ctx context.Context, jpegImgs [][]byte, userId int64, attemptNum int) (*models.FaceRecognitionResponse, error)
+}
+
+// newCheckinBackend picks the REST or gRPC backend from the config
+func newCheckinBackend(config *models.FaceRecognitionConfig, apiClient *api.APIClient) (CheckinBackend, error) {
+	if config.Backend != models.BackendGRPC {
+		return NewFaceRecognitionService(apiClient, config), nil
+	}
+
+	if config.GRPCAddress == "" {
+		return nil, fmt.Errorf("gRPC backend selected but no address configured")
+	}
+	timeout := 30 * time.Second
+	if apiClient != nil {
+		timeout = apiClient.Timeout
+	}
+	client, err := api.NewGRPCClient(config.GRPCAddress, timeout, apiClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+	logger.Info("Check-in backend: gRPC", "target", config.GRPCAddress)
+	return NewGRPCRecognitionService(client), nil
+}
+
+// FaceRecognitionService is the REST check-in backend
 type FaceRecognitionService struct {
 	apiClient *api.APIClient
 	config    *models.FaceRecognitionConfig
 }
 
 // NewFaceRecognitionService creates a new face recognition service
-func NewFaceRecognitionService(apiClient *api.APIClient) *FaceRecognitionService {
+func NewFaceRecognitionService(apiClient *api.APIClient, config *models.FaceRecognitionConfig) *FaceRecognitionService {
+	if apiClient == nil {
+		apiClient = api.NewAPIClient(30 * time.Second)
+	}
 	return &FaceRecognitionService{
-		apiClient: api.NewAPIClient(30 * time.Second),
+		apiClient: apiClient,
+		config:    config,
 	}
 }
 
-// SubmitImage submits a base64 encoded image to the face recognition API
-func (s *FaceRecognitionService) SubmitImage(base64Img string, userId int64, attemptNum int) (*models.FaceRecognitionResponse, error) {
-	log.Printf("\n📤 [Attempt %d/5] Submitting image to API...", attemptNum)
+// SubmitImage submits a JPEG image to the face recognition API
+func (s *FaceRecognitionService) SubmitImage(ctx context.Context, jpegImg []byte, userId int64, attemptNum int) (*models.FaceRecognitionResponse, error) {
+	return s.SubmitImages(ctx, [][]byte{jpegImg}, userId, attemptNum)
+}
 
-	// Prepare request payload
-	reqBody := models.FaceRecognitionRequest{
-		UserId: userId,
-		Imgs:   []string{base64Img},
-	}
+// SubmitImages submits several crops in one request and lets the backend pick the best match
+func (s *FaceRecognitionService) SubmitImages(ctx context.Context, jpegImgs [][]byte, userId int64, attemptNum int) (*models.FaceRecognitionResponse, error) {
+	callID := api.CallIDFromContext(ctx)
+	logger.Info("Submitting images to API", "user_id", userId, "call_id", callID,
+		"attempt", attemptNum, "images", len(jpegImgs), "mode", s.uploadMode())
 
 	// Send request
-	body, statusCode, err := s.apiClient.SendRequest(reqBody, models.APICheckIn)
+	body, statusCode, err := s.send(ctx, jpegImgs, userId)
 	if err != nil {
-		log.Printf("❌ API request failed: %v", err)
+		logger.Error("API request failed", "user_id", userId, "call_id", callID, "err", err)
 		return nil, err
 	}
 
@@ -46,33 +80,69 @@ func (s *FaceRecognitionService) SubmitImage(base64Img string, userId int64, att
 
 	// Check status code
 	if !s.apiClient.IsSuccessStatusCode(statusCode) {
-		if len(body) > 0 && len(body) < 500 {
-			log.Printf("   Error: %s", string(body))
-		}
-		return nil, fmt.Errorf("API returned status %d", statusCode)
+		return nil, api.ParseError(body, statusCode)
 	}
 
 	// Parse response
 	var result models.FaceRecognitionResponse
 	if err := s.apiClient.ParseResponse(body, &result); err != nil {
-		log.Printf("⚠️  Failed to parse response JSON: %v", err)
+		logger.Warn("Failed to parse response JSON", "err", err)
 		return nil, err
 	}
 
 	// Log recognition details
-	s.logRecognitionResult(&result)
+	logRecognitionResult(userId, &result)
 
 	return &result, nil
 }
 
-// logRecognitionResult logs the details of the face recognition result
-func (s *FaceRecognitionService) logRecognitionResult(result *models.FaceRecognitionResponse) {
-	log.Printf("👤 Employee: %s %s", result.FirstName, result.LastName)
-	log.Printf("🎯 Status: %s", result.FacialRecognitionStatus)
-	log.Printf("✅ Identity Verified: %v", result.IdentityVerified)
-	log.Printf("📊 Probability: %.2f%%", result.Probability*100)
+// uploadMode returns the configured upload mode, JSON by default
+func (s *FaceRecognitionService) uploadMode() string {
+	if s.config != nil && s.config.UploadMode == models.UploadModeMultipart {
+		return models.UploadModeMultipart
+	}
+	return models.UploadModeJSON
+}
 
+// send posts the crops in the configured upload mode
+func (s *FaceRecognitionService) send(ctx context.Context, jpegImgs [][]byte, userId int64) ([]byte, int, error) {
+	if s.uploadMode() == models.UploadModeMultipart {
+		files := make([]api.MultipartFile, len(jpegImgs))
+		for i, img := range jpegImgs {
+			files[i] = api.MultipartFile{
+				Field:       "imgs",
+				FileName:    fmt.Sprintf("crop-%d.jpg", i),
+				ContentType: "image/jpeg",
+				Data:        img,
+			}
+		}
+		fields := map[string]string{"userId": strconv.FormatInt(userId, 10)}
+		return s.apiClient.SendMultipartContext(ctx, models.APICheckIn, fields, files)
+	}
+
+	// Base64 only at the JSON boundary
+	base64Imgs := make([]string, len(jpegImgs))
+	for i, img := range jpegImgs {
+		base64Imgs[i] = base64.StdEncoding.EncodeToString(img)
+	}
+	reqBody := models.FaceRecognitionRequest{
+		UserId: userId,
+		Imgs:   base64Imgs,
+	}
+	return s.apiClient.SendRequestContext(ctx, reqBody, models.APICheckIn)
+}
+
+// logRecognitionResult logs the details of the face recognition result
+func logRecognitionResult(userId int64, result *models.FaceRecognitionResponse) {
+	args := []any{
+		"user_id", userId,
+		"employee", result.GetFullName(),
+		"status", result.FacialRecognitionStatus,
+		"verified", result.IdentityVerified,
+		"probability", result.Probability,
+	}
 	if result.HasLastClockEvent() {
-		log.Printf("⏰ Last Clock: %s", result.LastClockEventDTO.StartTime)
+		args = append(args, "last_clock", result.LastClockEventDTO.StartTime)
 	}
+	logger.Info("Recognition result", args...)
 }
@@ -0,0 +1,39 @@
+//go:build !cuda
+
+package detector
+
+import (
+	"fmt"
+	"image"
+	"mezon-checkin-bot/models"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// CUDA BACKEND STUB - Default build without CUDA
+// ============================================================
+
+type gpuBackend struct{}
+
+func newGPUBackend(config *models.FaceRecognitionConfig) (*gpuBackend, error) {
+	return nil, fmt.Errorf("binary built without CUDA support (use -tags cuda)")
+}
+
+func (g *gpuBackend) Resize(src gocv.Mat, dst *gocv.Mat, size image.Point) error {
+	return fmt.Errorf("CUDA not available")
+}
+
+func (g *gpuBackend) Detect(img gocv.Mat) ([]image.Rectangle, error) {
+	return nil, fmt.Errorf("CUDA not available")
+}
+
+func (g *gpuBackend) Close() {}
+
+func dnnTargetFor(acceleration string) (gocv.NetBackendType, gocv.NetTargetType) {
+	// OpenCL works with stock OpenCV builds, CUDA DNN needs the cuda build
+	if acceleration == models.AccelerationOpenCL {
+		return gocv.NetBackendOpenCV, gocv.NetTargetFP32
+	}
+	return gocv.NetBackendDefault, gocv.NetTargetCPU
+}
@@ -0,0 +1,95 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/models"
+	"mezon-checkin-bot/proto/checkin"
+)
+
+// ============================================================
+// GRPC RECOGNITION SERVICE - Check-in backend over gRPC
+// ============================================================
+
+// GRPCRecognitionService submits crops through the streaming gRPC API and
+// logs the backend's progress events while the result is computed
+type GRPCRecognitionService struct {
+	client checkin.CheckinServiceClient
+}
+
+// NewGRPCRecognitionService creates a gRPC check-in backend
+func NewGRPCRecognitionService(client *api.GRPCClient) *GRPCRecognitionService {
+	return &GRPCRecognitionService{client: checkin.NewCheckinServiceClient(client)}
+}
+
+// SubmitImages submits the crops and waits for the final result
+func (s *GRPCRecognitionService) SubmitImages(ctx context.Context, jpegImgs [][]byte, userId int64, attemptNum int) (*models.FaceRecognitionResponse, error) {
+	callID := api.CallIDFromContext(ctx)
+	logger.Info("Submitting images via gRPC", "user_id", userId, "call_id", callID, "attempt", attemptNum, "images", len(jpegImgs))
+
+	stream, err := s.client.RecognizeStream(ctx, &checkin.RecognizeRequest{
+		UserId:  userId,
+		Imgs:    jpegImgs,
+		Attempt: int32(attemptNum),
+	})
+	if err != nil {
+		logger.Error("gRPC request failed", "user_id", userId, "call_id", callID, "err", err)
+		return nil, err
+	}
+
+	// A signed response is verified at the end of the stream, the result is
+	// only used once Recv returned io.EOF
+	var result *models.FaceRecognitionResponse
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logger.Error("gRPC request failed", "user_id", userId, "call_id", callID, "err", err)
+			return nil, err
+		}
+		if progress := event.GetProgress(); progress != nil {
+			logger.Debug("Backend progress", "user_id", userId, "call_id", callID, "stage", progress.GetStage(), "percent", progress.GetPercent())
+		}
+		if res := event.GetResult(); res != nil {
+			result = recognitionResponse(res)
+		}
+	}
+	if result == nil {
+		return nil, fmt.Errorf("gRPC stream ended without a result")
+	}
+
+	logRecognitionResult(userId, result)
+
+	return result, nil
+}
+
+// recognitionResponse converts a result to the REST API's response
+func recognitionResponse(r *checkin.RecognizeResult) *models.FaceRecognitionResponse {
+	response := &models.FaceRecognitionResponse{
+		FacialRecognitionStatus: r.GetFacialRecognitionStatus(),
+		ImageVerifyID:           r.GetImageVerifyId(),
+		EmployeeID:              r.GetEmployeeId(),
+		AccountEmployeeID:       r.GetAccountEmployeeId(),
+		FirstName:               r.GetFirstName(),
+		LastName:                r.GetLastName(),
+		IdentityVerified:        r.GetIdentityVerified(),
+		Probability:             r.GetProbability(),
+		ShowMessage:             r.GetShowMessage(),
+		IsWFH:                   r.GetIsWfh(),
+	}
+	if event := r.GetLastClockEvent(); event != nil {
+		response.LastClockEventDTO = &models.LastClockEventDTO{
+			ClockID:   event.GetClockId(),
+			ShiftID:   event.ShiftId,
+			StartTime: event.GetStartTime(),
+			EndTime:   event.EndTime,
+			LastBreak: event.LastBreak,
+		}
+	}
+	return response
+}
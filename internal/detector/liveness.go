@@ -0,0 +1,327 @@
+package detector
+
+import (
+	"image"
+	"math"
+	"mezon-checkin-bot/internal/matpool"
+	"sort"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// LIVENESS TRACKER - Blink / head-turn detection across frames
+// ============================================================
+
+const (
+	defaultLivenessMaxFrames = 10
+	defaultEyeCascadePath    = "haarcascade_eye.xml"
+
+	frontalYaw      = 0.10 // |Yaw| below this is looking straight
+	turnedYaw       = 0.25 // |Yaw| above this is a turned head
+	minPoseFrames   = 2    // Consecutive frames a pose must be held
+	minOpenFrames   = 2    // Open-eye frames establishing the EAR baseline
+	closedEARFactor = 0.6  // EAR below this fraction of the baseline is a closed eye
+)
+
+// FaceLandmarks are the five YuNet face points in image coordinates. Without
+// YuNet only the eyes are known (from the eye cascade) and HasNose is false.
+type FaceLandmarks struct {
+	RightEye   image.Point // Subject's right eye, on the image left
+	LeftEye    image.Point
+	Nose       image.Point
+	MouthRight image.Point
+	MouthLeft  image.Point
+	HasNose    bool
+}
+
+// eyeDistance is the distance between the eye centers in pixels
+func (l FaceLandmarks) eyeDistance() float64 {
+	return math.Hypot(float64(l.LeftEye.X-l.RightEye.X), float64(l.LeftEye.Y-l.RightEye.Y))
+}
+
+// Yaw estimates the head rotation as the horizontal nose offset from the eye
+// midpoint, normalized by the eye distance (0 = frontal). Turning a flat
+// photo scales both equally, so only a real head changes it.
+func (l FaceLandmarks) Yaw() float64 {
+	dist := l.eyeDistance()
+	if !l.HasNose || dist == 0 {
+		return 0
+	}
+	mid := float64(l.RightEye.X+l.LeftEye.X) / 2
+	return (float64(l.Nose.X) - mid) / dist
+}
+
+// Frontal reports whether the face looks straight at the camera. Always
+// true without a nose landmark.
+func (l FaceLandmarks) Frontal() bool {
+	return math.Abs(l.Yaw()) < frontalYaw
+}
+
+// Turned reports whether the head is clearly turned to one side
+func (l FaceLandmarks) Turned() bool {
+	return l.HasNose && math.Abs(l.Yaw()) >= turnedYaw
+}
+
+// EyesClosed reports whether ear is low enough against the open-eye ratio
+// of the same face to count as closed eyes
+func EyesClosed(ear, openEAR float64) bool {
+	return openEAR > 0 && ear < openEAR*closedEARFactor
+}
+
+// relativeTo maps the landmarks found in face "from" onto face "to", for
+// frames where the eye cascade loses closed eyes
+func (l FaceLandmarks) relativeTo(from, to image.Rectangle) FaceLandmarks {
+	if from.Dx() == 0 {
+		return l
+	}
+	scale := float64(to.Dx()) / float64(from.Dx())
+	move := func(p image.Point) image.Point {
+		return image.Pt(
+			to.Min.X+int(float64(p.X-from.Min.X)*scale),
+			to.Min.Y+int(float64(p.Y-from.Min.Y)*scale),
+		)
+	}
+	return FaceLandmarks{RightEye: move(l.RightEye), LeftEye: move(l.LeftEye)}
+}
+
+// Landmarks returns the face landmarks: all five from YuNet when the DNN
+// backend is active, otherwise the eye centers from the eye cascade
+func (fd *FaceDetector) Landmarks(img gocv.Mat, face image.Rectangle) (FaceLandmarks, bool) {
+	if fd.landmarker != nil {
+		if landmarks, ok := fd.landmarker.Landmarks(img, face); ok {
+			return landmarks, true
+		}
+	}
+	if !fd.eyeReady {
+		return FaceLandmarks{}, false
+	}
+
+	// Eyes are in the upper half of the face box
+	upper := image.Rect(face.Min.X, face.Min.Y, face.Max.X, face.Min.Y+face.Dy()/2)
+	eyes := detectEyes(fd.eyeClassifiers, img, upper)
+	if len(eyes) < 2 {
+		return FaceLandmarks{}, false
+	}
+	sort.Slice(eyes, func(i, j int) bool {
+		return eyes[i].Dx()*eyes[i].Dy() > eyes[j].Dx()*eyes[j].Dy()
+	})
+	right, left := centerOf(eyes[0]), centerOf(eyes[1])
+	if right.X > left.X {
+		right, left = left, right
+	}
+	return FaceLandmarks{RightEye: right, LeftEye: left}, true
+}
+
+// EyeAspectRatio estimates how open the eyes are: the height/width ratio of
+// the dark iris and lash region around each eye center, averaged. Closed
+// eyes leave a thin horizontal line.
+func EyeAspectRatio(img gocv.Mat, landmarks FaceLandmarks) (float64, bool) {
+	dist := landmarks.eyeDistance()
+	if dist < 10 {
+		return 0, false
+	}
+
+	gray := matpool.Get()
+	defer matpool.Put(gray)
+	gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+
+	var sum float64
+	for _, eye := range []image.Point{landmarks.RightEye, landmarks.LeftEye} {
+		ratio, ok := eyePatchRatio(gray, eye, dist)
+		if !ok {
+			return 0, false
+		}
+		sum += ratio
+	}
+	return sum / 2, true
+}
+
+func eyePatchRatio(gray gocv.Mat, eye image.Point, eyeDist float64) (float64, bool) {
+	// Low enough to leave out the eyebrow
+	halfW, halfH := int(eyeDist*0.3), int(eyeDist*0.15)
+	patch := image.Rect(eye.X-halfW, eye.Y-halfH, eye.X+halfW, eye.Y+halfH).
+		Intersect(image.Rect(0, 0, gray.Cols(), gray.Rows()))
+	if patch.Dx() < 4 || patch.Dy() < 4 {
+		return 0, false
+	}
+
+	roi := gray.Region(patch)
+	defer roi.Close()
+	dark := gocv.NewMat()
+	defer dark.Close()
+	gocv.Threshold(roi, &dark, 0, 255, gocv.ThresholdBinaryInv|gocv.ThresholdOtsu)
+
+	contours := gocv.FindContours(dark, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+
+	var largest image.Rectangle
+	bestArea := 0.0
+	for i := 0; i < contours.Size(); i++ {
+		contour := contours.At(i)
+		if area := gocv.ContourArea(contour); area > bestArea {
+			bestArea = area
+			largest = gocv.BoundingRect(contour)
+		}
+	}
+	if largest.Dx() == 0 {
+		return 0, false
+	}
+	return float64(largest.Dy()) / float64(largest.Dx()), true
+}
+
+type headPose int
+
+const (
+	poseUnknown headPose = iota
+	poseFrontal
+	poseTurned
+)
+
+// LivenessTracker accumulates landmark observations of the same face over
+// consecutive frames. The face is considered live once either a blink (eye
+// aspect ratio open → closed → open) or a head turn (landmark yaw frontal,
+// then turned, each held for minPoseFrames) is seen. Moving a printed photo
+// changes neither. A frame without landmarks breaks both sequences rather
+// than counting as a closed eye.
+type LivenessTracker struct {
+	landmarks func(gocv.Mat, image.Rectangle) (FaceLandmarks, bool)
+	maxFrames int
+
+	frames   int
+	verified bool
+
+	// Head turn
+	pose       headPose
+	poseRun    int
+	sawFrontal bool
+	sawTurned  bool
+
+	// Blink
+	openEAR      float64 // Mean EAR of the open-eye run
+	openFrames   int
+	closedFrames int
+
+	lastEyes FaceLandmarks // Last landmarks with both eyes found
+	lastFace image.Rectangle
+	hasEyes  bool
+}
+
+// NewLivenessTracker creates a tracker for a single call
+func (fd *FaceDetector) NewLivenessTracker() *LivenessTracker {
+	maxFrames := fd.Config.LivenessMaxFrames
+	if maxFrames <= 0 {
+		maxFrames = defaultLivenessMaxFrames
+	}
+
+	return &LivenessTracker{
+		landmarks: fd.Landmarks,
+		maxFrames: maxFrames,
+	}
+}
+
+// Observe feeds one frame with its detected face (empty if none was found).
+// Returns true once liveness is confirmed.
+func (t *LivenessTracker) Observe(img gocv.Mat, face image.Rectangle) bool {
+	if t.verified {
+		return true
+	}
+	t.frames++
+	if face.Empty() {
+		t.breakSequences()
+		return false
+	}
+
+	landmarks, ok := t.landmarks(img, face)
+	switch {
+	case ok:
+		t.lastEyes, t.lastFace, t.hasEyes = landmarks, face, true
+	case t.hasEyes:
+		// The eye cascade misses closed eyes, measure where they were
+		landmarks = t.lastEyes.relativeTo(t.lastFace, face)
+	default:
+		t.breakSequences()
+		return false
+	}
+
+	if landmarks.HasNose && t.observeYaw(landmarks.Yaw()) {
+		t.markVerified("head_turn")
+		return true
+	}
+
+	ear, ok := EyeAspectRatio(img, landmarks)
+	if !ok {
+		t.breakSequences()
+		return false
+	}
+	if t.observeEAR(ear) {
+		t.markVerified("blink")
+		return true
+	}
+	return false
+}
+
+// observeYaw tracks held poses, true once both frontal and turned were held
+func (t *LivenessTracker) observeYaw(yaw float64) bool {
+	pose := poseUnknown
+	switch {
+	case math.Abs(yaw) < frontalYaw:
+		pose = poseFrontal
+	case math.Abs(yaw) >= turnedYaw:
+		pose = poseTurned
+	}
+
+	if pose == t.pose {
+		t.poseRun++
+	} else {
+		t.pose, t.poseRun = pose, 1
+	}
+	if t.poseRun >= minPoseFrames {
+		t.sawFrontal = t.sawFrontal || pose == poseFrontal
+		t.sawTurned = t.sawTurned || pose == poseTurned
+	}
+	return t.sawFrontal && t.sawTurned
+}
+
+// observeEAR tracks the open → closed → open sequence, true when it completes
+func (t *LivenessTracker) observeEAR(ear float64) bool {
+	closed := t.openFrames >= minOpenFrames && EyesClosed(ear, t.openEAR)
+	switch {
+	case closed:
+		t.closedFrames++
+		return false
+	case t.closedFrames > 0:
+		return true
+	default:
+		t.openFrames++
+		t.openEAR += (ear - t.openEAR) / float64(t.openFrames)
+		return false
+	}
+}
+
+// breakSequences restarts the pose and blink sequences; what was already
+// seen in full is kept
+func (t *LivenessTracker) breakSequences() {
+	t.pose, t.poseRun = poseUnknown, 0
+	t.openEAR, t.openFrames, t.closedFrames = 0, 0, 0
+}
+
+// Verified reports whether liveness has been confirmed
+func (t *LivenessTracker) Verified() bool {
+	return t.verified
+}
+
+// Exhausted reports whether the frame budget ran out without confirmation
+func (t *LivenessTracker) Exhausted() bool {
+	return !t.verified && t.frames >= t.maxFrames
+}
+
+// Frames returns the number of observed frames
+func (t *LivenessTracker) Frames() int {
+	return t.frames
+}
+
+func (t *LivenessTracker) markVerified(reason string) {
+	t.verified = true
+	logger.Info("Liveness confirmed", "reason", reason, "frames", t.frames)
+}
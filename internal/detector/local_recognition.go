@@ -0,0 +1,150 @@
+package detector
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"mezon-checkin-bot/models"
+	"os"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// LOCAL RECOGNIZER - On-device embeddings (SFace) matching
+// ============================================================
+
+const (
+	defaultLocalMatchThreshold = 0.363 // SFace cosine threshold
+	sfaceInputSize             = 112
+	localRecognitionStatus     = "LOCAL_MATCH"
+)
+
+type LocalRecognizer struct {
+	recognizer gocv.FaceRecognizerSF
+	store      *EmbeddingStore
+	threshold  float32
+}
+
+// NewLocalRecognizer loads the embedding model and the enrolled embeddings
+func NewLocalRecognizer(config *models.FaceRecognitionConfig) (*LocalRecognizer, error) {
+	if config.RecognitionModelPath == "" {
+		return nil, fmt.Errorf("recognition model path not configured")
+	}
+
+	if _, err := os.Stat(config.RecognitionModelPath); err != nil {
+		return nil, fmt.Errorf("recognition model not found: %w", err)
+	}
+
+	store, err := NewEmbeddingStore(config.EmbeddingsFilePath, config.EmbeddingsKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := config.LocalMatchThreshold
+	if threshold <= 0 {
+		threshold = defaultLocalMatchThreshold
+	}
+
+	lr := &LocalRecognizer{
+		recognizer: gocv.NewFaceRecognizerSF(config.RecognitionModelPath, ""),
+		store:      store,
+		threshold:  threshold,
+	}
+
+	logger.Info("Local recognizer ready", "enrolled", store.Count(), "threshold", threshold)
+	return lr, nil
+}
+
+// Embed computes a normalized embedding for a square face crop
+func (lr *LocalRecognizer) Embed(face gocv.Mat) ([]float32, error) {
+	resized := gocv.NewMat()
+	defer resized.Close()
+	gocv.Resize(face, &resized, image.Pt(sfaceInputSize, sfaceInputSize), 0, 0, gocv.InterpolationLinear)
+
+	feature := gocv.NewMat()
+	defer feature.Close()
+	lr.recognizer.Feature(resized, &feature)
+
+	data, err := feature.DataPtrFloat32()
+	if err != nil {
+		return nil, fmt.Errorf("read feature: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty feature")
+	}
+
+	embedding := make([]float32, len(data))
+	copy(embedding, data)
+	return embedding, nil
+}
+
+// Verify matches the crop against the user's enrolled embedding.
+// Returns enrolled=false when the user has no embedding yet.
+func (lr *LocalRecognizer) Verify(face gocv.Mat, userID int64) (response *models.FaceRecognitionResponse, enrolled bool, err error) {
+	record, exists := lr.store.Get(userID)
+	if !exists {
+		return nil, false, nil
+	}
+
+	embedding, err := lr.Embed(face)
+	if err != nil {
+		return nil, true, err
+	}
+
+	score := cosineSimilarity(embedding, record.Embedding)
+	logger.Debug("Local match score", "score", score, "threshold", lr.threshold)
+
+	if score < lr.threshold {
+		return nil, true, nil
+	}
+
+	return &models.FaceRecognitionResponse{
+		FacialRecognitionStatus: localRecognitionStatus,
+		EmployeeID:              record.EmployeeID,
+		FirstName:               record.FirstName,
+		LastName:                record.LastName,
+		IdentityVerified:        true,
+		Probability:             float64(score),
+	}, true, nil
+}
+
+// Enroll stores the crop's embedding using identity data from an API response
+func (lr *LocalRecognizer) Enroll(face gocv.Mat, userID int64, response *models.FaceRecognitionResponse) error {
+	embedding, err := lr.Embed(face)
+	if err != nil {
+		return err
+	}
+
+	return lr.store.Enroll(EmbeddingRecord{
+		UserID:     userID,
+		EmployeeID: response.EmployeeID,
+		FirstName:  response.FirstName,
+		LastName:   response.LastName,
+		Embedding:  embedding,
+		EnrolledAt: time.Now(),
+	})
+}
+
+// Close releases the model
+func (lr *LocalRecognizer) Close() {
+	lr.recognizer.Close()
+}
+
+func cosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
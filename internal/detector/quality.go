@@ -0,0 +1,143 @@
+package detector
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"mezon-checkin-bot/internal/matpool"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// FACE QUALITY SCORING - Reject unusable crops before the API
+// ============================================================
+
+const (
+	defaultMinSharpness    = 60.0  // Laplacian variance
+	defaultMinBrightness   = 50.0  // Mean gray level (0-255)
+	defaultMaxBrightness   = 210.0 // Mean gray level (0-255)
+	defaultMinFaceRatio    = 0.15  // Face width / frame width
+	defaultMaxYawAsymmetry = 0.25  // Left/right half brightness difference
+)
+
+// Reject reasons reported by ScoreQuality
+const (
+	RejectBlurry      = "blurry"
+	RejectTooDark     = "too dark"
+	RejectOverexposed = "overexposed"
+	RejectFaceSmall   = "face too small in frame"
+	RejectHeadTurned  = "head turned"
+)
+
+// QualityReport holds the metrics computed for a face crop
+type QualityReport struct {
+	Sharpness    float64 // Variance of Laplacian, higher is sharper
+	Brightness   float64 // Mean gray level
+	FaceRatio    float64 // Face width relative to frame width
+	YawAsymmetry float64 // Rough yaw proxy, 0 = frontal
+	Passed       bool
+	RejectReason string
+}
+
+// String returns a compact representation for logging
+func (q QualityReport) String() string {
+	return fmt.Sprintf("sharpness=%.1f brightness=%.1f face_ratio=%.2f yaw=%.2f",
+		q.Sharpness, q.Brightness, q.FaceRatio, q.YawAsymmetry)
+}
+
+// ScoreQuality computes blur, brightness, relative size and a pose estimate
+// for the face region of img and checks them against the configured limits.
+func (fd *FaceDetector) ScoreQuality(img gocv.Mat, face image.Rectangle) QualityReport {
+	report := QualityReport{}
+
+	face = face.Intersect(image.Rect(0, 0, img.Cols(), img.Rows()))
+	if face.Empty() {
+		report.RejectReason = "empty face region"
+		return report
+	}
+
+	region := img.Region(face)
+	defer region.Close()
+
+	gray := matpool.Get()
+	defer matpool.Put(gray)
+	gocv.CvtColor(region, &gray, gocv.ColorBGRToGray)
+
+	report.Sharpness = laplacianVariance(gray)
+	report.Brightness = gray.Mean().Val1
+	report.FaceRatio = float64(face.Dx()) / float64(img.Cols())
+	report.YawAsymmetry = yawAsymmetry(gray)
+
+	cfg := fd.Config
+	minSharpness := valueOrDefault(cfg.QualityMinSharpness, defaultMinSharpness)
+	minBrightness := valueOrDefault(cfg.QualityMinBrightness, defaultMinBrightness)
+	maxBrightness := valueOrDefault(cfg.QualityMaxBrightness, defaultMaxBrightness)
+	minFaceRatio := valueOrDefault(cfg.QualityMinFaceRatio, defaultMinFaceRatio)
+	maxYaw := valueOrDefault(cfg.QualityMaxYawAsymmetry, defaultMaxYawAsymmetry)
+
+	switch {
+	case report.Sharpness < minSharpness:
+		report.RejectReason = RejectBlurry
+	case report.Brightness < minBrightness:
+		report.RejectReason = RejectTooDark
+	case report.Brightness > maxBrightness:
+		report.RejectReason = RejectOverexposed
+	case report.FaceRatio < minFaceRatio:
+		report.RejectReason = RejectFaceSmall
+	case report.YawAsymmetry > maxYaw:
+		report.RejectReason = RejectHeadTurned
+	default:
+		report.Passed = true
+	}
+
+	return report
+}
+
+func laplacianVariance(gray gocv.Mat) float64 {
+	lap := matpool.Get()
+	defer matpool.Put(lap)
+	if err := gocv.Laplacian(gray, &lap, gocv.MatTypeCV64F, 1, 1, 0, gocv.BorderDefault); err != nil {
+		return 0
+	}
+
+	mean := gocv.NewMat()
+	defer mean.Close()
+	stddev := gocv.NewMat()
+	defer stddev.Close()
+	if err := gocv.MeanStdDev(lap, &mean, &stddev); err != nil {
+		return 0
+	}
+
+	sd := stddev.GetDoubleAt(0, 0)
+	return sd * sd
+}
+
+// yawAsymmetry compares mean brightness of the left and right halves of the
+// face. Frontal faces under even light are roughly symmetric; a turned head
+// shows one cheek much more than the other.
+func yawAsymmetry(gray gocv.Mat) float64 {
+	half := gray.Cols() / 2
+	if half == 0 {
+		return 0
+	}
+
+	left := gray.Region(image.Rect(0, 0, half, gray.Rows()))
+	defer left.Close()
+	right := gray.Region(image.Rect(gray.Cols()-half, 0, gray.Cols(), gray.Rows()))
+	defer right.Close()
+
+	l := left.Mean().Val1
+	r := right.Mean().Val1
+	if l+r == 0 {
+		return 0
+	}
+	return math.Abs(l-r) / ((l + r) / 2)
+}
+
+func valueOrDefault(v, def float64) float64 {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,86 @@
+package detector
+
+import (
+	"mezon-checkin-bot/models"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// RECOGNITION CACHE - Short-TTL successful results per user
+// ============================================================
+
+type cachedRecognition struct {
+	response  *models.FaceRecognitionResponse
+	expiresAt time.Time
+}
+
+type RecognitionCache struct {
+	ttl     time.Duration
+	entries map[int64]cachedRecognition
+	mu      sync.Mutex
+}
+
+// NewRecognitionCache creates a cache; a non-positive ttl disables it
+func NewRecognitionCache(ttl time.Duration) *RecognitionCache {
+	return &RecognitionCache{
+		ttl:     ttl,
+		entries: make(map[int64]cachedRecognition),
+	}
+}
+
+// Get returns a cached successful response for the user if still fresh
+func (c *RecognitionCache) Get(userID int64) (*models.FaceRecognitionResponse, bool) {
+	if c == nil || c.ttl <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[userID]
+	if !exists {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, userID)
+		return nil, false
+	}
+	return entry.response, true
+}
+
+// Put stores a response if it represents a successful recognition
+func (c *RecognitionCache) Put(userID int64, response *models.FaceRecognitionResponse) {
+	if c == nil || c.ttl <= 0 || !response.IsSuccessful() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[userID] = cachedRecognition{
+		response:  response,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+	c.evictExpiredLocked()
+}
+
+// Invalidate removes the cached entry for a user
+func (c *RecognitionCache) Invalidate(userID int64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+}
+
+func (c *RecognitionCache) evictExpiredLocked() {
+	now := time.Now()
+	for userID, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, userID)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package diagnostics
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// ============================================================
+// PPROF - Runtime profiling endpoints
+// ============================================================
+
+// RegisterPprof mounts the net/http/pprof handlers under /debug/pprof/ on mux.
+// They are not registered on http.DefaultServeMux, so nothing is exposed
+// unless a server is started for them.
+func RegisterPprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
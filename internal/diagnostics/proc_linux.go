@@ -0,0 +1,43 @@
+package diagnostics
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// countChildProcesses counts live child processes of this process whose
+// command name is name, by scanning /proc
+func countChildProcesses(name string) (int, bool) {
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return 0, false
+	}
+
+	self := os.Getpid()
+	count := 0
+	for _, path := range stats {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // Process exited while scanning
+		}
+
+		// Format: pid (comm) state ppid ... where comm may contain spaces
+		stat := string(data)
+		open := strings.IndexByte(stat, '(')
+		end := strings.LastIndexByte(stat, ')')
+		if open < 0 || end < open {
+			continue
+		}
+		fields := strings.Fields(stat[end+1:])
+		if len(fields) < 2 || stat[open+1:end] != name {
+			continue
+		}
+		// Zombies are leaks too: nobody waited for them
+		if ppid, err := strconv.Atoi(fields[1]); err == nil && ppid == self {
+			count++
+		}
+	}
+	return count, true
+}
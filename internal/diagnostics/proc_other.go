@@ -0,0 +1,8 @@
+//go:build !linux
+
+package diagnostics
+
+// countChildProcesses is only implemented on Linux
+func countChildProcesses(name string) (int, bool) {
+	return 0, false
+}
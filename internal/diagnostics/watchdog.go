@@ -0,0 +1,189 @@
+package diagnostics
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/logging"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// WATCHDOG - Goroutine and ffmpeg subprocess leak detection
+// ============================================================
+
+var logger = logging.For("diagnostics")
+
+const (
+	DefaultWatchdogInterval = time.Minute
+	DefaultWatchdogWindow   = 10
+
+	// Growth over a window below these is treated as noise
+	minGoroutineGrowth = 20
+	minProcessGrowth   = 2
+)
+
+// WatchdogConfig controls sampling
+type WatchdogConfig struct {
+	Interval time.Duration // Time between samples (0 = DefaultWatchdogInterval)
+	Window   int           // Samples that must not decrease before alerting (0 = DefaultWatchdogWindow)
+
+	// ActiveCalls reports the number of open calls. Growth that is matched by
+	// more calls is load, not a leak. Optional.
+	ActiveCalls func() int
+}
+
+// Sample is one reading of the watched resources
+type Sample struct {
+	At         time.Time
+	Goroutines int
+	FFmpeg     int // -1 when child processes can't be counted on this platform
+	Calls      int
+}
+
+// Alert reports a resource that grew over a whole window
+type Alert struct {
+	Resource string // "goroutines" or "ffmpeg"
+	From     int
+	To       int
+	Window   time.Duration
+	Calls    int // Open calls at the end of the window
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("%s tăng liên tục từ %d lên %d trong %s (cuộc gọi đang mở: %d)",
+		a.Resource, a.From, a.To, a.Window.Round(time.Second), a.Calls)
+}
+
+// Watchdog periodically samples goroutine and ffmpeg subprocess counts and
+// alerts when one of them grows monotonically across the window
+type Watchdog struct {
+	config  WatchdogConfig
+	samples []Sample
+	onAlert []func(Alert)
+	stop    chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+}
+
+// NewWatchdog creates a stopped watchdog
+func NewWatchdog(config WatchdogConfig) *Watchdog {
+	if config.Interval <= 0 {
+		config.Interval = DefaultWatchdogInterval
+	}
+	if config.Window < 2 {
+		config.Window = DefaultWatchdogWindow
+	}
+	return &Watchdog{
+		config: config,
+		stop:   make(chan struct{}),
+	}
+}
+
+// OnAlert registers a callback run for every alert (in addition to logging)
+func (w *Watchdog) OnAlert(fn func(Alert)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onAlert = append(w.onAlert, fn)
+}
+
+// Start samples in the background until Stop
+func (w *Watchdog) Start() {
+	go func() {
+		ticker := time.NewTicker(w.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.observe(w.sample())
+			}
+		}
+	}()
+}
+
+// Stop stops sampling
+func (w *Watchdog) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+}
+
+// observe adds a sample and raises alerts for resources that grew across the
+// whole window
+func (w *Watchdog) observe(s Sample) {
+	w.mu.Lock()
+	w.samples = append(w.samples, s)
+	if len(w.samples) > w.config.Window {
+		w.samples = w.samples[len(w.samples)-w.config.Window:]
+	}
+
+	logger.Debug("Watchdog sample", "goroutines", s.Goroutines, "ffmpeg", s.FFmpeg, "calls", s.Calls)
+
+	var alerts []Alert
+	if len(w.samples) == w.config.Window {
+		if alert, ok := w.check("goroutines", minGoroutineGrowth, func(s Sample) int { return s.Goroutines }); ok {
+			alerts = append(alerts, alert)
+		}
+		if s.FFmpeg >= 0 {
+			if alert, ok := w.check("ffmpeg", minProcessGrowth, func(s Sample) int { return s.FFmpeg }); ok {
+				alerts = append(alerts, alert)
+			}
+		}
+		if len(alerts) > 0 {
+			// Start a fresh window so a leak alerts once per window, not every tick
+			w.samples = w.samples[:0]
+		}
+	}
+	callbacks := w.onAlert
+	w.mu.Unlock()
+
+	for _, alert := range alerts {
+		logger.Warn("Possible resource leak",
+			"resource", alert.Resource, "from", alert.From, "to", alert.To,
+			"window", alert.Window, "calls", alert.Calls)
+		for _, fn := range callbacks {
+			fn(alert)
+		}
+	}
+}
+
+// check reports whether value never decreased over the window, grew by at
+// least minGrowth, and the growth isn't explained by more open calls
+func (w *Watchdog) check(resource string, minGrowth int, value func(Sample) int) (Alert, bool) {
+	first, last := w.samples[0], w.samples[len(w.samples)-1]
+
+	for i := 1; i < len(w.samples); i++ {
+		if value(w.samples[i]) < value(w.samples[i-1]) {
+			return Alert{}, false
+		}
+	}
+	if value(last)-value(first) < minGrowth || last.Calls > first.Calls {
+		return Alert{}, false
+	}
+
+	return Alert{
+		Resource: resource,
+		From:     value(first),
+		To:       value(last),
+		Window:   last.At.Sub(first.At),
+		Calls:    last.Calls,
+	}, true
+}
+
+func (w *Watchdog) sample() Sample {
+	s := Sample{
+		At:         time.Now(),
+		Goroutines: runtime.NumGoroutine(),
+		FFmpeg:     -1,
+	}
+	if n, ok := countChildProcesses("ffmpeg"); ok {
+		s.FFmpeg = n
+	}
+	if w.config.ActiveCalls != nil {
+		s.Calls = w.config.ActiveCalls()
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/utils"
+	"os"
+	"path/filepath"
+)
+
+// ============================================================
+// INTEGRITY CHECK - At startup, before anything is sealed with
+// a key that can't open what is already stored
+// ============================================================
+
+var canaryPlaintext = []byte("mezon-checkin data key check")
+
+// Check seals and opens with the active key, then opens the canary file at
+// path, which was sealed by the keys of the previous start. A missing key
+// (a wrong key, or an old one removed before the data was resealed) fails
+// here instead of when a stored file is read. The canary is written, or
+// resealed with the active key.
+func (k *Keyring) Check(path string) error {
+	aad := []byte(filepath.Base(path))
+	sealed, err := k.Seal(canaryPlaintext, aad)
+	if err != nil {
+		return err
+	}
+	if opened, err := k.Open(sealed, aad); err != nil || !bytes.Equal(opened, canaryPlaintext) {
+		return fmt.Errorf("active key %q does not round trip: %v", k.ActiveKeyID(), err)
+	}
+
+	stored, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		logger.Info("Data key canary created", "path", path, "key_id", k.ActiveKeyID())
+	case err != nil:
+		return fmt.Errorf("failed to read data key canary: %w", err)
+	default:
+		opened, err := k.Open(stored, aad)
+		if err != nil {
+			return fmt.Errorf("stored data is unreadable with the loaded keys (canary sealed with key %q): %w", KeyID(stored), err)
+		}
+		if !bytes.Equal(opened, canaryPlaintext) {
+			return fmt.Errorf("data key canary %s is corrupted", path)
+		}
+		if !k.Stale(stored) {
+			return nil
+		}
+		logger.Info("Data key canary resealed", "path", path, "previous_key_id", KeyID(stored), "key_id", k.ActiveKeyID())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, sealed, 0600); err != nil {
+		return fmt.Errorf("failed to write data key canary: %w", err)
+	}
+	return nil
+}
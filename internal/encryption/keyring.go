@@ -0,0 +1,292 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/logging"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// KEYRING - AES-256-GCM for the data kept at rest (face crops,
+// debug frames, embeddings), with several keys for rotation
+// ============================================================
+
+var logger = logging.For("encryption")
+
+// KeyLen is the length of a data key (AES-256)
+const KeyLen = 32
+
+// magic starts sealed data: magic | key ID length | key ID | nonce | ciphertext.
+// Data without it was sealed before keys had IDs: nonce | ciphertext.
+var magic = []byte("MCK1")
+
+// ErrUnknownKey is returned for data sealed with a key that is not loaded
+var ErrUnknownKey = errors.New("sealed with a key that is not loaded")
+
+// Keyring seals with the active key and opens with any loaded key, found by
+// the key ID in the sealed data. Rotating means loading the next key, making
+// it active, resealing the stored data and then removing the old key.
+type Keyring struct {
+	keys     map[string]cipher.AEAD
+	activeID string
+	mu       sync.RWMutex
+}
+
+// NewKeyring creates a keyring with the given key ID -> 32 byte key map
+func NewKeyring(keys map[string][]byte, activeID string) (*Keyring, error) {
+	k := &Keyring{}
+	if err := k.SetKeys(keys, activeID); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// SetKeys replaces the loaded keys and the active one. On error the current
+// keys stay in use.
+func (k *Keyring) SetKeys(keys map[string][]byte, activeID string) error {
+	if _, exists := keys[activeID]; !exists {
+		return fmt.Errorf("data key %q not loaded", activeID)
+	}
+
+	loaded := make(map[string]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		if id == "" || len(id) > 255 {
+			return fmt.Errorf("invalid data key ID %q", id)
+		}
+		if len(key) != KeyLen {
+			return fmt.Errorf("data key %q must be %d bytes, got %d", id, KeyLen, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("data key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return fmt.Errorf("data key %q: %w", id, err)
+		}
+		loaded[id] = aead
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = loaded
+	k.activeID = activeID
+	return nil
+}
+
+// ActiveKeyID returns the ID of the key that seals
+func (k *Keyring) ActiveKeyID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.activeID
+}
+
+// Has reports whether the key is loaded
+func (k *Keyring) Has(id string) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	_, exists := k.keys[id]
+	return exists
+}
+
+// Seal encrypts plaintext with the active key. aad is authenticated but not
+// stored, e.g. the file name so a file can't be swapped for another.
+func (k *Keyring) Seal(plaintext, aad []byte) ([]byte, error) {
+	k.mu.RLock()
+	id := k.activeID
+	aead := k.keys[id]
+	k.mu.RUnlock()
+
+	header := make([]byte, 0, len(magic)+1+len(id))
+	header = append(header, magic...)
+	header = append(header, byte(len(id)))
+	header = append(header, id...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+aead.Overhead())
+	sealed = append(sealed, header...)
+	sealed = append(sealed, nonce...)
+	return aead.Seal(sealed, nonce, plaintext, append(header, aad...)), nil
+}
+
+// Open decrypts data sealed with any loaded key. Data sealed before keys
+// had IDs is tried with every key.
+func (k *Keyring) Open(sealed, aad []byte) ([]byte, error) {
+	id, headerLen, ok := parseHeader(sealed)
+	if !ok {
+		return k.openLegacy(sealed, aad)
+	}
+
+	k.mu.RLock()
+	aead, exists := k.keys[id]
+	k.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("key %q: %w", id, ErrUnknownKey)
+	}
+
+	header, body := sealed[:headerLen], sealed[headerLen:]
+	if len(body) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed data is truncated")
+	}
+	plaintext, err := aead.Open(nil, body[:aead.NonceSize()], body[aead.NonceSize():], append(header[:len(header):len(header)], aad...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with key %q: %w", id, err)
+	}
+	return plaintext, nil
+}
+
+func (k *Keyring) openLegacy(sealed, aad []byte) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, aead := range k.keys {
+		if len(sealed) < aead.NonceSize() {
+			return nil, fmt.Errorf("sealed data is truncated")
+		}
+		if plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, fmt.Errorf("no loaded key decrypts the data")
+}
+
+// Stale reports whether sealed was not sealed with the active key, so it
+// must be resealed before the old key is removed
+func (k *Keyring) Stale(sealed []byte) bool {
+	id, _, ok := parseHeader(sealed)
+	return !ok || id != k.ActiveKeyID()
+}
+
+// Sealed reports whether data starts like data sealed by a keyring
+func Sealed(data []byte) bool {
+	_, _, ok := parseHeader(data)
+	return ok
+}
+
+// KeyID returns the ID of the key that sealed the data ("" = sealed before
+// keys had IDs)
+func KeyID(sealed []byte) string {
+	id, _, _ := parseHeader(sealed)
+	return id
+}
+
+func parseHeader(sealed []byte) (id string, headerLen int, ok bool) {
+	if !bytes.HasPrefix(sealed, magic) || len(sealed) <= len(magic) {
+		return "", 0, false
+	}
+	idLen := int(sealed[len(magic)])
+	headerLen = len(magic) + 1 + idLen
+	if idLen == 0 || len(sealed) < headerLen {
+		return "", 0, false
+	}
+	return string(sealed[len(magic)+1 : headerLen]), headerLen, true
+}
+
+// ============================================================
+// KEY SOURCES
+// ============================================================
+
+// KeyFile is the JSON key file read by LoadKeyFile, e.g. mounted by the
+// secrets manager
+type KeyFile struct {
+	ActiveKeyID string            `json:"active_key_id"`
+	Keys        map[string]string `json:"keys"` // Key ID -> base64 of 32 bytes
+}
+
+// LoadKeyFile reads a key file and decodes its keys
+func LoadKeyFile(path string) (map[string][]byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read data key file: %w", err)
+	}
+	var file KeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, "", fmt.Errorf("failed to parse data key file: %w", err)
+	}
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, value := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("data key %q is not base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return keys, file.ActiveKeyID, nil
+}
+
+// ParseKeys parses "id1:base64key1,id2:base64key2"
+func ParseKeys(value string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for i, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// The entry itself is not reported, it holds the key
+		id, encoded, ok := strings.Cut(part, ":")
+		if !ok || id == "" || encoded == "" {
+			return nil, fmt.Errorf("invalid data key entry #%d (expected id:base64)", i+1)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("data key %q is not base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// WatchKeyFile reloads the keys whenever the file changes, checking every
+// interval until stop is closed. A broken file is logged and ignored.
+func (k *Keyring) WatchKeyFile(path string, interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var lastMod time.Time
+		if info, err := os.Stat(path); err == nil {
+			lastMod = info.ModTime()
+		}
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				logger.Warn("Failed to check data key file", "err", err)
+				continue
+			}
+			if info.ModTime().Equal(lastMod) {
+				continue
+			}
+			lastMod = info.ModTime()
+
+			previous := k.ActiveKeyID()
+			keys, activeID, err := LoadKeyFile(path)
+			if err == nil {
+				err = k.SetKeys(keys, activeID)
+			}
+			if err != nil {
+				logger.Error("Data key file rejected, keeping current keys", "err", err)
+				continue
+			}
+			logger.Info("Data keys reloaded", "key_id", activeID, "previous_key_id", previous, "keys", len(keys))
+		}
+	}()
+}
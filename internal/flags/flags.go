@@ -0,0 +1,235 @@
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/logging"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// FEATURE FLAGS - Risky features rolled out per office or share
+// of calls, and rolled back without a restart
+// ============================================================
+
+var logger = logging.For("flags")
+
+// Flags consulted by the bot
+const (
+	DNNDetector      = "dnn_detector"      // DNN face detection instead of Haar
+	Liveness         = "liveness"          // Blink check before recognition
+	PersistentFFmpeg = "persistent_ffmpeg" // One ffmpeg decoder per call instead of one per frame
+	KeyframeFilter   = "keyframe_filter"   // Drop delta frames' RTP packets before assembling samples
+)
+
+// Rule decides where a flag is on. Rules only narrow what the environment
+// loaded: a flag cannot enable a model that was not configured.
+type Rule struct {
+	Enabled bool     `json:"enabled"`           // Off everywhere when false, the kill switch
+	Offices []string `json:"offices,omitempty"` // Only for users of these offices (empty = all)
+	Percent *int     `json:"percent,omitempty"` // Share of users, 0-100 (nil = all)
+}
+
+// File is the flags file format
+type File struct {
+	Flags map[string]Rule `json:"flags"`
+}
+
+// Call is who a flag is evaluated for
+type Call struct {
+	UserID  int64
+	Offices []string // Offices the user may check in at
+}
+
+// Set holds the rules. Remote rules win over the file, the file over the
+// defaults.
+type Set struct {
+	mu       sync.RWMutex
+	defaults map[string]bool
+	file     map[string]Rule
+	remote   map[string]Rule
+}
+
+// New creates a set with no rules; every flag is at its default
+func New() *Set {
+	return &Set{defaults: make(map[string]bool)}
+}
+
+// SetDefault is the flag's value when no rule names it
+func (s *Set) SetDefault(name string, enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaults[name] = enabled
+}
+
+// SetRemote replaces the rules pushed by the backend (nil = none)
+func (s *Set) SetRemote(rules map[string]Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remote = rules
+}
+
+// Enabled reports whether the flag is on for the call. Percentages bucket
+// by user, so a user gets the same answer on every call.
+func (s *Set) Enabled(name string, call Call) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rule, ok := s.remote[name]
+	if !ok {
+		rule, ok = s.file[name]
+	}
+	if !ok {
+		return s.defaults[name]
+	}
+	return rule.matches(name, call)
+}
+
+func (r Rule) matches(name string, call Call) bool {
+	if !r.Enabled {
+		return false
+	}
+	if len(r.Offices) > 0 && !anyOffice(r.Offices, call.Offices) {
+		return false
+	}
+	if r.Percent != nil {
+		return bucket(name, call.UserID) < *r.Percent
+	}
+	return true
+}
+
+func anyOffice(allowed, offices []string) bool {
+	for _, office := range offices {
+		for _, id := range allowed {
+			if strings.EqualFold(office, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bucket places the user in 0-99, differently for each flag
+func bucket(name string, userID int64) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + strconv.FormatInt(userID, 10)))
+	return int(h.Sum32() % 100)
+}
+
+// Snapshot returns each flag's rule in effect and where it comes from
+func (s *Set) Snapshot() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := make(map[string]string)
+	for name, enabled := range s.defaults {
+		snapshot[name] = fmt.Sprintf("default %v", enabled)
+	}
+	for name, rule := range s.file {
+		snapshot[name] = "file " + rule.String()
+	}
+	for name, rule := range s.remote {
+		snapshot[name] = "remote " + rule.String()
+	}
+	return snapshot
+}
+
+func (r Rule) String() string {
+	if !r.Enabled {
+		return "off"
+	}
+	parts := []string{"on"}
+	if len(r.Offices) > 0 {
+		parts = append(parts, "offices="+strings.Join(r.Offices, ","))
+	}
+	if r.Percent != nil {
+		parts = append(parts, fmt.Sprintf("%d%%", *r.Percent))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Validate rejects rules the set cannot evaluate
+func Validate(rules map[string]Rule) error {
+	for name, rule := range rules {
+		if rule.Percent != nil && (*rule.Percent < 0 || *rule.Percent > 100) {
+			return fmt.Errorf("flag %s: percent %d out of 0-100", name, *rule.Percent)
+		}
+	}
+	return nil
+}
+
+// ============================================================
+// FILE - Rules edited on disk, reloaded when the file changes
+// ============================================================
+
+// LoadFile reads the rules from path. A missing file clears them.
+func (s *Set) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		s.setFile(nil)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read flags file: %w", err)
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse flags file: %w", err)
+	}
+	if err := Validate(file.Flags); err != nil {
+		return err
+	}
+	s.setFile(file.Flags)
+	return nil
+}
+
+func (s *Set) setFile(rules map[string]Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file = rules
+}
+
+// WatchFile reloads the rules whenever the file changes, checking every
+// interval until stop is closed. A broken file is logged and ignored.
+func (s *Set) WatchFile(path string, interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		defer alerting.Recover("flags_watch")
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var lastMod time.Time
+		if info, err := os.Stat(path); err == nil {
+			lastMod = info.ModTime()
+		}
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			var modTime time.Time
+			if info, err := os.Stat(path); err == nil {
+				modTime = info.ModTime()
+			}
+			if modTime.Equal(lastMod) {
+				continue
+			}
+			lastMod = modTime
+
+			if err := s.LoadFile(path); err != nil {
+				logger.Error("Flags file rejected, keeping current rules", "err", err)
+				continue
+			}
+			logger.Info("Feature flags reloaded", "path", path)
+		}
+	}()
+}
@@ -0,0 +1,110 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/logging"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var logger = logging.For("geocode")
+
+// ============================================================
+// REVERSE GEOCODER - Coordinates to human-readable address
+// ============================================================
+
+// Geocoder resolves coordinates to a human-readable address
+type Geocoder interface {
+	ReverseGeocode(ctx context.Context, lat, lon float64) (string, error)
+}
+
+const (
+	DefaultNominatimURL = "https://nominatim.openstreetmap.org/reverse"
+	defaultTimeout      = 5 * time.Second
+	userAgent           = "mezon-checkin-bot"
+)
+
+// NominatimGeocoder uses an OpenStreetMap Nominatim compatible endpoint.
+// Results are cached per ~10m grid cell since people check in from the same
+// few places every day.
+type NominatimGeocoder struct {
+	endpoint string
+	language string
+	client   *http.Client
+	cache    map[string]string
+	mu       sync.RWMutex
+}
+
+// NewNominatimGeocoder creates a geocoder; empty endpoint uses the public server
+func NewNominatimGeocoder(endpoint, language string) *NominatimGeocoder {
+	if endpoint == "" {
+		endpoint = DefaultNominatimURL
+	}
+	if language == "" {
+		language = "vi"
+	}
+	return &NominatimGeocoder{
+		endpoint: endpoint,
+		language: language,
+		client:   &http.Client{Timeout: defaultTimeout},
+		cache:    make(map[string]string),
+	}
+}
+
+type nominatimResponse struct {
+	DisplayName string `json:"display_name"`
+	Error       string `json:"error"`
+}
+
+// ReverseGeocode returns the display name of the coordinates
+func (g *NominatimGeocoder) ReverseGeocode(ctx context.Context, lat, lon float64) (string, error) {
+	key := fmt.Sprintf("%.4f,%.4f", lat, lon)
+
+	g.mu.RLock()
+	address, cached := g.cache[key]
+	g.mu.RUnlock()
+	if cached {
+		return address, nil
+	}
+
+	query := url.Values{}
+	query.Set("format", "jsonv2")
+	query.Set("lat", fmt.Sprintf("%.6f", lat))
+	query.Set("lon", fmt.Sprintf("%.6f", lon))
+	query.Set("accept-language", g.language)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("reverse geocode failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("reverse geocode failed: HTTP %d", resp.StatusCode)
+	}
+
+	var result nominatimResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse reverse geocode response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("reverse geocode failed: %s", result.Error)
+	}
+
+	g.mu.Lock()
+	g.cache[key] = result.DisplayName
+	g.mu.Unlock()
+
+	logger.Debug("Reverse geocoded", "lat", lat, "lon", lon, "place", result.DisplayName)
+	return result.DisplayName, nil
+}
@@ -0,0 +1,194 @@
+package i18n
+
+// ============================================================
+// BUILT-IN MESSAGES - Overridable by catalog files
+// ============================================================
+
+var builtin = map[string]map[string]string{
+	"vi": {
+		// Check-in messages
+		"checkin.scanning.title":           "🔍 Đang nhận diện khuôn mặt",
+		"checkin.scanning.body":            "Vui lòng nhìn thẳng vào camera trong giây lát.",
+		"checkin.confirmation.title":       "Xác định danh tính thành công - Cần xác minh vị trí",
+		"checkin.confirmation.body":        "Xin chào {name}. Vui lòng gửi vị trí của bạn về cho hệ thống trong vòng 1 phút để hoàn thành check-in!",
+		"checkin.confirmation.is_this_you": "Đây có phải bạn không? Nếu không phải, đừng gửi vị trí và hãy báo cho quản trị viên.",
+		"checkin.success.title":            "✅ Check-in thành công!",
+		"checkin.success.body":             "Chào mừng {name}! Bạn đã check-in thành công.",
+		"checkin.success.body_at":          "Chào mừng {name}! Bạn đã check-in thành công tại {place}.",
+		"checkin.failed.title":             "❌ Check-in thất bại",
+		"checkin.failed.reason":            "Lý do: {reason}",
+		"checkin.failed.call_id":           "Mã cuộc gọi: {call_id} (gửi mã này khi liên hệ hỗ trợ)",
+		"checkin.retry_button":             "🔄 Thử lại",
+		"checkin.notice.title":             "⚠️ Lưu ý khi check-in",
+		"checkout.success.title":           "👋 Check-out thành công!",
+		"checkout.success.body":            "Bạn đã check-out lúc {time}. Hẹn gặp lại!",
+		"checkout.success.body_name":       "Tạm biệt {name}! Bạn đã check-out lúc {time}. Hẹn gặp lại!",
+
+		// Shift fields
+		"shift.field.shift":    "Ca làm việc",
+		"shift.field.status":   "Trạng thái",
+		"shift.checked_in_at":  "Đã check-in lúc {time}",
+		"shift.on_shift":       "Đang trong ca",
+		"shift.on_shift_since": "Đang trong ca từ {time}",
+		"shift.not_checked_in": "Chưa check-in",
+
+		// Failure reasons
+		"reason.timeout":                 "Hết thời gian chờ",
+		"reason.pli_timeout":             "Không nhận được video",
+		"reason.max_attempts":            "Không xác định được danh tính",
+		"reason.liveness_failed":         "Không xác minh được người thật trước camera",
+		"reason.unknown":                 "Lỗi không xác định",
+		"reason.checkout_failed":         "Không thể check-out, vui lòng thử lại sau",
+		"reason.manager_rejected":        "Quản lý đã từ chối yêu cầu check-in",
+		"reason.invalid_location":        "Vị trí không hợp lệ",
+		"reason.qr_office_mismatch":      "Vị trí không khớp với văn phòng {office} của mã QR",
+		"reason.suspicious_location":     "Vị trí đáng ngờ, vui lòng gọi cho bot để check-in",
+		"reason.confirmation_timeout":    "Hết thời gian xác nhận vị trí",
+		"reason.confirmation_timeout_qr": "Hết thời gian xác nhận vị trí, vui lòng quét lại mã QR",
+		"reason.location_unverified":     "Vị trí không được xác minh",
+		"reason.photo_liveness":          "Không xác minh được người thật qua ảnh, vui lòng gọi cho bot để check-in",
+		"reason.photo_unrecognized":      "Không xác định được danh tính qua ảnh, vui lòng gọi cho bot để check-in",
+		"reason.qr_failed":               "Không thể check-in bằng mã QR",
+		"reason.home_pending":            "Vị trí làm việc tại nhà của bạn đang chờ quản trị viên duyệt",
+		"notice.checkin_queued":          "Đã ghi nhận check-in của bạn. Hệ thống chấm công đang gián đoạn, check-in sẽ được đồng bộ khi hệ thống hoạt động trở lại.",
+		"notice.checkin_queued_at":       "Đã ghi nhận check-in của bạn tại {place}. Hệ thống chấm công đang gián đoạn, check-in sẽ được đồng bộ khi hệ thống hoạt động trở lại.",
+
+		// Notices
+		"notice.already_checked_in": "Bạn đã check-in hôm nay rồi.",
+		"notice.multiple_faces":     "Phát hiện nhiều khuôn mặt. Vui lòng đứng một mình trước camera.",
+		"notice.escalated":          "Yêu cầu check-in của bạn đã được gửi đến quản lý để duyệt. Kết quả sẽ được gửi sau.",
+		"notice.interrupted":        "Bot vừa khởi động lại khi bạn đang check-in nên check-in chưa hoàn tất. Vui lòng gọi lại cho bot để check-in.",
+		"notice.poor_accuracy":      "Độ chính xác GPS quá thấp (±{accuracy}m). Vui lòng bật định vị chính xác và gửi lại vị trí.",
+		"notice.short_link_failed":  "Không mở được liên kết bản đồ. Vui lòng chia sẻ vị trí trực tiếp hoặc gửi toạ độ.",
+		"notice.wfh_confirm":        "Bạn đang làm việc tại nhà. Vui lòng gửi vị trí hiện tại để xác nhận check-in.",
+		"notice.wfh_register":       "Bạn chưa đăng ký vị trí làm việc tại nhà. Vui lòng gửi vị trí hiện tại để đăng ký (chỉ cần một lần).",
+		"notice.qr_accepted":        "Mã QR hợp lệ ({office}). Vui lòng gửi vị trí hiện tại để hoàn tất check-in.",
+		"notice.resend_location":    "Vui lòng gửi lại vị trí của bạn trong vòng 1 phút để hoàn thành check-in.",
+		"notice.maintenance":        "Hệ thống đang bảo trì, vui lòng thử lại sau ít phút.",
+		"notice.draining":           "Bot đang khởi động lại, vui lòng gọi lại sau ít phút.",
+		"notice.calls_limited":      "Bạn đã có quá nhiều lần thử check-in. Vui lòng thử lại sau {time}.",
+		"notice.identity_blocked":   "Check-in tạm khóa vì quá nhiều lần thử không xác định được danh tính. Vui lòng thử lại sau {time} hoặc liên hệ quản trị viên.",
+		"notice.review":             "Vị trí của bạn cần được quản trị viên xác minh thêm. Kết quả check-in sẽ được gửi sau.",
+		"review.typed_location":     "vị trí được nhập dạng văn bản, không phải chia sẻ vị trí",
+		"notice.late_reason":        "Bạn check-in muộn {minutes} phút. Vui lòng trả lời tin nhắn này với lý do đi muộn trong vòng {ttl} phút.",
+		"notice.voice_confirmed":    "Đã nhận xác nhận bằng giọng nói. Vui lòng gửi vị trí hiện tại để hoàn tất check-in.",
+
+		// Location confirmation buttons
+		"button.share_location":       "📍 Gửi vị trí của tôi",
+		"button.at_office":            "🏢 Tôi đang ở văn phòng {office}",
+		"notice.confirmation_expired": "Yêu cầu xác nhận vị trí đã hết hạn. Vui lòng gọi lại cho bot để check-in.",
+		"notice.share_location":       "Nhấn nút 📎 (đính kèm) trong khung chat, chọn Vị trí rồi gửi vị trí hiện tại của bạn. Bạn cũng có thể dán link Google Maps vị trí hiện tại.",
+
+		// Channel announcements
+		"announce.checked_in":     "✅ {name} đã check-in lúc {time}",
+		"announce.checked_in_at":  "✅ {name} đã check-in tại {office} lúc {time}",
+		"announce.checked_in_wfh": "🏠 {name} đã check-in (làm việc tại nhà) lúc {time}",
+
+		// QR and retry replies
+		"qr.invalid":         "Mã QR không hợp lệ. Vui lòng quét lại mã đang hiển thị tại văn phòng.",
+		"qr.expired":         "Mã QR đã hết hạn. Vui lòng quét mã mới nhất.",
+		"qr.used":            "Bạn đã dùng mã QR này. Vui lòng quét mã mới tại văn phòng.",
+		"qr.office_inactive": "Văn phòng của mã QR không còn hoạt động.",
+		"retry.expired":      "Yêu cầu thử lại đã hết hạn. Vui lòng gọi lại cho bot để check-in.",
+		"retry.call_failed":  "Bot không thể gọi lại lúc này. Vui lòng gọi cho bot để check-in.",
+		"retry.calling":      "📞 Bot đang gọi lại cho bạn, vui lòng nhận cuộc gọi để check-in.",
+
+		// Selfie check-in
+		"photo.download_failed": "Không tải được ảnh, vui lòng gửi lại ảnh selfie (JPEG/PNG).",
+		"photo.no_face":         "Không tìm thấy khuôn mặt trong ảnh, vui lòng chụp lại rõ mặt.",
+		"photo.multiple_faces":  "Ảnh có nhiều khuôn mặt, vui lòng chụp chỉ một mình bạn.",
+		"photo.low_quality":     "Ảnh chưa đạt chất lượng ({reason}), vui lòng chụp lại.",
+		"photo.look_straight":   "Vui lòng gửi một ảnh selfie nhìn thẳng vào camera.",
+		"photo.turn_head":       "📸 Đã nhận ảnh {count}. Trong vòng {seconds} giây, hãy chụp và gửi thêm một ảnh selfie với đầu quay hẳn sang một bên.",
+		"photo.close_eyes":      "📸 Đã nhận ảnh {count}. Trong vòng {seconds} giây, hãy chụp và gửi thêm một ảnh selfie với hai mắt nhắm.",
+		"photo.challenge_late":  "Ảnh được gửi quá thời gian yêu cầu.",
+		"photo.challenge_wrong": "Ảnh chưa đúng yêu cầu.",
+	},
+
+	"en": {
+		"checkin.scanning.title":           "🔍 Scanning your face",
+		"checkin.scanning.body":            "Please look straight at the camera for a moment.",
+		"checkin.confirmation.title":       "Identity verified - Location required",
+		"checkin.confirmation.body":        "Hi {name}. Please send your location within 1 minute to complete your check-in!",
+		"checkin.confirmation.is_this_you": "Is this you? If not, do not send your location and tell an administrator.",
+		"checkin.success.title":            "✅ Checked in!",
+		"checkin.success.body":             "Welcome {name}! You have checked in.",
+		"checkin.success.body_at":          "Welcome {name}! You have checked in at {place}.",
+		"checkin.failed.title":             "❌ Check-in failed",
+		"checkin.failed.reason":            "Reason: {reason}",
+		"checkin.failed.call_id":           "Call ID: {call_id} (include it when contacting support)",
+		"checkin.retry_button":             "🔄 Try again",
+		"checkin.notice.title":             "⚠️ Check-in notice",
+		"checkout.success.title":           "👋 Checked out!",
+		"checkout.success.body":            "You checked out at {time}. See you!",
+		"checkout.success.body_name":       "Goodbye {name}! You checked out at {time}. See you!",
+
+		"shift.field.shift":    "Shift",
+		"shift.field.status":   "Status",
+		"shift.checked_in_at":  "Checked in at {time}",
+		"shift.on_shift":       "On shift",
+		"shift.on_shift_since": "On shift since {time}",
+		"shift.not_checked_in": "Not checked in",
+
+		"reason.timeout":                 "Timed out",
+		"reason.pli_timeout":             "No video received",
+		"reason.max_attempts":            "Could not identify you",
+		"reason.liveness_failed":         "Could not verify a live person in front of the camera",
+		"reason.unknown":                 "Unknown error",
+		"reason.checkout_failed":         "Could not check out, please try again later",
+		"reason.manager_rejected":        "A manager rejected the check-in",
+		"reason.invalid_location":        "Invalid location",
+		"reason.qr_office_mismatch":      "Your location does not match office {office} of the QR code",
+		"reason.suspicious_location":     "Suspicious location, please call the bot to check in",
+		"reason.confirmation_timeout":    "No location received in time",
+		"reason.confirmation_timeout_qr": "No location received in time, please scan the QR code again",
+		"reason.location_unverified":     "Location not verified",
+		"reason.photo_liveness":          "Could not verify a live person from the photos, please call the bot to check in",
+		"reason.photo_unrecognized":      "Could not identify you from the photos, please call the bot to check in",
+		"reason.qr_failed":               "Could not check in with the QR code",
+		"reason.home_pending":            "Your home location is waiting for an admin to approve it",
+		"notice.checkin_queued":          "Your check-in is recorded. The attendance system is unavailable, it will be synced once the system is back.",
+		"notice.checkin_queued_at":       "Your check-in at {place} is recorded. The attendance system is unavailable, it will be synced once the system is back.",
+
+		"notice.already_checked_in": "You have already checked in today.",
+		"notice.multiple_faces":     "Several faces detected. Please stand alone in front of the camera.",
+		"notice.escalated":          "Your check-in was sent to your managers for approval. You will get the result later.",
+		"notice.interrupted":        "The bot restarted during your check-in, so it did not complete. Please call the bot again to check in.",
+		"notice.poor_accuracy":      "GPS accuracy is too low (±{accuracy}m). Please turn on precise location and send it again.",
+		"notice.short_link_failed":  "Could not open the map link. Please share your location directly or send the coordinates.",
+		"notice.wfh_confirm":        "You are working from home. Please send your current location to confirm your check-in.",
+		"notice.wfh_register":       "You have no home location yet. Please send your current location to register it (only once).",
+		"notice.qr_accepted":        "QR code accepted ({office}). Please send your current location to complete your check-in.",
+		"notice.resend_location":    "Please send your location again within 1 minute to complete your check-in.",
+		"notice.maintenance":        "The system is under maintenance, please try again in a few minutes.",
+		"notice.draining":           "The bot is restarting, please call again in a few minutes.",
+		"notice.calls_limited":      "You have tried to check in too many times. Please try again after {time}.",
+		"notice.identity_blocked":   "Check-in is locked after too many attempts where you could not be identified. Please try again after {time} or contact an admin.",
+		"notice.review":             "Your location needs to be verified by an administrator. You will get the check-in result later.",
+		"review.typed_location":     "the location was typed as text, not shared",
+		"notice.late_reason":        "You checked in {minutes} minutes late. Please reply to this message with the reason within {ttl} minutes.",
+		"notice.voice_confirmed":    "Voice confirmation received. Please send your current location to complete your check-in.",
+
+		"announce.checked_in":     "✅ {name} checked in at {time}",
+		"announce.checked_in_at":  "✅ {name} checked in at {office}, {time}",
+		"announce.checked_in_wfh": "🏠 {name} checked in (working from home) at {time}",
+
+		"qr.invalid":         "Invalid QR code. Please scan the code shown at the office again.",
+		"qr.expired":         "The QR code has expired. Please scan the latest one.",
+		"qr.used":            "You already used this QR code. Please scan a new one at the office.",
+		"qr.office_inactive": "The office of this QR code is no longer active.",
+		"retry.expired":      "The retry request has expired. Please call the bot again to check in.",
+		"retry.call_failed":  "The bot cannot call you back right now. Please call the bot to check in.",
+		"retry.calling":      "📞 The bot is calling you back, please answer to check in.",
+
+		"photo.download_failed": "Could not download the photo, please send your selfie again (JPEG/PNG).",
+		"photo.no_face":         "No face found in the photo, please take a clearer one.",
+		"photo.multiple_faces":  "The photo has several faces, please take one of yourself only.",
+		"photo.low_quality":     "The photo quality is too low ({reason}), please take another one.",
+		"photo.look_straight":   "Please send a selfie looking straight at the camera.",
+		"photo.turn_head":       "📸 Photo {count} received. Within {seconds} seconds, take and send one more selfie with your head turned to one side.",
+		"photo.close_eyes":      "📸 Photo {count} received. Within {seconds} seconds, take and send one more selfie with both eyes closed.",
+		"photo.challenge_late":  "The photo arrived too late.",
+		"photo.challenge_wrong": "The photo does not match the request.",
+	},
+}
@@ -0,0 +1,157 @@
+// Package i18n holds the user-facing message catalogs, one per locale, with
+// {name} placeholders filled at render time.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/logging"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ============================================================
+// MESSAGE CATALOG
+// ============================================================
+
+var logger = logging.For("i18n")
+
+// Vars fill the {name} placeholders of a message
+type Vars map[string]any
+
+// Translator renders messages in one locale
+type Translator func(key string, vars Vars) string
+
+// Catalog maps locale -> message key -> template
+type Catalog struct {
+	defaultLocale string
+	messages      map[string]map[string]string
+	mu            sync.RWMutex
+}
+
+// New returns a catalog with the built-in messages. Keys missing in a
+// locale fall back to defaultLocale.
+func New(defaultLocale string) *Catalog {
+	return &Catalog{
+		defaultLocale: normalize(defaultLocale),
+		messages:      builtinMessages(),
+	}
+}
+
+// LoadDir replaces the messages with the built-in ones overridden by every
+// <locale>.json file in dir, each a flat object of key -> template. It can be
+// called again to reload; on error the current messages are kept. A missing
+// dir is not an error. Returns the number of messages loaded from files.
+func (c *Catalog) LoadDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list message catalogs: %w", err)
+	}
+
+	messages := builtinMessages()
+	count := 0
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var overrides map[string]string
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return 0, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		locale := strings.TrimSuffix(filepath.Base(path), ".json")
+		merge(messages, locale, overrides)
+		count += len(overrides)
+		logger.Info("Message catalog loaded", "locale", normalize(locale), "messages", len(overrides))
+	}
+
+	c.mu.Lock()
+	c.messages = messages
+	c.mu.Unlock()
+	return count, nil
+}
+
+// builtinMessages returns a copy of the built-in messages
+func builtinMessages() map[string]map[string]string {
+	messages := make(map[string]map[string]string, len(builtin))
+	for locale, templates := range builtin {
+		merge(messages, locale, templates)
+	}
+	return messages
+}
+
+func merge(messages map[string]map[string]string, locale string, templates map[string]string) {
+	locale = normalize(locale)
+	if messages[locale] == nil {
+		messages[locale] = make(map[string]string, len(templates))
+	}
+	for key, template := range templates {
+		messages[locale][key] = template
+	}
+}
+
+// Text renders the message in locale, falling back to the default locale
+// and then to the key itself so a missing message is visible but harmless
+func (c *Catalog) Text(locale, key string, vars Vars) string {
+	c.mu.RLock()
+	template, ok := c.messages[normalize(locale)][key]
+	if !ok {
+		template, ok = c.messages[c.defaultLocale][key]
+	}
+	c.mu.RUnlock()
+
+	if !ok {
+		logger.Warn("Missing message", "locale", locale, "key", key)
+		template = key
+	}
+	return interpolate(template, vars)
+}
+
+// DefaultLocale returns the fallback locale, used for messages to channels
+func (c *Catalog) DefaultLocale() string {
+	return c.defaultLocale
+}
+
+// For returns a Translator bound to locale
+func (c *Catalog) For(locale string) Translator {
+	return func(key string, vars Vars) string {
+		return c.Text(locale, key, vars)
+	}
+}
+
+// Locales returns the locales with at least one message
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+func interpolate(template string, vars Vars) string {
+	if len(vars) == 0 || !strings.Contains(template, "{") {
+		return template
+	}
+	pairs := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// normalize turns "en-US" / "vi_VN" into "en" / "vi", like the audio
+// locale resolver
+func normalize(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
@@ -0,0 +1,218 @@
+package leader
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/logging"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================
+// LEADER ELECTION - One of two instances answers calls, the
+// other stays connected and takes over when the lease frees up
+// ============================================================
+
+var logger = logging.For("leader")
+
+const (
+	DefaultLeaseTTL = 15 * time.Second
+	standbyPoll     = time.Second // How often a standby tries the lease, bounds the handoff gap
+)
+
+// Lock is a lease held by one instance at a time
+type Lock interface {
+	// Acquire takes the lease, or renews it when holder already has it. False
+	// without an error means another instance holds it.
+	Acquire(holder string, ttl time.Duration) (bool, error)
+	// Release frees the lease if holder has it
+	Release(holder string) error
+}
+
+// Elector keeps the lease while leader and competes for it while standby
+type Elector struct {
+	lock     Lock
+	holder   string
+	ttl      time.Duration
+	onChange func(leader bool)
+
+	mu        sync.Mutex // Guards the fields below, never held during a lease call
+	leader    atomic.Bool
+	started   bool      // Past the first attempt, later elections are takeovers
+	renewed   time.Time // Last successful Acquire while leader
+	holdUntil time.Time // Set by StepDown, no competing before
+	epoch     int       // Bumped by StepDown and Stop, voids a lease call in flight
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewElector creates a stopped elector, standby until Start wins the lease.
+// onChange is called with true when the instance becomes leader and false
+// when it stops being one.
+func NewElector(lock Lock, holder string, ttl time.Duration, onChange func(leader bool)) *Elector {
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	return &Elector{
+		lock:     lock,
+		holder:   holder,
+		ttl:      ttl,
+		onChange: onChange,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start tries the lease right away, so a lone instance answers calls from
+// the start, then keeps renewing or competing in the background
+func (e *Elector) Start() {
+	e.tick()
+	e.mu.Lock()
+	e.started = true
+	e.mu.Unlock()
+
+	go func() {
+		defer alerting.Recover("leader_election")
+
+		for {
+			wait := standbyPoll
+			if e.IsLeader() {
+				wait = e.ttl / 3
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-e.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			e.tick()
+		}
+	}()
+	logger.Info("Leader election started", "holder", e.holder, "ttl", e.ttl, "leader", e.IsLeader())
+}
+
+// IsLeader reports whether this instance holds the lease
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Holder is this instance's name in the lease
+func (e *Elector) Holder() string {
+	return e.holder
+}
+
+// StepDown releases the lease and stays standby for hold, so the other
+// instance takes new calls while the open ones finish here
+func (e *Elector) StepDown(hold time.Duration) error {
+	e.mu.Lock()
+	if !e.IsLeader() {
+		e.mu.Unlock()
+		return fmt.Errorf("not the leader")
+	}
+	e.holdUntil = time.Now().Add(hold)
+	e.epoch++
+	e.setLeader(false)
+	e.mu.Unlock()
+
+	if err := e.lock.Release(e.holder); err != nil {
+		return fmt.Errorf("failed to release the lease: %w", err)
+	}
+	logger.Warn("Stepped down", "hold", hold)
+	return nil
+}
+
+// Stop stops competing and releases the lease, letting the standby take
+// over without waiting for it to expire
+func (e *Elector) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stop)
+
+		e.mu.Lock()
+		e.epoch++
+		wasLeader := e.IsLeader()
+		e.setLeader(false)
+		e.mu.Unlock()
+
+		if !wasLeader {
+			return
+		}
+		if err := e.lock.Release(e.holder); err != nil {
+			logger.Warn("Failed to release the lease, the standby waits for it to expire", "err", err)
+			return
+		}
+		logger.Info("Lease released")
+	})
+}
+
+// tick renews or competes for the lease. The lease call may take a network
+// round trip, so it is made without e.mu: StepDown and Stop don't wait for
+// it, and void its result.
+func (e *Elector) tick() {
+	e.mu.Lock()
+	select {
+	case <-e.stop:
+		e.mu.Unlock()
+		return
+	default:
+	}
+	if time.Now().Before(e.holdUntil) {
+		e.mu.Unlock()
+		return
+	}
+	epoch := e.epoch
+	e.mu.Unlock()
+
+	acquired, err := e.lock.Acquire(e.holder, e.ttl)
+
+	e.mu.Lock()
+	if e.epoch != epoch {
+		e.mu.Unlock()
+		// Stepped down or stopped meanwhile: a lease just taken is given back
+		if err == nil && acquired {
+			if err := e.lock.Release(e.holder); err != nil {
+				logger.Warn("Failed to release the lease taken while stepping down", "err", err)
+			}
+		}
+		return
+	}
+	defer e.mu.Unlock()
+
+	if err != nil {
+		logger.Warn("Lease check failed", "err", err)
+		// Past the TTL the other instance may have taken the lease
+		if e.IsLeader() && time.Since(e.renewed) > e.ttl {
+			logger.Error("Lease not renewed in time, going standby", "since", time.Since(e.renewed).Round(time.Second))
+			e.setLeader(false)
+		}
+		return
+	}
+
+	if !acquired {
+		if e.IsLeader() {
+			logger.Error("Lease taken by another instance, going standby")
+		}
+		e.setLeader(false)
+		return
+	}
+
+	e.renewed = time.Now()
+	if e.IsLeader() {
+		return
+	}
+	if e.started {
+		alerting.Alert(alerting.KeyLeaderTakeover, "🔀 Chuyển instance xử lý cuộc gọi",
+			fmt.Sprintf("%s đã tiếp quản việc nhận cuộc gọi.", e.holder))
+	}
+	e.setLeader(true)
+}
+
+func (e *Elector) setLeader(leader bool) {
+	if e.leader.Swap(leader) == leader {
+		return
+	}
+	logger.Info("Leadership changed", "holder", e.holder, "leader", leader)
+	if e.onChange != nil {
+		e.onChange(leader)
+	}
+}
@@ -0,0 +1,149 @@
+package leader
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/models"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ============================================================
+// LOCKS - A lease file on a volume both instances mount, or a
+// lease kept by the backend
+// ============================================================
+
+// fileSettle is how long a writer waits before checking its write won; two
+// instances taking an expired lease together both write, the last one wins
+const fileSettle = 200 * time.Millisecond
+
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// FileLock keeps the lease in a JSON file. Both instances must see the same
+// file, e.g. on a shared volume, and the hosts' clocks must agree.
+type FileLock struct {
+	Path string
+}
+
+func (l FileLock) Acquire(holder string, ttl time.Duration) (bool, error) {
+	current, err := l.read()
+	if err != nil {
+		return false, err
+	}
+	if current.Holder != "" && current.Holder != holder && time.Now().Before(current.Expires) {
+		return false, nil
+	}
+
+	taking := current.Holder != holder
+	if err := l.write(holder, lease{Holder: holder, Expires: time.Now().Add(ttl)}); err != nil {
+		return false, err
+	}
+	if !taking {
+		return true, nil
+	}
+
+	time.Sleep(fileSettle)
+	if current, err = l.read(); err != nil {
+		return false, err
+	}
+	return current.Holder == holder, nil
+}
+
+func (l FileLock) Release(holder string) error {
+	current, err := l.read()
+	if err != nil {
+		return err
+	}
+	if current.Holder != holder {
+		return nil
+	}
+	if err := os.Remove(l.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lease file: %w", err)
+	}
+	return nil
+}
+
+// read returns the zero lease when there is no file
+func (l FileLock) read() (lease, error) {
+	var current lease
+	data, err := os.ReadFile(l.Path)
+	if os.IsNotExist(err) {
+		return current, nil
+	}
+	if err != nil {
+		return current, fmt.Errorf("failed to read lease file: %w", err)
+	}
+	if err := json.Unmarshal(data, &current); err != nil {
+		// A torn or foreign file is treated as free, the next write replaces it
+		logger.Warn("Ignoring unreadable lease file", "path", l.Path, "err", err)
+		return lease{}, nil
+	}
+	return current, nil
+}
+
+// write renames a temporary file of the holder's own over the lease, so
+// two instances writing at once never share the temporary file
+func (l FileLock) write(holder string, value lease) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	h := fnv.New32a()
+	h.Write([]byte(holder))
+	tmpPath := fmt.Sprintf("%s.%08x.tmp", l.Path, h.Sum32())
+
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write lease file: %w", err)
+	}
+	if err := os.Rename(tmpPath, l.Path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write lease file: %w", err)
+	}
+	return nil
+}
+
+// BackendLock asks the backend for the lease. It answers 2xx when holder
+// has it and 409 when another instance does.
+type BackendLock struct {
+	Client *api.APIClient
+}
+
+type leaseRequest struct {
+	Holder     string `json:"holder"`
+	TTLSeconds int    `json:"ttl_seconds"`
+	Release    bool   `json:"release,omitempty"`
+}
+
+func (l BackendLock) Acquire(holder string, ttl time.Duration) (bool, error) {
+	body, statusCode, err := l.Client.SendRequest(leaseRequest{
+		Holder:     holder,
+		TTLSeconds: int(ttl.Seconds()),
+	}, models.APIBotLeaderLease)
+	if err != nil {
+		return false, err
+	}
+	if statusCode == http.StatusConflict {
+		return false, nil
+	}
+	if !l.Client.IsSuccessStatusCode(statusCode) {
+		return false, api.ParseError(body, statusCode)
+	}
+	return true, nil
+}
+
+func (l BackendLock) Release(holder string) error {
+	body, statusCode, err := l.Client.SendRequest(leaseRequest{Holder: holder, Release: true}, models.APIBotLeaderLease)
+	if err != nil {
+		return err
+	}
+	if !l.Client.IsSuccessStatusCode(statusCode) && statusCode != http.StatusConflict {
+		return api.ParseError(body, statusCode)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ============================================================
+// LOGGING - Leveled, structured logging on top of log/slog
+// ============================================================
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// base is the handler all package loggers write to. Package loggers are
+// created at init time, before Setup runs, so they resolve it per record.
+var base atomic.Pointer[slog.Handler]
+
+// level is the minimum level of every logger, changeable at runtime
+var level slog.LevelVar
+
+// componentLevels override level for one component's logger (see For)
+var (
+	componentMu     sync.RWMutex
+	componentLevels = make(map[string]slog.Level)
+	components      = make(map[string]bool)
+)
+
+func init() {
+	setHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &level, ReplaceAttr: RedactAttr}))
+}
+
+// Setup configures level ("debug", "info", "warn", "error") and format
+// ("text" or "json") for every logger, including the slog default. Output is
+// always passed through RedactAttr.
+func Setup(level, format string) {
+	SetupWriter(os.Stderr, level, format)
+}
+
+// SetupWriter is Setup with a custom output
+func SetupWriter(w io.Writer, levelName, format string) {
+	level.Set(ParseLevel(levelName))
+	opts := &slog.HandlerOptions{Level: &level, ReplaceAttr: RedactAttr}
+
+	var h slog.Handler
+	if strings.EqualFold(format, FormatJSON) {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	setHandler(h)
+}
+
+// SetLevel changes the level of every logger without restarting
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// Level returns the current level
+func Level() slog.Level {
+	return level.Level()
+}
+
+// SetComponentLevel sets the level of one component ("webrtc", "client",
+// "detector"...), overriding the global level
+func SetComponentLevel(component string, l slog.Level) {
+	componentMu.Lock()
+	defer componentMu.Unlock()
+	componentLevels[component] = l
+}
+
+// ResetComponentLevel makes the component follow the global level again
+func ResetComponentLevel(component string) {
+	componentMu.Lock()
+	defer componentMu.Unlock()
+	delete(componentLevels, component)
+}
+
+// ComponentLevels returns the components with their own level
+func ComponentLevels() map[string]slog.Level {
+	componentMu.RLock()
+	defer componentMu.RUnlock()
+	levels := make(map[string]slog.Level, len(componentLevels))
+	for component, l := range componentLevels {
+		levels[component] = l
+	}
+	return levels
+}
+
+// Components returns the names loggers were created for, sorted
+func Components() []string {
+	componentMu.RLock()
+	defer componentMu.RUnlock()
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsComponent reports whether a logger was created for the component
+func IsComponent(component string) bool {
+	componentMu.RLock()
+	defer componentMu.RUnlock()
+	return components[component]
+}
+
+// ParseLevel maps a level name to a slog level, defaulting to info
+func ParseLevel(level string) slog.Level {
+	l, _ := LookupLevel(level)
+	return l
+}
+
+// LookupLevel is ParseLevel reporting whether the name is a known level
+func LookupLevel(level string) (slog.Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "info":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	default:
+		return slog.LevelInfo, false
+	}
+}
+
+// For returns the logger of a package, tagged with component=name. Its
+// level can be set apart with SetComponentLevel.
+func For(component string) *slog.Logger {
+	componentMu.Lock()
+	components[component] = true
+	componentMu.Unlock()
+	return slog.New(&dynamicHandler{component: component}).With("component", component)
+}
+
+// Fatal logs at error level and exits the process
+func Fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+func setHandler(h slog.Handler) {
+	base.Store(&h)
+	slog.SetDefault(slog.New(h))
+}
+
+// dynamicHandler forwards records to the current base handler, so loggers
+// created before Setup still pick up its level and format
+type dynamicHandler struct {
+	component string
+	attrs     []slog.Attr
+	group     string
+}
+
+func (h *dynamicHandler) current() slog.Handler {
+	handler := *base.Load()
+	if len(h.attrs) > 0 {
+		handler = handler.WithAttrs(h.attrs)
+	}
+	if h.group != "" {
+		handler = handler.WithGroup(h.group)
+	}
+	return handler
+}
+
+func (h *dynamicHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.component != "" {
+		componentMu.RLock()
+		override, ok := componentLevels[h.component]
+		componentMu.RUnlock()
+		if ok {
+			return l >= override
+		}
+	}
+	return (*base.Load()).Enabled(ctx, l)
+}
+
+func (h *dynamicHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.current().Handle(ctx, record)
+}
+
+func (h *dynamicHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.group != "" {
+		// Attributes after a group belong to it; freeze the handler chain
+		return h.current().WithAttrs(attrs)
+	}
+	merged := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	merged = append(merged, h.attrs...)
+	merged = append(merged, attrs...)
+	return &dynamicHandler{component: h.component, attrs: merged}
+}
+
+func (h *dynamicHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	if h.group != "" {
+		return h.current().WithGroup(name)
+	}
+	return &dynamicHandler{component: h.component, attrs: h.attrs, group: name}
+}
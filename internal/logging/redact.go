@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ============================================================
+// REDACTION - Secrets, image data and personal data kept out of logs
+// ============================================================
+
+const (
+	Redacted       = "[REDACTED]"
+	maxValueLength = 2048 // Longer values are truncated
+	minBase64Run   = 200  // Shorter runs may be IDs or hashes worth keeping
+)
+
+// secretKeys are removed entirely, in attributes, headers and JSON bodies
+var secretKeys = map[string]bool{
+	"token": true, "access_token": true, "refresh_token": true, "session": true,
+	"secret": true, "secret_key": true, "api_key": true, "bot_token": true,
+	"password": true, "authorization": true, "cookie": true, "signature": true,
+}
+
+// personalKeys keep their first character only. Coordinates, message text
+// (commands, voice transcripts) and usernames are personal data too.
+var personalKeys = map[string]bool{
+	"employee": true, "full_name": true, "fullname": true, "first_name": true, "last_name": true,
+	"display_name": true, "email": true, "phone": true, "address": true, "place": true,
+	"username": true, "text": true, "lat": true, "lon": true, "latitude": true, "longitude": true,
+}
+
+var (
+	dataURIPattern    = regexp.MustCompile(`data:[\w/+.-]+;base64,[A-Za-z0-9+/=]+`)
+	base64Pattern     = regexp.MustCompile(fmt.Sprintf(`[A-Za-z0-9+/]{%d,}={0,2}`, minBase64Run))
+	jwtPattern        = regexp.MustCompile(`eyJ[\w-]+\.[\w-]+\.[\w-]+`)
+	authSchemePattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[\w.~+/=-]+`)
+	jsonSecretPattern = regexp.MustCompile(`(?i)"((?:access_|refresh_|bot_)?token|session|secret(?:_key)?|api_key|password|signature)"\s*:\s*"[^"]*"`)
+	jsonPersonPattern = regexp.MustCompile(`(?i)"((?:full|first|last|display)_?name|email|phone|address)"\s*:\s*"([^"]*)"`)
+	emailPattern      = regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`)
+)
+
+// IsSecretKey reports whether values under key (an attribute, header or JSON
+// field name) must never be logged
+func IsSecretKey(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	return secretKeys[key] || secretKeys[strings.TrimPrefix(key, "x_")]
+}
+
+// RedactAttr is the slog ReplaceAttr hook applied to every handler
+func RedactAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.SourceKey) {
+		return a
+	}
+
+	key := strings.ToLower(a.Key)
+	switch {
+	case IsSecretKey(key):
+		return slog.String(a.Key, Redacted)
+	case personalKeys[key]:
+		return slog.String(a.Key, MaskPersonal(a.Value.String()))
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if s := a.Value.String(); s != "" {
+			if redacted := RedactString(s); redacted != s {
+				return slog.String(a.Key, redacted)
+			}
+		}
+	case slog.KindAny:
+		// Errors often wrap response bodies
+		if err, ok := a.Value.Any().(error); ok && err != nil {
+			if s := err.Error(); RedactString(s) != s {
+				return slog.String(a.Key, RedactString(s))
+			}
+		}
+	}
+	return a
+}
+
+// RedactString removes image data, tokens and personal fields from free text
+// such as request payloads and response bodies
+func RedactString(s string) string {
+	if strings.Contains(s, ";base64,") {
+		s = dataURIPattern.ReplaceAllStringFunc(s, func(m string) string {
+			prefix := m[:strings.Index(m, ",")+1]
+			return fmt.Sprintf("%s[%d bytes]", prefix, len(m)-len(prefix))
+		})
+	}
+	if len(s) >= minBase64Run {
+		s = base64Pattern.ReplaceAllStringFunc(s, func(m string) string {
+			return fmt.Sprintf("[base64 %d bytes]", len(m))
+		})
+	}
+	if strings.Contains(s, "eyJ") {
+		s = jwtPattern.ReplaceAllString(s, Redacted)
+	}
+	s = authSchemePattern.ReplaceAllString(s, "$1 "+Redacted)
+	if strings.Contains(s, `"`) {
+		s = jsonSecretPattern.ReplaceAllString(s, `"$1":"`+Redacted+`"`)
+		s = jsonPersonPattern.ReplaceAllStringFunc(s, func(m string) string {
+			parts := jsonPersonPattern.FindStringSubmatch(m)
+			return fmt.Sprintf(`"%s":"%s"`, parts[1], MaskPersonal(parts[2]))
+		})
+	}
+	if strings.Contains(s, "@") {
+		s = emailPattern.ReplaceAllStringFunc(s, maskEmail)
+	}
+
+	if len(s) > maxValueLength {
+		s = fmt.Sprintf("%s…(%d bytes truncated)", truncateUTF8(s, maxValueLength), len(s)-maxValueLength)
+	}
+	return s
+}
+
+// MaskPersonal keeps the first character of a name, address or similar value
+func MaskPersonal(s string) string {
+	if s == "" {
+		return ""
+	}
+	if strings.Contains(s, "@") {
+		return emailPattern.ReplaceAllStringFunc(s, maskEmail)
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return string(r) + "***"
+}
+
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return Redacted
+	}
+	r, _ := utf8.DecodeRuneInString(email)
+	return string(r) + "***" + email[at:]
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune
+func truncateUTF8(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,232 @@
+package matpool
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/logging"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// MAT POOL - Reuses the C buffers of per-frame Mats instead of
+// allocating and freeing several per frame and call
+// ============================================================
+
+var logger = logging.For("matpool")
+
+// DefaultSize is how many idle Mats the default pool keeps
+const DefaultSize = 64
+
+// Default is shared by the frame path and the detector
+var Default = New(DefaultSize)
+
+// tracking records where scoped Mats were taken, set by SetTracking
+var tracking atomic.Bool
+
+// SetTracking turns on leak reports: Mats a call took and never returned
+// are logged with their call site when the call's scope closes
+func SetTracking(enabled bool) {
+	tracking.Store(enabled)
+}
+
+// Pool keeps idle Mats. sync.Pool is not used: a Mat it drops is never
+// closed and its C memory leaks.
+type Pool struct {
+	mu        sync.Mutex
+	free      []gocv.Mat
+	size      int
+	allocated atomic.Uint64
+	reused    atomic.Uint64
+}
+
+// Stats counts Mats the pool created and handed out again
+type Stats struct {
+	Idle      int    `json:"idle"`
+	Allocated uint64 `json:"allocated"`
+	Reused    uint64 `json:"reused"`
+}
+
+// New creates a pool keeping at most size idle Mats
+func New(size int) *Pool {
+	return &Pool{size: size}
+}
+
+// Get returns an idle Mat or a new empty one, for functions writing into a
+// destination (CvtColor, Resize, CopyTo...), which resize it as needed
+func (p *Pool) Get() gocv.Mat {
+	p.mu.Lock()
+	if n := len(p.free); n > 0 {
+		mat := p.free[n-1]
+		p.free = p.free[:n-1]
+		p.mu.Unlock()
+		p.reused.Add(1)
+		return mat
+	}
+	p.mu.Unlock()
+	p.allocated.Add(1)
+	return gocv.NewMat()
+}
+
+// GetSized returns a Mat of the given size and type, with undefined
+// pixels. Frames of a call all have the same size, so one usually idles.
+func (p *Pool) GetSized(rows, cols int, matType gocv.MatType) gocv.Mat {
+	p.mu.Lock()
+	for i := len(p.free) - 1; i >= 0; i-- {
+		mat := p.free[i]
+		if mat.Rows() == rows && mat.Cols() == cols && mat.Type() == matType {
+			p.free = append(p.free[:i], p.free[i+1:]...)
+			p.mu.Unlock()
+			p.reused.Add(1)
+			return mat
+		}
+	}
+	p.mu.Unlock()
+	p.allocated.Add(1)
+	return gocv.NewMatWithSize(rows, cols, matType)
+}
+
+// Put returns a Mat the caller no longer uses. Regions must be closed, not
+// put: they share their parent's pixels.
+func (p *Pool) Put(mat gocv.Mat) {
+	if mat.Ptr() == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.free) >= p.size {
+		// The oldest idle Mat goes, the newest likely has the current size
+		p.free[0].Close()
+		p.free = append(p.free[:0], p.free[1:]...)
+	}
+	p.free = append(p.free, mat)
+}
+
+// Stats returns the pool's counters
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	idle := len(p.free)
+	p.mu.Unlock()
+	return Stats{Idle: idle, Allocated: p.allocated.Load(), Reused: p.reused.Load()}
+}
+
+// Close frees the idle Mats
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, mat := range p.free {
+		mat.Close()
+	}
+	p.free = nil
+}
+
+// Get takes a Mat from the default pool
+func Get() gocv.Mat {
+	return Default.Get()
+}
+
+// Put returns a Mat to the default pool
+func Put(mat gocv.Mat) {
+	Default.Put(mat)
+}
+
+// ============================================================
+// SCOPE - One call's Mats, to report the ones it never returned
+// ============================================================
+
+// Scope hands out Mats of one owner, e.g. a call. A nil scope allocates
+// and closes Mats without pooling.
+type Scope struct {
+	pool  *Pool
+	owner string
+	mu    sync.Mutex
+	held  []heldMat // Only while tracking
+}
+
+type heldMat struct {
+	mat  gocv.Mat
+	site string
+}
+
+// Scope creates a scope for owner, closed with Close when the owner ends
+func (p *Pool) Scope(owner string) *Scope {
+	return &Scope{pool: p, owner: owner}
+}
+
+// Get is Pool.Get, tracked
+func (s *Scope) Get() gocv.Mat {
+	if s == nil {
+		return gocv.NewMat()
+	}
+	mat := s.pool.Get()
+	s.track(mat)
+	return mat
+}
+
+// GetSized is Pool.GetSized, tracked
+func (s *Scope) GetSized(rows, cols int, matType gocv.MatType) gocv.Mat {
+	if s == nil {
+		return gocv.NewMatWithSize(rows, cols, matType)
+	}
+	mat := s.pool.GetSized(rows, cols, matType)
+	s.track(mat)
+	return mat
+}
+
+// Put returns a Mat taken from the scope
+func (s *Scope) Put(mat gocv.Mat) {
+	if s == nil {
+		mat.Close()
+		return
+	}
+	s.untrack(mat)
+	s.pool.Put(mat)
+}
+
+// Close reports the Mats taken and never returned. They are not closed,
+// whoever kept them may still use them. Returns how many leaked.
+func (s *Scope) Close() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.held) == 0 {
+		return 0
+	}
+
+	sites := make(map[string]int)
+	for _, held := range s.held {
+		sites[held.site]++
+	}
+	logger.Warn("Mats not returned to the pool", "owner", s.owner, "count", len(s.held), "sites", sites)
+	leaked := len(s.held)
+	s.held = nil
+	return leaked
+}
+
+func (s *Scope) track(mat gocv.Mat) {
+	if !tracking.Load() {
+		return
+	}
+	site := "unknown"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		site = fmt.Sprintf("%s:%d", file, line)
+	}
+	s.mu.Lock()
+	s.held = append(s.held, heldMat{mat: mat, site: site})
+	s.mu.Unlock()
+}
+
+func (s *Scope) untrack(mat gocv.Mat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, held := range s.held {
+		if held.mat.Ptr() == mat.Ptr() {
+			s.held = append(s.held[:i], s.held[i+1:]...)
+			return
+		}
+	}
+}
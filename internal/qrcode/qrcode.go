@@ -0,0 +1,452 @@
+// Package qrcode encodes short text as a QR code (byte mode, error
+// correction level M, versions 1-6) and renders it as PNG.
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// ============================================================
+// VERSIONS - Error correction level M
+// ============================================================
+
+type version struct {
+	blocks     int // Error correction blocks, all the same size up to version 6
+	dataPerBlk int // Data codewords per block
+	ecPerBlk   int // Error correction codewords per block
+}
+
+var versions = []version{
+	1: {blocks: 1, dataPerBlk: 16, ecPerBlk: 10},
+	2: {blocks: 1, dataPerBlk: 28, ecPerBlk: 16},
+	3: {blocks: 1, dataPerBlk: 44, ecPerBlk: 26},
+	4: {blocks: 2, dataPerBlk: 32, ecPerBlk: 18},
+	5: {blocks: 2, dataPerBlk: 43, ecPerBlk: 24},
+	6: {blocks: 4, dataPerBlk: 27, ecPerBlk: 16},
+}
+
+const (
+	maxVersion   = 6
+	quietZone    = 4 // Light modules around the symbol
+	eclFormatM   = 0 // Format bits of error correction level M
+	modeByte     = 0x4
+	countBits    = 8 // Character count bits of byte mode, versions 1-9
+	formatMask   = 0x5412
+	formatPoly   = 0x537
+	fieldPoly    = 0x11D
+	finderFactor = 40 // Penalty of a finder-like pattern
+)
+
+// MaxLen is the longest text that fits
+var MaxLen = (versions[maxVersion].blocks*versions[maxVersion].dataPerBlk*8 - 4 - countBits) / 8
+
+// Code is an encoded QR symbol
+type Code struct {
+	size       int
+	modules    [][]bool // [y][x], true = dark
+	isFunction [][]bool
+}
+
+// Encode encodes text in the smallest version that fits
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	ver := 0
+	for v := 1; v <= maxVersion; v++ {
+		if 4+countBits+8*len(data) <= versions[v].blocks*versions[v].dataPerBlk*8 {
+			ver = v
+			break
+		}
+	}
+	if ver == 0 {
+		return nil, fmt.Errorf("text too long for a QR code: %d bytes (max %d)", len(data), MaxLen)
+	}
+
+	codewords := addErrorCorrection(encodeData(data, ver), versions[ver])
+
+	size := 17 + 4*ver
+	c := &Code{size: size, modules: newGrid(size), isFunction: newGrid(size)}
+	c.drawFunctionPatterns(ver)
+	c.drawCodewords(codewords)
+
+	// Keep the mask with the lowest penalty
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask) // XOR again to undo
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+	return c, nil
+}
+
+// Size is the symbol width in modules, without the quiet zone
+func (c *Code) Size() int {
+	return c.size
+}
+
+// Dark reports whether the module at x, y is dark
+func (c *Code) Dark(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.size && y < c.size && c.modules[y][x]
+}
+
+// PNG renders the code with scale pixels per module and a quiet zone
+func (c *Code) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	width := (c.size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for py := 0; py < width; py++ {
+		for px := 0; px < width; px++ {
+			shade := color.Gray{Y: 255}
+			if c.Dark(px/scale-quietZone, py/scale-quietZone) {
+				shade = color.Gray{Y: 0}
+			}
+			img.SetGray(px, py, shade)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func newGrid(size int) [][]bool {
+	grid := make([][]bool, size)
+	for i := range grid {
+		grid[i] = make([]bool, size)
+	}
+	return grid
+}
+
+// ============================================================
+// DATA ENCODING
+// ============================================================
+
+// encodeData builds the byte mode bit stream, padded to the version's data capacity
+func encodeData(data []byte, ver int) []byte {
+	capacity := versions[ver].blocks * versions[ver].dataPerBlk
+
+	var bits bitBuffer
+	bits.append(modeByte, 4)
+	bits.append(len(data), countBits)
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	bits.append(0, min(4, capacity*8-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+
+	out := bits.bytes()
+	for pad := 0; len(out) < capacity; pad++ {
+		out = append(out, [2]byte{0xEC, 0x11}[pad%2])
+	}
+	return out
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
+
+// addErrorCorrection splits data into blocks, appends each block's
+// Reed-Solomon codewords and interleaves the result
+func addErrorCorrection(data []byte, v version) []byte {
+	divisor := rsDivisor(v.ecPerBlk)
+	blocks := make([][]byte, v.blocks)
+	ecc := make([][]byte, v.blocks)
+	for i := range blocks {
+		blocks[i] = data[i*v.dataPerBlk : (i+1)*v.dataPerBlk]
+		ecc[i] = rsRemainder(blocks[i], divisor)
+	}
+
+	out := make([]byte, 0, v.blocks*(v.dataPerBlk+v.ecPerBlk))
+	for i := 0; i < v.dataPerBlk; i++ {
+		for _, block := range blocks {
+			out = append(out, block[i])
+		}
+	}
+	for i := 0; i < v.ecPerBlk; i++ {
+		for _, block := range ecc {
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+// rsDivisor returns the generator polynomial of the given degree, highest
+// coefficient first and the leading 1 omitted
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo the QR field polynomial
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * fieldPoly)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// ============================================================
+// MODULE PLACEMENT
+// ============================================================
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns(ver int) {
+	for i := 0; i < c.size; i++ {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.size-4, 3)
+	c.drawFinder(3, c.size-4)
+
+	// Versions 2-6 have a single alignment pattern
+	if ver > 1 {
+		pos := c.size - 7
+		for dy := -2; dy <= 2; dy++ {
+			for dx := -2; dx <= 2; dx++ {
+				c.set(pos+dx, pos+dy, max(abs(dx), abs(dy)) != 1)
+			}
+		}
+	}
+
+	// Reserve the format areas, drawn for real once the mask is chosen
+	c.drawFormatBits(0)
+}
+
+// drawFinder draws a finder pattern with its separator around center x, y
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= c.size || yy >= c.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.set(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawFormatBits writes both copies of the format information and the dark module
+func (c *Code) drawFormatBits(mask int) {
+	data := eclFormatM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * formatPoly)
+	}
+	bits := (data<<10 | rem) ^ formatMask
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.set(c.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.size-15+i, bit(i))
+	}
+	c.set(8, c.size-8, true)
+}
+
+// drawCodewords fills the non-function modules in the zigzag order, leaving
+// remainder bits light
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if upward {
+					y = c.size - 1 - vert
+				}
+				if c.isFunction[y][x] || i >= len(data)*8 {
+					continue
+				}
+				c.modules[y][x] = (data[i/8]>>(7-i%8))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask XORs the data modules with the mask pattern
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.isFunction[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			c.modules[y][x] = c.modules[y][x] != invert
+		}
+	}
+}
+
+// ============================================================
+// MASK PENALTY
+// ============================================================
+
+// penalty scores how hard the symbol is to scan, lower is better
+func (c *Code) penalty() int {
+	result := 0
+	for i := 0; i < c.size; i++ {
+		row := make([]bool, c.size)
+		col := make([]bool, c.size)
+		for j := 0; j < c.size; j++ {
+			row[j] = c.modules[i][j]
+			col[j] = c.modules[j][i]
+		}
+		result += linePenalty(row) + linePenalty(col)
+	}
+
+	dark := 0
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.size && y+1 < c.size {
+				v := c.modules[y][x]
+				if c.modules[y][x+1] == v && c.modules[y+1][x] == v && c.modules[y+1][x+1] == v {
+					result += 3
+				}
+			}
+		}
+	}
+
+	total := c.size * c.size
+	result += abs(dark*20-total*10) / total * 10
+	return result
+}
+
+var finderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// linePenalty scores runs of one color and finder-like patterns in a line
+func linePenalty(line []bool) int {
+	result := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			result += run - 2
+		}
+		run = 1
+	}
+
+	for i := 0; i+len(finderLike[0]) <= len(line); i++ {
+		for _, pattern := range finderLike {
+			matched := true
+			for j, v := range pattern {
+				if line[i+j] != v {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				result += finderFactor
+			}
+		}
+	}
+	return result
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
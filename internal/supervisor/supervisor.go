@@ -0,0 +1,151 @@
+package supervisor
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/logging"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// SUPERVISOR - Invariants checked periodically, failed subsystems
+// re-initialized, the process restarted when that does not help
+// ============================================================
+
+var logger = logging.For("supervisor")
+
+const (
+	DefaultInterval      = 30 * time.Second
+	DefaultEscalateAfter = 3 // Consecutive failures before alerting
+)
+
+// Check is one invariant of a running bot
+type Check struct {
+	Name  string
+	Probe func() error // Nil error = healthy
+	Heal  func() error // Re-initializes the subsystem, optional
+
+	// Consecutive failures before the process is restarted (0 = never, for
+	// failures a restart cannot fix)
+	RestartAfter int
+}
+
+type checkState struct {
+	Check
+	failures int
+	alerted  bool
+}
+
+// Supervisor runs the checks every interval until Stop
+type Supervisor struct {
+	interval      time.Duration
+	escalateAfter int
+	checks        []*checkState
+	restart       func(reason string)
+	stop          chan struct{}
+	once          sync.Once
+}
+
+// New creates a stopped supervisor. restart is called once a check reaches
+// its RestartAfter; it should shut the bot down cleanly and exit so the
+// process manager starts it again.
+func New(interval time.Duration, restart func(reason string)) *Supervisor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Supervisor{
+		interval:      interval,
+		escalateAfter: DefaultEscalateAfter,
+		restart:       restart,
+		stop:          make(chan struct{}),
+	}
+}
+
+// Add registers a check. Not safe once started.
+func (s *Supervisor) Add(check Check) {
+	s.checks = append(s.checks, &checkState{Check: check})
+}
+
+// Start runs the checks in the background
+func (s *Supervisor) Start() {
+	go func() {
+		defer alerting.Recover("supervisor")
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.runChecks()
+			}
+		}
+	}()
+	logger.Info("Supervisor started", "checks", len(s.checks), "interval", s.interval)
+}
+
+// Stop stops checking
+func (s *Supervisor) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *Supervisor) runChecks() {
+	for _, check := range s.checks {
+		if s.runCheck(check) {
+			return
+		}
+	}
+}
+
+// runCheck probes, heals on failure and escalates. Returns true when the
+// process is being restarted.
+func (s *Supervisor) runCheck(check *checkState) bool {
+	err := check.Probe()
+	if err == nil {
+		if check.failures > 0 {
+			logger.Info("Subsystem recovered", "check", check.Name, "failures", check.failures)
+			if check.alerted {
+				alerting.Alert(alerting.KeySupervisor+":"+check.Name+":recovered", "✅ Đã tự khôi phục",
+					fmt.Sprintf("%s hoạt động trở lại sau %d lần kiểm tra lỗi.", check.Name, check.failures))
+			}
+		}
+		check.failures = 0
+		check.alerted = false
+		return false
+	}
+
+	check.failures++
+	logger.Warn("Invariant failed", "check", check.Name, "failures", check.failures, "err", err)
+
+	if check.Heal != nil {
+		if healErr := check.Heal(); healErr != nil {
+			logger.Warn("Re-initialization failed", "check", check.Name, "err", healErr)
+		} else if err = check.Probe(); err == nil {
+			logger.Info("Subsystem re-initialized", "check", check.Name)
+			check.failures = 0
+			check.alerted = false
+			return false
+		}
+	}
+
+	if check.failures >= s.escalateAfter && !check.alerted {
+		check.alerted = true
+		alerting.Alert(alerting.KeySupervisor+":"+check.Name, "🚨 Lỗi không tự khôi phục được",
+			fmt.Sprintf("%s lỗi %d lần liên tiếp: %v", check.Name, check.failures, err))
+	}
+
+	if check.RestartAfter > 0 && check.failures >= check.RestartAfter && s.restart != nil {
+		reason := fmt.Sprintf("%s failed %d times: %v", check.Name, check.failures, err)
+		logger.Error("Restarting the bot", "check", check.Name, "reason", reason)
+		alerting.Alert(alerting.KeySupervisor+":restart", "🔄 Khởi động lại bot",
+			fmt.Sprintf("%s lỗi %d lần liên tiếp, bot sẽ tự khởi động lại.", check.Name, check.failures))
+		s.Stop()
+		s.restart(reason)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// OTLP EXPORTER - OTLP/HTTP with the JSON encoding
+// ============================================================
+
+const (
+	otlpBatchSize     = 256
+	otlpQueueSize     = 2048
+	otlpFlushInterval = 5 * time.Second
+	otlpTimeout       = 10 * time.Second
+)
+
+// OTLP span kind and status codes
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+// OTLPExporter batches spans and posts them to an OpenTelemetry collector.
+// Spans are dropped when the queue is full rather than blocking a call.
+type OTLPExporter struct {
+	url     string
+	service string
+	client  *http.Client
+
+	spans     chan *Span
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewOTLPExporter creates an exporter posting to url (e.g.
+// "http://collector:4318/v1/traces") and starts its flush loop
+func NewOTLPExporter(url, serviceName string) *OTLPExporter {
+	e := &OTLPExporter{
+		url:     url,
+		service: serviceName,
+		client:  &http.Client{Timeout: otlpTimeout},
+		spans:   make(chan *Span, otlpQueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// ExportSpan queues an ended span
+func (e *OTLPExporter) ExportSpan(s *Span) {
+	select {
+	case e.spans <- s:
+	default:
+		logger.Debug("Trace queue full, span dropped", "span", s.name)
+	}
+}
+
+// Shutdown flushes the queued spans and stops the flush loop
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+
+	select {
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, otlpBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			logger.Warn("Failed to export spans", "count", len(batch), "err", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case s := <-e.spans:
+			batch = append(batch, s)
+			if len(batch) >= otlpBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.done:
+			for {
+				select {
+				case s := <-e.spans:
+					batch = append(batch, s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *OTLPExporter) send(spans []*Span) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ============================================================
+// OTLP JSON ENCODING
+// ============================================================
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+func (e *OTLPExporter) encode(spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID(),
+			SpanID:            s.SpanID(),
+			Name:              s.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if s.hasParent() {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		for _, a := range s.attrs {
+			span.Attributes = append(span.Attributes, otlpAttr(a))
+		}
+		if s.err != nil {
+			span.Status = otlpStatus{Code: otlpStatusError, Message: s.err.Error()}
+		}
+		encoded = append(encoded, span)
+	}
+
+	service := e.service
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{Key: "service.name", Value: otlpValue{StringValue: &service}},
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: DefaultServiceName},
+			Spans: encoded,
+		}},
+	}}}
+}
+
+func otlpAttr(a slog.Attr) otlpKeyValue {
+	v := a.Value.Resolve()
+
+	var value otlpValue
+	switch v.Kind() {
+	case slog.KindInt64:
+		s := strconv.FormatInt(v.Int64(), 10)
+		value.IntValue = &s
+	case slog.KindUint64:
+		s := strconv.FormatUint(v.Uint64(), 10)
+		value.IntValue = &s
+	case slog.KindFloat64:
+		f := v.Float64()
+		value.DoubleValue = &f
+	case slog.KindBool:
+		b := v.Bool()
+		value.BoolValue = &b
+	default:
+		s := v.String()
+		value.StringValue = &s
+	}
+	return otlpKeyValue{Key: a.Key, Value: value}
+}
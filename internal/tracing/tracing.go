@@ -0,0 +1,235 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"mezon-checkin-bot/internal/logging"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================
+// TRACING - Spans across the check-in call lifecycle
+// ============================================================
+
+var logger = logging.For("tracing")
+
+// DefaultServiceName is reported as service.name when none is configured
+const DefaultServiceName = "mezon-checkin-bot"
+
+// Span is one timed stage of a check-in. Spans started from a context that
+// carries a span become its children and share its trace ID. All methods are
+// safe on a nil span.
+type Span struct {
+	name     string
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	start    time.Time
+
+	mu    sync.Mutex
+	end   time.Time
+	attrs []slog.Attr
+	err   error
+	ended bool
+}
+
+type spanKey struct{}
+
+// Start starts a span named name. args are slog-style key/value pairs.
+func Start(ctx context.Context, name string, args ...any) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	s := &Span{name: name, start: time.Now(), attrs: toAttrs(args)}
+	if parent := FromContext(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+
+	return ContextWithSpan(ctx, s), s
+}
+
+// FromContext returns the current span of ctx, or nil
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithSpan returns a copy of ctx carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SetAttributes adds slog-style key/value pairs to the span
+func (s *Span) SetAttributes(args ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.attrs = append(s.attrs, toAttrs(args)...)
+	}
+}
+
+// RecordError marks the span as failed. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.err = err
+	}
+}
+
+// End ends the span and hands it to the exporter. Only the first call counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	currentExporter().ExportSpan(s)
+}
+
+// TraceID returns the hex trace ID ("" for a nil span)
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SpanID returns the hex span ID ("" for a nil span)
+func (s *Span) SpanID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.spanID[:])
+}
+
+// Duration returns how long the span took, or has taken so far
+func (s *Span) Duration() time.Duration {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return s.end.Sub(s.start)
+	}
+	return time.Since(s.start)
+}
+
+func (s *Span) hasParent() bool {
+	return s.parentID != [8]byte{}
+}
+
+// toAttrs converts key/value pairs with the same rules as slog
+func toAttrs(args []any) []slog.Attr {
+	if len(args) == 0 {
+		return nil
+	}
+	var r slog.Record
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+// ============================================================
+// EXPORTERS
+// ============================================================
+
+// Exporter receives every ended span
+type Exporter interface {
+	ExportSpan(s *Span)
+	Shutdown(ctx context.Context) error
+}
+
+var exporter atomic.Pointer[Exporter]
+
+func init() {
+	SetExporter(logExporter{})
+}
+
+// Setup exports spans over OTLP/HTTP to endpoint (the collector base URL, as in
+// OTEL_EXPORTER_OTLP_ENDPOINT). Without an endpoint spans are logged at debug
+// level.
+func Setup(endpoint, serviceName string) {
+	if endpoint == "" {
+		SetExporter(logExporter{})
+		return
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+
+	url := strings.TrimRight(endpoint, "/") + "/v1/traces"
+	SetExporter(NewOTLPExporter(url, serviceName))
+	logger.Info("Exporting traces", "url", url, "service", serviceName)
+}
+
+// SetExporter replaces the exporter. The previous one is not shut down.
+func SetExporter(e Exporter) {
+	exporter.Store(&e)
+}
+
+// Shutdown flushes and stops the current exporter
+func Shutdown(ctx context.Context) error {
+	return currentExporter().Shutdown(ctx)
+}
+
+func currentExporter() Exporter {
+	return *exporter.Load()
+}
+
+// logExporter writes ended spans to the debug log
+type logExporter struct{}
+
+func (logExporter) ExportSpan(s *Span) {
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+
+	args := make([]any, 0, 6+len(s.attrs))
+	args = append(args, "span", s.name, "trace_id", s.TraceID(), "duration", s.end.Sub(s.start))
+	if s.err != nil {
+		args = append(args, "err", s.err)
+	}
+	for _, a := range s.attrs {
+		args = append(args, a)
+	}
+	logger.Debug("Span ended", args...)
+}
+
+func (logExporter) Shutdown(context.Context) error {
+	return nil
+}
@@ -0,0 +1,252 @@
+package webrtc
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/audit"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/logging"
+	"mezon-checkin-bot/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// ADMIN CHANNEL - Operations commands typed in the admin channel
+// ============================================================
+
+const adminChannelHelp = "Lệnh vận hành:\n" +
+	"!status - trạng thái bot\n" +
+	"!connections - các cuộc gọi đang diễn ra\n" +
+	"!endcall <user id> - kết thúc cuộc gọi\n" +
+	"!unblock <user id> - gỡ giới hạn số cuộc gọi và khóa do nhận diện sai của người dùng\n" +
+	"!reload-offices - tải lại danh sách văn phòng\n" +
+	"!drain [off] - ngừng nhận cuộc gọi mới, các cuộc gọi đang diễn ra vẫn hoàn tất\n" +
+	"!maintenance [on [lời nhắn]|off] - bảo trì: trả lời cuộc gọi bằng thông báo rồi cúp máy\n" +
+	"!handoff - chuyển việc nhận cuộc gọi sang instance dự phòng, các cuộc gọi đang diễn ra vẫn hoàn tất\n" +
+	"!loglevel [thành phần] <debug|info|warn|error|reset> - đổi mức log, chung hoặc của một thành phần (webrtc, client, detector...)\n" +
+	"!verbose <on|off> - ghi log giao thức Mezon chi tiết"
+
+// SetupAdminChannel routes the commands typed in the client's admin channel
+// (see MezonClient.SetAdminChannel). Only admins may run them.
+func (w *WebRTCManager) SetupAdminChannel() {
+	w.onActive("admin_channel_command", func(data interface{}) {
+		w.handleAdminChannelCommand(data)
+	})
+}
+
+func (w *WebRTCManager) handleAdminChannelCommand(data interface{}) {
+	eventMap, ok := data.(map[string]interface{})
+	if !ok {
+		logger.Error("Invalid admin channel command data type")
+		return
+	}
+
+	userID, _ := eventMap["user_id"].(int64)
+	clanID, _ := eventMap["clan_id"].(int64)
+	channelID, _ := eventMap["channel_id"].(int64)
+	command, _ := eventMap["command"].(string)
+	args, _ := eventMap["args"].([]string)
+
+	handlers := map[string]func([]string) models.ChannelMessageContent{
+		"help":           func([]string) models.ChannelMessageContent { return client.BuildSimpleTextMessage(adminChannelHelp) },
+		"status":         w.handleStatusCommand,
+		"connections":    w.handleConnectionsCommand,
+		"endcall":        w.handleEndCallCommand,
+		"unblock":        w.handleUnblockCommand,
+		"reload-offices": w.handleReloadOfficesCommand,
+		"drain":          w.handleDrainCommand,
+		"maintenance":    w.handleMaintenanceCommand,
+		"handoff":        w.handleHandoffCommand,
+		"loglevel":       w.handleLogLevelCommand,
+		"verbose":        w.handleVerboseCommand,
+	}
+	handler, exists := handlers[command]
+	if !exists {
+		return
+	}
+
+	commandLine := strings.TrimSpace("!" + command + " " + strings.Join(args, " "))
+	reply := func(content models.ChannelMessageContent) {
+		if err := w.dmManager.SendChannelMessage(clanID, channelID, content); err != nil {
+			logger.Error("Failed to send admin channel reply", "channel_id", channelID, "err", err)
+		}
+	}
+	if !w.isAdmin(userID) {
+		logger.Warn("User is not allowed to run command", "user_id", userID, "command", command)
+		w.audit(userID, audit.ActionAdminDenied, "", commandLine)
+		reply(client.BuildErrorMessage("⛔ Không có quyền", "Lệnh này chỉ dành cho quản trị viên."))
+		return
+	}
+	w.audit(userID, audit.ActionAdminCommand, "", commandLine)
+	reply(handler(args))
+}
+
+func (w *WebRTCManager) handleStatusCommand(args []string) models.ChannelMessageContent {
+	connected := "✅ đã kết nối"
+	if !w.client.IsConnected() {
+		connected = "❌ mất kết nối"
+	}
+	accepting := "✅ đang nhận cuộc gọi"
+	switch {
+	case w.Draining():
+		accepting = "⏳ đang drain, không nhận cuộc gọi mới"
+	case w.Maintenance():
+		accepting = "🚧 bảo trì, không nhận cuộc gọi mới"
+	case !w.backendHealthy():
+		accepting = "⚠️ backend lỗi, không nhận cuộc gọi mới"
+	}
+
+	fields := []models.EmbedField{
+		{Name: "Mezon", Value: connected},
+		{Name: "Cuộc gọi", Value: accepting},
+		{Name: "Đang diễn ra", Value: strconv.Itoa(w.ActiveCalls())},
+		{Name: "Mức log", Value: strings.ToLower(logging.Level().String())},
+	}
+	w.mu.RLock()
+	queue := w.queue
+	w.mu.RUnlock()
+	if queue != nil {
+		fields = append(fields, models.EmbedField{Name: "Hàng đợi gửi lại", Value: strconv.Itoa(queue.Len())})
+	}
+	if holder := w.LeaderHolder(); holder != "" {
+		fields = append(fields, models.EmbedField{Name: "Instance", Value: holder})
+	}
+	return client.BuildFieldsMessage("📊 Trạng thái bot", "", fields)
+}
+
+func (w *WebRTCManager) handleConnectionsCommand(args []string) models.ChannelMessageContent {
+	connections := w.Connections()
+	if len(connections) == 0 {
+		return client.BuildSimpleTextMessage("Không có cuộc gọi nào.")
+	}
+
+	var b strings.Builder
+	for _, conn := range connections {
+		fmt.Fprintf(&b, "📞 %s - %s, %s, %s trước, mất %.1f%% gói\n",
+			w.userLabel(conn.UserID), conn.Stage, conn.State,
+			time.Since(conn.Since).Round(time.Second), conn.Quality.LossPercent)
+	}
+	return client.BuildSimpleTextMessage(strings.TrimRight(b.String(), "\n"))
+}
+
+func (w *WebRTCManager) handleEndCallCommand(args []string) models.ChannelMessageContent {
+	if len(args) < 1 {
+		return client.BuildSimpleTextMessage("Cách dùng: !endcall <user id>")
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return client.BuildErrorMessage("❌ User ID không hợp lệ", args[0])
+	}
+	if err := w.EndCall(userID); err != nil {
+		return client.BuildErrorMessage("❌ Không kết thúc được cuộc gọi", err.Error())
+	}
+	return client.BuildSuccessMessage("✅ Đã kết thúc cuộc gọi", w.userLabel(userID))
+}
+
+func (w *WebRTCManager) handleUnblockCommand(args []string) models.ChannelMessageContent {
+	if len(args) < 1 {
+		return client.BuildSimpleTextMessage("Cách dùng: !unblock <user id>")
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return client.BuildErrorMessage("❌ User ID không hợp lệ", args[0])
+	}
+	if err := w.ResetCallLimits(userID); err != nil {
+		return client.BuildErrorMessage("❌ Không gỡ được giới hạn", err.Error())
+	}
+	return client.BuildSuccessMessage("✅ Đã gỡ giới hạn cuộc gọi", w.userLabel(userID))
+}
+
+func (w *WebRTCManager) handleReloadOfficesCommand(args []string) models.ChannelMessageContent {
+	count, err := w.ReloadOffices()
+	if err != nil {
+		return client.BuildErrorMessage("❌ Tải lại thất bại", err.Error())
+	}
+	return client.BuildSuccessMessage("✅ Đã tải lại", fmt.Sprintf("%d văn phòng đang bật", count))
+}
+
+func (w *WebRTCManager) handleDrainCommand(args []string) models.ChannelMessageContent {
+	if len(args) > 0 && strings.EqualFold(args[0], "off") {
+		w.SetDraining(false)
+		return client.BuildSuccessMessage("✅ Đã nhận cuộc gọi trở lại", "")
+	}
+	w.SetDraining(true)
+	return client.BuildSuccessMessage("🚧 Ngừng nhận cuộc gọi mới",
+		fmt.Sprintf("%d cuộc gọi đang diễn ra sẽ được hoàn tất. Dùng !drain off để nhận lại.", w.ActiveCalls()))
+}
+
+func (w *WebRTCManager) handleMaintenanceCommand(args []string) models.ChannelMessageContent {
+	if len(args) == 0 {
+		if !w.Maintenance() {
+			return client.BuildSimpleTextMessage("Bot đang hoạt động bình thường.")
+		}
+		return client.BuildSimpleTextMessage("🚧 Đang bảo trì. Lời nhắn: " + w.maintenanceNotice(0))
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on":
+		w.SetMaintenanceMessage(strings.Join(args[1:], " "))
+		w.SetMaintenance(true)
+		return client.BuildSuccessMessage("🚧 Đã bật bảo trì",
+			"Người gọi sẽ nhận lời nhắn: "+w.maintenanceNotice(0))
+	case "off":
+		w.SetMaintenance(false)
+		return client.BuildSuccessMessage("✅ Đã tắt bảo trì", "")
+	default:
+		return client.BuildSimpleTextMessage("Cách dùng: !maintenance [on [lời nhắn]|off]")
+	}
+}
+
+func (w *WebRTCManager) handleHandoffCommand(args []string) models.ChannelMessageContent {
+	if err := w.Handoff(); err != nil {
+		return client.BuildErrorMessage("❌ Không chuyển được", err.Error())
+	}
+	return client.BuildSuccessMessage("🔀 Đã nhường cho instance dự phòng",
+		fmt.Sprintf("%d cuộc gọi đang diễn ra sẽ được hoàn tất trên %s.", w.ActiveCalls(), w.LeaderHolder()))
+}
+
+func (w *WebRTCManager) handleLogLevelCommand(args []string) models.ChannelMessageContent {
+	if len(args) == 0 {
+		levels := w.LogLevels()
+		fields := []models.EmbedField{{Name: "Chung", Value: levels.Level}}
+		components := make([]string, 0, len(levels.Components))
+		for component := range levels.Components {
+			components = append(components, component)
+		}
+		sort.Strings(components)
+		for _, component := range components {
+			fields = append(fields, models.EmbedField{Name: component, Value: levels.Components[component]})
+		}
+		return client.BuildFieldsMessage("📝 Mức log", "", fields)
+	}
+
+	component, level := "", args[0]
+	if len(args) > 1 {
+		component, level = strings.ToLower(args[0]), args[1]
+	}
+	if err := w.SetLogLevel(component, level); err != nil {
+		return client.BuildErrorMessage("❌ Không đổi được mức log", err.Error())
+	}
+	if component == "" {
+		component = "chung"
+	}
+	return client.BuildSuccessMessage("✅ Đã đổi mức log", component+": "+strings.ToLower(level))
+}
+
+func (w *WebRTCManager) handleVerboseCommand(args []string) models.ChannelMessageContent {
+	if len(args) < 1 {
+		return client.BuildSimpleTextMessage(fmt.Sprintf("Verbose: %v. Cách dùng: !verbose <on|off>", w.client.GetVerbose()))
+	}
+	switch strings.ToLower(args[0]) {
+	case "on":
+		w.client.SetVerbose(true)
+	case "off":
+		w.client.SetVerbose(false)
+	default:
+		return client.BuildSimpleTextMessage("Cách dùng: !verbose <on|off>")
+	}
+	logger.Warn("Verbose protocol logging changed", "verbose", w.client.GetVerbose())
+	return client.BuildSuccessMessage("✅ Verbose: "+strings.ToLower(args[0]), "")
+}
@@ -0,0 +1,265 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/i18n"
+	"mezon-checkin-bot/internal/utils"
+	"mezon-checkin-bot/models"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// ANNOUNCEMENTS - Check-ins posted to team channels
+// ============================================================
+
+// AnnouncementChannel is a clan channel that receives check-in announcements
+type AnnouncementChannel struct {
+	ClanID    int64
+	ChannelID int64
+}
+
+// AnnouncementConfig posts a short "checked in" embed after every approved
+// check-in to the channels of the clans the user belongs to, per the
+// employee profile's clan_ids. Users without a known clan and users who
+// opted out with !announce off are not announced.
+type AnnouncementConfig struct {
+	Channels   []AnnouncementChannel // Keyed by clan: "clanID:channelID"
+	OptOutPath string                // Users who opted out, saved as JSON
+}
+
+// announcementOptOuts is the opt-out file format
+type announcementOptOuts struct {
+	Users []int64 `json:"users"`
+}
+
+const announceUsage = "Cách dùng:\n" +
+	"!announce off - không thông báo check-in của bạn lên kênh chung\n" +
+	"!announce on - thông báo lại\n" +
+	"!announce - xem trạng thái"
+
+// ParseAnnouncementChannels parses "clanID:channelID" pairs separated by commas
+func ParseAnnouncementChannels(value string) ([]AnnouncementChannel, error) {
+	var channels []AnnouncementChannel
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		clan, channel, found := strings.Cut(part, ":")
+		clanID, clanErr := strconv.ParseInt(clan, 10, 64)
+		channelID, channelErr := strconv.ParseInt(channel, 10, 64)
+		if !found || clanErr != nil || channelErr != nil {
+			return nil, fmt.Errorf("invalid announcement channel %q (use clanID:channelID)", part)
+		}
+		channels = append(channels, AnnouncementChannel{ClanID: clanID, ChannelID: channelID})
+	}
+	return channels, nil
+}
+
+// SetAnnouncementConfig enables check-in announcements and loads the opt-outs
+func (w *WebRTCManager) SetAnnouncementConfig(cfg AnnouncementConfig) error {
+	optOuts, err := loadAnnounceOptOuts(cfg.OptOutPath)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.announcements = cfg
+	w.announceOptOuts = optOuts
+	w.mu.Unlock()
+
+	logger.Info("Check-in announcements configured", "channels", len(cfg.Channels), "opted_out", len(optOuts))
+	return nil
+}
+
+// reloadAnnounceOptOuts reads the opt-outs again, e.g. changed by the other
+// instance while it was leading
+func (w *WebRTCManager) reloadAnnounceOptOuts() error {
+	w.mu.RLock()
+	path := w.announcements.OptOutPath
+	w.mu.RUnlock()
+	if path == "" {
+		return nil
+	}
+
+	optOuts, err := loadAnnounceOptOuts(path)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.announceOptOuts = optOuts
+	w.mu.Unlock()
+	return nil
+}
+
+// loadAnnounceOptOuts reads the opt-out file ("" or missing = nobody)
+func loadAnnounceOptOuts(path string) (map[int64]bool, error) {
+	optOuts := make(map[int64]bool)
+	if path == "" {
+		return optOuts, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read announcement opt-outs: %w", err)
+	default:
+		var file announcementOptOuts
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse announcement opt-outs: %w", err)
+		}
+		for _, userID := range file.Users {
+			optOuts[userID] = true
+		}
+	}
+	return optOuts, nil
+}
+
+// announceCheckin posts the check-in to the announcement channels
+func (w *WebRTCManager) announceCheckin(userID int64, event CheckinEvent, at time.Time) {
+	w.mu.RLock()
+	configured := w.announcements.Channels
+	optedOut := w.announceOptOuts[userID]
+	w.mu.RUnlock()
+	if len(configured) == 0 || optedOut || w.dmManager == nil {
+		return
+	}
+
+	channels := w.userAnnouncementChannels(userID, configured)
+	if len(channels) == 0 {
+		logger.Debug("No announcement channel in the user's clans", "user_id", userID)
+		return
+	}
+
+	zone := Office{}.Zone()
+	if w.locationConfig != nil {
+		zone = w.locationConfig.OfficeZone(event.OfficeID)
+	}
+	locale := w.messages.DefaultLocale()
+	vars := i18n.Vars{"name": w.announceName(userID), "time": at.In(zone).Format("15:04")}
+	key := "announce.checked_in"
+	switch {
+	case event.WFH:
+		key = "announce.checked_in_wfh"
+	case event.OfficeID != "":
+		key = "announce.checked_in_at"
+		vars["office"] = event.OfficeID
+	}
+	content := client.BuildAnnouncementMessage(w.messages.Text(locale, key, vars))
+
+	go func() {
+		for _, channel := range channels {
+			if err := w.dmManager.SendChannelMessage(channel.ClanID, channel.ChannelID, content); err != nil {
+				logger.Warn("Failed to announce check-in", "user_id", userID, "channel_id", channel.ChannelID, "err", err)
+			}
+		}
+	}()
+}
+
+// userAnnouncementChannels keeps the channels of the user's clans
+func (w *WebRTCManager) userAnnouncementChannels(userID int64, channels []AnnouncementChannel) []AnnouncementChannel {
+	profile, ok := w.Profile(userID)
+	if !ok {
+		return nil
+	}
+	var matched []AnnouncementChannel
+	for _, channel := range channels {
+		if slices.Contains(profile.ClanIDs, channel.ClanID) {
+			matched = append(matched, channel)
+		}
+	}
+	return matched
+}
+
+// announceName is the user's name without the employee ID, which is not
+// for public channels
+func (w *WebRTCManager) announceName(userID int64) string {
+	if profile, ok := w.Profile(userID); ok && profile.Name != "" {
+		return profile.Name
+	}
+	w.mu.RLock()
+	name := w.shifts[userID].name
+	w.mu.RUnlock()
+	if name != "" {
+		return name
+	}
+	return strconv.FormatInt(userID, 10)
+}
+
+// handleAnnounceCommand lets users opt out of announcements
+func (w *WebRTCManager) handleAnnounceCommand(userID int64, args []string) models.ChannelMessageContent {
+	w.mu.RLock()
+	enabled := len(w.announcements.Channels) > 0
+	optedOut := w.announceOptOuts[userID]
+	w.mu.RUnlock()
+	if !enabled {
+		return client.BuildErrorMessage("❌ Thông báo check-in chưa được bật", "Vui lòng liên hệ quản trị viên.")
+	}
+
+	if len(args) == 0 {
+		status := "Check-in của bạn đang được thông báo lên kênh chung."
+		if optedOut {
+			status = "Check-in của bạn không được thông báo lên kênh chung."
+		}
+		return client.BuildSimpleTextMessage(status + "\n\n" + announceUsage)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "off":
+		if err := w.setAnnounceOptOut(userID, true); err != nil {
+			logger.Error("Failed to save announcement opt-out", "user_id", userID, "err", err)
+			return client.BuildErrorMessage("❌ Không thể lưu lựa chọn", "Vui lòng thử lại sau.")
+		}
+		return client.BuildSuccessMessage("🔕 Đã tắt thông báo", "Check-in của bạn sẽ không được thông báo lên kênh chung.")
+	case "on":
+		if err := w.setAnnounceOptOut(userID, false); err != nil {
+			logger.Error("Failed to save announcement opt-out", "user_id", userID, "err", err)
+			return client.BuildErrorMessage("❌ Không thể lưu lựa chọn", "Vui lòng thử lại sau.")
+		}
+		return client.BuildSuccessMessage("🔔 Đã bật thông báo", "Check-in của bạn sẽ được thông báo lên kênh chung.")
+	default:
+		return client.BuildSimpleTextMessage(announceUsage)
+	}
+}
+
+// setAnnounceOptOut updates and saves the user's choice
+func (w *WebRTCManager) setAnnounceOptOut(userID int64, optOut bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if optOut {
+		w.announceOptOuts[userID] = true
+	} else {
+		delete(w.announceOptOuts, userID)
+	}
+
+	path := w.announcements.OptOutPath
+	if path == "" {
+		return nil
+	}
+	file := announcementOptOuts{Users: make([]int64, 0, len(w.announceOptOuts))}
+	for id := range w.announceOptOuts {
+		file.Users = append(file.Users, id)
+	}
+	sort.Slice(file.Users, func(i, j int) bool { return file.Users[i] < file.Users[j] })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal opt-outs: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write opt-outs: %w", err)
+	}
+	return nil
+}
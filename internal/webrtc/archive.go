@@ -0,0 +1,482 @@
+package webrtc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/encryption"
+	"mezon-checkin-bot/internal/utils"
+	"mezon-checkin-bot/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// IMAGE ARCHIVE - Encrypted face crops for audits
+// ============================================================
+
+// Archived crop kinds
+const (
+	ArchiveAccepted = "accepted" // Crop that got the user recognized
+	ArchiveRejected = "rejected" // Crop submitted without a match
+)
+
+const (
+	defaultRetention      = 30 * 24 * time.Hour
+	archiveSweepInterval  = time.Hour
+	archiveDayLayout      = "2006-01-02"
+	archiveFileExt        = ".enc"
+	maxArchiveImagesShown = 5
+)
+
+// ArchiveConfig enables the image archive
+type ArchiveConfig struct {
+	Dir          string
+	Keys         *encryption.Keyring
+	Retention    time.Duration // Crops older than this are deleted (default 30 days)
+	KeepRejected bool          // Also keep crops that were not recognized
+}
+
+// ImageArchive stores face crops encrypted at rest, one directory per day:
+// <dir>/<YYYY-MM-DD>/<user id>_<call id>_<kind>_<unix ms>.enc
+type ImageArchive struct {
+	cfg ArchiveConfig
+	mu  sync.Mutex // Serializes purges with writes
+}
+
+// ArchivedImage is one decrypted crop
+type ArchivedImage struct {
+	UserID int64
+	CallID string
+	Kind   string
+	At     time.Time
+	JPEG   []byte
+}
+
+// ArchiveStats summarizes the archive
+type ArchiveStats struct {
+	Files  int
+	Bytes  int64
+	Oldest string // Oldest day, "" if empty
+}
+
+// ArchiveCheck counts the crops by the key that sealed them
+type ArchiveCheck struct {
+	Files      int
+	Stale      int // Sealed with another key than the active one, resealed by Reseal
+	Unreadable int // Sealed with a key that is no longer loaded
+}
+
+// NewImageArchive creates the archive directory
+func NewImageArchive(cfg ArchiveConfig) (*ImageArchive, error) {
+	if cfg.Keys == nil {
+		return nil, fmt.Errorf("image archive needs data keys")
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultRetention
+	}
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return &ImageArchive{cfg: cfg}, nil
+}
+
+// Save encrypts and writes one crop
+func (a *ImageArchive) Save(userID int64, callID, kind string, jpeg []byte, at time.Time) error {
+	// The file name is authenticated so a crop can't be moved to another user
+	name := fmt.Sprintf("%d_%s_%s_%d%s", userID, callID, kind, at.UnixMilli(), archiveFileExt)
+	sealed, err := a.cfg.Keys.Seal(jpeg, []byte(name))
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	dir := filepath.Join(a.cfg.Dir, at.Format(archiveDayLayout))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), sealed, 0600); err != nil {
+		return fmt.Errorf("failed to write archived image: %w", err)
+	}
+	return nil
+}
+
+// Find decrypts the crops of a call, oldest first
+func (a *ImageArchive) Find(callID string) ([]ArchivedImage, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var images []ArchivedImage
+	err := a.walk(func(path, name string) error {
+		image, ok := parseArchiveName(name)
+		if !ok || image.CallID != callID {
+			return nil
+		}
+		sealed, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		image.JPEG, err = a.cfg.Keys.Open(sealed, []byte(name))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", name, err)
+		}
+		images = append(images, image)
+		return nil
+	})
+	sort.Slice(images, func(i, j int) bool { return images[i].At.Before(images[j].At) })
+	return images, err
+}
+
+// PurgeUser deletes every crop of the user. Returns the number deleted.
+func (a *ImageArchive) PurgeUser(userID int64) (int, error) {
+	prefix := strconv.FormatInt(userID, 10) + "_"
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	deleted := 0
+	err := a.walk(func(path, name string) error {
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+// PurgeBefore deletes the crops of the days before day. Returns the number deleted.
+func (a *ImageArchive) PurgeBefore(day time.Time) (int, error) {
+	cutoff := day.Format(archiveDayLayout)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	days, err := a.days()
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, d := range days {
+		if d >= cutoff {
+			break
+		}
+		dir := filepath.Join(a.cfg.Dir, d)
+		entries, _ := os.ReadDir(dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return deleted, fmt.Errorf("failed to delete %s: %w", dir, err)
+		}
+		deleted += len(entries)
+	}
+	return deleted, nil
+}
+
+// Stats counts the archived crops
+func (a *ImageArchive) Stats() (ArchiveStats, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var stats ArchiveStats
+	if days, err := a.days(); err == nil && len(days) > 0 {
+		stats.Oldest = days[0]
+	}
+	err := a.walk(func(path, name string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		stats.Files++
+		stats.Bytes += info.Size()
+		return nil
+	})
+	return stats, err
+}
+
+// Verify reads which key sealed each crop, without decrypting them
+func (a *ImageArchive) Verify() (ArchiveCheck, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var check ArchiveCheck
+	err := a.walk(func(path, name string) error {
+		sealed, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		check.Files++
+		if !a.cfg.Keys.Stale(sealed) {
+			return nil
+		}
+		check.Stale++
+		if id := encryption.KeyID(sealed); id != "" && !a.cfg.Keys.Has(id) {
+			check.Unreadable++
+		}
+		return nil
+	})
+	return check, err
+}
+
+// Reseal re-encrypts the crops not sealed with the active key, so the old
+// key can be removed. Crops no loaded key opens are skipped. Returns the
+// number resealed.
+func (a *ImageArchive) Reseal() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	resealed := 0
+	err := a.walk(func(path, name string) error {
+		sealed, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !a.cfg.Keys.Stale(sealed) {
+			return nil
+		}
+		jpeg, err := a.cfg.Keys.Open(sealed, []byte(name))
+		if errors.Is(err, encryption.ErrUnknownKey) {
+			logger.Warn("Archived image sealed with an unloaded key, not resealed", "file", name, "key_id", encryption.KeyID(sealed))
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", name, err)
+		}
+		if sealed, err = a.cfg.Keys.Seal(jpeg, []byte(name)); err != nil {
+			return err
+		}
+		if err := utils.WriteFileAtomic(path, sealed, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		resealed++
+		return nil
+	})
+	return resealed, err
+}
+
+// enforceRetention deletes expired days every sweep interval until stop closes
+func (a *ImageArchive) enforceRetention(stop <-chan struct{}) {
+	defer alerting.Recover("image_archive")
+
+	ticker := time.NewTicker(archiveSweepInterval)
+	defer ticker.Stop()
+	for {
+		deleted, err := a.PurgeBefore(time.Now().Add(-a.cfg.Retention))
+		if err != nil {
+			logger.Error("Failed to enforce archive retention", "err", err)
+		} else if deleted > 0 {
+			logger.Info("Expired archived images deleted", "count", deleted, "retention", a.cfg.Retention)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// days lists the day directories, oldest first
+func (a *ImageArchive) days() ([]string, error) {
+	entries, err := os.ReadDir(a.cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	var days []string
+	for _, entry := range entries {
+		if _, err := time.Parse(archiveDayLayout, entry.Name()); entry.IsDir() && err == nil {
+			days = append(days, entry.Name())
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+// walk calls fn for every archived file
+func (a *ImageArchive) walk(fn func(path, name string) error) error {
+	err := filepath.WalkDir(a.cfg.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), archiveFileExt) {
+			return nil
+		}
+		return fn(path, d.Name())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	return nil
+}
+
+func parseArchiveName(name string) (ArchivedImage, bool) {
+	parts := strings.Split(strings.TrimSuffix(name, archiveFileExt), "_")
+	if len(parts) != 4 {
+		return ArchivedImage{}, false
+	}
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return ArchivedImage{}, false
+	}
+	millis, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return ArchivedImage{}, false
+	}
+	return ArchivedImage{UserID: userID, CallID: parts[1], Kind: parts[2], At: time.UnixMilli(millis)}, true
+}
+
+// ============================================================
+// ARCHIVING CROPS
+// ============================================================
+
+// SetImageArchive enables archiving of submitted crops and starts the
+// retention sweep
+func (w *WebRTCManager) SetImageArchive(archive *ImageArchive) {
+	w.mu.Lock()
+	w.archive = archive
+	w.mu.Unlock()
+
+	go archive.enforceRetention(w.shutdown)
+	logger.Info("Image archive enabled", "dir", archive.cfg.Dir,
+		"retention", archive.cfg.Retention, "keep_rejected", archive.cfg.KeepRejected)
+}
+
+// archiveCrop saves a submitted crop in the background. Rejected crops are
+// only kept when configured.
+func (w *WebRTCManager) archiveCrop(userID int64, jpeg []byte, recognized bool) {
+	w.mu.RLock()
+	archive := w.archive
+	w.mu.RUnlock()
+	if archive == nil || len(jpeg) == 0 || (!recognized && !archive.cfg.KeepRejected) {
+		return
+	}
+
+	kind := ArchiveRejected
+	if recognized {
+		kind = ArchiveAccepted
+	}
+	callID := w.callID(userID)
+	if callID == "" {
+		callID = "none"
+	}
+	// The caller's buffer goes back to the pool
+	crop := append([]byte(nil), jpeg...)
+	at := time.Now()
+
+	go func() {
+		if err := archive.Save(userID, callID, kind, crop, at); err != nil {
+			logger.Warn("Failed to archive crop", "user_id", userID, "err", err)
+		}
+	}()
+}
+
+// ============================================================
+// ARCHIVE COMMAND
+// ============================================================
+
+const archiveUsage = "Cách dùng:\n" +
+	"!archive stats\n" +
+	"!archive show <call id>\n" +
+	"!archive purge user <user id>\n" +
+	"!archive purge before <YYYY-MM-DD>\n" +
+	"!archive reseal"
+
+func (w *WebRTCManager) handleArchiveCommand(args []string) models.ChannelMessageContent {
+	w.mu.RLock()
+	archive := w.archive
+	w.mu.RUnlock()
+	if archive == nil {
+		return client.BuildErrorMessage("❌ Kho ảnh chưa bật", "Đặt IMAGE_ARCHIVE_ENABLED và khóa mã hóa (DATA_KEYS_FILE) để lưu ảnh check-in.")
+	}
+	if len(args) == 0 {
+		return client.BuildSimpleTextMessage(archiveUsage)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "stats":
+		stats, err := archive.Stats()
+		if err != nil {
+			return client.BuildErrorMessage("❌ Không đọc được kho ảnh", err.Error())
+		}
+		oldest := stats.Oldest
+		if oldest == "" {
+			oldest = "-"
+		}
+		return client.BuildSimpleTextMessage(fmt.Sprintf("Kho ảnh: %d ảnh, %.1f MB, cũ nhất %s, lưu %d ngày",
+			stats.Files, float64(stats.Bytes)/(1<<20), oldest, int(archive.cfg.Retention.Hours()/24)))
+
+	case "show":
+		if len(args) < 2 {
+			return client.BuildSimpleTextMessage(archiveUsage)
+		}
+		images, err := archive.Find(args[1])
+		if err != nil {
+			return client.BuildErrorMessage("❌ Không đọc được kho ảnh", err.Error())
+		}
+		if len(images) == 0 {
+			return client.BuildSimpleTextMessage(fmt.Sprintf("Không có ảnh nào của cuộc gọi %s.", args[1]))
+		}
+		if len(images) > maxArchiveImagesShown {
+			images = images[len(images)-maxArchiveImagesShown:]
+		}
+		captions := make([]string, len(images))
+		urls := make([]string, len(images))
+		for i, image := range images {
+			captions[i] = fmt.Sprintf("User %s - %s - %s", w.userLabel(image.UserID), image.Kind, image.At.In(Office{}.Zone()).Format("02/01 15:04:05"))
+			urls[i] = w.hostImage(image.JPEG, "image/jpeg", 0)
+		}
+		logger.Info("Archived images viewed", "call_id", args[1], "count", len(images))
+		return client.BuildImageGalleryMessage("🗂️ Ảnh check-in "+args[1], captions, urls)
+
+	case "purge":
+		if len(args) < 3 {
+			return client.BuildSimpleTextMessage(archiveUsage)
+		}
+		var deleted int
+		var err error
+		switch strings.ToLower(args[1]) {
+		case "user":
+			userID, parseErr := strconv.ParseInt(args[2], 10, 64)
+			if parseErr != nil {
+				return client.BuildErrorMessage("❌ User ID không hợp lệ", args[2])
+			}
+			deleted, err = archive.PurgeUser(userID)
+		case "before":
+			day, parseErr := time.ParseInLocation(archiveDayLayout, args[2], time.Local)
+			if parseErr != nil {
+				return client.BuildErrorMessage("❌ Ngày không hợp lệ", args[2]+" (dùng YYYY-MM-DD)")
+			}
+			deleted, err = archive.PurgeBefore(day)
+		default:
+			return client.BuildSimpleTextMessage(archiveUsage)
+		}
+		if err != nil {
+			return client.BuildErrorMessage("❌ Xóa ảnh thất bại", err.Error())
+		}
+		logger.Info("Archived images purged", "scope", args[1], "value", args[2], "count", deleted)
+		return client.BuildSuccessMessage("✅ Đã xóa ảnh", fmt.Sprintf("Đã xóa %d ảnh", deleted))
+
+	case "reseal":
+		// After rotating the data keys, before the old key is removed
+		resealed, err := archive.Reseal()
+		if err != nil {
+			return client.BuildErrorMessage("❌ Mã hóa lại ảnh thất bại", err.Error())
+		}
+		logger.Info("Archived images resealed", "key_id", archive.cfg.Keys.ActiveKeyID(), "count", resealed)
+		return client.BuildSuccessMessage("✅ Đã mã hóa lại ảnh",
+			fmt.Sprintf("Đã mã hóa lại %d ảnh bằng khóa %s", resealed, archive.cfg.Keys.ActiveKeyID()))
+
+	default:
+		return client.BuildSimpleTextMessage(archiveUsage)
+	}
+}
@@ -0,0 +1,190 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/utils"
+	"mezon-checkin-bot/models"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// OFFICE ASSIGNMENTS - Which offices each employee may check in at
+// ============================================================
+
+// LoadAssignments loads per-user office assignments from the backend (when
+// enabled) or the assignments file. Without the backend, a missing file is
+// not an error: every user may then check in at any office. With the
+// backend, its answer is saved to the file as the last known good copy; when
+// it fails the loaded assignments are kept, then the file is used, and with
+// neither no one may check in until a refresh succeeds.
+func (c *LocationConfig) LoadAssignments(apiClient *api.APIClient) error {
+	if !c.Enabled {
+		return nil
+	}
+
+	fromAPI := c.AssignmentsFromAPI && apiClient != nil
+	if fromAPI {
+		var list OfficeAssignmentList
+		err := fetchAssignments(apiClient, &list)
+		if err == nil {
+			var assignments map[int64][]string
+			if assignments, err = parseAssignments(list); err == nil {
+				c.setAssignments(assignments, true)
+				c.saveAssignments(list)
+				logger.Info("Loaded office assignments", "users", len(assignments), "source", models.APIOfficeAssignments)
+				return nil
+			}
+		}
+
+		c.mu.RLock()
+		loaded := c.assignmentsLoaded
+		c.mu.RUnlock()
+		if loaded {
+			logger.Warn("Failed to refresh office assignments from API, keeping loaded ones", "err", err)
+			return nil
+		}
+		logger.Warn("Failed to load office assignments from API", "err", err)
+	}
+
+	var list OfficeAssignmentList
+	if c.AssignmentsFilePath != "" {
+		data, err := os.ReadFile(c.AssignmentsFilePath)
+		switch {
+		case os.IsNotExist(err):
+			if fromAPI {
+				// Without the backend or a saved copy, nobody's offices are known
+				c.setAssignments(nil, false)
+				logger.Error("No office assignments available, check-ins are blocked until the API responds")
+				return nil
+			}
+			logger.Info("No office assignments file, all offices allowed", "path", c.AssignmentsFilePath)
+		case err != nil:
+			return fmt.Errorf("failed to read assignments file: %w", err)
+		default:
+			if err := json.Unmarshal(data, &list); err != nil {
+				return fmt.Errorf("failed to parse assignments JSON: %w", err)
+			}
+			defer logger.Info("Loaded office assignments", "users", len(list.Users), "source", c.AssignmentsFilePath)
+		}
+	} else if fromAPI {
+		c.setAssignments(nil, false)
+		logger.Error("No office assignments available, check-ins are blocked until the API responds")
+		return nil
+	}
+
+	assignments, err := parseAssignments(list)
+	if err != nil {
+		return err
+	}
+	c.setAssignments(assignments, true)
+	return nil
+}
+
+// setAssignments replaces the assignments. Not loaded = fail closed.
+func (c *LocationConfig) setAssignments(assignments map[int64][]string, loaded bool) {
+	c.mu.Lock()
+	c.assignments = assignments
+	c.assignmentsLoaded = loaded
+	c.mu.Unlock()
+}
+
+// saveAssignments keeps the backend's assignments as the file fallback
+func (c *LocationConfig) saveAssignments(list OfficeAssignmentList) {
+	if c.AssignmentsFilePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		logger.Warn("Failed to marshal office assignments", "err", err)
+		return
+	}
+	if err := utils.WriteFileAtomic(c.AssignmentsFilePath, data, 0644); err != nil {
+		logger.Warn("Failed to save office assignments", "err", err)
+	}
+}
+
+// StartAssignmentSync reloads the office assignments every interval until
+// shutdown (default 30m)
+func (w *WebRTCManager) StartAssignmentSync(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultProfileRefresh
+	}
+	w.refreshEvery("assignment_sync", interval, func() {
+		if err := w.locationConfig.LoadAssignments(w.apiClient); err != nil {
+			logger.Warn("Failed to reload office assignments", "err", err)
+		}
+	})
+}
+
+func fetchAssignments(apiClient *api.APIClient, list *OfficeAssignmentList) error {
+	body, statusCode, err := apiClient.GetRequest(models.APIOfficeAssignments)
+	if err != nil {
+		return err
+	}
+	if !apiClient.IsSuccessStatusCode(statusCode) {
+		return fmt.Errorf("API returned status %d", statusCode)
+	}
+	return apiClient.ParseResponse(body, list)
+}
+
+func parseAssignments(list OfficeAssignmentList) (map[int64][]string, error) {
+	assignments := make(map[int64][]string, len(list.Users))
+	for key, officeIDs := range list.Users {
+		userID, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID %q in assignments: %w", key, err)
+		}
+		if len(officeIDs) > 0 {
+			assignments[userID] = officeIDs
+		}
+	}
+	return assignments, nil
+}
+
+// SetProfileAssignments replaces the offices taken from employee profiles
+func (c *LocationConfig) SetProfileAssignments(assignments map[int64][]string) {
+	c.mu.Lock()
+	c.profileOffices = assignments
+	c.mu.Unlock()
+}
+
+// AssignedOffices returns the office IDs the user is assigned to
+// (nil = no restriction). The employee profile wins over the assignments
+// file or endpoint. Users without a profile get no office while the
+// backend's assignments could not be loaded.
+func (c *LocationConfig) AssignedOffices(userID int64) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if offices, ok := c.profileOffices[userID]; ok {
+		return offices
+	}
+	if c.AssignmentsFromAPI && !c.assignmentsLoaded {
+		return []string{}
+	}
+	return c.assignments[userID]
+}
+
+// GetOfficesForUser returns the enabled offices the user may check in at
+func (c *LocationConfig) GetOfficesForUser(userID int64) []Office {
+	allowed := c.AssignedOffices(userID)
+	offices := c.GetOffices()
+	if allowed == nil {
+		return offices
+	}
+
+	filtered := make([]Office, 0, len(allowed))
+	for _, office := range offices {
+		for _, id := range allowed {
+			if strings.EqualFold(office.ID, id) {
+				filtered = append(filtered, office)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -1,8 +1,9 @@
 package webrtc
 
 import (
-	"log"
+	"fmt"
 	"mezon-checkin-bot/internal/audio"
+	"strings"
 	"time"
 )
 
@@ -15,38 +16,38 @@ func (w *WebRTCManager) startWelcomeAudio(userID int64) {
 		return
 	}
 
-	w.mu.RLock()
-	state, exists := w.connections[userID]
-	w.mu.RUnlock()
+	state, exists := w.connections.Get(userID)
 
 	if !exists || state.audioPlayer == nil {
-		log.Printf("⚠️  No audio player found for user %d", userID)
+		w.callLogger(userID).Warn("No audio player found")
 		return
 	}
 
-	welcomePath, hasWelcome := w.audioLibrary.Get("welcome")
+	locale := w.locales.Resolve(userID)
+	welcomePath, hasWelcome := w.audioLibrary.GetLocale(locale, "welcome")
 	musicPath, hasMusic := w.audioLibrary.Get("background_music")
 
 	if !hasWelcome {
-		log.Println("⚠️  Welcome audio not configured")
+		state.logger.Warn("Welcome audio not configured")
 		return
 	}
 
-	log.Println("🎵 Starting welcome sequence...")
+	state.logger.Info("Starting welcome sequence", "locale", locale)
 
 	state.audioPlayer.Play(audio.AudioItem{
 		FilePath: welcomePath,
 		Name:     "welcome",
 		Loop:     false,
 		OnFinish: func() {
-			log.Println("✅ Welcome audio finished")
+			state.logger.Info("Welcome audio finished")
 
 			if hasMusic && w.audioConfig.BackgroundMusicEnabled {
-				log.Println("🎵 Starting background music...")
-				state.audioPlayer.Play(audio.AudioItem{
+				state.logger.Info("Starting background music")
+				state.audioPlayer.PlayBackground(audio.AudioItem{
 					FilePath: musicPath,
 					Name:     "background_music",
 					Loop:     true,
+					Gain:     w.audioConfig.BackgroundMusicGain,
 				})
 			}
 		},
@@ -63,32 +64,160 @@ func (w *WebRTCManager) playCheckinFailAudio(userID int64) {
 		return
 	}
 
-	w.mu.RLock()
-	state, exists := w.connections[userID]
-	w.mu.RUnlock()
+	state, exists := w.connections.Get(userID)
 
 	if !exists || state.audioPlayer == nil {
-		log.Printf("⚠️  No audio player found for user %d", userID)
+		w.callLogger(userID).Warn("No audio player found")
 		go w.endCallAfterDelay(userID, "checkin_fail_no_player", 500*time.Millisecond)
 		return
 	}
 
-	checkinPath, hasCheckin := w.audioLibrary.Get("checkin_fail")
+	checkinPath, hasCheckin := w.audioLibrary.GetLocale(w.locales.Resolve(userID), "checkin_fail")
 	if !hasCheckin {
-		log.Println("⚠️  Checkin fail audio not configured")
+		state.logger.Warn("Checkin fail audio not configured")
 		go w.endCallAfterDelay(userID, "checkin_fail_no_file", 500*time.Millisecond)
 		return
 	}
 
-	log.Println("❌ Playing checkin fail audio...")
+	state.logger.Info("Playing checkin fail audio")
 
 	state.audioPlayer.PlayNow(audio.AudioItem{
 		FilePath: checkinPath,
 		Name:     "checkin_fail",
 		Loop:     false,
 		OnFinish: func() {
-			log.Println("✅ Checkin fail audio finished")
+			state.logger.Info("Checkin fail audio finished")
 			go w.endCallAfterDelay(userID, "checkin_fail_complete", 1*time.Second)
 		},
 	})
 }
+
+// ============================================================
+// CHECKIN SUCCESS AUDIO
+// ============================================================
+
+// playCheckinSuccessAudio plays the success prompt in the user's language.
+// Returns false if nothing was queued, in which case onFinish is not called.
+func (w *WebRTCManager) playCheckinSuccessAudio(userID int64, onFinish func()) bool {
+	if !w.audioConfig.Enabled {
+		return false
+	}
+
+	state, exists := w.connections.Get(userID)
+
+	if !exists || state.audioPlayer == nil {
+		return false
+	}
+
+	successPath, hasSuccess := w.audioLibrary.GetLocale(w.locales.Resolve(userID), "checkin_success")
+	if !hasSuccess {
+		return false
+	}
+
+	state.audioPlayer.PlayNow(audio.AudioItem{
+		FilePath: successPath,
+		Name:     "checkin_success",
+		Loop:     false,
+		OnFinish: onFinish,
+	})
+	return true
+}
+
+// ============================================================
+// TTS GREETING
+// ============================================================
+
+const defaultGreetingTemplate = "Xin chào %s"
+
+// playGreeting speaks the greeting with the employee's first name.
+// Returns false if nothing was queued, in which case onFinish is not called.
+func (w *WebRTCManager) playGreeting(userID int64, firstName string, onFinish func()) bool {
+	if !w.audioConfig.Enabled || w.tts == nil || strings.TrimSpace(firstName) == "" {
+		return false
+	}
+
+	state, exists := w.connections.Get(userID)
+
+	if !exists || state.audioPlayer == nil {
+		return false
+	}
+
+	template := w.audioConfig.GreetingTemplate
+	if template == "" {
+		template = defaultGreetingTemplate
+	}
+
+	greetingPath, err := w.tts.Render(fmt.Sprintf(template, firstName))
+	if err != nil {
+		state.logger.Warn("TTS greeting failed", "err", err)
+		return false
+	}
+
+	state.logger.Info("Playing greeting", "first_name", firstName)
+
+	state.audioPlayer.PlayNow(audio.AudioItem{
+		FilePath: greetingPath,
+		Name:     "greeting",
+		Loop:     false,
+		OnFinish: onFinish,
+	})
+	return true
+}
+
+// ============================================================
+// STAGE PROMPTS
+// ============================================================
+
+// playStagePrompt guides the caller through a check-in stage, once per call.
+// The recorded prompt is used if registered, otherwise the default text is
+// spoken with TTS. Returns false if nothing was queued, in which case
+// onFinish is not called.
+func (w *WebRTCManager) playStagePrompt(userID int64, stage string, onFinish func()) bool {
+	if !w.audioConfig.Enabled {
+		return false
+	}
+
+	state, exists := w.connections.Get(userID)
+
+	if !exists || state.audioPlayer == nil {
+		return false
+	}
+
+	state.mu.Lock()
+	if state.promptedStages == nil {
+		state.promptedStages = make(map[string]bool)
+	}
+	alreadyPrompted := state.promptedStages[stage]
+	state.promptedStages[stage] = true
+	state.mu.Unlock()
+
+	if alreadyPrompted {
+		return false
+	}
+
+	promptPath, hasPrompt := w.audioLibrary.GetLocale(w.locales.Resolve(userID), audio.StageAudioName(stage))
+	if !hasPrompt {
+		text, hasText := audio.DefaultStageTexts[stage]
+		if !hasText || w.tts == nil {
+			return false
+		}
+
+		// Rendered at startup, rendering here would stall the call
+		var cached bool
+		promptPath, cached = w.tts.Cached(text)
+		if !cached {
+			state.logger.Warn("TTS stage prompt not rendered yet", "stage", stage)
+			return false
+		}
+	}
+
+	state.logger.Info("Stage prompt", "stage", stage)
+
+	state.audioPlayer.Play(audio.AudioItem{
+		FilePath: promptPath,
+		Name:     audio.StageAudioName(stage),
+		Loop:     false,
+		OnFinish: onFinish,
+	})
+	return true
+}
@@ -0,0 +1,138 @@
+package webrtc
+
+import (
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/audit"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/models"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// AUDIT - Security-relevant actions
+// ============================================================
+
+const (
+	defaultAuditEntries = 20
+	maxAuditEntries     = 100
+)
+
+const auditUsage = "Cách dùng:\n" +
+	"!audit [số dòng]\n" +
+	"!audit user <user id> [số dòng]\n" +
+	"!audit verify"
+
+// SetAuditLog enables the audit log
+func (w *WebRTCManager) SetAuditLog(log *audit.Log) {
+	w.mu.Lock()
+	w.auditLog = log
+	w.mu.Unlock()
+}
+
+// audit records an action. Failures are only logged: auditing must never
+// block a check-in.
+func (w *WebRTCManager) audit(actor int64, action, target, detail string) {
+	w.mu.RLock()
+	log := w.auditLog
+	w.mu.RUnlock()
+	if log == nil {
+		return
+	}
+	if err := log.Append(actor, action, target, detail); err != nil {
+		logger.Error("Failed to write audit entry", "action", action, "err", err)
+	}
+}
+
+// AuditAdminAPI records a state changing admin API request
+func (w *WebRTCManager) AuditAdminAPI(request, remote string) {
+	w.audit(0, audit.ActionAdminAPI, remote, request)
+}
+
+// auditRequest records a request sent to the backend on the user's behalf
+func (w *WebRTCManager) auditRequest(action string, userID int64, statusCode int, err error) {
+	var result string
+	switch {
+	case errors.Is(err, api.ErrQueued):
+		result = "queued"
+	case err != nil:
+		result = "error: " + err.Error()
+	default:
+		result = fmt.Sprintf("HTTP %d", statusCode)
+	}
+	w.audit(0, action, strconv.FormatInt(userID, 10), result)
+}
+
+func (w *WebRTCManager) handleAuditCommand(args []string) models.ChannelMessageContent {
+	w.mu.RLock()
+	log := w.auditLog
+	w.mu.RUnlock()
+	if log == nil {
+		return client.BuildErrorMessage("❌ Nhật ký kiểm toán chưa bật", "Kiểm tra cấu hình AUDIT_LOG_PATH.")
+	}
+
+	limit := defaultAuditEntries
+	var match func(audit.Entry) bool
+
+	switch {
+	case len(args) > 0 && strings.EqualFold(args[0], "verify"):
+		count, err := log.Verify()
+		if err != nil {
+			logger.Error("Audit log verification failed", "valid_entries", count, "err", err)
+			return client.BuildErrorMessage("🚨 Nhật ký kiểm toán bị thay đổi", fmt.Sprintf("%d dòng hợp lệ, sau đó: %v", count, err))
+		}
+		return client.BuildSuccessMessage("✅ Nhật ký kiểm toán toàn vẹn", fmt.Sprintf("%d dòng hợp lệ", count))
+
+	case len(args) > 0 && strings.EqualFold(args[0], "user"):
+		if len(args) < 2 {
+			return client.BuildSimpleTextMessage(auditUsage)
+		}
+		userID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return client.BuildErrorMessage("❌ User ID không hợp lệ", args[1])
+		}
+		target := args[1]
+		match = func(entry audit.Entry) bool { return entry.Actor == userID || entry.Target == target }
+		args = args[2:]
+	}
+
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return client.BuildSimpleTextMessage(auditUsage)
+		}
+		limit = min(n, maxAuditEntries)
+	}
+
+	entries, err := log.Recent(limit, match)
+	if err != nil {
+		return client.BuildErrorMessage("❌ Không đọc được nhật ký kiểm toán", err.Error())
+	}
+	return client.BuildSimpleTextMessage(formatAuditEntries(entries))
+}
+
+func formatAuditEntries(entries []audit.Entry) string {
+	if len(entries) == 0 {
+		return "Chưa có hoạt động nào."
+	}
+
+	zone := Office{}.Zone()
+	var b strings.Builder
+	for _, entry := range entries {
+		actor := "bot"
+		if entry.Actor != 0 {
+			actor = strconv.FormatInt(entry.Actor, 10)
+		}
+		fmt.Fprintf(&b, "#%d %s %s %s", entry.Seq, entry.At.In(zone).Format("02/01 15:04:05"), actor, entry.Action)
+		if entry.Target != "" {
+			fmt.Fprintf(&b, " → %s", entry.Target)
+		}
+		if entry.Detail != "" {
+			fmt.Fprintf(&b, ": %s", entry.Detail)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
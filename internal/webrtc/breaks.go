@@ -0,0 +1,161 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/models"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// BREAKS - Start and end breaks from DM commands
+// ============================================================
+
+const breakUsage = "Cách dùng:\n" +
+	"!break start - bắt đầu nghỉ giải lao\n" +
+	"!break end - kết thúc nghỉ giải lao\n" +
+	"!break - xem trạng thái"
+
+// handleBreakCommand starts, ends or shows the user's break
+func (w *WebRTCManager) handleBreakCommand(userID int64, args []string) models.ChannelMessageContent {
+	w.mu.RLock()
+	enabled := w.attendance.BreaksEnabled
+	w.mu.RUnlock()
+
+	if !enabled {
+		return client.BuildErrorMessage("❌ Nghỉ giải lao chưa được bật", "Vui lòng liên hệ quản trị viên.")
+	}
+
+	if len(args) == 0 {
+		return w.breakStatus(userID)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "start":
+		return w.startBreak(userID)
+	case "end", "stop":
+		return w.endBreak(userID)
+	default:
+		return client.BuildSimpleTextMessage(breakUsage)
+	}
+}
+
+func (w *WebRTCManager) startBreak(userID int64) models.ChannelMessageContent {
+	event, err := w.submitBreak(userID, models.APIBreakStart)
+	switch {
+	case errors.Is(err, api.ErrBreakInProgress):
+		return client.BuildErrorMessage("⚠️ Bạn đang nghỉ giải lao", "Gửi !break end khi quay lại làm việc.")
+	case errors.Is(err, api.ErrNotClockedIn):
+		return client.BuildErrorMessage("❌ Chưa check-in", "Bạn cần check-in trước khi bắt đầu nghỉ giải lao.")
+	case err != nil:
+		logger.Error("Failed to start break", "user_id", userID, "err", err)
+		return client.BuildErrorMessage("❌ Không thể bắt đầu nghỉ", "Vui lòng thử lại sau.")
+	}
+
+	// The backend's LastBreak is authoritative for the start time
+	zone := w.userZone(userID)
+	start := time.Now().In(zone)
+	if t, ok := lastBreakTime(event, zone); ok {
+		start = t
+	}
+
+	w.mu.Lock()
+	w.breaks[userID] = start
+	w.mu.Unlock()
+
+	w.recordEvent(userID, CheckinEvent{Outcome: OutcomeBreakStarted, At: start})
+	logger.Info("Break started", "user_id", userID, "at", start)
+	return client.BuildSuccessMessage("☕ Bắt đầu nghỉ giải lao",
+		fmt.Sprintf("Bắt đầu lúc %s. Gửi !break end khi quay lại làm việc.", start.Format("15:04")))
+}
+
+func (w *WebRTCManager) endBreak(userID int64) models.ChannelMessageContent {
+	_, err := w.submitBreak(userID, models.APIBreakEnd)
+	switch {
+	case errors.Is(err, api.ErrNoActiveBreak):
+		w.clearBreak(userID)
+		return client.BuildErrorMessage("⚠️ Không có giờ nghỉ nào đang diễn ra", "Gửi !break start để bắt đầu nghỉ.")
+	case errors.Is(err, api.ErrNotClockedIn):
+		return client.BuildErrorMessage("❌ Chưa check-in", "Bạn cần check-in trước.")
+	case err != nil:
+		logger.Error("Failed to end break", "user_id", userID, "err", err)
+		return client.BuildErrorMessage("❌ Không thể kết thúc nghỉ", "Vui lòng thử lại sau.")
+	}
+
+	end := time.Now().In(w.userZone(userID))
+	start, tracked := w.clearBreak(userID)
+	start = start.In(end.Location())
+
+	w.recordEvent(userID, CheckinEvent{Outcome: OutcomeBreakEnded, At: end})
+	description := fmt.Sprintf("Kết thúc lúc %s.", end.Format("15:04"))
+	if tracked {
+		minutes := int(end.Sub(start).Round(time.Minute).Minutes())
+		description = fmt.Sprintf("Nghỉ từ %s đến %s (%d phút).", start.Format("15:04"), end.Format("15:04"), minutes)
+		logger.Info("Break ended", "user_id", userID, "minutes", minutes)
+	}
+	return client.BuildSuccessMessage("✅ Kết thúc nghỉ giải lao", description)
+}
+
+func (w *WebRTCManager) breakStatus(userID int64) models.ChannelMessageContent {
+	w.mu.RLock()
+	start, onBreak := w.breaks[userID]
+	w.mu.RUnlock()
+
+	if !onBreak {
+		return client.BuildSimpleTextMessage("Bạn không trong giờ nghỉ.\n\n" + breakUsage)
+	}
+	minutes := int(time.Since(start).Minutes())
+	return client.BuildSimpleTextMessage(fmt.Sprintf("☕ Bạn đang nghỉ từ %s (%d phút).\n\n%s",
+		start.Format("15:04"), minutes, breakUsage))
+}
+
+// clearBreak forgets the user's break, returning its start if one was tracked
+func (w *WebRTCManager) clearBreak(userID int64) (time.Time, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	start, ok := w.breaks[userID]
+	delete(w.breaks, userID)
+	return start, ok
+}
+
+// submitBreak posts a break request and returns the clock event from the
+// response, if the backend sent one
+func (w *WebRTCManager) submitBreak(userID int64, endpoint string) (*models.LastClockEventDTO, error) {
+	body, statusCode, err := w.apiClient.SendRequestContext(context.Background(), models.BreakRequest{UserId: userID}, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	w.apiClient.LogResponse(body, statusCode)
+	if apiErr := api.ParseError(body, statusCode); apiErr != nil {
+		return nil, apiErr
+	}
+
+	// The backend answers with the updated clock event, or with the
+	// recognition-style wrapper around it
+	var wrapped struct {
+		LastClockEventDTO *models.LastClockEventDTO `json:"lastClockEventDTO"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err == nil && wrapped.LastClockEventDTO != nil {
+		return wrapped.LastClockEventDTO, nil
+	}
+	var event models.LastClockEventDTO
+	if err := json.Unmarshal(body, &event); err == nil && event.ClockID != "" {
+		return &event, nil
+	}
+	return nil, nil
+}
+
+// lastBreakTime returns the break time reported in the clock event
+func lastBreakTime(event *models.LastClockEventDTO, zone *time.Location) (time.Time, bool) {
+	if event == nil || event.LastBreak == nil {
+		return time.Time{}, false
+	}
+	return parseBackendTime(*event.LastBreak, zone)
+}
@@ -0,0 +1,106 @@
+package webrtc
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/alerting"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// CALL GOROUTINES - What each call runs, checked to have exited
+// once the call is cleaned up
+// ============================================================
+
+const (
+	callGoroutineBudget = 12              // More at once means one is started in a loop
+	callGoroutineExit   = 5 * time.Second // Time given after cleanup before reporting a leak
+)
+
+// callGoroutines counts a call's running goroutines by name
+type callGoroutines struct {
+	mu      sync.Mutex
+	running map[string]int
+	total   int
+}
+
+func newCallGoroutines() *callGoroutines {
+	return &callGoroutines{running: make(map[string]int)}
+}
+
+// Go runs fn as a goroutine of the call
+func (g *callGoroutines) Go(name string, fn func()) {
+	g.mu.Lock()
+	g.running[name]++
+	g.total++
+	total := g.total
+	g.mu.Unlock()
+	if total > callGoroutineBudget {
+		logger.Warn("Call goroutine budget exceeded", "goroutine", name, "running", total, "budget", callGoroutineBudget)
+	}
+
+	go func() {
+		defer func() {
+			g.mu.Lock()
+			if g.running[name]--; g.running[name] == 0 {
+				delete(g.running, name)
+			}
+			g.total--
+			g.mu.Unlock()
+		}()
+		fn()
+	}()
+}
+
+// Running returns how many goroutines of each name are still running
+func (g *callGoroutines) Running() map[string]int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	running := make(map[string]int, len(g.running))
+	for name, count := range g.running {
+		running[name] = count
+	}
+	return running
+}
+
+func (g *callGoroutines) count() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.total
+}
+
+// goCall runs fn as a goroutine of the user's call, untracked when the call
+// is already gone
+func (w *WebRTCManager) goCall(userID int64, name string, fn func()) {
+	state, exists := w.connections.Get(userID)
+	if !exists || state.goroutines == nil {
+		go fn()
+		return
+	}
+	state.goroutines.Go(name, fn)
+}
+
+// verifyCallGoroutines checks in the background that the cleaned up call's
+// goroutines exit, alerting with the ones that do not. It cannot wait in
+// cleanupConnection, which those goroutines call themselves.
+func (w *WebRTCManager) verifyCallGoroutines(state *connectionState) {
+	if state.goroutines == nil {
+		return
+	}
+	go func() {
+		defer alerting.Recover("call_goroutines")
+
+		deadline := time.Now().Add(callGoroutineExit)
+		for state.goroutines.count() > 0 {
+			if time.Now().After(deadline) {
+				running := state.goroutines.Running()
+				state.logger.Error("Call goroutines still running after cleanup", "running", running)
+				alerting.Alert(alerting.KeyResourceLeak+":call_goroutines", "⚠️ Nghi ngờ rò rỉ tài nguyên",
+					fmt.Sprintf("Cuộc gọi đã kết thúc %s nhưng vẫn còn goroutine chạy: %v", callGoroutineExit, running))
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		state.logger.Debug("All call goroutines exited")
+	}()
+}
@@ -0,0 +1,230 @@
+package webrtc
+
+import (
+	"fmt"
+	"log/slog"
+	"mezon-checkin-bot/internal/i18n"
+	"mezon-checkin-bot/models"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// CALL LIMITS - Calls per user per hour and day, and temporary
+// blocks after repeated identity failures, so nobody can try
+// faces against the recognition API
+// ============================================================
+
+// Defaults for the zero fields of CallLimitConfig
+const (
+	defaultCallsPerHour     = 10
+	defaultCallsPerDay      = 30
+	defaultIdentityFailures = 5
+	defaultIdentityBlock    = time.Hour
+)
+
+// CallLimitConfig limits the calls of each user
+type CallLimitConfig struct {
+	Enabled          bool
+	PerHour          int           // Calls started in the last hour (0 = default)
+	PerDay           int           // Calls started in the last 24 hours (0 = default)
+	IdentityFailures int           // Failed identifications in a row before a block (0 = default)
+	IdentityBlock    time.Duration // How long the block lasts (0 = default)
+}
+
+// Reasons a call is refused, also the event reasons and notice keys
+const (
+	callLimitRate     = "calls_limited"
+	callLimitIdentity = "identity_blocked"
+)
+
+type callHistory struct {
+	calls        []time.Time // Starts within the last day, oldest first
+	failures     int         // Identity failures since the last success
+	blockedUntil time.Time
+}
+
+type callLimiter struct {
+	mu     sync.Mutex
+	config CallLimitConfig
+	users  map[int64]*callHistory
+	pruned time.Time
+}
+
+func newCallLimiter(config CallLimitConfig) *callLimiter {
+	if config.PerHour == 0 {
+		config.PerHour = defaultCallsPerHour
+	}
+	if config.PerDay == 0 {
+		config.PerDay = defaultCallsPerDay
+	}
+	if config.IdentityFailures == 0 {
+		config.IdentityFailures = defaultIdentityFailures
+	}
+	if config.IdentityBlock == 0 {
+		config.IdentityBlock = defaultIdentityBlock
+	}
+	return &callLimiter{config: config, users: make(map[int64]*callHistory), pruned: time.Now()}
+}
+
+// allow counts a new call of the user. A refused call is not counted; the
+// reason and when the user may call again are returned.
+func (l *callLimiter) allow(userID int64, now time.Time) (reason string, retryAt time.Time, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+
+	history := l.users[userID]
+	if history == nil {
+		history = &callHistory{}
+		l.users[userID] = history
+	}
+	if now.Before(history.blockedUntil) {
+		return callLimitIdentity, history.blockedUntil, false
+	}
+
+	history.calls = dropBefore(history.calls, now.Add(-24*time.Hour))
+	if len(history.calls) >= l.config.PerDay {
+		return callLimitRate, history.calls[len(history.calls)-l.config.PerDay].Add(24 * time.Hour), false
+	}
+	lastHour := dropBefore(history.calls, now.Add(-time.Hour))
+	if len(lastHour) >= l.config.PerHour {
+		return callLimitRate, lastHour[len(lastHour)-l.config.PerHour].Add(time.Hour), false
+	}
+	history.calls = append(history.calls, now)
+	return "", time.Time{}, true
+}
+
+// identityFailed counts a failed identification, blocking the user once
+// there were too many in a row. Returns the end of a new block.
+func (l *callLimiter) identityFailed(userID int64, now time.Time) (blockedUntil time.Time, blocked bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	history := l.users[userID]
+	if history == nil {
+		history = &callHistory{}
+		l.users[userID] = history
+	}
+	history.failures++
+	if history.failures < l.config.IdentityFailures {
+		return time.Time{}, false
+	}
+	history.failures = 0
+	history.blockedUntil = now.Add(l.config.IdentityBlock)
+	return history.blockedUntil, true
+}
+
+// identified resets the user's failures
+func (l *callLimiter) identified(userID int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if history := l.users[userID]; history != nil {
+		history.failures = 0
+	}
+}
+
+// reset lifts the user's block and forgets their calls and failures
+func (l *callLimiter) reset(userID int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.users, userID)
+}
+
+// prune forgets users without calls in the last day nor a block, hourly
+func (l *callLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < time.Hour {
+		return
+	}
+	l.pruned = now
+	for userID, history := range l.users {
+		history.calls = dropBefore(history.calls, now.Add(-24*time.Hour))
+		if len(history.calls) == 0 && history.failures == 0 && !now.Before(history.blockedUntil) {
+			delete(l.users, userID)
+		}
+	}
+}
+
+// dropBefore returns the times from cutoff on; times is sorted
+func dropBefore(times []time.Time, cutoff time.Time) []time.Time {
+	for i, t := range times {
+		if !t.Before(cutoff) {
+			return times[i:]
+		}
+	}
+	return times[:0]
+}
+
+// SetCallLimits limits how often each user can call. Set before calls are
+// taken.
+func (w *WebRTCManager) SetCallLimits(config CallLimitConfig) {
+	if !config.Enabled {
+		w.callLimits = nil
+		return
+	}
+	w.callLimits = newCallLimiter(config)
+	logger.Info("Call limits enabled",
+		"per_hour", w.callLimits.config.PerHour,
+		"per_day", w.callLimits.config.PerDay,
+		"identity_failures", w.callLimits.config.IdentityFailures,
+		"identity_block", w.callLimits.config.IdentityBlock)
+}
+
+// ResetCallLimits lifts the user's limits, e.g. after an admin checked a
+// block
+func (w *WebRTCManager) ResetCallLimits(userID int64) error {
+	if w.callLimits == nil {
+		return fmt.Errorf("call limits are not enabled")
+	}
+	w.callLimits.reset(userID)
+	return nil
+}
+
+// checkCallLimits counts a new call, turning it away when the user is over
+// a limit. Offers for an open call (renegotiation) are not counted.
+func (w *WebRTCManager) checkCallLimits(userID, channelID int64, callLog *slog.Logger) bool {
+	if w.callLimits == nil || w.connections.Has(userID) {
+		return true
+	}
+	reason, retryAt, ok := w.callLimits.allow(userID, time.Now())
+	if ok {
+		return true
+	}
+
+	callLog.Warn("Call limit reached, rejecting call", "limit", reason, "retry_at", retryAt)
+	w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: reason})
+	vars := i18n.Vars{"time": retryAt.In(w.userZone(userID)).Format("15:04")}
+	if err := w.SendCheckinNotice(channelID, userID, w.text(userID, "notice."+reason, vars)); err != nil {
+		callLog.Error("Failed to send call limit notice", "err", err)
+	}
+	w.hangUpUnanswered(userID, channelID, callLog)
+	return false
+}
+
+// recordIdentityResult feeds the outcome of a recognition call to the
+// limits; failure reasons other than identification are ignored
+func (w *WebRTCManager) recordIdentityResult(userID int64, reason string) {
+	if w.callLimits == nil {
+		return
+	}
+	switch reason {
+	case "":
+		w.callLimits.identified(userID)
+	case "max_attempts", "liveness_failed":
+		if until, blocked := w.callLimits.identityFailed(userID, time.Now()); blocked {
+			w.callLogger(userID).Warn("Too many identity failures, user blocked", "until", until)
+		}
+	}
+}
+
+// hangUpUnanswered ends a call that was never answered
+func (w *WebRTCManager) hangUpUnanswered(userID, channelID int64, callLog *slog.Logger) {
+	if err := w.client.SendWebRTCSignal(
+		userID,
+		w.client.ClientID,
+		channelID,
+		models.WebrtcSDPQuit,
+		"",
+	); err != nil {
+		callLog.Warn("Quit signal failed", "err", err)
+	}
+}
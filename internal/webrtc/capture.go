@@ -2,10 +2,20 @@ package webrtc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"image"
-	"log"
+	"log/slog"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/audio"
+	"mezon-checkin-bot/internal/detector"
+	"mezon-checkin-bot/internal/flags"
+	"mezon-checkin-bot/internal/matpool"
+	"mezon-checkin-bot/internal/tracing"
 	"mezon-checkin-bot/models"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/rtp/codecs"
@@ -20,14 +30,27 @@ import (
 // ============================================================
 
 func (w *WebRTCManager) realtimeFaceDetectionCapture(userID int64, track *webrtc.TrackRemote, ctx context.Context) {
-	log.Printf("📸 Starting face detection for %d...", userID)
+	// A panic must not leave the peer connection open and the call registered
+	defer func() {
+		if r := recover(); r != nil {
+			alerting.Panic("capture", r, w.callAttrs(userID)...)
+			w.cleanupConnection(userID)
+		}
+	}()
+
+	callLog := w.callLogger(userID)
+	callLog.Info("Starting face detection")
+	w.setCallStage(userID, CallStageWaitingKeyframe)
 
 	defer func() {
-		log.Printf("   🧹 Face detection cleanup for %d", userID)
+		callLog.Debug("Face detection cleanup")
 	}()
 
+	// The whole call uses the tuning in effect when it started
+	capture := w.capture()
+
 	sampleBuilder := samplebuilder.New(
-		w.captureConfig.SampleBufferMax,
+		capture.SampleBufferMax,
 		&codecs.VP8Packet{},
 		track.Codec().ClockRate,
 	)
@@ -38,93 +61,140 @@ func (w *WebRTCManager) realtimeFaceDetectionCapture(userID int64, track *webrtc
 		successCount:          0,
 		rtpCount:              0,
 		firstKeyframeReceived: false,
+		logger:                callLog,
+		mats:                  matpool.Default.Scope(fmt.Sprintf("call %d", userID)),
+	}
+	defer captureState.mats.Close()
+	call := w.flagCall(userID)
+	if baseline := w.faceDetector.BaselineDetector(); baseline != nil && !w.flags.Enabled(flags.DNNDetector, call) {
+		captureState.detector = baseline
+	}
+	captureState.persistentFFmpeg = w.flags.Enabled(flags.PersistentFFmpeg, call)
+	var keyframes *keyframeFilter
+	if w.flags.Enabled(flags.KeyframeFilter, call) {
+		keyframes = &keyframeFilter{}
+	}
+	defer func() {
+		if captureState.decoder != nil {
+			captureState.decoder.Close()
+		}
+	}()
+	if w.faceDetector.Config.LivenessEnabled && w.flags.Enabled(flags.Liveness, call) {
+		captureState.liveness = w.faceDetector.NewLivenessTracker()
+	}
+	if w.faceDetector.BatchEnabled() {
+		captureState.batch = detector.NewCropBatch(w.faceDetector.Config.BatchSize, w.faceDetector.Config.BatchWindow)
 	}
 
-	sampleChan := make(chan *media.Sample, 10)
+	sampleChan := make(chan *media.Sample, sampleQueueSize)
+	defer func() {
+		delta, evicted := captureState.drops.delta.Load(), captureState.drops.evicted.Load()
+		if delta+evicted > 0 {
+			callLog.Info("Samples dropped while detection was busy", "delta_frames", delta, "evicted", evicted)
+		}
+		w.sampleDrops.delta.Add(delta)
+		w.sampleDrops.evicted.Add(evicted)
+		if keyframes != nil {
+			callLog.Debug("Delta frame packets filtered", "packets", keyframes.dropped.Load())
+		}
+	}()
 
 	// RTP reader with context cancellation
 	rtpCtx, rtpCancel := context.WithCancel(ctx)
 	defer rtpCancel()
 
-	go func() {
+	w.goCall(userID, "rtp_reader", func() {
 		defer close(sampleChan)
 		for {
 			select {
 			case <-rtpCtx.Done():
-				log.Println("   🛑 RTP reader stopped")
+				callLog.Debug("RTP reader stopped")
 				return
 			default:
 				pkt, _, err := track.ReadRTP()
 				if err != nil {
 					if !strings.Contains(err.Error(), "closed") {
-						log.Printf("   ⚠️  RTP error: %v", err)
+						callLog.Warn("RTP read failed", "err", err)
 					}
 					return
 				}
 
+				if keyframes != nil && !keyframes.pass(pkt) {
+					continue
+				}
 				sampleBuilder.Push(pkt)
 				if sample := sampleBuilder.Pop(); sample != nil {
-					select {
-					case sampleChan <- sample:
-					case <-rtpCtx.Done():
-						return
-					}
+					queueSample(sampleChan, sample, &captureState.drops)
 				}
 			}
 		}
-	}()
+	})
+
+	callLog.Info("Scanning for faces")
 
-	log.Println("   ⏳ Scanning for faces...")
+	_, keyframeSpan := tracing.Start(ctx, "capture.keyframe_wait")
+	defer keyframeSpan.End()
 
-	captureTimeout := time.After(w.captureConfig.CaptureTimeout)
-	pliTimeout := time.After(w.captureConfig.PLITimeout)
+	captureTimeout := time.After(capture.CaptureTimeout)
+	pliTimeout := time.After(capture.PLITimeout)
 
 	// Get connection state
-	w.mu.RLock()
-	state, exists := w.connections[userID]
-	w.mu.RUnlock()
+	state, exists := w.connections.Get(userID)
 
 	if !exists {
-		log.Printf("   ❌ Connection not found")
+		callLog.Error("Connection not found")
 		return
 	}
 
+	w.startStatus(userID, state.channelID)
+	stopTyping := w.startTyping(userID, state.channelID)
+	defer stopTyping()
+
 	// Main loop
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("   🛑 Context cancelled")
+			callLog.Debug("Capture cancelled")
 			return
 
 		case <-captureTimeout:
-			log.Printf("   ⏱️  Timeout after %v", w.captureConfig.CaptureTimeout)
-			w.handleCaptureFailure(userID, state, "timeout")
+			callLog.Warn("Capture timed out", "timeout", capture.CaptureTimeout)
+			w.handleCaptureFailure(userID, state, "timeout", captureState.totalAttempts)
 			return
 
 		case <-pliTimeout:
 			if !captureState.firstKeyframeReceived {
-				log.Println("   ❌ PLI timeout")
-				w.handleCaptureFailure(userID, state, "pli_timeout")
+				callLog.Warn("No keyframe before PLI timeout")
+				w.handleCaptureFailure(userID, state, "pli_timeout", captureState.totalAttempts)
 				return
 			}
 
 		case sample, ok := <-sampleChan:
 			if !ok {
-				log.Println("   📡 Stream ended")
+				callLog.Info("Video stream ended")
 				return
 			}
 
 			// Check max attempts
-			if captureState.totalAttempts >= w.captureConfig.MaxAttempts {
-				log.Printf("   ❌ Max attempts: %d/%d",
-					captureState.successCount, captureState.totalAttempts)
-				w.handleCaptureFailure(userID, state, "max_attempts")
+			if captureState.totalAttempts >= capture.MaxAttempts {
+				// Give pending batched crops one last chance
+				if captureState.batch != nil && captureState.batch.Len() > 0 {
+					if response := w.submitBatch(ctx, userID, captureState); response != nil {
+						callLog.Info("Recognition succeeded")
+						w.handleCaptureSuccess(userID, state, response, captureState.matchedCrop)
+						return
+					}
+				}
+				callLog.Warn("Max attempts reached",
+					"successes", captureState.successCount, "attempts", captureState.totalAttempts)
+				w.handleCaptureFailure(userID, state, "max_attempts", captureState.totalAttempts)
+				w.escalate(userID, state.channelID, captureState.bestCrop, captureState.totalAttempts)
 				return
 			}
 
 			captureState.rtpCount++
-			if captureState.rtpCount == w.captureConfig.InitialRTPCount {
-				log.Printf("   📦 Video stream active")
+			if captureState.rtpCount == capture.InitialRTPCount {
+				callLog.Info("Video stream active")
 			}
 
 			// Process keyframes only
@@ -134,34 +204,67 @@ func (w *WebRTCManager) realtimeFaceDetectionCapture(userID int64, track *webrtc
 
 			if !captureState.firstKeyframeReceived {
 				captureState.firstKeyframeReceived = true
-				log.Println("   ✅ Keyframe received!")
+				w.setCallStage(userID, CallStageDetecting)
+				keyframeSpan.End()
+				callLog.Info("First keyframe received")
 			}
 
 			// Rate limiting
-			if time.Since(captureState.lastCaptureTime) < w.captureConfig.CaptureInterval {
+			if time.Since(captureState.lastCaptureTime) < capture.CaptureInterval ||
+				time.Now().Before(captureState.retryAt) {
 				continue
 			}
 
 			// Decode frame
-			img, err := w.vp8FrameToGoCV(sample.Data)
+			attemptCtx, attemptSpan := tracing.Start(ctx, "capture.attempt", "attempt", captureState.totalAttempts+1)
+			img, err := w.decodeFrame(captureState, sample.Data)
 			if err != nil {
+				attemptSpan.RecordError(err)
+				attemptSpan.End()
+				continue
+			}
+
+			// Liveness check before any API submission
+			if captureState.liveness != nil && !captureState.liveness.Verified() {
+				w.observeLiveness(*img, captureState)
+				captureState.mats.Put(*img)
+				attemptSpan.SetAttributes("liveness", true)
+				attemptSpan.End()
+
+				if captureState.liveness.Exhausted() {
+					callLog.Warn("Liveness not confirmed", "frames", captureState.liveness.Frames())
+					w.handleCaptureFailure(userID, state, "liveness_failed", captureState.totalAttempts)
+					return
+				}
 				continue
 			}
 
 			// Detect face
-			hasFace, response := w.detectAndSendFullImage(*img, userID, captureState.totalAttempts+1)
-			img.Close() // CRITICAL: Close immediately
+			hasFace, response := w.detectAndSendFullImage(attemptCtx, *img, userID, captureState.totalAttempts+1, captureState)
+			captureState.mats.Put(*img) // CRITICAL: Return immediately
+			attemptSpan.SetAttributes("face", hasFace, "recognized", response != nil)
+			attemptSpan.End()
 
 			captureState.totalAttempts++
 
+			if captureState.lastErr != nil && w.handleRecognitionError(userID, state, captureState) {
+				return
+			}
+
 			// Handle success
 			if hasFace && response != nil {
 				captureState.lastCaptureTime = time.Now()
 				captureState.successCount++
 
 				if captureState.successCount > 0 {
-					log.Printf("   ✅ RECOGNITION SUCCESS!")
-					w.handleCaptureSuccess(userID, state, response)
+					callLog.Info("Recognition succeeded", "attempt", captureState.totalAttempts)
+					w.recordEvent(userID, CheckinEvent{
+						Outcome:     OutcomeRecognized,
+						Attempts:    captureState.totalAttempts,
+						WFH:         response.IsWFH,
+						Probability: response.Probability,
+					})
+					w.handleCaptureSuccess(userID, state, response, captureState.matchedCrop)
 					return
 				}
 			}
@@ -173,15 +276,76 @@ func (w *WebRTCManager) realtimeFaceDetectionCapture(userID int64, track *webrtc
 // CAPTURE RESULT HANDLERS
 // ============================================================
 
-func (w *WebRTCManager) handleCaptureSuccess(userID int64, state *connectionState, response *models.FaceRecognitionResponse) {
-	log.Println("   🎯 Processing successful checkin...")
+// face is the recognized crop (nil if unknown), shown in the confirmation
+func (w *WebRTCManager) handleCaptureSuccess(userID int64, state *connectionState, response *models.FaceRecognitionResponse, face []byte) {
+	w.recordIdentityResult(userID, "")
+	if w.callMode(userID, response) == ModeCheckout {
+		w.handleCheckout(userID, state, response)
+		return
+	}
+
+	state.logger.Info("Processing successful checkin", "employee", response.GetFullName(), "wfh", response != nil && response.IsWFH)
+	w.setCallStage(userID, CallStageAwaitingLocation)
+	awaitLocation := w.confirmRecognized(userID, state.channelID, response, face, state.logger)
+
+	// Play audio (non-blocking)
+	// Keep the call open so the user can confirm by voice
+	endDelay := 500 * time.Millisecond
+	if w.stt != nil && response != nil {
+		endDelay = w.voiceConfirmWindow()
+	}
+	endCall := func() {
+		go w.endCallAfterDelay(userID, "checkin_success_complete", endDelay)
+	}
+	// Users still have to send their location
+	finish := endCall
+	if awaitLocation {
+		finish = func() {
+			if !w.playStagePrompt(userID, audio.StageSendLocation, endCall) {
+				endCall()
+			}
+		}
+	}
+	// Rendering the greeting can take seconds, the pipeline is stopped
+	// meanwhile. The call ends after successAudioTimeout even if the player
+	// never reports the end of the audio.
+	go func() {
+		played := response != nil && w.playGreeting(userID, response.FirstName, finish)
+		if !played {
+			played = w.playCheckinSuccessAudio(userID, finish)
+		}
+		if !played {
+			finish()
+		}
+	}()
+	w.endCallAfterTimeout(userID, state, successAudioTimeout+endDelay)
+
+	// Wait for audio to stream
+	time.Sleep(500 * time.Millisecond)
+
+	// Stop media pipeline
+	state.logger.Debug("Stopping media pipeline")
+	if state.cancelFunc != nil {
+		state.cancelFunc()
+	}
+
+	state.logger.Info("Success handling complete")
+}
+
+// confirmRecognized sends the outcome of a recognized check-in: the location
+// request, or success for WFH users without location checks. It touches no
+// call, selfies use it too. Reports whether the user still has to send
+// their location.
+func (w *WebRTCManager) confirmRecognized(userID, channelID int64, response *models.FaceRecognitionResponse, face []byte, callLog *slog.Logger) bool {
+	w.rememberShift(userID, response)
+	w.journal(journalEntry{UserID: userID, ChannelID: channelID, Stage: journalRecognized})
 
 	// Send confirmation message with timeout guarantee
 	if response != nil && !response.IsWFH {
 		done := make(chan error, 1)
 		go func() {
-			log.Println("   📧 Sending confirmation...")
-			err := w.SendCheckinConfirmation(state.channelID, userID, response.GetFullName())
+			callLog.Debug("Sending confirmation")
+			err := w.SendCheckinConfirmation(channelID, userID, response.GetFullName(), face)
 			done <- err
 		}()
 
@@ -189,39 +353,76 @@ func (w *WebRTCManager) handleCaptureSuccess(userID int64, state *connectionStat
 		select {
 		case err := <-done:
 			if err != nil {
-				log.Printf("   ❌ Failed to send confirmation: %v", err)
+				callLog.Error("Failed to send confirmation", "err", err)
 			} else {
-				log.Println("   ✅ Confirmation sent")
+				callLog.Info("Confirmation sent")
 			}
 		case <-time.After(5 * time.Second):
-			log.Println("   ⚠️  Confirmation timeout")
+			callLog.Warn("Confirmation timed out")
 		}
 	}
 
-	if response != nil && response.IsWFH {
-		if err := w.SendCheckinSuccess(state.channelID, userID, ""); err != nil {
-			log.Printf("❌ Failed to send success message: %v", err)
+	// WFH users confirm against their registered home location
+	wfhLocation := response != nil && response.IsWFH && w.locationConfig.Enabled
+	if wfhLocation {
+		if err := w.SendWFHConfirmation(channelID, userID); err != nil {
+			callLog.Error("Failed to send WFH confirmation", "err", err)
+		}
+	} else if response != nil && response.IsWFH {
+		if err := w.SendCheckinSuccess(channelID, userID, ""); err != nil {
+			callLog.Error("Failed to send success message", "err", err)
 		}
+		w.recordApproval(userID, channelID, CheckinEvent{WFH: true})
 	}
+	// The pending confirmation takes over from here
+	w.journalDone(userID)
 
-	// Play audio (non-blocking)
-	log.Println("   🎵 Playing success audio...")
-	go w.endCallAfterDelay(userID, "checkin_success_complete", 500*time.Millisecond)
+	return (response != nil && !response.IsWFH) || wfhLocation
+}
 
-	// Wait for audio to stream
-	time.Sleep(500 * time.Millisecond)
+// successAudioTimeout bounds the greeting, success clip and location prompt
+// together
+const successAudioTimeout = 20 * time.Second
+
+// handleRecognitionError reacts to typed backend errors. Returns true when the
+// capture is over.
+func (w *WebRTCManager) handleRecognitionError(userID int64, state *connectionState, cs *captureState) bool {
+	err := cs.lastErr
+	cs.lastErr = nil
+
+	switch {
+	case errors.Is(err, api.ErrAlreadyCheckedIn):
+		if w.callMode(userID, nil) == ModeCheckout {
+			w.handleCheckout(userID, state, nil)
+			return true
+		}
+		state.logger.Info("User already checked in")
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "already_checked_in", Attempts: cs.totalAttempts})
+		if state.cancelFunc != nil {
+			state.cancelFunc()
+		}
+		if err := w.SendCheckinNotice(state.channelID, userID, w.text(userID, "notice.already_checked_in", nil)); err != nil {
+			state.logger.Error("Failed to send message", "err", err)
+		}
+		go w.endCallAfterDelay(userID, "already_checked_in", 500*time.Millisecond)
+		return true
 
-	// Stop media pipeline
-	log.Println("   🛑 Stopping media pipeline...")
-	if state.cancelFunc != nil {
-		state.cancelFunc()
-	}
+	case errors.Is(err, api.ErrRateLimited):
+		wait := api.RetryAfter(err)
+		state.logger.Warn("Rate limited", "retry_in", wait)
+		cs.retryAt = time.Now().Add(wait)
+		cs.totalAttempts-- // Not the user's fault, don't burn an attempt
 
-	log.Println("   ✅ Success handling complete!")
+	case errors.Is(err, api.ErrUnrecognized):
+		state.logger.Info("Face not recognized, trying again")
+	}
+	return false
 }
 
-func (w *WebRTCManager) handleCaptureFailure(userID int64, state *connectionState, reason string) {
-	log.Printf("   ❌ Capture failed: %s", reason)
+func (w *WebRTCManager) handleCaptureFailure(userID int64, state *connectionState, reason string, attempts int) {
+	state.logger.Warn("Capture failed", "reason", reason)
+	w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: reason, Attempts: attempts})
+	w.recordIdentityResult(userID, reason)
 
 	// Cancel context first
 	if state.cancelFunc != nil {
@@ -229,20 +430,15 @@ func (w *WebRTCManager) handleCaptureFailure(userID int64, state *connectionStat
 	}
 
 	// Map reason to message
-	failureMessages := map[string]string{
-		"timeout":      "Hết thời gian chờ",
-		"pli_timeout":  "Không nhận được video",
-		"max_attempts": "Không xác định được danh tính",
-	}
-
-	failureMessage := failureMessages[reason]
-	if failureMessage == "" {
-		failureMessage = "Lỗi không xác định"
+	failureMessage := w.text(userID, "reason.unknown", nil)
+	switch reason {
+	case "timeout", "pli_timeout", "max_attempts", "liveness_failed":
+		failureMessage = w.text(userID, "reason."+reason, nil)
 	}
 
 	// Send failure message
-	if err := w.SendCheckinFailed(state.channelID, userID, failureMessage); err != nil {
-		log.Printf("   ❌ Failed to send message: %v", err)
+	if err := w.SendCheckinFailedWithRetry(state.channelID, userID, failureMessage, RetryCall, false); err != nil {
+		state.logger.Error("Failed to send message", "err", err)
 	}
 	go w.endCallAfterDelay(userID, "checkin_fail_no_audio_config", 500*time.Millisecond)
 
@@ -250,21 +446,194 @@ func (w *WebRTCManager) handleCaptureFailure(userID int64, state *connectionStat
 	// w.playCheckinFailAudio(userID)
 }
 
+// ============================================================
+// SAMPLE QUEUE - The RTP reader never waits for detection
+// ============================================================
+
+// sampleQueueSize is how many samples wait for the capture loop
+const sampleQueueSize = 10
+
+// sampleDrops counts samples dropped because the capture loop was busy
+type sampleDrops struct {
+	delta   atomic.Uint64 // Delta frames refused, detection only uses keyframes
+	evicted atomic.Uint64 // Oldest samples evicted for a newer keyframe
+}
+
+// queueSample queues without blocking the RTP reader, which would back up
+// the samplebuilder. When the queue is full, delta frames are dropped and a
+// keyframe evicts the oldest samples, so detection gets the freshest one.
+func queueSample(samples chan *media.Sample, sample *media.Sample, drops *sampleDrops) {
+	for {
+		select {
+		case samples <- sample:
+			return
+		default:
+		}
+		if !isVP8Keyframe(sample.Data) {
+			drops.delta.Add(1)
+			return
+		}
+		select {
+		case <-samples:
+			drops.evicted.Add(1)
+		default:
+		}
+	}
+}
+
+// SampleDrops returns the samples dropped by ended calls since startup
+func (w *WebRTCManager) SampleDrops() (delta, evicted uint64) {
+	return w.sampleDrops.delta.Load(), w.sampleDrops.evicted.Load()
+}
+
 // ============================================================
 // FACE DETECTION & SUBMISSION
 // ============================================================
 
-func (w *WebRTCManager) detectAndSendFullImage(img gocv.Mat, userId int64, attemptNum int) (bool, *models.FaceRecognitionResponse) {
+func (w *WebRTCManager) detectAndSendFullImage(ctx context.Context, img gocv.Mat, userId int64, attemptNum int, cs *captureState) (bool, *models.FaceRecognitionResponse) {
 	if !w.faceDetector.Config.Enabled || img.Empty() {
 		return false, nil
 	}
 
+	largestFace, faces, found := w.locateFaces(img, cs.detector)
+	if !found {
+		return false, nil
+	}
+	faceCount := len(faces)
+
+	if w.faceDetector.Config.RejectMultipleFaces && faceCount > 1 {
+		cs.logger.Warn("Multiple faces in frame, rejected", "attempt", attemptNum, "max_attempts", w.capture().MaxAttempts, "faces", faceCount)
+		w.promptSingleFace(userId)
+		return false, nil
+	}
+
+	cs.logger.Info("Face detected",
+		"attempt", attemptNum, "max_attempts", w.capture().MaxAttempts,
+		"faces", faceCount, "area", largestFace.Dx()*largestFace.Dy())
+	w.playStagePrompt(userId, audio.StageScanning, nil)
+
+	var quality detector.QualityReport
+	if w.faceDetector.Config.QualityGateEnabled || cs.batch != nil {
+		quality = w.faceDetector.ScoreQuality(img, largestFace)
+	}
+	if w.faceDetector.Config.QualityGateEnabled {
+		if !quality.Passed {
+			cs.logger.Info("Low quality face skipped", "reason", quality.RejectReason, "quality", quality.String())
+			if quality.RejectReason == detector.RejectHeadTurned {
+				w.playStagePrompt(userId, audio.StageLookStraight, nil)
+			}
+			return false, nil
+		}
+		cs.logger.Debug("Quality OK", "quality", quality.String())
+	}
+
+	expandedFace := w.expandAndCenterFace(largestFace, img.Cols(), img.Rows())
+	croppedFace := img.Region(expandedFace)
+	defer croppedFace.Close()
+
+	finalSquare := w.makeSquare(croppedFace, cs.mats)
+	defer func() { cs.mats.Put(finalSquare) }()
+
+	if w.faceDetector.Config.AlignFaces {
+		if aligned, ok := w.faceDetector.AlignFace(finalSquare); ok {
+			cs.mats.Put(finalSquare)
+			finalSquare = aligned
+		}
+	}
+
+	buf := w.bufferPool.Get()
+	defer w.bufferPool.Put(buf)
+
+	if err := w.encodeImageToJPEG(finalSquare, buf); err != nil {
+		cs.logger.Warn("Encode failed", "err", err)
+		return true, nil
+	}
+	jpegImg := buf.Bytes()
+
+	// Sharpness when scored, face size otherwise
+	score := float64(largestFace.Dx() * largestFace.Dy())
+	if w.faceDetector.Config.QualityGateEnabled || cs.batch != nil {
+		score = quality.Sharpness
+	}
+	cs.keepBest(jpegImg, score)
+	w.saveDebugFrame(img, faces, largestFace, userId, attemptNum)
+
+	if cs.batch != nil {
+		cs.batch.Add(jpegImg, quality.Sharpness)
+		cs.logger.Debug("Batched crop", "count", cs.batch.Len(), "batch_size", w.faceDetector.Config.BatchSize)
+		if !cs.batch.Ready() {
+			return true, nil
+		}
+		return true, w.submitBatch(ctx, userId, cs)
+	}
+
+	w.setCallStage(userId, CallStageRecognizing)
+	ctx, span := tracing.Start(ctx, "api.recognize", "attempt", attemptNum, "images", 1)
+	response, err := w.faceDetector.Recognize(ctx, finalSquare, jpegImg, userId, attemptNum)
+	span.RecordError(err)
+	span.End()
+	w.archiveCrop(userId, jpegImg, response != nil)
+	if response != nil {
+		cs.matchedCrop = append(cs.matchedCrop[:0], jpegImg...)
+	} else {
+		w.setCallStage(userId, CallStageDetecting)
+	}
+
+	cs.lastErr = err
+	return true, response
+}
+
+// submitBatch sends all pending batched crops in a single API call
+func (w *WebRTCManager) submitBatch(ctx context.Context, userId int64, cs *captureState) *models.FaceRecognitionResponse {
+	imgs := cs.batch.Take()
+	w.setCallStage(userId, CallStageRecognizing)
+
+	ctx, span := tracing.Start(ctx, "api.recognize", "attempt", cs.totalAttempts+1, "images", len(imgs))
+	response, err := w.faceDetector.SubmitImagesToAPI(ctx, imgs, userId, cs.totalAttempts+1)
+	span.RecordError(err)
+	span.End()
+	for _, img := range imgs {
+		w.archiveCrop(userId, img, response != nil)
+	}
+
+	cs.lastErr = err
+	if err != nil || response == nil {
+		w.setCallStage(userId, CallStageDetecting)
+	}
+	if err != nil {
+		cs.logger.Warn("Batch submission failed", "err", err)
+		return nil
+	}
+	if response != nil {
+		// The batch is matched as a whole, show its sharpest crop
+		cs.matchedCrop = append(cs.matchedCrop[:0], cs.bestCrop...)
+	}
+	return response
+}
+
+// observeLiveness feeds one frame to the liveness tracker. Frames without a
+// valid face still count toward the frame budget.
+func (w *WebRTCManager) observeLiveness(img gocv.Mat, cs *captureState) {
+	face, _, _ := w.locateFace(img, cs.detector)
+	cs.liveness.Observe(img, face)
+}
+
+// locateFace detects faces on a scaled-down copy and returns the largest valid
+// face in original image coordinates, together with the number of valid faces.
+// faceDetector overrides w.detector when not nil.
+func (w *WebRTCManager) locateFace(img gocv.Mat, faceDetector detector.Detector) (image.Rectangle, int, bool) {
+	face, faces, found := w.locateFaces(img, faceDetector)
+	return face, len(faces), found
+}
+
+// locateFaces is locateFace returning every valid face
+func (w *WebRTCManager) locateFaces(img gocv.Mat, faceDetector detector.Detector) (image.Rectangle, []image.Rectangle, bool) {
 	origW := img.Cols()
 	origH := img.Rows()
 
 	if origW == 0 || origH == 0 {
-		log.Printf("   ⚠️  Invalid image dimensions: %dx%d", origW, origH)
-		return false, nil
+		logger.Warn("Invalid image dimensions", "width", origW, "height", origH)
+		return image.Rectangle{}, nil, false
 	}
 
 	var detectionImg gocv.Mat
@@ -275,28 +644,30 @@ func (w *WebRTCManager) detectAndSendFullImage(img gocv.Mat, userId int64, attem
 	if w.dimensionConfig.SkipDetectionResize && origW <= maxDetectionWidth {
 		detectionImg = img
 		needResize = false
-		log.Printf("   📐 Detection: using decoded size %dx%d (resize skipped)", origW, origH)
+		logger.Debug("Detection on decoded size (resize skipped)", "width", origW, "height", origH)
 	} else {
 		targetW := w.dimensionConfig.DetectionWidth
 		scale = float64(targetW) / float64(origW)
 		targetH := int(float64(origH) * scale)
 
-		detectionImg = gocv.NewMat()
-		defer detectionImg.Close()
-		gocv.Resize(img, &detectionImg, image.Pt(targetW, targetH), 0, 0, gocv.InterpolationLinear)
+		detectionImg = matpool.Get()
+		defer func() { matpool.Put(detectionImg) }()
+		w.faceDetector.Resize(img, &detectionImg, image.Pt(targetW, targetH))
 
-		log.Printf("   📐 Detection: %dx%d → %dx%d (scale=%.2f)",
-			origW, origH, targetW, targetH, scale)
+		logger.Debug("Detection on resized frame",
+			"width", origW, "height", origH, "target_width", targetW, "target_height", targetH, "scale", scale)
 	}
 
-	graySmall := gocv.NewMat()
-	defer graySmall.Close()
-	gocv.CvtColor(detectionImg, &graySmall, gocv.ColorBGRToGray)
+	if faceDetector == nil {
+		w.mu.RLock()
+		faceDetector = w.detector
+		w.mu.RUnlock()
+	}
 
-	rectsSmall := w.faceDetector.Classifier.DetectMultiScale(graySmall)
+	rectsSmall := faceDetector.Detect(detectionImg)
 
 	if len(rectsSmall) == 0 {
-		return false, nil
+		return image.Rectangle{}, nil, false
 	}
 
 	var candidateRects []image.Rectangle
@@ -314,28 +685,11 @@ func (w *WebRTCManager) detectAndSendFullImage(img gocv.Mat, userId int64, attem
 
 	largestFace, found := w.findLargestValidFace(candidateRects)
 	if !found {
-		log.Printf("   ⚠️  All faces too small (min: %dpx)", w.faceDetector.Config.MinFaceSize)
-		return false, nil
-	}
-
-	log.Printf("   👤 [Attempt %d/%d] Detected %d face(s), chosen area=%d",
-		attemptNum, w.captureConfig.MaxAttempts, len(candidateRects), largestFace.Dx()*largestFace.Dy())
-
-	expandedFace := w.expandAndCenterFace(largestFace, origW, origH)
-	croppedFace := img.Region(expandedFace)
-	defer croppedFace.Close()
-
-	finalSquare := w.makeSquare(croppedFace)
-	defer finalSquare.Close()
-
-	base64Img, err := w.encodeImageToBase64(finalSquare)
-	if err != nil {
-		log.Printf("   ⚠️  Encode failed: %v", err)
-		return true, nil
+		logger.Debug("All faces too small", "min_face_size", w.faceDetector.Config.MinFaceSize)
+		return image.Rectangle{}, nil, false
 	}
 
-	response, _ := w.faceDetector.SubmitSingleImageToAPI(base64Img, userId, attemptNum)
-	return true, response
+	return largestFace, w.validFaces(candidateRects), true
 }
 
 func (w *WebRTCManager) findLargestValidFace(rects []image.Rectangle) (image.Rectangle, bool) {
@@ -354,3 +708,38 @@ func (w *WebRTCManager) findLargestValidFace(rects []image.Rectangle) (image.Rec
 
 	return largestFace, maxArea > 0
 }
+
+func (w *WebRTCManager) validFaces(rects []image.Rectangle) []image.Rectangle {
+	var valid []image.Rectangle
+	for _, rect := range rects {
+		if rect.Dx() >= w.faceDetector.Config.MinFaceSize &&
+			rect.Dy() >= w.faceDetector.Config.MinFaceSize {
+			valid = append(valid, rect)
+		}
+	}
+	return valid
+}
+
+// promptSingleFace asks the caller to be alone in frame, once per call
+func (w *WebRTCManager) promptSingleFace(userID int64) {
+	state, exists := w.connections.Get(userID)
+
+	if !exists {
+		return
+	}
+
+	state.mu.Lock()
+	alreadyPrompted := state.multiFacePrompted
+	state.multiFacePrompted = true
+	state.mu.Unlock()
+
+	if alreadyPrompted {
+		return
+	}
+
+	go func() {
+		if err := w.SendCheckinNotice(state.channelID, userID, w.text(userID, "notice.multiple_faces", nil)); err != nil {
+			state.logger.Error("Failed to send multiple faces notice", "err", err)
+		}
+	}()
+}
@@ -0,0 +1,257 @@
+package webrtc
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/audio"
+	"mezon-checkin-bot/internal/audit"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/tracing"
+	"mezon-checkin-bot/models"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// CHECK-OUT - Same call pipeline, clock-out instead of check-in
+// ============================================================
+
+// Call modes
+const (
+	ModeCheckin  = "checkin"
+	ModeCheckout = "checkout"
+)
+
+// checkoutRequestTTL is how long a !checkout request waits for the call
+const checkoutRequestTTL = 10 * time.Minute
+
+// SetAttendanceConfig sets which attendance actions calls perform
+func (w *WebRTCManager) SetAttendanceConfig(cfg AttendanceConfig) error {
+	if cfg.CheckoutAfter != "" {
+		if _, err := time.Parse("15:04", cfg.CheckoutAfter); err != nil {
+			return fmt.Errorf("invalid check-out time %q: %w", cfg.CheckoutAfter, err)
+		}
+	}
+	if err := validateWorkingHours(cfg.WorkStart, cfg.WorkEnd, cfg.OvertimeCutoff); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.attendance = cfg
+	w.mu.Unlock()
+
+	logger.Info("Attendance configured",
+		"checkout", cfg.CheckoutEnabled, "checkout_after", cfg.CheckoutAfter, "breaks", cfg.BreaksEnabled,
+		"work_start", cfg.WorkStart, "work_end", cfg.WorkEnd, "late_grace", cfg.LateGrace,
+		"overtime", cfg.OvertimeEnabled, "overtime_cutoff", cfg.OvertimeCutoff)
+	return nil
+}
+
+// callMode decides whether a recognized call checks the user in or out.
+// response may be nil when the backend rejected the recognition.
+func (w *WebRTCManager) callMode(userID int64, response *models.FaceRecognitionResponse) string {
+	w.mu.Lock()
+	cfg := w.attendance
+	requestedAt, requested := w.checkoutRequests[userID]
+	delete(w.checkoutRequests, userID)
+	w.mu.Unlock()
+
+	if !cfg.CheckoutEnabled {
+		return ModeCheckin
+	}
+	if requested && time.Since(requestedAt) < checkoutRequestTTL {
+		return ModeCheckout
+	}
+
+	now := time.Now().In(w.userZone(userID))
+	if response.IsClockedIn() && clockedInToday(response.LastClockEventDTO.StartTime, now) {
+		return ModeCheckout
+	}
+	if cfg.CheckoutAfter != "" && now.Format("15:04") >= cfg.CheckoutAfter {
+		return ModeCheckout
+	}
+	return ModeCheckin
+}
+
+// clockedInToday reports whether an open clock event started today. Unknown
+// formats count as today: the backend knows better than our parser.
+func clockedInToday(startTime string, now time.Time) bool {
+	start, ok := parseBackendTime(startTime, now.Location())
+	if !ok {
+		return true
+	}
+	return start.Format("2006-01-02") == now.Format("2006-01-02")
+}
+
+// parseBackendTime parses a clock event timestamp. Times without a zone are
+// taken to be in loc.
+func parseBackendTime(value string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.In(loc), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ============================================================
+// CHECK-OUT HANDLING
+// ============================================================
+
+// handleCheckout clocks the user out and ends the call. response is nil when
+// the backend only told us the user was already checked in.
+func (w *WebRTCManager) handleCheckout(userID int64, state *connectionState, response *models.FaceRecognitionResponse) {
+	state.logger.Info("Processing check-out", "employee", response.GetFullName())
+	w.setCallStage(userID, CallStageCheckingOut)
+
+	// Stop the media pipeline, the face is no longer needed
+	if state.cancelFunc != nil {
+		state.cancelFunc()
+	}
+
+	if !w.checkOut(userID, state.channelID, response, state.logger) {
+		w.playCheckinFailAudio(userID)
+		return
+	}
+
+	endCall := func() {
+		go w.endCallAfterDelay(userID, "checkout_success_complete", 500*time.Millisecond)
+	}
+	if !w.playCheckoutSuccessAudio(userID, endCall) {
+		endCall()
+	}
+	w.endCallAfterTimeout(userID, state, successAudioTimeout)
+}
+
+// checkOut clocks the user out and sends the outcome. It touches no call,
+// selfies use it too. Reports whether the user was clocked out.
+func (w *WebRTCManager) checkOut(userID, channelID int64, response *models.FaceRecognitionResponse, callLog *slog.Logger) bool {
+	if err := w.clockOut(userID); err != nil {
+		callLog.Error("Check-out failed", "err", err)
+		if err := w.SendCheckinFailed(channelID, userID, w.text(userID, "reason.checkout_failed", nil)); err != nil {
+			callLog.Error("Failed to send message", "err", err)
+		}
+		return false
+	}
+
+	name := strings.TrimSpace(response.GetFullName())
+	if err := w.SendCheckoutSuccess(channelID, userID, name); err != nil {
+		callLog.Error("Failed to send check-out message", "err", err)
+	}
+
+	var officeID string
+	if last, ok := w.LastConfirmedLocation(userID); ok && time.Since(last.At) < 24*time.Hour {
+		officeID = last.OfficeID
+	}
+	w.offerOvertime(userID, channelID, officeID, ModeCheckout, time.Now())
+	return true
+}
+
+// clockOut calls the clock-out endpoint, queueing it while the backend is down
+func (w *WebRTCManager) clockOut(userID int64) error {
+	ctx, span := tracing.Start(w.traceContext(userID), "api.clock_out")
+	defer span.End()
+
+	w.mu.RLock()
+	queue := w.queue
+	w.mu.RUnlock()
+
+	reqBody := models.ClockOut{UserId: userID}
+
+	var body []byte
+	var statusCode int
+	var err error
+	if queue != nil {
+		key := fmt.Sprintf("clock-out:%d:%s", userID, time.Now().Format("2006-01-02"))
+		body, statusCode, err = queue.SubmitOrQueue(ctx, key, models.APICheckOut, reqBody)
+		w.auditRequest(audit.ActionClockOut, userID, statusCode, err)
+		if errors.Is(err, api.ErrQueued) {
+			span.SetAttributes("queued", true)
+			w.recordEvent(userID, CheckinEvent{Outcome: OutcomeCheckedOut})
+			return nil
+		}
+	} else {
+		body, statusCode, err = w.apiClient.SendRequestContext(ctx, reqBody, models.APICheckOut)
+		w.auditRequest(audit.ActionClockOut, userID, statusCode, err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "checkout_error"})
+		return err
+	}
+
+	w.apiClient.LogResponse(body, statusCode)
+
+	span.SetAttributes("status", statusCode)
+	if apiErr := api.ParseError(body, statusCode); apiErr != nil {
+		span.RecordError(apiErr)
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "checkout_rejected"})
+		return apiErr
+	}
+
+	w.recordEvent(userID, CheckinEvent{Outcome: OutcomeCheckedOut})
+	w.clearBreak(userID)
+	return nil
+}
+
+// SendCheckoutSuccess tells the user they are checked out
+func (w *WebRTCManager) SendCheckoutSuccess(channelID int64, userID int64, userName string) error {
+	if w.dmManager == nil {
+		return fmt.Errorf("DM manager not initialized")
+	}
+
+	content := client.BuildCheckoutSuccessMessage(w.tr(userID), userName, time.Now().In(w.userZone(userID)))
+	return w.sendDM("checkout_success", channelID, userID, content)
+}
+
+// playCheckoutSuccessAudio plays the check-out prompt, falling back to the
+// check-in success sound. Returns false if nothing was queued, in which case
+// onFinish is not called.
+func (w *WebRTCManager) playCheckoutSuccessAudio(userID int64, onFinish func()) bool {
+	if !w.audioConfig.Enabled {
+		return false
+	}
+
+	state, exists := w.connections.Get(userID)
+
+	if !exists || state.audioPlayer == nil {
+		return false
+	}
+
+	path, ok := w.audioLibrary.GetLocale(w.locales.Resolve(userID), "checkout_success")
+	if !ok {
+		return w.playCheckinSuccessAudio(userID, onFinish)
+	}
+
+	state.audioPlayer.PlayNow(audio.AudioItem{
+		FilePath: path,
+		Name:     "checkout_success",
+		Loop:     false,
+		OnFinish: onFinish,
+	})
+	return true
+}
+
+// ============================================================
+// CHECK-OUT COMMAND
+// ============================================================
+
+// handleCheckoutCommand makes the user's next call a check-out
+func (w *WebRTCManager) handleCheckoutCommand(userID int64, args []string) models.ChannelMessageContent {
+	w.mu.Lock()
+	enabled := w.attendance.CheckoutEnabled
+	if enabled {
+		w.checkoutRequests[userID] = time.Now()
+	}
+	w.mu.Unlock()
+
+	if !enabled {
+		return client.BuildErrorMessage("❌ Check-out chưa được bật", "Vui lòng liên hệ quản trị viên.")
+	}
+
+	logger.Info("Check-out requested", "user_id", userID)
+	return client.BuildSimpleTextMessage(fmt.Sprintf(
+		"Cuộc gọi tiếp theo trong %d phút sẽ được dùng để check-out.", int(checkoutRequestTTL.Minutes())))
+}
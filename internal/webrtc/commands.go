@@ -0,0 +1,347 @@
+package webrtc
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/audit"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/mezon-protobuf/go/rtapi"
+	"mezon-checkin-bot/models"
+	"strconv"
+	"strings"
+)
+
+// ============================================================
+// CHAT COMMANDS
+// ============================================================
+
+const officeUsage = "Cách dùng:\n" +
+	"!office list\n" +
+	"!office add <id> <lat> <lon> <bán kính m> <tên>\n" +
+	"!office disable <id>\n" +
+	"!office enable <id>\n" +
+	"!office tz <id> <timezone, VD: Asia/Ho_Chi_Minh>\n" +
+	"!office hours <id> <bắt đầu> <kết thúc> [mốc làm thêm], VD: 08:30 17:30 18:00\n" +
+	"!office qr <id> <channel id | off>\n" +
+	"!office history <id> [số dòng]\n" +
+	"!office export\n" +
+	"!office import"
+
+const (
+	defaultOfficeHistory = 10
+	maxOfficeHistory     = 50
+)
+
+// SetAdmins sets the users allowed to run admin commands
+func (w *WebRTCManager) SetAdmins(userIDs []int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.admins = make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		w.admins[id] = true
+	}
+	logger.Info("Admins configured", "count", len(w.admins))
+}
+
+func (w *WebRTCManager) isAdmin(userID int64) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.admins[userID]
+}
+
+func (w *WebRTCManager) SetupCommandHandler() {
+	logger.Info("Setting up command handler")
+
+	w.onActive("command_received", func(data interface{}) {
+		w.handleCommandEvent(data)
+	})
+	w.onOwned("text_message_received", func(data interface{}) {
+		w.handleTextMessageEvent(data)
+	})
+	w.onActive("image_message_received", func(data interface{}) {
+		w.handleImageMessageEvent(data)
+	})
+	w.onOwned("message_button_clicked", func(data interface{}) {
+		w.handleButtonClickEvent(data)
+	})
+}
+
+func (w *WebRTCManager) handleCommandEvent(data interface{}) {
+	eventMap, ok := data.(map[string]interface{})
+	if !ok {
+		logger.Error("Invalid command event data type")
+		return
+	}
+
+	userID, _ := eventMap["user_id"].(int64)
+	channelID, _ := eventMap["channel_id"].(int64)
+	command, _ := eventMap["command"].(string)
+	args, _ := eventMap["args"].([]string)
+
+	if userID == 0 || channelID == 0 {
+		logger.Warn("Missing user_id or channel_id in command event")
+		return
+	}
+
+	// Commands open to every user
+	userHandlers := map[string]func(int64, []string) models.ChannelMessageContent{
+		"checkout": w.handleCheckoutCommand,
+		"break":    w.handleBreakCommand,
+		"wfh":      w.handleWFHCommand,
+		"leave":    w.handleLeaveCommand,
+		"announce": w.handleAnnounceCommand,
+	}
+	if handler, exists := userHandlers[command]; exists {
+		w.replyCommand(channelID, userID, handler(userID, args))
+		return
+	}
+
+	handlers := map[string]func([]string) models.ChannelMessageContent{
+		"office": func(args []string) models.ChannelMessageContent {
+			return w.handleOfficeCommand(userID, args)
+		},
+		"review":  w.handleReviewCommand,
+		"home":    w.handleHomeCommand,
+		"archive": w.handleArchiveCommand,
+		"audit":   w.handleAuditCommand,
+		"export":  w.handleExportCommand,
+	}
+
+	handler, exists := handlers[command]
+	if !exists {
+		return
+	}
+	commandLine := strings.TrimSpace("!" + command + " " + strings.Join(args, " "))
+	if !w.isAdmin(userID) {
+		logger.Warn("User is not allowed to run command", "user_id", userID, "command", command)
+		w.audit(userID, audit.ActionAdminDenied, "", commandLine)
+		w.replyCommand(channelID, userID, client.BuildErrorMessage("⛔ Không có quyền", "Lệnh này chỉ dành cho quản trị viên."))
+		return
+	}
+	w.audit(userID, audit.ActionAdminCommand, "", commandLine)
+	w.replyCommand(channelID, userID, handler(args))
+}
+
+// handleButtonClickEvent routes message button clicks by button ID
+func (w *WebRTCManager) handleButtonClickEvent(data interface{}) {
+	clicked, ok := data.(*rtapi.MessageButtonClicked)
+	if !ok {
+		logger.Error("Invalid button click event data type")
+		return
+	}
+
+	buttonID := clicked.GetButtonId()
+	if w.Standby() && !strings.HasPrefix(buttonID, locationButtonPrefix) {
+		return // A standby only answers the confirmations it holds
+	}
+	switch {
+	case buttonID == RetryButtonID:
+		w.handleRetryClick(clicked)
+	case strings.HasPrefix(buttonID, escalationButtonPrefix):
+		w.handleEscalationClick(clicked)
+	case strings.HasPrefix(buttonID, overtimeButtonPrefix):
+		w.handleOvertimeClick(clicked)
+	case strings.HasPrefix(buttonID, locationButtonPrefix):
+		w.handleLocationClick(clicked)
+	}
+}
+
+func (w *WebRTCManager) replyCommand(channelID, userID int64, content models.ChannelMessageContent) {
+	if w.dmManager == nil {
+		return
+	}
+	if err := w.dmManager.SendDM(channelID, userID, content); err != nil {
+		logger.Error("Failed to send command reply", "user_id", userID, "err", err)
+	}
+}
+
+// ============================================================
+// OFFICE COMMAND
+// ============================================================
+
+func (w *WebRTCManager) handleOfficeCommand(actor int64, args []string) models.ChannelMessageContent {
+	if len(args) == 0 {
+		return client.BuildSimpleTextMessage(officeUsage)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		return client.BuildSimpleTextMessage(formatOfficeList(w.locationConfig.AllOffices()))
+
+	case "add":
+		office, err := parseOfficeArgs(args[1:])
+		if err != nil {
+			return client.BuildErrorMessage("❌ Thêm văn phòng thất bại", err.Error()+"\n\n"+officeUsage)
+		}
+		if err := w.locationConfig.AddOffice(office, actor); err != nil {
+			return client.BuildErrorMessage("❌ Thêm văn phòng thất bại", err.Error())
+		}
+		logger.Info("Office added", "office", office.ID, "lat", office.Latitude, "lon", office.Longitude, "radius_m", office.RadiusMeters)
+		return client.BuildSuccessMessage("✅ Đã thêm văn phòng", fmt.Sprintf("%s - %s", office.ID, office.Name))
+
+	case "disable", "enable":
+		if len(args) < 2 {
+			return client.BuildSimpleTextMessage(officeUsage)
+		}
+		enabled := strings.ToLower(args[0]) == "enable"
+		if err := w.locationConfig.SetOfficeEnabled(args[1], enabled, actor); err != nil {
+			return client.BuildErrorMessage("❌ Cập nhật văn phòng thất bại", err.Error())
+		}
+		logger.Info("Office updated", "office", args[1], "enabled", enabled)
+		status := "tắt"
+		if enabled {
+			status = "bật"
+		}
+		return client.BuildSuccessMessage("✅ Đã cập nhật văn phòng", fmt.Sprintf("Đã %s văn phòng %s", status, args[1]))
+
+	case "tz":
+		if len(args) < 3 {
+			return client.BuildSimpleTextMessage(officeUsage)
+		}
+		if err := w.locationConfig.SetOfficeTimezone(args[1], args[2], actor); err != nil {
+			return client.BuildErrorMessage("❌ Cập nhật văn phòng thất bại", err.Error())
+		}
+		logger.Info("Office updated", "office", args[1], "timezone", args[2])
+		return client.BuildSuccessMessage("✅ Đã cập nhật văn phòng", fmt.Sprintf("Múi giờ của %s: %s", args[1], args[2]))
+
+	case "hours":
+		if len(args) < 4 {
+			return client.BuildSimpleTextMessage(officeUsage)
+		}
+		var cutoff string
+		if len(args) > 4 {
+			cutoff = args[4]
+		}
+		if err := w.locationConfig.SetOfficeHours(args[1], args[2], args[3], cutoff, actor); err != nil {
+			return client.BuildErrorMessage("❌ Cập nhật văn phòng thất bại", err.Error())
+		}
+		logger.Info("Office updated", "office", args[1], "work_start", args[2], "work_end", args[3], "overtime_cutoff", cutoff)
+		return client.BuildSuccessMessage("✅ Đã cập nhật văn phòng", fmt.Sprintf("Giờ làm việc của %s: %s - %s", args[1], args[2], args[3]))
+
+	case "qr":
+		if len(args) < 3 {
+			return client.BuildSimpleTextMessage(officeUsage)
+		}
+		var qrChannelID int64
+		if !strings.EqualFold(args[2], "off") {
+			id, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil || id <= 0 {
+				return client.BuildErrorMessage("❌ Cập nhật văn phòng thất bại", fmt.Sprintf("channel id không hợp lệ: %s", args[2]))
+			}
+			qrChannelID = id
+		}
+		if err := w.locationConfig.SetOfficeQRChannel(args[1], qrChannelID, actor); err != nil {
+			return client.BuildErrorMessage("❌ Cập nhật văn phòng thất bại", err.Error())
+		}
+		logger.Info("Office updated", "office", args[1], "qr_channel_id", qrChannelID)
+		if qrChannelID == 0 {
+			return client.BuildSuccessMessage("✅ Đã cập nhật văn phòng", fmt.Sprintf("Đã tắt mã QR của %s", args[1]))
+		}
+		return client.BuildSuccessMessage("✅ Đã cập nhật văn phòng", fmt.Sprintf("Mã QR của %s sẽ được đăng vào kênh %d", args[1], qrChannelID))
+
+	case "history":
+		if len(args) < 2 {
+			return client.BuildSimpleTextMessage(officeUsage)
+		}
+		limit := defaultOfficeHistory
+		if len(args) > 2 {
+			if n, err := strconv.Atoi(args[2]); err == nil && n > 0 {
+				limit = min(n, maxOfficeHistory)
+			}
+		}
+		versions, err := w.locationConfig.OfficeHistory(args[1], limit)
+		if err != nil {
+			return client.BuildErrorMessage("❌ Không đọc được lịch sử văn phòng", err.Error())
+		}
+		return client.BuildSimpleTextMessage(w.formatOfficeHistory(args[1], versions))
+
+	case "export":
+		if err := w.locationConfig.ExportOffices(); err != nil {
+			return client.BuildErrorMessage("❌ Xuất văn phòng thất bại", err.Error())
+		}
+		logger.Info("Offices exported", "path", w.locationConfig.OfficesFilePath)
+		return client.BuildSuccessMessage("✅ Đã xuất văn phòng", "Đã ghi danh sách văn phòng vào "+w.locationConfig.OfficesFilePath)
+
+	case "import":
+		changed, err := w.locationConfig.ImportOffices(actor)
+		if err != nil {
+			return client.BuildErrorMessage("❌ Nhập văn phòng thất bại", err.Error())
+		}
+		logger.Info("Offices imported", "path", w.locationConfig.OfficesFilePath, "changed", changed)
+		return client.BuildSuccessMessage("✅ Đã nhập văn phòng",
+			fmt.Sprintf("%d văn phòng được cập nhật từ %s", changed, w.locationConfig.OfficesFilePath))
+
+	default:
+		return client.BuildSimpleTextMessage(officeUsage)
+	}
+}
+
+func parseOfficeArgs(args []string) (Office, error) {
+	if len(args) < 5 {
+		return Office{}, fmt.Errorf("thiếu tham số")
+	}
+
+	lat, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return Office{}, fmt.Errorf("vĩ độ không hợp lệ: %s", args[1])
+	}
+	lon, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return Office{}, fmt.Errorf("kinh độ không hợp lệ: %s", args[2])
+	}
+	radius, err := strconv.ParseFloat(args[3], 64)
+	if err != nil || radius <= 0 {
+		return Office{}, fmt.Errorf("bán kính không hợp lệ: %s", args[3])
+	}
+
+	return Office{
+		ID:           strings.ToUpper(args[0]),
+		Name:         strings.Join(args[4:], " "),
+		Latitude:     lat,
+		Longitude:    lon,
+		RadiusMeters: radius,
+		Enabled:      true,
+	}, nil
+}
+
+func (w *WebRTCManager) formatOfficeHistory(id string, versions []OfficeVersion) string {
+	if len(versions) == 0 {
+		return fmt.Sprintf("Chưa có lịch sử thay đổi của văn phòng %s.", id)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Lịch sử văn phòng %s:\n", strings.ToUpper(id))
+	for _, version := range versions {
+		actor := "bot"
+		if version.ChangedBy != 0 {
+			actor = w.userLabel(version.ChangedBy)
+		}
+		fmt.Fprintf(&b, "v%d %s - %s - %s\n", version.Version,
+			version.At.In(Office{}.Zone()).Format("02/01/2006 15:04"), actor, version.Change)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatOfficeList(offices []Office) string {
+	if len(offices) == 0 {
+		return "Chưa có văn phòng nào."
+	}
+
+	var b strings.Builder
+	for _, office := range offices {
+		status := "✅"
+		if !office.Enabled {
+			status = "⛔"
+		}
+		fmt.Fprintf(&b, "%s %s - %s (%.6f, %.6f) - %.0fm - %s",
+			status, office.ID, office.Name, office.Latitude, office.Longitude, office.RadiusMeters, office.Zone())
+		if office.WorkStart != "" {
+			fmt.Fprintf(&b, " - %s-%s", office.WorkStart, office.WorkEnd)
+		}
+		if office.QRChannelID != 0 {
+			b.WriteString(" - QR")
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -36,6 +36,7 @@ func DefaultCaptureConfig() CaptureConfig {
 		CaptureInterval: 1 * time.Second,
 		MaxAttempts:     5,
 		SampleBufferMax: 128,
+		TypingInterval:  3 * time.Second,
 	}
 }
 
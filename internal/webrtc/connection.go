@@ -1,7 +1,7 @@
 package webrtc
 
 import (
-	"log"
+	"log/slog"
 	"mezon-checkin-bot/models"
 	"time"
 )
@@ -25,22 +25,33 @@ func (cs *connectionState) closeAudioStop() {
 	}
 }
 
+// callLogger returns the per-call logger of the user's connection, or the
+// package logger tagged with user_id (and call_id while the check-in is still
+// open, e.g. awaiting the location reply) when the call has ended
+func (w *WebRTCManager) callLogger(userID int64) *slog.Logger {
+	state, exists := w.connections.Get(userID)
+
+	if exists && state.logger != nil {
+		return state.logger
+	}
+	if callID := w.callID(userID); callID != "" {
+		return logger.With("user_id", userID, "call_id", callID)
+	}
+	return logger.With("user_id", userID)
+}
+
 // ============================================================
 // CONNECTION CLEANUP
 // ============================================================
 
 func (w *WebRTCManager) cleanupConnection(userID int64) {
-	w.mu.Lock()
-	state, exists := w.connections[userID]
+	state, exists := w.connections.Delete(userID)
 	if !exists {
-		w.mu.Unlock()
 		return
 	}
-	delete(w.connections, userID)
-	w.mu.Unlock()
 
 	state.cleanupOnce.Do(func() {
-		log.Printf("🧹 Cleaning up %d", userID)
+		state.logger.Info("Cleaning up connection")
 
 		// 1. Cancel context (stops goroutines)
 		if state.cancelFunc != nil {
@@ -56,7 +67,7 @@ func (w *WebRTCManager) cleanupConnection(userID int64) {
 		// 4. Close peer connection
 		if state.pc != nil {
 			if err := state.pc.Close(); err != nil {
-				log.Printf("   ⚠️  PC close: %v", err)
+				state.logger.Warn("Peer connection close failed", "err", err)
 			}
 		}
 
@@ -68,10 +79,16 @@ func (w *WebRTCManager) cleanupConnection(userID int64) {
 			models.WebrtcSDPQuit,
 			"",
 		); err != nil {
-			log.Printf("   ⚠️  Quit signal: %v", err)
+			state.logger.Warn("Quit signal failed", "err", err)
 		}
 
-		log.Printf("   ✅ Cleanup complete")
+		// 6. End the call's share of the check-in trace
+		w.releaseTrace(userID, state.trace)
+
+		// 7. Check nothing the call started outlives it
+		w.verifyCallGoroutines(state)
+
+		state.logger.Info("Cleanup complete")
 	})
 }
 
@@ -79,22 +96,33 @@ func (w *WebRTCManager) cleanupConnection(userID int64) {
 // DELAYED CALL END
 // ============================================================
 
+// endCallAfterTimeout ends the call once timeout passes unless it already
+// ended, for callbacks that might never fire. A new call by the user is
+// left alone.
+func (w *WebRTCManager) endCallAfterTimeout(userID int64, state *connectionState, timeout time.Duration) {
+	time.AfterFunc(timeout, func() {
+		current, _ := w.connections.Get(userID)
+		if current == state {
+			w.endCallAfterDelay(userID, "success_audio_timeout", 0)
+		}
+	})
+}
+
 func (w *WebRTCManager) endCallAfterDelay(userID int64, reason string, delay time.Duration) {
-	log.Printf("📞 Scheduling call end for user %d (reason: %s, delay: %v)", userID, reason, delay)
+	callLog := w.callLogger(userID)
+	callLog.Info("Scheduling call end", "reason", reason, "delay", delay)
 
 	time.Sleep(delay)
 
-	w.mu.RLock()
-	state, exists := w.connections[userID]
-	w.mu.RUnlock()
+	state, exists := w.connections.Get(userID)
 
 	if !exists {
-		log.Printf("   ⚠️  Connection already cleaned up")
+		callLog.Debug("Connection already cleaned up")
 		return
 	}
 
 	state.endCallOnce.Do(func() {
-		log.Printf("   ✅ Ending call for user %d", userID)
+		callLog.Info("Ending call", "reason", reason)
 		w.cleanupConnection(userID)
 	})
 }
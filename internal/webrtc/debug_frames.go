@@ -0,0 +1,103 @@
+package webrtc
+
+import (
+	"fmt"
+	"image"
+	"mezon-checkin-bot/internal/encryption"
+	"mezon-checkin-bot/internal/matpool"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// DEBUG FRAMES - The full frame of each recognition attempt,
+// kept to debug the pipeline. Bystanders' faces are blurred.
+// ============================================================
+
+// DebugFramesConfig saves attempt frames as JPEG. Off unless enabled: the
+// frames are biometric data.
+type DebugFramesConfig struct {
+	Enabled   bool
+	Dir       string              // Default the manager's output directory
+	BlurFaces bool                // Blur every detected face except the one submitted
+	Keys      *encryption.Keyring // Seals the frames (.jpg.enc) when set, read with: bot decrypt <file>
+}
+
+// SetDebugFrames turns debug frame saving on or off
+func (w *WebRTCManager) SetDebugFrames(config DebugFramesConfig) error {
+	if config.Dir == "" {
+		config.Dir = w.outputDir
+	}
+	if config.Enabled {
+		if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+			return fmt.Errorf("create debug frames dir: %w", err)
+		}
+		logger.Warn("Saving debug frames", "dir", config.Dir, "blur_faces", config.BlurFaces, "encrypted", config.Keys != nil)
+	}
+	w.mu.Lock()
+	w.debugFrames = config
+	w.mu.Unlock()
+	return nil
+}
+
+// saveDebugFrame writes the frame with the faces other than submitted
+// blurred when configured
+func (w *WebRTCManager) saveDebugFrame(img gocv.Mat, faces []image.Rectangle, submitted image.Rectangle, userID int64, attempt int) {
+	w.mu.RLock()
+	config := w.debugFrames
+	w.mu.RUnlock()
+	if !config.Enabled {
+		return
+	}
+
+	frame := matpool.Get()
+	defer matpool.Put(frame)
+	img.CopyTo(&frame)
+	if config.BlurFaces {
+		for _, face := range faces {
+			if face != submitted {
+				blurRegion(&frame, face)
+			}
+		}
+	}
+
+	buf := w.bufferPool.Get()
+	defer w.bufferPool.Put(buf)
+	if err := w.encodeImageToJPEG(frame, buf); err != nil {
+		logger.Warn("Failed to encode debug frame", "user_id", userID, "err", err)
+		return
+	}
+	name := fmt.Sprintf("%d_%s_%d.jpg", userID, time.Now().Format("20060102-150405.000"), attempt)
+	data := buf.Bytes()
+	if config.Keys != nil {
+		// The file name is authenticated like archived crops
+		name += ".enc"
+		sealed, err := config.Keys.Seal(data, []byte(name))
+		if err != nil {
+			logger.Warn("Failed to encrypt debug frame", "user_id", userID, "err", err)
+			return
+		}
+		data = sealed
+	}
+	if err := os.WriteFile(filepath.Join(config.Dir, name), data, 0o600); err != nil {
+		logger.Warn("Failed to save debug frame", "user_id", userID, "err", err)
+	}
+}
+
+// blurRegion blurs rect of mat in place, strongly enough that the face
+// cannot be recognized
+func blurRegion(mat *gocv.Mat, rect image.Rectangle) {
+	rect = rect.Intersect(image.Rect(0, 0, mat.Cols(), mat.Rows()))
+	if rect.Empty() {
+		return
+	}
+	region := mat.Region(rect)
+	defer region.Close()
+
+	// Odd kernel, about half the face
+	kernel := max(rect.Dx(), rect.Dy())/2 | 1
+	gocv.GaussianBlur(region, &region, image.Pt(kernel, kernel), 0, 0, gocv.BorderDefault)
+}
@@ -0,0 +1,287 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/audit"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/utils"
+	"mezon-checkin-bot/mezon-protobuf/go/rtapi"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// ESCALATION - Manager approval when recognition fails
+// ============================================================
+
+// EscalationConfig is the manager/HR channel that approves check-ins the
+// bot could not recognize. Zero IDs disable escalation.
+type EscalationConfig struct {
+	ClanID    int64
+	ChannelID int64
+	Approvers []int64 // Users who may decide, besides admins
+	Path      string  // Open requests, saved across restarts ("" = memory only)
+}
+
+const (
+	escalationButtonPrefix = "escalation_"
+	escalationApprove      = "approve"
+	escalationReject       = "reject"
+
+	// Requests older than this can no longer be approved
+	escalationTTL = 8 * time.Hour
+)
+
+// pendingApproval is a failed check-in waiting for a manager's decision
+type pendingApproval struct {
+	userID      int64
+	channelID   int64 // The user's DM channel
+	callID      string
+	requestedAt time.Time
+}
+
+// approvalRecord is a pendingApproval saved to disk
+type approvalRecord struct {
+	UserID      int64     `json:"user_id"`
+	ChannelID   int64     `json:"channel_id"`
+	CallID      string    `json:"call_id,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// SetEscalationConfig sets the channel that approves unrecognized check-ins
+// and restores the requests still open at cfg.Path. A standby reads them
+// when it takes over.
+func (w *WebRTCManager) SetEscalationConfig(cfg EscalationConfig) error {
+	if cfg.Path != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	w.mu.Lock()
+	w.escalation = cfg
+	w.mu.Unlock()
+
+	var restored int
+	if !w.Standby() {
+		if err := w.reloadApprovals(); err != nil {
+			return err
+		}
+		w.mu.RLock()
+		restored = len(w.approvals)
+		w.mu.RUnlock()
+	}
+
+	logger.Info("Escalation configured", "enabled", cfg.ChannelID != 0, "channel_id", cfg.ChannelID,
+		"approvers", len(cfg.Approvers), "restored", restored)
+	return nil
+}
+
+// reloadApprovals replaces the open requests with the ones saved, dropping
+// the expired ones
+func (w *WebRTCManager) reloadApprovals() error {
+	w.mu.RLock()
+	path := w.escalation.Path
+	w.mu.RUnlock()
+	if path == "" {
+		return nil
+	}
+
+	var records []approvalRecord
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return fmt.Errorf("failed to read pending approvals: %w", err)
+	default:
+		if err := json.Unmarshal(data, &records); err != nil {
+			return fmt.Errorf("failed to parse pending approvals: %w", err)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	clear(w.approvals)
+	for _, record := range records {
+		if time.Since(record.RequestedAt) > escalationTTL {
+			continue
+		}
+		w.approvals[record.UserID] = &pendingApproval{
+			userID:      record.UserID,
+			channelID:   record.ChannelID,
+			callID:      record.CallID,
+			requestedAt: record.RequestedAt,
+		}
+	}
+	w.saveApprovalsLocked()
+	return nil
+}
+
+// canApprove reports whether the user may decide escalated check-ins
+func (w *WebRTCManager) canApprove(userID int64) bool {
+	if w.isAdmin(userID) {
+		return true
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return slices.Contains(w.escalation.Approvers, userID)
+}
+
+// saveApprovalsLocked writes the open requests to disk. Caller holds w.mu.
+// A standby leaves the file to the leader.
+func (w *WebRTCManager) saveApprovalsLocked() {
+	path := w.escalation.Path
+	if path == "" || w.Standby() {
+		return
+	}
+
+	records := make([]approvalRecord, 0, len(w.approvals))
+	for _, approval := range w.approvals {
+		records = append(records, approvalRecord{
+			UserID:      approval.userID,
+			ChannelID:   approval.channelID,
+			CallID:      approval.callID,
+			RequestedAt: approval.requestedAt,
+		})
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		logger.Error("Failed to marshal pending approvals", "err", err)
+		return
+	}
+	if err := utils.WriteFileAtomic(path, data, 0644); err != nil {
+		logger.Error("Failed to write pending approvals", "err", err)
+	}
+}
+
+// escalate sends the best capture to the manager channel for approval. A
+// standby finishing a call doesn't: it can't save the request and the
+// managers' decision goes to the leader.
+func (w *WebRTCManager) escalate(userID, channelID int64, crop []byte, attempts int) {
+	w.mu.RLock()
+	cfg := w.escalation
+	w.mu.RUnlock()
+	if cfg.ChannelID == 0 || w.dmManager == nil || w.Standby() {
+		return
+	}
+
+	callLog := w.callLogger(userID)
+	approval := &pendingApproval{
+		userID:      userID,
+		channelID:   channelID,
+		callID:      w.callID(userID),
+		requestedAt: time.Now(),
+	}
+
+	// The crop stays available as long as the request can be decided
+	var imageURL string
+	if len(crop) > 0 {
+		imageURL = w.hostImage(crop, "image/jpeg", escalationTTL)
+	}
+
+	content := client.BuildApprovalRequestMessage(w.userLabel(userID), approval.callID, attempts, imageURL,
+		escalationButtonID(escalationApprove, userID), escalationButtonID(escalationReject, userID))
+	if err := w.dmManager.SendChannelMessage(cfg.ClanID, cfg.ChannelID, content); err != nil {
+		callLog.Error("Failed to send approval request", "err", err)
+		return
+	}
+
+	w.mu.Lock()
+	w.approvals[userID] = approval
+	w.saveApprovalsLocked()
+	w.mu.Unlock()
+
+	callLog.Info("Check-in escalated to managers", "attempts", attempts, "image", len(crop) > 0)
+	notice := w.text(userID, "notice.escalated", nil)
+	if err := w.SendCheckinNotice(channelID, userID, notice); err != nil {
+		callLog.Error("Failed to send escalation notice", "err", err)
+	}
+}
+
+func escalationButtonID(action string, userID int64) string {
+	return fmt.Sprintf("%s%s:%d", escalationButtonPrefix, action, userID)
+}
+
+// parseEscalationButtonID splits "escalation_<action>:<user ID>"
+func parseEscalationButtonID(buttonID string) (string, int64, bool) {
+	action, id, found := strings.Cut(strings.TrimPrefix(buttonID, escalationButtonPrefix), ":")
+	if !found {
+		return "", 0, false
+	}
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return action, userID, true
+}
+
+// handleEscalationClick applies a manager's Approve/Reject click
+func (w *WebRTCManager) handleEscalationClick(clicked *rtapi.MessageButtonClicked) {
+	action, userID, ok := parseEscalationButtonID(clicked.GetButtonId())
+	if !ok {
+		logger.Warn("Malformed escalation button", "button_id", clicked.GetButtonId())
+		return
+	}
+
+	w.mu.RLock()
+	cfg := w.escalation
+	w.mu.RUnlock()
+	if clicked.GetChannelId() != cfg.ChannelID {
+		logger.Warn("Escalation click outside the manager channel", "channel_id", clicked.GetChannelId())
+		return
+	}
+
+	managerID := clicked.GetUserId()
+	reply := func(text string) {
+		if err := w.dmManager.SendChannelMessage(cfg.ClanID, cfg.ChannelID, client.BuildSimpleTextMessage(text)); err != nil {
+			logger.Error("Failed to send escalation reply", "err", err)
+		}
+	}
+
+	// Nobody decides their own check-in, and only approvers decide at all
+	if managerID == userID || !w.canApprove(managerID) {
+		logger.Warn("Escalation click by a user who may not decide", "user_id", userID, "manager_id", managerID)
+		reply(fmt.Sprintf("⛔ %s không có quyền duyệt yêu cầu này.", w.userLabel(managerID)))
+		return
+	}
+
+	w.mu.Lock()
+	approval, exists := w.approvals[userID]
+	delete(w.approvals, userID)
+	w.saveApprovalsLocked()
+	w.mu.Unlock()
+
+	if !exists || time.Since(approval.requestedAt) > escalationTTL {
+		reply(fmt.Sprintf("Yêu cầu của người dùng %s đã được xử lý hoặc hết hạn.", w.userLabel(userID)))
+		return
+	}
+
+	switch action {
+	case escalationApprove:
+		logger.Info("Escalation approved", "user_id", userID, "manager_id", managerID, "call_id", approval.callID)
+		if err := w.approveCheckin(userID, approval.channelID, ""); err != nil {
+			logger.Error("Escalated approval failed", "user_id", userID, "err", err)
+			reply(fmt.Sprintf("❌ Không thể duyệt check-in cho người dùng %s: %v", w.userLabel(userID), err))
+			return
+		}
+		w.recordApproval(userID, approval.channelID, CheckinEvent{CallID: approval.callID})
+		w.audit(managerID, audit.ActionManualApproval, strconv.FormatInt(userID, 10), "call "+approval.callID)
+		reply(fmt.Sprintf("✅ Người dùng %s đã được duyệt check-in bởi %s.", w.userLabel(userID), w.userLabel(managerID)))
+
+	case escalationReject:
+		logger.Info("Escalation rejected", "user_id", userID, "manager_id", managerID, "call_id", approval.callID)
+		w.recordEvent(userID, CheckinEvent{CallID: approval.callID, Outcome: OutcomeFailed, Reason: "escalation_rejected"})
+		w.audit(managerID, audit.ActionManualReject, strconv.FormatInt(userID, 10), "call "+approval.callID)
+		if err := w.SendCheckinFailed(approval.channelID, userID, w.text(userID, "reason.manager_rejected", nil)); err != nil {
+			logger.Error("Failed to send rejection", "user_id", userID, "err", err)
+		}
+		reply(fmt.Sprintf("❌ Yêu cầu check-in của người dùng %s đã bị từ chối bởi %s.", w.userLabel(userID), w.userLabel(managerID)))
+
+	default:
+		logger.Warn("Unknown escalation action", "action", action)
+	}
+}
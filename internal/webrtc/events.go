@@ -0,0 +1,139 @@
+package webrtc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// CHECK-IN EVENTS - Outcome of every check-in stage
+// ============================================================
+
+// Check-in event outcomes
+const (
+	OutcomeRecognized   = "recognized" // Face recognized, location may still be pending
+	OutcomeApproved     = "approved"   // Check-in accepted by the backend
+	OutcomeCheckedOut   = "checked_out"
+	OutcomeBreakStarted = "break_started"
+	OutcomeBreakEnded   = "break_ended"
+	OutcomeFailed       = "failed" // Check-in ended without approval, see Reason
+)
+
+// CheckinEvent is one outcome of a check-in call
+type CheckinEvent struct {
+	UserID   int64  `json:"user_id"`
+	CallID   string `json:"call_id,omitempty"`
+	Outcome  string `json:"outcome"`
+	Reason   string `json:"reason,omitempty"`
+	Attempts int    `json:"attempts,omitempty"` // Recognition attempts used
+	OfficeID string `json:"office_id,omitempty"`
+	WFH      bool   `json:"wfh,omitempty"`
+	Late     bool   `json:"late,omitempty"`         // Approved after the shift start
+	LateMins int    `json:"late_minutes,omitempty"` // Minutes after the shift start
+
+	Probability float64 `json:"probability,omitempty"` // Recognition probability
+	DistanceM   float64 `json:"distance_m,omitempty"`  // Distance to the matched office
+
+	At time.Time `json:"at"`
+}
+
+// CheckinEventStore persists check-in events for reports
+type CheckinEventStore interface {
+	Append(event CheckinEvent) error
+	// Between returns the events in [from, to)
+	Between(from, to time.Time) ([]CheckinEvent, error)
+}
+
+// FileEventStore appends events to a JSON lines file
+type FileEventStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileEventStore creates a store writing to path
+func NewFileEventStore(path string) (*FileEventStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	return &FileEventStore{path: path}, nil
+}
+
+// Append writes one event
+func (s *FileEventStore) Append(event CheckinEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal check-in event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	return nil
+}
+
+// Between scans the file for events in [from, to)
+func (s *FileEventStore) Between(from, to time.Time) ([]CheckinEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer file.Close()
+
+	var events []CheckinEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event CheckinEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue // Skip a torn last line
+		}
+		if !event.At.Before(from) && event.At.Before(to) {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+	return events, nil
+}
+
+// recordEvent stamps and persists a check-in event. Failures are only logged:
+// reports must never break a check-in.
+func (w *WebRTCManager) recordEvent(userID int64, event CheckinEvent) {
+	w.mu.RLock()
+	store := w.events
+	w.mu.RUnlock()
+	if store == nil {
+		return
+	}
+
+	event.UserID = userID
+	if event.CallID == "" {
+		event.CallID = w.callID(userID)
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	if err := store.Append(event); err != nil {
+		w.callLogger(userID).Warn("Failed to record check-in event", "outcome", event.Outcome, "err", err)
+	}
+}
@@ -0,0 +1,168 @@
+package webrtc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// SQL EVENT STORE - SQLite and Postgres
+// ============================================================
+
+// sqlDialect holds what differs between the supported databases
+type sqlDialect struct {
+	driver   string // database/sql driver name
+	idColumn string
+	numbered bool // Placeholders are $1, $2... instead of ?
+}
+
+// Event store kinds accepted by NewSQLEventStore. The drivers are linked in
+// by the main package's sqlite and postgres build tags.
+var sqlDialects = map[string]sqlDialect{
+	"sqlite":   {driver: "sqlite", idColumn: "id INTEGER PRIMARY KEY AUTOINCREMENT"},
+	"postgres": {driver: "pgx", idColumn: "id BIGSERIAL PRIMARY KEY", numbered: true},
+}
+
+// Times are Unix milliseconds, which both drivers scan the same way
+const sqlEventSchema = `CREATE TABLE IF NOT EXISTS checkin_events (
+	%s,
+	user_id BIGINT NOT NULL,
+	call_id TEXT NOT NULL DEFAULT '',
+	outcome TEXT NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	office_id TEXT NOT NULL DEFAULT '',
+	wfh BOOLEAN NOT NULL DEFAULT FALSE,
+	late BOOLEAN NOT NULL DEFAULT FALSE,
+	late_minutes INTEGER NOT NULL DEFAULT 0,
+	probability DOUBLE PRECISION NOT NULL DEFAULT 0,
+	distance_m DOUBLE PRECISION NOT NULL DEFAULT 0,
+	at_ms BIGINT NOT NULL
+)`
+
+var sqlEventIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS checkin_events_at ON checkin_events (at_ms)",
+	"CREATE INDEX IF NOT EXISTS checkin_events_user ON checkin_events (user_id, at_ms)",
+}
+
+const sqlEventColumns = "user_id, call_id, outcome, reason, attempts, office_id, wfh, late, late_minutes, probability, distance_m, at_ms"
+
+// SQLEventStore keeps check-in events in a SQL table for reports and audits
+type SQLEventStore struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// SQLEventStoreSupported reports whether the binary can open the kind of
+// event store, its driver being linked in by the build tag of the same name
+func SQLEventStoreSupported(kind string) error {
+	dialect, ok := sqlDialects[kind]
+	if !ok {
+		return fmt.Errorf("unknown event store %q, expected sqlite or postgres", kind)
+	}
+	if !slices.Contains(sql.Drivers(), dialect.driver) {
+		return fmt.Errorf("built without %s support, rebuild with -tags %s", kind, kind)
+	}
+	return nil
+}
+
+// NewSQLEventStore connects to the database and creates the table if missing.
+// kind is "sqlite" (dsn = file path) or "postgres" (dsn = connection URL).
+func NewSQLEventStore(kind, dsn string) (*SQLEventStore, error) {
+	if err := SQLEventStoreSupported(kind); err != nil {
+		return nil, err
+	}
+	dialect := sqlDialects[kind]
+	if dsn == "" {
+		return nil, fmt.Errorf("%s event store needs a DSN", kind)
+	}
+
+	db, err := sql.Open(dialect.driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s event store: %w", kind, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s event store: %w", kind, err)
+	}
+
+	statements := append([]string{fmt.Sprintf(sqlEventSchema, dialect.idColumn)}, sqlEventIndexes...)
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create event table: %w", err)
+		}
+	}
+
+	logger.Info("Check-in events stored in database", "kind", kind)
+	return &SQLEventStore{db: db, dialect: dialect}, nil
+}
+
+// Append inserts one event
+func (s *SQLEventStore) Append(event CheckinEvent) error {
+	query := s.dialect.rebind("INSERT INTO checkin_events (" + sqlEventColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	_, err := s.db.Exec(query,
+		event.UserID, event.CallID, event.Outcome, event.Reason, event.Attempts, event.OfficeID,
+		event.WFH, event.Late, event.LateMins, event.Probability, event.DistanceM, event.At.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to insert check-in event: %w", err)
+	}
+	return nil
+}
+
+// Between returns the events in [from, to), oldest first
+func (s *SQLEventStore) Between(from, to time.Time) ([]CheckinEvent, error) {
+	query := s.dialect.rebind("SELECT " + sqlEventColumns + " FROM checkin_events WHERE at_ms >= ? AND at_ms < ? ORDER BY at_ms, id")
+	rows, err := s.db.Query(query, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query check-in events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []CheckinEvent
+	for rows.Next() {
+		var event CheckinEvent
+		var atMillis int64
+		if err := rows.Scan(&event.UserID, &event.CallID, &event.Outcome, &event.Reason, &event.Attempts, &event.OfficeID,
+			&event.WFH, &event.Late, &event.LateMins, &event.Probability, &event.DistanceM, &atMillis); err != nil {
+			return nil, fmt.Errorf("failed to read check-in event: %w", err)
+		}
+		event.At = time.UnixMilli(atMillis)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read check-in events: %w", err)
+	}
+	return events, nil
+}
+
+// Close closes the database
+func (s *SQLEventStore) Close() error {
+	return s.db.Close()
+}
+
+// rebind turns ? placeholders into $n for dialects that number them
+func (d sqlDialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,295 @@
+package webrtc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mezon-checkin-bot/internal/audit"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/xlsx"
+	"mezon-checkin-bot/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// EXPORT - Attendance records as CSV or XLSX
+// ============================================================
+
+// ExportConfig protects the export endpoint. Token is accepted as a bearer
+// token and also signs the short-lived links handed out by !export.
+type ExportConfig struct {
+	Token   string
+	BaseURL string        // Public URL of the admin server, for !export links
+	LinkTTL time.Duration // How long an !export link works (default 15m)
+}
+
+// ExportPath is where ExportHandler is mounted
+const ExportPath = "/admin/export"
+
+const (
+	exportDayLayout      = "2006-01-02"
+	defaultExportLinkTTL = 15 * time.Minute
+	maxExportDays        = 366
+)
+
+// Export formats
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+var exportHeader = []string{
+	"date", "time", "user_id", "outcome", "reason", "office_id", "wfh",
+	"late", "late_minutes", "attempts", "probability", "distance_m", "call_id",
+}
+
+const exportUsage = "Cách dùng:\n" +
+	"!export <từ YYYY-MM-DD> [đến YYYY-MM-DD] [csv|xlsx]"
+
+// SetExportConfig enables the export endpoint and the !export command
+func (w *WebRTCManager) SetExportConfig(cfg ExportConfig) {
+	if cfg.LinkTTL <= 0 {
+		cfg.LinkTTL = defaultExportLinkTTL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	w.mu.Lock()
+	w.exportConfig = cfg
+	w.mu.Unlock()
+}
+
+// ExportEvents writes the check-in events of the calendar days from..to
+// (inclusive, each event on its office's day) to out and returns how many
+// were written
+func (w *WebRTCManager) ExportEvents(out io.Writer, format string, from, to time.Time) (int, error) {
+	w.mu.RLock()
+	store := w.events
+	w.mu.RUnlock()
+	if store == nil {
+		return 0, fmt.Errorf("no check-in event store configured")
+	}
+
+	events, err := w.eventsOnDays(store, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load check-in events: %w", err)
+	}
+
+	var writeRow func([]string) error
+	var finish func() error
+	switch format {
+	case ExportCSV:
+		cw := csv.NewWriter(out)
+		writeRow = cw.Write
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case ExportXLSX:
+		xw, err := xlsx.NewWriter(out, "Check-in")
+		if err != nil {
+			return 0, err
+		}
+		writeRow = xw.Write
+		finish = xw.Close
+	default:
+		return 0, fmt.Errorf("unknown export format %q", format)
+	}
+
+	if err := writeRow(exportHeader); err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		if err := writeRow(exportRow(event, w.eventZone(event))); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), finish()
+}
+
+func exportRow(event CheckinEvent, zone *time.Location) []string {
+	at := event.At.In(zone)
+	return []string{
+		at.Format(exportDayLayout),
+		at.Format("15:04:05"),
+		strconv.FormatInt(event.UserID, 10),
+		event.Outcome,
+		event.Reason,
+		event.OfficeID,
+		strconv.FormatBool(event.WFH),
+		strconv.FormatBool(event.Late),
+		strconv.Itoa(event.LateMins),
+		strconv.Itoa(event.Attempts),
+		strconv.FormatFloat(event.Probability, 'f', 3, 64),
+		strconv.FormatFloat(event.DistanceM, 'f', 0, 64),
+		event.CallID,
+	}
+}
+
+// parseExportRange parses inclusive calendar day bounds, matched against
+// each event's office day. An empty to means the same day as from.
+func parseExportRange(fromText, toText string) (time.Time, time.Time, error) {
+	zone := time.UTC
+	from, err := time.ParseInLocation(exportDayLayout, fromText, zone)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", fromText)
+	}
+	to := from
+	if toText != "" {
+		if to, err = time.ParseInLocation(exportDayLayout, toText, zone); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", toText)
+		}
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%s is before %s", toText, fromText)
+	}
+	if to.Sub(from) >= maxExportDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("range is longer than %d days", maxExportDays)
+	}
+	return from, to, nil
+}
+
+// ============================================================
+// EXPORT ENDPOINT
+// ============================================================
+
+// ExportHandler serves GET ExportPath?from=&to=&format= to callers holding
+// the token or a valid signed link. It is nil while no token is set.
+func (w *WebRTCManager) ExportHandler() http.Handler {
+	w.mu.RLock()
+	cfg := w.exportConfig
+	w.mu.RUnlock()
+	if cfg.Token == "" {
+		return nil
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		fromText, toText := query.Get("from"), query.Get("to")
+		format := strings.ToLower(query.Get("format"))
+		if format == "" {
+			format = ExportCSV
+		}
+
+		bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		authorized := hmac.Equal([]byte(bearer), []byte(cfg.Token)) ||
+			verifyExportLink(cfg.Token, fromText, toText, format, query.Get("expires"), query.Get("sig"), time.Now())
+		if !authorized {
+			logger.Warn("Unauthorized export request", "remote", r.RemoteAddr)
+			http.Error(rw, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		from, to, err := parseExportRange(fromText, toText)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		name := fmt.Sprintf("checkin_%s_%s.%s", from.Format(exportDayLayout), to.Format(exportDayLayout), format)
+		switch format {
+		case ExportCSV:
+			rw.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		case ExportXLSX:
+			rw.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		default:
+			http.Error(rw, "format must be csv or xlsx", http.StatusBadRequest)
+			return
+		}
+		rw.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+
+		count, err := w.ExportEvents(rw, format, from, to)
+		if err != nil {
+			// Headers are already sent, the client sees a truncated file
+			logger.Error("Export failed", "file", name, "err", err)
+			return
+		}
+		logger.Info("Check-in events exported", "file", name, "events", count, "remote", r.RemoteAddr)
+		w.audit(0, audit.ActionDataExport, name, fmt.Sprintf("%d events to %s", count, r.RemoteAddr))
+	})
+}
+
+// signExportLink returns the query of a link valid until expires
+func signExportLink(token, from, to, format string, expires time.Time) url.Values {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return url.Values{
+		"from":    {from},
+		"to":      {to},
+		"format":  {format},
+		"expires": {exp},
+		"sig":     {exportSignature(token, from, to, format, exp)},
+	}
+}
+
+func verifyExportLink(token, from, to, format, expires, signature string, now time.Time) bool {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.After(time.Unix(unix, 0)) {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	want, _ := hex.DecodeString(exportSignature(token, from, to, format, expires))
+	return hmac.Equal(got, want)
+}
+
+func exportSignature(token, from, to, format, expires string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(strings.Join([]string{from, to, format, expires}, "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ============================================================
+// EXPORT COMMAND
+// ============================================================
+
+func (w *WebRTCManager) handleExportCommand(args []string) models.ChannelMessageContent {
+	w.mu.RLock()
+	cfg := w.exportConfig
+	w.mu.RUnlock()
+	if cfg.Token == "" || cfg.BaseURL == "" {
+		return client.BuildErrorMessage("❌ Xuất dữ liệu chưa bật", "Đặt EXPORT_TOKEN và EXPORT_BASE_URL.")
+	}
+	if len(args) == 0 {
+		return client.BuildSimpleTextMessage(exportUsage)
+	}
+
+	format := ExportCSV
+	var days []string
+	for _, arg := range args {
+		switch lower := strings.ToLower(arg); lower {
+		case ExportCSV, ExportXLSX:
+			format = lower
+		default:
+			days = append(days, arg)
+		}
+	}
+	if len(days) == 0 || len(days) > 2 {
+		return client.BuildSimpleTextMessage(exportUsage)
+	}
+	fromText, toText := days[0], days[0]
+	if len(days) == 2 {
+		toText = days[1]
+	}
+	if _, _, err := parseExportRange(fromText, toText); err != nil {
+		return client.BuildErrorMessage("❌ Khoảng ngày không hợp lệ", err.Error())
+	}
+
+	expires := time.Now().Add(cfg.LinkTTL)
+	link := cfg.BaseURL + ExportPath + "?" + signExportLink(cfg.Token, fromText, toText, format, expires).Encode()
+	return client.BuildSuccessMessage("📥 Xuất dữ liệu check-in",
+		fmt.Sprintf("Từ %s đến %s (%s)\nLink tải, hết hạn lúc %s:\n%s",
+			fromText, toText, strings.ToUpper(format), expires.In(Office{}.Zone()).Format("15:04"), link))
+}
@@ -0,0 +1,175 @@
+package webrtc
+
+import (
+	"fmt"
+	"io"
+	"mezon-checkin-bot/internal/matpool"
+	"os/exec"
+	"sync"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// PERSISTENT FFMPEG - One decoder process per call, fed an IVF
+// stream, instead of one process per frame
+// ============================================================
+
+const persistentDecodeTimeout = 2 * time.Second
+
+type persistentDecoder struct {
+	cmd          *exec.Cmd
+	stdin        io.WriteCloser
+	stdout       io.ReadCloser
+	width        int // Size of the VP8 stream
+	height       int
+	decodeWidth  int // Size of the frames ffmpeg writes
+	decodeHeight int
+	frames       uint64
+	frameHeader  [ivfFrameHeaderSize]byte // Reused for every frame
+	closeOnce    sync.Once
+}
+
+// newPersistentDecoder starts ffmpeg for a stream of the given size and
+// writes the IVF file header
+func (w *WebRTCManager) newPersistentDecoder(width, height int) (*persistentDecoder, error) {
+	decodeWidth, decodeHeight := w.getOptimalDecodeSize(width, height)
+
+	args := []string{
+		"-loglevel", "error",
+		"-nostdin",
+		"-probesize", "32",
+		"-fflags", "nobuffer",
+		"-f", "ivf",
+		"-i", "pipe:0",
+	}
+	if decodeWidth != width || decodeHeight != height {
+		args = append(args,
+			"-vf", fmt.Sprintf("scale=%d:%d:flags=fast_bilinear", decodeWidth, decodeHeight),
+		)
+	}
+	args = append(args,
+		"-f", "rawvideo",
+		"-pix_fmt", "bgr24",
+		"-threads", "1",
+		"-flush_packets", "1",
+		"pipe:1",
+	)
+
+	cmd := exec.Command("ffmpeg", args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("ffmpeg start: %w", err)
+	}
+
+	d := &persistentDecoder{
+		cmd:          cmd,
+		stdin:        stdin,
+		stdout:       stdout,
+		width:        width,
+		height:       height,
+		decodeWidth:  decodeWidth,
+		decodeHeight: decodeHeight,
+	}
+	// The file header without a frame
+	if _, err := stdin.Write(ivfFileHeader(width, height)); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("write header: %w", err)
+	}
+	return d, nil
+}
+
+// fits reports whether the keyframe has the stream's size; a new size needs
+// a new decoder
+func (d *persistentDecoder) fits(width, height int) bool {
+	return d.width == width && d.height == height
+}
+
+// decode sends one frame and reads back its pixels into a Mat from mats. On
+// error the decoder is closed and must not be used again.
+func (d *persistentDecoder) decode(frameData []byte, mats *matpool.Scope) (*gocv.Mat, error) {
+	putIVFFrameHeader(&d.frameHeader, len(frameData), d.frames)
+	d.frames++
+
+	// A stuck ffmpeg is killed, which unblocks the read
+	timer := time.AfterFunc(persistentDecodeTimeout, d.Close)
+	defer timer.Stop()
+
+	// Two writes instead of copying the frame behind its header
+	if _, err := d.stdin.Write(d.frameHeader[:]); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("write: %w", err)
+	}
+	if _, err := d.stdin.Write(frameData); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("write: %w", err)
+	}
+
+	// Read straight into the Mat's pixels
+	mat := mats.GetSized(d.decodeHeight, d.decodeWidth, gocv.MatTypeCV8UC3)
+	pixels, err := mat.DataPtrUint8()
+	if err != nil {
+		mats.Put(mat)
+		d.Close()
+		return nil, fmt.Errorf("mat data: %w", err)
+	}
+	if _, err := io.ReadFull(d.stdout, pixels); err != nil {
+		mats.Put(mat)
+		d.Close()
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return &mat, nil
+}
+
+// Close stops ffmpeg
+func (d *persistentDecoder) Close() {
+	d.closeOnce.Do(func() {
+		d.stdin.Close()
+		if d.cmd.Process != nil {
+			d.cmd.Process.Kill()
+		}
+		d.cmd.Wait()
+	})
+}
+
+// decodeFrame decodes a keyframe with the call's persistent decoder when
+// the flag gave it one, falling back to one ffmpeg per frame for the rest of
+// the call if the decoder fails. The Mat goes back with cs.mats.Put.
+func (w *WebRTCManager) decodeFrame(cs *captureState, frameData []byte) (*gocv.Mat, error) {
+	if !cs.persistentFFmpeg {
+		return w.vp8FrameToGoCV(frameData, cs.mats)
+	}
+
+	width, height, err := getVP8KeyframeDims(frameData)
+	if err != nil {
+		return nil, fmt.Errorf("parse dims: %w", err)
+	}
+	if cs.decoder != nil && !cs.decoder.fits(width, height) {
+		cs.decoder.Close()
+		cs.decoder = nil
+	}
+	if cs.decoder == nil {
+		if cs.decoder, err = w.newPersistentDecoder(width, height); err != nil {
+			cs.logger.Warn("Persistent decoder unavailable, decoding per frame", "err", err)
+			cs.persistentFFmpeg = false
+			return w.vp8FrameToGoCV(frameData, cs.mats)
+		}
+	}
+
+	mat, err := cs.decoder.decode(frameData, cs.mats)
+	if err != nil {
+		cs.logger.Warn("Persistent decoder failed, decoding per frame", "err", err)
+		cs.decoder = nil
+		cs.persistentFFmpeg = false
+		return w.vp8FrameToGoCV(frameData, cs.mats)
+	}
+	return mat, nil
+}
@@ -0,0 +1,302 @@
+package webrtc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// LOCATION HISTORY - Append-only log of confirmed locations
+// ============================================================
+
+const (
+	historyWindow        = 24 * time.Hour // In-memory window used for anomaly checks
+	cityHopWindow        = 1 * time.Hour
+	cityHopDistanceM     = 30000.0 // Further than this counts as another city
+	sharedDeviceMinUsers = 5       // Distinct users reporting identical coordinates
+	sharedDeviceWindow   = historyWindow
+
+	// The file keeps this much history and is compacted once a day
+	historyRetention       = 90 * 24 * time.Hour
+	historyCompactInterval = 24 * time.Hour
+)
+
+// LocationRecord is one confirmed check-in location
+type LocationRecord struct {
+	UserID    int64     `json:"user_id"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Address   string    `json:"address,omitempty"`
+	OfficeID  string    `json:"office_id,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// LocationHistory persists every confirmed location as JSON lines and keeps
+// the last historyWindow in memory
+type LocationHistory struct {
+	path        string
+	recent      []LocationRecord
+	lastCompact time.Time
+	mu          sync.Mutex
+}
+
+// NewLocationHistory loads the recent part of the history file. An empty path
+// keeps the history in memory only.
+func NewLocationHistory(path string) (*LocationHistory, error) {
+	h := &LocationHistory{path: path}
+	if path == "" {
+		return h, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open location history: %w", err)
+	}
+	defer file.Close()
+
+	cutoff := time.Now().Add(-historyWindow)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record LocationRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // Skip a torn last line
+		}
+		if record.At.After(cutoff) {
+			h.recent = append(h.recent, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read location history: %w", err)
+	}
+
+	logger.Info("Loaded recent location records", "count", len(h.recent), "path", path)
+
+	if err := h.compactLocked(time.Now()); err != nil {
+		logger.Warn("Failed to compact location history", "err", err)
+	}
+	return h, nil
+}
+
+// Append records a confirmed location
+func (h *LocationHistory) Append(record LocationRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.prune(record.At)
+	h.recent = append(h.recent, record)
+
+	if h.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal location record: %w", err)
+	}
+
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open location history: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write location history: %w", err)
+	}
+
+	if record.At.Sub(h.lastCompact) > historyCompactInterval {
+		if err := h.compactLocked(record.At); err != nil {
+			logger.Warn("Failed to compact location history", "err", err)
+		}
+	}
+	return nil
+}
+
+// compactLocked rewrites the file without records older than
+// historyRetention. Caller holds h.mu.
+func (h *LocationHistory) compactLocked(now time.Time) error {
+	h.lastCompact = now
+
+	file, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open location history: %w", err)
+	}
+	defer file.Close()
+
+	cutoff := now.Add(-historyRetention)
+	var kept []byte
+	dropped := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record LocationRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || !record.At.After(cutoff) {
+			dropped++
+			continue
+		}
+		kept = append(kept, scanner.Bytes()...)
+		kept = append(kept, '\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read location history: %w", err)
+	}
+	if dropped == 0 {
+		return nil
+	}
+
+	if err := utils.WriteFileAtomic(h.path, kept, 0644); err != nil {
+		return fmt.Errorf("failed to write location history: %w", err)
+	}
+	logger.Info("Compacted location history", "dropped", dropped, "kept_bytes", len(kept))
+	return nil
+}
+
+// Recent returns the records newer than since
+func (h *LocationHistory) Recent(since time.Time) []LocationRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var records []LocationRecord
+	for _, record := range h.recent {
+		if record.At.After(since) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// UsersAt returns the users with a record since the given time at the same
+// coordinates, to 6 decimals (about 10cm, closer than two real fixes get)
+func (h *LocationHistory) UsersAt(lat, lon float64, since time.Time) map[int64]bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	users := make(map[int64]bool)
+	for _, record := range h.recent {
+		if record.At.After(since) && sameCoordinates(record.Latitude, record.Longitude, lat, lon) {
+			users[record.UserID] = true
+		}
+	}
+	return users
+}
+
+// Latest returns the most recent record of each user in the window
+func (h *LocationHistory) Latest() map[int64]LocationRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	latest := make(map[int64]LocationRecord)
+	for _, record := range h.recent {
+		if prev, ok := latest[record.UserID]; !ok || record.At.After(prev.At) {
+			latest[record.UserID] = record
+		}
+	}
+	return latest
+}
+
+// prune drops records older than the window. Caller holds h.mu.
+func (h *LocationHistory) prune(now time.Time) {
+	cutoff := now.Add(-historyWindow)
+	kept := h.recent[:0]
+	for _, record := range h.recent {
+		if record.At.After(cutoff) {
+			kept = append(kept, record)
+		}
+	}
+	h.recent = kept
+}
+
+// ============================================================
+// ANOMALY DETECTION - Alerts on already approved check-ins
+// ============================================================
+
+// detectLocationAnomalies compares a new record with the recent history
+func (w *WebRTCManager) detectLocationAnomalies(record LocationRecord, recent []LocationRecord) []string {
+	var anomalies []string
+
+	// 1. Same user in two cities within an hour
+	for _, prev := range recent {
+		if prev.UserID != record.UserID || record.At.Sub(prev.At) > cityHopWindow {
+			continue
+		}
+		distance := calculateDistance(prev.Latitude, prev.Longitude, record.Latitude, record.Longitude)
+		if distance > cityHopDistanceM {
+			anomalies = append(anomalies, fmt.Sprintf("cách lần check-in lúc %s %.0fkm",
+				w.locationConfig.OfficeTime(prev.OfficeID, prev.At).Format("15:04"), distance/1000))
+			break
+		}
+	}
+
+	// 2. Identical coordinates from many users (shared or emulated device)
+	users := w.history.UsersAt(record.Latitude, record.Longitude, record.At.Add(-sharedDeviceWindow))
+	users[record.UserID] = true
+	if len(users) >= sharedDeviceMinUsers {
+		anomalies = append(anomalies, fmt.Sprintf("%d người dùng check-in cùng tọa độ trong 24h", len(users)))
+	}
+
+	return anomalies
+}
+
+func sameCoordinates(lat1, lon1, lat2, lon2 float64) bool {
+	return fmt.Sprintf("%.6f,%.6f", lat1, lon1) == fmt.Sprintf("%.6f,%.6f", lat2, lon2)
+}
+
+// recordLocationHistory persists a confirmed location and alerts the admin
+// channel on anomalies
+func (w *WebRTCManager) recordLocationHistory(userID int64, confirmed ConfirmedLocation) {
+	if w.history == nil {
+		return
+	}
+
+	record := LocationRecord{
+		UserID:    userID,
+		Latitude:  confirmed.Latitude,
+		Longitude: confirmed.Longitude,
+		Address:   confirmed.Address,
+		OfficeID:  confirmed.OfficeID,
+		At:        confirmed.At,
+	}
+
+	anomalies := w.detectLocationAnomalies(record, w.history.Recent(record.At.Add(-historyWindow)))
+
+	if err := w.history.Append(record); err != nil {
+		logger.Warn("Failed to persist location history", "err", err)
+	}
+
+	if len(anomalies) > 0 {
+		go w.sendAnomalyAlert(record, anomalies)
+	}
+}
+
+// sendAnomalyAlert posts the anomaly to the configured admin channel
+func (w *WebRTCManager) sendAnomalyAlert(record LocationRecord, anomalies []string) {
+	logger.Warn("Location anomaly", "user_id", record.UserID, "anomalies", anomalies)
+
+	cfg := w.locationConfig
+	description := fmt.Sprintf("User %s check-in lúc %s tại (%.6f, %.6f)\n- %s",
+		w.userLabel(record.UserID), cfg.OfficeTime(record.OfficeID, record.At).Format("15:04"), record.Latitude, record.Longitude,
+		strings.Join(anomalies, "\n- "))
+	if record.Address != "" {
+		description += "\nĐịa chỉ: " + record.Address
+	}
+
+	if err := w.sendLocationAlert("🚨 Vị trí bất thường", description); err != nil {
+		logger.Error("Failed to send anomaly alert", "user_id", record.UserID, "err", err)
+	}
+}
@@ -0,0 +1,112 @@
+package webrtc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// HOSTED IMAGES - Short-lived image URLs for embeds
+// ============================================================
+
+// Mezon embeds load images by URL and there is no upload API here, so QR
+// codes, face crops and archived images are served from the admin server
+// under unguessable IDs that expire. Without a public base URL no image is
+// attached.
+
+// ImagePath is where ImageHandler is mounted
+const ImagePath = "/images/"
+
+const (
+	hostedImageTTL   = 15 * time.Minute
+	maxHostedImages  = 500
+	hostedImageIDLen = 16 // Random bytes in an image ID
+)
+
+type hostedImage struct {
+	data        []byte
+	contentType string
+	expires     time.Time
+}
+
+// imageHost keeps the images currently referenced by embeds
+type imageHost struct {
+	baseURL string
+	images  map[string]hostedImage
+	mu      sync.Mutex
+}
+
+// SetImageBaseURL enables hosted images at baseURL + ImagePath, the public
+// (TLS terminating) URL of the admin server. Empty disables them.
+func (w *WebRTCManager) SetImageBaseURL(baseURL string) {
+	w.images.mu.Lock()
+	w.images.baseURL = strings.TrimRight(baseURL, "/")
+	w.images.mu.Unlock()
+
+	logger.Info("Image hosting configured", "enabled", baseURL != "")
+}
+
+// hostImage stores the image for hostedImageTTL and returns its URL, or ""
+// when image hosting is disabled
+func (w *WebRTCManager) hostImage(data []byte, contentType string, ttl time.Duration) string {
+	if ttl <= 0 {
+		ttl = hostedImageTTL
+	}
+
+	id := make([]byte, hostedImageIDLen)
+	if _, err := rand.Read(id); err != nil {
+		logger.Error("Failed to generate image ID", "err", err)
+		return ""
+	}
+	key := hex.EncodeToString(id)
+
+	h := &w.images
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.baseURL == "" {
+		return ""
+	}
+
+	now := time.Now()
+	for k, image := range h.images {
+		if now.After(image.expires) {
+			delete(h.images, k)
+		}
+	}
+	if len(h.images) >= maxHostedImages {
+		logger.Warn("Too many hosted images, image not attached", "count", len(h.images))
+		return ""
+	}
+	h.images[key] = hostedImage{data: data, contentType: contentType, expires: now.Add(ttl)}
+	return h.baseURL + ImagePath + key
+}
+
+// ImageHandler serves GET ImagePath<id> while the image has not expired
+func (w *WebRTCManager) ImageHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		key := strings.TrimPrefix(r.URL.Path, ImagePath)
+		h := &w.images
+		h.mu.Lock()
+		image, exists := h.images[key]
+		h.mu.Unlock()
+		if !exists || time.Now().After(image.expires) {
+			http.NotFound(rw, r)
+			return
+		}
+
+		rw.Header().Set("Content-Type", image.contentType)
+		rw.Header().Set("Content-Length", strconv.Itoa(len(image.data)))
+		rw.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(time.Until(image.expires).Seconds())))
+		rw.Write(image.data)
+	})
+}
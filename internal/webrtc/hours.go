@@ -0,0 +1,81 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// WORKING HOURS - Per-office schedule with global defaults
+// ============================================================
+
+// workingHours is the schedule that applies to one office
+type workingHours struct {
+	start  string // "HH:MM", empty = unknown
+	end    string
+	cutoff string // Overtime starts after this, defaults to end
+	zone   *time.Location
+}
+
+// validateWorkingHours checks that each set value is a valid "HH:MM"
+func validateWorkingHours(start, end, cutoff string) error {
+	for _, value := range []string{start, end, cutoff} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", value); err != nil {
+			return fmt.Errorf("invalid time %q, expected HH:MM: %w", value, err)
+		}
+	}
+	if start != "" && end != "" && end <= start {
+		return fmt.Errorf("work end %s is not after work start %s", end, start)
+	}
+	if cutoff != "" && end != "" && cutoff < end {
+		return fmt.Errorf("overtime cutoff %s is before work end %s", cutoff, end)
+	}
+	return nil
+}
+
+// SetOfficeHours changes an office's working hours and saves them.
+// An empty cutoff means overtime starts at the end of the day.
+func (c *LocationConfig) SetOfficeHours(id, start, end, cutoff string, actor int64) error {
+	if err := validateWorkingHours(start, end, cutoff); err != nil {
+		return err
+	}
+	return c.updateOffice(id, actor, strings.TrimSpace("hours "+start+" "+end+" "+cutoff), func(office *Office) {
+		office.WorkStart = start
+		office.WorkEnd = end
+		office.OvertimeCutoff = cutoff
+	})
+}
+
+// officeHours returns the office's working hours, falling back field by
+// field to the attendance defaults ("" or unknown ID = defaults only)
+func (w *WebRTCManager) officeHours(officeID string) workingHours {
+	w.mu.RLock()
+	cfg := w.attendance
+	w.mu.RUnlock()
+
+	hours := workingHours{start: cfg.WorkStart, end: cfg.WorkEnd, cutoff: cfg.OvertimeCutoff, zone: Office{}.Zone()}
+	if officeID != "" && w.locationConfig != nil {
+		for _, office := range w.locationConfig.AllOffices() {
+			if !strings.EqualFold(office.ID, officeID) {
+				continue
+			}
+			hours.zone = office.Zone()
+			if office.WorkStart != "" {
+				hours.start = office.WorkStart
+			}
+			if office.WorkEnd != "" {
+				hours.end = office.WorkEnd
+				hours.cutoff = office.OvertimeCutoff
+			}
+			break
+		}
+	}
+	if hours.cutoff == "" {
+		hours.cutoff = hours.end
+	}
+	return hours
+}
@@ -2,7 +2,6 @@ package webrtc
 
 import (
 	"encoding/json"
-	"log"
 	"mezon-checkin-bot/models"
 	"strings"
 	"time"
@@ -15,9 +14,7 @@ import (
 // ============================================================
 
 func (w *WebRTCManager) sendICECandidate(userID int64, candidate *webrtc.ICECandidate) {
-	w.mu.RLock()
-	state, exists := w.connections[userID]
-	w.mu.RUnlock()
+	state, exists := w.connections.Get(userID)
 
 	if !exists {
 		return
@@ -26,7 +23,7 @@ func (w *WebRTCManager) sendICECandidate(userID int64, candidate *webrtc.ICECand
 	init := candidate.ToJSON()
 	candidateJSON, err := json.Marshal(init)
 	if err != nil {
-		log.Printf("⚠️  Failed to marshal ICE candidate: %v", err)
+		state.logger.Warn("Failed to marshal ICE candidate", "err", err)
 		return
 	}
 
@@ -37,7 +34,7 @@ func (w *WebRTCManager) sendICECandidate(userID int64, candidate *webrtc.ICECand
 		models.WebrtcICECandidate,
 		string(candidateJSON),
 	); err != nil {
-		log.Printf("⚠️  Failed to send ICE candidate: %v", err)
+		state.logger.Warn("Failed to send ICE candidate", "err", err)
 	}
 }
 
@@ -46,7 +43,8 @@ func (w *WebRTCManager) sendICECandidate(userID int64, candidate *webrtc.ICECand
 // ============================================================
 
 func (w *WebRTCManager) sendICECandidatesFromSDP(userID int64, channelID int64, sdp string) {
-	log.Println("🔍 Extracting ICE candidates from SDP...")
+	callLog := w.callLogger(userID)
+	callLog.Debug("Extracting ICE candidates from SDP")
 
 	lines := strings.Split(strings.ReplaceAll(sdp, "\r\n", "\n"), "\n")
 	midMap := make(map[int]string)
@@ -89,7 +87,7 @@ func (w *WebRTCManager) sendICECandidatesFromSDP(userID int64, channelID int64,
 
 			candidateJSON, err := json.Marshal(candidate)
 			if err != nil {
-				log.Printf("⚠️  Failed to marshal candidate: %v", err)
+				callLog.Warn("Failed to marshal ICE candidate", "err", err)
 				continue
 			}
 
@@ -106,5 +104,5 @@ func (w *WebRTCManager) sendICECandidatesFromSDP(userID int64, channelID int64,
 		}
 	}
 
-	log.Printf("✅ Sent %d ICE candidates from SDP\n", count)
+	callLog.Info("Sent ICE candidates from SDP", "count", count)
 }
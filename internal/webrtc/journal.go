@@ -0,0 +1,237 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/utils"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ============================================================
+// CHECK-IN JOURNAL - Resume check-ins interrupted by a crash
+// ============================================================
+
+// Journal stages. An entry is removed once the check-in is settled.
+const (
+	journalRecognized = "recognized" // Face recognized, location not asked yet
+	journalSubmitting = "submitting" // Location accepted, approval being sent
+)
+
+// journalMaxAge bounds how old an entry can be and still be resent. Older
+// entries, or ones from another office day, are no longer the user's
+// check-in: approvals are not resent and the user is only told to call
+// again.
+const journalMaxAge = 10 * time.Minute
+
+// journalEntry is the last stage a check-in reached
+type journalEntry struct {
+	UserID    int64     `json:"user_id"`
+	ChannelID int64     `json:"channel_id"`
+	CallID    string    `json:"call_id,omitempty"`
+	Stage     string    `json:"stage"`
+	At        time.Time `json:"at"`
+
+	// Set when submitting, to resend the approval
+	Place     string             `json:"place,omitempty"`
+	Location  *ConfirmedLocation `json:"location,omitempty"`
+	QR        bool               `json:"qr,omitempty"`
+	WFH       bool               `json:"wfh,omitempty"`
+	DistanceM float64            `json:"distance_m,omitempty"`
+}
+
+// RecoverJournal reloads the journal at path and keeps writing there.
+// Approvals that were being sent are sent again (the backend treats a
+// repeat as already checked in); users whose check-in stopped before the
+// location request are asked to call again. Entries older than
+// journalMaxAge are only reported. Call it after
+// RestorePendingConfirmations. A standby only keeps the path and recovers
+// when it takes over; its own check-ins already journaled are kept.
+func (w *WebRTCManager) RecoverJournal(path string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+	w.journalMu.Lock()
+	w.journalPath = path
+	if w.journalEntries == nil {
+		w.journalEntries = make(map[int64]journalEntry)
+	}
+	w.journalMu.Unlock()
+	if w.Standby() {
+		return 0, nil
+	}
+
+	var entries []journalEntry
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return 0, fmt.Errorf("failed to read check-in journal: %w", err)
+	default:
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return 0, fmt.Errorf("failed to parse check-in journal: %w", err)
+		}
+	}
+
+	// Entries stay journaled until recovered, in case of another crash
+	w.journalMu.Lock()
+	recovered := entries[:0]
+	for _, entry := range entries {
+		if _, exists := w.journalEntries[entry.UserID]; exists {
+			continue // This instance's own check-in, or already recovering
+		}
+		w.journalEntries[entry.UserID] = entry
+		recovered = append(recovered, entry)
+	}
+	w.saveJournalLocked()
+	w.journalMu.Unlock()
+
+	for _, entry := range recovered {
+		go w.recoverEntry(entry)
+	}
+	if len(recovered) > 0 {
+		logger.Info("Recovering interrupted check-ins", "count", len(recovered))
+	}
+	return len(recovered), nil
+}
+
+// handOverJournal leaves the check-ins waiting for a location to the next
+// leader, which restores their confirmations. The ones still running here,
+// an open call or an approval being sent, stay in memory only.
+func (w *WebRTCManager) handOverJournal() {
+	w.journalMu.Lock()
+	defer w.journalMu.Unlock()
+	if w.journalPath == "" {
+		return
+	}
+
+	kept := make(map[int64]journalEntry)
+	for userID, entry := range w.journalEntries {
+		if entry.Stage != journalRecognized || w.connections.Has(userID) {
+			kept[userID] = entry
+			delete(w.journalEntries, userID)
+		}
+	}
+	w.saveJournalLocked()
+	w.journalEntries = kept
+}
+
+func (w *WebRTCManager) recoverEntry(entry journalEntry) {
+	userID, channelID := entry.UserID, entry.ChannelID
+	log := logger.With("user_id", userID, "call_id", entry.CallID, "stage", entry.Stage)
+	defer w.journalDone(userID)
+
+	if w.journalStale(entry) {
+		log.Info("Dropping stale check-in journal entry", "at", entry.At)
+		if entry.Stage != journalSubmitting {
+			return
+		}
+		w.recordEvent(userID, CheckinEvent{CallID: entry.CallID, Outcome: OutcomeFailed, Reason: "interrupted"})
+		if err := w.SendCheckinNotice(channelID, userID, w.text(userID, "notice.interrupted", nil)); err != nil {
+			log.Error("Failed to notify user of interrupted check-in", "err", err)
+		}
+		return
+	}
+
+	switch entry.Stage {
+	case journalSubmitting:
+		log.Info("Resending interrupted approval")
+		var err error
+		if entry.QR && entry.Location != nil {
+			err = w.approveQRCheckin(userID, channelID, *entry.Location, entry.Place)
+		} else {
+			err = w.approveCheckin(userID, channelID, entry.Place)
+		}
+		if err != nil {
+			log.Error("Interrupted approval failed", "err", err)
+			return
+		}
+		event := CheckinEvent{CallID: entry.CallID, WFH: entry.WFH, DistanceM: entry.DistanceM}
+		if entry.Location != nil {
+			event.OfficeID = entry.Location.OfficeID
+			w.recordConfirmedLocation(userID, *entry.Location)
+		}
+		w.recordApproval(userID, channelID, event)
+
+	case journalRecognized:
+		w.confirmationMu.Lock()
+		_, pending := w.pendingConfirmations[userID]
+		w.confirmationMu.Unlock()
+		if pending {
+			// The restored confirmation picks up from here
+			return
+		}
+		log.Info("Check-in interrupted before the location request")
+		w.recordEvent(userID, CheckinEvent{CallID: entry.CallID, Outcome: OutcomeFailed, Reason: "interrupted"})
+		notice := w.text(userID, "notice.interrupted", nil)
+		if err := w.SendCheckinNotice(channelID, userID, notice); err != nil {
+			log.Error("Failed to notify user of interrupted check-in", "err", err)
+		}
+	}
+}
+
+// journalStale reports whether the entry is too old to resume: older than
+// journalMaxAge or from an earlier day at the office
+func (w *WebRTCManager) journalStale(entry journalEntry) bool {
+	if time.Since(entry.At) > journalMaxAge {
+		return true
+	}
+	zone := Office{}.Zone()
+	if entry.Location != nil && w.locationConfig != nil {
+		zone = w.locationConfig.OfficeZone(entry.Location.OfficeID)
+	}
+	y1, m1, d1 := entry.At.In(zone).Date()
+	y2, m2, d2 := time.Now().In(zone).Date()
+	return y1 != y2 || m1 != m2 || d1 != d2
+}
+
+// journal records the stage a check-in reached
+func (w *WebRTCManager) journal(entry journalEntry) {
+	if entry.CallID == "" {
+		entry.CallID = w.callID(entry.UserID)
+	}
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+
+	w.journalMu.Lock()
+	defer w.journalMu.Unlock()
+	if w.journalEntries == nil {
+		return
+	}
+	w.journalEntries[entry.UserID] = entry
+	w.saveJournalLocked()
+}
+
+// journalDone removes the user's entry once the check-in is settled
+func (w *WebRTCManager) journalDone(userID int64) {
+	w.journalMu.Lock()
+	defer w.journalMu.Unlock()
+	if _, exists := w.journalEntries[userID]; !exists {
+		return
+	}
+	delete(w.journalEntries, userID)
+	w.saveJournalLocked()
+}
+
+// saveJournalLocked writes the journal atomically. Caller holds journalMu.
+// A standby leaves the file to the leader.
+func (w *WebRTCManager) saveJournalLocked() {
+	if w.journalPath == "" || w.Standby() {
+		return
+	}
+
+	entries := make([]journalEntry, 0, len(w.journalEntries))
+	for _, entry := range w.journalEntries {
+		entries = append(entries, entry)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		logger.Error("Failed to marshal check-in journal", "err", err)
+		return
+	}
+	if err := utils.WriteFileAtomic(w.journalPath, data, 0644); err != nil {
+		logger.Error("Failed to write check-in journal", "err", err)
+	}
+}
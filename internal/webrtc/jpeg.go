@@ -0,0 +1,56 @@
+package webrtc
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// JPEG ENCODING - Straight from the Mat with OpenCV, or through
+// image.Image and image/jpeg
+// ============================================================
+
+const (
+	JPEGEncoderOpenCV = "opencv" // Default: encodes the Mat's pixels in place
+	JPEGEncoderGo     = "go"     // Copies the pixels twice (Mat -> image.Image -> encoder)
+)
+
+// EncodeJPEG writes the JPEG encoding of mat into buf with the encoder,
+// OpenCV unless it is JPEGEncoderGo. OpenCV falls back to image/jpeg when it
+// fails.
+func EncodeJPEG(mat gocv.Mat, quality int, encoder string, buf *bytes.Buffer) error {
+	if encoder == JPEGEncoderGo {
+		return encodeJPEGGo(mat, quality, buf)
+	}
+	if err := encodeJPEGOpenCV(mat, quality, buf); err != nil {
+		logger.Warn("OpenCV JPEG encoding failed, using image/jpeg", "err", err)
+		return encodeJPEGGo(mat, quality, buf)
+	}
+	return nil
+}
+
+// encodeJPEGOpenCV encodes in C; only the compressed bytes are copied out
+func encodeJPEGOpenCV(mat gocv.Mat, quality int, buf *bytes.Buffer) error {
+	encoded, err := gocv.IMEncodeWithParams(gocv.JPEGFileExt, mat, []int{gocv.IMWriteJpegQuality, quality})
+	if err != nil {
+		return fmt.Errorf("IMEncode failed: %w", err)
+	}
+	defer encoded.Close()
+	buf.Grow(encoded.Len())
+	buf.Write(encoded.GetBytes())
+	return nil
+}
+
+func encodeJPEGGo(mat gocv.Mat, quality int, buf *bytes.Buffer) error {
+	img, err := mat.ToImage()
+	if err != nil {
+		return fmt.Errorf("ToImage failed: %w", err)
+	}
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return fmt.Errorf("jpeg encode failed: %w", err)
+	}
+	return nil
+}
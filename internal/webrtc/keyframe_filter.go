@@ -0,0 +1,81 @@
+package webrtc
+
+import (
+	"sync/atomic"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+)
+
+// ============================================================
+// KEYFRAME FILTER - Detection only decodes keyframes, so delta
+// frames are dropped packet by packet before the samplebuilder
+// assembles them
+// ============================================================
+
+// recentFrames is how many frames the filter remembers, for packets
+// arriving after the next frame started
+const recentFrames = 4
+
+type filteredFrame struct {
+	timestamp uint32
+	keyframe  bool
+}
+
+// keyframeFilter passes the RTP packets of VP8 keyframes. Passed packets
+// are renumbered to close the gaps left by dropped frames, otherwise the
+// samplebuilder would wait for them as if they were lost.
+type keyframeFilter struct {
+	frames  [recentFrames]filteredFrame
+	next    int           // Slot of frames overwritten next
+	seen    int           // Slots in use
+	offset  uint16        // Packets dropped so far, subtracted from sequence numbers
+	dropped atomic.Uint64 // Read once the call ends, while the reader may still run
+}
+
+// pass reports whether pkt belongs to a keyframe, renumbering it if so.
+// A frame whose first packet is lost or late is dropped whole; the PLI
+// sender asks for another keyframe.
+func (f *keyframeFilter) pass(pkt *rtp.Packet) bool {
+	keyframe, known := f.lookup(pkt.Timestamp)
+	if !known {
+		keyframe = startsKeyframe(pkt.Payload)
+		f.remember(pkt.Timestamp, keyframe)
+	}
+	if !keyframe {
+		f.offset++
+		f.dropped.Add(1)
+		return false
+	}
+	pkt.SequenceNumber -= f.offset
+	return true
+}
+
+func (f *keyframeFilter) lookup(timestamp uint32) (keyframe, known bool) {
+	for i := 0; i < f.seen; i++ {
+		if f.frames[i].timestamp == timestamp {
+			return f.frames[i].keyframe, true
+		}
+	}
+	return false, false
+}
+
+func (f *keyframeFilter) remember(timestamp uint32, keyframe bool) {
+	f.frames[f.next] = filteredFrame{timestamp: timestamp, keyframe: keyframe}
+	f.next = (f.next + 1) % recentFrames
+	if f.seen < recentFrames {
+		f.seen++
+	}
+}
+
+// startsKeyframe reports whether the RTP payload is the first packet of a
+// VP8 keyframe: the start of partition 0, whose frame tag has the
+// inter-frame bit clear
+func startsKeyframe(payload []byte) bool {
+	var descriptor codecs.VP8Packet
+	frame, err := descriptor.Unmarshal(payload)
+	if err != nil || descriptor.S != 1 || descriptor.PID != 0 || len(frame) == 0 {
+		return false
+	}
+	return frame[0]&0x01 == 0
+}
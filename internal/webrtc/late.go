@@ -0,0 +1,217 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/i18n"
+	"mezon-checkin-bot/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ============================================================
+// LATE CHECK-IN - Detection and reason collection
+// ============================================================
+
+const (
+	lateReasonTTL    = 30 * time.Minute // How long the reason prompt stays open
+	maxLateReasonLen = 500
+)
+
+// lateReasonRequest is an open prompt for a late check-in reason
+type lateReasonRequest struct {
+	channelID int64
+	date      string // Office date of the check-in, "2006-01-02"
+	minutes   int
+	askedAt   time.Time
+}
+
+// recordApproval records an approved check-in, tagging it late when the user
+// was recognized after their shift start, and asks late users for a reason.
+// Check-ins outside working hours are offered an overtime registration instead.
+func (w *WebRTCManager) recordApproval(userID, channelID int64, event CheckinEvent) {
+	event.Outcome = OutcomeApproved
+
+	checkedInAt, minutes, late := w.lateness(userID, event.OfficeID)
+	if late {
+		event.Late = true
+		event.LateMins = minutes
+	}
+	w.recordEvent(userID, event)
+	w.announceCheckin(userID, event, checkedInAt)
+
+	if late {
+		w.askLateReason(userID, channelID, checkedInAt, minutes)
+	} else {
+		w.offerOvertime(userID, channelID, event.OfficeID, ModeCheckin, checkedInAt)
+	}
+}
+
+// lateness returns when the user checked in and how many minutes after their
+// shift start (or the office's work start) that was. Check-ins after the
+// overtime cutoff are a separate session, never late.
+func (w *WebRTCManager) lateness(userID int64, officeID string) (time.Time, int, bool) {
+	hours := w.officeHours(officeID)
+
+	w.mu.RLock()
+	grace := w.attendance.LateGrace
+	info, recognized := w.shifts[userID]
+	w.mu.RUnlock()
+
+	// Recognition is the moment the user showed up, approval may come later
+	checkedInAt := time.Now().In(hours.zone)
+	if recognized && info.at.In(hours.zone).Format("2006-01-02") == checkedInAt.Format("2006-01-02") {
+		checkedInAt = info.at.In(hours.zone)
+	} else {
+		info = shiftInfo{}
+	}
+
+	if cutoff, ok := clockOn(hours.cutoff, checkedInAt); ok && checkedInAt.After(cutoff) {
+		return checkedInAt, 0, false
+	}
+
+	start, ok := shiftStart(info.shift, checkedInAt)
+	if !ok && hours.start != "" {
+		start, ok = clockOn(hours.start, checkedInAt)
+	}
+	if !ok || !checkedInAt.After(start.Add(grace)) {
+		return checkedInAt, 0, false
+	}
+	return checkedInAt, int(checkedInAt.Sub(start).Minutes()), true
+}
+
+// shiftStart returns the shift's start on the day of ref
+func shiftStart(shift *models.Shift, ref time.Time) (time.Time, bool) {
+	if shift == nil || shift.StartTime == "" {
+		return time.Time{}, false
+	}
+	if t, ok := clockOn(shift.StartTime, ref); ok {
+		return t, true
+	}
+	return parseBackendTime(shift.StartTime, ref.Location())
+}
+
+// clockOn places an "HH:MM[:SS]" time on the day of ref
+func clockOn(value string, ref time.Time) (time.Time, bool) {
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(ref.Year(), ref.Month(), ref.Day(), t.Hour(), t.Minute(), t.Second(), 0, ref.Location()), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// askLateReason opens a reason prompt and DMs the user
+func (w *WebRTCManager) askLateReason(userID, channelID int64, checkedInAt time.Time, minutes int) {
+	w.mu.Lock()
+	w.lateReasons[userID] = &lateReasonRequest{
+		channelID: channelID,
+		date:      checkedInAt.Format("2006-01-02"),
+		minutes:   minutes,
+		askedAt:   time.Now(),
+	}
+	w.mu.Unlock()
+
+	w.callLogger(userID).Info("Late check-in", "late_minutes", minutes)
+	if w.dmManager == nil {
+		return
+	}
+
+	content := client.BuildCheckinNoticeMessage(w.tr(userID), w.text(userID, "notice.late_reason", i18n.Vars{
+		"minutes": minutes,
+		"ttl":     int(lateReasonTTL.Minutes()),
+	}))
+	if err := w.sendDM("late_reason", channelID, userID, content); err != nil {
+		w.callLogger(userID).Error("Failed to ask for late reason", "err", err)
+	}
+}
+
+// ============================================================
+// LATE REASON REPLY
+// ============================================================
+
+// handleTextMessageEvent takes plain text DMs as scanned QR codes or late reasons
+func (w *WebRTCManager) handleTextMessageEvent(data interface{}) {
+	eventMap, ok := data.(map[string]interface{})
+	if !ok {
+		logger.Error("Invalid text message event data type")
+		return
+	}
+
+	userID, _ := eventMap["user_id"].(int64)
+	channelID, _ := eventMap["channel_id"].(int64)
+	text, _ := eventMap["text"].(string)
+	if userID == 0 || text == "" {
+		return
+	}
+
+	// QR codes start a check-in, left to the leader
+	if code := strings.TrimSpace(text); !w.Standby() && strings.HasPrefix(code, qrCodePrefix) && w.handleQRCode(userID, channelID, code) {
+		return
+	}
+
+	w.mu.Lock()
+	request, exists := w.lateReasons[userID]
+	if exists {
+		delete(w.lateReasons, userID)
+	}
+	w.mu.Unlock()
+
+	if !exists || time.Since(request.askedAt) > lateReasonTTL {
+		return
+	}
+
+	if utf8.RuneCountInString(text) > maxLateReasonLen {
+		text = string([]rune(text)[:maxLateReasonLen])
+	}
+
+	var reply models.ChannelMessageContent
+	if err := w.submitLateReason(userID, request, text); err != nil {
+		logger.Error("Failed to submit late reason", "user_id", userID, "err", err)
+		reply = client.BuildErrorMessage("❌ Không thể gửi lý do đi muộn", "Vui lòng liên hệ quản lý của bạn.")
+	} else {
+		logger.Info("Late reason submitted", "user_id", userID, "late_minutes", request.minutes)
+		reply = client.BuildSuccessMessage("✅ Đã ghi nhận lý do đi muộn", "Cảm ơn bạn!")
+	}
+	w.replyCommand(channelID, userID, reply)
+}
+
+// submitLateReason forwards the reason, queueing it while the backend is down
+func (w *WebRTCManager) submitLateReason(userID int64, request *lateReasonRequest, reason string) error {
+	w.mu.RLock()
+	queue := w.queue
+	w.mu.RUnlock()
+
+	reqBody := models.LateReason{
+		UserId:      userID,
+		Date:        request.date,
+		LateMinutes: request.minutes,
+		Reason:      reason,
+	}
+
+	var body []byte
+	var statusCode int
+	var err error
+	if queue != nil {
+		key := fmt.Sprintf("late-reason:%d:%s", userID, request.date)
+		body, statusCode, err = queue.SubmitOrQueue(context.Background(), key, models.APILateReason, reqBody)
+		if errors.Is(err, api.ErrQueued) {
+			return nil
+		}
+	} else {
+		body, statusCode, err = w.apiClient.SendRequestContext(context.Background(), reqBody, models.APILateReason)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.apiClient.LogResponse(body, statusCode)
+	if apiErr := api.ParseError(body, statusCode); apiErr != nil {
+		return apiErr
+	}
+	return nil
+}
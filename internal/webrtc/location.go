@@ -1,75 +1,119 @@
 package webrtc
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"math"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/audit"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/i18n"
+	"mezon-checkin-bot/internal/tracing"
+	mzapi "mezon-checkin-bot/mezon-protobuf/go/api"
 	"mezon-checkin-bot/models"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
+// defaultMaxAccuracyMeters is the worst reported GPS accuracy accepted
+const defaultMaxAccuracyMeters = 50.0
+
 // ============================================================
 // LOAD OFFICES
 // ============================================================
 
 func (c *LocationConfig) LoadOffices() error {
 	if !c.Enabled {
-		log.Println("📍 Location validation is disabled")
+		logger.Info("Location validation is disabled")
 		return nil
 	}
 
+	offices, source, err := c.loadOfficeList()
+	if err != nil {
+		return err
+	}
+	checkOfficeTimezones(offices)
+
+	c.mu.Lock()
+	c.allOffices = offices
+	c.rebuildEnabledOffices()
+	c.mu.Unlock()
+
+	if len(c.offices) == 0 {
+		return fmt.Errorf("no enabled offices found in %s", source)
+	}
+
+	logger.Info("Loaded office locations", "count", len(c.offices), "source", source)
+	for _, office := range c.offices {
+		logger.Info("Office", "name", office.Name,
+			"lat", office.Latitude, "lon", office.Longitude, "radius_m", office.RadiusMeters)
+	}
+
+	return nil
+}
+
+// loadOfficeList reads the offices from the store, importing the file into
+// an empty store, or from the file when there is no store
+func (c *LocationConfig) loadOfficeList() ([]Office, string, error) {
+	if c.OfficeStore == nil {
+		offices, err := c.readOfficesFile()
+		return offices, c.OfficesFilePath, err
+	}
+
+	offices, err := c.OfficeStore.Offices()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load offices from database: %w", err)
+	}
+	if len(offices) > 0 {
+		return offices, "database", nil
+	}
+
+	logger.Info("Office database is empty, importing offices file", "path", c.OfficesFilePath)
+	if offices, err = c.readOfficesFile(); err != nil {
+		return nil, "", err
+	}
+	for _, office := range offices {
+		if _, err := c.OfficeStore.SaveOffice(office, 0, "import "+filepath.Base(c.OfficesFilePath)); err != nil {
+			return nil, "", fmt.Errorf("failed to import offices: %w", err)
+		}
+	}
+	return offices, "database", nil
+}
+
+// readOfficesFile parses offices.json, creating the default file if missing
+func (c *LocationConfig) readOfficesFile() ([]Office, error) {
 	workDir, _ := os.Getwd()
-	log.Printf("🔍 Current working directory: %s", workDir)
-	log.Printf("🔍 Looking for offices file at: %s", c.OfficesFilePath)
+	logger.Debug("Loading offices", "cwd", workDir, "path", c.OfficesFilePath)
 
 	if _, err := os.Stat(c.OfficesFilePath); os.IsNotExist(err) {
-		log.Printf("⚠️  Offices file not found, creating default...")
+		logger.Warn("Offices file not found, creating default", "path", c.OfficesFilePath)
 
 		dir := filepath.Dir(c.OfficesFilePath)
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory: %w", err)
+			return nil, fmt.Errorf("failed to create directory: %w", err)
 		}
 
 		if err := c.createDefaultOfficesFile(); err != nil {
-			return fmt.Errorf("failed to create default offices file: %w", err)
+			return nil, fmt.Errorf("failed to create default offices file: %w", err)
 		}
 
-		log.Printf("✅ Created default offices file at: %s", c.OfficesFilePath)
+		logger.Info("Created default offices file", "path", c.OfficesFilePath)
 	}
 
 	data, err := os.ReadFile(c.OfficesFilePath)
 	if err != nil {
-		return fmt.Errorf("failed to read offices file: %w", err)
+		return nil, fmt.Errorf("failed to read offices file: %w", err)
 	}
 
 	var officeList OfficeList
 	if err := json.Unmarshal(data, &officeList); err != nil {
-		return fmt.Errorf("failed to parse offices JSON: %w", err)
-	}
-
-	c.mu.Lock()
-	c.offices = make([]Office, 0, len(officeList.Offices))
-	for _, office := range officeList.Offices {
-		if office.Enabled {
-			c.offices = append(c.offices, office)
-		}
-	}
-	c.mu.Unlock()
-
-	if len(c.offices) == 0 {
-		return fmt.Errorf("no enabled offices found in %s", c.OfficesFilePath)
-	}
-
-	log.Printf("✅ Loaded %d office location(s):", len(c.offices))
-	for _, office := range c.offices {
-		log.Printf("   - %s: (%.6f, %.6f) - radius: %.0fm",
-			office.Name, office.Latitude, office.Longitude, office.RadiusMeters)
+		return nil, fmt.Errorf("failed to parse offices JSON: %w", err)
 	}
-
-	return nil
+	return officeList.Offices, nil
 }
 
 func (c *LocationConfig) createDefaultOfficesFile() error {
@@ -146,6 +190,14 @@ func (c *LocationConfig) createDefaultOfficesFile() error {
 	return nil
 }
 
+// maxAccuracy is the worst GPS accuracy accepted for a location reply
+func (c *LocationConfig) maxAccuracy() float64 {
+	if c.MaxAccuracyMeters > 0 {
+		return c.MaxAccuracyMeters
+	}
+	return defaultMaxAccuracyMeters
+}
+
 func (c *LocationConfig) GetOffices() []Office {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -185,8 +237,11 @@ func calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
 // FIND NEAREST OFFICE
 // ============================================================
 
-func (w *WebRTCManager) findNearestOffice(lat, lon float64) *LocationMatch {
-	offices := w.locationConfig.GetOffices()
+// findNearestOffice returns the closest allowed office. The reported GPS
+// accuracy is client supplied, so it never widens the radius: imprecise
+// fixes are rejected up front by maxAccuracy instead.
+func (w *WebRTCManager) findNearestOffice(userID int64, lat, lon, accuracy float64) *LocationMatch {
+	offices := w.locationConfig.GetOfficesForUser(userID)
 
 	if len(offices) == 0 {
 		return nil
@@ -205,6 +260,7 @@ func (w *WebRTCManager) findNearestOffice(lat, lon float64) *LocationMatch {
 		match := &LocationMatch{
 			Office:   office,
 			Distance: distance,
+			Accuracy: accuracy,
 			IsValid:  distance <= office.RadiusMeters,
 		}
 
@@ -220,54 +276,67 @@ func (w *WebRTCManager) findNearestOffice(lat, lon float64) *LocationMatch {
 // VALIDATE LOCATION
 // ============================================================
 
-func (w *WebRTCManager) validateLocation(lat, lon float64) bool {
-	if !w.locationConfig.Enabled {
-		log.Println("⚠️  Location validation disabled")
-		return true
-	}
+func (w *WebRTCManager) validateLocation(userID int64, lat, lon float64) bool {
+	_, valid := w.matchLocation(userID, lat, lon, 0)
+	return valid
+}
 
+// validCoordinates rejects (0, 0) and out of range coordinates
+func validCoordinates(lat, lon float64) bool {
 	if lat == 0 && lon == 0 {
-		log.Println("❌ Invalid coordinates: (0, 0)")
+		logger.Warn("Invalid coordinates (0, 0)")
 		return false
 	}
 
 	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
-		log.Printf("❌ Invalid coordinates range: (%.6f, %.6f)", lat, lon)
+		logger.Warn("Invalid coordinates range", "lat", lat, "lon", lon)
 		return false
 	}
+	return true
+}
 
-	match := w.findNearestOffice(lat, lon)
-	if match == nil {
-		log.Println("❌ No offices configured")
-		return false
+// matchLocation validates the coordinates against the offices the user is
+// assigned to and returns the nearest one (nil when validation is disabled
+// or the coordinates are unusable)
+func (w *WebRTCManager) matchLocation(userID int64, lat, lon, accuracy float64) (*LocationMatch, bool) {
+	if !w.locationConfig.Enabled {
+		logger.Debug("Location validation disabled")
+		return nil, true
 	}
 
-	log.Printf("📍 Location validation:")
-	log.Printf("   User location: (%.6f, %.6f)", lat, lon)
-	log.Printf("   Nearest office: %s", match.Office.Name)
-	log.Printf("   Office location: (%.6f, %.6f)", match.Office.Latitude, match.Office.Longitude)
-	log.Printf("   Distance: %.2f meters", match.Distance)
-	log.Printf("   Max allowed: %.2f meters", match.Office.RadiusMeters)
+	if !validCoordinates(lat, lon) {
+		return nil, false
+	}
 
-	if match.IsValid {
-		log.Printf("   ✅ Location is VALID (within %s radius)", match.Office.Name)
-	} else {
-		log.Printf("   ❌ Location is INVALID (%.2fm > %.2fm from %s)",
-			match.Distance, match.Office.RadiusMeters, match.Office.Name)
-
-		offices := w.locationConfig.GetOffices()
-		if len(offices) > 1 {
-			log.Printf("   Other offices:")
-			for _, office := range offices {
-				if office.ID != match.Office.ID {
-					dist := calculateDistance(office.Latitude, office.Longitude, lat, lon)
-					log.Printf("      - %s: %.2fm away", office.Name, dist)
-				}
+	match := w.findNearestOffice(userID, lat, lon, accuracy)
+	if match == nil {
+		if w.locationConfig.AssignedOffices(userID) != nil {
+			logger.Warn("None of the assigned offices are enabled", "user_id", userID)
+		} else {
+			logger.Error("No offices configured")
+		}
+		return nil, false
+	}
+
+	logger.Info("Location validation",
+		"user_id", userID,
+		"lat", lat, "lon", lon,
+		"accuracy_m", accuracy,
+		"office", match.Office.Name,
+		"distance_m", match.Distance,
+		"radius_m", match.Office.RadiusMeters,
+		"valid", match.IsValid)
+
+	if !match.IsValid {
+		for _, office := range w.locationConfig.GetOfficesForUser(userID) {
+			if office.ID != match.Office.ID {
+				dist := calculateDistance(office.Latitude, office.Longitude, lat, lon)
+				logger.Debug("Distance to other office", "user_id", userID, "office", office.Name, "distance_m", dist)
 			}
 		}
 	}
 
-	return match.IsValid
+	return match, match.IsValid
 }
 
 // ============================================================
@@ -275,19 +344,21 @@ func (w *WebRTCManager) validateLocation(lat, lon float64) bool {
 // ============================================================
 
 func (w *WebRTCManager) SetupLocationHandler() {
-	log.Println("🎧 Setting up location message handler...")
+	logger.Info("Setting up location message handler")
+	w.client.SetLocationAwaited(w.hasPendingConfirmation)
 
-	w.client.On("location_message_received", func(data interface{}) {
+	w.onOwned("location_message_received", func(data interface{}) {
 		w.handleLocationMessageEvent(data)
 	})
-
-	log.Println("✅ Location handler setup complete")
+	w.onOwned("message_reaction", func(data interface{}) {
+		w.handleReactionEvent(data)
+	})
 }
 
 func (w *WebRTCManager) handleLocationMessageEvent(data interface{}) {
 	eventMap, ok := data.(map[string]interface{})
 	if !ok {
-		log.Printf("❌ Invalid location event data type")
+		logger.Error("Invalid location event data type")
 		return
 	}
 
@@ -296,22 +367,51 @@ func (w *WebRTCManager) handleLocationMessageEvent(data interface{}) {
 	displayName, _ := eventMap["display_name"].(string)
 	latitude, latOk := eventMap["latitude"].(float64)
 	longitude, lonOk := eventMap["longitude"].(float64)
+	accuracy, _ := eventMap["accuracy"].(float64)
+	typed, _ := eventMap["typed"].(bool)
+	shortLink, _ := eventMap["short_link"].(string)
 
-	if !latOk || !lonOk {
-		log.Printf("❌ Missing or invalid coordinates in event")
+	if userID == 0 || channelID == 0 {
+		logger.Warn("Missing user_id or channel_id in event")
 		return
 	}
 
-	if userID == 0 || channelID == 0 {
-		log.Printf("❌ Missing user_id or channel_id in event")
+	// Short links take HTTP requests to resolve, only done for a pending
+	// check-in and here, off the message dispatch
+	if shortLink != "" {
+		if !w.hasPendingConfirmation(userID) {
+			w.callLogger(userID).Warn("No pending confirmation, short link not resolved")
+			return
+		}
+		var err error
+		latitude, longitude, err = client.ResolveLocationLink(w.traceContext(userID), shortLink)
+		if err != nil {
+			w.callLogger(userID).Warn("Failed to resolve map short link", "link", shortLink, "err", err)
+			if err := w.SendCheckinNotice(channelID, userID, w.text(userID, "notice.short_link_failed", nil)); err != nil {
+				logger.Error("Failed to send short link notice", "user_id", userID, "err", err)
+			}
+			return
+		}
+		latOk, lonOk = true, true
+	}
+
+	if !latOk || !lonOk {
+		logger.Warn("Missing or invalid coordinates in event")
 		return
 	}
 
-	log.Printf("📍 Processing location from %s (%d)", displayName, userID)
-	log.Printf("   Coordinates: (%.6f, %.6f)", latitude, longitude)
+	logger.Info("Processing location", "user_id", userID, "display_name", displayName,
+		"lat", latitude, "lon", longitude, "accuracy_m", accuracy)
 
-	if err := w.HandleLocationReply(userID, channelID, latitude, longitude); err != nil {
-		log.Printf("❌ Failed to handle location reply: %v", err)
+	// Answers to the location are threaded under the user's message
+	if message, ok := eventMap["message"].(*mzapi.ChannelMessage); ok {
+		w.setReplyTarget(userID, message)
+		defer w.setReplyTarget(userID, nil)
+		go w.acknowledgeLocation(channelID, message)
+	}
+
+	if err := w.handleLocationReply(userID, channelID, latitude, longitude, accuracy, typed); err != nil {
+		logger.Error("Failed to handle location reply", "user_id", userID, "err", err)
 	}
 }
 
@@ -319,13 +419,235 @@ func (w *WebRTCManager) handleLocationMessageEvent(data interface{}) {
 // HANDLE LOCATION REPLY
 // ============================================================
 
-func (w *WebRTCManager) HandleLocationReply(userID int64, channelID int64, latitude, longitude float64) error {
+// HandleLocationReply validates the location reply to a pending check-in.
+// accuracy is the reported GPS accuracy in meters (0 = unknown).
+func (w *WebRTCManager) HandleLocationReply(userID int64, channelID int64, latitude, longitude, accuracy float64) error {
+	return w.handleLocationReply(userID, channelID, latitude, longitude, accuracy, false)
+}
+
+// handleLocationReply handles a location reply; typed coordinates (a pasted
+// link or "lat, lon" text) are never approved without manual review
+func (w *WebRTCManager) handleLocationReply(userID int64, channelID int64, latitude, longitude, accuracy float64, typed bool) error {
+	callLog := w.callLogger(userID)
+	if !w.hasPendingConfirmation(userID) {
+		callLog.Warn("No pending confirmation")
+		return fmt.Errorf("no pending confirmation")
+	}
+
+	// A poor fix keeps the confirmation pending so the user can resend
+	if maxAccuracy := w.locationConfig.maxAccuracy(); accuracy > maxAccuracy {
+		callLog.Warn("Location accuracy too poor", "accuracy_m", accuracy, "max_accuracy_m", maxAccuracy)
+		notice := w.text(userID, "notice.poor_accuracy", i18n.Vars{"accuracy": fmt.Sprintf("%.0f", accuracy)})
+		if err := w.SendCheckinNotice(channelID, userID, notice); err != nil {
+			callLog.Error("Failed to send retry prompt", "err", err)
+		}
+		return fmt.Errorf("location accuracy too poor")
+	}
+
+	// Keep the check-in trace open until the reply is handled
+	trace := w.retainTrace(userID)
+	defer w.releaseTrace(userID, trace)
+
+	wfh := w.pendingIsWFH(userID)
+	qrOffice := w.pendingQROffice(userID)
+	if !w.takePendingConfirmation(userID) {
+		return fmt.Errorf("no pending confirmation")
+	}
+
+	callLog.Info("Location confirmed", "lat", latitude, "lon", longitude)
+
+	var match *LocationMatch
+	var isValidLocation, homePending bool
+	if wfh {
+		isValidLocation, homePending = w.validateHomeLocation(userID, latitude, longitude, accuracy)
+	} else {
+		match, isValidLocation = w.matchLocation(userID, latitude, longitude, accuracy)
+		// A QR code only checks in at the office that posted it
+		if qrOffice != "" && (match == nil || !strings.EqualFold(match.Office.ID, qrOffice)) {
+			isValidLocation = false
+		}
+	}
+
+	if !isValidLocation {
+		callLog.Warn("Invalid location", "home_pending", homePending)
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "invalid_location", WFH: wfh})
+		if homePending {
+			// Resending the location won't help until an admin approves it
+			if err := w.SendCheckinFailed(channelID, userID, w.text(userID, "reason.home_pending", nil)); err != nil {
+				callLog.Error("Failed to send invalid location message", "err", err)
+			}
+		} else if qrOffice != "" {
+			// Retrying would confirm without the code, the user scans again instead
+			err := w.SendCheckinFailed(channelID, userID, w.text(userID, "reason.qr_office_mismatch", i18n.Vars{"office": qrOffice}))
+			if err != nil {
+				callLog.Error("Failed to send invalid location message", "err", err)
+			}
+		} else if err := w.SendCheckinFailedWithRetry(channelID, userID, w.text(userID, "reason.invalid_location", nil), RetryLocation, wfh); err != nil {
+			callLog.Error("Failed to send invalid location message", "err", err)
+		}
+
+		connExists := w.connections.Has(userID)
+
+		if connExists {
+			w.playCheckinFailAudio(userID)
+			go w.endCallAfterDelay(userID, "invalid_location", 2*time.Second)
+		}
+		return fmt.Errorf("invalid location")
+	}
+
+	// Only accepted locations are looked up, the geocoder is slow and rate
+	// limited
+	address := w.reverseGeocode(w.traceContext(userID), latitude, longitude)
+	if address != "" {
+		callLog.Info("Reverse geocoded address", "address", address)
+	}
+
+	place := describePlace(match, address)
+	if wfh {
+		place = describeHomePlace(address)
+	}
+	confirmed := ConfirmedLocation{
+		Latitude:  latitude,
+		Longitude: longitude,
+		Address:   address,
+		At:        time.Now(),
+	}
+	if match != nil {
+		confirmed.OfficeID = match.Office.ID
+		confirmed.Office = match.Office.Name
+	}
+
+	// Plausibility checks: suspicious replies go to manual review
+	reasons := w.checkSpoofing(userID, latitude, longitude, confirmed.At)
+	if typed {
+		// Read by the reviewers, in the default locale
+		reasons = append(reasons, w.messages.Text(w.messages.DefaultLocale(), "review.typed_location", nil))
+	}
+	if len(reasons) > 0 {
+		if qrOffice != "" {
+			// Reviews approve a recognized check-in, QR check-ins have none
+			callLog.Warn("Suspicious location for QR check-in", "reasons", reasons)
+			w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "suspicious_location", OfficeID: qrOffice})
+			return w.SendCheckinFailed(channelID, userID, w.text(userID, "reason.suspicious_location", nil))
+		}
+		w.holdForReview(&pendingReview{
+			UserID:    userID,
+			ChannelID: channelID,
+			Location:  confirmed,
+			Place:     place,
+			Reasons:   reasons,
+		})
+		return nil
+	}
+
+	approve := w.approveCheckin
+	if qrOffice != "" {
+		approve = func(userID, channelID int64, place string) error {
+			return w.approveQRCheckin(userID, channelID, confirmed, place)
+		}
+	}
+	event := CheckinEvent{OfficeID: confirmed.OfficeID, WFH: wfh}
+	if match != nil {
+		event.DistanceM = match.Distance
+	}
+	w.journal(journalEntry{
+		UserID:    userID,
+		ChannelID: channelID,
+		Stage:     journalSubmitting,
+		Place:     place,
+		Location:  &confirmed,
+		QR:        qrOffice != "",
+		WFH:       wfh,
+		DistanceM: event.DistanceM,
+	})
+	defer w.journalDone(userID)
+
+	if err := approve(userID, channelID, place); err != nil {
+		return err
+	}
+	w.recordApproval(userID, channelID, event)
+	w.recordConfirmedLocation(userID, confirmed)
+
+	return nil
+}
+
+// setReplyTarget makes the user's DMs replies to message until it is reset
+// with nil
+func (w *WebRTCManager) setReplyTarget(userID int64, message *mzapi.ChannelMessage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if message == nil {
+		delete(w.replyTargets, userID)
+		return
+	}
+	w.replyTargets[userID] = message
+}
+
+func (w *WebRTCManager) replyTarget(userID int64) *mzapi.ChannelMessage {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.replyTargets[userID]
+}
+
+func (w *WebRTCManager) recordConfirmedLocation(userID int64, confirmed ConfirmedLocation) {
+	w.locationMu.Lock()
+	w.confirmedLocations[userID] = confirmed
+	w.locationMu.Unlock()
+
+	w.recordLocationHistory(userID, confirmed)
+}
+
+// LastConfirmedLocation returns where the user last checked in from
+func (w *WebRTCManager) LastConfirmedLocation(userID int64) (ConfirmedLocation, bool) {
+	w.locationMu.RLock()
+	defer w.locationMu.RUnlock()
+	loc, exists := w.confirmedLocations[userID]
+	return loc, exists
+}
+
+// reverseGeocode returns the address of the coordinates, or "" if no
+// geocoder is configured or the lookup fails
+func (w *WebRTCManager) reverseGeocode(ctx context.Context, lat, lon float64) string {
+	if w.geocoder == nil {
+		return ""
+	}
+
+	ctx, span := tracing.Start(ctx, "location.reverse_geocode")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	address, err := w.geocoder.ReverseGeocode(ctx, lat, lon)
+	span.RecordError(err)
+	if err != nil {
+		logger.Warn("Reverse geocoding failed", "err", err)
+		return ""
+	}
+	return address
+}
+
+// describePlace formats the office name and address for the success DM
+func describePlace(match *LocationMatch, address string) string {
+	switch {
+	case match != nil && match.IsValid && address != "":
+		return match.Office.Name + ", " + address
+	case match != nil && match.IsValid:
+		return match.Office.Name
+	default:
+		return address
+	}
+}
+
+// takePendingConfirmation marks the user's pending confirmation as confirmed
+// and stops its timeout. Returns false if there was none.
+func (w *WebRTCManager) takePendingConfirmation(userID int64) bool {
 	w.confirmationMu.Lock()
+	defer w.confirmationMu.Unlock()
+
 	state, exists := w.pendingConfirmations[userID]
 	if !exists {
-		w.confirmationMu.Unlock()
-		log.Printf("⚠️  No pending confirmation for user %d", userID)
-		return fmt.Errorf("no pending confirmation")
+		return false
 	}
 
 	state.mu.Lock()
@@ -339,28 +661,35 @@ func (w *WebRTCManager) HandleLocationReply(userID int64, channelID int64, latit
 	})
 
 	delete(w.pendingConfirmations, userID)
-	w.confirmationMu.Unlock()
-
-	log.Printf("✅ Location confirmed from user %d: (%.6f, %.6f)", userID, latitude, longitude)
+	w.savePendingLocked()
 
-	isValidLocation := w.validateLocation(latitude, longitude)
+	state.span.SetAttributes("outcome", "confirmed")
+	state.span.End()
+	w.releaseTrace(userID, state.trace)
+	return true
+}
 
-	if !isValidLocation {
-		log.Printf("❌ Invalid location for user %d", userID)
-		if err := w.SendCheckinFailed(channelID, userID, "Vị trí không hợp lệ"); err != nil {
-			log.Printf("❌ Failed to send invalid location message: %v", err)
-		}
+// pendingIsWFH reports whether the pending confirmation is a WFH check-in
+func (w *WebRTCManager) pendingIsWFH(userID int64) bool {
+	w.confirmationMu.Lock()
+	defer w.confirmationMu.Unlock()
+	state, exists := w.pendingConfirmations[userID]
+	return exists && state.wfh
+}
 
-		w.mu.RLock()
-		_, connExists := w.connections[userID]
-		w.mu.RUnlock()
+// hasPendingConfirmation reports whether the user still has to confirm check-in
+func (w *WebRTCManager) hasPendingConfirmation(userID int64) bool {
+	w.confirmationMu.Lock()
+	defer w.confirmationMu.Unlock()
+	_, exists := w.pendingConfirmations[userID]
+	return exists
+}
 
-		if connExists {
-			w.playCheckinFailAudio(userID)
-			go w.endCallAfterDelay(userID, "invalid_location", 2*time.Second)
-		}
-		return fmt.Errorf("invalid location")
-	}
+// approveCheckin updates the check-in status and notifies the user
+func (w *WebRTCManager) approveCheckin(userID int64, channelID int64, place string) error {
+	callLog := w.callLogger(userID)
+	ctx, span := tracing.Start(w.traceContext(userID), "api.update_status")
+	defer span.End()
 
 	// Call API to update status
 	reqBody := models.UpdateStatus{
@@ -368,26 +697,57 @@ func (w *WebRTCManager) HandleLocationReply(userID int64, channelID int64, latit
 		Status: "APPROVED",
 	}
 
-	body, statusCode, err := w.apiClient.SendRequest(reqBody, models.APIUpdateStatus)
+	w.mu.RLock()
+	queue := w.queue
+	w.mu.RUnlock()
+
+	var body []byte
+	var statusCode int
+	var err error
+	if queue != nil {
+		// One pending approval per user and day; replays are idempotent
+		key := fmt.Sprintf("update-status:%d:%s", userID, time.Now().Format("2006-01-02"))
+		body, statusCode, err = queue.SubmitOrQueue(ctx, key, models.APIUpdateStatus, reqBody)
+		w.auditRequest(audit.ActionStatusUpdate, userID, statusCode, err)
+		if errors.Is(err, api.ErrQueued) {
+			span.SetAttributes("queued", true)
+			callLog.Info("Approval queued until the backend recovers")
+			if err := w.SendCheckinQueued(channelID, userID, place); err != nil {
+				callLog.Error("Failed to send queued message", "err", err)
+				return err
+			}
+			return nil
+		}
+	} else {
+		body, statusCode, err = w.apiClient.SendRequestContext(ctx, reqBody, models.APIUpdateStatus)
+		w.auditRequest(audit.ActionStatusUpdate, userID, statusCode, err)
+	}
 	if err != nil {
-		log.Printf("❌ API request failed: %v", err)
+		span.RecordError(err)
+		callLog.Error("API request failed", "err", err)
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "approval_error"})
 		return err
 	}
 
 	w.apiClient.LogResponse(body, statusCode)
 
-	if !w.apiClient.IsSuccessStatusCode(statusCode) {
-		if len(body) > 0 && len(body) < 500 {
-			log.Printf("   Error: %s", string(body))
+	span.SetAttributes("status", statusCode)
+	if apiErr := api.ParseError(body, statusCode); apiErr != nil {
+		span.RecordError(apiErr)
+		callLog.Warn("Approval rejected", "err", apiErr)
+		if errors.Is(apiErr, api.ErrAlreadyCheckedIn) {
+			callLog.Info("User was already approved")
+			return w.SendCheckinSuccessAt(channelID, userID, "", place)
 		}
-		if err := w.SendCheckinFailed(channelID, userID, "Vị trí không hợp lệ"); err != nil {
-			log.Printf("❌ Failed to send invalid location message: %v", err)
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "approval_rejected"})
+		if err := w.SendCheckinFailed(channelID, userID, w.text(userID, "reason.invalid_location", nil)); err != nil {
+			callLog.Error("Failed to send invalid location message", "err", err)
 		}
-		return fmt.Errorf("API returned status %d", statusCode)
+		return apiErr
 	}
 
-	if err := w.SendCheckinSuccess(channelID, userID, ""); err != nil {
-		log.Printf("❌ Failed to send success message: %v", err)
+	if err := w.SendCheckinSuccessAt(channelID, userID, "", place); err != nil {
+		callLog.Error("Failed to send success message", "err", err)
 		return err
 	}
 
@@ -398,7 +758,13 @@ func (w *WebRTCManager) HandleLocationReply(userID int64, channelID int64, latit
 // CONFIRMATION TIMEOUT
 // ============================================================
 
-func (w *WebRTCManager) startConfirmationTimeout(userID, channelID int64) {
+// confirmationTimeout is how long users have to send their location
+const confirmationTimeout = 60 * time.Second
+
+var errConfirmationTimeout = errors.New("no location received before the timeout")
+
+func (w *WebRTCManager) startConfirmationTimeout(userID, channelID int64, wfh bool) {
+	callLog := w.callLogger(userID)
 	w.confirmationMu.Lock()
 
 	// Cancel old confirmation if exists
@@ -408,9 +774,16 @@ func (w *WebRTCManager) startConfirmationTimeout(userID, channelID int64) {
 				oldState.timer.Stop()
 			}
 		})
+		oldState.span.SetAttributes("outcome", "replaced")
+		oldState.span.End()
+		w.releaseTrace(userID, oldState.trace)
 	}
 
-	timer := time.AfterFunc(60*time.Second, func() {
+	// The confirmation keeps the check-in trace open after the call ends
+	trace := w.retainTrace(userID)
+	_, span := tracing.Start(w.traceContext(userID), "checkin.location_confirmation", "wfh", wfh)
+
+	timer := time.AfterFunc(confirmationTimeout, func() {
 		w.handleConfirmationTimeout(userID, channelID)
 	})
 
@@ -418,15 +791,21 @@ func (w *WebRTCManager) startConfirmationTimeout(userID, channelID int64) {
 		userID:    userID,
 		channelID: channelID,
 		timer:     timer,
+		deadline:  time.Now().Add(confirmationTimeout),
 		confirmed: false,
+		wfh:       wfh,
+		trace:     trace,
+		span:      span,
 	}
+	w.savePendingLocked()
 
 	w.confirmationMu.Unlock()
 
-	log.Printf("⏰ Started 60s confirmation timer for user %d", userID)
+	callLog.Info("Started confirmation timer", "timeout", confirmationTimeout)
 }
 
 func (w *WebRTCManager) handleConfirmationTimeout(userID int64, channelID int64) {
+	callLog := w.callLogger(userID)
 	w.confirmationMu.Lock()
 	state, exists := w.pendingConfirmations[userID]
 	if !exists {
@@ -440,23 +819,33 @@ func (w *WebRTCManager) handleConfirmationTimeout(userID int64, channelID int64)
 
 	if alreadyConfirmed {
 		delete(w.pendingConfirmations, userID)
+		w.savePendingLocked()
 		w.confirmationMu.Unlock()
-		log.Printf("✅ User %d already confirmed, skipping timeout", userID)
+		callLog.Debug("User already confirmed, skipping timeout")
 		return
 	}
 
 	delete(w.pendingConfirmations, userID)
+	w.savePendingLocked()
 	w.confirmationMu.Unlock()
 
-	log.Printf("⏱️ Confirmation timeout for user %d - no location received", userID)
+	callLog.Warn("Confirmation timeout, no location received")
+	w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "confirmation_timeout", WFH: state.wfh})
+
+	state.span.SetAttributes("outcome", "timeout")
+	state.span.RecordError(errConfirmationTimeout)
+	state.span.End()
+	defer w.releaseTrace(userID, state.trace)
 
-	if err := w.SendCheckinFailed(channelID, userID, "Hết thời gian xác nhận vị trí"); err != nil {
-		log.Printf("❌ Failed to send timeout message: %v", err)
+	if state.qrOffice != "" {
+		if err := w.SendCheckinFailed(channelID, userID, w.text(userID, "reason.confirmation_timeout_qr", nil)); err != nil {
+			callLog.Error("Failed to send timeout message", "err", err)
+		}
+	} else if err := w.SendCheckinFailedWithRetry(channelID, userID, w.text(userID, "reason.confirmation_timeout", nil), RetryLocation, state.wfh); err != nil {
+		callLog.Error("Failed to send timeout message", "err", err)
 	}
 
-	w.mu.RLock()
-	_, connExists := w.connections[userID]
-	w.mu.RUnlock()
+	connExists := w.connections.Has(userID)
 
 	if connExists {
 		w.playCheckinFailAudio(userID)
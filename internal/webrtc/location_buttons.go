@@ -0,0 +1,131 @@
+package webrtc
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/i18n"
+	"mezon-checkin-bot/mezon-protobuf/go/rtapi"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// LOCATION BUTTONS - Alternatives to sending a location message
+// ============================================================
+
+const (
+	locationButtonPrefix       = "location_"
+	locationShareButtonID      = locationButtonPrefix + "share"
+	locationOfficeButtonPrefix = locationButtonPrefix + "office:"
+
+	maxOfficeButtons = 3
+)
+
+// confirmationButtons returns the buttons of the location confirmation DM:
+// "share my location" and, when enabled, one "I'm at office X" per office
+// the user usually checks in at
+func (w *WebRTCManager) confirmationButtons(userID int64) []client.MessageButton {
+	buttons := []client.MessageButton{{
+		ID:    locationShareButtonID,
+		Label: w.text(userID, "button.share_location", nil),
+		Style: client.ButtonStyleSuccess,
+	}}
+
+	for _, office := range w.buttonOffices(userID) {
+		buttons = append(buttons, client.MessageButton{
+			ID:    locationOfficeButtonPrefix + office.ID,
+			Label: w.text(userID, "button.at_office", i18n.Vars{"office": office.Name}),
+			Style: client.ButtonStyleSuccess,
+		})
+	}
+	return buttons
+}
+
+// buttonOffices returns the offices offered as buttons: the assigned ones, or
+// else the office of the last confirmed location
+func (w *WebRTCManager) buttonOffices(userID int64) []Office {
+	cfg := w.locationConfig
+	if cfg == nil || !cfg.OfficeButtonsEnabled {
+		return nil
+	}
+
+	if cfg.AssignedOffices(userID) != nil {
+		offices := cfg.GetOfficesForUser(userID)
+		if len(offices) > maxOfficeButtons {
+			offices = offices[:maxOfficeButtons]
+		}
+		return offices
+	}
+
+	if last, ok := w.LastConfirmedLocation(userID); ok && last.OfficeID != "" {
+		if office, exists := cfg.findOffice(last.OfficeID); exists && office.Enabled {
+			return []Office{office}
+		}
+	}
+	return nil
+}
+
+// handleLocationClick handles the buttons of the confirmation DM
+func (w *WebRTCManager) handleLocationClick(clicked *rtapi.MessageButtonClicked) {
+	userID := clicked.GetUserId()
+	channelID := clicked.GetChannelId()
+	buttonID := clicked.GetButtonId()
+
+	if !w.hasPendingConfirmation(userID) {
+		w.replyCommand(channelID, userID, client.BuildSimpleTextMessage(
+			w.text(userID, "notice.confirmation_expired", nil)))
+		return
+	}
+
+	if buttonID == locationShareButtonID {
+		if err := w.SendCheckinNotice(channelID, userID, w.text(userID, "notice.share_location", nil)); err != nil {
+			logger.Error("Failed to send share location notice", "user_id", userID, "err", err)
+		}
+		return
+	}
+
+	officeID := strings.TrimPrefix(buttonID, locationOfficeButtonPrefix)
+	if err := w.claimOffice(userID, channelID, officeID); err != nil {
+		w.callLogger(userID).Warn("Office claim rejected", "office_id", officeID, "err", err)
+	}
+}
+
+// claimOffice completes the pending confirmation with an office the user
+// says they are at. Without a GPS fix the check-in waits for manual review.
+func (w *WebRTCManager) claimOffice(userID, channelID int64, officeID string) error {
+	var office Office
+	found := false
+	for _, candidate := range w.buttonOffices(userID) {
+		if strings.EqualFold(candidate.ID, officeID) {
+			office, found = candidate, true
+			break
+		}
+	}
+	// WFH and QR confirmations need a location
+	if !found || w.pendingIsWFH(userID) || w.pendingQROffice(userID) != "" {
+		return fmt.Errorf("office %s not offered", officeID)
+	}
+
+	// Keep the check-in trace open until the claim is queued
+	trace := w.retainTrace(userID)
+	defer w.releaseTrace(userID, trace)
+
+	if !w.takePendingConfirmation(userID) {
+		return fmt.Errorf("no pending confirmation")
+	}
+
+	w.callLogger(userID).Info("Office claimed with button", "office_id", office.ID)
+	w.holdForReview(&pendingReview{
+		UserID:    userID,
+		ChannelID: channelID,
+		Location: ConfirmedLocation{
+			OfficeID: office.ID,
+			Office:   office.Name,
+			At:       time.Now(),
+		},
+		Place:   office.Name,
+		Reasons: []string{fmt.Sprintf("tự xác nhận ở văn phòng %s, không có GPS", office.ID)},
+		NoGPS:   true,
+	})
+	return nil
+}
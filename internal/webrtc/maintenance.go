@@ -0,0 +1,121 @@
+package webrtc
+
+import (
+	"log/slog"
+	"mezon-checkin-bot/internal/audio"
+	"time"
+)
+
+// ============================================================
+// MAINTENANCE - Calls are answered with a short notice and hung
+// up while an admin has the bot in maintenance or the backend is down
+// ============================================================
+
+// maintenanceCallLimit hangs up a maintenance call whose notice never
+// finished, e.g. because the connection never came up
+const maintenanceCallLimit = 15 * time.Second
+
+// SetMaintenance turns new calls away with the maintenance notice until
+// disabled. Open calls are not affected. Disabling clears the message.
+func (w *WebRTCManager) SetMaintenance(enabled bool) {
+	if w.maintenance.Swap(enabled) != enabled {
+		logger.Warn("Maintenance mode changed", "enabled", enabled)
+	}
+	if !enabled {
+		w.SetMaintenanceMessage("")
+	}
+}
+
+// SetMaintenanceMessage replaces the maintenance DM, e.g. to say when the
+// bot is back ("" = the default notice). The audio notice does not change.
+func (w *WebRTCManager) SetMaintenanceMessage(message string) {
+	w.mu.Lock()
+	w.maintenanceMessage = message
+	w.mu.Unlock()
+}
+
+// Maintenance reports whether maintenance mode was turned on by an admin
+func (w *WebRTCManager) Maintenance() bool {
+	return w.maintenance.Load()
+}
+
+// MaintenanceMessage returns the message set with SetMaintenanceMessage
+func (w *WebRTCManager) MaintenanceMessage() string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.maintenanceMessage
+}
+
+// underMaintenance reports whether new calls are turned away
+func (w *WebRTCManager) underMaintenance() bool {
+	return w.Maintenance() || !w.backendHealthy()
+}
+
+// maintenanceNotice is the DM sent to callers turned away
+func (w *WebRTCManager) maintenanceNotice(userID int64) string {
+	if message := w.MaintenanceMessage(); message != "" && w.Maintenance() {
+		return message
+	}
+	return w.text(userID, "notice.maintenance", nil)
+}
+
+func (w *WebRTCManager) sendMaintenanceNotice(userID, channelID int64, callLog *slog.Logger) {
+	if err := w.SendCheckinNotice(channelID, userID, w.maintenanceNotice(userID)); err != nil {
+		callLog.Error("Failed to send maintenance notice", "err", err)
+	}
+}
+
+// rejectCallMaintenance tells the caller the bot is in maintenance or the
+// backend is down and hangs up without answering. Used when there is no
+// audio to play the notice.
+func (w *WebRTCManager) rejectCallMaintenance(userID int64, channelID int64, callLog *slog.Logger) {
+	callLog.Warn("Under maintenance, rejecting call", "manual", w.Maintenance())
+	w.sendMaintenanceNotice(userID, channelID, callLog)
+	w.hangUpUnanswered(userID, channelID, callLog)
+}
+
+// isMaintenanceCall reports whether the call was answered only for the
+// maintenance notice
+func (w *WebRTCManager) isMaintenanceCall(userID int64) bool {
+	state, exists := w.connections.Get(userID)
+	return exists && state.maintenance
+}
+
+// playMaintenanceNotice plays the notice once connected, then hangs up
+func (w *WebRTCManager) playMaintenanceNotice(userID int64) {
+	hangUp := func() {
+		go w.endCallAfterDelay(userID, "maintenance", 500*time.Millisecond)
+	}
+	if !w.playStagePrompt(userID, audio.StageMaintenance, hangUp) {
+		w.callLogger(userID).Warn("Maintenance audio not available, hanging up")
+		hangUp()
+	}
+}
+
+// ============================================================
+// DRAINING - New calls are hung up unanswered while the open ones
+// finish, before a restart or upgrade. Unlike maintenance, no
+// call is answered to play a notice.
+// ============================================================
+
+// SetDraining stops taking new calls until disabled. Open calls finish.
+func (w *WebRTCManager) SetDraining(enabled bool) {
+	if w.draining.Swap(enabled) != enabled {
+		logger.Warn("Draining changed", "enabled", enabled, "active_calls", w.ActiveCalls())
+	}
+}
+
+// Draining reports whether new calls are hung up
+func (w *WebRTCManager) Draining() bool {
+	return w.draining.Load()
+}
+
+// rejectCallDraining tells the caller to call back shortly and hangs up
+// without answering
+func (w *WebRTCManager) rejectCallDraining(userID int64, channelID int64, callLog *slog.Logger) {
+	callLog.Warn("Draining, rejecting call", "active_calls", w.ActiveCalls())
+	if err := w.SendCheckinNotice(channelID, userID, w.text(userID, "notice.draining", nil)); err != nil {
+		callLog.Error("Failed to send draining notice", "err", err)
+	}
+	w.hangUpUnanswered(userID, channelID, callLog)
+}
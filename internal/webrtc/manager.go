@@ -2,17 +2,23 @@ package webrtc
 
 import (
 	"fmt"
-	"log"
 	"mezon-checkin-bot/internal/api"
 	"mezon-checkin-bot/internal/audio"
 	"mezon-checkin-bot/internal/client"
 	"mezon-checkin-bot/internal/detector"
+	"mezon-checkin-bot/internal/flags"
+	"mezon-checkin-bot/internal/geocode"
+	"mezon-checkin-bot/internal/i18n"
+	"mezon-checkin-bot/internal/logging"
+	mzapi "mezon-checkin-bot/mezon-protobuf/go/api"
 	"mezon-checkin-bot/mezon-protobuf/go/rtapi"
 	"mezon-checkin-bot/models"
 	"sync"
 	"time"
 )
 
+var logger = logging.For("webrtc")
+
 // ============================================================
 // MANAGER INITIALIZATION
 // ============================================================
@@ -38,61 +44,232 @@ func NewWebRTCManager(
 		return nil, fmt.Errorf("failed to load offices: %w", err)
 	}
 
+	if err := locationConfig.LoadAssignments(apiClient); err != nil {
+		return nil, fmt.Errorf("failed to load office assignments: %w", err)
+	}
+
+	if err := locationConfig.LoadHomeLocations(); err != nil {
+		return nil, fmt.Errorf("failed to load home locations: %w", err)
+	}
+
+	history, err := NewLocationHistory(locationConfig.HistoryFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load location history: %w", err)
+	}
+
 	audioLibrary := audio.NewAudioLibrary()
+	audioLibrary.SetCacheDir(audioConfig.TranscodeCacheDir)
 
 	if audioConfig.Enabled {
 		audioFiles := map[string]string{
 			"welcome":          audioConfig.WelcomeAudioPath,
 			"checkin_success":  audioConfig.CheckinSuccessPath,
 			"checkin_fail":     audioConfig.CheckinFailPath,
+			"checkout_success": audioConfig.CheckoutSuccessPath,
 			"background_music": audioConfig.BackgroundMusicPath,
 		}
 
 		for name, path := range audioFiles {
 			if path != "" {
 				if err := audioLibrary.Register(name, path); err != nil {
-					log.Printf("⚠️  Failed to register %s audio: %v", name, err)
+					logger.Warn("Failed to register audio", "name", name, "err", err)
 				}
 			}
 		}
 
-		log.Printf("🎵 Audio system initialized: %d audio files registered", len(audioLibrary.List()))
+		for stage, path := range audioConfig.StagePrompts {
+			if err := audioLibrary.Register(audio.StageAudioName(stage), path); err != nil {
+				logger.Warn("Failed to register stage prompt", "stage", stage, "err", err)
+			}
+		}
+
+		for locale, dir := range audioConfig.LanguagePacks {
+			count := audioLibrary.RegisterPack(locale, dir)
+			logger.Info("Language pack registered", "locale", locale, "files", count, "dir", dir)
+		}
+
+		audioLibrary.PrepareGain("background_music", audioConfig.BackgroundMusicGain)
+
+		logger.Info("Audio system initialized", "files", len(audioLibrary.List()))
+	}
+
+	var stt audio.SpeechRecognizer
+	if audioConfig.STTEnabled {
+		engine, err := audio.NewSTTEngine(audioConfig.STTCommand)
+		if err != nil {
+			logger.Warn("Voice confirmation disabled", "err", err)
+		} else {
+			stt = engine
+			logger.Info("Voice confirmation enabled")
+		}
+	}
+
+	locales, err := audio.NewLocaleResolver(audioConfig.UserLocalesPath, audioConfig.DefaultLocale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user locales: %w", err)
+	}
+
+	var tts *audio.TTSEngine
+	if audioConfig.Enabled && audioConfig.TTSEnabled {
+		tts, err = audio.NewTTSEngine(audioConfig.TTSCommand, audioConfig.TTSCacheDir)
+		if err != nil {
+			logger.Warn("TTS disabled", "err", err)
+		} else {
+			logger.Info("TTS greeting enabled")
+			// Stage prompts only play from the cache, never rendered mid-call
+			texts := make([]string, 0, len(audio.DefaultStageTexts))
+			for _, text := range audio.DefaultStageTexts {
+				texts = append(texts, text)
+			}
+			tts.Prerender(texts)
+		}
+	}
+
+	var geocoder geocode.Geocoder
+	if locationConfig.ReverseGeocodeEnabled {
+		geocoder = geocode.NewNominatimGeocoder(locationConfig.ReverseGeocodeURL, "vi")
+		logger.Info("Reverse geocoding enabled")
 	}
 
 	dmManager := client.NewDMManager(mezonClient)
 
 	webrtc := &WebRTCManager{
-		connections:          make(map[int64]*connectionState),
+		connections:          newConnectionRegistry(),
+		outputDir:            outputDir,
 		client:               mezonClient,
 		faceDetector:         faceDetector,
+		detector:             faceDetector,
 		audioConfig:          audioConfig,
 		audioLibrary:         audioLibrary,
+		tts:                  tts,
+		stt:                  stt,
+		locales:              locales,
+		messages:             i18n.New(audio.DefaultLocale),
 		bufferPool:           newBufferPool(),
-		captureConfig:        DefaultCaptureConfig(),
 		dimensionConfig:      DefaultDimensionConfig(),
 		dmManager:            dmManager,
 		pendingConfirmations: make(map[int64]*confirmationState),
 		locationConfig:       locationConfig,
 		shutdown:             make(chan struct{}),
 		apiClient:            apiClient,
+		geocoder:             geocoder,
+		confirmedLocations:   make(map[int64]ConfirmedLocation),
+		reviews:              make(map[int64]*pendingReview),
+		traces:               make(map[int64]*checkinTrace),
+		history:              history,
+		checkoutRequests:     make(map[int64]time.Time),
+		breaks:               make(map[int64]time.Time),
+		shifts:               make(map[int64]shiftInfo),
+		lateReasons:          make(map[int64]*lateReasonRequest),
+		retries:              make(map[int64]retryOffer),
+		approvals:            make(map[int64]*pendingApproval),
+		overtimeOffers:       make(map[int64]*overtimeOffer),
+		photoSessions:        make(map[int64]*photoSession),
+		replyTargets:         make(map[int64]*mzapi.ChannelMessage),
+		images:               imageHost{images: make(map[string]hostedImage)},
+		qrUses:               make(map[string]time.Time),
 	}
 
+	captureConfig := DefaultCaptureConfig()
+	webrtc.captureConfig.Store(&captureConfig)
+
+	// Without rules, features are as the environment configured them
+	webrtc.flags = flags.New()
+	webrtc.flags.SetDefault(flags.DNNDetector, faceDetector.BaselineDetector() != nil)
+	webrtc.flags.SetDefault(flags.Liveness, faceDetector.Config.LivenessEnabled)
+	webrtc.flags.SetDefault(flags.PersistentFFmpeg, false)
+	webrtc.flags.SetDefault(flags.KeyframeFilter, true)
+
+	// Seed impossible-travel checks with check-ins from before a restart
+	for userID, record := range history.Latest() {
+		webrtc.confirmedLocations[userID] = ConfirmedLocation{
+			Latitude:  record.Latitude,
+			Longitude: record.Longitude,
+			Address:   record.Address,
+			OfficeID:  record.OfficeID,
+			At:        record.At,
+		}
+	}
+
+	audioLibrary.StartRemoteRefresh(audioConfig.RemoteRefreshInterval, webrtc.shutdown)
+
 	webrtc.SetupLocationHandler()
+	webrtc.SetupCommandHandler()
+	webrtc.SetupAdminChannel()
 	webrtc.SetupProtobufHandler()
 	return webrtc, nil
 }
 
+// FaceDetector returns the detector shared by all calls
+func (w *WebRTCManager) FaceDetector() *detector.FaceDetector {
+	return w.faceDetector
+}
+
+// Flags returns the feature flags, to load the flags file
+func (w *WebRTCManager) Flags() *flags.Set {
+	return w.flags
+}
+
+// flagCall describes the user's call for the feature flags
+func (w *WebRTCManager) flagCall(userID int64) flags.Call {
+	return flags.Call{UserID: userID, Offices: w.locationConfig.AssignedOffices(userID)}
+}
+
+// ActiveCalls returns the number of open calls
+func (w *WebRTCManager) ActiveCalls() int {
+	return w.connections.Len()
+}
+
+// SetHealthChecker starts backend health checks; while unhealthy, new calls
+// are turned away with a maintenance DM
+func (w *WebRTCManager) SetHealthChecker(h *api.HealthChecker) {
+	w.mu.Lock()
+	w.health = h
+	w.mu.Unlock()
+
+	if h != nil {
+		h.Start(w.shutdown)
+	}
+}
+
+func (w *WebRTCManager) backendHealthy() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.health == nil || w.health.Healthy()
+}
+
+// SetSubmissionQueue enables durable retry of status updates and starts
+// the replay worker
+func (w *WebRTCManager) SetSubmissionQueue(q *api.SubmissionQueue, interval time.Duration) {
+	w.mu.Lock()
+	w.queue = q
+	w.mu.Unlock()
+
+	if q != nil {
+		// The other instance's queue is the same directory, the leader replays it
+		q.SetActive(func() bool { return !w.Standby() })
+		q.Start(interval, w.shutdown)
+	}
+}
+
+// SetEventStore enables recording of check-in events for reports
+func (w *WebRTCManager) SetEventStore(store CheckinEventStore) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.events = store
+}
+
 // ============================================================
 // PROTOBUF HANDLER SETUP
 // ============================================================
 
 func (w *WebRTCManager) SetupProtobufHandler() {
-	log.Println("🎧 Setting up WebRTC protobuf handler...")
+	logger.Info("Setting up WebRTC protobuf handler")
 
 	w.client.On("webrtc_signaling_fwd", func(data interface{}) {
 		pbMsg, ok := data.(*rtapi.WebrtcSignalingFwd)
 		if !ok {
-			log.Printf("❌ Invalid webrtc_signaling_fwd data type: %T", data)
+			logger.Error("Invalid webrtc_signaling_fwd data type", "type", fmt.Sprintf("%T", data))
 			return
 		}
 
@@ -110,34 +287,30 @@ func (w *WebRTCManager) SetupProtobufHandler() {
 		// If Bot is receiver → signal from User to bot
 		if event.ReceiverId == w.client.ClientID {
 			userID = event.CallerId
-			log.Printf("📞 Signal FROM user %d TO bot", userID)
+			logger.Debug("Signal from user to bot", "user_id", userID)
 		} else if event.CallerId == w.client.ClientID {
 			// If Bot is caller → echo back of signal bot sent
 			userID = event.ReceiverId
-			log.Printf("📞 Signal FROM bot TO user %d (echo)", userID)
+			logger.Debug("Signal from bot to user (echo)", "user_id", userID)
 		} else {
 			// Signal not related to bot
-			log.Printf("⚠️  Signal không liên quan đến bot (Caller: %d, Receiver: %d)",
-				event.CallerId, event.ReceiverId)
+			logger.Debug("Signal not addressed to bot", "caller_id", event.CallerId, "receiver_id", event.ReceiverId)
 			return
 		}
 
 		if userID == 0 {
-			log.Printf("❌ Could not determine user ID")
+			logger.Error("Could not determine user ID for signal")
 			return
 		}
 
-		log.Printf("📞 WebRTC signal - Type: %d, Channel: %d, UserID: %d",
-			event.DataType, event.ChannelId, userID)
-
 		go func() {
 			if err := w.HandleSignal(userID, event); err != nil {
-				log.Printf("❌ Error handling WebRTC signal: %v", err)
+				logger.Error("Error handling WebRTC signal", "user_id", userID, "channel_id", event.ChannelId, "err", err)
 			}
 		}()
 	})
 
-	log.Println("✅ WebRTC protobuf handler setup complete")
+	logger.Info("WebRTC protobuf handler ready")
 }
 
 // ============================================================
@@ -147,30 +320,20 @@ func (w *WebRTCManager) SetupProtobufHandler() {
 func (w *WebRTCManager) CloseAll() {
 	w.shutdownOnce.Do(func() {
 		close(w.shutdown)
-		log.Println("🛑 Shutdown starting...")
+		logger.Info("Shutdown starting")
+		w.stopLeaderElection()
 
 		// 1. Cancel confirmations
-		w.confirmationMu.Lock()
-		for _, state := range w.pendingConfirmations {
-			state.cancelOnce.Do(func() {
-				if state.timer != nil {
-					state.timer.Stop()
-				}
-			})
-		}
-		w.pendingConfirmations = make(map[int64]*confirmationState)
-		w.confirmationMu.Unlock()
+		w.dropPendingConfirmations("shutdown")
 
 		// 2. Get connections
-		w.mu.Lock()
-		connections := make([]*connectionState, 0, len(w.connections))
-		userIDs := make([]int64, 0, len(w.connections))
-		for uid, state := range w.connections {
+		registered := w.connections.DeleteAll()
+		connections := make([]*connectionState, 0, len(registered))
+		userIDs := make([]int64, 0, len(registered))
+		for uid, state := range registered {
 			connections = append(connections, state)
 			userIDs = append(userIDs, uid)
 		}
-		w.connections = make(map[int64]*connectionState)
-		w.mu.Unlock()
 
 		// 3. Parallel cleanup with timeout
 		done := make(chan struct{})
@@ -187,7 +350,8 @@ func (w *WebRTCManager) CloseAll() {
 					if s.pc != nil {
 						s.pc.Close()
 					}
-					log.Printf("   ✅ Closed: %d", uid)
+					w.releaseTrace(uid, s.trace)
+					logger.Info("Connection closed", "user_id", uid)
 				}(state, userIDs[i])
 			}
 			wg.Wait()
@@ -197,9 +361,9 @@ func (w *WebRTCManager) CloseAll() {
 		// Wait with timeout
 		select {
 		case <-done:
-			log.Println("   ✅ All closed")
+			logger.Info("All connections closed")
 		case <-time.After(5 * time.Second):
-			log.Println("   ⚠️  Timeout")
+			logger.Warn("Timed out closing connections")
 		}
 
 		// 4. Close detector
@@ -207,6 +371,6 @@ func (w *WebRTCManager) CloseAll() {
 			w.faceDetector.Close()
 		}
 
-		log.Println("🛑 Shutdown complete")
+		logger.Info("Shutdown complete")
 	})
 }
@@ -2,31 +2,54 @@ package webrtc
 
 import (
 	"fmt"
-	"log"
 	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/i18n"
+	"mezon-checkin-bot/internal/tracing"
+	"mezon-checkin-bot/models"
 )
 
 // ============================================================
 // CHECKIN CONFIRMATION MESSAGE
 // ============================================================
 
-func (w *WebRTCManager) SendCheckinConfirmation(channelID int64, userID int64, detectedName string) error {
+// SendCheckinConfirmation asks for the user's location, showing the
+// recognized face (if any) so a wrong match is noticed right away
+func (w *WebRTCManager) SendCheckinConfirmation(channelID int64, userID int64, detectedName string, face []byte) error {
 	if w.dmManager == nil {
 		return fmt.Errorf("DM manager not initialized")
 	}
 
-	log.Printf("📧 Sending check-in confirmation to user %d", userID)
+	callLog := w.callLogger(userID)
+	callLog.Info("Sending check-in confirmation")
 
-	content := client.BuildCheckinConfirmationMessage(detectedName)
+	content := client.BuildCheckinConfirmationMessage(w.tr(userID), detectedName, w.faceThumbnailURL(face),
+		w.shiftFields(userID, false), w.confirmationButtons(userID))
 
-	if err := w.dmManager.SendDM(channelID, userID, content); err != nil {
-		log.Printf("❌ Failed to send DM: %v", err)
+	if err := w.sendDM("confirmation", channelID, userID, content); err != nil {
+		callLog.Error("Failed to send DM", "err", err)
 		return err
 	}
 
-	log.Println("✅ Check-in confirmation sent!")
+	callLog.Debug("Check-in confirmation sent")
 
-	w.startConfirmationTimeout(userID, channelID)
+	w.startConfirmationTimeout(userID, channelID, false)
+
+	return nil
+}
+
+// SendWFHConfirmation asks a WFH user for their location, registering it as
+// their home location if they have none yet
+func (w *WebRTCManager) SendWFHConfirmation(channelID int64, userID int64) error {
+	notice := w.text(userID, "notice.wfh_confirm", nil)
+	if _, registered := w.locationConfig.HomeLocation(userID); !registered {
+		notice = w.text(userID, "notice.wfh_register", nil)
+	}
+
+	if err := w.SendCheckinNotice(channelID, userID, notice); err != nil {
+		return err
+	}
+
+	w.startConfirmationTimeout(userID, channelID, true)
 
 	return nil
 }
@@ -40,16 +63,60 @@ func (w *WebRTCManager) SendCheckinSuccess(channelID int64, userID int64, userNa
 		return fmt.Errorf("DM manager not initialized")
 	}
 
-	log.Printf("📧 Sending check-in success to user %d", userID)
+	callLog := w.callLogger(userID)
+	callLog.Info("Sending check-in success")
 
-	content := client.BuildCheckinSuccessMessage(userName)
+	content := client.BuildCheckinSuccessMessage(w.tr(userID), userName, w.shiftFields(userID, true))
 
-	if err := w.dmManager.SendDM(channelID, userID, content); err != nil {
-		log.Printf("❌ Failed to send DM: %v", err)
+	if err := w.sendDM("success", channelID, userID, content); err != nil {
+		callLog.Error("Failed to send DM", "err", err)
 		return err
 	}
 
-	log.Println("✅ Check-in success message sent!")
+	callLog.Debug("Check-in success message sent")
+	return nil
+}
+
+// SendCheckinSuccessAt sends the success DM including where the user checked in
+func (w *WebRTCManager) SendCheckinSuccessAt(channelID int64, userID int64, userName string, place string) error {
+	if place == "" {
+		return w.SendCheckinSuccess(channelID, userID, userName)
+	}
+	if w.dmManager == nil {
+		return fmt.Errorf("DM manager not initialized")
+	}
+
+	callLog := w.callLogger(userID)
+	callLog.Info("Sending check-in success", "place", place)
+
+	content := client.BuildCheckinSuccessAtMessage(w.tr(userID), userName, place, w.shiftFields(userID, true))
+
+	if err := w.sendDM("success", channelID, userID, content); err != nil {
+		callLog.Error("Failed to send DM", "err", err)
+		return err
+	}
+
+	callLog.Debug("Check-in success message sent")
+	return nil
+}
+
+// SendCheckinQueued tells the user the check-in is recorded but the backend
+// is down, so it only shows up in attendance once the queue replays it
+func (w *WebRTCManager) SendCheckinQueued(channelID int64, userID int64, place string) error {
+	notice := w.text(userID, "notice.checkin_queued", nil)
+	if place != "" {
+		notice = w.text(userID, "notice.checkin_queued_at", i18n.Vars{"place": place})
+	}
+	if w.dmManager == nil {
+		return fmt.Errorf("DM manager not initialized")
+	}
+
+	// Its own kind: it takes the place of the success DM, so it is kept
+	content := client.BuildCheckinNoticeMessage(w.tr(userID), notice)
+	if err := w.sendDM("queued", channelID, userID, content); err != nil {
+		w.callLogger(userID).Error("Failed to send DM", "err", err)
+		return err
+	}
 	return nil
 }
 
@@ -58,19 +125,94 @@ func (w *WebRTCManager) SendCheckinSuccess(channelID int64, userID int64, userNa
 // ============================================================
 
 func (w *WebRTCManager) SendCheckinFailed(channelID int64, userID int64, reason string) error {
+	return w.sendCheckinFailed(channelID, userID, reason, "")
+}
+
+// SendCheckinFailedWithRetry sends the failure DM with a "try again" button
+// that calls the user back or restarts the location confirmation
+func (w *WebRTCManager) SendCheckinFailedWithRetry(channelID int64, userID int64, reason string, kind string, wfh bool) error {
+	w.offerRetry(userID, channelID, kind, wfh)
+	return w.sendCheckinFailed(channelID, userID, reason, RetryButtonID)
+}
+
+func (w *WebRTCManager) sendCheckinFailed(channelID int64, userID int64, reason string, retryButtonID string) error {
 	if w.dmManager == nil {
 		return fmt.Errorf("DM manager not initialized")
 	}
 
-	log.Printf("📧 Sending check-in failed to user %d", userID)
+	callLog := w.callLogger(userID)
+	callLog.Info("Sending check-in failed")
 
-	content := client.BuildCheckinFailedMessage(reason)
+	content := client.BuildCheckinFailedMessage(w.tr(userID), reason, w.callID(userID), retryButtonID)
 
-	if err := w.dmManager.SendDM(channelID, userID, content); err != nil {
-		log.Printf("❌ Failed to send DM: %v", err)
+	if err := w.sendDM("failed", channelID, userID, content); err != nil {
+		callLog.Error("Failed to send DM", "err", err)
 		return err
 	}
 
-	log.Println("✅ Check-in failed message sent!")
+	callLog.Debug("Check-in failed message sent")
 	return nil
 }
+
+// ============================================================
+// CHECKIN NOTICE MESSAGE
+// ============================================================
+
+func (w *WebRTCManager) SendCheckinNotice(channelID int64, userID int64, notice string) error {
+	if w.dmManager == nil {
+		return fmt.Errorf("DM manager not initialized")
+	}
+
+	callLog := w.callLogger(userID)
+	callLog.Info("Sending check-in notice")
+
+	content := client.BuildCheckinNoticeMessage(w.tr(userID), notice)
+
+	if err := w.sendDM("notice", channelID, userID, content); err != nil {
+		callLog.Error("Failed to send DM", "err", err)
+		return err
+	}
+
+	callLog.Debug("Check-in notice sent")
+	return nil
+}
+
+// ============================================================
+// LOCATION ALERTS
+// ============================================================
+
+// sendLocationAlert posts an alert to the location alert channel. Without one
+// configured it does nothing.
+func (w *WebRTCManager) sendLocationAlert(title, description string) error {
+	cfg := w.locationConfig
+	if cfg == nil || cfg.AlertChannelID == 0 || w.dmManager == nil {
+		return nil
+	}
+
+	content := client.BuildErrorMessage(title, description)
+	return w.dmManager.SendChannelMessage(cfg.AlertClanID, cfg.AlertChannelID, content)
+}
+
+// sendDM delivers a check-in DM as a span of the user's check-in trace
+func (w *WebRTCManager) sendDM(kind string, channelID int64, userID int64, content models.ChannelMessageContent) error {
+	ctx, span := tracing.Start(w.traceContext(userID), "dm.send", "kind", kind)
+	defer span.End()
+
+	handled, err := w.sendStatus(ctx, kind, channelID, userID, content)
+	if !handled {
+		var messageID int64
+		if replyTo := w.replyTarget(userID); replyTo != nil && replyTo.ChannelId == channelID {
+			messageID, err = w.dmManager.SendDMReply(ctx, channelID, userID, content, replyTo)
+		} else {
+			messageID, err = w.dmManager.SendDMWithID(ctx, channelID, userID, content)
+		}
+		if err == nil {
+			w.trackTransient(kind, channelID, userID, messageID)
+		}
+	}
+	if err == nil && finalKinds[kind] {
+		w.expireTransient(userID)
+	}
+	span.RecordError(err)
+	return err
+}
@@ -0,0 +1,167 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/utils"
+	"path/filepath"
+	"strings"
+)
+
+// ============================================================
+// OFFICE MANAGEMENT - Runtime edits persisted to the office store
+// or offices.json
+// ============================================================
+
+// AllOffices returns every office, including disabled ones
+func (c *LocationConfig) AllOffices() []Office {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	offices := make([]Office, len(c.allOffices))
+	copy(offices, c.allOffices)
+	return offices
+}
+
+// AddOffice adds a new office and saves it
+func (c *LocationConfig) AddOffice(office Office, actor int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.allOffices {
+		if strings.EqualFold(existing.ID, office.ID) {
+			return fmt.Errorf("office %s already exists", office.ID)
+		}
+	}
+
+	c.allOffices = append(c.allOffices, office)
+	c.rebuildEnabledOffices()
+	return c.persistOffice(office, actor, "add")
+}
+
+// SetOfficeEnabled enables or disables an office and saves it
+func (c *LocationConfig) SetOfficeEnabled(id string, enabled bool, actor int64) error {
+	change := "disable"
+	if enabled {
+		change = "enable"
+	}
+	return c.updateOffice(id, actor, change, func(office *Office) {
+		office.Enabled = enabled
+	})
+}
+
+// updateOffice applies fn to the office with the ID and saves it
+func (c *LocationConfig) updateOffice(id string, actor int64, change string, fn func(*Office)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.allOffices {
+		if strings.EqualFold(c.allOffices[i].ID, id) {
+			fn(&c.allOffices[i])
+			c.rebuildEnabledOffices()
+			return c.persistOffice(c.allOffices[i], actor, change)
+		}
+	}
+	return fmt.Errorf("office %s not found", id)
+}
+
+// ReplaceOffices swaps in a complete office list at once, as pushed by the
+// remote config. The list is not persisted; the remote config keeps its own
+// copy.
+func (c *LocationConfig) ReplaceOffices(offices []Office) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.allOffices = offices
+	c.rebuildEnabledOffices()
+}
+
+// rebuildEnabledOffices refreshes the validation list. Caller holds c.mu.
+func (c *LocationConfig) rebuildEnabledOffices() {
+	c.offices = make([]Office, 0, len(c.allOffices))
+	for _, office := range c.allOffices {
+		if office.Enabled {
+			c.offices = append(c.offices, office)
+		}
+	}
+}
+
+// persistOffice saves a changed office as a new version, or rewrites the
+// offices file when there is no store. Caller holds c.mu.
+func (c *LocationConfig) persistOffice(office Office, actor int64, change string) error {
+	if c.OfficeStore == nil {
+		return c.saveOffices()
+	}
+	_, err := c.OfficeStore.SaveOffice(office, actor, change)
+	return err
+}
+
+// saveOffices writes all offices back to the file atomically. Caller holds c.mu.
+func (c *LocationConfig) saveOffices() error {
+	data, err := json.MarshalIndent(OfficeList{Offices: c.allOffices}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal offices: %w", err)
+	}
+
+	if err := utils.WriteFileAtomic(c.OfficesFilePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write offices file: %w", err)
+	}
+	return nil
+}
+
+// ============================================================
+// IMPORT / EXPORT - offices.json next to the office store
+// ============================================================
+
+// ExportOffices writes the current offices to the offices file
+func (c *LocationConfig) ExportOffices() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveOffices()
+}
+
+// ImportOffices loads the offices file into the store, adding new offices
+// and versioning changed ones, and returns how many changed. Offices missing
+// from the file are kept. Without a store the file is simply reloaded.
+func (c *LocationConfig) ImportOffices(actor int64) (int, error) {
+	if c.OfficeStore == nil {
+		if err := c.LoadOffices(); err != nil {
+			return 0, err
+		}
+		return len(c.AllOffices()), nil
+	}
+
+	offices, err := c.readOfficesFile()
+	if err != nil {
+		return 0, err
+	}
+	checkOfficeTimezones(offices)
+
+	changed := 0
+	for _, office := range offices {
+		saved, err := c.OfficeStore.SaveOffice(office, actor, "import "+filepath.Base(c.OfficesFilePath))
+		if err != nil {
+			return changed, err
+		}
+		if saved {
+			changed++
+		}
+	}
+
+	stored, err := c.OfficeStore.Offices()
+	if err != nil {
+		return changed, fmt.Errorf("failed to reload offices: %w", err)
+	}
+	c.mu.Lock()
+	c.allOffices = stored
+	c.rebuildEnabledOffices()
+	c.mu.Unlock()
+	return changed, nil
+}
+
+// OfficeHistory returns the office's latest versions, newest first
+func (c *LocationConfig) OfficeHistory(id string, limit int) ([]OfficeVersion, error) {
+	if c.OfficeStore == nil {
+		return nil, fmt.Errorf("office history needs EVENT_STORE=sqlite or postgres")
+	}
+	return c.OfficeStore.OfficeHistory(id, limit)
+}
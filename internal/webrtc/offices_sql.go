@@ -0,0 +1,178 @@
+package webrtc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// OFFICE STORE - Versioned office definitions in the database
+// ============================================================
+
+// OfficeStore keeps office definitions with a version per change. When set
+// on LocationConfig it replaces offices.json, which is then only used to
+// import and export.
+type OfficeStore interface {
+	Offices() ([]Office, error)
+	// SaveOffice stores the office as a new version unless nothing changed
+	SaveOffice(office Office, actor int64, change string) (bool, error)
+	// OfficeHistory returns up to limit of the latest versions, newest first
+	OfficeHistory(id string, limit int) ([]OfficeVersion, error)
+}
+
+// OfficeVersion is one saved state of an office
+type OfficeVersion struct {
+	Version   int
+	Office    Office
+	ChangedBy int64 // User ID, 0 = the bot (import, migration)
+	Change    string
+	At        time.Time
+}
+
+// Offices are stored as JSON so new Office fields need no migration
+const sqlOfficeSchema = `CREATE TABLE IF NOT EXISTS offices (
+	id TEXT PRIMARY KEY,
+	version INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	updated_by BIGINT NOT NULL DEFAULT 0,
+	updated_at_ms BIGINT NOT NULL
+)`
+
+const sqlOfficeVersionSchema = `CREATE TABLE IF NOT EXISTS office_versions (
+	%s,
+	office_id TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	changed_by BIGINT NOT NULL DEFAULT 0,
+	change TEXT NOT NULL DEFAULT '',
+	at_ms BIGINT NOT NULL
+)`
+
+const sqlOfficeVersionIndex = "CREATE INDEX IF NOT EXISTS office_versions_office ON office_versions (office_id, version)"
+
+// SQLOfficeStore keeps offices in the event store's database
+type SQLOfficeStore struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Offices returns an office store sharing the event store's database,
+// creating its tables if missing
+func (s *SQLEventStore) Offices() (*SQLOfficeStore, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	statements := []string{sqlOfficeSchema, fmt.Sprintf(sqlOfficeVersionSchema, s.dialect.idColumn), sqlOfficeVersionIndex}
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return nil, fmt.Errorf("failed to create office tables: %w", err)
+		}
+	}
+	return &SQLOfficeStore{db: s.db, dialect: s.dialect}, nil
+}
+
+// Offices returns the current version of every office, ordered by ID
+func (s *SQLOfficeStore) Offices() ([]Office, error) {
+	rows, err := s.db.Query("SELECT data FROM offices ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query offices: %w", err)
+	}
+	defer rows.Close()
+
+	var offices []Office
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read office: %w", err)
+		}
+		var office Office
+		if err := json.Unmarshal([]byte(data), &office); err != nil {
+			return nil, fmt.Errorf("failed to parse office: %w", err)
+		}
+		offices = append(offices, office)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read offices: %w", err)
+	}
+	return offices, nil
+}
+
+// SaveOffice upserts the office and records the version in one transaction
+func (s *SQLOfficeStore) SaveOffice(office Office, actor int64, change string) (bool, error) {
+	data, err := json.Marshal(office)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal office: %w", err)
+	}
+	id := strings.ToUpper(office.ID)
+	atMillis := time.Now().UnixMilli()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin office update: %w", err)
+	}
+	defer tx.Rollback()
+
+	var version int
+	var current string
+	err = tx.QueryRow(s.dialect.rebind("SELECT version, data FROM offices WHERE id = ?"), id).Scan(&version, &current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return false, fmt.Errorf("failed to read office %s: %w", id, err)
+	case current == string(data):
+		return false, nil
+	}
+	version++
+
+	_, err = tx.Exec(s.dialect.rebind(`INSERT INTO offices (id, version, data, updated_by, updated_at_ms) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET version = excluded.version, data = excluded.data,
+		updated_by = excluded.updated_by, updated_at_ms = excluded.updated_at_ms`),
+		id, version, string(data), actor, atMillis)
+	if err != nil {
+		return false, fmt.Errorf("failed to save office %s: %w", id, err)
+	}
+	_, err = tx.Exec(s.dialect.rebind("INSERT INTO office_versions (office_id, version, data, changed_by, change, at_ms) VALUES (?, ?, ?, ?, ?, ?)"),
+		id, version, string(data), actor, change, atMillis)
+	if err != nil {
+		return false, fmt.Errorf("failed to record office %s version: %w", id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit office update: %w", err)
+	}
+	return true, nil
+}
+
+// OfficeHistory returns up to limit of the office's latest versions, newest first
+func (s *SQLOfficeStore) OfficeHistory(id string, limit int) ([]OfficeVersion, error) {
+	query := s.dialect.rebind("SELECT version, data, changed_by, change, at_ms FROM office_versions WHERE office_id = ? ORDER BY version DESC LIMIT ?")
+	rows, err := s.db.Query(query, strings.ToUpper(id), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query office history: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []OfficeVersion
+	for rows.Next() {
+		var version OfficeVersion
+		var data string
+		var atMillis int64
+		if err := rows.Scan(&version.Version, &data, &version.ChangedBy, &version.Change, &atMillis); err != nil {
+			return nil, fmt.Errorf("failed to read office version: %w", err)
+		}
+		if err := json.Unmarshal([]byte(data), &version.Office); err != nil {
+			return nil, fmt.Errorf("failed to parse office version: %w", err)
+		}
+		version.At = time.UnixMilli(atMillis)
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read office history: %w", err)
+	}
+	return versions, nil
+}
@@ -0,0 +1,199 @@
+package webrtc
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/logging"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// ============================================================
+// OPERATIONS - Live state and controls for the admin API
+// ============================================================
+
+// recentEventsWindow bounds how far back RecentEvents looks
+const recentEventsWindow = 24 * time.Hour
+
+// Stages of a call, as shown by the dashboard
+const (
+	CallStageConnecting       = "connecting"
+	CallStageWaitingKeyframe  = "waiting_keyframe"
+	CallStageDetecting        = "detecting"
+	CallStageRecognizing      = "recognizing"
+	CallStageAwaitingLocation = "awaiting_location"
+	CallStageCheckingOut      = "checking_out"
+	CallStageMaintenance      = "maintenance" // Answered only to play the maintenance notice
+)
+
+// ConnectionInfo describes an open call
+type ConnectionInfo struct {
+	UserID    int64       `json:"user_id"`
+	ChannelID int64       `json:"channel_id"`
+	CallID    string      `json:"call_id,omitempty"`
+	State     string      `json:"state"` // Peer connection state
+	Stage     string      `json:"stage"`
+	Quality   CallQuality `json:"quality"`
+	Since     time.Time   `json:"since"`
+
+	Goroutines map[string]int `json:"goroutines,omitempty"` // Running, by name
+}
+
+// CallQuality is the received video's quality, from the WebRTC stats
+type CallQuality struct {
+	PacketsReceived uint32  `json:"packets_received"`
+	PacketsLost     int32   `json:"packets_lost"`
+	LossPercent     float64 `json:"loss_percent"`
+	JitterMs        float64 `json:"jitter_ms"`
+	RTTMs           float64 `json:"rtt_ms"`
+}
+
+// setCallStage records how far the user's call got
+func (w *WebRTCManager) setCallStage(userID int64, stage string) {
+	state, exists := w.connections.Get(userID)
+	if !exists {
+		return
+	}
+	state.mu.Lock()
+	state.stage = stage
+	state.mu.Unlock()
+}
+
+// callQuality reads the video stats of a peer connection
+func callQuality(pc *webrtc.PeerConnection) CallQuality {
+	var quality CallQuality
+	for _, stats := range pc.GetStats() {
+		switch s := stats.(type) {
+		case webrtc.InboundRTPStreamStats:
+			if s.Kind != "video" {
+				continue
+			}
+			quality.PacketsReceived += s.PacketsReceived
+			quality.PacketsLost += s.PacketsLost
+			quality.JitterMs = max(quality.JitterMs, s.Jitter*1000)
+		case webrtc.ICECandidatePairStats:
+			if s.Nominated {
+				quality.RTTMs = s.CurrentRoundTripTime * 1000
+			}
+		}
+	}
+	if total := float64(quality.PacketsReceived) + float64(quality.PacketsLost); total > 0 && quality.PacketsLost > 0 {
+		quality.LossPercent = float64(quality.PacketsLost) / total * 100
+	}
+	return quality
+}
+
+// Connections lists the open calls, oldest first
+func (w *WebRTCManager) Connections() []ConnectionInfo {
+	infos := make([]ConnectionInfo, 0, w.connections.Len())
+	w.connections.Range(func(userID int64, state *connectionState) {
+		info := ConnectionInfo{UserID: userID, ChannelID: state.channelID, Since: state.startedAt}
+		state.mu.Lock()
+		info.Stage = state.stage
+		state.mu.Unlock()
+		if state.pc != nil {
+			info.State = state.pc.ConnectionState().String()
+			info.Quality = callQuality(state.pc)
+		}
+		if state.trace != nil {
+			info.CallID = state.trace.callID
+		}
+		if state.goroutines != nil {
+			info.Goroutines = state.goroutines.Running()
+		}
+		infos = append(infos, info)
+	})
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Since.Before(infos[j].Since) })
+	return infos
+}
+
+// EndCall hangs up the user's call
+func (w *WebRTCManager) EndCall(userID int64) error {
+	exists := w.connections.Has(userID)
+	if !exists {
+		return fmt.Errorf("user %d has no open call", userID)
+	}
+
+	w.endCallAfterDelay(userID, "ended_by_admin", 0)
+	return nil
+}
+
+// ReloadOffices reloads the offices from their store or file and returns how
+// many are enabled
+func (w *WebRTCManager) ReloadOffices() (int, error) {
+	if err := w.locationConfig.LoadOffices(); err != nil {
+		return 0, err
+	}
+	return len(w.locationConfig.GetOffices()), nil
+}
+
+// LogLevels describes how much the bot logs
+type LogLevels struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"` // Components with their own level
+	Verbose    bool              `json:"verbose"`    // Raw Mezon protocol tracing
+}
+
+// LogLevels returns the global and per-component log levels
+func (w *WebRTCManager) LogLevels() LogLevels {
+	levels := LogLevels{
+		Level:      strings.ToLower(logging.Level().String()),
+		Components: make(map[string]string),
+		Verbose:    w.client.GetVerbose(),
+	}
+	for component, level := range logging.ComponentLevels() {
+		levels.Components[component] = strings.ToLower(level.String())
+	}
+	return levels
+}
+
+// SetLogLevel changes the global level, or the component's when component
+// is set. "reset" makes the component follow the global level again.
+func (w *WebRTCManager) SetLogLevel(component, name string) error {
+	if component != "" && !logging.IsComponent(component) {
+		return fmt.Errorf("unknown component %q, known: %s", component, strings.Join(logging.Components(), ", "))
+	}
+	if component != "" && strings.EqualFold(name, "reset") {
+		logging.ResetComponentLevel(component)
+		logger.Warn("Component log level reset", "log_component", component)
+		return nil
+	}
+
+	level, ok := logging.LookupLevel(name)
+	if !ok {
+		return fmt.Errorf("unknown level %q, use debug, info, warn or error", name)
+	}
+	if component == "" {
+		logging.SetLevel(level)
+		logger.Warn("Log level changed", "level", level)
+		return nil
+	}
+	logging.SetComponentLevel(component, level)
+	logger.Warn("Component log level changed", "log_component", component, "level", level)
+	return nil
+}
+
+// RecentEvents returns up to limit check-in events of the last day, newest
+// first
+func (w *WebRTCManager) RecentEvents(limit int) ([]CheckinEvent, error) {
+	w.mu.RLock()
+	store := w.events
+	w.mu.RUnlock()
+	if store == nil {
+		return nil, fmt.Errorf("no event store configured")
+	}
+
+	now := time.Now()
+	events, err := store.Between(now.Add(-recentEventsWindow), now)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].At.After(events[j].At) })
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
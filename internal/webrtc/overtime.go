@@ -0,0 +1,253 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/mezon-protobuf/go/rtapi"
+	"mezon-checkin-bot/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// OVERTIME - Registration offered outside working hours
+// ============================================================
+
+const (
+	overtimeButtonPrefix = "overtime_"
+	overtimeOfferTTL     = 30 * time.Minute
+
+	// Arriving this much before the work start counts as overtime
+	earlyArrivalGrace = time.Hour
+)
+
+// overtimeDurations are offered as buttons, in minutes
+var overtimeDurations = []int{60, 120, 180, 240}
+
+// overtimeReasons are offered as buttons after the duration
+var overtimeReasons = []struct {
+	Key   string
+	Label string
+}{
+	{"deadline", "Dự án gấp"},
+	{"incident", "Xử lý sự cố"},
+	{"meeting", "Họp / đối tác"},
+	{"other", "Khác"},
+}
+
+// overtimeOffer is an open overtime registration prompt
+type overtimeOffer struct {
+	channelID int64
+	date      string // Office date, "2006-01-02"
+	start     time.Time
+	minutes   int // Chosen duration, 0 until picked
+	expires   time.Time
+}
+
+// offerOvertime prompts for an overtime registration when a check-in or
+// check-out at falls outside the office's working hours
+func (w *WebRTCManager) offerOvertime(userID, channelID int64, officeID, mode string, at time.Time) {
+	w.mu.RLock()
+	enabled := w.attendance.OvertimeEnabled
+	w.mu.RUnlock()
+	if !enabled || w.dmManager == nil {
+		return
+	}
+
+	hours := w.officeHours(officeID)
+	at = at.In(hours.zone)
+	start, suggested, ok := overtimeWindow(hours, mode, at)
+	if !ok {
+		return
+	}
+
+	w.mu.Lock()
+	w.overtimeOffers[userID] = &overtimeOffer{
+		channelID: channelID,
+		date:      at.Format("2006-01-02"),
+		start:     start,
+		expires:   time.Now().Add(overtimeOfferTTL),
+	}
+	w.mu.Unlock()
+
+	w.callLogger(userID).Info("Offering overtime registration", "mode", mode, "start", start.Format("15:04"), "suggested_minutes", suggested)
+
+	durations := overtimeDurations
+	if suggested > 0 && !containsInt(durations, suggested) {
+		durations = append([]int{suggested}, durations...)
+	}
+	buttons := make([]client.MessageButton, 0, len(durations)+1)
+	for _, minutes := range durations {
+		buttons = append(buttons, client.MessageButton{
+			ID:    fmt.Sprintf("%sminutes:%d", overtimeButtonPrefix, minutes),
+			Label: formatOvertimeDuration(minutes),
+			Style: client.ButtonStyleSuccess,
+		})
+	}
+	buttons = append(buttons, client.MessageButton{ID: overtimeButtonPrefix + "skip", Label: "Bỏ qua", Style: client.ButtonStyleDanger})
+
+	content := client.BuildMessageWithButtons("⏰ Đăng ký làm thêm giờ?",
+		fmt.Sprintf("Bạn đang làm việc ngoài giờ (từ %s). Chọn thời gian làm thêm để đăng ký.", start.Format("15:04")), buttons)
+	if err := w.sendDM("overtime_offer", channelID, userID, content); err != nil {
+		w.callLogger(userID).Error("Failed to send overtime offer", "err", err)
+	}
+}
+
+// overtimeWindow returns when the overtime started and a suggested duration
+// (0 = none) if at is outside the working hours
+func overtimeWindow(hours workingHours, mode string, at time.Time) (time.Time, int, bool) {
+	workStart, hasStart := clockOn(hours.start, at)
+	workEnd, hasEnd := clockOn(hours.end, at)
+	cutoff, hasCutoff := clockOn(hours.cutoff, at)
+
+	switch {
+	case mode == ModeCheckout && hasCutoff && hasEnd && at.After(cutoff):
+		return workEnd, roundOvertime(at.Sub(workEnd)), true
+	case mode == ModeCheckin && hasStart && at.Before(workStart.Add(-earlyArrivalGrace)):
+		return at, roundOvertime(workStart.Sub(at)), true
+	case mode == ModeCheckin && hasCutoff && at.After(cutoff):
+		return at, 0, true
+	}
+	return time.Time{}, 0, false
+}
+
+// roundOvertime rounds to the nearest half hour, in minutes
+func roundOvertime(d time.Duration) int {
+	return int(d.Round(30 * time.Minute).Minutes())
+}
+
+func formatOvertimeDuration(minutes int) string {
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%d giờ", minutes/60)
+	}
+	return fmt.Sprintf("%.1f giờ", float64(minutes)/60)
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================
+// OVERTIME BUTTONS
+// ============================================================
+
+// handleOvertimeClick walks the prompt: duration, then reason, then submit
+func (w *WebRTCManager) handleOvertimeClick(clicked *rtapi.MessageButtonClicked) {
+	userID := clicked.GetUserId()
+	action, value, _ := strings.Cut(strings.TrimPrefix(clicked.GetButtonId(), overtimeButtonPrefix), ":")
+
+	w.mu.Lock()
+	offer, exists := w.overtimeOffers[userID]
+	if exists && (action == "skip" || time.Now().After(offer.expires)) {
+		delete(w.overtimeOffers, userID)
+	}
+	w.mu.Unlock()
+
+	channelID := clicked.GetChannelId()
+	if !exists || time.Now().After(offer.expires) {
+		w.replyCommand(channelID, userID, client.BuildSimpleTextMessage("Yêu cầu đăng ký làm thêm giờ đã hết hạn."))
+		return
+	}
+
+	switch action {
+	case "skip":
+		w.replyCommand(offer.channelID, userID, client.BuildSimpleTextMessage("Đã bỏ qua đăng ký làm thêm giờ."))
+
+	case "minutes":
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes <= 0 {
+			logger.Warn("Invalid overtime duration", "button_id", clicked.GetButtonId())
+			return
+		}
+		w.mu.Lock()
+		offer.minutes = minutes
+		w.mu.Unlock()
+
+		buttons := make([]client.MessageButton, 0, len(overtimeReasons))
+		for _, reason := range overtimeReasons {
+			buttons = append(buttons, client.MessageButton{
+				ID:    overtimeButtonPrefix + "reason:" + reason.Key,
+				Label: reason.Label,
+				Style: client.ButtonStyleSuccess,
+			})
+		}
+		w.replyCommand(offer.channelID, userID, client.BuildMessageWithButtons("⏰ Lý do làm thêm giờ",
+			fmt.Sprintf("Thời gian: %s. Chọn lý do để hoàn tất đăng ký.", formatOvertimeDuration(minutes)), buttons))
+
+	case "reason":
+		w.mu.Lock()
+		minutes := offer.minutes
+		if minutes > 0 {
+			delete(w.overtimeOffers, userID)
+		}
+		w.mu.Unlock()
+		if minutes == 0 {
+			w.replyCommand(offer.channelID, userID, client.BuildSimpleTextMessage("Vui lòng chọn thời gian làm thêm trước."))
+			return
+		}
+
+		label := value
+		for _, reason := range overtimeReasons {
+			if reason.Key == value {
+				label = reason.Label
+			}
+		}
+		if err := w.submitOvertime(userID, offer, minutes, label); err != nil {
+			logger.Error("Failed to submit overtime", "user_id", userID, "err", err)
+			w.replyCommand(offer.channelID, userID, client.BuildErrorMessage("❌ Không thể đăng ký làm thêm giờ", "Vui lòng thử lại sau hoặc liên hệ quản lý."))
+			return
+		}
+		logger.Info("Overtime registered", "user_id", userID, "date", offer.date, "minutes", minutes, "reason", value)
+		w.replyCommand(offer.channelID, userID, client.BuildSuccessMessage("✅ Đã đăng ký làm thêm giờ",
+			fmt.Sprintf("Từ %s, %s\nLý do: %s", offer.start.Format("15:04"), formatOvertimeDuration(minutes), label)))
+
+	default:
+		logger.Warn("Unknown overtime button", "button_id", clicked.GetButtonId())
+	}
+}
+
+// submitOvertime forwards the registration, queueing it while the backend is down
+func (w *WebRTCManager) submitOvertime(userID int64, offer *overtimeOffer, minutes int, reason string) error {
+	w.mu.RLock()
+	queue := w.queue
+	w.mu.RUnlock()
+
+	reqBody := models.OvertimeRequest{
+		UserId:    userID,
+		Date:      offer.date,
+		StartTime: offer.start.Format("15:04"),
+		Minutes:   minutes,
+		Reason:    reason,
+	}
+
+	var body []byte
+	var statusCode int
+	var err error
+	if queue != nil {
+		key := fmt.Sprintf("overtime:%d:%s:%s", userID, offer.date, reqBody.StartTime)
+		body, statusCode, err = queue.SubmitOrQueue(context.Background(), key, models.APIOvertime, reqBody)
+		if errors.Is(err, api.ErrQueued) {
+			return nil
+		}
+	} else {
+		body, statusCode, err = w.apiClient.SendRequestContext(context.Background(), reqBody, models.APIOvertime)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.apiClient.LogResponse(body, statusCode)
+	if apiErr := api.ParseError(body, statusCode); apiErr != nil {
+		return apiErr
+	}
+	return nil
+}
@@ -3,7 +3,7 @@ package webrtc
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"mezon-checkin-bot/internal/audio"
 	"strings"
 	"time"
@@ -16,7 +16,7 @@ import (
 // PEER CONNECTION CREATION
 // ============================================================
 
-func (w *WebRTCManager) createPeerConnection() (*webrtc.PeerConnection, error) {
+func (w *WebRTCManager) createPeerConnection(ctx context.Context) (*webrtc.PeerConnection, error) {
 	mediaEngine := &webrtc.MediaEngine{}
 
 	// Register VP8 video codec
@@ -52,15 +52,7 @@ func (w *WebRTCManager) createPeerConnection() (*webrtc.PeerConnection, error) {
 	api := webrtc.NewAPI(webrtc.WithMediaEngine(mediaEngine))
 
 	config := webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{URLs: []string{"stun:stun.l.google.com:19302"}},
-			{URLs: []string{"stun:stun1.l.google.com:19302"}},
-			{
-				URLs:       []string{"turn:relay.mezon.vn:5349"},
-				Username:   "turnmezon",
-				Credential: "QuTs4zUEcbylWemXL7MK",
-			},
-		},
+		ICEServers: w.iceServers(ctx),
 	}
 
 	return api.NewPeerConnection(config)
@@ -71,17 +63,20 @@ func (w *WebRTCManager) createPeerConnection() (*webrtc.PeerConnection, error) {
 // ============================================================
 
 func (w *WebRTCManager) setupPeerConnectionHandlers(userID int64, pc *webrtc.PeerConnection, ctx context.Context) {
+	callLog := w.callLogger(userID)
+
 	// ICE candidate handler
 	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
 		if candidate == nil {
-			log.Println("✅ ICE gathering complete")
+			callLog.Debug("ICE gathering complete")
 			time.Sleep(1 * time.Second)
 			if pc.LocalDescription() != nil {
-				w.mu.RLock()
-				state, exists := w.connections[userID]
-				w.mu.RUnlock()
+				state, exists := w.connections.Get(userID)
 				if exists {
-					go w.sendICECandidatesFromSDP(userID, state.channelID, pc.LocalDescription().SDP)
+					sdp := pc.LocalDescription().SDP
+					state.goroutines.Go("ice_sender", func() {
+						w.sendICECandidatesFromSDP(userID, state.channelID, sdp)
+					})
 				}
 			}
 			return
@@ -91,49 +86,59 @@ func (w *WebRTCManager) setupPeerConnectionHandlers(userID int64, pc *webrtc.Pee
 
 	// Connection state handler
 	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
-		log.Printf("🔗 Connection: %s", state.String())
+		callLog.Info("Connection state changed", "state", state.String())
 		switch state {
 		case webrtc.PeerConnectionStateConnected:
-			log.Println("🎉 WebRTC CONNECTED!")
+			if w.isMaintenanceCall(userID) {
+				w.playMaintenanceNotice(userID)
+				return
+			}
 			w.startWelcomeAudio(userID)
 
 		case webrtc.PeerConnectionStateClosed, webrtc.PeerConnectionStateFailed:
-			log.Printf("🔴 Connection closed/failed: %s", state.String())
+			callLog.Warn("Connection closed or failed", "state", state.String())
 			w.cleanupConnection(userID)
 		}
 	})
 
 	// Track handler
 	pc.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
-		log.Printf("🎬 Track: %s (Codec: %s)", track.Kind().String(), track.Codec().MimeType)
+		callLog.Info("Track received", "kind", track.Kind().String(), "codec", track.Codec().MimeType)
+		if w.isMaintenanceCall(userID) {
+			return
+		}
 
 		if track.Kind() == webrtc.RTPCodecTypeVideo {
 			codec := track.Codec().MimeType
 
 			if strings.Contains(codec, "VP8") {
-				log.Println("   ✅ VP8 detected - OPTIMIZED real-time face detection enabled")
+				callLog.Info("VP8 detected, real-time face detection enabled")
 
 				ssrc := uint32(track.SSRC())
 
 				// Send immediate PLI
-				go func() {
+				w.goCall(userID, "pli_burst", func() {
 					for i := 0; i < 3; i++ {
 						if err := pc.WriteRTCP([]rtcp.Packet{
 							&rtcp.PictureLossIndication{MediaSSRC: ssrc},
 						}); err == nil {
-							log.Println("   ⚡ Immediate PLI sent (forcing IDR)")
+							callLog.Debug("Immediate PLI sent (forcing IDR)")
 						}
 						time.Sleep(100 * time.Millisecond)
 					}
-				}()
+				})
 
 				// Periodic PLI sender
-				go w.startPLISender(ctx, pc, ssrc)
+				w.goCall(userID, "pli_sender", func() { w.startPLISender(ctx, pc, ssrc, callLog) })
 
 				// Face detection
-				go w.realtimeFaceDetectionCapture(userID, track, ctx)
+				w.goCall(userID, "capture", func() { w.realtimeFaceDetectionCapture(userID, track, ctx) })
 			}
 		}
+
+		if track.Kind() == webrtc.RTPCodecTypeAudio && w.stt != nil {
+			w.goCall(userID, "voice_confirmation", func() { w.listenForVoiceConfirmation(userID, track) })
+		}
 	})
 }
 
@@ -156,24 +161,27 @@ func (w *WebRTCManager) setupAudioTrack(userID int64, pc *webrtc.PeerConnection)
 		return fmt.Errorf("failed to add track: %w", err)
 	}
 
-	log.Println("   ✅ Audio track added to peer connection")
+	w.callLogger(userID).Debug("Audio track added to peer connection")
 
 	// RTCP reader
-	go func() {
+	w.goCall(userID, "rtcp_reader", func() {
 		rtcpBuf := make([]byte, 1500)
 		for {
 			if _, _, rtcpErr := rtpSender.Read(rtcpBuf); rtcpErr != nil {
 				return
 			}
 		}
-	}()
-
-	w.mu.Lock()
-	defer w.mu.Unlock()
+	})
 
-	if state, exists := w.connections[userID]; exists {
+	if state, exists := w.connections.Get(userID); exists {
+		state.mu.Lock()
+		defer state.mu.Unlock()
 		state.audioPlayer = audio.NewAudioPlayer(audioTrack, state.audioStop)
-		log.Println("   ✅ Audio player initialized")
+		state.audioPlayer.SetCacheDir(w.audioConfig.TranscodeCacheDir)
+		if w.audioConfig.DuckingEnabled {
+			state.audioPlayer.EnableDucking(w.audioConfig.DuckingLevel)
+		}
+		state.logger.Debug("Audio player initialized")
 	}
 
 	return nil
@@ -183,7 +191,7 @@ func (w *WebRTCManager) setupAudioTrack(userID int64, pc *webrtc.PeerConnection)
 // PLI SENDER
 // ============================================================
 
-func (w *WebRTCManager) startPLISender(ctx context.Context, pc *webrtc.PeerConnection, ssrc uint32) {
+func (w *WebRTCManager) startPLISender(ctx context.Context, pc *webrtc.PeerConnection, ssrc uint32, callLog *slog.Logger) {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
@@ -191,7 +199,7 @@ func (w *WebRTCManager) startPLISender(ctx context.Context, pc *webrtc.PeerConne
 	maxErrors := 3
 
 	defer func() {
-		log.Println("   🛑 PLI sender stopped")
+		callLog.Debug("PLI sender stopped")
 	}()
 
 	for {
@@ -213,12 +221,12 @@ func (w *WebRTCManager) startPLISender(ctx context.Context, pc *webrtc.PeerConne
 			}); err != nil {
 				consecutiveErrors++
 				if consecutiveErrors >= maxErrors {
-					log.Printf("   ⚠️  PLI stopping (errors: %d)", consecutiveErrors)
+					callLog.Warn("PLI sender stopping", "errors", consecutiveErrors)
 					return
 				}
 			} else {
 				consecutiveErrors = 0
-				log.Println("   ✉️  PLI sent")
+				callLog.Debug("PLI sent")
 			}
 		}
 	}
@@ -0,0 +1,158 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/tracing"
+	"mezon-checkin-bot/internal/utils"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ============================================================
+// PENDING CONFIRMATIONS - Survive restarts
+// ============================================================
+
+// pendingRecord is a location confirmation saved to disk
+type pendingRecord struct {
+	UserID    int64     `json:"user_id"`
+	ChannelID int64     `json:"channel_id"`
+	Deadline  time.Time `json:"deadline"`
+	WFH       bool      `json:"wfh,omitempty"`
+	QROffice  string    `json:"qr_office,omitempty"`
+	CallID    string    `json:"call_id,omitempty"`
+}
+
+// RestorePendingConfirmations reloads the confirmations saved at path and
+// keeps saving there. Expired confirmations time out right away so the user
+// is told to retry. A standby only keeps the path, the confirmations are the
+// leader's until it takes over.
+func (w *WebRTCManager) RestorePendingConfirmations(path string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+	w.confirmationMu.Lock()
+	w.pendingPath = path
+	w.confirmationMu.Unlock()
+	if w.Standby() {
+		return 0, nil
+	}
+
+	var records []pendingRecord
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return 0, fmt.Errorf("failed to read pending confirmations: %w", err)
+	default:
+		if err := json.Unmarshal(data, &records); err != nil {
+			return 0, fmt.Errorf("failed to parse pending confirmations: %w", err)
+		}
+	}
+
+	w.confirmationMu.Lock()
+	for _, record := range records {
+		if _, exists := w.pendingConfirmations[record.UserID]; exists {
+			continue
+		}
+		w.restoreConfirmationLocked(record)
+	}
+	w.savePendingLocked()
+	w.confirmationMu.Unlock()
+
+	if len(records) > 0 {
+		logger.Info("Pending confirmations restored", "count", len(records))
+	}
+	return len(records), nil
+}
+
+// restoreConfirmationLocked recreates a confirmation with the time it had
+// left. Caller holds confirmationMu.
+func (w *WebRTCManager) restoreConfirmationLocked(record pendingRecord) {
+	userID, channelID := record.UserID, record.ChannelID
+
+	callID := record.CallID
+	if callID == "" {
+		callID = "restored"
+	}
+	// The confirmation owns the trace's first reference
+	trace := w.startCheckinTrace(userID, channelID, callID)
+	_, span := tracing.Start(trace.ctx, "checkin.location_confirmation", "wfh", record.WFH, "restored", true)
+
+	remaining := max(time.Until(record.Deadline), 0)
+	w.pendingConfirmations[userID] = &confirmationState{
+		userID:    userID,
+		channelID: channelID,
+		deadline:  record.Deadline,
+		wfh:       record.WFH,
+		qrOffice:  record.QROffice,
+		trace:     trace,
+		span:      span,
+		timer: time.AfterFunc(remaining, func() {
+			w.handleConfirmationTimeout(userID, channelID)
+		}),
+	}
+	w.callLogger(userID).Info("Restored pending confirmation", "remaining", remaining.Round(time.Second))
+}
+
+// dropPendingConfirmations cancels every confirmation without telling the
+// users or saving, so they are restored from the saved file: on the next
+// start, or by the instance taking over. Returns how many were dropped.
+func (w *WebRTCManager) dropPendingConfirmations(outcome string) int {
+	w.confirmationMu.Lock()
+	defer w.confirmationMu.Unlock()
+
+	dropped := len(w.pendingConfirmations)
+	for uid, state := range w.pendingConfirmations {
+		state.cancelOnce.Do(func() {
+			if state.timer != nil {
+				state.timer.Stop()
+			}
+		})
+		state.span.SetAttributes("outcome", outcome)
+		state.span.End()
+		w.releaseTrace(uid, state.trace)
+	}
+	w.pendingConfirmations = make(map[int64]*confirmationState)
+	return dropped
+}
+
+// savePendingLocked writes the pending confirmations to disk. Caller holds
+// confirmationMu. Nothing is saved during shutdown so the confirmations
+// cleared there are restored on the next start, nor by a standby: the file
+// is the leader's.
+func (w *WebRTCManager) savePendingLocked() {
+	if w.pendingPath == "" || w.Standby() {
+		return
+	}
+	select {
+	case <-w.shutdown:
+		return
+	default:
+	}
+
+	records := make([]pendingRecord, 0, len(w.pendingConfirmations))
+	for userID, state := range w.pendingConfirmations {
+		record := pendingRecord{
+			UserID:    userID,
+			ChannelID: state.channelID,
+			Deadline:  state.deadline,
+			WFH:       state.wfh,
+			QROffice:  state.qrOffice,
+		}
+		if state.trace != nil {
+			record.CallID = state.trace.callID
+		}
+		records = append(records, record)
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		logger.Error("Failed to marshal pending confirmations", "err", err)
+		return
+	}
+	if err := utils.WriteFileAtomic(w.pendingPath, data, 0644); err != nil {
+		logger.Error("Failed to write pending confirmations", "err", err)
+	}
+}
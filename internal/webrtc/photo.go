@@ -0,0 +1,439 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math/rand/v2"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/detector"
+	"mezon-checkin-bot/internal/i18n"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// PHOTO CHECK-IN - Selfies sent by DM instead of a call
+// ============================================================
+
+const (
+	defaultRequiredPhotos  = 2
+	defaultPhotoWindow     = 2 * time.Minute
+	defaultChallengeWindow = 30 * time.Second
+	defaultPhotoMaxBytes   = 5 << 20
+	extraPhotoAttempts     = 2 // Selfies allowed beyond RequiredPhotos to pass the challenges
+	photoDownloadTimeout   = 15 * time.Second
+	maxPhotoRedirects      = 5
+)
+
+var defaultPhotoHosts = []string{"mezon.vn", "mezon.ai"}
+
+// Pose challenges answered with the next selfie
+const (
+	challengeTurnHead  = "turn_head"
+	challengeCloseEyes = "close_eyes"
+)
+
+// photoSession collects one user's selfies until recognition
+type photoSession struct {
+	channelID    int64
+	started      time.Time
+	photos       int
+	passed       int     // Challenges answered in time
+	openEAR      float64 // Eye aspect ratio of the frontal selfie
+	challenge    string  // Pose asked for in the next selfie ("" = frontal)
+	challengedAt time.Time
+	capture      *captureState // Keeps the best crop
+	trace        *checkinTrace
+	mu           sync.Mutex
+}
+
+// SetPhotoCheckinConfig enables selfie check-in, filling in defaults
+func (w *WebRTCManager) SetPhotoCheckinConfig(cfg PhotoCheckinConfig) {
+	if cfg.RequiredPhotos <= 0 {
+		cfg.RequiredPhotos = defaultRequiredPhotos
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultPhotoWindow
+	}
+	if cfg.ChallengeWindow <= 0 {
+		cfg.ChallengeWindow = defaultChallengeWindow
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultPhotoMaxBytes
+	}
+	if len(cfg.AllowedHosts) == 0 {
+		cfg.AllowedHosts = defaultPhotoHosts
+	}
+
+	w.mu.Lock()
+	w.photoConfig = cfg
+	w.mu.Unlock()
+
+	logger.Info("Photo check-in configured", "enabled", cfg.Enabled,
+		"required_photos", cfg.RequiredPhotos, "window", cfg.Window, "challenge_window", cfg.ChallengeWindow,
+		"min_probability", cfg.MinProbability)
+}
+
+func (w *WebRTCManager) handleImageMessageEvent(data interface{}) {
+	eventMap, ok := data.(map[string]interface{})
+	if !ok {
+		logger.Error("Invalid image message event data type")
+		return
+	}
+
+	userID, _ := eventMap["user_id"].(int64)
+	channelID, _ := eventMap["channel_id"].(int64)
+	attachments, _ := eventMap["attachments"].([]client.Attachment)
+	if userID == 0 || channelID == 0 || len(attachments) == 0 {
+		return
+	}
+
+	w.mu.RLock()
+	cfg := w.photoConfig
+	w.mu.RUnlock()
+	inCall := w.connections.Has(userID)
+	if !cfg.Enabled || inCall {
+		return
+	}
+
+	go func() {
+		defer alerting.Recover("photo_checkin", "user_id", userID)
+		w.handleSelfie(userID, channelID, attachments[0], cfg)
+	}()
+}
+
+// handleSelfie adds one selfie to the user's session and recognizes once
+// enough selfies answered their pose challenges
+func (w *WebRTCManager) handleSelfie(userID, channelID int64, attachment client.Attachment, cfg PhotoCheckinConfig) {
+	session := w.photoSessionFor(userID, channelID, cfg)
+	callLog := w.callLogger(userID)
+	ctx := w.traceContext(userID)
+	stopTyping := w.startTyping(userID, channelID)
+	defer stopTyping()
+
+	// Downloaded before locking, a slow download doesn't hold the next selfie
+	img, err := downloadPhoto(ctx, attachment, cfg)
+	if err != nil {
+		callLog.Warn("Selfie download failed", "err", err)
+		w.replySelfie(channelID, userID, w.text(userID, "photo.download_failed", nil))
+		return
+	}
+	defer img.Close()
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	face, faceCount, found := w.locateFace(img, nil)
+	switch {
+	case !found:
+		w.replySelfie(channelID, userID, w.text(userID, "photo.no_face", nil))
+		return
+	case faceCount > 1:
+		w.replySelfie(channelID, userID, w.text(userID, "photo.multiple_faces", nil))
+		return
+	}
+
+	// Photos always go through the quality gate
+	quality := w.faceDetector.ScoreQuality(img, face)
+	if !quality.Passed {
+		callLog.Info("Low quality selfie", "reason", quality.RejectReason)
+		w.replySelfie(channelID, userID, w.text(userID, "photo.low_quality", i18n.Vars{"reason": quality.RejectReason}))
+		return
+	}
+
+	jpeg, err := w.encodeFaceCrop(img, face)
+	if err != nil {
+		callLog.Warn("Selfie encode failed", "err", err)
+		return
+	}
+
+	landmarks, ok := w.faceDetector.Landmarks(img, face)
+	if !ok {
+		w.replySelfie(channelID, userID, w.text(userID, "photo.no_face", nil))
+		return
+	}
+
+	session.photos++
+	answered, reply := w.checkChallenge(userID, session, img, landmarks, cfg)
+	if answered && session.challenge == "" {
+		// Only the frontal selfie is submitted for recognition
+		session.capture.keepBest(jpeg, quality.Sharpness)
+	}
+	if answered && session.challenge != "" {
+		session.passed++
+	}
+	callLog.Info("Selfie received", "photos", session.photos, "challenge", session.challenge,
+		"answered", answered, "passed", session.passed)
+
+	if session.capture.bestCrop == nil || session.passed < cfg.RequiredPhotos-1 {
+		if session.photos >= cfg.RequiredPhotos+extraPhotoAttempts {
+			w.endPhotoSession(userID, session)
+			defer w.releaseTrace(userID, session.trace)
+			w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "liveness_failed", Attempts: session.photos})
+			if err := w.SendCheckinFailed(channelID, userID, w.text(userID, "reason.photo_liveness", nil)); err != nil {
+				callLog.Error("Failed to send message", "err", err)
+			}
+			return
+		}
+		if session.capture.bestCrop != nil {
+			reply = strings.TrimSpace(reply + " " + w.nextChallenge(userID, session, landmarks, cfg))
+		}
+		w.replySelfie(channelID, userID, reply)
+		return
+	}
+
+	w.endPhotoSession(userID, session)
+	defer w.releaseTrace(userID, session.trace)
+	w.recognizeSelfie(ctx, userID, channelID, session, cfg)
+}
+
+// checkChallenge reports whether the selfie shows what was asked: a frontal
+// face first, then the challenged pose within ChallengeWindow. reply
+// explains a miss.
+func (w *WebRTCManager) checkChallenge(userID int64, session *photoSession, img gocv.Mat, landmarks detector.FaceLandmarks, cfg PhotoCheckinConfig) (bool, string) {
+	ear, earOK := detector.EyeAspectRatio(img, landmarks)
+
+	if session.challenge == "" {
+		if !landmarks.Frontal() || !earOK {
+			return false, w.text(userID, "photo.look_straight", nil)
+		}
+		session.openEAR = ear
+		return true, ""
+	}
+
+	if time.Since(session.challengedAt) > cfg.ChallengeWindow {
+		return false, w.text(userID, "photo.challenge_late", nil)
+	}
+	switch session.challenge {
+	case challengeTurnHead:
+		if landmarks.Turned() {
+			return true, ""
+		}
+	case challengeCloseEyes:
+		if earOK && detector.EyesClosed(ear, session.openEAR) {
+			return true, ""
+		}
+	}
+	return false, w.text(userID, "photo.challenge_wrong", nil)
+}
+
+// nextChallenge picks a random pose for the next selfie and returns the
+// prompt. A head turn needs the nose landmark, which only YuNet provides.
+func (w *WebRTCManager) nextChallenge(userID int64, session *photoSession, landmarks detector.FaceLandmarks, cfg PhotoCheckinConfig) string {
+	session.challenge = challengeCloseEyes
+	if landmarks.HasNose && rand.IntN(2) == 0 {
+		session.challenge = challengeTurnHead
+	}
+	session.challengedAt = time.Now()
+
+	key := "photo.close_eyes"
+	if session.challenge == challengeTurnHead {
+		key = "photo.turn_head"
+	}
+	return w.text(userID, key, i18n.Vars{
+		"count":   session.photos,
+		"seconds": int(cfg.ChallengeWindow.Seconds()),
+	})
+}
+
+// recognizeSelfie submits the best selfie and continues the check-in flow
+// (location confirmation, success message) without touching any call
+func (w *WebRTCManager) recognizeSelfie(ctx context.Context, userID, channelID int64, session *photoSession, cfg PhotoCheckinConfig) {
+	callLog := w.callLogger(userID)
+
+	crop, err := gocv.IMDecode(session.capture.bestCrop, gocv.IMReadColor)
+	if err != nil {
+		callLog.Error("Failed to decode best selfie", "err", err)
+		return
+	}
+	defer crop.Close()
+
+	response, err := w.faceDetector.Recognize(ctx, crop, session.capture.bestCrop, userID, session.photos)
+	w.archiveCrop(userID, session.capture.bestCrop, err == nil && response != nil)
+	switch {
+	case errors.Is(err, api.ErrAlreadyCheckedIn):
+		if w.callMode(userID, nil) == ModeCheckout {
+			w.checkOut(userID, channelID, nil, callLog)
+			return
+		}
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "already_checked_in", Attempts: session.photos})
+		if err := w.SendCheckinNotice(channelID, userID, w.text(userID, "notice.already_checked_in", nil)); err != nil {
+			callLog.Error("Failed to send message", "err", err)
+		}
+		return
+	case err == nil && response != nil && cfg.MinProbability > 0 && response.Probability < cfg.MinProbability:
+		callLog.Warn("Selfie probability below photo threshold", "probability", response.Probability, "min", cfg.MinProbability)
+		response = nil
+	case err != nil:
+		callLog.Warn("Selfie recognition failed", "err", err)
+	}
+
+	if response == nil {
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "max_attempts", Attempts: session.photos})
+		if err := w.SendCheckinFailed(channelID, userID, w.text(userID, "reason.photo_unrecognized", nil)); err != nil {
+			callLog.Error("Failed to send message", "err", err)
+		}
+		return
+	}
+
+	callLog.Info("Selfie recognized", "photos", session.photos)
+	w.recordEvent(userID, CheckinEvent{
+		Outcome:     OutcomeRecognized,
+		Attempts:    session.photos,
+		WFH:         response.IsWFH,
+		Probability: response.Probability,
+	})
+	w.recordIdentityResult(userID, "")
+	if w.callMode(userID, response) == ModeCheckout {
+		w.checkOut(userID, channelID, response, callLog)
+		return
+	}
+	w.confirmRecognized(userID, channelID, response, session.capture.bestCrop, callLog)
+}
+
+// photoSessionFor returns the user's open session, starting a new one (and
+// check-in trace) when there is none or the window passed
+func (w *WebRTCManager) photoSessionFor(userID, channelID int64, cfg PhotoCheckinConfig) *photoSession {
+	w.mu.Lock()
+	session, exists := w.photoSessions[userID]
+	if exists && time.Since(session.started) <= cfg.Window {
+		w.mu.Unlock()
+		return session
+	}
+	expired := session
+	session = &photoSession{
+		channelID: channelID,
+		started:   time.Now(),
+		capture:   &captureState{},
+	}
+	w.photoSessions[userID] = session
+	w.mu.Unlock()
+
+	if exists {
+		logger.Info("Photo session expired, starting over", "user_id", userID)
+		w.releaseTrace(userID, expired.trace)
+	}
+	session.trace = w.startCheckinTrace(userID, channelID, api.NewCallID())
+	return session
+}
+
+// endPhotoSession closes the session. The caller releases its trace once the
+// outcome is handled, later check-in stages hold their own references.
+func (w *WebRTCManager) endPhotoSession(userID int64, session *photoSession) {
+	w.mu.Lock()
+	if w.photoSessions[userID] == session {
+		delete(w.photoSessions, userID)
+	}
+	w.mu.Unlock()
+}
+
+func (w *WebRTCManager) replySelfie(channelID, userID int64, text string) {
+	if err := w.SendCheckinNotice(channelID, userID, text); err != nil {
+		logger.Error("Failed to reply to selfie", "user_id", userID, "err", err)
+	}
+}
+
+// encodeFaceCrop crops, squares and optionally aligns the face like call
+// frames, returning the JPEG
+func (w *WebRTCManager) encodeFaceCrop(img gocv.Mat, face image.Rectangle) ([]byte, error) {
+	expanded := w.expandAndCenterFace(face, img.Cols(), img.Rows())
+	cropped := img.Region(expanded)
+	defer cropped.Close()
+
+	square := w.makeSquare(cropped, nil)
+	defer square.Close()
+
+	if w.faceDetector.Config.AlignFaces {
+		if aligned, ok := w.faceDetector.AlignFace(square); ok {
+			square.Close()
+			square = aligned
+		}
+	}
+
+	buf := w.bufferPool.Get()
+	defer w.bufferPool.Put(buf)
+	if err := w.encodeImageToJPEG(square, buf); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// downloadPhoto fetches an image attachment from an allowed host and decodes it
+func downloadPhoto(ctx context.Context, attachment client.Attachment, cfg PhotoCheckinConfig) (gocv.Mat, error) {
+	u, err := url.Parse(attachment.URL)
+	if err != nil || u.Scheme != "https" || !allowedPhotoHost(u.Hostname(), cfg.AllowedHosts) {
+		return gocv.Mat{}, fmt.Errorf("attachment URL not allowed: %s", attachment.URL)
+	}
+	if attachment.Size > cfg.MaxBytes {
+		return gocv.Mat{}, fmt.Errorf("image too large: %d bytes", attachment.Size)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := photoHTTPClient(cfg).Do(req)
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return gocv.Mat{}, fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, cfg.MaxBytes+1))
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("download failed: %w", err)
+	}
+	if int64(len(data)) > cfg.MaxBytes {
+		return gocv.Mat{}, fmt.Errorf("image larger than %d bytes", cfg.MaxBytes)
+	}
+
+	img, err := gocv.IMDecode(data, gocv.IMReadColor)
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("decode failed: %w", err)
+	}
+	if img.Empty() {
+		img.Close()
+		return gocv.Mat{}, fmt.Errorf("not a supported image")
+	}
+	return img, nil
+}
+
+// photoHTTPClient downloads attachments, following redirects only to
+// allowed hosts over https
+func photoHTTPClient(cfg PhotoCheckinConfig) *http.Client {
+	return &http.Client{
+		Timeout: photoDownloadTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxPhotoRedirects {
+				return fmt.Errorf("too many redirects")
+			}
+			if req.URL.Scheme != "https" || !allowedPhotoHost(req.URL.Hostname(), cfg.AllowedHosts) {
+				return fmt.Errorf("redirect to %s not allowed", req.URL.Host)
+			}
+			return nil
+		},
+	}
+}
+
+func allowedPhotoHost(host string, allowed []string) bool {
+	host = strings.ToLower(host)
+	for _, suffix := range allowed {
+		suffix = strings.ToLower(suffix)
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/utils"
+	"mezon-checkin-bot/models"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ============================================================
+// EMPLOYEE PROFILES - Backend metadata cached locally
+// ============================================================
+
+// EmployeeProfile is what the backend knows about a Mezon user
+type EmployeeProfile struct {
+	UserID     int64    `json:"user_id,string"`
+	EmployeeID string   `json:"employee_id,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	OfficeIDs  []string `json:"office_ids,omitempty"` // Offices the employee may check in at
+	ClanIDs    []int64  `json:"clan_ids,omitempty"`   // Clans whose announcement channels show the employee's check-ins
+	Locale     string   `json:"locale,omitempty"`
+}
+
+// EmployeeProfileList is the response of the profiles endpoint
+type EmployeeProfileList struct {
+	Users []EmployeeProfile `json:"users"`
+}
+
+// ProfileSyncConfig controls the profile cache
+type ProfileSyncConfig struct {
+	Path     string        // Cache file, read at start so profiles survive a slow backend
+	Interval time.Duration // Refresh period (default 30m)
+}
+
+const defaultProfileRefresh = 30 * time.Minute
+
+// StartProfileSync loads the cached profiles and refreshes them from the
+// backend every cfg.Interval until shutdown. A bad cache is only logged.
+func (w *WebRTCManager) StartProfileSync(cfg ProfileSyncConfig) error {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultProfileRefresh
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	var cached EmployeeProfileList
+	data, err := os.ReadFile(cfg.Path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		logger.Warn("Failed to read profile cache", "path", cfg.Path, "err", err)
+	case json.Unmarshal(data, &cached) != nil:
+		logger.Warn("Ignoring unreadable profile cache", "path", cfg.Path)
+	default:
+		w.applyProfiles(cached.Users)
+		logger.Info("Employee profiles loaded from cache", "users", len(cached.Users), "path", cfg.Path)
+	}
+
+	w.refreshEvery("profile_sync", cfg.Interval, func() {
+		if err := w.refreshProfiles(cfg.Path); err != nil {
+			logger.Warn("Failed to refresh employee profiles, keeping cached ones", "err", err)
+		}
+	})
+	return nil
+}
+
+// refreshEvery runs refresh now and then every interval until shutdown
+func (w *WebRTCManager) refreshEvery(name string, interval time.Duration, refresh func()) {
+	go func() {
+		defer alerting.Recover(name)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			refresh()
+			select {
+			case <-w.shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// refreshProfiles fetches the profiles and replaces the cache on success
+func (w *WebRTCManager) refreshProfiles(path string) error {
+	body, statusCode, err := w.apiClient.GetRequest(models.APIEmployeeProfiles)
+	if err != nil {
+		return err
+	}
+	if !w.apiClient.IsSuccessStatusCode(statusCode) {
+		return fmt.Errorf("API returned status %d", statusCode)
+	}
+	var list EmployeeProfileList
+	if err := w.apiClient.ParseResponse(body, &list); err != nil {
+		return err
+	}
+
+	w.applyProfiles(list.Users)
+	logger.Info("Employee profiles refreshed", "users", len(list.Users))
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile cache: %w", err)
+	}
+	if err := utils.WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profile cache: %w", err)
+	}
+	return nil
+}
+
+// applyProfiles makes the profiles the source of names, locales and office
+// assignments
+func (w *WebRTCManager) applyProfiles(list []EmployeeProfile) {
+	profiles := make(map[int64]EmployeeProfile, len(list))
+	offices := make(map[int64][]string)
+	for _, profile := range list {
+		if profile.UserID == 0 {
+			continue
+		}
+		profiles[profile.UserID] = profile
+		if len(profile.OfficeIDs) > 0 {
+			offices[profile.UserID] = profile.OfficeIDs
+		}
+		w.locales.SetProfileLocale(profile.UserID, profile.Locale)
+	}
+
+	w.mu.Lock()
+	w.profiles = profiles
+	w.mu.Unlock()
+	w.locationConfig.SetProfileAssignments(offices)
+}
+
+// Profile returns the cached profile of the user
+func (w *WebRTCManager) Profile(userID int64) (EmployeeProfile, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	profile, ok := w.profiles[userID]
+	return profile, ok
+}
+
+// userLabel names the user in messages to managers: "Name (EMP01)" when the
+// profile is known, the user ID otherwise
+func (w *WebRTCManager) userLabel(userID int64) string {
+	profile, ok := w.Profile(userID)
+	if !ok || profile.Name == "" {
+		return strconv.FormatInt(userID, 10)
+	}
+	if profile.EmployeeID != "" {
+		return fmt.Sprintf("%s (%s)", profile.Name, profile.EmployeeID)
+	}
+	return profile.Name
+}
@@ -0,0 +1,351 @@
+package webrtc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/audit"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/i18n"
+	"mezon-checkin-bot/internal/qrcode"
+	"mezon-checkin-bot/internal/tracing"
+	"mezon-checkin-bot/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// QR CHECK-IN - Rotating signed codes posted per office
+// ============================================================
+
+// QRCheckinConfig signs the office QR codes. Codes are posted as images to
+// each office's QRChannelID in ClanID, meant to be shown on a display at
+// the office. Each code works once per user.
+type QRCheckinConfig struct {
+	Secret   []byte        // HMAC key, at least minQRSecretLen bytes
+	Rotation time.Duration // How often a new code is posted (default 5m)
+	ClanID   int64
+}
+
+const (
+	qrCodePrefix      = "MZQR-"
+	defaultQRRotation = 5 * time.Minute
+	minQRSecretLen    = 16
+	qrSignatureLen    = 8 // Bytes of the HMAC kept in the code
+	qrImageScale      = 8 // Pixels per module
+)
+
+var (
+	errInvalidQRCode = errors.New("invalid QR code")
+	errExpiredQRCode = errors.New("QR code expired")
+)
+
+// StartQRCheckin posts a fresh code to every office with a QR channel each
+// rotation until shutdown
+func (w *WebRTCManager) StartQRCheckin(cfg QRCheckinConfig) error {
+	if len(cfg.Secret) < minQRSecretLen {
+		return fmt.Errorf("QR secret must be at least %d bytes", minQRSecretLen)
+	}
+	if cfg.Rotation <= 0 {
+		cfg.Rotation = defaultQRRotation
+	}
+	w.images.mu.Lock()
+	hosted := w.images.baseURL != ""
+	w.images.mu.Unlock()
+	if !hosted {
+		return fmt.Errorf("QR check-in needs image hosting (IMAGE_BASE_URL)")
+	}
+
+	w.mu.Lock()
+	w.qrConfig = cfg
+	w.mu.Unlock()
+
+	go func() {
+		defer alerting.Recover("qr_checkin")
+
+		ticker := time.NewTicker(cfg.Rotation)
+		defer ticker.Stop()
+		for {
+			w.postQRCodes(cfg, time.Now())
+
+			select {
+			case <-w.shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	logger.Info("QR check-in enabled", "rotation", cfg.Rotation, "clan_id", cfg.ClanID)
+	return nil
+}
+
+// postQRCodes posts the current code of each office. A code outlives its
+// rotation so a scan made just before the next code appears still works.
+func (w *WebRTCManager) postQRCodes(cfg QRCheckinConfig, now time.Time) {
+	if w.dmManager == nil || w.locationConfig == nil || w.Standby() {
+		return
+	}
+
+	expires := now.Add(2 * cfg.Rotation)
+	for _, office := range w.locationConfig.GetOffices() {
+		if office.QRChannelID == 0 {
+			continue
+		}
+
+		code := signQRCode(cfg.Secret, office.ID, expires)
+		imageURL, err := w.qrImageURL(code, expires)
+		if err != nil {
+			logger.Error("Failed to render QR code", "office_id", office.ID, "err", err)
+			continue
+		}
+
+		content := client.BuildQRCodeMessage(office.Name, imageURL, expires.In(office.Zone()))
+		if err := w.dmManager.SendChannelMessage(cfg.ClanID, office.QRChannelID, content); err != nil {
+			logger.Error("Failed to post QR code", "office_id", office.ID, "err", err)
+			continue
+		}
+		logger.Debug("QR code posted", "office_id", office.ID, "expires", expires)
+	}
+}
+
+// qrImageURL renders the code and hosts it until the code expires
+func (w *WebRTCManager) qrImageURL(code string, expires time.Time) (string, error) {
+	qr, err := qrcode.Encode(code)
+	if err != nil {
+		return "", err
+	}
+	png, err := qr.PNG(qrImageScale)
+	if err != nil {
+		return "", err
+	}
+	url := w.hostImage(png, "image/png", time.Until(expires))
+	if url == "" {
+		return "", errors.New("image hosting unavailable")
+	}
+	return url, nil
+}
+
+// signQRCode builds "MZQR-<office>-<expiry base36>-<signature hex>"
+func signQRCode(secret []byte, officeID string, expires time.Time) string {
+	payload := strings.ToUpper(officeID) + "-" + strconv.FormatInt(expires.Unix(), 36)
+	return qrCodePrefix + payload + "-" + hex.EncodeToString(qrSignature(secret, payload))
+}
+
+// verifyQRCode checks the signature and expiry, returning the office ID
+func verifyQRCode(secret []byte, code string, now time.Time) (string, error) {
+	payload, signature, ok := cutLast(strings.TrimPrefix(code, qrCodePrefix), "-")
+	if !ok || !strings.HasPrefix(code, qrCodePrefix) {
+		return "", errInvalidQRCode
+	}
+	officeID, expiry, ok := cutLast(payload, "-")
+	if !ok || officeID == "" {
+		return "", errInvalidQRCode
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, qrSignature(secret, payload)) {
+		return "", errInvalidQRCode
+	}
+
+	unix, err := strconv.ParseInt(expiry, 36, 64)
+	if err != nil {
+		return "", errInvalidQRCode
+	}
+	if now.After(time.Unix(unix, 0)) {
+		return "", errExpiredQRCode
+	}
+	return officeID, nil
+}
+
+func qrSignature(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)[:qrSignatureLen]
+}
+
+// cutLast splits s around the last sep
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return "", "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// ============================================================
+// QR CODE REPLY
+// ============================================================
+
+// handleQRCode starts a check-in from a scanned code sent by DM. The user then
+// confirms with their location, which must match the code's office. Returns
+// false if QR check-in is disabled.
+func (w *WebRTCManager) handleQRCode(userID, channelID int64, code string) bool {
+	w.mu.RLock()
+	secret := w.qrConfig.Secret
+	w.mu.RUnlock()
+	if len(secret) == 0 {
+		return false
+	}
+
+	officeID, err := verifyQRCode(secret, code, time.Now())
+	if err != nil {
+		logger.Warn("Rejected QR code", "user_id", userID, "err", err)
+		reason := w.text(userID, "qr.invalid", nil)
+		if errors.Is(err, errExpiredQRCode) {
+			reason = w.text(userID, "qr.expired", nil)
+		}
+		w.replyCommand(channelID, userID, client.BuildErrorMessage("❌ "+w.text(userID, "reason.qr_failed", nil), reason))
+		return true
+	}
+
+	if !w.useQRCode(userID, code, time.Now()) {
+		logger.Warn("Rejected reused QR code", "user_id", userID, "office_id", officeID)
+		w.replyCommand(channelID, userID, client.BuildErrorMessage(
+			"❌ "+w.text(userID, "reason.qr_failed", nil), w.text(userID, "qr.used", nil)))
+		return true
+	}
+
+	office, exists := w.locationConfig.findOffice(officeID)
+	if !exists || !office.Enabled {
+		w.replyCommand(channelID, userID, client.BuildErrorMessage(
+			"❌ "+w.text(userID, "reason.qr_failed", nil), w.text(userID, "qr.office_inactive", nil)))
+		return true
+	}
+
+	// The confirmation takes its own reference on the trace
+	trace := w.startCheckinTrace(userID, channelID, api.NewCallID())
+	defer w.releaseTrace(userID, trace)
+
+	w.callLogger(userID).Info("QR code accepted", "office_id", office.ID)
+	w.startConfirmationTimeout(userID, channelID, false)
+	w.setPendingQROffice(userID, office.ID)
+
+	notice := w.text(userID, "notice.qr_accepted", i18n.Vars{"office": office.Name})
+	if err := w.SendCheckinNotice(channelID, userID, notice); err != nil {
+		w.callLogger(userID).Error("Failed to send QR notice", "err", err)
+	}
+	return true
+}
+
+// useQRCode marks the code used by the user, reporting false if it already
+// was. Entries are kept until the code expires.
+func (w *WebRTCManager) useQRCode(userID int64, code string, now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for key, expires := range w.qrUses {
+		if now.After(expires) {
+			delete(w.qrUses, key)
+		}
+	}
+
+	key := strconv.FormatInt(userID, 10) + ":" + code
+	if _, used := w.qrUses[key]; used {
+		return false
+	}
+	w.qrUses[key] = now.Add(2 * w.qrConfig.Rotation)
+	return true
+}
+
+// findOffice returns the office with the ID, enabled or not
+func (c *LocationConfig) findOffice(id string) (Office, bool) {
+	for _, office := range c.AllOffices() {
+		if strings.EqualFold(office.ID, id) {
+			return office, true
+		}
+	}
+	return Office{}, false
+}
+
+// SetOfficeQRChannel sets where the office's QR code is posted (0 = none)
+// and saves it
+func (c *LocationConfig) SetOfficeQRChannel(id string, channelID int64, actor int64) error {
+	return c.updateOffice(id, actor, fmt.Sprintf("qr channel %d", channelID), func(office *Office) {
+		office.QRChannelID = channelID
+	})
+}
+
+func (w *WebRTCManager) setPendingQROffice(userID int64, officeID string) {
+	w.confirmationMu.Lock()
+	defer w.confirmationMu.Unlock()
+	if state, exists := w.pendingConfirmations[userID]; exists {
+		state.qrOffice = officeID
+		w.savePendingLocked()
+	}
+}
+
+// pendingQROffice returns the office of a pending QR check-in ("" if none)
+func (w *WebRTCManager) pendingQROffice(userID int64) string {
+	w.confirmationMu.Lock()
+	defer w.confirmationMu.Unlock()
+	if state, exists := w.pendingConfirmations[userID]; exists {
+		return state.qrOffice
+	}
+	return ""
+}
+
+// approveQRCheckin records a QR check-in confirmed at the office and notifies
+// the user. Unlike approveCheckin there is no recognized check-in to approve.
+func (w *WebRTCManager) approveQRCheckin(userID, channelID int64, confirmed ConfirmedLocation, place string) error {
+	callLog := w.callLogger(userID)
+	ctx, span := tracing.Start(w.traceContext(userID), "api.qr_check_in")
+	defer span.End()
+
+	reqBody := models.QRCheckIn{
+		UserId:    userID,
+		OfficeId:  confirmed.OfficeID,
+		Latitude:  confirmed.Latitude,
+		Longitude: confirmed.Longitude,
+	}
+
+	w.mu.RLock()
+	queue := w.queue
+	w.mu.RUnlock()
+
+	var body []byte
+	var statusCode int
+	var err error
+	if queue != nil {
+		key := fmt.Sprintf("qr-check-in:%d:%s", userID, time.Now().Format("2006-01-02"))
+		body, statusCode, err = queue.SubmitOrQueue(ctx, key, models.APIQRCheckIn, reqBody)
+		w.auditRequest(audit.ActionQRCheckin, userID, statusCode, err)
+		if errors.Is(err, api.ErrQueued) {
+			span.SetAttributes("queued", true)
+			callLog.Info("QR check-in queued until the backend recovers")
+			return w.SendCheckinQueued(channelID, userID, place)
+		}
+	} else {
+		body, statusCode, err = w.apiClient.SendRequestContext(ctx, reqBody, models.APIQRCheckIn)
+		w.auditRequest(audit.ActionQRCheckin, userID, statusCode, err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		callLog.Error("API request failed", "err", err)
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "approval_error", OfficeID: confirmed.OfficeID})
+		return err
+	}
+
+	w.apiClient.LogResponse(body, statusCode)
+
+	span.SetAttributes("status", statusCode)
+	if apiErr := api.ParseError(body, statusCode); apiErr != nil {
+		span.RecordError(apiErr)
+		if errors.Is(apiErr, api.ErrAlreadyCheckedIn) {
+			callLog.Info("User was already checked in")
+			return w.SendCheckinSuccessAt(channelID, userID, "", place)
+		}
+		callLog.Warn("QR check-in rejected", "err", apiErr)
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "approval_rejected", OfficeID: confirmed.OfficeID})
+		if err := w.SendCheckinFailed(channelID, userID, w.text(userID, "reason.qr_failed", nil)); err != nil {
+			callLog.Error("Failed to send failure message", "err", err)
+		}
+		return apiErr
+	}
+
+	return w.SendCheckinSuccessAt(channelID, userID, "", place)
+}
@@ -0,0 +1,67 @@
+package webrtc
+
+import (
+	"fmt"
+	mzapi "mezon-checkin-bot/mezon-protobuf/go/api"
+	"time"
+)
+
+// ============================================================
+// REACTIONS - Quick acknowledgements without extra messages
+// ============================================================
+
+const (
+	// consentEmoji on the confirmation DM tells the bot the location is coming
+	consentEmoji = "✅"
+	// receivedEmoji is put on the user's location message as soon as it arrives
+	receivedEmoji = "👀"
+)
+
+// handleReactionEvent treats a ✅ on one of the bot's DMs, while the user has
+// a pending confirmation, as consent to share the location: the deadline is
+// extended once so finding the location picker doesn't time the check-in out
+func (w *WebRTCManager) handleReactionEvent(data interface{}) {
+	reaction, ok := data.(*mzapi.MessageReaction)
+	if !ok {
+		logger.Error("Invalid message_reaction data type", "type", fmt.Sprintf("%T", data))
+		return
+	}
+	if reaction.Action || reaction.Emoji != consentEmoji || reaction.MessageSenderId != w.client.ClientID {
+		return // Removed, another emoji, or not on a bot DM
+	}
+
+	userID := reaction.SenderId
+	w.confirmationMu.Lock()
+	defer w.confirmationMu.Unlock()
+
+	state, exists := w.pendingConfirmations[userID]
+	if !exists || state.channelID != reaction.ChannelId {
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.confirmed || state.consented || state.timer == nil {
+		return
+	}
+	if !state.timer.Stop() {
+		return // Already timing out
+	}
+	state.consented = true
+	state.timer.Reset(confirmationTimeout)
+	state.deadline = time.Now().Add(confirmationTimeout)
+	w.savePendingLocked()
+
+	w.callLogger(userID).Info("Location consent by reaction, confirmation extended", "timeout", confirmationTimeout)
+}
+
+// acknowledgeLocation reacts to the user's location message so they see it
+// was received while validation runs
+func (w *WebRTCManager) acknowledgeLocation(channelID int64, message *mzapi.ChannelMessage) {
+	if w.dmManager == nil || message == nil || message.MessageId == 0 {
+		return
+	}
+	if err := w.dmManager.ReactToDM(channelID, message, receivedEmoji); err != nil {
+		logger.Debug("Failed to react to location message", "user_id", message.SenderId, "err", err)
+	}
+}
@@ -0,0 +1,111 @@
+package webrtc
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ============================================================
+// CONNECTIONS REGISTRY - Open calls by user ID, sharded so the
+// signaling, ICE, capture and audio paths of different calls do
+// not contend on one lock. Each call's own fields are guarded by
+// connectionState.mu.
+// ============================================================
+
+const connectionShards = 32 // Power of two
+
+type connectionShard struct {
+	mu          sync.RWMutex
+	connections map[int64]*connectionState
+}
+
+type connectionRegistry struct {
+	shards [connectionShards]connectionShard
+	count  atomic.Int64
+}
+
+func newConnectionRegistry() *connectionRegistry {
+	r := &connectionRegistry{}
+	for i := range r.shards {
+		r.shards[i].connections = make(map[int64]*connectionState)
+	}
+	return r
+}
+
+func (r *connectionRegistry) shard(userID int64) *connectionShard {
+	return &r.shards[uint64(userID)&(connectionShards-1)]
+}
+
+// Get returns the user's open call
+func (r *connectionRegistry) Get(userID int64) (*connectionState, bool) {
+	shard := r.shard(userID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	state, exists := shard.connections[userID]
+	return state, exists
+}
+
+// Has reports whether the user has an open call
+func (r *connectionRegistry) Has(userID int64) bool {
+	_, exists := r.Get(userID)
+	return exists
+}
+
+// Set registers the user's call, replacing any previous one
+func (r *connectionRegistry) Set(userID int64, state *connectionState) {
+	shard := r.shard(userID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if _, exists := shard.connections[userID]; !exists {
+		r.count.Add(1)
+	}
+	shard.connections[userID] = state
+}
+
+// Delete unregisters the user's call and returns it, so only one caller
+// cleans it up
+func (r *connectionRegistry) Delete(userID int64) (*connectionState, bool) {
+	shard := r.shard(userID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	state, exists := shard.connections[userID]
+	if exists {
+		delete(shard.connections, userID)
+		r.count.Add(-1)
+	}
+	return state, exists
+}
+
+// Len is the number of open calls
+func (r *connectionRegistry) Len() int {
+	return int(r.count.Load())
+}
+
+// Range calls fn for each open call, one shard locked at a time. fn must
+// not touch the registry.
+func (r *connectionRegistry) Range(fn func(userID int64, state *connectionState)) {
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.RLock()
+		for userID, state := range shard.connections {
+			fn(userID, state)
+		}
+		shard.mu.RUnlock()
+	}
+}
+
+// DeleteAll unregisters every call and returns them, for shutdown
+func (r *connectionRegistry) DeleteAll() map[int64]*connectionState {
+	all := make(map[int64]*connectionState)
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.Lock()
+		for userID, state := range shard.connections {
+			all[userID] = state
+		}
+		r.count.Add(-int64(len(shard.connections)))
+		shard.connections = make(map[int64]*connectionState)
+		shard.mu.Unlock()
+	}
+	return all
+}
@@ -0,0 +1,229 @@
+package webrtc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/flags"
+	"mezon-checkin-bot/internal/utils"
+	"mezon-checkin-bot/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// REMOTE CONFIG - Offices, capture tuning and flags pulled from
+// the backend so a fleet of bots is retuned centrally
+// ============================================================
+
+// RemoteConfig is the response of the bot config endpoint. Sections left
+// out keep the bot's current settings.
+type RemoteConfig struct {
+	Version string                `json:"version"`
+	Offices []Office              `json:"offices,omitempty"`
+	Capture *RemoteCaptureConfig  `json:"capture,omitempty"`
+	Flags   map[string]flags.Rule `json:"flags,omitempty"`
+}
+
+// RemoteCaptureConfig tunes the capture pipeline. Fields left at 0 use
+// DefaultCaptureConfig.
+type RemoteCaptureConfig struct {
+	CaptureTimeoutSeconds int `json:"capture_timeout_seconds,omitempty"`
+	PLITimeoutSeconds     int `json:"pli_timeout_seconds,omitempty"`
+	CaptureIntervalMs     int `json:"capture_interval_ms,omitempty"`
+	MaxAttempts           int `json:"max_attempts,omitempty"`
+	SampleBufferMax       int `json:"sample_buffer_max,omitempty"`
+	TypingIntervalSeconds int `json:"typing_interval_seconds,omitempty"`
+}
+
+// RemoteConfigSyncConfig controls the remote config pull
+type RemoteConfigSyncConfig struct {
+	Path     string        // Last applied config, used at start until the backend answers
+	Interval time.Duration // Refresh period (default 5m)
+}
+
+const defaultRemoteConfigRefresh = 5 * time.Minute
+
+// StartRemoteConfigSync applies the saved config, then pulls the config from
+// the backend every cfg.Interval until shutdown. A config that fails
+// validation is rejected whole and the current settings are kept.
+func (w *WebRTCManager) StartRemoteConfigSync(cfg RemoteConfigSyncConfig) error {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultRemoteConfigRefresh
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := os.ReadFile(cfg.Path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		logger.Warn("Failed to read saved remote config", "path", cfg.Path, "err", err)
+	default:
+		if err := w.applyRemoteConfig(data); err != nil {
+			logger.Warn("Ignoring saved remote config", "path", cfg.Path, "err", err)
+		}
+	}
+
+	w.refreshEvery("remote_config", cfg.Interval, func() {
+		if err := w.refreshRemoteConfig(cfg.Path); err != nil {
+			logger.Warn("Failed to refresh remote config, keeping current settings", "err", err)
+		}
+	})
+	return nil
+}
+
+// refreshRemoteConfig fetches the config and applies it when it changed
+func (w *WebRTCManager) refreshRemoteConfig(path string) error {
+	body, statusCode, err := w.apiClient.GetRequest(models.APIBotConfig)
+	if err != nil {
+		return err
+	}
+	if !w.apiClient.IsSuccessStatusCode(statusCode) {
+		return fmt.Errorf("API returned status %d", statusCode)
+	}
+
+	w.mu.RLock()
+	unchanged := bytes.Equal(body, w.remoteConfigRaw)
+	w.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	if err := w.applyRemoteConfig(body); err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(path, body, 0644); err != nil {
+		return fmt.Errorf("failed to save remote config: %w", err)
+	}
+	return nil
+}
+
+// applyRemoteConfig validates every section before changing anything
+func (w *WebRTCManager) applyRemoteConfig(data []byte) error {
+	var config RemoteConfig
+	if err := w.apiClient.ParseResponse(data, &config); err != nil {
+		return err
+	}
+
+	var capture *CaptureConfig
+	if config.Capture != nil {
+		built, err := config.Capture.build()
+		if err != nil {
+			return fmt.Errorf("capture: %w", err)
+		}
+		capture = &built
+	}
+	if config.Offices != nil {
+		if err := validateRemoteOffices(config.Offices); err != nil {
+			return fmt.Errorf("offices: %w", err)
+		}
+		checkOfficeTimezones(config.Offices)
+	}
+	if err := flags.Validate(config.Flags); err != nil {
+		return fmt.Errorf("flags: %w", err)
+	}
+
+	if capture != nil {
+		w.captureConfig.Store(capture)
+	}
+	if config.Offices != nil {
+		w.locationConfig.ReplaceOffices(config.Offices)
+	}
+	if config.Flags != nil {
+		w.flags.SetRemote(config.Flags)
+	}
+	w.mu.Lock()
+	w.remoteConfigRaw = data
+	w.remoteConfigVersion = config.Version
+	w.mu.Unlock()
+
+	logger.Info("Remote config applied", "version", config.Version,
+		"offices", len(config.Offices), "capture", capture != nil, "flags", len(config.Flags))
+	return nil
+}
+
+// build turns the remote tuning into a capture config, rejecting values
+// that would break calls
+func (r RemoteCaptureConfig) build() (CaptureConfig, error) {
+	config := DefaultCaptureConfig()
+	if r.CaptureTimeoutSeconds < 0 || r.PLITimeoutSeconds < 0 || r.CaptureIntervalMs < 0 ||
+		r.MaxAttempts < 0 || r.SampleBufferMax < 0 || r.TypingIntervalSeconds < 0 {
+		return config, errors.New("negative value")
+	}
+	if r.CaptureTimeoutSeconds > 0 {
+		config.CaptureTimeout = time.Duration(r.CaptureTimeoutSeconds) * time.Second
+	}
+	if r.PLITimeoutSeconds > 0 {
+		config.PLITimeout = time.Duration(r.PLITimeoutSeconds) * time.Second
+	}
+	if r.CaptureIntervalMs > 0 {
+		config.CaptureInterval = time.Duration(r.CaptureIntervalMs) * time.Millisecond
+	}
+	if r.MaxAttempts > 0 {
+		config.MaxAttempts = r.MaxAttempts
+	}
+	if r.SampleBufferMax > 0 {
+		if r.SampleBufferMax > 1<<15 {
+			return config, fmt.Errorf("sample_buffer_max %d too large", r.SampleBufferMax)
+		}
+		config.SampleBufferMax = uint16(r.SampleBufferMax)
+	}
+	if r.TypingIntervalSeconds > 0 {
+		config.TypingInterval = time.Duration(r.TypingIntervalSeconds) * time.Second
+	}
+
+	if config.PLITimeout >= config.CaptureTimeout {
+		return config, fmt.Errorf("pli timeout %s must be shorter than the capture timeout %s",
+			config.PLITimeout, config.CaptureTimeout)
+	}
+	if config.CaptureInterval >= config.CaptureTimeout {
+		return config, fmt.Errorf("capture interval %s must be shorter than the capture timeout %s",
+			config.CaptureInterval, config.CaptureTimeout)
+	}
+	return config, nil
+}
+
+// validateRemoteOffices rejects a list with duplicate IDs, bad coordinates
+// or no enabled office
+func validateRemoteOffices(offices []Office) error {
+	seen := make(map[string]bool, len(offices))
+	enabled := 0
+	for _, office := range offices {
+		id := strings.ToUpper(office.ID)
+		switch {
+		case id == "":
+			return errors.New("office without an ID")
+		case seen[id]:
+			return fmt.Errorf("duplicate office %s", office.ID)
+		case !validCoordinates(office.Latitude, office.Longitude):
+			return fmt.Errorf("office %s has invalid coordinates", office.ID)
+		case office.RadiusMeters <= 0:
+			return fmt.Errorf("office %s has no radius", office.ID)
+		}
+		seen[id] = true
+		if office.Enabled {
+			enabled++
+		}
+	}
+	if enabled == 0 {
+		return errors.New("no enabled office")
+	}
+	return nil
+}
+
+// capture returns the capture tuning in effect
+func (w *WebRTCManager) capture() CaptureConfig {
+	return *w.captureConfig.Load()
+}
+
+// RemoteConfigVersion returns the version of the applied remote config
+// ("" = none)
+func (w *WebRTCManager) RemoteConfigVersion() string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.remoteConfigVersion
+}
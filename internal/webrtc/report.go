@@ -0,0 +1,228 @@
+package webrtc
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// DAILY REPORT - Check-in summary posted to a channel
+// ============================================================
+
+// DefaultReportTime is when the daily summary is posted if none is configured
+const DefaultReportTime = "18:00"
+
+// ReportConfig selects where and when the daily summary is posted
+type ReportConfig struct {
+	ClanID    int64
+	ChannelID int64
+	At        string // "HH:MM" in the default office timezone
+}
+
+// DailySummary aggregates one day of check-in events
+type DailySummary struct {
+	Date        time.Time
+	CheckedIn   int            // Distinct users approved
+	CheckedOut  int            // Distinct users checked out
+	Late        int            // Distinct users approved late
+	Failures    map[string]int // Failed check-ins by reason
+	AvgAttempts float64        // Mean recognition attempts of recognized calls
+	ByOffice    map[string]int // Distinct approved users per office ID ("" = WFH or no office)
+}
+
+// SummarizeEvents builds the summary of the given day's events
+func SummarizeEvents(date time.Time, events []CheckinEvent) DailySummary {
+	summary := DailySummary{
+		Date:     date,
+		Failures: make(map[string]int),
+		ByOffice: make(map[string]int),
+	}
+
+	approved := make(map[int64]bool)
+	checkedOut := make(map[int64]bool)
+	recognized, attempts := 0, 0
+	for _, event := range events {
+		switch event.Outcome {
+		case OutcomeApproved:
+			if approved[event.UserID] {
+				continue
+			}
+			approved[event.UserID] = true
+			summary.ByOffice[event.OfficeID]++
+			if event.Late {
+				summary.Late++
+			}
+		case OutcomeCheckedOut:
+			checkedOut[event.UserID] = true
+		case OutcomeRecognized:
+			recognized++
+			attempts += event.Attempts
+		case OutcomeFailed:
+			summary.Failures[event.Reason]++
+		}
+	}
+
+	summary.CheckedIn = len(approved)
+	summary.CheckedOut = len(checkedOut)
+	if recognized > 0 {
+		summary.AvgAttempts = float64(attempts) / float64(recognized)
+	}
+	return summary
+}
+
+// failureReasonLabels are the report names of failure reasons
+var failureReasonLabels = map[string]string{
+	"timeout":              "Hết thời gian chờ",
+	"pli_timeout":          "Không nhận được video",
+	"max_attempts":         "Không xác định được danh tính",
+	"liveness_failed":      "Không xác minh được người thật",
+	"already_checked_in":   "Đã check-in trước đó",
+	"invalid_location":     "Vị trí không hợp lệ",
+	"confirmation_timeout": "Hết thời gian xác nhận vị trí",
+	"approval_rejected":    "Backend từ chối",
+	"approval_error":       "Lỗi gọi backend",
+	"review_rejected":      "Bị từ chối khi xem xét",
+	"review_expired":       "Hết hạn chờ xem xét",
+	"escalation_rejected":  "Quản lý từ chối",
+	"checkout_rejected":    "Backend từ chối check-out",
+	"checkout_error":       "Lỗi gọi backend khi check-out",
+}
+
+// buildSummaryMessage renders the summary as an embed
+func (w *WebRTCManager) buildSummaryMessage(summary DailySummary) models.ChannelMessageContent {
+	totalFailures := 0
+	var failures []string
+	for _, reason := range sortedKeys(summary.Failures) {
+		label := failureReasonLabels[reason]
+		if label == "" {
+			label = reason
+		}
+		failures = append(failures, fmt.Sprintf("%s: %d", label, summary.Failures[reason]))
+		totalFailures += summary.Failures[reason]
+	}
+	if len(failures) == 0 {
+		failures = append(failures, "Không có")
+	}
+
+	officeNames := make(map[string]string)
+	for _, office := range w.locationConfig.AllOffices() {
+		officeNames[office.ID] = office.Name
+	}
+	var offices []string
+	for _, id := range sortedKeys(summary.ByOffice) {
+		name := officeNames[id]
+		switch {
+		case id == "":
+			name = "WFH / khác"
+		case name == "":
+			name = id
+		}
+		offices = append(offices, fmt.Sprintf("%s: %d", name, summary.ByOffice[id]))
+	}
+	if len(offices) == 0 {
+		offices = append(offices, "Không có")
+	}
+
+	title := fmt.Sprintf("📊 Tổng kết check-in ngày %s", summary.Date.Format("02/01/2006"))
+	fields := []models.EmbedField{
+		{Name: "Check-in thành công", Value: fmt.Sprintf("%d", summary.CheckedIn), Inline: true},
+		{Name: "Đi muộn", Value: fmt.Sprintf("%d", summary.Late), Inline: true},
+		{Name: "Check-out", Value: fmt.Sprintf("%d", summary.CheckedOut), Inline: true},
+		{Name: "Thất bại", Value: fmt.Sprintf("%d", totalFailures), Inline: true},
+		{Name: "Số lần nhận diện trung bình", Value: fmt.Sprintf("%.1f", summary.AvgAttempts), Inline: true},
+		{Name: "Lý do thất bại", Value: strings.Join(failures, "\n")},
+		{Name: "Theo văn phòng", Value: strings.Join(offices, "\n")},
+	}
+	return client.BuildFieldsMessage(title, "", fields)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PostDailySummary posts the summary of the day containing date
+func (w *WebRTCManager) PostDailySummary(cfg ReportConfig, date time.Time) error {
+	w.mu.RLock()
+	store := w.events
+	w.mu.RUnlock()
+	if store == nil {
+		return fmt.Errorf("no check-in event store configured")
+	}
+
+	// The report's day is taken in the default timezone, each event is
+	// counted on its own office's day
+	zone := Office{}.Zone()
+	date = date.In(zone)
+	from := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, zone)
+	events, err := w.eventsOnDays(store, from, from)
+	if err != nil {
+		return fmt.Errorf("failed to load check-in events: %w", err)
+	}
+
+	summary := SummarizeEvents(from, events)
+	logger.Info("Posting daily summary",
+		"date", from.Format("2006-01-02"), "checked_in", summary.CheckedIn, "events", len(events))
+
+	return w.dmManager.SendChannelMessage(cfg.ClanID, cfg.ChannelID, w.buildSummaryMessage(summary))
+}
+
+// StartDailyReport posts the summary every day at cfg.At until shutdown
+func (w *WebRTCManager) StartDailyReport(cfg ReportConfig) error {
+	if cfg.At == "" {
+		cfg.At = DefaultReportTime
+	}
+	at, err := time.Parse("15:04", cfg.At)
+	if err != nil {
+		return fmt.Errorf("invalid report time %q: %w", cfg.At, err)
+	}
+
+	go func() {
+		defer alerting.Recover("daily_report")
+
+		for {
+			next := nextReportTime(time.Now(), at.Hour(), at.Minute())
+			logger.Debug("Next daily summary scheduled", "at", next)
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-w.shutdown:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			if w.Standby() {
+				logger.Debug("Standby, daily summary left to the leader")
+				continue
+			}
+			if err := w.PostDailySummary(cfg, next); err != nil {
+				logger.Error("Failed to post daily summary", "err", err)
+			}
+		}
+	}()
+
+	logger.Info("Daily summary enabled", "at", cfg.At, "timezone", DefaultTimezone, "channel_id", cfg.ChannelID)
+	return nil
+}
+
+// nextReportTime returns the next hour:minute in the default office timezone
+// strictly after now
+func nextReportTime(now time.Time, hour, minute int) time.Time {
+	zone := Office{}.Zone()
+	now = now.In(zone)
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, zone)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,176 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ============================================================
+// ATTENDANCE REQUESTS - WFH days and leave registered by DM
+// ============================================================
+
+const (
+	maxRequestDays      = 31 // Longest range one request may cover
+	maxRequestReasonLen = 500
+)
+
+const wfhUsage = "Cách dùng:\n" +
+	"!wfh <ngày> [đến ngày] [lý do]\n" +
+	"VD: !wfh 20/10, !wfh 20/10 22/10 chăm con ốm"
+
+const leaveUsage = "Cách dùng:\n" +
+	"!leave <ngày> [đến ngày] <lý do>\n" +
+	"VD: !leave 20/10 việc gia đình, !leave 20/10 24/10 nghỉ phép năm"
+
+// Date formats accepted in requests, besides "today" and "tomorrow"
+var requestDateLayouts = []string{"02/01/2006", "2/1/2006", "2006-01-02", "02/01", "2/1"}
+
+func (w *WebRTCManager) handleWFHCommand(userID int64, args []string) models.ChannelMessageContent {
+	from, to, reason, err := parseRequestArgs(args, time.Now().In(w.userZone(userID)))
+	if err != nil {
+		return client.BuildErrorMessage("❌ Yêu cầu WFH không hợp lệ", err.Error()+"\n\n"+wfhUsage)
+	}
+
+	err = w.submitAttendanceRequest(models.APIWFHRequest, userID, from, to, reason)
+	if err != nil {
+		return requestErrorMessage("WFH", err)
+	}
+
+	logger.Info("WFH request registered", "user_id", userID, "from", from.Format("2006-01-02"), "to", to.Format("2006-01-02"))
+	return client.BuildSuccessMessage("🏠 Đã đăng ký làm việc tại nhà", describeRequestRange(from, to, reason))
+}
+
+func (w *WebRTCManager) handleLeaveCommand(userID int64, args []string) models.ChannelMessageContent {
+	from, to, reason, err := parseRequestArgs(args, time.Now().In(w.userZone(userID)))
+	if err == nil && reason == "" {
+		err = errors.New("vui lòng nhập lý do nghỉ")
+	}
+	if err != nil {
+		return client.BuildErrorMessage("❌ Đơn nghỉ phép không hợp lệ", err.Error()+"\n\n"+leaveUsage)
+	}
+
+	err = w.submitAttendanceRequest(models.APILeaveRequest, userID, from, to, reason)
+	if err != nil {
+		return requestErrorMessage("nghỉ phép", err)
+	}
+
+	logger.Info("Leave request registered", "user_id", userID, "from", from.Format("2006-01-02"), "to", to.Format("2006-01-02"))
+	return client.BuildSuccessMessage("🌴 Đã gửi đơn nghỉ phép", describeRequestRange(from, to, reason))
+}
+
+// parseRequestArgs reads "<date> [date] [reason...]". Dates must not be in
+// the past and the range is capped at maxRequestDays.
+func parseRequestArgs(args []string, now time.Time) (time.Time, time.Time, string, error) {
+	if len(args) == 0 {
+		return time.Time{}, time.Time{}, "", errors.New("thiếu ngày")
+	}
+
+	from, err := parseRequestDate(args[0], now)
+	if err != nil {
+		return time.Time{}, time.Time{}, "", err
+	}
+	to, rest := from, args[1:]
+	if len(rest) > 0 {
+		if end, err := parseRequestDate(rest[0], now); err == nil {
+			to, rest = end, rest[1:]
+		}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case from.Before(today):
+		return time.Time{}, time.Time{}, "", fmt.Errorf("ngày %s đã qua", from.Format("02/01/2006"))
+	case to.Before(from):
+		return time.Time{}, time.Time{}, "", errors.New("ngày kết thúc trước ngày bắt đầu")
+	case to.Sub(from) >= maxRequestDays*24*time.Hour:
+		return time.Time{}, time.Time{}, "", fmt.Errorf("mỗi yêu cầu tối đa %d ngày", maxRequestDays)
+	}
+
+	reason := strings.Join(rest, " ")
+	if utf8.RuneCountInString(reason) > maxRequestReasonLen {
+		return time.Time{}, time.Time{}, "", fmt.Errorf("lý do tối đa %d ký tự", maxRequestReasonLen)
+	}
+	return from, to, reason, nil
+}
+
+// parseRequestDate parses a date in now's location. Dates without a year are
+// in the current year, or the next one if that day already passed.
+func parseRequestDate(value string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(value) {
+	case "today", "homnay":
+		return today, nil
+	case "tomorrow", "mai":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	for _, layout := range requestDateLayouts {
+		t, err := time.ParseInLocation(layout, value, now.Location())
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "2006") {
+			t = time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+			if t.Before(today) {
+				t = t.AddDate(1, 0, 0)
+			}
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("ngày không hợp lệ: %s (dùng DD/MM hoặc DD/MM/YYYY)", value)
+}
+
+// submitAttendanceRequest forwards a WFH or leave request to the backend
+func (w *WebRTCManager) submitAttendanceRequest(endpoint string, userID int64, from, to time.Time, reason string) error {
+	reqBody := models.AttendanceRequest{
+		UserId:   userID,
+		FromDate: from.Format("2006-01-02"),
+		ToDate:   to.Format("2006-01-02"),
+		Reason:   reason,
+	}
+
+	body, statusCode, err := w.apiClient.SendRequestContext(context.Background(), reqBody, endpoint)
+	if err != nil {
+		return err
+	}
+
+	w.apiClient.LogResponse(body, statusCode)
+	if apiErr := api.ParseError(body, statusCode); apiErr != nil {
+		return apiErr
+	}
+	return nil
+}
+
+// requestErrorMessage explains a rejected request, passing on the backend's
+// message when it sent one
+func requestErrorMessage(kind string, err error) models.ChannelMessageContent {
+	if errors.Is(err, api.ErrDuplicateRequest) {
+		return client.BuildErrorMessage("⚠️ Yêu cầu đã tồn tại", fmt.Sprintf("Bạn đã đăng ký %s cho những ngày này.", kind))
+	}
+
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < 500 && apiErr.Message != "" {
+		return client.BuildErrorMessage(fmt.Sprintf("❌ Yêu cầu %s bị từ chối", kind), apiErr.Message)
+	}
+
+	logger.Error("Attendance request failed", "kind", kind, "err", err)
+	return client.BuildErrorMessage(fmt.Sprintf("❌ Không thể gửi yêu cầu %s", kind), "Vui lòng thử lại sau.")
+}
+
+func describeRequestRange(from, to time.Time, reason string) string {
+	description := fmt.Sprintf("Ngày: %s", from.Format("02/01/2006"))
+	if !to.Equal(from) {
+		description = fmt.Sprintf("Từ %s đến %s (%d ngày)", from.Format("02/01/2006"), to.Format("02/01/2006"), int(to.Sub(from).Hours()/24)+1)
+	}
+	if reason != "" {
+		description += "\nLý do: " + reason
+	}
+	return description
+}
@@ -0,0 +1,112 @@
+package webrtc
+
+import (
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/mezon-protobuf/go/rtapi"
+	"mezon-checkin-bot/models"
+	"time"
+)
+
+// ============================================================
+// RETRY - "Try again" button on failure messages
+// ============================================================
+
+// RetryButtonID is the component ID of the "try again" button
+const RetryButtonID = "checkin_retry"
+
+// Retry kinds
+const (
+	RetryCall     = "call"     // Ring the user for a new recognition call
+	RetryLocation = "location" // Restart the location confirmation
+)
+
+// retryOfferTTL is how long a "try again" button stays usable
+const retryOfferTTL = 15 * time.Minute
+
+// retryOffer is what the user's latest "try again" button does
+type retryOffer struct {
+	kind      string
+	wfh       bool
+	channelID int64
+	expires   time.Time
+}
+
+// offerRetry remembers what a click on the failure message's button retries,
+// replacing any older offer
+func (w *WebRTCManager) offerRetry(userID, channelID int64, kind string, wfh bool) {
+	w.mu.Lock()
+	w.retries[userID] = retryOffer{
+		kind:      kind,
+		wfh:       wfh,
+		channelID: channelID,
+		expires:   time.Now().Add(retryOfferTTL),
+	}
+	w.mu.Unlock()
+}
+
+// handleRetryClick runs the clicking user's retry offer
+func (w *WebRTCManager) handleRetryClick(clicked *rtapi.MessageButtonClicked) {
+	userID := clicked.GetUserId()
+	w.mu.Lock()
+	offer, exists := w.retries[userID]
+	delete(w.retries, userID)
+	w.mu.Unlock()
+	inCall := w.connections.Has(userID)
+
+	channelID := clicked.GetChannelId()
+	if exists {
+		channelID = offer.channelID
+	}
+
+	switch {
+	case !exists || time.Now().After(offer.expires):
+		w.replyCommand(channelID, userID, client.BuildSimpleTextMessage(
+			w.text(userID, "retry.expired", nil)))
+	case inCall:
+		logger.Debug("Retry ignored, user is already in a call", "user_id", userID)
+	case offer.kind == RetryLocation:
+		w.retryLocation(userID, channelID, offer.wfh)
+	default:
+		w.retryCall(userID, channelID)
+	}
+}
+
+// retryLocation reopens the location confirmation with a fresh timer
+func (w *WebRTCManager) retryLocation(userID, channelID int64, wfh bool) {
+	logger.Info("Retrying location confirmation", "user_id", userID, "wfh", wfh)
+
+	w.startConfirmationTimeout(userID, channelID, wfh)
+	if err := w.SendCheckinNotice(channelID, userID, w.text(userID, "notice.resend_location", nil)); err != nil {
+		logger.Error("Failed to send location retry notice", "user_id", userID, "err", err)
+	}
+}
+
+// retryCall rings the user. Accepting makes their client send an offer,
+// which starts a normal check-in call.
+func (w *WebRTCManager) retryCall(userID, channelID int64) {
+	if w.Draining() {
+		w.offerRetry(userID, channelID, RetryCall, false)
+		if err := w.SendCheckinNotice(channelID, userID, w.text(userID, "notice.draining", nil)); err != nil {
+			logger.Error("Failed to send draining notice", "user_id", userID, "err", err)
+		}
+		return
+	}
+	if w.underMaintenance() {
+		// Keep the button usable once the backend is back
+		w.offerRetry(userID, channelID, RetryCall, false)
+		if err := w.SendCheckinNotice(channelID, userID, w.maintenanceNotice(userID)); err != nil {
+			logger.Error("Failed to send maintenance notice", "user_id", userID, "err", err)
+		}
+		return
+	}
+
+	logger.Info("Calling user back", "user_id", userID)
+	if err := w.client.SendWebRTCSignal(userID, w.client.ClientID, channelID, models.WebrtcSDPInit, "{}"); err != nil {
+		logger.Error("Failed to call user back", "user_id", userID, "err", err)
+		w.replyCommand(channelID, userID, client.BuildSimpleTextMessage(
+			w.text(userID, "retry.call_failed", nil)))
+		return
+	}
+	w.replyCommand(channelID, userID, client.BuildSimpleTextMessage(
+		w.text(userID, "retry.calling", nil)))
+}
@@ -0,0 +1,97 @@
+package webrtc
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/i18n"
+	"mezon-checkin-bot/models"
+	"time"
+)
+
+// ============================================================
+// SHIFTS - Shift and clock status shown in check-in messages
+// ============================================================
+
+// shiftInfo is what the latest recognition told us about the user's day
+type shiftInfo struct {
+	shift      *models.Shift
+	clockEvent *models.LastClockEventDTO
+	name       string // Recognized full name
+	at         time.Time
+}
+
+// rememberShift keeps the shift details of a recognition for the messages
+// sent after it, which may come after the call has ended
+func (w *WebRTCManager) rememberShift(userID int64, response *models.FaceRecognitionResponse) {
+	if response == nil {
+		return
+	}
+
+	w.mu.Lock()
+	w.shifts[userID] = shiftInfo{
+		shift:      response.TodayShift(),
+		clockEvent: response.LastClockEventDTO,
+		name:       response.GetFullName(),
+		at:         time.Now(),
+	}
+	w.mu.Unlock()
+}
+
+// shiftFields renders today's shift and the clock status as embed fields.
+// checkedIn is true once the check-in went through.
+func (w *WebRTCManager) shiftFields(userID int64, checkedIn bool) []models.EmbedField {
+	w.mu.RLock()
+	info, ok := w.shifts[userID]
+	w.mu.RUnlock()
+
+	zone := w.userZone(userID)
+	now := time.Now().In(zone)
+	if !ok || info.at.In(zone).Format("2006-01-02") != now.Format("2006-01-02") {
+		return nil
+	}
+
+	t := w.tr(userID)
+	var fields []models.EmbedField
+	if info.shift != nil {
+		fields = append(fields, models.EmbedField{Name: t("shift.field.shift", nil), Value: formatShift(info.shift, zone), Inline: true})
+	}
+
+	var status string
+	switch {
+	case checkedIn:
+		status = t("shift.checked_in_at", i18n.Vars{"time": now.Format("15:04")})
+	case info.clockEvent != nil && info.clockEvent.StartTime != "" && info.clockEvent.EndTime == nil:
+		status = t("shift.on_shift", nil)
+		if start, ok := parseBackendTime(info.clockEvent.StartTime, zone); ok {
+			status = t("shift.on_shift_since", i18n.Vars{"time": start.Format("15:04")})
+		}
+	default:
+		status = t("shift.not_checked_in", nil)
+	}
+	fields = append(fields, models.EmbedField{Name: t("shift.field.status", nil), Value: status, Inline: true})
+	return fields
+}
+
+// formatShift renders a shift as "Name (08:30 - 17:30)"
+func formatShift(shift *models.Shift, zone *time.Location) string {
+	hours := fmt.Sprintf("%s - %s", shiftClock(shift.StartTime, zone), shiftClock(shift.EndTime, zone))
+	if shift.Name == "" {
+		return hours
+	}
+	return fmt.Sprintf("%s (%s)", shift.Name, hours)
+}
+
+// shiftClock formats a shift time as HH:MM, leaving unknown formats as sent
+func shiftClock(value string, zone *time.Location) string {
+	if value == "" {
+		return "?"
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("15:04")
+		}
+	}
+	if t, ok := parseBackendTime(value, zone); ok {
+		return t.Format("15:04")
+	}
+	return value
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/tracing"
 	"mezon-checkin-bot/internal/utils"
 	"mezon-checkin-bot/mezon-protobuf/go/rtapi"
 	"mezon-checkin-bot/models"
@@ -18,31 +21,47 @@ import (
 // MAIN SIGNAL HANDLER
 // ============================================================
 
-func (w *WebRTCManager) HandleSignal(userID int64, signal *rtapi.WebrtcSignalingFwd) error {
+func (w *WebRTCManager) HandleSignal(userID int64, signal *rtapi.WebrtcSignalingFwd) (err error) {
+	// Last resort: a panic while negotiating must not leave a half-built call
+	defer func() {
+		if r := recover(); r != nil {
+			alerting.Panic("signaling", r, append(w.callAttrs(userID), "signal_type", signal.GetDataType())...)
+			w.cleanupConnection(userID)
+			err = fmt.Errorf("panic while handling signal: %v", r)
+		}
+	}()
+
 	if signal == nil {
 		return fmt.Errorf("signal cannot be nil")
 	}
 
-	log.Println("\n" + strings.Repeat("=", 60))
-	log.Printf("📡 WebRTC Signal (Type: %d)", signal.DataType)
-	log.Printf("   UserID: %d", userID)
-	log.Printf("   CallerID: %d", signal.CallerId)
-	log.Printf("   ChannelID: %d", signal.ChannelId)
-	log.Println(strings.Repeat("=", 60))
+	callLog := logger.With("user_id", userID, "channel_id", signal.ChannelId)
+	if w.leavesSignal(userID) {
+		callLog.Debug("Standby, signal left to the leader", "type", signal.DataType)
+		return nil
+	}
+	if w.client.UserBlocked(userID) {
+		callLog.Warn("Signal from a blocked user ignored", "type", signal.DataType)
+		if signal.DataType == models.WebrtcSDPOffer {
+			w.hangUpUnanswered(userID, signal.ChannelId, callLog)
+		}
+		return nil
+	}
+	callLog.Info("WebRTC signal", "type", signal.DataType, "caller_id", signal.CallerId)
 
 	switch signal.DataType {
 	case models.WebrtcSDPOffer:
-		return w.handleOffer(userID, signal)
+		return w.handleOffer(userID, signal, callLog)
 	case models.WebrtcICECandidate:
 		return w.handleICECandidate(userID, signal)
 	case models.WebrtcSDPStatusRemoteMedia:
 		return nil
 	case models.WebrtcSDPQuit:
-		log.Printf("👋 Call ended by user")
+		callLog.Info("Call ended by user")
 		w.cleanupConnection(userID)
 		return nil
 	default:
-		log.Printf("⚠️  Unknown signal type: %d", signal.DataType)
+		callLog.Warn("Unknown signal type", "type", signal.DataType)
 		return nil
 	}
 }
@@ -51,10 +70,30 @@ func (w *WebRTCManager) HandleSignal(userID int64, signal *rtapi.WebrtcSignaling
 // OFFER HANDLING
 // ============================================================
 
-func (w *WebRTCManager) handleOffer(userID int64, signal *rtapi.WebrtcSignalingFwd) error {
-	log.Println("📝 Processing offer...")
-	log.Printf("   UserID: %d", userID)
-	log.Printf("   ChannelID: %d", signal.ChannelId)
+func (w *WebRTCManager) handleOffer(userID int64, signal *rtapi.WebrtcSignalingFwd, callLog *slog.Logger) error {
+	callID := api.NewCallID()
+	callLog = callLog.With("call_id", callID)
+	callLog.Info("Processing offer")
+
+	if !w.checkCallLimits(userID, signal.ChannelId, callLog) {
+		return nil
+	}
+	if w.Draining() {
+		w.rejectCallDraining(userID, signal.ChannelId, callLog)
+		return nil
+	}
+
+	// Degraded mode: don't make users sit through a capture that can't be
+	// submitted. With audio the call is answered to say so, then hung up.
+	maintenance := w.underMaintenance()
+	if maintenance && !w.audioConfig.Enabled {
+		w.rejectCallMaintenance(userID, signal.ChannelId, callLog)
+		return nil
+	}
+	if maintenance {
+		callLog.Warn("Under maintenance, answering with the notice", "manual", w.Maintenance())
+		w.sendMaintenanceNotice(userID, signal.ChannelId, callLog)
+	}
 
 	// Decompress if needed
 	offerData := signal.JsonData
@@ -81,28 +120,45 @@ func (w *WebRTCManager) handleOffer(userID int64, signal *rtapi.WebrtcSignalingF
 	}
 
 	// Create peer connection
-	pc, err := w.createPeerConnection()
+	pc, err := w.createPeerConnection(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to create peer connection: %w", err)
 	}
 
-	// Setup context
-	ctx, cancel := context.WithCancel(context.Background())
+	// Setup context carrying the check-in trace
+	trace := w.startCheckinTrace(userID, signal.ChannelId, callID)
+	callLog = callLog.With("trace_id", trace.span.TraceID())
+	ctx, cancel := context.WithCancel(trace.ctx)
+
+	_, offerSpan := tracing.Start(ctx, "webrtc.offer_answer")
+	defer offerSpan.End()
+
 	state := &connectionState{
-		pc:         pc,
-		channelID:  signal.ChannelId,
-		audioStop:  make(chan struct{}),
-		cancelFunc: cancel,
-		pendingICE: make([]webrtc.ICECandidateInit, 0, 10),
-		iceReady:   false,
+		pc:          pc,
+		channelID:   signal.ChannelId,
+		startedAt:   time.Now(),
+		stage:       CallStageConnecting,
+		maintenance: maintenance,
+		audioStop:   make(chan struct{}),
+		cancelFunc:  cancel,
+		pendingICE:  make([]webrtc.ICECandidateInit, 0, 10),
+		iceReady:    false,
+		logger:      callLog,
+		trace:       trace,
+		goroutines:  newCallGoroutines(),
+	}
+
+	if maintenance {
+		state.stage = CallStageMaintenance
 	}
 
 	// Register connection
-	w.mu.Lock()
-	w.connections[userID] = state
-	w.mu.Unlock()
+	w.connections.Set(userID, state)
 
-	log.Printf("✅ Connection created for user %d", userID)
+	callLog.Info("Connection created")
+	if maintenance {
+		go w.endCallAfterDelay(userID, "maintenance_timeout", maintenanceCallLimit)
+	}
 
 	// Setup handlers
 	w.setupPeerConnectionHandlers(userID, pc, ctx)
@@ -112,6 +168,7 @@ func (w *WebRTCManager) handleOffer(userID int64, signal *rtapi.WebrtcSignalingF
 		Type: webrtc.SDPTypeOffer,
 		SDP:  sdp,
 	}); err != nil {
+		offerSpan.RecordError(err)
 		w.cleanupConnection(userID)
 		return fmt.Errorf("failed to set remote description: %w", err)
 	}
@@ -119,19 +176,21 @@ func (w *WebRTCManager) handleOffer(userID int64, signal *rtapi.WebrtcSignalingF
 	// Setup audio
 	if w.audioConfig.Enabled {
 		if err := w.setupAudioTrack(userID, pc); err != nil {
-			log.Printf("⚠️  Failed to setup audio: %v", err)
+			callLog.Warn("Failed to setup audio", "err", err)
 		}
 	}
 
 	// Create answer
 	answer, err := pc.CreateAnswer(nil)
 	if err != nil {
+		offerSpan.RecordError(err)
 		w.cleanupConnection(userID)
 		return fmt.Errorf("failed to create answer: %w", err)
 	}
 
 	// Set local description
 	if err := pc.SetLocalDescription(answer); err != nil {
+		offerSpan.RecordError(err)
 		w.cleanupConnection(userID)
 		return fmt.Errorf("failed to set local description: %w", err)
 	}
@@ -158,6 +217,7 @@ func (w *WebRTCManager) handleOffer(userID int64, signal *rtapi.WebrtcSignalingF
 		models.WebrtcSDPAnswer,
 		compressedAnswer,
 	); err != nil {
+		offerSpan.RecordError(err)
 		w.cleanupConnection(userID)
 		return fmt.Errorf("failed to send answer: %w", err)
 	}
@@ -170,18 +230,17 @@ func (w *WebRTCManager) handleOffer(userID int64, signal *rtapi.WebrtcSignalingF
 	state.mu.Unlock()
 
 	if len(pendingCandidates) > 0 {
-		log.Printf("📦 Processing %d pending ICE candidates...", len(pendingCandidates))
+		callLog.Debug("Processing pending ICE candidates", "count", len(pendingCandidates))
 		for i, candidate := range pendingCandidates {
 			if err := pc.AddICECandidate(candidate); err != nil {
-				log.Printf("⚠️  Failed to add pending ICE %d: %v", i+1, err)
+				callLog.Warn("Failed to add pending ICE", "index", i+1, "err", err)
 			} else {
-				log.Printf("✅ Added pending ICE %d/%d", i+1, len(pendingCandidates))
+				callLog.Debug("Added pending ICE", "index", i+1, "total", len(pendingCandidates))
 			}
 		}
 	}
 
-	log.Println("✅ Answer sent!")
-	log.Println(strings.Repeat("=", 60))
+	callLog.Info("Answer sent")
 
 	return nil
 }
@@ -196,12 +255,10 @@ func (w *WebRTCManager) handleICECandidate(userID int64, signal *rtapi.WebrtcSig
 		return fmt.Errorf("invalid candidate: %w", err)
 	}
 
-	w.mu.RLock()
-	state, exists := w.connections[userID]
-	w.mu.RUnlock()
+	state, exists := w.connections.Get(userID)
 
 	if !exists {
-		log.Printf("⚠️  Connection not found for user %d", userID)
+		logger.Warn("Connection not found for ICE candidate", "user_id", userID)
 		return fmt.Errorf("connection not found")
 	}
 
@@ -211,13 +268,13 @@ func (w *WebRTCManager) handleICECandidate(userID int64, signal *rtapi.WebrtcSig
 	// Queue if not ready
 	if !state.iceReady {
 		state.pendingICE = append(state.pendingICE, candidate)
-		log.Printf("📦 Queued ICE (total: %d)", len(state.pendingICE))
+		state.logger.Debug("Queued ICE", "total", len(state.pendingICE))
 		return nil
 	}
 
 	// Add immediately
 	if err := state.pc.AddICECandidate(candidate); err != nil {
-		log.Printf("⚠️  Failed to add ICE: %v", err)
+		state.logger.Warn("Failed to add ICE", "err", err)
 		return err
 	}
 
@@ -226,6 +283,6 @@ func (w *WebRTCManager) handleICECandidate(userID int64, signal *rtapi.WebrtcSig
 	if candidate.SDPMid != nil {
 		sdpMid = *candidate.SDPMid
 	}
-	log.Printf("✅ Added ICE (sdpMid: %s)", sdpMid)
+	state.logger.Debug("Added ICE", "sdp_mid", sdpMid)
 	return nil
 }
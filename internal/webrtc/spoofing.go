@@ -0,0 +1,283 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/utils"
+	"mezon-checkin-bot/models"
+)
+
+// ============================================================
+// GPS SPOOFING HEURISTICS
+// ============================================================
+
+const (
+	maxTravelSpeedMPS      = 250.0 / 3.6 // 250 km/h, faster than any commute
+	officeCenterToleranceM = 0.5         // Real GPS fixes are never exactly on the pin
+	sharedCoordsWindow     = 10 * time.Minute
+	sharedCoordsMinUsers   = 3 // Distinct users sending identical coordinates
+
+	// Held confirmations older than this are dropped: the check-in is for a
+	// day that is over
+	reviewTTL = 12 * time.Hour
+)
+
+// pendingReview is a location confirmation held for manual review, saved
+// across restarts
+type pendingReview struct {
+	UserID    int64             `json:"user_id"`
+	ChannelID int64             `json:"channel_id"`
+	Location  ConfirmedLocation `json:"location"`
+	Place     string            `json:"place,omitempty"`
+	Reasons   []string          `json:"reasons"`
+	NoGPS     bool              `json:"no_gps,omitempty"` // Office claimed with a button, Location has no coordinates
+	HeldAt    time.Time         `json:"held_at"`
+}
+
+// checkSpoofing returns the reasons the coordinates look fabricated (empty if plausible)
+func (w *WebRTCManager) checkSpoofing(userID int64, lat, lon float64, now time.Time) []string {
+	var reasons []string
+
+	// 1. Impossible travel since the last confirmed location
+	if last, ok := w.LastConfirmedLocation(userID); ok {
+		elapsed := now.Sub(last.At).Seconds()
+		distance := calculateDistance(last.Latitude, last.Longitude, lat, lon)
+		if elapsed > 0 && distance/elapsed > maxTravelSpeedMPS {
+			reasons = append(reasons, fmt.Sprintf("di chuyển %.0fkm trong %s", distance/1000, now.Sub(last.At).Round(time.Minute)))
+		}
+	}
+
+	// 2. Exactly on an office pin
+	for _, office := range w.locationConfig.AllOffices() {
+		if calculateDistance(office.Latitude, office.Longitude, lat, lon) < officeCenterToleranceM {
+			reasons = append(reasons, fmt.Sprintf("trùng chính xác tọa độ văn phòng %s", office.ID))
+			break
+		}
+	}
+
+	// 3. Identical coordinates from several users checked in recently
+	users := w.history.UsersAt(lat, lon, now.Add(-sharedCoordsWindow))
+	users[userID] = true
+	if len(users) >= sharedCoordsMinUsers {
+		reasons = append(reasons, fmt.Sprintf("%d người dùng gửi cùng tọa độ", len(users)))
+	}
+
+	return reasons
+}
+
+// holdForReview queues a suspicious confirmation instead of approving it.
+// A review already waiting for the user is kept: it was first. A standby
+// finishing a check-in can't save the review and the leader's admins decide
+// it, so the user calls the leader again.
+func (w *WebRTCManager) holdForReview(review *pendingReview) {
+	if w.Standby() {
+		logger.Warn("Location review left to the leader", "user_id", review.UserID, "reasons", review.Reasons)
+		w.recordEvent(review.UserID, CheckinEvent{Outcome: OutcomeFailed, Reason: "standby_review", OfficeID: review.Location.OfficeID})
+		if err := w.SendCheckinNotice(review.ChannelID, review.UserID, w.text(review.UserID, "notice.draining", nil)); err != nil {
+			logger.Error("Failed to send review notice", "user_id", review.UserID, "err", err)
+		}
+		return
+	}
+	review.HeldAt = time.Now()
+
+	w.locationMu.Lock()
+	w.expireReviewsLocked(review.HeldAt)
+	existing, pending := w.reviews[review.UserID]
+	if !pending {
+		w.reviews[review.UserID] = review
+		w.saveReviewsLocked()
+	}
+	w.locationMu.Unlock()
+
+	if pending {
+		logger.Warn("Location review already pending, new one dropped", "user_id", review.UserID,
+			"held_at", existing.HeldAt, "reasons", review.Reasons)
+	} else {
+		logger.Warn("Location held for review", "user_id", review.UserID, "reasons", review.Reasons)
+	}
+
+	notice := w.text(review.UserID, "notice.review", nil)
+	if err := w.SendCheckinNotice(review.ChannelID, review.UserID, notice); err != nil {
+		logger.Error("Failed to send review notice", "user_id", review.UserID, "err", err)
+	}
+}
+
+// resolveReview approves or rejects a held confirmation
+func (w *WebRTCManager) resolveReview(userID int64, approve bool) error {
+	w.locationMu.Lock()
+	w.expireReviewsLocked(time.Now())
+	review, exists := w.reviews[userID]
+	delete(w.reviews, userID)
+	w.saveReviewsLocked()
+	w.locationMu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("không có yêu cầu xem xét cho user %d", userID)
+	}
+
+	if !approve {
+		logger.Info("Review rejected", "user_id", userID)
+		w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "review_rejected"})
+		return w.SendCheckinFailed(review.ChannelID, userID, w.text(userID, "reason.location_unverified", nil))
+	}
+
+	logger.Info("Review approved", "user_id", userID)
+	if err := w.approveCheckin(userID, review.ChannelID, review.Place); err != nil {
+		return err
+	}
+	w.recordApproval(userID, review.ChannelID, CheckinEvent{OfficeID: review.Location.OfficeID})
+	if !review.NoGPS {
+		w.recordConfirmedLocation(userID, review.Location)
+	}
+	return nil
+}
+
+// RestoreReviews reloads the reviews saved at path and keeps saving there.
+// Reviews older than reviewTTL are dropped. The file replaces the reviews in
+// memory: a standby only keeps the path and reads it again when it takes
+// over, the reviews decided meanwhile were the leader's.
+func (w *WebRTCManager) RestoreReviews(path string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+	w.locationMu.Lock()
+	w.reviewsPath = path
+	w.locationMu.Unlock()
+	if w.Standby() {
+		return 0, nil
+	}
+
+	var reviews []*pendingReview
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return 0, fmt.Errorf("failed to read location reviews: %w", err)
+	default:
+		if err := json.Unmarshal(data, &reviews); err != nil {
+			return 0, fmt.Errorf("failed to parse location reviews: %w", err)
+		}
+	}
+
+	w.locationMu.Lock()
+	defer w.locationMu.Unlock()
+	clear(w.reviews)
+	for _, review := range reviews {
+		w.reviews[review.UserID] = review
+	}
+	w.expireReviewsLocked(time.Now())
+	w.saveReviewsLocked()
+
+	if len(w.reviews) > 0 {
+		logger.Info("Location reviews restored", "count", len(w.reviews))
+	}
+	return len(w.reviews), nil
+}
+
+// expireReviewsLocked drops reviews older than reviewTTL. Caller holds
+// locationMu.
+func (w *WebRTCManager) expireReviewsLocked(now time.Time) {
+	for userID, review := range w.reviews {
+		if now.Sub(review.HeldAt) <= reviewTTL {
+			continue
+		}
+		delete(w.reviews, userID)
+		logger.Info("Location review expired", "user_id", userID, "held_at", review.HeldAt)
+		go w.recordEvent(userID, CheckinEvent{Outcome: OutcomeFailed, Reason: "review_expired", OfficeID: review.Location.OfficeID})
+	}
+}
+
+// saveReviewsLocked writes the reviews to disk. Caller holds locationMu.
+// A standby leaves the file to the leader.
+func (w *WebRTCManager) saveReviewsLocked() {
+	if w.reviewsPath == "" || w.Standby() {
+		return
+	}
+
+	reviews := make([]*pendingReview, 0, len(w.reviews))
+	for _, review := range w.reviews {
+		reviews = append(reviews, review)
+	}
+	data, err := json.Marshal(reviews)
+	if err != nil {
+		logger.Error("Failed to marshal location reviews", "err", err)
+		return
+	}
+	if err := utils.WriteFileAtomic(w.reviewsPath, data, 0600); err != nil {
+		logger.Error("Failed to write location reviews", "err", err)
+	}
+}
+
+// ============================================================
+// REVIEW COMMAND
+// ============================================================
+
+const reviewUsage = "Cách dùng:\n" +
+	"!review list\n" +
+	"!review approve <user id>\n" +
+	"!review reject <user id>"
+
+func (w *WebRTCManager) handleReviewCommand(args []string) models.ChannelMessageContent {
+	if len(args) == 0 {
+		return client.BuildSimpleTextMessage(reviewUsage)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		return client.BuildSimpleTextMessage(w.formatReviews())
+
+	case "approve", "reject":
+		if len(args) < 2 {
+			return client.BuildSimpleTextMessage(reviewUsage)
+		}
+		var userID int64
+		if _, err := fmt.Sscan(args[1], &userID); err != nil {
+			return client.BuildErrorMessage("❌ User ID không hợp lệ", args[1])
+		}
+		approve := strings.ToLower(args[0]) == "approve"
+		if err := w.resolveReview(userID, approve); err != nil {
+			return client.BuildErrorMessage("❌ Xử lý thất bại", err.Error())
+		}
+		return client.BuildSuccessMessage("✅ Đã xử lý", fmt.Sprintf("%s check-in của user %d", args[0], userID))
+
+	default:
+		return client.BuildSimpleTextMessage(reviewUsage)
+	}
+}
+
+func (w *WebRTCManager) formatReviews() string {
+	w.locationMu.Lock()
+	w.expireReviewsLocked(time.Now())
+	reviews := make([]*pendingReview, 0, len(w.reviews))
+	for _, review := range w.reviews {
+		reviews = append(reviews, review)
+	}
+	w.locationMu.Unlock()
+
+	if len(reviews) == 0 {
+		return "Không có check-in nào cần xem xét."
+	}
+
+	sort.Slice(reviews, func(i, j int) bool {
+		return reviews[i].Location.At.Before(reviews[j].Location.At)
+	})
+
+	var b strings.Builder
+	for _, review := range reviews {
+		coords := fmt.Sprintf("(%.6f, %.6f)", review.Location.Latitude, review.Location.Longitude)
+		if review.NoGPS {
+			coords = "(không có GPS)"
+		}
+		fmt.Fprintf(&b, "🚩 %d lúc %s %s: %s\n",
+			review.UserID, w.locationConfig.OfficeTime(review.Location.OfficeID, review.Location.At).Format("15:04"),
+			coords, strings.Join(review.Reasons, "; "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
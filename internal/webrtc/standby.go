@@ -0,0 +1,218 @@
+package webrtc
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/leader"
+	mzapi "mezon-checkin-bot/mezon-protobuf/go/api"
+	"mezon-checkin-bot/mezon-protobuf/go/rtapi"
+	"time"
+)
+
+// ============================================================
+// STANDBY - Two instances share the bot account during upgrades;
+// the standby stays connected but leaves calls and messages to
+// the leader until it takes over. After a handoff the old leader
+// finishes its open calls and answers those users until done;
+// location confirmations already saved move to the new leader.
+// ============================================================
+
+// handoffHold keeps an instance that handed off from taking the lease
+// back while it is being upgraded
+const handoffHold = 10 * time.Minute
+
+// LeaderConfig configures leader election
+type LeaderConfig struct {
+	Lock   leader.Lock
+	Holder string        // This instance's name, e.g. hostname
+	TTL    time.Duration // Lease lifetime, how long a crashed leader blocks the standby (0 = default)
+}
+
+// StartLeaderElection starts standby and answers calls once the lease is
+// won. The lease is released by CloseAll.
+func (w *WebRTCManager) StartLeaderElection(cfg LeaderConfig) error {
+	if cfg.Lock == nil {
+		return fmt.Errorf("leader election needs a lock")
+	}
+	if cfg.Holder == "" {
+		return fmt.Errorf("leader election needs a holder name")
+	}
+
+	w.SetStandby(true)
+	w.elector = leader.NewElector(cfg.Lock, cfg.Holder, cfg.TTL, func(isLeader bool) {
+		w.SetStandby(!isLeader)
+	})
+	w.elector.Start()
+	return nil
+}
+
+// SetStandby stops taking new calls, messages and scheduled posts while
+// another instance leads. Open calls are finished.
+func (w *WebRTCManager) SetStandby(standby bool) {
+	if w.standby.Load() == standby {
+		return
+	}
+	if standby {
+		// While the files are still this instance's, before the lease goes
+		w.handOverState()
+	}
+	if w.standby.Swap(standby) == standby {
+		return
+	}
+	logger.Warn("Standby mode changed", "standby", standby, "active_calls", w.ActiveCalls())
+	if !standby {
+		// Called under the elector's lock, the files are read in the background
+		go w.takeOverState()
+	}
+}
+
+// Standby reports whether another instance is answering calls
+func (w *WebRTCManager) Standby() bool {
+	return w.standby.Load()
+}
+
+// Handoff releases the lease so the standby takes new calls, while the
+// open ones finish here. The lease is not competed for until handoffHold.
+func (w *WebRTCManager) Handoff() error {
+	if w.elector == nil {
+		return fmt.Errorf("leader election is not enabled")
+	}
+	return w.elector.StepDown(handoffHold)
+}
+
+// LeaderHolder is this instance's name in the lease ("" = no election)
+func (w *WebRTCManager) LeaderHolder() string {
+	if w.elector == nil {
+		return ""
+	}
+	return w.elector.Holder()
+}
+
+// stopLeaderElection releases the lease at shutdown, before open calls are
+// closed, so the standby takes over as early as possible
+func (w *WebRTCManager) stopLeaderElection() {
+	if w.elector != nil {
+		w.elector.Stop()
+	}
+}
+
+// onActive subscribes to a client event handled only by the leader; both
+// instances receive every message
+func (w *WebRTCManager) onActive(event string, handler func(data interface{})) {
+	w.client.On(event, func(data interface{}) {
+		if w.Standby() {
+			return
+		}
+		handler(data)
+	})
+}
+
+// onOwned subscribes to a client event handled by the leader, and by a
+// standby for the users whose check-in it still holds
+func (w *WebRTCManager) onOwned(event string, handler func(data interface{})) {
+	w.client.On(event, func(data interface{}) {
+		if w.Standby() && !w.ownsUser(eventUserID(data)) {
+			return
+		}
+		handler(data)
+	})
+}
+
+// ownsUser reports whether the user's check-in is held here: an open call,
+// a location confirmation or a late reason asked for
+func (w *WebRTCManager) ownsUser(userID int64) bool {
+	if userID == 0 {
+		return false
+	}
+	if w.connections.Has(userID) || w.hasPendingConfirmation(userID) {
+		return true
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	_, asked := w.lateReasons[userID]
+	return asked
+}
+
+// eventUserID returns the user who caused a client event (0 = unknown)
+func eventUserID(data interface{}) int64 {
+	switch event := data.(type) {
+	case map[string]interface{}:
+		userID, _ := event["user_id"].(int64)
+		return userID
+	case *rtapi.MessageButtonClicked:
+		return event.GetUserId()
+	case *mzapi.MessageReaction:
+		return event.SenderId
+	}
+	return 0
+}
+
+// ============================================================
+// SHARED STATE - Both instances see the same data directory.
+// Only the leader writes the pending confirmations, journal,
+// reviews and approvals; a standby keeps what its open calls
+// need in memory and reads the files when it takes over.
+// ============================================================
+
+// handOverState leaves the state files to the next leader. The confirmations
+// are dropped here, they are saved and the next leader restores them, and
+// the journal keeps only the check-ins it should recover.
+func (w *WebRTCManager) handOverState() {
+	if dropped := w.dropPendingConfirmations("handed_over"); dropped > 0 {
+		logger.Info("Pending confirmations handed over to the leader", "count", dropped)
+	}
+	w.handOverJournal()
+}
+
+// takeOverState reads the state the previous leader saved. Confirmations
+// come before the journal, which resumes from them.
+func (w *WebRTCManager) takeOverState() {
+	w.confirmationMu.Lock()
+	pendingPath := w.pendingPath
+	w.confirmationMu.Unlock()
+	if pendingPath != "" {
+		if _, err := w.RestorePendingConfirmations(pendingPath); err != nil {
+			logger.Error("Failed to take over pending confirmations", "err", err)
+		}
+	}
+
+	w.locationMu.Lock()
+	reviewsPath := w.reviewsPath
+	w.locationMu.Unlock()
+	if reviewsPath != "" {
+		if _, err := w.RestoreReviews(reviewsPath); err != nil {
+			logger.Error("Failed to take over location reviews", "err", err)
+		}
+	}
+
+	if err := w.reloadApprovals(); err != nil {
+		logger.Error("Failed to take over pending approvals", "err", err)
+	}
+
+	w.journalMu.Lock()
+	journalPath := w.journalPath
+	w.journalMu.Unlock()
+	if journalPath != "" {
+		if _, err := w.RecoverJournal(journalPath); err != nil {
+			logger.Error("Failed to take over check-in journal", "err", err)
+		}
+	}
+
+	// Changed by commands the previous leader answered
+	if w.locationConfig != nil {
+		if err := w.locationConfig.LoadHomeLocations(); err != nil {
+			logger.Error("Failed to reload home locations", "err", err)
+		}
+	}
+	if err := w.reloadAnnounceOptOuts(); err != nil {
+		logger.Error("Failed to reload announcement opt-outs", "err", err)
+	}
+}
+
+// leavesSignal reports whether the signal is for the leader: a standby only
+// keeps negotiating calls it already has
+func (w *WebRTCManager) leavesSignal(userID int64) bool {
+	if !w.Standby() {
+		return false
+	}
+	return !w.connections.Has(userID)
+}
@@ -0,0 +1,118 @@
+package webrtc
+
+import (
+	"context"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/models"
+	"sync"
+)
+
+// ============================================================
+// STATUS MESSAGE - One DM per call, edited as the check-in progresses
+// ============================================================
+
+// statusKinds are the DM kinds shown in the status message. The final ones
+// end it; the next call starts a new one.
+var statusKinds = map[string]bool{
+	"scanning":     false,
+	"confirmation": false,
+	"success":      true,
+	"failed":       true,
+}
+
+// statusMessage is the DM the call's steps are written into
+type statusMessage struct {
+	channelID int64
+	messageID int64
+	mu        sync.Mutex // Serializes the sends and edits of one user
+}
+
+// SetStatusMessages makes each call show its progress (scanning, confirm
+// location, success or failure) by editing a single DM instead of sending one
+// per step. Notices are still sent separately.
+func (w *WebRTCManager) SetStatusMessages(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !enabled {
+		w.statusMessages = nil
+		return
+	}
+	if w.statusMessages == nil {
+		w.statusMessages = make(map[int64]*statusMessage)
+	}
+}
+
+// startStatus starts the status message of a new call, sending "scanning"
+// in the background
+func (w *WebRTCManager) startStatus(userID, channelID int64) {
+	w.mu.Lock()
+	if w.statusMessages == nil || w.dmManager == nil {
+		w.mu.Unlock()
+		return
+	}
+	// A status left over from an earlier call is not edited again
+	delete(w.statusMessages, userID)
+	w.mu.Unlock()
+
+	go func() {
+		content := client.BuildCheckinScanningMessage(w.tr(userID))
+		if err := w.sendDM("scanning", channelID, userID, content); err != nil {
+			w.callLogger(userID).Warn("Failed to send scanning status", "err", err)
+		}
+	}()
+}
+
+// sendStatus writes a step into the user's status message, sending it if
+// there is none yet or the edit fails. Returns false when status messages are
+// off or kind is not a status step.
+func (w *WebRTCManager) sendStatus(ctx context.Context, kind string, channelID int64, userID int64, content models.ChannelMessageContent) (bool, error) {
+	final, isStatus := statusKinds[kind]
+	if !isStatus {
+		return false, nil
+	}
+
+	w.mu.Lock()
+	if w.statusMessages == nil {
+		w.mu.Unlock()
+		return false, nil
+	}
+	status, exists := w.statusMessages[userID]
+	if exists && kind == "scanning" {
+		// A later step got there first
+		w.mu.Unlock()
+		return true, nil
+	}
+	if !exists || status.channelID != channelID {
+		status = &statusMessage{channelID: channelID}
+		w.statusMessages[userID] = status
+	}
+	w.mu.Unlock()
+
+	status.mu.Lock()
+	defer status.mu.Unlock()
+
+	var err error
+	if status.messageID != 0 {
+		if err = w.dmManager.UpdateDM(ctx, channelID, status.messageID, content); err == nil {
+			w.endStatus(userID, status, final)
+			return true, nil
+		}
+		w.callLogger(userID).Warn("Failed to update status message, sending a new one", "kind", kind, "err", err)
+	}
+
+	status.messageID, err = w.dmManager.SendDMWithID(ctx, channelID, userID, content)
+	w.endStatus(userID, status, final || err != nil)
+	return true, err
+}
+
+// endStatus forgets a finished status message, unless a newer call replaced it
+func (w *WebRTCManager) endStatus(userID int64, status *statusMessage, final bool) {
+	if !final {
+		return
+	}
+	w.mu.Lock()
+	if w.statusMessages != nil && w.statusMessages[userID] == status {
+		delete(w.statusMessages, userID)
+	}
+	w.mu.Unlock()
+}
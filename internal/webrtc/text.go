@@ -0,0 +1,103 @@
+package webrtc
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/i18n"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ============================================================
+// MESSAGE TEXTS - User-facing messages in the user's locale
+// ============================================================
+
+// tr returns a translator for the user's locale
+func (w *WebRTCManager) tr(userID int64) i18n.Translator {
+	return w.messages.For(w.locales.Resolve(userID))
+}
+
+// text renders a message in the user's locale
+func (w *WebRTCManager) text(userID int64, key string, vars i18n.Vars) string {
+	return w.messages.Text(w.locales.Resolve(userID), key, vars)
+}
+
+// ============================================================
+// MESSAGE TEMPLATES - Wording and branding from a directory
+// ============================================================
+
+// TemplatesConfig points at the message templates directory:
+//
+//	theme.json             embed colors and footer (client.Theme)
+//	messages/<locale>.json message texts, overriding the built-in ones
+type TemplatesConfig struct {
+	Dir            string
+	ReloadInterval time.Duration // How often to check for changes (0 = no hot reload)
+}
+
+// LoadTemplates loads the templates and, with a reload interval, reloads
+// them whenever a file in the directory changes. A missing directory keeps
+// the built-in texts and theme.
+func (w *WebRTCManager) LoadTemplates(cfg TemplatesConfig) error {
+	if err := w.loadTemplates(cfg.Dir); err != nil {
+		return err
+	}
+	if cfg.ReloadInterval <= 0 {
+		return nil
+	}
+
+	go func() {
+		defer alerting.Recover("template_reload")
+
+		ticker := time.NewTicker(cfg.ReloadInterval)
+		defer ticker.Stop()
+		last := templatesVersion(cfg.Dir)
+		for {
+			select {
+			case <-w.shutdown:
+				return
+			case <-ticker.C:
+			}
+
+			version := templatesVersion(cfg.Dir)
+			if version == last {
+				continue
+			}
+			// Remember the version even on error, the fix comes as another change
+			last = version
+			if err := w.loadTemplates(cfg.Dir); err != nil {
+				logger.Warn("Failed to reload message templates, keeping the current ones", "err", err)
+				continue
+			}
+			logger.Info("Message templates reloaded", "dir", cfg.Dir)
+		}
+	}()
+	return nil
+}
+
+func (w *WebRTCManager) loadTemplates(dir string) error {
+	if err := client.LoadTheme(filepath.Join(dir, "theme.json")); err != nil {
+		return err
+	}
+	if _, err := w.messages.LoadDir(filepath.Join(dir, "messages")); err != nil {
+		return err
+	}
+	return nil
+}
+
+// templatesVersion fingerprints the template files by name, size and
+// modification time
+func templatesVersion(dir string) string {
+	paths, _ := filepath.Glob(filepath.Join(dir, "messages", "*.json"))
+	paths = append(paths, filepath.Join(dir, "theme.json"))
+
+	var version string
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			version += fmt.Sprintf("%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return version
+}
@@ -0,0 +1,138 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// OFFICE TIMEZONES - Time-based logic runs in the office's local zone
+// ============================================================
+
+// DefaultTimezone applies to offices without a Timezone
+const DefaultTimezone = "Asia/Ho_Chi_Minh"
+
+var zoneCache sync.Map // IANA name -> *time.Location
+
+// loadZone resolves an IANA timezone name, caching the result
+func loadZone(name string) (*time.Location, error) {
+	if cached, ok := zoneCache.Load(name); ok {
+		return cached.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q: %w", name, err)
+	}
+	zoneCache.Store(name, loc)
+	return loc, nil
+}
+
+// Zone returns the office's timezone, falling back to DefaultTimezone
+func (o Office) Zone() *time.Location {
+	name := o.Timezone
+	if name == "" {
+		name = DefaultTimezone
+	}
+	if loc, err := loadZone(name); err == nil {
+		return loc
+	}
+	if loc, err := loadZone(DefaultTimezone); err == nil {
+		return loc
+	}
+	return time.Local
+}
+
+// OfficeZone returns the timezone of the office ("" or unknown ID = default)
+func (c *LocationConfig) OfficeZone(officeID string) *time.Location {
+	if officeID != "" {
+		for _, office := range c.AllOffices() {
+			if strings.EqualFold(office.ID, officeID) {
+				return office.Zone()
+			}
+		}
+	}
+	return Office{}.Zone()
+}
+
+// OfficeTime converts t to the office's local time
+func (c *LocationConfig) OfficeTime(officeID string, t time.Time) time.Time {
+	return t.In(c.OfficeZone(officeID))
+}
+
+// userZone returns the timezone of the user's office: the office of their
+// last check-in, else their only assigned office, else the default
+func (w *WebRTCManager) userZone(userID int64) *time.Location {
+	if w.locationConfig == nil {
+		return Office{}.Zone()
+	}
+	if last, ok := w.LastConfirmedLocation(userID); ok && last.OfficeID != "" {
+		return w.locationConfig.OfficeZone(last.OfficeID)
+	}
+	if assigned := w.locationConfig.AssignedOffices(userID); len(assigned) == 1 {
+		return w.locationConfig.OfficeZone(assigned[0])
+	}
+	return Office{}.Zone()
+}
+
+// eventZone returns the timezone an event happened in: its office's, or the
+// user's office for events without one
+func (w *WebRTCManager) eventZone(event CheckinEvent) *time.Location {
+	if event.OfficeID != "" && w.locationConfig != nil {
+		return w.locationConfig.OfficeZone(event.OfficeID)
+	}
+	return w.userZone(event.UserID)
+}
+
+// maxZoneOffset bounds how far an office's day can be from UTC
+const maxZoneOffset = 14 * time.Hour
+
+// eventsOnDays returns the events whose local date, in their office's
+// timezone, is one of the calendar days from..to (inclusive). Only the
+// year, month and day of from and to are used.
+func (w *WebRTCManager) eventsOnDays(store CheckinEventStore, from, to time.Time) ([]CheckinEvent, error) {
+	first := calendarDay(from)
+	last := calendarDay(to)
+	events, err := store.Between(first.Add(-maxZoneOffset), last.AddDate(0, 0, 1).Add(maxZoneOffset))
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := events[:0]
+	for _, event := range events {
+		day := calendarDay(event.At.In(w.eventZone(event)))
+		if !day.Before(first) && !day.After(last) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered, nil
+}
+
+// calendarDay is t's date as midnight UTC, for comparing dates across zones
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// SetOfficeTimezone changes an office's timezone and saves it
+func (c *LocationConfig) SetOfficeTimezone(id, timezone string, actor int64) error {
+	if _, err := loadZone(timezone); err != nil {
+		return err
+	}
+	return c.updateOffice(id, actor, "timezone "+timezone, func(office *Office) {
+		office.Timezone = timezone
+	})
+}
+
+// checkOfficeTimezones warns about offices with an unknown timezone; they
+// fall back to DefaultTimezone
+func checkOfficeTimezones(offices []Office) {
+	for _, office := range offices {
+		if office.Timezone == "" {
+			continue
+		}
+		if _, err := loadZone(office.Timezone); err != nil {
+			logger.Warn("Invalid office timezone", "office", office.ID, "err", err, "fallback", DefaultTimezone)
+		}
+	}
+}
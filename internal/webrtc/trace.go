@@ -0,0 +1,104 @@
+package webrtc
+
+import (
+	"context"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/tracing"
+)
+
+// ============================================================
+// CHECK-IN TRACES
+// ============================================================
+
+// checkinTrace is the root span of one check-in. The call and the pending
+// location confirmation each hold a reference, so the span covers everything
+// from the offer to the location reply and ends when both are done. Its
+// context also carries the call ID sent with backend requests.
+type checkinTrace struct {
+	ctx    context.Context
+	span   *tracing.Span
+	callID string
+	refs   int // Guarded by WebRTCManager.traceMu
+}
+
+// startCheckinTrace starts the root span of a new call and registers it as
+// the user's current trace. The caller owns the first reference.
+func (w *WebRTCManager) startCheckinTrace(userID, channelID int64, callID string) *checkinTrace {
+	ctx, span := tracing.Start(api.WithCallID(context.Background(), callID), "checkin",
+		"user_id", userID, "channel_id", channelID, "call_id", callID)
+	trace := &checkinTrace{ctx: ctx, span: span, callID: callID, refs: 1}
+
+	w.traceMu.Lock()
+	w.traces[userID] = trace
+	w.traceMu.Unlock()
+
+	return trace
+}
+
+// retainTrace takes a reference on the user's current trace (nil if none)
+func (w *WebRTCManager) retainTrace(userID int64) *checkinTrace {
+	w.traceMu.Lock()
+	defer w.traceMu.Unlock()
+
+	trace := w.traces[userID]
+	if trace != nil {
+		trace.refs++
+	}
+	return trace
+}
+
+// releaseTrace drops a reference and ends the root span with the last one
+func (w *WebRTCManager) releaseTrace(userID int64, trace *checkinTrace) {
+	if trace == nil {
+		return
+	}
+
+	// Under traceMu so retainTrace can't revive a trace that is ending
+	w.traceMu.Lock()
+	trace.refs--
+	last := trace.refs == 0
+	if last && w.traces[userID] == trace {
+		delete(w.traces, userID)
+	}
+	w.traceMu.Unlock()
+
+	if last {
+		trace.span.End()
+	}
+}
+
+// traceContext returns the context of the user's current trace, so stages
+// outside the media pipeline (DMs, location reply) join the call's trace
+func (w *WebRTCManager) traceContext(userID int64) context.Context {
+	w.traceMu.Lock()
+	defer w.traceMu.Unlock()
+
+	if trace := w.traces[userID]; trace != nil {
+		return trace.ctx
+	}
+	return context.Background()
+}
+
+// callAttrs returns the user's call metadata as slog-style key/value pairs,
+// for crash reports
+func (w *WebRTCManager) callAttrs(userID int64) []any {
+	w.traceMu.Lock()
+	defer w.traceMu.Unlock()
+
+	attrs := []any{"user_id", userID}
+	if trace := w.traces[userID]; trace != nil {
+		attrs = append(attrs, "call_id", trace.callID, "trace_id", trace.span.TraceID())
+	}
+	return attrs
+}
+
+// callID returns the ID of the user's current call ("" if none)
+func (w *WebRTCManager) callID(userID int64) string {
+	w.traceMu.Lock()
+	defer w.traceMu.Unlock()
+
+	if trace := w.traces[userID]; trace != nil {
+		return trace.callID
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package webrtc
+
+import (
+	"context"
+	"time"
+)
+
+// ============================================================
+// TRANSIENT MESSAGES - Interim DMs deleted once the check-in is done
+// ============================================================
+
+// transientKinds are the DM kinds only useful while a check-in is going on
+var transientKinds = map[string]bool{
+	"scanning":     true,
+	"confirmation": true,
+	"notice":       true,
+}
+
+// finalKinds end a check-in; the interim DMs before them are deleted
+var finalKinds = map[string]bool{
+	"success":          true,
+	"failed":           true,
+	"queued":           true,
+	"checkout_success": true,
+}
+
+type transientMessage struct {
+	channelID int64
+	messageID int64
+}
+
+// SetTransientMessageTTL deletes the confirmation prompts, notices and
+// progress DMs of a check-in ttl after its final DM, so only the result is
+// left in the conversation. Zero keeps them.
+func (w *WebRTCManager) SetTransientMessageTTL(ttl time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.transientTTL = ttl
+	if ttl <= 0 {
+		w.transient = nil
+		return
+	}
+	if w.transient == nil {
+		w.transient = make(map[int64][]transientMessage)
+	}
+}
+
+// trackTransient remembers an interim DM until the check-in ends
+func (w *WebRTCManager) trackTransient(kind string, channelID, userID, messageID int64) {
+	if !transientKinds[kind] || messageID == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.transient != nil {
+		w.transient[userID] = append(w.transient[userID], transientMessage{channelID: channelID, messageID: messageID})
+	}
+}
+
+// expireTransient schedules deletion of the user's interim DMs. DMs sent
+// later belong to the next check-in and are not affected.
+func (w *WebRTCManager) expireTransient(userID int64) {
+	w.mu.Lock()
+	messages := w.transient[userID]
+	delete(w.transient, userID)
+	ttl := w.transientTTL
+	w.mu.Unlock()
+
+	if len(messages) == 0 {
+		return
+	}
+
+	time.AfterFunc(ttl, func() {
+		select {
+		case <-w.shutdown:
+			return
+		default:
+		}
+		for _, msg := range messages {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := w.dmManager.DeleteDM(ctx, msg.channelID, msg.messageID); err != nil {
+				logger.Warn("Failed to delete transient DM", "user_id", userID, "message_id", msg.messageID, "err", err)
+			}
+			cancel()
+		}
+	})
+}
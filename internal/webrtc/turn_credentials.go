@@ -0,0 +1,168 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// ============================================================
+// TURN CREDENTIALS - Time-limited credentials from a REST
+// endpoint (coturn REST API convention) instead of a static
+// password that breaks calls once rotated server-side
+// ============================================================
+
+const (
+	defaultTURNTimeout = 3 * time.Second
+	defaultTURNService = "turn"
+	defaultTURNUser    = "mezon-checkin-bot"
+)
+
+// stunServers are always offered
+var stunServers = []webrtc.ICEServer{
+	{URLs: []string{"stun:stun.l.google.com:19302"}},
+	{URLs: []string{"stun:stun1.l.google.com:19302"}},
+}
+
+// staticTURNServer is used without a credentials endpoint, or when it fails
+// and no fetched credentials are still valid
+var staticTURNServer = webrtc.ICEServer{
+	URLs:       []string{"turn:relay.mezon.vn:5349"},
+	Username:   "turnmezon",
+	Credential: "QuTs4zUEcbylWemXL7MK",
+}
+
+// TURNConfig fetches TURN credentials with
+// GET <URL>?service=turn&username=<Username>&key=<APIKey>
+type TURNConfig struct {
+	URL      string // "" = the static TURN server
+	APIKey   string
+	Username string        // User part of the generated username (default mezon-checkin-bot)
+	Timeout  time.Duration // Per fetch, it delays answering the call (default 3s)
+}
+
+// turnResponse is the coturn REST API reply
+type turnResponse struct {
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	TTL      int      `json:"ttl"` // Seconds
+	URIs     []string `json:"uris"`
+}
+
+// turnCredentials caches the last fetched credentials. They are refetched
+// once half their lifetime is gone, so a call never starts with credentials
+// about to expire.
+type turnCredentials struct {
+	cfg     TURNConfig
+	client  *http.Client
+	mu      sync.Mutex
+	server  webrtc.ICEServer
+	fetched time.Time
+	expires time.Time
+}
+
+// SetTURNCredentials fetches TURN credentials before each peer connection.
+// The first fetch is done now to report a wrong endpoint or key at startup.
+func (w *WebRTCManager) SetTURNCredentials(cfg TURNConfig) {
+	if cfg.URL == "" {
+		return
+	}
+	if cfg.Username == "" {
+		cfg.Username = defaultTURNUser
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTURNTimeout
+	}
+
+	turn := &turnCredentials{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+	if _, err := turn.iceServer(context.Background()); err != nil {
+		logger.Warn("Failed to fetch TURN credentials, calls use the static TURN server until it works", "err", err)
+	}
+
+	w.mu.Lock()
+	w.turn = turn
+	w.mu.Unlock()
+	logger.Info("TURN credentials fetched per call", "url", cfg.URL)
+}
+
+// iceServers returns the servers of a new peer connection
+func (w *WebRTCManager) iceServers(ctx context.Context) []webrtc.ICEServer {
+	w.mu.RLock()
+	turn := w.turn
+	w.mu.RUnlock()
+
+	servers := append([]webrtc.ICEServer(nil), stunServers...)
+	if turn == nil {
+		return append(servers, staticTURNServer)
+	}
+	server, err := turn.iceServer(ctx)
+	if err != nil {
+		logger.Warn("Failed to fetch TURN credentials, using the static TURN server", "err", err)
+		return append(servers, staticTURNServer)
+	}
+	return append(servers, server)
+}
+
+// iceServer returns the cached credentials, refetching them past half their
+// lifetime. Credentials that failed to refresh are used until they expire.
+func (t *turnCredentials) iceServer(ctx context.Context) (webrtc.ICEServer, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(t.fetched.Add(t.expires.Sub(t.fetched) / 2)) {
+		return t.server, nil
+	}
+
+	response, err := t.fetch(ctx)
+	if err != nil {
+		if now.Before(t.expires) {
+			logger.Warn("Failed to refresh TURN credentials, using the current ones", "expires", t.expires, "err", err)
+			return t.server, nil
+		}
+		return webrtc.ICEServer{}, err
+	}
+
+	t.server = webrtc.ICEServer{URLs: response.URIs, Username: response.Username, Credential: response.Password}
+	t.fetched = now
+	t.expires = now.Add(time.Duration(response.TTL) * time.Second)
+	logger.Debug("TURN credentials fetched", "username", response.Username, "ttl", response.TTL, "uris", response.URIs)
+	return t.server, nil
+}
+
+func (t *turnCredentials) fetch(ctx context.Context) (*turnResponse, error) {
+	query := url.Values{}
+	query.Set("service", defaultTURNService)
+	query.Set("username", t.cfg.Username)
+	if t.cfg.APIKey != "" {
+		query.Set("key", t.cfg.APIKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.cfg.URL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TURN credentials request: %w", err)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("TURN credentials request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TURN credentials request failed: HTTP %d", resp.StatusCode)
+	}
+
+	var response turnResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse TURN credentials: %w", err)
+	}
+	if response.Username == "" || response.Password == "" || len(response.URIs) == 0 || response.TTL <= 0 {
+		return nil, fmt.Errorf("TURN credentials response is missing username, password, uris or ttl")
+	}
+	return &response, nil
+}
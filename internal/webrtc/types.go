@@ -2,11 +2,21 @@ package webrtc
 
 import (
 	"context"
+	"log/slog"
 	"mezon-checkin-bot/internal/api"
 	"mezon-checkin-bot/internal/audio"
+	"mezon-checkin-bot/internal/audit"
 	"mezon-checkin-bot/internal/client"
 	"mezon-checkin-bot/internal/detector"
+	"mezon-checkin-bot/internal/flags"
+	"mezon-checkin-bot/internal/geocode"
+	"mezon-checkin-bot/internal/i18n"
+	"mezon-checkin-bot/internal/leader"
+	"mezon-checkin-bot/internal/matpool"
+	"mezon-checkin-bot/internal/tracing"
+	mzapi "mezon-checkin-bot/mezon-protobuf/go/api"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/webrtc/v4"
@@ -17,22 +27,124 @@ import (
 // ============================================================
 
 type WebRTCManager struct {
-	connections          map[int64]*connectionState
+	connections          *connectionRegistry // Guarded by its own shard locks, not mu
 	mu                   sync.RWMutex
 	client               *client.MezonClient
 	faceDetector         *detector.FaceDetector
+	detector             detector.Detector // faceDetector, as used by the capture pipeline
 	audioConfig          audio.AudioConfig
 	audioLibrary         *audio.AudioLibrary
+	tts                  *audio.TTSEngine
+	stt                  audio.SpeechRecognizer
+	locales              *audio.LocaleResolver
+	messages             *i18n.Catalog // User-facing texts, per locale
 	bufferPool           *bufferPool
-	captureConfig        CaptureConfig
+	captureConfig        atomic.Pointer[CaptureConfig] // Swapped whole by remote config, read with capture()
 	dimensionConfig      DimensionConfig
 	dmManager            *client.DMManager
 	pendingConfirmations map[int64]*confirmationState
 	confirmationMu       sync.RWMutex
+	pendingPath          string                 // Where pendingConfirmations are saved ("" = memory only)
+	journalEntries       map[int64]journalEntry // User ID -> unsettled check-in, nil = no journal
+	journalPath          string
+	journalMu            sync.Mutex
 	locationConfig       *LocationConfig
 	shutdown             chan struct{}
 	shutdownOnce         sync.Once
 	apiClient            *api.APIClient
+	geocoder             geocode.Geocoder // Set once by NewWebRTCManager, nil = no addresses
+	confirmedLocations   map[int64]ConfirmedLocation
+	reviews              map[int64]*pendingReview
+	reviewsPath          string // Where reviews are saved ("" = memory only)
+	locationMu           sync.RWMutex
+	history              *LocationHistory
+	health               *api.HealthChecker
+	maintenance          atomic.Bool     // Set by admins, turns calls away like an unhealthy backend
+	maintenanceMessage   string          // Replaces the maintenance DM while set
+	draining             atomic.Bool     // Set by !drain, new calls are hung up while open ones finish
+	standby              atomic.Bool     // Another instance leads, only open calls are served
+	elector              *leader.Elector // Set once by StartLeaderElection, nil = always leader
+	sampleDrops          sampleDrops     // Totals of ended calls
+	queue                *api.SubmissionQueue
+	events               CheckinEventStore
+	admins               map[int64]bool
+	traces               map[int64]*checkinTrace // User ID -> current check-in trace
+	traceMu              sync.Mutex
+	attendance           AttendanceConfig
+	checkoutRequests     map[int64]time.Time // User ID -> when !checkout was sent
+	breaks               map[int64]time.Time // User ID -> start of the current break
+	shifts               map[int64]shiftInfo // User ID -> shift from the latest recognition
+	lateReasons          map[int64]*lateReasonRequest
+	retries              map[int64]retryOffer // User ID -> "try again" button on the last failure
+	escalation           EscalationConfig
+	approvals            map[int64]*pendingApproval // User ID -> check-in awaiting manager approval
+	overtimeOffers       map[int64]*overtimeOffer
+	photoConfig          PhotoCheckinConfig
+	photoSessions        map[int64]*photoSession // User ID -> selfies received so far
+	qrConfig             QRCheckinConfig
+	archive              *ImageArchive // Nil = crops are not kept
+	auditLog             *audit.Log
+	exportConfig         ExportConfig
+	profiles             map[int64]EmployeeProfile // User ID -> cached backend profile
+	remoteConfigRaw      []byte                    // Last applied remote config, to skip unchanged ones
+	remoteConfigVersion  string
+	flags                *flags.Set // Risky features per office or share of calls
+	announcements        AnnouncementConfig
+	announceOptOuts      map[int64]bool               // Users whose check-ins are not announced
+	statusMessages       map[int64]*statusMessage     // User ID -> the call's status DM (nil = one DM per step)
+	transient            map[int64][]transientMessage // User ID -> interim DMs of the check-in (nil = kept)
+	transientTTL         time.Duration
+	replyTargets         map[int64]*mzapi.ChannelMessage // User ID -> location message being answered
+	images               imageHost                       // Images referenced by embeds
+	qrUses               map[string]time.Time            // "user:code" -> code expiry, each code works once per user
+	callLimits           *callLimiter                    // Nil = users may call without limit
+	outputDir            string                          // Debug frames, unless DebugFramesConfig.Dir is set
+	debugFrames          DebugFramesConfig
+	turn                 *turnCredentials // Nil = the static TURN server
+}
+
+// ============================================================
+// ATTENDANCE
+// ============================================================
+
+// AttendanceConfig controls which attendance actions a call can perform
+type AttendanceConfig struct {
+	// Calls check out instead of in when the user asked with !checkout, the
+	// backend reports an open clock event from today, or it is CheckoutAfter
+	CheckoutEnabled bool
+	CheckoutAfter   string // "HH:MM" in the user's office timezone (empty = never by time)
+
+	BreaksEnabled bool // !break start / !break end
+
+	// Check-ins after the shift start, or WorkStart for users without shift
+	// data, plus LateGrace are late and the user is asked for a reason
+	WorkStart string // "HH:MM" in the default office timezone (empty = shift data only)
+	LateGrace time.Duration
+
+	// Default working hours for offices without their own. Check-ins and
+	// check-outs outside them offer an overtime registration.
+	WorkEnd         string // "HH:MM"
+	OvertimeCutoff  string // "HH:MM", empty = WorkEnd
+	OvertimeEnabled bool
+}
+
+// ============================================================
+// PHOTO CHECK-IN
+// ============================================================
+
+// PhotoCheckinConfig enables check-in by DMing selfies, for users whose
+// network blocks WebRTC. A single photo can't show a blink or head turn, and
+// gallery photos can show either, so after a frontal selfie the user is
+// challenged with a random pose (head turned or eyes closed) that must be
+// answered within ChallengeWindow.
+type PhotoCheckinConfig struct {
+	Enabled         bool
+	RequiredPhotos  int           // Selfies needed before recognition, the first frontal one included (default 2)
+	Window          time.Duration // All selfies must arrive within this (default 2m)
+	ChallengeWindow time.Duration // Each challenged selfie must arrive within this (default 30s)
+	MinProbability  float64       // Minimum recognition probability, stricter than calls (0 = backend decision)
+	MaxBytes        int64         // Largest accepted image (default 5 MB)
+	AllowedHosts    []string      // Attachment URL host suffixes (default Mezon CDN)
 }
 
 // ============================================================
@@ -45,11 +157,22 @@ type connectionState struct {
 	audioPlayer *audio.AudioPlayer
 	audioStop   chan struct{}
 	cancelFunc  context.CancelFunc
+	startedAt   time.Time
+	stage       string // CallStage*, guarded by mu
+	maintenance bool   // Answered only to play the maintenance notice, set before registration
 	cleanupOnce sync.Once
 	endCallOnce sync.Once
 	mu          sync.Mutex
 	pendingICE  []webrtc.ICECandidateInit
 	iceReady    bool
+
+	multiFacePrompted bool
+	voiceConfirmed    bool // The spoken confirmation was acknowledged
+	promptedStages    map[string]bool
+
+	logger     *slog.Logger    // Per-call logger carrying user_id, channel_id and trace_id
+	trace      *checkinTrace   // Reference released on cleanup
+	goroutines *callGoroutines // Checked to have exited after cleanup
 }
 
 // ============================================================
@@ -60,9 +183,16 @@ type confirmationState struct {
 	userID     int64
 	channelID  int64
 	timer      *time.Timer
+	deadline   time.Time // When the timer fires, saved across restarts
 	cancelOnce sync.Once
 	confirmed  bool
+	consented  bool   // Reacted ✅ to the confirmation, the deadline was extended once
+	wfh        bool   // Validate against the registered home location
+	qrOffice   string // QR check-ins must be confirmed at this office
 	mu         sync.Mutex
+
+	trace *checkinTrace // Keeps the check-in trace open until the reply
+	span  *tracing.Span // Time spent waiting for the location reply
 }
 
 // ============================================================
@@ -75,6 +205,28 @@ type captureState struct {
 	successCount          int
 	rtpCount              int
 	firstKeyframeReceived bool
+	liveness              *detector.LivenessTracker
+	batch                 *detector.CropBatch
+	lastErr               error     // Error of the last recognition request
+	retryAt               time.Time // Backend asked to wait until then
+	bestCrop              []byte    // Best face crop (JPEG), sent along with escalations
+	bestScore             float64
+	matchedCrop           []byte            // Crop the backend recognized, shown back to the user
+	detector              detector.Detector // This call's detector, nil = w.detector
+	persistentFFmpeg      bool
+	decoder               *persistentDecoder // Started on the first keyframe when persistentFFmpeg
+	mats                  *matpool.Scope     // Frames, squares... of the call, reported if never returned
+	drops                 sampleDrops        // Written by the RTP reader
+	logger                *slog.Logger
+}
+
+// keepBest remembers the crop if it scores higher than the best so far
+func (cs *captureState) keepBest(jpeg []byte, score float64) {
+	if cs.bestCrop != nil && score <= cs.bestScore {
+		return
+	}
+	cs.bestCrop = append(cs.bestCrop[:0], jpeg...)
+	cs.bestScore = score
 }
 
 // ============================================================
@@ -84,8 +236,46 @@ type captureState struct {
 type LocationConfig struct {
 	Enabled         bool
 	OfficesFilePath string
-	offices         []Office
-	mu              sync.RWMutex
+
+	// Optional reverse geocoding of confirmed locations
+	ReverseGeocodeEnabled bool
+	ReverseGeocodeURL     string // Nominatim compatible endpoint (empty = public server)
+
+	// Replies with a worse reported GPS accuracy are rejected with a retry
+	// prompt (0 = defaultMaxAccuracyMeters)
+	MaxAccuracyMeters float64
+
+	// Every confirmed location is appended here (JSON lines)
+	HistoryFilePath string
+
+	// Admin channel receiving location anomaly alerts (0 = log only)
+	AlertClanID    int64
+	AlertChannelID int64
+
+	// Home locations of WFH users. The first WFH check-in proposes one, which
+	// is only used once an admin approves it (or set with !home set).
+	HomeLocationsFilePath string
+	HomeRadiusMeters      float64 // 0 = defaultHomeRadiusMeters
+
+	// Offer "I'm at office X" buttons on the confirmation DM. A claim has no
+	// GPS fix, so it is held for manual review instead of approved.
+	OfficeButtonsEnabled bool
+
+	// Per-user office assignments (users not listed may check in at any office)
+	AssignmentsFilePath string
+	AssignmentsFromAPI  bool // Load from the backend, falling back to the file
+
+	// Versioned office storage. Nil = OfficesFilePath is read and written
+	// directly; otherwise the file is imported once and kept for import/export.
+	OfficeStore OfficeStore
+
+	offices           []Office           // Enabled offices used for validation
+	allOffices        []Office           // Every office in the file, including disabled ones
+	assignments       map[int64][]string // User ID -> allowed office IDs
+	assignmentsLoaded bool               // False after the backend and its saved copy both failed
+	profileOffices    map[int64][]string // From employee profiles, takes precedence over assignments
+	homes             map[int64]HomeLocation
+	mu                sync.RWMutex
 }
 
 type Office struct {
@@ -95,18 +285,56 @@ type Office struct {
 	Longitude    float64 `json:"longitude"`
 	RadiusMeters float64 `json:"radius_meters"`
 	Enabled      bool    `json:"enabled"`
+	Timezone     string  `json:"timezone,omitempty"` // IANA name, empty = DefaultTimezone
+
+	// Working hours, "HH:MM" in the office timezone (empty = AttendanceConfig)
+	WorkStart      string `json:"work_start,omitempty"`
+	WorkEnd        string `json:"work_end,omitempty"`
+	OvertimeCutoff string `json:"overtime_cutoff,omitempty"` // Check-outs after this are overtime (empty = WorkEnd)
+
+	QRChannelID int64 `json:"qr_channel_id,omitempty"` // Where the check-in QR code is posted (0 = none)
 }
 
 type OfficeList struct {
 	Offices []Office `json:"offices"`
 }
 
+// HomeLocation is where a WFH user registered to work from
+type HomeLocation struct {
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	RadiusMeters float64   `json:"radius_meters"`
+	Address      string    `json:"address,omitempty"`
+	RegisteredAt time.Time `json:"registered_at"`
+	Pending      bool      `json:"pending,omitempty"` // Proposed by the user, not approved by an admin yet
+}
+
+type HomeLocationList struct {
+	Users map[string]HomeLocation `json:"users"`
+}
+
+// OfficeAssignmentList maps user IDs to the office IDs they may check in at
+type OfficeAssignmentList struct {
+	Users map[string][]string `json:"users"`
+}
+
 type LocationMatch struct {
 	Office   Office
 	Distance float64
+	Accuracy float64 // Reported GPS accuracy in meters (0 = unknown)
 	IsValid  bool
 }
 
+// ConfirmedLocation is the last location a user checked in from
+type ConfirmedLocation struct {
+	Latitude  float64
+	Longitude float64
+	Address   string
+	OfficeID  string
+	Office    string
+	At        time.Time
+}
+
 // ============================================================
 // DIMENSION & CAPTURE CONFIG
 // ============================================================
@@ -127,6 +355,7 @@ type CaptureConfig struct {
 	CaptureInterval time.Duration
 	MaxAttempts     int
 	SampleBufferMax uint16
+	TypingInterval  time.Duration // Resend the DM typing indicator while scanning (0 = off)
 }
 
 // ============================================================
@@ -0,0 +1,42 @@
+package webrtc
+
+import (
+	"mezon-checkin-bot/internal/alerting"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// TYPING INDICATOR - Shows the bot is working on the check-in
+// ============================================================
+
+// startTyping shows the bot typing in the user's DM until stop is called
+func (w *WebRTCManager) startTyping(userID, channelID int64) (stop func()) {
+	interval := w.capture().TypingInterval
+	if interval <= 0 || w.dmManager == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer alerting.Recover("typing_indicator")
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := w.dmManager.SendTyping(channelID); err != nil {
+				w.callLogger(userID).Debug("Failed to send typing indicator", "err", err)
+			}
+			select {
+			case <-done:
+				return
+			case <-w.shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -3,12 +3,12 @@ package webrtc
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"image"
-	"image/jpeg"
-	"log"
+	"mezon-checkin-bot/internal/matpool"
 	"os/exec"
+	"sync"
 	"time"
 
 	"gocv.io/x/gocv"
@@ -106,59 +106,81 @@ func (w *WebRTCManager) getOptimalDecodeSize(origWidth, origHeight int) (int, in
 }
 
 // ============================================================
-// IVF DATA CREATION
+// IVF DATA CREATION - Frames are wrapped in IVF for ffmpeg. The
+// file header only depends on the size, so it is built once per
+// size; frames are written into pooled buffers.
 // ============================================================
 
-func (w *WebRTCManager) createIVFData(frameData []byte, width, height int) []byte {
-	// IVF File Header (32 bytes)
-	ivfHeader := make([]byte, 32)
-
-	copy(ivfHeader[0:4], []byte{'D', 'K', 'I', 'F'})
-	ivfHeader[4] = 0
-	ivfHeader[5] = 0
-	ivfHeader[6] = 32
-	ivfHeader[7] = 0
-	copy(ivfHeader[8:12], []byte{'V', 'P', '8', '0'})
-	ivfHeader[12] = byte(width & 0xff)
-	ivfHeader[13] = byte((width >> 8) & 0xff)
-	ivfHeader[14] = byte(height & 0xff)
-	ivfHeader[15] = byte((height >> 8) & 0xff)
-	ivfHeader[16] = 30
-	ivfHeader[17] = 0
-	ivfHeader[18] = 0
-	ivfHeader[19] = 0
-	ivfHeader[20] = 1
-	ivfHeader[21] = 0
-	ivfHeader[22] = 0
-	ivfHeader[23] = 0
-	ivfHeader[24] = 1
-	ivfHeader[25] = 0
-	ivfHeader[26] = 0
-	ivfHeader[27] = 0
-
-	// IVF Frame Header (12 bytes)
-	frameSize := uint32(len(frameData))
-	frameHeader := make([]byte, 12)
-
-	frameHeader[0] = byte(frameSize & 0xff)
-	frameHeader[1] = byte((frameSize >> 8) & 0xff)
-	frameHeader[2] = byte((frameSize >> 16) & 0xff)
-	frameHeader[3] = byte((frameSize >> 24) & 0xff)
-
-	totalSize := 32 + 12 + len(frameData)
-	result := make([]byte, 0, totalSize)
-	result = append(result, ivfHeader...)
-	result = append(result, frameHeader...)
-	result = append(result, frameData...)
-
-	return result
+const (
+	ivfFileHeaderSize  = 32
+	ivfFrameHeaderSize = 12
+	ivfMaxCachedSizes  = 64 // Calls use a handful of sizes; more are built per frame
+)
+
+type ivfSize struct{ width, height int }
+
+var (
+	ivfHeadersMu sync.RWMutex
+	ivfHeaders   = make(map[ivfSize][]byte)
+)
+
+// ivfFileHeader returns the file header for the size. It is shared and must
+// not be modified.
+func ivfFileHeader(width, height int) []byte {
+	size := ivfSize{width, height}
+	ivfHeadersMu.RLock()
+	header, ok := ivfHeaders[size]
+	ivfHeadersMu.RUnlock()
+	if ok {
+		return header
+	}
+
+	header = make([]byte, ivfFileHeaderSize)
+	copy(header[0:4], "DKIF")
+	binary.LittleEndian.PutUint16(header[4:6], 0)                 // Version
+	binary.LittleEndian.PutUint16(header[6:8], ivfFileHeaderSize) // Header size
+	copy(header[8:12], "VP80")
+	binary.LittleEndian.PutUint16(header[12:14], uint16(width))
+	binary.LittleEndian.PutUint16(header[14:16], uint16(height))
+	binary.LittleEndian.PutUint32(header[16:20], 30) // Frame rate
+	binary.LittleEndian.PutUint32(header[20:24], 1)  // Time scale
+	binary.LittleEndian.PutUint32(header[24:28], 1)  // Frame count
+
+	ivfHeadersMu.Lock()
+	defer ivfHeadersMu.Unlock()
+	if cached, ok := ivfHeaders[size]; ok {
+		return cached
+	}
+	if len(ivfHeaders) < ivfMaxCachedSizes {
+		ivfHeaders[size] = header
+	}
+	return header
+}
+
+// putIVFFrameHeader fills a frame header for a frame of frameSize bytes
+func putIVFFrameHeader(header *[ivfFrameHeaderSize]byte, frameSize int, pts uint64) {
+	binary.LittleEndian.PutUint32(header[0:4], uint32(frameSize))
+	binary.LittleEndian.PutUint64(header[4:12], pts)
+}
+
+// writeIVF writes a one-frame IVF file into buf
+func writeIVF(buf *bytes.Buffer, frameData []byte, width, height int) {
+	var frameHeader [ivfFrameHeaderSize]byte
+	putIVFFrameHeader(&frameHeader, len(frameData), 0)
+
+	buf.Grow(ivfFileHeaderSize + ivfFrameHeaderSize + len(frameData))
+	buf.Write(ivfFileHeader(width, height))
+	buf.Write(frameHeader[:])
+	buf.Write(frameData)
 }
 
 // ============================================================
 // VP8 TO GOCV MAT
 // ============================================================
 
-func (w *WebRTCManager) vp8FrameToGoCV(frameData []byte) (*gocv.Mat, error) {
+// vp8FrameToGoCV decodes a keyframe into a Mat from mats, to be put back
+// there (nil = a new Mat, to be closed)
+func (w *WebRTCManager) vp8FrameToGoCV(frameData []byte, mats *matpool.Scope) (*gocv.Mat, error) {
 	origWidth, origHeight, err := getVP8KeyframeDims(frameData)
 	if err != nil {
 		return nil, fmt.Errorf("parse dims: %w", err)
@@ -169,7 +191,11 @@ func (w *WebRTCManager) vp8FrameToGoCV(frameData []byte) (*gocv.Mat, error) {
 	}
 
 	decodeWidth, decodeHeight := w.getOptimalDecodeSize(origWidth, origHeight)
-	ivfData := w.createIVFData(frameData, origWidth, origHeight)
+
+	// Returned to the pool once the writer below is done with it
+	ivfBuf := w.bufferPool.Get()
+	defer w.bufferPool.Put(ivfBuf)
+	writeIVF(ivfBuf, frameData, origWidth, origHeight)
 
 	// Build ffmpeg args
 	args := []string{
@@ -225,7 +251,7 @@ func (w *WebRTCManager) vp8FrameToGoCV(frameData []byte) (*gocv.Mat, error) {
 	writeErr := make(chan error, 1)
 	go func() {
 		defer stdin.Close()
-		if _, err := stdin.Write(ivfData); err != nil {
+		if _, err := stdin.Write(ivfBuf.Bytes()); err != nil {
 			writeErr <- fmt.Errorf("write: %w", err)
 			return
 		}
@@ -252,38 +278,77 @@ func (w *WebRTCManager) vp8FrameToGoCV(frameData []byte) (*gocv.Mat, error) {
 		return nil, fmt.Errorf("short frame: %d < %d", buf.Len(), expectedSize)
 	}
 
-	// Copy data for buffer reuse
-	frameBytes := make([]byte, expectedSize)
-	copy(frameBytes, buf.Bytes()[:expectedSize])
+	// Copied straight into the Mat, the buffer goes back to its pool
+	mat := mats.GetSized(decodeHeight, decodeWidth, gocv.MatTypeCV8UC3)
+	if err := copyIntoMat(&mat, buf.Bytes()[:expectedSize]); err != nil {
+		mats.Put(mat)
+		return nil, err
+	}
+	return &mat, nil
+}
 
-	mat, err := gocv.NewMatFromBytes(
-		decodeHeight,
-		decodeWidth,
-		gocv.MatTypeCV8UC3,
-		frameBytes,
-	)
+// copyIntoMat fills a continuous Mat of the frame's size with its pixels
+func copyIntoMat(mat *gocv.Mat, frameBytes []byte) error {
+	if mat.Empty() {
+		return fmt.Errorf("empty mat")
+	}
+	pixels, err := mat.DataPtrUint8()
 	if err != nil {
-		return nil, fmt.Errorf("NewMatFromBytes: %w", err)
+		return fmt.Errorf("mat data: %w", err)
 	}
+	if len(pixels) != len(frameBytes) {
+		return fmt.Errorf("mat size %d, frame size %d", len(pixels), len(frameBytes))
+	}
+	copy(pixels, frameBytes)
+	return nil
+}
 
-	if mat.Empty() {
-		mat.Close()
-		return nil, fmt.Errorf("empty mat")
+// DecodeVP8Frame decodes a VP8 keyframe, raw or as the first frame of an IVF
+// file, the way calls decode them. Used by the decode-frame command.
+func DecodeVP8Frame(data []byte) (gocv.Mat, error) {
+	frame, err := firstVP8Frame(data)
+	if err != nil {
+		return gocv.Mat{}, err
 	}
 
-	return &mat, nil
+	w := &WebRTCManager{dimensionConfig: DefaultDimensionConfig(), bufferPool: newBufferPool()}
+	mat, err := w.vp8FrameToGoCV(frame, nil)
+	if err != nil {
+		return gocv.Mat{}, err
+	}
+	return *mat, nil
+}
+
+// firstVP8Frame returns the first frame of an IVF file, or data itself when
+// it is a raw frame
+func firstVP8Frame(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte("DKIF")) {
+		return data, nil
+	}
+	if len(data) < ivfFileHeaderSize+ivfFrameHeaderSize {
+		return nil, fmt.Errorf("truncated IVF file")
+	}
+	size := int(binary.LittleEndian.Uint32(data[ivfFileHeaderSize : ivfFileHeaderSize+4]))
+	data = data[ivfFileHeaderSize+ivfFrameHeaderSize:]
+	if size > len(data) {
+		return nil, fmt.Errorf("truncated IVF frame: %d < %d bytes", len(data), size)
+	}
+	return data[:size], nil
 }
 
 // ============================================================
 // IMAGE PROCESSING
 // ============================================================
 
-func (w *WebRTCManager) makeSquare(mat gocv.Mat) gocv.Mat {
+// makeSquare pads the crop to a square on black, into a Mat from mats
+func (w *WebRTCManager) makeSquare(mat gocv.Mat, mats *matpool.Scope) gocv.Mat {
 	cropWidth := mat.Cols()
 	cropHeight := mat.Rows()
 
 	if cropWidth == cropHeight {
-		return mat.Clone()
+		square := mats.Get()
+		mat.CopyTo(&square)
+		return square
 	}
 
 	maxSize := cropWidth
@@ -291,7 +356,8 @@ func (w *WebRTCManager) makeSquare(mat gocv.Mat) gocv.Mat {
 		maxSize = cropHeight
 	}
 
-	finalSquare := gocv.NewMatWithSize(maxSize, maxSize, mat.Type())
+	finalSquare := mats.GetSized(maxSize, maxSize, mat.Type())
+	finalSquare.SetTo(gocv.NewScalar(0, 0, 0, 0)) // Pooled pixels are undefined
 	offsetX := (maxSize - cropWidth) / 2
 	offsetY := (maxSize - cropHeight) / 2
 
@@ -375,27 +441,53 @@ func (w *WebRTCManager) expandAndCenterFace(face image.Rectangle, imgWidth, imgH
 	return image.Rect(squareX1, squareY1, squareX2, squareY2)
 }
 
-func (w *WebRTCManager) encodeImageToBase64(mat gocv.Mat) (string, error) {
-	imgGo, err := mat.ToImage()
-	if err != nil {
-		buf, err := gocv.IMEncode(gocv.JPEGFileExt, mat)
-		if err != nil {
-			return "", fmt.Errorf("IMEncode failed: %w", err)
-		}
-		defer buf.Close()
-		return base64.StdEncoding.EncodeToString(buf.GetBytes()), nil
+// encodeImageToJPEG writes the JPEG encoding of mat into buf. The caller owns
+// buf (usually taken from the buffer pool) and must not return it to the pool
+// while the bytes are still being uploaded.
+func (w *WebRTCManager) encodeImageToJPEG(mat gocv.Mat, buf *bytes.Buffer) error {
+	if err := EncodeJPEG(mat, w.faceDetector.Config.JPEGQuality, w.faceDetector.Config.JPEGEncoder, buf); err != nil {
+		return err
 	}
 
-	buf := w.bufferPool.Get()
-	defer w.bufferPool.Put(buf)
+	logger.Debug("Encoded image", "size_kb", float64(buf.Len())/1024.0,
+		"quality", w.faceDetector.Config.JPEGQuality)
 
-	err = jpeg.Encode(buf, imgGo, &jpeg.Options{Quality: w.faceDetector.Config.JPEGQuality})
-	if err != nil {
-		return "", fmt.Errorf("jpeg encode failed: %w", err)
+	return nil
+}
+
+// ============================================================
+// FACE THUMBNAIL
+// ============================================================
+
+// faceThumbnailSize is the width of the face shown in DMs
+const faceThumbnailSize = 160
+
+// faceThumbnailURL shrinks a face crop to a hosted JPEG. Returns "" if the
+// crop can't be decoded or image hosting is disabled.
+func (w *WebRTCManager) faceThumbnailURL(crop []byte) string {
+	if len(crop) == 0 {
+		return ""
 	}
 
-	log.Printf("   📦 Image size: %.1fKB (quality: %d)",
-		float64(buf.Len())/1024.0, w.faceDetector.Config.JPEGQuality)
+	face, err := gocv.IMDecode(crop, gocv.IMReadColor)
+	if err != nil || face.Empty() {
+		face.Close()
+		return ""
+	}
+	defer face.Close()
 
-	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+	thumb := face
+	if face.Cols() > faceThumbnailSize {
+		thumb = gocv.NewMat()
+		defer thumb.Close()
+		size := image.Pt(faceThumbnailSize, faceThumbnailSize*face.Rows()/face.Cols())
+		gocv.Resize(face, &thumb, size, 0, 0, gocv.InterpolationArea)
+	}
+
+	buf := w.bufferPool.Get()
+	defer w.bufferPool.Put(buf)
+	if err := w.encodeImageToJPEG(thumb, buf); err != nil {
+		return ""
+	}
+	return w.hostImage(bytes.Clone(buf.Bytes()), "image/jpeg", 0)
 }
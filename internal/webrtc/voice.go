@@ -0,0 +1,183 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/audio"
+	"strings"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// ============================================================
+// VOICE CONFIRMATION - STT on the caller's audio track
+// ============================================================
+
+const (
+	voiceFrameBytes      = audio.STTSampleRate / 50 * 2 // 20ms mono s16le
+	voiceEnergyThreshold = 500.0                        // RMS above which a frame counts as speech
+	voiceSilenceFrames   = 30                           // 600ms of silence ends an utterance
+	voiceMaxFrames       = 250                          // 5s max utterance
+	voiceMinFrames       = 10                           // ignore clicks shorter than 200ms
+
+	defaultVoiceConfirmWindow = 15 * time.Second
+)
+
+var defaultVoiceConfirmPhrases = []string{"đồng ý", "xác nhận", "dong y"}
+
+// listenForVoiceConfirmation decodes the caller's audio and acknowledges the
+// pending check-in when a confirmation phrase is recognized. Runs until the
+// track ends (peer connection closed).
+func (w *WebRTCManager) listenForVoiceConfirmation(userID int64, track *webrtc.TrackRemote) {
+	defer alerting.Recover("voice", w.callAttrs(userID)...)
+
+	decoder, err := audio.NewOpusDecoder()
+	if err != nil {
+		logger.Warn("Voice confirmation unavailable", "user_id", userID, "err", err)
+		return
+	}
+	defer decoder.Close()
+
+	go w.segmentUtterances(userID, decoder.PCM())
+
+	logger.Info("Listening for voice confirmation", "user_id", userID)
+
+	for {
+		packet, _, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+		if err := decoder.WriteRTP(packet); err != nil {
+			logger.Warn("Voice decoder write failed", "user_id", userID, "err", err)
+			return
+		}
+	}
+}
+
+// segmentUtterances splits PCM into utterances with a simple energy VAD and
+// transcribes them while a confirmation is pending
+func (w *WebRTCManager) segmentUtterances(userID int64, pcm io.Reader) {
+	defer alerting.Recover("voice", w.callAttrs(userID)...)
+
+	frame := make([]byte, voiceFrameBytes)
+	var utterance []byte
+	speechFrames, silentFrames := 0, 0
+
+	for {
+		if _, err := io.ReadFull(pcm, frame); err != nil {
+			return
+		}
+
+		speaking := frameRMS(frame) > voiceEnergyThreshold
+		if speaking {
+			speechFrames++
+			silentFrames = 0
+		} else if speechFrames > 0 {
+			silentFrames++
+		}
+
+		if speechFrames == 0 {
+			continue
+		}
+		utterance = append(utterance, frame...)
+
+		frames := len(utterance) / voiceFrameBytes
+		if silentFrames < voiceSilenceFrames && frames < voiceMaxFrames {
+			continue
+		}
+
+		if speechFrames >= voiceMinFrames && w.hasPendingConfirmation(userID) {
+			w.handleUtterance(userID, utterance)
+		}
+		utterance = nil
+		speechFrames, silentFrames = 0, 0
+	}
+}
+
+func (w *WebRTCManager) handleUtterance(userID int64, pcm []byte) {
+	text, err := w.stt.Transcribe(pcm)
+	if err != nil {
+		logger.Warn("STT failed", "user_id", userID, "err", err)
+		return
+	}
+	if text == "" {
+		return
+	}
+
+	logger.Debug("Voice transcript", "user_id", userID, "text", text)
+
+	if !w.isConfirmPhrase(text) {
+		return
+	}
+
+	state, exists := w.connections.Get(userID)
+	if !exists {
+		return
+	}
+
+	state.mu.Lock()
+	acknowledged := state.voiceConfirmed
+	state.voiceConfirmed = true
+	state.mu.Unlock()
+	if acknowledged {
+		return
+	}
+
+	if err := w.HandleVoiceConfirmation(userID, state.channelID); err != nil {
+		logger.Error("Voice confirmation failed", "user_id", userID, "err", err)
+	}
+}
+
+// HandleVoiceConfirmation acknowledges a spoken confirmation phrase. It is a
+// shortcut to the location step only: the check-in still waits for a
+// location reply, validated like any other.
+func (w *WebRTCManager) HandleVoiceConfirmation(userID int64, channelID int64) error {
+	if !w.hasPendingConfirmation(userID) {
+		return nil
+	}
+
+	logger.Info("Voice confirmation received", "user_id", userID)
+
+	notice := w.text(userID, "notice.voice_confirmed", nil)
+	if w.pendingIsWFH(userID) {
+		notice = w.text(userID, "notice.wfh_confirm", nil)
+	}
+	return w.SendCheckinNotice(channelID, userID, notice)
+}
+
+func (w *WebRTCManager) isConfirmPhrase(text string) bool {
+	phrases := w.audioConfig.VoiceConfirmPhrases
+	if len(phrases) == 0 {
+		phrases = defaultVoiceConfirmPhrases
+	}
+
+	text = strings.ToLower(text)
+	for _, phrase := range phrases {
+		if strings.Contains(text, strings.ToLower(phrase)) {
+			return true
+		}
+	}
+	return false
+}
+
+// voiceConfirmWindow is how long the call stays open after recognition so the
+// user can confirm verbally
+func (w *WebRTCManager) voiceConfirmWindow() time.Duration {
+	if w.audioConfig.VoiceConfirmWindow > 0 {
+		return w.audioConfig.VoiceConfirmWindow
+	}
+	return defaultVoiceConfirmWindow
+}
+
+func frameRMS(frame []byte) float64 {
+	var sum float64
+	samples := len(frame) / 2
+	for i := 0; i < samples; i++ {
+		s := float64(int16(binary.LittleEndian.Uint16(frame[i*2:])))
+		sum += s * s
+	}
+	return math.Sqrt(sum / float64(samples))
+}
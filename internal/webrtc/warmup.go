@@ -0,0 +1,97 @@
+package webrtc
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// ============================================================
+// WARMUP - Runs what the first call runs at startup, so a broken
+// install fails with the reason instead of on the first call
+// ============================================================
+
+// DefaultWarmupFrame is the test keyframe the Docker image builds
+const DefaultWarmupFrame = "assets/warmup.ivf"
+
+// warmupFrameSize is what detection runs on when the frame cannot be decoded
+const warmupFrameSize = 320
+
+// WarmupCheck is one step of the startup self-test
+type WarmupCheck struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// Warmup decodes the test frame through ffmpeg, runs face detection on it,
+// which also loads the models, and opens every audio file
+func (w *WebRTCManager) Warmup(framePath string) []WarmupCheck {
+	if framePath == "" {
+		framePath = DefaultWarmupFrame
+	}
+	var checks []WarmupCheck
+	run := func(name string, fn func() error) {
+		start := time.Now()
+		err := fn()
+		checks = append(checks, WarmupCheck{Name: name, Err: err, Duration: time.Since(start)})
+	}
+
+	var frame *gocv.Mat
+	run("decode_frame", func() error {
+		data, err := os.ReadFile(framePath)
+		if err != nil {
+			return fmt.Errorf("test frame: %w", err)
+		}
+		vp8, err := firstVP8Frame(data)
+		if err != nil {
+			return fmt.Errorf("test frame %s: %w", framePath, err)
+		}
+		frame, err = w.vp8FrameToGoCV(vp8, nil)
+		return err
+	})
+	if frame != nil {
+		defer frame.Close()
+	}
+
+	if w.faceDetector.Config.Enabled {
+		run("face_detector", func() error {
+			if err := w.faceDetector.CheckModels(); err != nil {
+				return err
+			}
+			img := frame
+			if img == nil {
+				blank := gocv.NewMatWithSize(warmupFrameSize, warmupFrameSize, gocv.MatTypeCV8UC3)
+				defer blank.Close()
+				img = &blank
+			}
+			w.detector.Detect(*img)
+			return nil
+		})
+	}
+
+	if w.audioConfig.Enabled {
+		names := w.audioLibrary.List()
+		sort.Strings(names)
+		for _, name := range names {
+			run("audio_"+name, func() error {
+				return w.audioLibrary.Check(name)
+			})
+		}
+		// Configured but not registered, Register already logged why
+		for name, path := range map[string]string{
+			"welcome":          w.audioConfig.WelcomeAudioPath,
+			"checkin_success":  w.audioConfig.CheckinSuccessPath,
+			"checkin_fail":     w.audioConfig.CheckinFailPath,
+			"checkout_success": w.audioConfig.CheckoutSuccessPath,
+		} {
+			if _, exists := w.audioLibrary.Get(name); !exists && path != "" {
+				checks = append(checks, WarmupCheck{Name: "audio_" + name, Err: fmt.Errorf("%s not loaded", path)})
+			}
+		}
+	}
+	return checks
+}
@@ -0,0 +1,327 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/utils"
+	"mezon-checkin-bot/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// WFH HOME LOCATIONS - Registered once, validated on every WFH check-in
+// ============================================================
+
+// defaultHomeRadiusMeters is wider than an office radius: homes are not
+// surveyed and GPS indoors drifts more
+const defaultHomeRadiusMeters = 200.0
+
+const homeUsage = "Cách dùng:\n" +
+	"!home list\n" +
+	"!home approve <user id>\n" +
+	"!home set <user id> <lat> <lon> [bán kính m]\n" +
+	"!home reset <user id>"
+
+// LoadHomeLocations loads registered home locations. A missing file is not an
+// error: users propose one on their first WFH check-in.
+func (c *LocationConfig) LoadHomeLocations() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.homes = make(map[int64]HomeLocation)
+	if c.HomeLocationsFilePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.HomeLocationsFilePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read home locations file: %w", err)
+	}
+
+	var list HomeLocationList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("failed to parse home locations JSON: %w", err)
+	}
+
+	for key, home := range list.Users {
+		userID, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid user ID %q in home locations: %w", key, err)
+		}
+		c.homes[userID] = home
+	}
+
+	logger.Info("Loaded WFH home locations", "count", len(c.homes))
+	return nil
+}
+
+// HomeLocation returns the user's registered home location
+func (c *LocationConfig) HomeLocation(userID int64) (HomeLocation, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	home, exists := c.homes[userID]
+	return home, exists
+}
+
+// HomeLocations returns every registered home location
+func (c *LocationConfig) HomeLocations() map[int64]HomeLocation {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	homes := make(map[int64]HomeLocation, len(c.homes))
+	for userID, home := range c.homes {
+		homes[userID] = home
+	}
+	return homes
+}
+
+// RegisterHomeLocation stores the user's home location and saves the file
+func (c *LocationConfig) RegisterHomeLocation(userID int64, home HomeLocation) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.homes == nil {
+		c.homes = make(map[int64]HomeLocation)
+	}
+	c.homes[userID] = home
+	return c.saveHomeLocations()
+}
+
+// ApproveHomeLocation makes a proposed home location usable
+func (c *LocationConfig) ApproveHomeLocation(userID int64) (HomeLocation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	home, exists := c.homes[userID]
+	if !exists {
+		return HomeLocation{}, fmt.Errorf("user %d has no home location", userID)
+	}
+	if !home.Pending {
+		return home, fmt.Errorf("home location of user %d is already approved", userID)
+	}
+	home.Pending = false
+	c.homes[userID] = home
+	return home, c.saveHomeLocations()
+}
+
+// RemoveHomeLocation deletes the registration so the user registers again
+func (c *LocationConfig) RemoveHomeLocation(userID int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.homes[userID]; !exists {
+		return fmt.Errorf("user %d has no home location", userID)
+	}
+	delete(c.homes, userID)
+	return c.saveHomeLocations()
+}
+
+// saveHomeLocations writes the file. Caller holds c.mu.
+func (c *LocationConfig) saveHomeLocations() error {
+	if c.HomeLocationsFilePath == "" {
+		return nil
+	}
+
+	list := HomeLocationList{Users: make(map[string]HomeLocation, len(c.homes))}
+	for userID, home := range c.homes {
+		list.Users[strconv.FormatInt(userID, 10)] = home
+	}
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal home locations: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.HomeLocationsFilePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Home addresses are personal data
+	if err := utils.WriteFileAtomic(c.HomeLocationsFilePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write home locations file: %w", err)
+	}
+	return nil
+}
+
+func (c *LocationConfig) homeRadius() float64 {
+	if c.HomeRadiusMeters > 0 {
+		return c.HomeRadiusMeters
+	}
+	return defaultHomeRadiusMeters
+}
+
+// ============================================================
+// VALIDATE WFH LOCATION
+// ============================================================
+
+// validateHomeLocation checks a WFH location reply against the approved home
+// location. The first reply proposes it to the admins; pending is true while
+// it waits for approval and the check-in is refused.
+func (w *WebRTCManager) validateHomeLocation(userID int64, lat, lon, accuracy float64) (valid, pending bool) {
+	if !validCoordinates(lat, lon) {
+		return false, false
+	}
+
+	home, registered := w.locationConfig.HomeLocation(userID)
+	if !registered {
+		home = HomeLocation{
+			Latitude:     lat,
+			Longitude:    lon,
+			RadiusMeters: w.locationConfig.homeRadius(),
+			Address:      w.reverseGeocode(w.traceContext(userID), lat, lon),
+			RegisteredAt: time.Now(),
+			Pending:      true,
+		}
+		if err := w.locationConfig.RegisterHomeLocation(userID, home); err != nil {
+			logger.Error("Failed to register home location", "user_id", userID, "err", err)
+			return false, false
+		}
+
+		logger.Info("Home location proposed", "user_id", userID, "lat", lat, "lon", lon)
+		go w.sendHomeApprovalRequest(userID, home)
+		return false, true
+	}
+	if home.Pending {
+		logger.Info("WFH check-in refused, home location not approved", "user_id", userID)
+		return false, true
+	}
+
+	distance := calculateDistance(home.Latitude, home.Longitude, lat, lon)
+	valid = distance <= home.RadiusMeters
+
+	logger.Info("WFH location validation",
+		"user_id", userID,
+		"lat", lat, "lon", lon,
+		"accuracy_m", accuracy,
+		"distance_m", distance,
+		"radius_m", home.RadiusMeters,
+		"valid", valid)
+	return valid, false
+}
+
+// sendHomeApprovalRequest asks the admin channel to approve a proposed home
+func (w *WebRTCManager) sendHomeApprovalRequest(userID int64, home HomeLocation) {
+	description := fmt.Sprintf("User %s đề xuất vị trí làm việc tại nhà (%.6f, %.6f)",
+		w.userLabel(userID), home.Latitude, home.Longitude)
+	if home.Address != "" {
+		description += "\nĐịa chỉ: " + home.Address
+	}
+	description += fmt.Sprintf("\nDuyệt bằng: !home approve %d", userID)
+
+	if err := w.sendLocationAlert("🏠 Vị trí WFH chờ duyệt", description); err != nil {
+		logger.Error("Failed to send home approval request", "user_id", userID, "err", err)
+	}
+}
+
+// describeHomePlace formats a WFH location for the success DM
+func describeHomePlace(address string) string {
+	if address == "" {
+		return "Làm việc tại nhà"
+	}
+	return "Làm việc tại nhà, " + address
+}
+
+// ============================================================
+// HOME COMMAND
+// ============================================================
+
+func (w *WebRTCManager) handleHomeCommand(args []string) models.ChannelMessageContent {
+	if len(args) == 0 {
+		return client.BuildSimpleTextMessage(homeUsage)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		return client.BuildSimpleTextMessage(formatHomeLocations(w.locationConfig.HomeLocations()))
+
+	case "approve":
+		if len(args) < 2 {
+			return client.BuildSimpleTextMessage(homeUsage)
+		}
+		userID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return client.BuildErrorMessage("❌ User ID không hợp lệ", args[1])
+		}
+		if _, err := w.locationConfig.ApproveHomeLocation(userID); err != nil {
+			return client.BuildErrorMessage("❌ Duyệt thất bại", err.Error())
+		}
+		logger.Info("Home location approved", "user_id", userID)
+		return client.BuildSuccessMessage("✅ Đã duyệt", fmt.Sprintf("User %d có thể check-in WFH", userID))
+
+	case "set":
+		if len(args) < 4 {
+			return client.BuildSimpleTextMessage(homeUsage)
+		}
+		userID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return client.BuildErrorMessage("❌ User ID không hợp lệ", args[1])
+		}
+		lat, latErr := strconv.ParseFloat(args[2], 64)
+		lon, lonErr := strconv.ParseFloat(args[3], 64)
+		if latErr != nil || lonErr != nil || !validCoordinates(lat, lon) {
+			return client.BuildErrorMessage("❌ Tọa độ không hợp lệ", strings.Join(args[2:4], " "))
+		}
+		radius := w.locationConfig.homeRadius()
+		if len(args) > 4 {
+			if radius, err = strconv.ParseFloat(args[4], 64); err != nil || radius <= 0 {
+				return client.BuildErrorMessage("❌ Bán kính không hợp lệ", args[4])
+			}
+		}
+		home := HomeLocation{Latitude: lat, Longitude: lon, RadiusMeters: radius, RegisteredAt: time.Now()}
+		if err := w.locationConfig.RegisterHomeLocation(userID, home); err != nil {
+			return client.BuildErrorMessage("❌ Lưu thất bại", err.Error())
+		}
+		logger.Info("Home location set by admin", "user_id", userID)
+		return client.BuildSuccessMessage("✅ Đã lưu", fmt.Sprintf("Vị trí làm việc tại nhà của user %d: (%.6f, %.6f) - %.0fm", userID, lat, lon, radius))
+
+	case "reset":
+		if len(args) < 2 {
+			return client.BuildSimpleTextMessage(homeUsage)
+		}
+		userID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return client.BuildErrorMessage("❌ User ID không hợp lệ", args[1])
+		}
+		if err := w.locationConfig.RemoveHomeLocation(userID); err != nil {
+			return client.BuildErrorMessage("❌ Xóa thất bại", err.Error())
+		}
+		return client.BuildSuccessMessage("✅ Đã xóa", fmt.Sprintf("User %d sẽ đề xuất lại vị trí ở lần check-in WFH tiếp theo", userID))
+
+	default:
+		return client.BuildSimpleTextMessage(homeUsage)
+	}
+}
+
+func formatHomeLocations(homes map[int64]HomeLocation) string {
+	if len(homes) == 0 {
+		return "Chưa có vị trí làm việc tại nhà nào được đăng ký."
+	}
+
+	userIDs := make([]int64, 0, len(homes))
+	for userID := range homes {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	var b strings.Builder
+	for _, userID := range userIDs {
+		home := homes[userID]
+		status := ""
+		if home.Pending {
+			status = " (chờ duyệt)"
+		}
+		fmt.Fprintf(&b, "🏠 %d: (%.6f, %.6f) - %.0fm, đăng ký %s%s\n",
+			userID, home.Latitude, home.Longitude, home.RadiusMeters,
+			home.RegisteredAt.In(Office{}.Zone()).Format("02/01/2006"), status)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
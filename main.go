@@ -4,31 +4,57 @@
 // - Reduced samplebuilder latency (maxLate: 128)
 // - Better JPEG quality control (90)
 // - Faster capture interval (1s)
-// - Persistent ffmpeg process option (commented, can enable)
+// - Persistent ffmpeg process option (feature flag persistent_ffmpeg)
 
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"mezon-checkin-bot/internal/alerting"
 	"mezon-checkin-bot/internal/api"
-	"mezon-checkin-bot/internal/audio"
 	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/crash"
+	"mezon-checkin-bot/internal/diagnostics"
+	"mezon-checkin-bot/internal/logging"
+	"mezon-checkin-bot/internal/matpool"
+	"mezon-checkin-bot/internal/tracing"
 	"mezon-checkin-bot/models"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"mezon-checkin-bot/internal/webrtc"
 )
 
+var logger = logging.For("main")
+
 // ============================================================
 // MAIN
 // ============================================================
 
 func main() {
+	logging.Setup(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	if err := runCommand(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// serve runs the bot until interrupted
+func serve() {
+	tracing.Setup(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), os.Getenv("OTEL_SERVICE_NAME"))
+	if err := crash.Setup(os.Getenv("SENTRY_DSN"), os.Getenv("SENTRY_ENVIRONMENT"), os.Getenv("SENTRY_RELEASE")); err != nil {
+		logger.Warn("Crash reporting disabled", "err", err)
+	}
+
 	fmt.Println("╔════════════════════════════════════════════════════╗")
 	fmt.Println("║  Mezon WebRTC Bot - OPTIMIZED FACE DETECTION     ║")
 	fmt.Println("║  🚀 Performance improvements:                      ║")
@@ -38,84 +64,230 @@ func main() {
 	fmt.Println("║     - Controlled JPEG quality (90)                ║")
 	fmt.Println("╚════════════════════════════════════════════════════╝")
 
-	botID := os.Getenv("BOT_ID")
-	botToken := os.Getenv("BOT_TOKEN")
-
-	host := os.Getenv("MEZON_HOST")
-	if host == "" {
-		host = "gw.mezon.ai"
+	config, err := mezonConfigFromEnv()
+	if err != nil {
+		logging.Fatal(logger, "Invalid bot configuration", "err", err)
 	}
-
-	port := os.Getenv("MEZON_PORT")
-	if port == "" {
-		port = "443"
+	// A binary built without the event store's driver stops here, before
+	// connecting to Mezon, instead of once the calls are set up
+	if kind := os.Getenv("EVENT_STORE"); kind != "" && kind != "file" {
+		if err := webrtc.SQLEventStoreSupported(kind); err != nil {
+			logging.Fatal(logger, "Invalid event store", "err", err)
+		}
 	}
 
-	useSSL := true
-	if os.Getenv("MEZON_USE_SSL") == "false" {
-		useSSL = false
-	}
-	botIDInt, err := strconv.ParseInt(botID, 10, 64)
-	config := models.Config{
-		BotID:    botIDInt,
-		BotToken: botToken,
-		Host:     host,
-		Port:     port,
-		UseSSL:   useSSL,
+	logger.Info("Bot configured", "bot_id", config.BotID)
+	stopSigning := make(chan struct{})
+	defer close(stopSigning)
+	apiClient, err := apiClientFromEnv(stopSigning)
+	if err != nil {
+		logging.Fatal(logger, "Failed to configure API client", "err", err)
 	}
+	// Reports the gocv Mats each call took from the pool and never returned
+	matpool.SetTracking(os.Getenv("MAT_LEAK_TRACKING") == "true")
+
+	alerter := alerting.New(alerting.DefaultCooldown)
+	alerting.SetDefault(alerter)
 
-	log.Printf("📋 Bot ID: %d", config.BotID)
-	apiClient := api.NewAPIClient(30 * time.Second)
-	client := client.NewMezonClient(config)
+	client := newMezonClient(config)
 	defer client.Close() // IMPORTANT: Always defer Close()
-	// Khởi tạo location config
-	locationConfig := &webrtc.LocationConfig{
-		Enabled:         true,
-		OfficesFilePath: "config/offices.json", // Đường dẫn tương đối từ thư mục chạy
-	}
 
-	faceConfig := &models.FaceRecognitionConfig{
+	channels := alertChannelsFromEnv()
+	locationConfig := locationConfigFromEnv(channels.locationClanID, channels.locationChannelID)
+	faceConfig := faceConfigFromEnv()
+	audioConfig := audioConfigFromEnv()
 
-		Enabled:     true,
-		MinFaceSize: 80,
-		JPEGQuality: 90, // High quality JPEG (range: 1-100)
-	}
-	audioConfig := audio.AudioConfig{
-		WelcomeAudioPath:   "./audio/welcome.ogg",
-		CheckinSuccessPath: "./audio/checkin-success.ogg",
-		CheckinFailPath:    "./audio/checkin-failed.ogg",
-		Enabled:            true,
+	stopDataKeys := make(chan struct{})
+	defer close(stopDataKeys)
+	dataKeys, err := dataKeysFromEnv(stopDataKeys)
+	if err != nil {
+		logging.Fatal(logger, "Invalid data encryption keys", "err", err)
 	}
+	checkDataKeys(dataKeys, faceConfig)
 	if err := client.Login(); err != nil {
-		log.Fatalf("❌ Failed to login: %v", err)
+		logging.Fatal(logger, "Failed to login", "err", err)
 	}
+	setupAlerts(client, alerter, channels)
 
-	webrtcManager, err := webrtc.NewWebRTCManager(client, "./image-captures", faceConfig, audioConfig, locationConfig, apiClient)
-	if err != nil {
-		log.Fatalf("❌ Failed to create WebRTC manager: %v", err)
-	}
-
-	log.Println("\n✅ Bot started with OPTIMIZED FACE DETECTION!")
-	log.Println("📞 Waiting for calls...")
-	log.Println("🎯 Optimizations:")
-	log.Println("   ✅ VP8 video capture")
-	log.Println("   ⚡ Fast face detection on scaled images (320px)")
-	log.Println("   ⚡ Reduced latency (maxLate: 128 vs 512)")
-	log.Println("   ⚡ Faster capture interval (1s vs 2s)")
-	log.Println("   ⚡ Faster PLI requests (1s vs 2s)")
-	log.Println("   ✅ High quality JPEG encoding (quality: 90)")
-	log.Println("   ✅ Sends expanded square images when face detected")
-	log.Println("   ✅ Sequential API submission (max 5 attempts)")
-	log.Printf("   - API: %s", models.APICheckIn)
-	log.Printf("   - Min face size: %dpx", faceConfig.MinFaceSize)
-	log.Println("   Press Ctrl+C to stop")
+	eventStore := openEventStore(locationConfig)
+	webrtcManager := newManager(client, apiClient, faceConfig, audioConfig, locationConfig, alerter, channels)
+	configureCalls(webrtcManager, dataKeys)
+	healthChecker := startSubmissionQueue(webrtcManager, apiClient, alerter)
+	// Decodes a test frame, runs detection and opens the audio files before
+	// the first call does
+	warmup(webrtcManager, healthChecker, alerter)
+
+	webrtcManager.SetEventStore(eventStore)
+	stopSyncs := make(chan struct{})
+	defer close(stopSyncs)
+	startSyncs(webrtcManager, locationConfig, stopSyncs)
+	restoreState(webrtcManager)
+	openAuditLog(webrtcManager)
+	setupImageArchive(webrtcManager, dataKeys, alerter)
+	configureCheckinFeatures(webrtcManager)
+	startServers(webrtcManager, client)
+	watchdog := startWatchdog(webrtcManager, alerter)
+
+	// Re-initializes failed subsystems, restarts the bot when that fails
+	restartCh := make(chan string, 1)
+	sup := startSupervisor(client, webrtcManager, func(reason string) {
+		select {
+		case restartCh <- reason:
+		default:
+		}
+	})
+
+	logger.Info("Bot started, waiting for calls",
+		"api", models.APICheckIn,
+		"min_face_size", faceConfig.MinFaceSize,
+		"jpeg_quality", faceConfig.JPEGQuality)
+	fmt.Println("Press Ctrl+C to stop")
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-	<-sigCh
+	var restartReason string
+	select {
+	case <-sigCh:
+	case restartReason = <-restartCh:
+	}
 
-	log.Println("\n⚠️  Shutting down...")
+	logger.Info("Shutting down")
+	sup.Stop()
+	watchdog.Stop()
 	webrtcManager.CloseAll()
 	client.Close()
-	log.Println("✅ Done!")
+	if closer, ok := eventStore.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Warn("Failed to close check-in event store", "err", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := tracing.Shutdown(ctx); err != nil {
+		logger.Warn("Failed to flush traces", "err", err)
+	}
+	if err := crash.Flush(ctx); err != nil {
+		logger.Warn("Failed to flush crash reports", "err", err)
+	}
+	cancel()
+	logger.Info("Done")
+
+	if restartReason != "" {
+		logger.Warn("Exiting for a restart", "reason", restartReason, "exit_code", restartExitCode)
+		os.Exit(restartExitCode)
+	}
+}
+
+// verifyArchive alerts when archived crops were sealed with keys that are
+// no longer loaded, and logs the ones left to reseal after a rotation
+func verifyArchive(archive *webrtc.ImageArchive, alerter *alerting.Alerter) {
+	check, err := archive.Verify()
+	switch {
+	case err != nil:
+		logger.Warn("Failed to verify image archive", "err", err)
+	case check.Unreadable > 0:
+		alerter.Alert(alerting.KeyDataKeys, "🚨 Kho ảnh có ảnh không giải mã được",
+			fmt.Sprintf("%d/%d ảnh được mã hóa bằng khóa không còn được nạp. Thêm lại khóa cũ vào DATA_KEYS_FILE.", check.Unreadable, check.Files))
+	case check.Stale > 0:
+		logger.Info("Archived images sealed with an old key, run !archive reseal", "stale", check.Stale, "files", check.Files)
+	default:
+		logger.Debug("Image archive verified", "files", check.Files)
+	}
+}
+
+// parseIDs parses a comma separated list of IDs
+func parseIDs(value string) []int64 {
+	var ids []int64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			logger.Warn("Invalid ID ignored", "value", part)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// adminChannelSender posts alerts to the admin channel over the bot's
+// websocket, so they can only be delivered while connected
+func adminChannelSender(c *client.MezonClient, clanID, channelID int64) alerting.Sender {
+	var dmManager *client.DMManager
+	var once sync.Once
+	return func(title, description string) error {
+		once.Do(func() {
+			dmManager = client.NewDMManager(c)
+		})
+		return dmManager.SendChannelMessage(clanID, channelID, client.BuildErrorMessage(title, description))
+	}
+}
+
+// startMetricsServer exposes API latency and error-rate metrics on /metrics
+func startMetricsServer(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", api.DefaultMetrics.Handler())
+
+	go func() {
+		logger.Info("Metrics available", "url", "http://"+addr+"/metrics")
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Warn("Metrics server stopped", "err", err)
+		}
+	}()
+}
+
+// defaultAdminAddr keeps pprof and the export on the host itself
+const defaultAdminAddr = "127.0.0.1:9091"
+
+// startAdminServer exposes the pprof endpoints under /debug/pprof/ when
+// enabled and the given handlers (admin API, attendance export, hosted
+// images). Without a TLS certificate it only listens on a loopback address,
+// behind a TLS terminating proxy.
+func startAdminServer(addr, certFile, keyFile string, pprofEnabled bool, handlers map[string]http.Handler) {
+	useTLS := certFile != "" && keyFile != ""
+	if !useTLS && !isLoopbackAddr(addr) {
+		logger.Error("ADMIN_ADDR is not a loopback address and ADMIN_TLS_CERT/ADMIN_TLS_KEY are not set, admin server not started",
+			"addr", addr)
+		return
+	}
+
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	mux := http.NewServeMux()
+	for path, handler := range handlers {
+		mux.Handle(path, handler)
+		logger.Info("Admin endpoint available", "url", scheme+"://"+addr+path)
+	}
+	if pprofEnabled {
+		diagnostics.RegisterPprof(mux)
+		logger.Info("pprof available", "url", scheme+"://"+addr+"/debug/pprof/")
+	}
+
+	go func() {
+		var err error
+		if useTLS {
+			err = http.ListenAndServeTLS(addr, certFile, keyFile, mux)
+		} else {
+			err = http.ListenAndServe(addr, mux)
+		}
+		logger.Warn("Admin server stopped", "err", err)
+	}()
+}
+
+// isLoopbackAddr reports whether host:port only accepts local connections
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"mezon-checkin-bot/internal/encryption"
+	"time"
+)
+
 // ============================================================
 // CONFIGURATION
 // ============================================================
@@ -18,9 +23,91 @@ type Config struct {
 type FaceRecognitionConfig struct {
 	Enabled     bool
 	MinFaceSize int
-	JPEGQuality int // Configurable JPEG quality (85-95 recommended)
+	JPEGQuality int    // Configurable JPEG quality (85-95 recommended)
+	JPEGEncoder string // "opencv" (default) or "go", compare them with bench-jpeg
+
+	// Detection backend: "haar" (default), "dnn" or "external". Haar is kept
+	// as fallback when the other backend cannot be loaded or finds nothing.
+	DetectionBackend       string
+	DNNModelType           string  // "yunet" or "ssd"
+	DNNModelPath           string  // .onnx (YuNet) or .caffemodel (SSD)
+	DNNConfigPath          string  // .prototxt for SSD, empty for YuNet
+	DNNConfidenceThreshold float32 // 0.0-1.0, default 0.6
+	DNNInputSize           int     // Network input size in px (default 320 YuNet, 300 SSD)
+	// Command reading a JPEG on stdin and printing [{"x":..,"y":..,"w":..,"h":..}]
+	ExternalDetectorCommand string
+
+	// Liveness: require a blink or head turn across frames before submission
+	LivenessEnabled   bool
+	LivenessMaxFrames int    // Frames to observe before failing (default 10)
+	EyeCascadePath    string // Haar eye cascade used for blink detection and alignment
+
+	// Quality gate: crops below these limits are never sent to the API.
+	// Zero values fall back to the detector defaults.
+	QualityGateEnabled     bool
+	QualityMinSharpness    float64 // Laplacian variance
+	QualityMinBrightness   float64 // Mean gray level (0-255)
+	QualityMaxBrightness   float64 // Mean gray level (0-255)
+	QualityMinFaceRatio    float64 // Face width / frame width
+	QualityMaxYawAsymmetry float64 // 0 = frontal
+
+	// Successful results are reused for repeat calls within this window (0 = disabled)
+	RecognitionCacheTTL time.Duration
+
+	// Local mode: match SFace embeddings on-device, API is only used for enrollment
+	LocalRecognitionEnabled bool
+	RecognitionModelPath    string  // SFace .onnx model
+	EmbeddingsFilePath      string  // JSON store of enrolled embeddings
+	LocalMatchThreshold     float32 // Cosine similarity (default 0.363)
+	// Seals the embeddings file when set (nil = plaintext)
+	EmbeddingsKeys *encryption.Keyring
+
+	// Reject frames with more than one valid-sized face (bystander protection)
+	RejectMultipleFaces bool
+
+	// Acceleration: "none" (default), "cuda" (needs -tags cuda) or "opencl".
+	// Falls back to CPU automatically when the device is unavailable. OpenCL
+	// only runs the DNN detector; Haar detection and resizing stay on the CPU.
+	Acceleration   string
+	GPUCascadePath string // CUDA-format Haar cascade (haarcascades_cuda)
+
+	// Rotate crops so the eyes are level before encoding (uses EyeCascadePath)
+	AlignFaces bool
+
+	// Batch mode: collect the BatchSize best crops (or whatever arrived within
+	// BatchWindow) and submit them in a single API call. 0 or 1 = one per call.
+	BatchSize   int
+	BatchWindow time.Duration
+
+	// Upload mode for crops: "json" (base64 in JSON, default) or "multipart"
+	// (raw JPEG parts, ~33% smaller). The backend must accept the chosen mode.
+	UploadMode string
+
+	// Check-in backend transport: "rest" (default) or "grpc". gRPC streams
+	// progress updates and suits backends running in the same cluster.
+	Backend     string
+	GRPCAddress string // "host:port" (plaintext) or "https://host:port"
 }
 
+const (
+	DetectionBackendHaar     = "haar"
+	DetectionBackendDNN      = "dnn"
+	DetectionBackendExternal = "external"
+
+	DNNModelYuNet = "yunet"
+	DNNModelSSD   = "ssd"
+
+	AccelerationNone   = "none"
+	AccelerationCUDA   = "cuda"
+	AccelerationOpenCL = "opencl"
+
+	UploadModeJSON      = "json"
+	UploadModeMultipart = "multipart"
+
+	BackendREST = "rest"
+	BackendGRPC = "grpc"
+)
+
 // ============================================================
 // SESSION & AUTHENTICATION
 // ============================================================
@@ -27,8 +27,20 @@ var (
 	BaseURL = getBaseURL()
 
 	// API endpoints
-	APICheckIn      = BaseURL + "/employees/bot/check-in"
-	APIUpdateStatus = BaseURL + "/employees/bot/update-status"
+	APICheckIn           = BaseURL + "/employees/bot/check-in"
+	APIUpdateStatus      = BaseURL + "/employees/bot/update-status"
+	APICheckOut          = BaseURL + "/employees/bot/check-out"
+	APIBreakStart        = BaseURL + "/employees/bot/break-start"
+	APIBreakEnd          = BaseURL + "/employees/bot/break-end"
+	APILateReason        = BaseURL + "/employees/bot/late-reason"
+	APIWFHRequest        = BaseURL + "/employees/bot/wfh-request"
+	APILeaveRequest      = BaseURL + "/employees/bot/leave-request"
+	APIOvertime          = BaseURL + "/employees/bot/overtime"
+	APIQRCheckIn         = BaseURL + "/employees/bot/qr-check-in"
+	APIOfficeAssignments = BaseURL + "/employees/bot/office-assignments"
+	APIEmployeeProfiles  = BaseURL + "/employees/bot/profiles"
+	APIBotConfig         = BaseURL + "/employees/bot/config"
+	APIBotLeaderLease    = BaseURL + "/employees/bot/leader-lease"
 )
 
 var (
@@ -54,3 +66,44 @@ type UpdateStatus struct {
 	UserId int64  `json:"userId"`
 	Status string `json:"status"`
 }
+
+type ClockOut struct {
+	UserId int64 `json:"userId"`
+}
+
+type BreakRequest struct {
+	UserId int64 `json:"userId"`
+}
+
+// AttendanceRequest registers WFH days or leave for a date range
+type AttendanceRequest struct {
+	UserId   int64  `json:"userId"`
+	FromDate string `json:"fromDate"` // "2006-01-02"
+	ToDate   string `json:"toDate"`   // Inclusive
+	Reason   string `json:"reason,omitempty"`
+}
+
+// OvertimeRequest registers overtime worked on Date
+type OvertimeRequest struct {
+	UserId    int64  `json:"userId"`
+	Date      string `json:"date"`      // "2006-01-02"
+	StartTime string `json:"startTime"` // "15:04"
+	Minutes   int    `json:"minutes"`
+	Reason    string `json:"reason"`
+}
+
+// QRCheckIn records a check-in by office QR code, without face recognition
+type QRCheckIn struct {
+	UserId    int64   `json:"userId"`
+	OfficeId  string  `json:"officeId"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// LateReason explains a late check-in, attached to that day's record
+type LateReason struct {
+	UserId      int64  `json:"userId"`
+	Date        string `json:"date"` // "2006-01-02"
+	LateMinutes int    `json:"lateMinutes"`
+	Reason      string `json:"reason"`
+}
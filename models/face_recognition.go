@@ -35,7 +35,7 @@ type FaceRecognitionResponse struct {
 	AccountEmployeeID       string             `json:"accountEmployeeId"`
 	FirstName               string             `json:"firstName"`
 	LastName                string             `json:"lastName"`
-	Shifts                  []interface{}      `json:"shifts"`
+	Shifts                  []Shift            `json:"shifts"`
 	LastClockEventDTO       *LastClockEventDTO `json:"lastClockEventDTO"`
 	IdentityVerified        bool               `json:"identityVerified"`
 	Probability             float64            `json:"probability"`
@@ -51,6 +51,15 @@ type LastClockEventDTO struct {
 	LastBreak *string `json:"lastBreak"`
 }
 
+// Shift is a work shift assigned to the employee. Times are as sent by the
+// backend, either "HH:MM[:SS]" or a full timestamp.
+type Shift struct {
+	ShiftID   string `json:"shiftId"`
+	Name      string `json:"shiftName"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+}
+
 // ============================================================
 // HELPER METHODS
 // ============================================================
@@ -87,3 +96,24 @@ func (r *FaceRecognitionResponse) IsSuccessful() bool {
 func (r *FaceRecognitionResponse) HasLastClockEvent() bool {
 	return r != nil && r.LastClockEventDTO != nil
 }
+
+// TodayShift returns the shift of the open clock event, or the first shift
+// assigned today. Returns nil if the employee has no shift.
+func (r *FaceRecognitionResponse) TodayShift() *Shift {
+	if r == nil || len(r.Shifts) == 0 {
+		return nil
+	}
+	if r.HasLastClockEvent() && r.LastClockEventDTO.ShiftID != nil {
+		for i := range r.Shifts {
+			if r.Shifts[i].ShiftID == *r.LastClockEventDTO.ShiftID {
+				return &r.Shifts[i]
+			}
+		}
+	}
+	return &r.Shifts[0]
+}
+
+// IsClockedIn checks if the last clock event is still open (no end time)
+func (r *FaceRecognitionResponse) IsClockedIn() bool {
+	return r.HasLastClockEvent() && r.LastClockEventDTO.StartTime != "" && r.LastClockEventDTO.EndTime == nil
+}
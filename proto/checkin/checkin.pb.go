@@ -0,0 +1,538 @@
+// Check-in backend gRPC API.
+//
+// checkin.pb.go and checkin_grpc.pb.go are generated from this file with
+// protoc-gen-go and protoc-gen-go-grpc, run go generate ./proto/... after
+// changing it.
+//
+// With API_SIGNED_RESPONSES=true the bot requires x-key-id, x-timestamp and
+// x-signature trailers on RecognizeStream, error statuses included, signed
+// over the grpc-status code and the concatenated response messages (see
+// internal/api/response_signing.go).
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: checkin.proto
+
+package checkin
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RecognizeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Imgs          [][]byte               `protobuf:"bytes,2,rep,name=imgs,proto3" json:"imgs,omitempty"` // Raw JPEG crops, best first
+	Attempt       int32                  `protobuf:"varint,3,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecognizeRequest) Reset() {
+	*x = RecognizeRequest{}
+	mi := &file_checkin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecognizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognizeRequest) ProtoMessage() {}
+
+func (x *RecognizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_checkin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognizeRequest.ProtoReflect.Descriptor instead.
+func (*RecognizeRequest) Descriptor() ([]byte, []int) {
+	return file_checkin_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RecognizeRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *RecognizeRequest) GetImgs() [][]byte {
+	if x != nil {
+		return x.Imgs
+	}
+	return nil
+}
+
+func (x *RecognizeRequest) GetAttempt() int32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+type LastClockEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClockId       string                 `protobuf:"bytes,1,opt,name=clock_id,json=clockId,proto3" json:"clock_id,omitempty"`
+	ShiftId       *string                `protobuf:"bytes,2,opt,name=shift_id,json=shiftId,proto3,oneof" json:"shift_id,omitempty"`
+	StartTime     string                 `protobuf:"bytes,3,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime       *string                `protobuf:"bytes,4,opt,name=end_time,json=endTime,proto3,oneof" json:"end_time,omitempty"`
+	LastBreak     *string                `protobuf:"bytes,5,opt,name=last_break,json=lastBreak,proto3,oneof" json:"last_break,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LastClockEvent) Reset() {
+	*x = LastClockEvent{}
+	mi := &file_checkin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LastClockEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LastClockEvent) ProtoMessage() {}
+
+func (x *LastClockEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_checkin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LastClockEvent.ProtoReflect.Descriptor instead.
+func (*LastClockEvent) Descriptor() ([]byte, []int) {
+	return file_checkin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LastClockEvent) GetClockId() string {
+	if x != nil {
+		return x.ClockId
+	}
+	return ""
+}
+
+func (x *LastClockEvent) GetShiftId() string {
+	if x != nil && x.ShiftId != nil {
+		return *x.ShiftId
+	}
+	return ""
+}
+
+func (x *LastClockEvent) GetStartTime() string {
+	if x != nil {
+		return x.StartTime
+	}
+	return ""
+}
+
+func (x *LastClockEvent) GetEndTime() string {
+	if x != nil && x.EndTime != nil {
+		return *x.EndTime
+	}
+	return ""
+}
+
+func (x *LastClockEvent) GetLastBreak() string {
+	if x != nil && x.LastBreak != nil {
+		return *x.LastBreak
+	}
+	return ""
+}
+
+type RecognizeResult struct {
+	state                   protoimpl.MessageState `protogen:"open.v1"`
+	FacialRecognitionStatus string                 `protobuf:"bytes,1,opt,name=facial_recognition_status,json=facialRecognitionStatus,proto3" json:"facial_recognition_status,omitempty"`
+	ImageVerifyId           string                 `protobuf:"bytes,2,opt,name=image_verify_id,json=imageVerifyId,proto3" json:"image_verify_id,omitempty"`
+	EmployeeId              string                 `protobuf:"bytes,3,opt,name=employee_id,json=employeeId,proto3" json:"employee_id,omitempty"`
+	AccountEmployeeId       string                 `protobuf:"bytes,4,opt,name=account_employee_id,json=accountEmployeeId,proto3" json:"account_employee_id,omitempty"`
+	FirstName               string                 `protobuf:"bytes,5,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName                string                 `protobuf:"bytes,6,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	IdentityVerified        bool                   `protobuf:"varint,7,opt,name=identity_verified,json=identityVerified,proto3" json:"identity_verified,omitempty"`
+	Probability             float64                `protobuf:"fixed64,8,opt,name=probability,proto3" json:"probability,omitempty"`
+	ShowMessage             bool                   `protobuf:"varint,9,opt,name=show_message,json=showMessage,proto3" json:"show_message,omitempty"`
+	IsWfh                   bool                   `protobuf:"varint,10,opt,name=is_wfh,json=isWfh,proto3" json:"is_wfh,omitempty"`
+	LastClockEvent          *LastClockEvent        `protobuf:"bytes,11,opt,name=last_clock_event,json=lastClockEvent,proto3" json:"last_clock_event,omitempty"`
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *RecognizeResult) Reset() {
+	*x = RecognizeResult{}
+	mi := &file_checkin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecognizeResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognizeResult) ProtoMessage() {}
+
+func (x *RecognizeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_checkin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognizeResult.ProtoReflect.Descriptor instead.
+func (*RecognizeResult) Descriptor() ([]byte, []int) {
+	return file_checkin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RecognizeResult) GetFacialRecognitionStatus() string {
+	if x != nil {
+		return x.FacialRecognitionStatus
+	}
+	return ""
+}
+
+func (x *RecognizeResult) GetImageVerifyId() string {
+	if x != nil {
+		return x.ImageVerifyId
+	}
+	return ""
+}
+
+func (x *RecognizeResult) GetEmployeeId() string {
+	if x != nil {
+		return x.EmployeeId
+	}
+	return ""
+}
+
+func (x *RecognizeResult) GetAccountEmployeeId() string {
+	if x != nil {
+		return x.AccountEmployeeId
+	}
+	return ""
+}
+
+func (x *RecognizeResult) GetFirstName() string {
+	if x != nil {
+		return x.FirstName
+	}
+	return ""
+}
+
+func (x *RecognizeResult) GetLastName() string {
+	if x != nil {
+		return x.LastName
+	}
+	return ""
+}
+
+func (x *RecognizeResult) GetIdentityVerified() bool {
+	if x != nil {
+		return x.IdentityVerified
+	}
+	return false
+}
+
+func (x *RecognizeResult) GetProbability() float64 {
+	if x != nil {
+		return x.Probability
+	}
+	return 0
+}
+
+func (x *RecognizeResult) GetShowMessage() bool {
+	if x != nil {
+		return x.ShowMessage
+	}
+	return false
+}
+
+func (x *RecognizeResult) GetIsWfh() bool {
+	if x != nil {
+		return x.IsWfh
+	}
+	return false
+}
+
+func (x *RecognizeResult) GetLastClockEvent() *LastClockEvent {
+	if x != nil {
+		return x.LastClockEvent
+	}
+	return nil
+}
+
+type RecognizeProgress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stage         string                 `protobuf:"bytes,1,opt,name=stage,proto3" json:"stage,omitempty"`      // e.g. "received", "detecting", "matching"
+	Percent       int32                  `protobuf:"varint,2,opt,name=percent,proto3" json:"percent,omitempty"` // 0-100
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecognizeProgress) Reset() {
+	*x = RecognizeProgress{}
+	mi := &file_checkin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecognizeProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognizeProgress) ProtoMessage() {}
+
+func (x *RecognizeProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_checkin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognizeProgress.ProtoReflect.Descriptor instead.
+func (*RecognizeProgress) Descriptor() ([]byte, []int) {
+	return file_checkin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RecognizeProgress) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *RecognizeProgress) GetPercent() int32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+type RecognizeEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*RecognizeEvent_Progress
+	//	*RecognizeEvent_Result
+	Event         isRecognizeEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecognizeEvent) Reset() {
+	*x = RecognizeEvent{}
+	mi := &file_checkin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecognizeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecognizeEvent) ProtoMessage() {}
+
+func (x *RecognizeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_checkin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecognizeEvent.ProtoReflect.Descriptor instead.
+func (*RecognizeEvent) Descriptor() ([]byte, []int) {
+	return file_checkin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RecognizeEvent) GetEvent() isRecognizeEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *RecognizeEvent) GetProgress() *RecognizeProgress {
+	if x != nil {
+		if x, ok := x.Event.(*RecognizeEvent_Progress); ok {
+			return x.Progress
+		}
+	}
+	return nil
+}
+
+func (x *RecognizeEvent) GetResult() *RecognizeResult {
+	if x != nil {
+		if x, ok := x.Event.(*RecognizeEvent_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isRecognizeEvent_Event interface {
+	isRecognizeEvent_Event()
+}
+
+type RecognizeEvent_Progress struct {
+	Progress *RecognizeProgress `protobuf:"bytes,1,opt,name=progress,proto3,oneof"`
+}
+
+type RecognizeEvent_Result struct {
+	Result *RecognizeResult `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+func (*RecognizeEvent_Progress) isRecognizeEvent_Event() {}
+
+func (*RecognizeEvent_Result) isRecognizeEvent_Event() {}
+
+var File_checkin_proto protoreflect.FileDescriptor
+
+const file_checkin_proto_rawDesc = "" +
+	"\n" +
+	"\rcheckin.proto\x12\n" +
+	"checkin.v1\"Y\n" +
+	"\x10RecognizeRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x12\n" +
+	"\x04imgs\x18\x02 \x03(\fR\x04imgs\x12\x18\n" +
+	"\aattempt\x18\x03 \x01(\x05R\aattempt\"\xd7\x01\n" +
+	"\x0eLastClockEvent\x12\x19\n" +
+	"\bclock_id\x18\x01 \x01(\tR\aclockId\x12\x1e\n" +
+	"\bshift_id\x18\x02 \x01(\tH\x00R\ashiftId\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"start_time\x18\x03 \x01(\tR\tstartTime\x12\x1e\n" +
+	"\bend_time\x18\x04 \x01(\tH\x01R\aendTime\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"last_break\x18\x05 \x01(\tH\x02R\tlastBreak\x88\x01\x01B\v\n" +
+	"\t_shift_idB\v\n" +
+	"\t_end_timeB\r\n" +
+	"\v_last_break\"\xd1\x03\n" +
+	"\x0fRecognizeResult\x12:\n" +
+	"\x19facial_recognition_status\x18\x01 \x01(\tR\x17facialRecognitionStatus\x12&\n" +
+	"\x0fimage_verify_id\x18\x02 \x01(\tR\rimageVerifyId\x12\x1f\n" +
+	"\vemployee_id\x18\x03 \x01(\tR\n" +
+	"employeeId\x12.\n" +
+	"\x13account_employee_id\x18\x04 \x01(\tR\x11accountEmployeeId\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x05 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x06 \x01(\tR\blastName\x12+\n" +
+	"\x11identity_verified\x18\a \x01(\bR\x10identityVerified\x12 \n" +
+	"\vprobability\x18\b \x01(\x01R\vprobability\x12!\n" +
+	"\fshow_message\x18\t \x01(\bR\vshowMessage\x12\x15\n" +
+	"\x06is_wfh\x18\n" +
+	" \x01(\bR\x05isWfh\x12D\n" +
+	"\x10last_clock_event\x18\v \x01(\v2\x1a.checkin.v1.LastClockEventR\x0elastClockEvent\"C\n" +
+	"\x11RecognizeProgress\x12\x14\n" +
+	"\x05stage\x18\x01 \x01(\tR\x05stage\x12\x18\n" +
+	"\apercent\x18\x02 \x01(\x05R\apercent\"\x8d\x01\n" +
+	"\x0eRecognizeEvent\x12;\n" +
+	"\bprogress\x18\x01 \x01(\v2\x1d.checkin.v1.RecognizeProgressH\x00R\bprogress\x125\n" +
+	"\x06result\x18\x02 \x01(\v2\x1b.checkin.v1.RecognizeResultH\x00R\x06resultB\a\n" +
+	"\x05event2\xa7\x01\n" +
+	"\x0eCheckinService\x12F\n" +
+	"\tRecognize\x12\x1c.checkin.v1.RecognizeRequest\x1a\x1b.checkin.v1.RecognizeResult\x12M\n" +
+	"\x0fRecognizeStream\x12\x1c.checkin.v1.RecognizeRequest\x1a\x1a.checkin.v1.RecognizeEvent0\x01B!Z\x1fmezon-checkin-bot/proto/checkinb\x06proto3"
+
+var (
+	file_checkin_proto_rawDescOnce sync.Once
+	file_checkin_proto_rawDescData []byte
+)
+
+func file_checkin_proto_rawDescGZIP() []byte {
+	file_checkin_proto_rawDescOnce.Do(func() {
+		file_checkin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_checkin_proto_rawDesc), len(file_checkin_proto_rawDesc)))
+	})
+	return file_checkin_proto_rawDescData
+}
+
+var file_checkin_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_checkin_proto_goTypes = []any{
+	(*RecognizeRequest)(nil),  // 0: checkin.v1.RecognizeRequest
+	(*LastClockEvent)(nil),    // 1: checkin.v1.LastClockEvent
+	(*RecognizeResult)(nil),   // 2: checkin.v1.RecognizeResult
+	(*RecognizeProgress)(nil), // 3: checkin.v1.RecognizeProgress
+	(*RecognizeEvent)(nil),    // 4: checkin.v1.RecognizeEvent
+}
+var file_checkin_proto_depIdxs = []int32{
+	1, // 0: checkin.v1.RecognizeResult.last_clock_event:type_name -> checkin.v1.LastClockEvent
+	3, // 1: checkin.v1.RecognizeEvent.progress:type_name -> checkin.v1.RecognizeProgress
+	2, // 2: checkin.v1.RecognizeEvent.result:type_name -> checkin.v1.RecognizeResult
+	0, // 3: checkin.v1.CheckinService.Recognize:input_type -> checkin.v1.RecognizeRequest
+	0, // 4: checkin.v1.CheckinService.RecognizeStream:input_type -> checkin.v1.RecognizeRequest
+	2, // 5: checkin.v1.CheckinService.Recognize:output_type -> checkin.v1.RecognizeResult
+	4, // 6: checkin.v1.CheckinService.RecognizeStream:output_type -> checkin.v1.RecognizeEvent
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_checkin_proto_init() }
+func file_checkin_proto_init() {
+	if File_checkin_proto != nil {
+		return
+	}
+	file_checkin_proto_msgTypes[1].OneofWrappers = []any{}
+	file_checkin_proto_msgTypes[4].OneofWrappers = []any{
+		(*RecognizeEvent_Progress)(nil),
+		(*RecognizeEvent_Result)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_checkin_proto_rawDesc), len(file_checkin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_checkin_proto_goTypes,
+		DependencyIndexes: file_checkin_proto_depIdxs,
+		MessageInfos:      file_checkin_proto_msgTypes,
+	}.Build()
+	File_checkin_proto = out.File
+	file_checkin_proto_goTypes = nil
+	file_checkin_proto_depIdxs = nil
+}
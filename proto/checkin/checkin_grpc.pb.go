@@ -0,0 +1,178 @@
+// Check-in backend gRPC API.
+//
+// checkin.pb.go and checkin_grpc.pb.go are generated from this file with
+// protoc-gen-go and protoc-gen-go-grpc, run go generate ./proto/... after
+// changing it.
+//
+// With API_SIGNED_RESPONSES=true the bot requires x-key-id, x-timestamp and
+// x-signature trailers on RecognizeStream, error statuses included, signed
+// over the grpc-status code and the concatenated response messages (see
+// internal/api/response_signing.go).
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: checkin.proto
+
+package checkin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CheckinService_Recognize_FullMethodName       = "/checkin.v1.CheckinService/Recognize"
+	CheckinService_RecognizeStream_FullMethodName = "/checkin.v1.CheckinService/RecognizeStream"
+)
+
+// CheckinServiceClient is the client API for CheckinService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CheckinServiceClient interface {
+	// Recognize returns only the final result
+	Recognize(ctx context.Context, in *RecognizeRequest, opts ...grpc.CallOption) (*RecognizeResult, error)
+	// RecognizeStream sends progress events followed by exactly one result
+	RecognizeStream(ctx context.Context, in *RecognizeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RecognizeEvent], error)
+}
+
+type checkinServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCheckinServiceClient(cc grpc.ClientConnInterface) CheckinServiceClient {
+	return &checkinServiceClient{cc}
+}
+
+func (c *checkinServiceClient) Recognize(ctx context.Context, in *RecognizeRequest, opts ...grpc.CallOption) (*RecognizeResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecognizeResult)
+	err := c.cc.Invoke(ctx, CheckinService_Recognize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkinServiceClient) RecognizeStream(ctx context.Context, in *RecognizeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RecognizeEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CheckinService_ServiceDesc.Streams[0], CheckinService_RecognizeStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RecognizeRequest, RecognizeEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CheckinService_RecognizeStreamClient = grpc.ServerStreamingClient[RecognizeEvent]
+
+// CheckinServiceServer is the server API for CheckinService service.
+// All implementations must embed UnimplementedCheckinServiceServer
+// for forward compatibility.
+type CheckinServiceServer interface {
+	// Recognize returns only the final result
+	Recognize(context.Context, *RecognizeRequest) (*RecognizeResult, error)
+	// RecognizeStream sends progress events followed by exactly one result
+	RecognizeStream(*RecognizeRequest, grpc.ServerStreamingServer[RecognizeEvent]) error
+	mustEmbedUnimplementedCheckinServiceServer()
+}
+
+// UnimplementedCheckinServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCheckinServiceServer struct{}
+
+func (UnimplementedCheckinServiceServer) Recognize(context.Context, *RecognizeRequest) (*RecognizeResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Recognize not implemented")
+}
+func (UnimplementedCheckinServiceServer) RecognizeStream(*RecognizeRequest, grpc.ServerStreamingServer[RecognizeEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method RecognizeStream not implemented")
+}
+func (UnimplementedCheckinServiceServer) mustEmbedUnimplementedCheckinServiceServer() {}
+func (UnimplementedCheckinServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeCheckinServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CheckinServiceServer will
+// result in compilation errors.
+type UnsafeCheckinServiceServer interface {
+	mustEmbedUnimplementedCheckinServiceServer()
+}
+
+func RegisterCheckinServiceServer(s grpc.ServiceRegistrar, srv CheckinServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCheckinServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CheckinService_ServiceDesc, srv)
+}
+
+func _CheckinService_Recognize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecognizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckinServiceServer).Recognize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CheckinService_Recognize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckinServiceServer).Recognize(ctx, req.(*RecognizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CheckinService_RecognizeStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RecognizeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CheckinServiceServer).RecognizeStream(m, &grpc.GenericServerStream[RecognizeRequest, RecognizeEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CheckinService_RecognizeStreamServer = grpc.ServerStreamingServer[RecognizeEvent]
+
+// CheckinService_ServiceDesc is the grpc.ServiceDesc for CheckinService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CheckinService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "checkin.v1.CheckinService",
+	HandlerType: (*CheckinServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Recognize",
+			Handler:    _CheckinService_Recognize_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RecognizeStream",
+			Handler:       _CheckinService_RecognizeStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "checkin.proto",
+}
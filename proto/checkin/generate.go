@@ -0,0 +1,5 @@
+// Package checkin is the check-in backend gRPC API generated from
+// checkin.proto
+package checkin
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative checkin.proto
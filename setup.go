@@ -0,0 +1,425 @@
+package main
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/adminserver"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/audio"
+	"mezon-checkin-bot/internal/audit"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/diagnostics"
+	"mezon-checkin-bot/internal/encryption"
+	"mezon-checkin-bot/internal/logging"
+	"mezon-checkin-bot/internal/webrtc"
+	"mezon-checkin-bot/models"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// SETUP - One function per subsystem, called in order by serve.
+// A configuration error stops the bot before it takes calls.
+// ============================================================
+
+// newMezonClient creates the client with where the bot joins when added and
+// the users it ignores. It is not logged in yet.
+func newMezonClient(config models.Config) *client.MezonClient {
+	mezonClient := client.NewMezonClient(config)
+	mezonClient.SetAccess(client.AccessConfig{
+		AllowedClans:    parseIDs(os.Getenv("AUTOJOIN_CLAN_IDS")),
+		AllowedChannels: parseIDs(os.Getenv("AUTOJOIN_CHANNEL_IDS")),
+		BlockedUsers:    parseIDs(os.Getenv("BLOCKED_USER_IDS")),
+	})
+
+	// Quick restarts (deploys) reuse the previous session instead of re-authenticating
+	if os.Getenv("SESSION_CACHE_ENABLED") == "true" {
+		if err := mezonClient.SetSessionCache("data/mezon_session.enc"); err != nil {
+			logger.Warn("Session cache disabled", "err", err)
+		}
+	}
+	return mezonClient
+}
+
+// checkDataKeys fails before anything is sealed with keys that can't read
+// what is stored. Face crops, debug frames and embeddings are sealed with
+// them.
+func checkDataKeys(dataKeys *encryption.Keyring, faceConfig *models.FaceRecognitionConfig) {
+	if dataKeys == nil {
+		return
+	}
+	if err := dataKeys.Check("data/data_keys.check"); err != nil {
+		logging.Fatal(logger, "Data key integrity check failed", "err", err)
+	}
+	faceConfig.EmbeddingsKeys = dataKeys
+}
+
+// setupAlerts sends operational alerts to the admin channel once logged in
+func setupAlerts(mezonClient *client.MezonClient, alerter *alerting.Alerter, channels alertChannels) {
+	if channels.adminChannelID != 0 {
+		alerter.SetSender(adminChannelSender(mezonClient, channels.adminClanID, channels.adminChannelID))
+	} else {
+		logger.Warn("No admin alert channel configured, alerts are only logged")
+	}
+	mezonClient.On("reconnected", func(interface{}) { alerter.Flush() })
+	mezonClient.On("reconnect_failed", func(data interface{}) {
+		event, _ := data.(map[string]interface{})
+		description := fmt.Sprintf("Kết nối lại Mezon thất bại %v lần liên tiếp: %v", event["attempts"], event["error"])
+		if final, _ := event["final"].(bool); final {
+			description += "\nBot đã ngừng thử kết nối lại, cần khởi động lại."
+		}
+		alerter.Alert(alerting.KeyReconnectFailed, "🚨 Mất kết nối Mezon", description)
+	})
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		alerter.Alert(alerting.KeyFFmpegMissing, "🚨 Thiếu ffmpeg",
+			"Không tìm thấy ffmpeg trong PATH. Xử lý video và phát âm thanh sẽ không hoạt động.")
+	}
+}
+
+// openEventStore opens the check-in event log. EVENT_STORE=sqlite|postgres
+// needs the binary built with the same tag; offices then move into the same
+// database, imported from offices.json.
+func openEventStore(locationConfig *webrtc.LocationConfig) webrtc.CheckinEventStore {
+	var eventStore webrtc.CheckinEventStore
+	var err error
+	switch kind := os.Getenv("EVENT_STORE"); kind {
+	case "", "file":
+		eventStore, err = webrtc.NewFileEventStore("data/checkin_events.jsonl")
+	default:
+		var sqlStore *webrtc.SQLEventStore
+		if sqlStore, err = webrtc.NewSQLEventStore(kind, os.Getenv("EVENT_STORE_DSN")); err == nil {
+			eventStore = sqlStore
+			locationConfig.OfficeStore, err = sqlStore.Offices()
+		}
+	}
+	if err != nil {
+		logging.Fatal(logger, "Failed to open check-in event log", "err", err)
+	}
+	return eventStore
+}
+
+// newManager creates the call manager, with leader election and the admins
+func newManager(mezonClient *client.MezonClient, apiClient *api.APIClient, faceConfig *models.FaceRecognitionConfig,
+	audioConfig audio.AudioConfig, locationConfig *webrtc.LocationConfig, alerter *alerting.Alerter, channels alertChannels) *webrtc.WebRTCManager {
+	manager, err := webrtc.NewWebRTCManager(mezonClient, "./image-captures", faceConfig, audioConfig, locationConfig, apiClient)
+	if err != nil {
+		alerter.Alert(alerting.KeyClassifierLoad, "🚨 Không khởi động được bot",
+			fmt.Sprintf("Khởi tạo bộ nhận diện khuôn mặt thất bại: %v", err))
+		logging.Fatal(logger, "Failed to create WebRTC manager", "err", err)
+	}
+	// Two instances during upgrades: the standby stays connected and takes
+	// over new calls when the leader hands off, stops or dies
+	if leaderConfig, ok, err := leaderConfigFromEnv(apiClient); err != nil {
+		logging.Fatal(logger, "Invalid leader election config", "err", err)
+	} else if ok {
+		if err := manager.StartLeaderElection(leaderConfig); err != nil {
+			logging.Fatal(logger, "Failed to start leader election", "err", err)
+		}
+	}
+	manager.SetAdmins(parseIDs(os.Getenv("ADMIN_USER_IDS")))
+	if os.Getenv("ADMIN_CHANNEL_COMMANDS_ENABLED") == "true" && channels.adminChannelID != 0 {
+		// Operators run !status, !endcall, !drain... in the admin alert channel
+		mezonClient.SetAdminChannel(channels.adminClanID, channels.adminChannelID)
+	}
+	return manager
+}
+
+// configureCalls sets how calls run: texts, limits, debug frames, TURN,
+// status messages and attendance rules
+func configureCalls(manager *webrtc.WebRTCManager, dataKeys *encryption.Keyring) {
+	// Message texts and embed branding, reloaded when the files change
+	templatesDir := os.Getenv("TEMPLATES_DIR")
+	if templatesDir == "" {
+		templatesDir = "config/templates"
+	}
+	templatesReload := 30
+	if value := os.Getenv("TEMPLATES_RELOAD_SECONDS"); value != "" {
+		templatesReload, _ = strconv.Atoi(value) // 0 disables hot reload
+	}
+	if err := manager.LoadTemplates(webrtc.TemplatesConfig{
+		Dir:            templatesDir,
+		ReloadInterval: time.Duration(templatesReload) * time.Second,
+	}); err != nil {
+		logging.Fatal(logger, "Failed to load message templates", "err", err)
+	}
+	// Calls per user and blocks after failed identifications, against brute force
+	callsPerHour, _ := strconv.Atoi(os.Getenv("CALL_LIMIT_PER_HOUR"))
+	callsPerDay, _ := strconv.Atoi(os.Getenv("CALL_LIMIT_PER_DAY"))
+	identityFailures, _ := strconv.Atoi(os.Getenv("IDENTITY_FAILURE_LIMIT"))
+	identityBlock, _ := strconv.Atoi(os.Getenv("IDENTITY_BLOCK_MINUTES"))
+	manager.SetCallLimits(webrtc.CallLimitConfig{
+		Enabled:          os.Getenv("CALL_LIMITS_ENABLED") == "true",
+		PerHour:          callsPerHour,
+		PerDay:           callsPerDay,
+		IdentityFailures: identityFailures,
+		IdentityBlock:    time.Duration(identityBlock) * time.Minute,
+	})
+	// Full frames of each attempt, for debugging the pipeline
+	if err := manager.SetDebugFrames(webrtc.DebugFramesConfig{
+		Enabled:   os.Getenv("DEBUG_FRAMES_ENABLED") == "true",
+		Dir:       os.Getenv("DEBUG_FRAMES_DIR"),
+		BlurFaces: os.Getenv("DEBUG_FRAMES_BLUR_FACES") != "false",
+		Keys:      dataKeys,
+	}); err != nil {
+		logging.Fatal(logger, "Invalid debug frames config", "err", err)
+	}
+	// Time-limited TURN credentials, instead of the static ones
+	turnTimeout, _ := strconv.Atoi(os.Getenv("TURN_CREDENTIALS_TIMEOUT_SECONDS"))
+	manager.SetTURNCredentials(webrtc.TURNConfig{
+		URL:      os.Getenv("TURN_CREDENTIALS_URL"),
+		APIKey:   os.Getenv("TURN_CREDENTIALS_KEY"),
+		Username: os.Getenv("TURN_CREDENTIALS_USER"),
+		Timeout:  time.Duration(turnTimeout) * time.Second,
+	})
+	// Edit one status DM per call instead of sending one per step
+	manager.SetStatusMessages(os.Getenv("DM_STATUS_EDIT_ENABLED") == "true")
+	transientTTL, _ := strconv.Atoi(os.Getenv("TRANSIENT_DM_TTL_MINUTES")) // 0 keeps interim DMs
+	manager.SetTransientMessageTTL(time.Duration(transientTTL) * time.Minute)
+	lateGraceMinutes, _ := strconv.Atoi(os.Getenv("LATE_GRACE_MINUTES"))
+	if err := manager.SetAttendanceConfig(webrtc.AttendanceConfig{
+		CheckoutEnabled: os.Getenv("CHECKOUT_ENABLED") == "true",
+		CheckoutAfter:   os.Getenv("CHECKOUT_AFTER"), // "HH:MM", e.g. "16:30"
+		BreaksEnabled:   os.Getenv("BREAKS_ENABLED") == "true",
+		WorkStart:       os.Getenv("WORK_START"), // "HH:MM", used when the backend sends no shift
+		LateGrace:       time.Duration(lateGraceMinutes) * time.Minute,
+		WorkEnd:         os.Getenv("WORK_END"),        // "HH:MM"
+		OvertimeCutoff:  os.Getenv("OVERTIME_CUTOFF"), // "HH:MM", default WORK_END
+		OvertimeEnabled: os.Getenv("OVERTIME_ENABLED") == "true",
+	}); err != nil {
+		logging.Fatal(logger, "Invalid attendance config", "err", err)
+	}
+}
+
+// startSubmissionQueue queues the submissions the backend can't take and
+// replays them once its health check recovers
+func startSubmissionQueue(manager *webrtc.WebRTCManager, apiClient *api.APIClient, alerter *alerting.Alerter) *api.HealthChecker {
+	healthChecker := api.NewHealthChecker(models.BaseURL, api.DefaultHealthInterval, api.DefaultHealthFailureLimit)
+	healthChecker.SetTransport(apiClient.Transport())
+
+	submissionQueue, err := api.NewSubmissionQueue("data/submission-queue", apiClient)
+	if err != nil {
+		logging.Fatal(logger, "Failed to open submission queue", "err", err)
+	}
+	healthChecker.OnRecover(submissionQueue.Drain)
+	healthChecker.OnUnhealthy(func(failures int) {
+		alerter.Alert(alerting.KeyBackendDown, "🚨 Backend check-in không phản hồi",
+			fmt.Sprintf("%s lỗi %d lần ping liên tiếp. Ảnh check-in sẽ được xếp hàng chờ gửi lại.", models.BaseURL, failures))
+	})
+	manager.SetSubmissionQueue(submissionQueue, api.DefaultQueueRetryInterval)
+	manager.SetHealthChecker(healthChecker)
+	return healthChecker
+}
+
+// startSyncs keeps employee profiles, office assignments, feature flags and
+// remote config up to date until stop is closed
+func startSyncs(manager *webrtc.WebRTCManager, locationConfig *webrtc.LocationConfig, stop chan struct{}) {
+	// Names, locales and office assignments from the backend, cached on disk
+	if os.Getenv("EMPLOYEE_PROFILES_FROM_API") == "true" {
+		profileRefresh, _ := strconv.Atoi(os.Getenv("EMPLOYEE_PROFILES_REFRESH_MINUTES"))
+		if err := manager.StartProfileSync(webrtc.ProfileSyncConfig{
+			Path:     "data/employee_profiles.json",
+			Interval: time.Duration(profileRefresh) * time.Minute,
+		}); err != nil {
+			logging.Fatal(logger, "Failed to start employee profile sync", "err", err)
+		}
+	}
+
+	if locationConfig.AssignmentsFromAPI {
+		assignmentRefresh, _ := strconv.Atoi(os.Getenv("OFFICE_ASSIGNMENTS_REFRESH_MINUTES"))
+		manager.StartAssignmentSync(time.Duration(assignmentRefresh) * time.Minute)
+	}
+
+	// Rules for the risky features, edited on disk and reloaded
+	if flagsPath := os.Getenv("FEATURE_FLAGS_FILE"); flagsPath != "" {
+		if err := manager.Flags().LoadFile(flagsPath); err != nil {
+			logging.Fatal(logger, "Failed to load feature flags", "err", err)
+		}
+		manager.Flags().WatchFile(flagsPath, 30*time.Second, stop)
+	}
+
+	// Offices, capture tuning and flags managed centrally for the fleet
+	if os.Getenv("REMOTE_CONFIG_ENABLED") == "true" {
+		remoteRefresh, _ := strconv.Atoi(os.Getenv("REMOTE_CONFIG_REFRESH_MINUTES"))
+		if err := manager.StartRemoteConfigSync(webrtc.RemoteConfigSyncConfig{
+			Path:     "data/remote_config.json",
+			Interval: time.Duration(remoteRefresh) * time.Minute,
+		}); err != nil {
+			logging.Fatal(logger, "Failed to start remote config sync", "err", err)
+		}
+	}
+}
+
+// restoreState resumes the check-ins a restart interrupted
+func restoreState(manager *webrtc.WebRTCManager) {
+	if _, err := manager.RestorePendingConfirmations("data/pending_confirmations.json"); err != nil {
+		logger.Warn("Failed to restore pending confirmations", "err", err)
+	}
+	if _, err := manager.RestoreReviews("data/location_reviews.json"); err != nil {
+		logger.Warn("Failed to restore location reviews", "err", err)
+	}
+	if _, err := manager.RecoverJournal("data/checkin_journal.json"); err != nil {
+		logger.Warn("Failed to recover check-in journal", "err", err)
+	}
+}
+
+// openAuditLog opens the HMAC-chained audit log
+func openAuditLog(manager *webrtc.WebRTCManager) {
+	auditPath := os.Getenv("AUDIT_LOG_PATH")
+	if auditPath == "" {
+		auditPath = "data/audit_log.jsonl"
+	}
+	auditKey, err := auditKeyFromEnv()
+	if err != nil {
+		logging.Fatal(logger, "Invalid audit log key", "err", err)
+	}
+	auditLog, err := audit.Open(auditPath, auditKey)
+	if err != nil {
+		logging.Fatal(logger, "Failed to open audit log", "err", err)
+	}
+	manager.SetAuditLog(auditLog)
+}
+
+// setupImageArchive archives face crops, only encrypted with the data keys
+func setupImageArchive(manager *webrtc.WebRTCManager, dataKeys *encryption.Keyring, alerter *alerting.Alerter) {
+	if os.Getenv("IMAGE_ARCHIVE_ENABLED") != "true" && os.Getenv("IMAGE_ARCHIVE_KEY") == "" {
+		return
+	}
+	if dataKeys == nil {
+		logging.Fatal(logger, "The image archive needs data keys, set DATA_KEYS_FILE or DATA_KEYS")
+	}
+	archiveDir := os.Getenv("IMAGE_ARCHIVE_DIR")
+	if archiveDir == "" {
+		archiveDir = "data/image_archive"
+	}
+	retentionDays, _ := strconv.Atoi(os.Getenv("IMAGE_ARCHIVE_RETENTION_DAYS"))
+	archive, err := webrtc.NewImageArchive(webrtc.ArchiveConfig{
+		Dir:          archiveDir,
+		Keys:         dataKeys,
+		Retention:    time.Duration(retentionDays) * 24 * time.Hour,
+		KeepRejected: os.Getenv("IMAGE_ARCHIVE_KEEP_REJECTED") == "true",
+	})
+	if err != nil {
+		logging.Fatal(logger, "Failed to open image archive", "err", err)
+	}
+	verifyArchive(archive, alerter)
+	manager.SetImageArchive(archive)
+}
+
+// configureCheckinFeatures enables the optional check-in features: daily
+// report, escalation, announcements, QR and photo check-in, export
+func configureCheckinFeatures(manager *webrtc.WebRTCManager) {
+	reportClanID, _ := strconv.ParseInt(os.Getenv("REPORT_CLAN_ID"), 10, 64)
+	reportChannelID, _ := strconv.ParseInt(os.Getenv("REPORT_CHANNEL_ID"), 10, 64)
+	if reportChannelID != 0 {
+		if err := manager.StartDailyReport(webrtc.ReportConfig{
+			ClanID:    reportClanID,
+			ChannelID: reportChannelID,
+			At:        os.Getenv("REPORT_TIME"), // "HH:MM", default 18:00
+		}); err != nil {
+			logging.Fatal(logger, "Invalid daily report config", "err", err)
+		}
+	}
+
+	// Manager/HR channel approving check-ins that failed recognition
+	escalationClanID, _ := strconv.ParseInt(os.Getenv("ESCALATION_CLAN_ID"), 10, 64)
+	escalationChannelID, _ := strconv.ParseInt(os.Getenv("ESCALATION_CHANNEL_ID"), 10, 64)
+	if err := manager.SetEscalationConfig(webrtc.EscalationConfig{
+		ClanID:    escalationClanID,
+		ChannelID: escalationChannelID,
+		Approvers: parseIDs(os.Getenv("ESCALATION_APPROVER_IDS")),
+		Path:      "data/pending_approvals.json",
+	}); err != nil {
+		logging.Fatal(logger, "Failed to restore pending approvals", "err", err)
+	}
+
+	// Team channels seeing each check-in, "clanID:channelID,..."
+	announceChannels, err := webrtc.ParseAnnouncementChannels(os.Getenv("CHECKIN_ANNOUNCE_CHANNELS"))
+	if err != nil {
+		logging.Fatal(logger, "Invalid announcement channels", "err", err)
+	}
+	if err := manager.SetAnnouncementConfig(webrtc.AnnouncementConfig{
+		Channels:   announceChannels,
+		OptOutPath: "data/announce_optout.json",
+	}); err != nil {
+		logging.Fatal(logger, "Failed to load announcement opt-outs", "err", err)
+	}
+
+	// Public URL of the admin server, for images in embeds (QR codes, face
+	// crops). Mezon fetches them from there.
+	manager.SetImageBaseURL(os.Getenv("IMAGE_BASE_URL"))
+
+	if os.Getenv("QR_CHECKIN_ENABLED") == "true" {
+		qrClanID, _ := strconv.ParseInt(os.Getenv("QR_CHECKIN_CLAN_ID"), 10, 64)
+		qrRotation, _ := strconv.Atoi(os.Getenv("QR_CHECKIN_ROTATION_MINUTES"))
+		if err := manager.StartQRCheckin(webrtc.QRCheckinConfig{
+			Secret:   []byte(os.Getenv("QR_CHECKIN_SECRET")),
+			Rotation: time.Duration(qrRotation) * time.Minute,
+			ClanID:   qrClanID,
+		}); err != nil {
+			logging.Fatal(logger, "Invalid QR check-in config", "err", err)
+		}
+	}
+
+	photoPhotos, _ := strconv.Atoi(os.Getenv("PHOTO_CHECKIN_PHOTOS"))
+	photoMinProbability, _ := strconv.ParseFloat(os.Getenv("PHOTO_CHECKIN_MIN_PROBABILITY"), 64)
+	var photoHosts []string
+	if hosts := os.Getenv("PHOTO_CHECKIN_HOSTS"); hosts != "" {
+		photoHosts = strings.Split(hosts, ",")
+	}
+	manager.SetPhotoCheckinConfig(webrtc.PhotoCheckinConfig{
+		Enabled:        os.Getenv("PHOTO_CHECKIN_ENABLED") == "true",
+		RequiredPhotos: photoPhotos,
+		MinProbability: photoMinProbability,
+		AllowedHosts:   photoHosts,
+	})
+
+	// Attendance export on the admin server, EXPORT_BASE_URL is its public
+	// (TLS terminating) URL
+	exportTTL, _ := strconv.Atoi(os.Getenv("EXPORT_LINK_TTL_MINUTES"))
+	manager.SetExportConfig(webrtc.ExportConfig{
+		Token:   os.Getenv("EXPORT_TOKEN"),
+		BaseURL: os.Getenv("EXPORT_BASE_URL"),
+		LinkTTL: time.Duration(exportTTL) * time.Minute,
+	})
+}
+
+// startServers starts the metrics listener and the admin server. pprof and
+// the export are kept off the plaintext metrics listener: the admin listener
+// binds to localhost unless it serves TLS.
+func startServers(manager *webrtc.WebRTCManager, mezonClient *client.MezonClient) {
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		startMetricsServer(addr)
+	}
+
+	adminAddr := os.Getenv("ADMIN_ADDR")
+	if adminAddr == "" {
+		adminAddr = defaultAdminAddr
+	}
+	adminHandlers := map[string]http.Handler{webrtc.ImagePath: manager.ImageHandler()}
+	if export := manager.ExportHandler(); export != nil {
+		adminHandlers[webrtc.ExportPath] = export
+	}
+	if token := os.Getenv("ADMIN_API_TOKEN"); token != "" {
+		adminHandlers[adminserver.Path] = adminserver.New(manager, mezonClient, token).Handler()
+	}
+	startAdminServer(adminAddr, os.Getenv("ADMIN_TLS_CERT"), os.Getenv("ADMIN_TLS_KEY"),
+		os.Getenv("PPROF_ENABLED") == "true", adminHandlers)
+}
+
+// startWatchdog alerts on calls that never reach cleanupConnection, they
+// leak goroutines and ffmpeg processes
+func startWatchdog(manager *webrtc.WebRTCManager, alerter *alerting.Alerter) *diagnostics.Watchdog {
+	watchdog := diagnostics.NewWatchdog(diagnostics.WatchdogConfig{
+		ActiveCalls: manager.ActiveCalls,
+	})
+	watchdog.OnAlert(func(alert diagnostics.Alert) {
+		alerter.Alert(alerting.KeyResourceLeak+":"+alert.Resource, "⚠️ Nghi ngờ rò rỉ tài nguyên", alert.String())
+	})
+	watchdog.Start()
+	return watchdog
+}
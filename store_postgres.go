@@ -0,0 +1,10 @@
+//go:build postgres
+
+package main
+
+// Links the pgx driver for EVENT_STORE=postgres:
+//
+//	go build -tags postgres
+//
+// The Docker image is built with this tag.
+import _ "github.com/jackc/pgx/v5/stdlib"
@@ -0,0 +1,8 @@
+//go:build sqlite
+
+package main
+
+// Links the pure Go SQLite driver for EVENT_STORE=sqlite:
+//
+//	go build -tags sqlite
+import _ "modernc.org/sqlite"
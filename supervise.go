@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mezon-checkin-bot/internal/client"
+	"mezon-checkin-bot/internal/supervisor"
+	"mezon-checkin-bot/internal/webrtc"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// ============================================================
+// SUPERVISION - Invariants of the running bot and how to heal them
+// ============================================================
+
+// restartExitCode is returned when the supervisor restarts the bot; the
+// process manager (restart: unless-stopped) starts it again
+const restartExitCode = 3
+
+// stalledAfter is how long without any websocket message, pongs included,
+// means the message handler is gone
+const stalledAfter = 3 * client.PingInterval * time.Second
+
+// startSupervisor checks the Mezon connection, its message handler, ffmpeg
+// and the detection models. restart is called when reconnecting keeps
+// failing.
+func startSupervisor(mezonClient *client.MezonClient, manager *webrtc.WebRTCManager, restart func(reason string)) *supervisor.Supervisor {
+	interval, _ := strconv.Atoi(os.Getenv("SUPERVISOR_INTERVAL_SECONDS"))
+	restartAfter, _ := strconv.Atoi(os.Getenv("SUPERVISOR_RESTART_AFTER"))
+	if restartAfter == 0 {
+		restartAfter = 10
+	}
+	if os.Getenv("SUPERVISOR_RESTART_ENABLED") == "false" {
+		restartAfter = 0
+	}
+
+	sup := supervisor.New(time.Duration(interval)*time.Second, restart)
+	sup.Add(supervisor.Check{
+		Name: "mezon_websocket",
+		Probe: func() error {
+			if !mezonClient.IsConnected() {
+				return fmt.Errorf("websocket not connected")
+			}
+			return nil
+		},
+		Heal:         mezonClient.Reconnect,
+		RestartAfter: restartAfter,
+	})
+	sup.Add(supervisor.Check{
+		Name: "mezon_message_handler",
+		Probe: func() error {
+			last := mezonClient.LastReceived()
+			if mezonClient.IsConnected() && !last.IsZero() && time.Since(last) > stalledAfter {
+				return fmt.Errorf("no message for %s", time.Since(last).Round(time.Second))
+			}
+			return nil
+		},
+		Heal:         mezonClient.Reconnect,
+		RestartAfter: restartAfter,
+	})
+	sup.Add(supervisor.Check{
+		Name:  "ffmpeg",
+		Probe: checkFFmpeg,
+	})
+	sup.Add(supervisor.Check{
+		Name:  "face_models",
+		Probe: manager.FaceDetector().CheckModels,
+		Heal:  manager.FaceDetector().ReloadModels,
+	})
+	sup.Start()
+	return sup
+}
+
+// checkFFmpeg starts ffmpeg the way calls do
+func checkFFmpeg() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := exec.CommandContext(ctx, "ffmpeg", "-version").Run(); err != nil {
+		return fmt.Errorf("cannot run ffmpeg: %w", err)
+	}
+	return nil
+}
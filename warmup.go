@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"mezon-checkin-bot/internal/alerting"
+	"mezon-checkin-bot/internal/api"
+	"mezon-checkin-bot/internal/logging"
+	"mezon-checkin-bot/internal/webrtc"
+	"mezon-checkin-bot/models"
+	"os"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// WARMUP - Startup self-test: what the first call needs is tried
+// before the bot reports itself ready
+// ============================================================
+
+// warmup runs the manager's checks and pings the backend, then logs a
+// readiness summary. Failures are alerted, and stop the bot with
+// WARMUP_STRICT=true.
+func warmup(manager *webrtc.WebRTCManager, health *api.HealthChecker, alerter *alerting.Alerter) {
+	if os.Getenv("WARMUP_ENABLED") == "false" {
+		return
+	}
+
+	checks := manager.Warmup(os.Getenv("WARMUP_FRAME"))
+	start := time.Now()
+	var pingErr error
+	if !health.Check() {
+		pingErr = fmt.Errorf("health check of %s failed", models.BaseURL)
+	}
+	checks = append(checks, webrtc.WarmupCheck{Name: "backend", Err: pingErr, Duration: time.Since(start)})
+
+	var failed []string
+	for _, check := range checks {
+		if check.Err != nil {
+			logger.Error("Warmup check failed", "check", check.Name, "err", check.Err)
+			failed = append(failed, fmt.Sprintf("%s: %v", check.Name, check.Err))
+			continue
+		}
+		logger.Debug("Warmup check passed", "check", check.Name, "duration", check.Duration)
+	}
+	logger.Info("Warmup finished", "ready", len(failed) == 0, "checks", len(checks), "failed", len(failed))
+	if len(failed) == 0 {
+		return
+	}
+
+	alerter.Alert(alerting.KeyWarmupFailed, "🚨 Bot khởi động chưa sẵn sàng",
+		"Kiểm tra khi khởi động thất bại, cuộc gọi có thể lỗi:\n"+strings.Join(failed, "\n"))
+	if os.Getenv("WARMUP_STRICT") == "true" {
+		logging.Fatal(logger, "Warmup failed", "failed", failed)
+	}
+}